@@ -0,0 +1,96 @@
+// Command node-release generates the release signing key and signs node
+// binaries into the node-<arch>.manifest.json files the panel serves.
+//
+//	node-release keygen
+//	NODE_RELEASE_KEY=<base64 private key> node-release sign -version 3.2.0 -arch amd64 node-amd64
+//
+// The private key never belongs on a panel; the panel and nodes only need
+// the public key (NODE_RELEASE_PUBKEY / release.pub).
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"flux-panel/go-backend/pkg"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	switch os.Args[1] {
+	case "keygen":
+		keygen()
+	case "sign":
+		sign(os.Args[2:])
+	default:
+		usage()
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: node-release keygen | node-release sign -version <v> -arch <arch> [-key <file>] [-o <manifest>] <binary>")
+	os.Exit(2)
+}
+
+func keygen() {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		fatal(err)
+	}
+	fmt.Printf("private: %s\n", base64.StdEncoding.EncodeToString(priv.Seed()))
+	fmt.Printf("public:  %s\n", base64.StdEncoding.EncodeToString(pub))
+}
+
+func sign(args []string) {
+	fs := flag.NewFlagSet("sign", flag.ExitOnError)
+	version := fs.String("version", "", "release version, as the node reports it")
+	arch := fs.String("arch", "", "GOARCH of the binary")
+	keyFile := fs.String("key", "", "file holding the base64 private key (default: $NODE_RELEASE_KEY)")
+	out := fs.String("o", "", "manifest path (default: <binary>.manifest.json)")
+	fs.Parse(args)
+	if fs.NArg() != 1 || *version == "" || *arch == "" {
+		usage()
+	}
+	binary := fs.Arg(0)
+
+	raw := os.Getenv("NODE_RELEASE_KEY")
+	if *keyFile != "" {
+		b, err := os.ReadFile(*keyFile)
+		if err != nil {
+			fatal(err)
+		}
+		raw = string(b)
+	}
+	if raw == "" {
+		fatal(fmt.Errorf("no signing key: pass -key or set NODE_RELEASE_KEY"))
+	}
+	key, err := pkg.ParseReleasePrivateKey(raw)
+	if err != nil {
+		fatal(err)
+	}
+
+	release, err := pkg.SignNodeRelease(binary, *version, *arch, key)
+	if err != nil {
+		fatal(err)
+	}
+	if *out == "" {
+		*out = binary + ".manifest.json"
+	}
+	b, _ := json.MarshalIndent(release, "", "  ")
+	if err := os.WriteFile(*out, append(b, '\n'), 0644); err != nil {
+		fatal(err)
+	}
+	fmt.Printf("%s: %s %s %d bytes sha256=%s\n", *out, release.Version, release.Arch, release.Size, release.SHA256)
+}
+
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, "node-release:", err)
+	os.Exit(1)
+}
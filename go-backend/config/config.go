@@ -16,8 +16,24 @@ type Config struct {
 	NodeBinaryDir  string
 	Port           int
 	AllowedOrigins []string
+	// ClusterAddr is this replica's address as seen by other replicas
+	// (e.g. http://10.0.0.5:6365). Setting it enables multi-instance mode.
+	ClusterAddr   string
+	ClusterSecret string
+	// NodeReleasePubKey is the base64 Ed25519 key node release manifests
+	// are signed with; falls back to release.pub in NodeBinaryDir.
+	NodeReleasePubKey string
+	// NodeLegacyAuth accepts nodes that send their secret in the WebSocket
+	// URL. Off unless NODE_LEGACY_AUTH=true; only for migrating old nodes.
+	NodeLegacyAuth bool
+	// TrustedProxies are the addresses (IPs or CIDRs) whose X-Forwarded-For
+	// is believed when working out a client's IP. Defaults to loopback and
+	// private ranges, which covers the bundled nginx; "none" trusts nobody.
+	TrustedProxies []string
 }
 
+const defaultTrustedProxies = "127.0.0.1,::1,10.0.0.0/8,172.16.0.0/12,192.168.0.0/16,fc00::/7"
+
 var Cfg *Config
 
 func Load() {
@@ -31,6 +47,12 @@ func Load() {
 		NodeBinaryDir:  getEnv("NODE_BINARY_DIR", "/data/node"),
 		Port:           getEnvInt("SERVER_PORT", 6365),
 		AllowedOrigins: parseOrigins(os.Getenv("ALLOWED_ORIGINS")),
+		ClusterAddr:    os.Getenv("CLUSTER_ADVERTISE_ADDR"),
+		ClusterSecret:  os.Getenv("CLUSTER_SECRET"),
+
+		NodeReleasePubKey: os.Getenv("NODE_RELEASE_PUBKEY"),
+		NodeLegacyAuth:    os.Getenv("NODE_LEGACY_AUTH") == "true",
+		TrustedProxies:    parseTrustedProxies(getEnv("TRUSTED_PROXIES", defaultTrustedProxies)),
 	}
 }
 
@@ -59,6 +81,21 @@ func parseOrigins(raw string) []string {
 	return origins
 }
 
+// parseTrustedProxies splits a comma-separated list; "none" gives an empty,
+// non-nil list so that no proxy is trusted.
+func parseTrustedProxies(raw string) []string {
+	proxies := []string{}
+	if strings.TrimSpace(raw) == "none" {
+		return proxies
+	}
+	for _, p := range strings.Split(raw, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			proxies = append(proxies, p)
+		}
+	}
+	return proxies
+}
+
 func getEnvInt(key string, fallback int) int {
 	if v := os.Getenv(key); v != "" {
 		if n, err := strconv.Atoi(v); err == nil {
@@ -6,6 +6,39 @@ type FlowDto struct {
 	D int64  `json:"d"`
 }
 
+// FlowBatchDto is a sequenced traffic report from a node's flow spool.
+// Seq increases monotonically per Epoch; Epoch changes when the node's
+// spool is recreated (e.g. after a reinstall).
+type FlowBatchDto struct {
+	Seq     int64         `json:"s"`
+	Epoch   string        `json:"e"`
+	Items   []FlowDto     `json:"items"`
+	Clients []XrayFlowDto `json:"clients"`
+}
+
+// XrayFlowDto is the traffic delta of one Xray client.
+type XrayFlowDto struct {
+	Email string `json:"email"`
+	U     int64  `json:"u"`
+	D     int64  `json:"d"`
+}
+
+// XrayIPReportDto lists the source IPs each Xray client connected from
+// since the node's previous report.
+type XrayIPReportDto struct {
+	Clients []XrayClientIPsDto `json:"clients"`
+}
+
+type XrayClientIPsDto struct {
+	Email string          `json:"email"`
+	IPs   []XrayIPSeenDto `json:"ips"`
+}
+
+type XrayIPSeenDto struct {
+	IP       string `json:"ip"`
+	LastSeen int64  `json:"t"` // unix seconds
+}
+
 type GostResponse struct {
 	Code int         `json:"code"`
 	Msg  string      `json:"msg"`
@@ -8,6 +8,8 @@ type ForwardDto struct {
 	InPort        *int   `json:"inPort"`
 	ListenIp      string `json:"listenIp"`
 	InterfaceName string `json:"interfaceName"`
+	UdpProbe      string `json:"udpProbe"`
+	UdpProbeData  string `json:"udpProbeData"`
 }
 
 type ForwardUpdateDto struct {
@@ -20,6 +22,8 @@ type ForwardUpdateDto struct {
 	InPort        *int   `json:"inPort"`
 	ListenIp      string `json:"listenIp"`
 	InterfaceName string `json:"interfaceName"`
+	UdpProbe      string `json:"udpProbe"`
+	UdpProbeData  string `json:"udpProbeData"`
 }
 
 type ForwardOrderItem struct {
@@ -8,6 +8,7 @@ type NodeDto struct {
 	PortSta   int    `json:"portSta"`
 	PortEnd   int    `json:"portEnd"`
 	GroupName string `json:"groupName"`
+	Region    string `json:"region"`
 }
 
 type NodeUpdateDto struct {
@@ -19,6 +20,7 @@ type NodeUpdateDto struct {
 	PortSta   *int    `json:"portSta"`
 	PortEnd   *int    `json:"portEnd"`
 	GroupName *string `json:"groupName"`
+	Region    *string `json:"region"`
 }
 
 type NodeSetProtocolDto struct {
@@ -27,3 +29,43 @@ type NodeSetProtocolDto struct {
 	Tls   int   `json:"tls" binding:"oneof=0 1"`
 	Socks int   `json:"socks" binding:"oneof=0 1"`
 }
+
+type NodeJoinTokenDto struct {
+	Name            string `json:"name" binding:"required"`
+	GroupName       string `json:"groupName"`
+	Region          string `json:"region"`
+	PortSta         int    `json:"portSta"`
+	PortEnd         int    `json:"portEnd"`
+	MaxUses         int    `json:"maxUses"` // 0 = unlimited
+	RequireApproval bool   `json:"requireApproval"`
+	ExpireTime      int64  `json:"expireTime"` // ms, 0 = never
+}
+
+type NodeJoinTokenUpdateDto struct {
+	ID              int64   `json:"id" binding:"required"`
+	Name            *string `json:"name"`
+	GroupName       *string `json:"groupName"`
+	Region          *string `json:"region"`
+	PortSta         *int    `json:"portSta"`
+	PortEnd         *int    `json:"portEnd"`
+	MaxUses         *int    `json:"maxUses"`
+	RequireApproval *bool   `json:"requireApproval"`
+	Enabled         *bool   `json:"enabled"`
+	ExpireTime      *int64  `json:"expireTime"`
+}
+
+// NodeEnrollDto is what the join script reports about the machine.
+type NodeEnrollDto struct {
+	Hostname  string `form:"hostname"`
+	Ips       string `form:"ips"` // whitespace separated, as printed by hostname -I
+	MachineId string `form:"machineId"`
+}
+
+type NodeRolloutDto struct {
+	Version       string `json:"version"`   // optional; must match the signed release
+	GroupName     string `json:"groupName"` // empty = all nodes
+	Percent       int    `json:"percent"`
+	CanaryCount   int    `json:"canaryCount"`
+	BatchSize     int    `json:"batchSize"`
+	HealthTimeout int    `json:"healthTimeout"` // seconds
+}
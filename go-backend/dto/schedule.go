@@ -0,0 +1,7 @@
+package dto
+
+type ScheduledJobUpdateDto struct {
+	Name    string  `json:"name" binding:"required"`
+	Spec    *string `json:"spec"`
+	Enabled *bool   `json:"enabled"`
+}
@@ -0,0 +1,31 @@
+package dto
+
+type SubscriptionProfileDto struct {
+	Name           string `json:"name" binding:"required"`
+	NodeIds        string `json:"nodeIds"`
+	InboundIds     string `json:"inboundIds"`
+	SortBy         string `json:"sortBy"`
+	RemarkTemplate string `json:"remarkTemplate"`
+	HostOverrides  string `json:"hostOverrides"`
+}
+
+type SubscriptionProfileUpdateDto struct {
+	ID             int64   `json:"id" binding:"required"`
+	Name           string  `json:"name"`
+	NodeIds        *string `json:"nodeIds"`
+	InboundIds     *string `json:"inboundIds"`
+	SortBy         *string `json:"sortBy"`
+	RemarkTemplate *string `json:"remarkTemplate"`
+	HostOverrides  *string `json:"hostOverrides"`
+}
+
+type SubscriptionTokenDto struct {
+	Name      string `json:"name"`
+	ProfileId int64  `json:"profileId" binding:"required"`
+}
+
+type SubscriptionTokenUpdateDto struct {
+	ID        int64  `json:"id" binding:"required"`
+	Name      string `json:"name"`
+	ProfileId *int64 `json:"profileId"`
+}
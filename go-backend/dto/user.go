@@ -22,6 +22,7 @@ type UserDto struct {
 	ExpTime         int64            `json:"expTime"`
 	FlowResetType   int              `json:"flowResetType"`
 	FlowResetDay    int              `json:"flowResetDay"`
+	Timezone        string           `json:"timezone"`
 	Status          *int             `json:"status"`
 	GostEnabled     *int             `json:"gostEnabled"`
 	XrayEnabled     *int             `json:"vEnabled"`
@@ -39,6 +40,7 @@ type UserUpdateDto struct {
 	ExpTime         int64            `json:"expTime"`
 	FlowResetType   int              `json:"flowResetType"`
 	FlowResetDay    int              `json:"flowResetDay"`
+	Timezone        *string          `json:"timezone"`
 	Status          *int             `json:"status"`
 	GostEnabled     *int             `json:"gostEnabled"`
 	XrayEnabled     *int             `json:"vEnabled"`
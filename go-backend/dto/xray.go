@@ -53,6 +53,31 @@ type XrayClientUpdateDto struct {
 	Remark       string `json:"remark"`
 }
 
+// XrayClientBulkCreateDto creates Count clients on one inbound sharing the
+// same limits. EmailTemplate may use {prefix}, {n}, {user} and {rand}.
+type XrayClientBulkCreateDto struct {
+	InboundId     int64  `json:"inboundId" binding:"required"`
+	Count         int    `json:"count" binding:"required"`
+	StartIndex    int    `json:"startIndex"`
+	UserId        int64  `json:"userId"`
+	Prefix        string `json:"prefix"`
+	EmailTemplate string `json:"emailTemplate"`
+	Flow          string `json:"flow"`
+	TotalTraffic  *int64 `json:"totalTraffic"`
+	ExpTime       *int64 `json:"expTime"`
+	LimitIp       *int   `json:"limitIp"`
+	Reset         *int   `json:"reset"`
+}
+
+type XrayClientBulkDto struct {
+	Ids []int64 `json:"ids" binding:"required"`
+}
+
+type XrayClientBulkMoveDto struct {
+	Ids       []int64 `json:"ids" binding:"required"`
+	InboundId int64   `json:"inboundId" binding:"required"`
+}
+
 type XrayTlsCertDto struct {
 	NodeId        int64  `json:"nodeId" binding:"required"`
 	Domain        string `json:"domain" binding:"required"`
@@ -0,0 +1,41 @@
+package dto
+
+type XrayOutboundDto struct {
+	NodeId             int64  `json:"nodeId" binding:"required"`
+	Tag                string `json:"tag" binding:"required"`
+	Protocol           string `json:"protocol" binding:"required"`
+	SettingsJson       string `json:"settingsJson"`
+	StreamSettingsJson string `json:"streamSettingsJson"`
+	ChainInboundId     int64  `json:"chainInboundId"`
+	DialerProxy        string `json:"dialerProxy"`
+	Remark             string `json:"remark"`
+	Inx                int    `json:"inx"`
+}
+
+// XrayOutboundUpdateDto replaces all editable fields of an outbound.
+type XrayOutboundUpdateDto struct {
+	ID int64 `json:"id" binding:"required"`
+	XrayOutboundDto
+	Enable *int `json:"enable"`
+}
+
+type XrayRoutingRuleDto struct {
+	NodeId      int64  `json:"nodeId" binding:"required"`
+	InboundIds  string `json:"inboundIds"`
+	UserIds     string `json:"userIds"`
+	Domain      string `json:"domain"`
+	Ip          string `json:"ip"`
+	Port        string `json:"port"`
+	Network     string `json:"network"`
+	Protocol    string `json:"protocol"`
+	OutboundTag string `json:"outboundTag" binding:"required"`
+	Remark      string `json:"remark"`
+	Inx         int    `json:"inx"`
+}
+
+// XrayRoutingRuleUpdateDto replaces all editable fields of a rule.
+type XrayRoutingRuleUpdateDto struct {
+	ID int64 `json:"id" binding:"required"`
+	XrayRoutingRuleDto
+	Enable *int `json:"enable"`
+}
@@ -0,0 +1,44 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"flux-panel/go-backend/pkg"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ClusterSend executes a command forwarded by another replica on a node
+// connected to this one.
+func ClusterSend(c *gin.Context) {
+	var req pkg.ClusterSendRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.String(http.StatusBadRequest, "invalid request")
+		return
+	}
+	timeout := time.Duration(req.TimeoutMs) * time.Millisecond
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	c.JSON(http.StatusOK, pkg.WS.SendLocalMsgWithTimeout(req.NodeId, req.Data, req.Type, timeout))
+}
+
+// ClusterBroadcast relays admin broadcasts published by another replica to
+// the admin sessions connected here.
+func ClusterBroadcast(c *gin.Context) {
+	var messages []string
+	if err := c.ShouldBindJSON(&messages); err != nil {
+		c.String(http.StatusBadRequest, "invalid request")
+		return
+	}
+	for _, msg := range messages {
+		pkg.WS.BroadcastLocal(msg)
+	}
+	c.Status(http.StatusOK)
+}
+
+// ClusterSysInfo returns the cached system info of nodes connected here.
+func ClusterSysInfo(c *gin.Context) {
+	c.JSON(http.StatusOK, pkg.WS.LocalNodeSystemInfo())
+}
@@ -1,8 +1,11 @@
 package handler
 
 import (
+	"flux-panel/go-backend/config"
+	"flux-panel/go-backend/pkg"
 	"flux-panel/go-backend/service"
 	"io"
+	"log"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -15,37 +18,53 @@ func FlowDebug(c *gin.Context) {
 
 const maxFlowBodySize = 10 << 20 // 10 MB
 
-// getNodeSecret extracts the node secret from the request.
-// It checks the X-Node-Secret header first, then falls back to the query parameter.
-func getNodeSecret(c *gin.Context) string {
-	if s := c.GetHeader("X-Node-Secret"); s != "" {
-		return s
-	}
-	return c.Query("secret")
-}
-
-func FlowUpload(c *gin.Context) {
-	secret := getNodeSecret(c)
+// readNodeReport reads a node report and authenticates it, returning the
+// body and the secret it is encrypted with. Reports must be signed (see
+// pkg.VerifyNodeRequest); the plain secret in the X-Node-Secret header or
+// query is accepted only while NODE_LEGACY_AUTH is on.
+func readNodeReport(c *gin.Context) (string, string, bool) {
 	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxFlowBodySize)
 	body, err := io.ReadAll(c.Request.Body)
 	if err != nil {
 		c.String(http.StatusRequestEntityTooLarge, "request body too large")
+		return "", "", false
+	}
+
+	if c.GetHeader(pkg.HeaderNodeKid) != "" {
+		_, secret, err := pkg.VerifyNodeRequest(c.Request.Header, c.Request.Method, c.Request.URL.Path, body, service.LookupNodeKey)
+		if err != nil {
+			log.Printf("[节点上报] %s 认证失败: %v", c.Request.URL.Path, err)
+			c.String(http.StatusUnauthorized, "unauthorized")
+			return "", "", false
+		}
+		return string(body), secret, true
+	}
+
+	if config.Cfg.NodeLegacyAuth {
+		secret := c.GetHeader("X-Node-Secret")
+		if secret == "" {
+			secret = c.Query("secret")
+		}
+		return string(body), secret, true
+	}
+	c.String(http.StatusUnauthorized, "unauthorized")
+	return "", "", false
+}
+
+func FlowUpload(c *gin.Context) {
+	body, secret, ok := readNodeReport(c)
+	if !ok {
 		return
 	}
-	result := service.ProcessFlowUpload(string(body), secret)
-	c.String(http.StatusOK, result)
+	c.String(http.StatusOK, service.ProcessFlowUpload(body, secret))
 }
 
 func FlowConfig(c *gin.Context) {
-	secret := getNodeSecret(c)
-	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxFlowBodySize)
-	body, err := io.ReadAll(c.Request.Body)
-	if err != nil {
-		c.String(http.StatusRequestEntityTooLarge, "request body too large")
+	body, secret, ok := readNodeReport(c)
+	if !ok {
 		return
 	}
-	result := service.ProcessFlowConfig(string(body), secret)
-	c.String(http.StatusOK, result)
+	c.String(http.StatusOK, service.ProcessFlowConfig(body, secret))
 }
 
 func FlowTest(c *gin.Context) {
@@ -53,13 +72,17 @@ func FlowTest(c *gin.Context) {
 }
 
 func FlowXrayUpload(c *gin.Context) {
-	secret := getNodeSecret(c)
-	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxFlowBodySize)
-	body, err := io.ReadAll(c.Request.Body)
-	if err != nil {
-		c.String(http.StatusRequestEntityTooLarge, "request body too large")
+	body, secret, ok := readNodeReport(c)
+	if !ok {
+		return
+	}
+	c.String(http.StatusOK, service.ProcessXrayFlowUpload(body, secret))
+}
+
+func FlowXrayIPs(c *gin.Context) {
+	body, secret, ok := readNodeReport(c)
+	if !ok {
 		return
 	}
-	result := service.ProcessXrayFlowUpload(string(body), secret)
-	c.String(http.StatusOK, result)
+	c.String(http.StatusOK, service.ProcessXrayIPReport(body, secret))
 }
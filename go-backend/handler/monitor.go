@@ -45,6 +45,47 @@ func MonitorForwardFlowHistory(c *gin.Context) {
 	c.JSON(http.StatusOK, service.GetForwardFlowHistory(d.ForwardId, d.Hours))
 }
 
+func MonitorNodeMetricsHistory(c *gin.Context) {
+	var d struct {
+		NodeId int64 `json:"nodeId" binding:"required"`
+		Hours  int   `json:"hours"`
+	}
+	if err := c.ShouldBindJSON(&d); err != nil {
+		c.JSON(http.StatusOK, dto.Err("参数错误"))
+		return
+	}
+	c.JSON(http.StatusOK, service.GetNodeMetricsHistory(d.NodeId, d.Hours))
+}
+
+func MonitorNodeMatrix(c *gin.Context) {
+	c.JSON(http.StatusOK, service.GetNodeLinkMatrix())
+}
+
+func MonitorNodeLinkHistory(c *gin.Context) {
+	var d struct {
+		SrcNodeId int64 `json:"srcNodeId" binding:"required"`
+		DstNodeId int64 `json:"dstNodeId" binding:"required"`
+		Hours     int   `json:"hours"`
+	}
+	if err := c.ShouldBindJSON(&d); err != nil {
+		c.JSON(http.StatusOK, dto.Err("参数错误"))
+		return
+	}
+	c.JSON(http.StatusOK, service.GetNodeLinkHistory(d.SrcNodeId, d.DstNodeId, d.Hours))
+}
+
+func MonitorNodeLinkTest(c *gin.Context) {
+	var d struct {
+		SrcNodeId int64 `json:"srcNodeId" binding:"required"`
+		DstNodeId int64 `json:"dstNodeId" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&d); err != nil {
+		c.JSON(http.StatusOK, dto.Err("参数错误"))
+		return
+	}
+	c.JSON(http.StatusOK, service.TestNodeLink(d.SrcNodeId, d.DstNodeId))
+}
+
 func MonitorTrafficOverview(c *gin.Context) {
 	var d struct {
 		Granularity string `json:"granularity"`
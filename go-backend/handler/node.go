@@ -2,44 +2,12 @@ package handler
 
 import (
 	"flux-panel/go-backend/dto"
-	"flux-panel/go-backend/pkg"
 	"flux-panel/go-backend/service"
 	"net/http"
-	"strconv"
-	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
-func versionLessThan(a, b string) bool {
-	parse := func(v string) [3]int {
-		v = strings.TrimSpace(v)
-		v = strings.TrimPrefix(v, "v")
-		if v == "" || v == "dev" {
-			return [3]int{0, 0, 0}
-		}
-		if idx := strings.Index(v, "-"); idx >= 0 {
-			v = v[:idx]
-		}
-		parts := strings.Split(v, ".")
-		var out [3]int
-		for i := 0; i < len(parts) && i < 3; i++ {
-			if n, err := strconv.Atoi(parts[i]); err == nil {
-				out[i] = n
-			}
-		}
-		return out
-	}
-	pa := parse(a)
-	pb := parse(b)
-	for i := 0; i < 3; i++ {
-		if pa[i] != pb[i] {
-			return pa[i] < pb[i]
-		}
-	}
-	return false
-}
-
 func NodeCreate(c *gin.Context) {
 	var d dto.NodeDto
 	if err := c.ShouldBindJSON(&d); err != nil {
@@ -147,23 +115,109 @@ func NodeUpdateBinary(c *gin.Context) {
 		c.JSON(http.StatusOK, dto.Err("参数错误"))
 		return
 	}
+	c.JSON(http.StatusOK, service.UpdateNodeBinary(d.ID))
+}
+
+func NodeRollbackBinary(c *gin.Context) {
+	var d struct {
+		ID int64 `json:"id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&d); err != nil {
+		c.JSON(http.StatusOK, dto.Err("参数错误"))
+		return
+	}
+	c.JSON(http.StatusOK, service.RollbackNodeBinary(d.ID))
+}
+
+func NodeRotateSecret(c *gin.Context) {
+	var d struct {
+		ID         int64 `json:"id" binding:"required"`
+		GraceHours int   `json:"graceHours"`
+	}
+	if err := c.ShouldBindJSON(&d); err != nil {
+		c.JSON(http.StatusOK, dto.Err("参数错误"))
+		return
+	}
+	c.JSON(http.StatusOK, service.RotateNodeSecret(d.ID, d.GraceHours))
+}
+
+func NodeApprove(c *gin.Context) {
+	var d struct {
+		ID int64 `json:"id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&d); err != nil {
+		c.JSON(http.StatusOK, dto.Err("参数错误"))
+		return
+	}
+	c.JSON(http.StatusOK, service.ApproveNode(d.ID))
+}
+
+func NodeJoinTokenCreate(c *gin.Context) {
+	var d dto.NodeJoinTokenDto
+	if err := c.ShouldBindJSON(&d); err != nil {
+		c.JSON(http.StatusOK, dto.Err("参数错误"))
+		return
+	}
+	c.JSON(http.StatusOK, service.CreateNodeJoinToken(d))
+}
+
+func NodeJoinTokenList(c *gin.Context) {
+	var d struct {
+		PanelAddr string `json:"panelAddr"`
+	}
+	c.ShouldBindJSON(&d)
+	c.JSON(http.StatusOK, service.ListNodeJoinTokens(d.PanelAddr))
+}
+
+func NodeJoinTokenUpdate(c *gin.Context) {
+	var d dto.NodeJoinTokenUpdateDto
+	if err := c.ShouldBindJSON(&d); err != nil {
+		c.JSON(http.StatusOK, dto.Err("参数错误"))
+		return
+	}
+	c.JSON(http.StatusOK, service.UpdateNodeJoinToken(d))
+}
+
+func NodeJoinTokenDelete(c *gin.Context) {
+	var d struct {
+		ID int64 `json:"id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&d); err != nil {
+		c.JSON(http.StatusOK, dto.Err("参数错误"))
+		return
+	}
+	c.JSON(http.StatusOK, service.DeleteNodeJoinToken(d.ID))
+}
 
-	// Legacy nodes (no disguise name) must be reinstalled manually
-	node := service.GetNodeById(d.ID)
-	if node != nil && node.DisguiseName == "" && versionLessThan(node.Version, "2.1.0") {
-		c.JSON(http.StatusOK, dto.Err("该节点需要使用新的安装命令重新安装，请在节点管理页面点击安装按钮获取新命令"))
+func NodeReleaseInfo(c *gin.Context) {
+	c.JSON(http.StatusOK, service.GetNodeReleaseInfo())
+}
+
+func NodeRolloutCreate(c *gin.Context) {
+	var d dto.NodeRolloutDto
+	if err := c.ShouldBindJSON(&d); err != nil {
+		c.JSON(http.StatusOK, dto.Err("参数错误"))
 		return
 	}
+	c.JSON(http.StatusOK, service.CreateNodeRollout(d))
+}
 
-	panelAddr := service.GetPanelAddress("")
-	result := pkg.NodeUpdateBinary(d.ID, panelAddr)
-	if result == nil || result.Msg != "OK" {
-		msg := "节点更新失败"
-		if result != nil {
-			msg = result.Msg
-		}
-		c.JSON(http.StatusOK, dto.Err(msg))
+func NodeRolloutList(c *gin.Context) {
+	c.JSON(http.StatusOK, service.ListNodeRollouts())
+}
+
+func nodeRolloutAction(c *gin.Context, fn func(int64) dto.R) {
+	var d struct {
+		ID int64 `json:"id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&d); err != nil {
+		c.JSON(http.StatusOK, dto.Err("参数错误"))
 		return
 	}
-	c.JSON(http.StatusOK, dto.Ok(result))
+	c.JSON(http.StatusOK, fn(d.ID))
 }
+
+func NodeRolloutDetail(c *gin.Context)   { nodeRolloutAction(c, service.GetNodeRollout) }
+func NodeRolloutCancel(c *gin.Context)   { nodeRolloutAction(c, service.CancelNodeRollout) }
+func NodeRolloutResume(c *gin.Context)   { nodeRolloutAction(c, service.ResumeNodeRollout) }
+func NodeRolloutRollback(c *gin.Context) { nodeRolloutAction(c, service.RollbackNodeRollout) }
@@ -2,9 +2,12 @@ package handler
 
 import (
 	"flux-panel/go-backend/config"
+	"flux-panel/go-backend/dto"
 	"flux-panel/go-backend/model"
+	"flux-panel/go-backend/pkg"
 	"flux-panel/go-backend/service"
 	"fmt"
+	"log"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -198,11 +201,7 @@ func NodeInstallXray(c *gin.Context) {
 
 // findNodeBySecret looks up a node by its secret.
 func findNodeBySecret(secret string) *model.Node {
-	var node model.Node
-	if err := service.DB.Where("secret = ?", secret).First(&node).Error; err != nil {
-		return nil
-	}
-	return &node
+	return service.FindNodeBySecret(secret)
 }
 
 // CamoInstallScript generates a fully pre-configured install script with disguised paths.
@@ -214,7 +213,12 @@ func CamoInstallScript(c *gin.Context) {
 		c.String(http.StatusNotFound, "not found")
 		return
 	}
+	c.Header("Content-Type", "text/plain; charset=utf-8")
+	c.String(http.StatusOK, camoInstallScript(c, node))
+}
 
+// camoInstallScript renders the install script of a node.
+func camoInstallScript(c *gin.Context, node *model.Node) string {
 	// Ensure disguise names exist (backfill for legacy nodes)
 	disguise := node.DisguiseName
 	xrayDisguise := node.XrayDisguiseName
@@ -236,7 +240,7 @@ func CamoInstallScript(c *gin.Context) {
 	addrValue = strings.TrimPrefix(addrValue, "https://")
 	addrValue = strings.TrimSuffix(addrValue, "/")
 
-	script := fmt.Sprintf(`#!/bin/bash
+	return fmt.Sprintf(`#!/bin/bash
 set -e
 
 # ─── Camouflaged Node Install Script ───
@@ -365,7 +369,72 @@ echo "Config: /etc/$DISGUISE/config.json"
 echo "Logs: journalctl -u $DISGUISE -f"
 echo "Uninstall: bash /etc/$DISGUISE/uninstall.sh"
 `, disguise, xrayDisguise, node.Secret, panelAddr, useTLS, addrValue, disguise, xrayDisguise)
+}
+
+// JoinBootstrapScript serves the script behind a join token's one-liner.
+// It only collects the machine's details and posts them to JoinEnroll, so
+// fetching it (e.g. by a link preview) doesn't use up the token.
+func JoinBootstrapScript(c *gin.Context) {
+	token := c.Param("token")
+	if service.FindNodeJoinToken(token) == nil {
+		c.String(http.StatusNotFound, "not found")
+		return
+	}
+
+	panelAddr := service.GetPanelAddress(c.GetHeader("Origin"))
+	script := fmt.Sprintf(`#!/bin/bash
+set -e
+
+# ─── Node Enrollment Script ───
+PANEL_ADDR="%s"
+JOIN_TOKEN="%s"
+
+CURL_FLAGS="-sSL"
+if [ "${1}" = "6" ]; then
+    CURL_FLAGS="-6sSL"
+fi
+
+HOST_NAME=$(hostname 2>/dev/null || true)
+HOST_IPS=$(hostname -I 2>/dev/null || ip -o addr show scope global 2>/dev/null | awk '{print $4}' | cut -d/ -f1 | tr '\n' ' ' || true)
+MACHINE_ID=$(cat /etc/machine-id 2>/dev/null || true)
+
+echo "Enrolling with panel..."
+SCRIPT_FILE=$(mktemp)
+trap 'rm -f "$SCRIPT_FILE"' EXIT
+CODE=$(curl $CURL_FLAGS -o "$SCRIPT_FILE" -w "%%{http_code}" -X POST \
+    --data-urlencode "hostname=$HOST_NAME" \
+    --data-urlencode "ips=$HOST_IPS" \
+    --data-urlencode "machineId=$MACHINE_ID" \
+    "$PANEL_ADDR/j/$JOIN_TOKEN/enroll")
+if [ "$CODE" != "200" ]; then
+    echo "Enrollment failed ($CODE): $(cat "$SCRIPT_FILE")"
+    exit 1
+fi
+
+bash "$SCRIPT_FILE" "$@"
+`, panelAddr, token)
+
+	c.Header("Content-Type", "text/plain; charset=utf-8")
+	c.String(http.StatusOK, script)
+}
 
+// JoinEnroll creates a node for the machine presenting a join token and
+// answers with that node's install script.
+func JoinEnroll(c *gin.Context) {
+	var d dto.NodeEnrollDto
+	if err := c.ShouldBind(&d); err != nil {
+		c.String(http.StatusBadRequest, "invalid request")
+		return
+	}
+	node, err := service.EnrollNode(c.Param("token"), d, c.ClientIP())
+	if err != nil {
+		c.String(http.StatusForbidden, err.Error())
+		return
+	}
+	script := camoInstallScript(c, node)
+	if node.Pending {
+		script += "echo \"Node is waiting for approval in the panel and will connect once approved.\"\n"
+	}
 	c.Header("Content-Type", "text/plain; charset=utf-8")
 	c.String(http.StatusOK, script)
 }
@@ -378,7 +447,51 @@ func CamoInstallBinary(c *gin.Context) {
 		c.String(http.StatusNotFound, "not found")
 		return
 	}
+	serveNodeBinary(c)
+}
+
+// NodeReleaseBinary serves the node binary to a node updating itself. The
+// request is signed like node reports, so the secret never appears in a URL.
+func NodeReleaseBinary(c *gin.Context) {
+	if verifyNodeDownload(c) {
+		serveNodeBinary(c)
+	}
+}
+
+// NodeReleaseManifest serves the signed release manifest of the node
+// binary; nodes verify the download against it before updating.
+func NodeReleaseManifest(c *gin.Context) {
+	if !verifyNodeDownload(c) {
+		return
+	}
+
+	arch := c.Param("arch")
+	if !allowedArchs[arch] {
+		c.String(http.StatusBadRequest, "invalid architecture")
+		return
+	}
+
+	manifestPath := filepath.Join(config.Cfg.NodeBinaryDir, fmt.Sprintf("node-%s.manifest.json", arch))
+	if _, err := os.Stat(manifestPath); os.IsNotExist(err) {
+		c.String(http.StatusNotFound, "manifest not found")
+		return
+	}
+
+	c.Header("Content-Type", "application/json")
+	c.File(manifestPath)
+}
+
+func verifyNodeDownload(c *gin.Context) bool {
+	_, _, err := pkg.VerifyNodeRequest(c.Request.Header, c.Request.Method, c.Request.URL.Path, nil, service.LookupNodeKey)
+	if err != nil {
+		log.Printf("[节点更新] %s 认证失败: %v", c.Request.URL.Path, err)
+		c.String(http.StatusUnauthorized, "unauthorized")
+		return false
+	}
+	return true
+}
 
+func serveNodeBinary(c *gin.Context) {
 	arch := c.Param("arch")
 	if !allowedArchs[arch] {
 		c.String(http.StatusBadRequest, "invalid architecture")
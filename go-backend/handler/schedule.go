@@ -0,0 +1,42 @@
+package handler
+
+import (
+	"flux-panel/go-backend/dto"
+	"flux-panel/go-backend/service"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func ScheduleJobList(c *gin.Context) {
+	c.JSON(http.StatusOK, service.ListScheduledJobs())
+}
+
+func ScheduleJobUpdate(c *gin.Context) {
+	var d dto.ScheduledJobUpdateDto
+	if err := c.ShouldBindJSON(&d); err != nil {
+		c.JSON(http.StatusOK, dto.Err("参数错误"))
+		return
+	}
+	c.JSON(http.StatusOK, service.UpdateScheduledJob(d))
+}
+
+func ScheduleJobRun(c *gin.Context) {
+	var d struct {
+		Name string `json:"name" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&d); err != nil {
+		c.JSON(http.StatusOK, dto.Err("参数错误"))
+		return
+	}
+	c.JSON(http.StatusOK, service.RunScheduledJobNow(d.Name))
+}
+
+func ScheduleJobHistory(c *gin.Context) {
+	var d struct {
+		Name  string `json:"name"`
+		Limit int    `json:"limit"`
+	}
+	c.ShouldBindJSON(&d)
+	c.JSON(http.StatusOK, service.GetScheduledJobRuns(d.Name, d.Limit))
+}
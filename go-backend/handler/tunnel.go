@@ -98,7 +98,7 @@ func TunnelUpdateOrder(c *gin.Context) {
 
 func TunnelDiagnose(c *gin.Context) {
 	var d struct {
-		ID int64 `json:"id" binding:"required"`
+		ID int64 `json:"tunnelId" binding:"required"`
 	}
 	if err := c.ShouldBindJSON(&d); err != nil {
 		c.JSON(http.StatusOK, dto.Err("参数错误"))
@@ -57,6 +57,17 @@ func XrayClientResetTraffic(c *gin.Context) {
 	c.JSON(http.StatusOK, service.ResetXrayClientTraffic(d.ID, GetUserId(c), GetRoleId(c)))
 }
 
+func XrayClientOnlineIPs(c *gin.Context) {
+	var d struct {
+		ID int64 `json:"id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&d); err != nil {
+		c.JSON(http.StatusOK, dto.Err("参数错误"))
+		return
+	}
+	c.JSON(http.StatusOK, service.GetXrayClientOnlineIPs(d.ID, GetUserId(c), GetRoleId(c)))
+}
+
 func XrayClientLink(c *gin.Context) {
 	var d struct {
 		ID int64 `json:"id" binding:"required"`
@@ -67,3 +78,57 @@ func XrayClientLink(c *gin.Context) {
 	}
 	c.JSON(http.StatusOK, service.GetClientLink(d.ID, GetUserId(c), GetRoleId(c)))
 }
+
+func XrayClientBulkCreate(c *gin.Context) {
+	var d dto.XrayClientBulkCreateDto
+	if err := c.ShouldBindJSON(&d); err != nil {
+		c.JSON(http.StatusOK, dto.Err("参数错误"))
+		return
+	}
+	c.JSON(http.StatusOK, service.BulkCreateXrayClients(d, GetUserId(c), GetRoleId(c)))
+}
+
+func XrayClientBulkEnable(c *gin.Context) {
+	var d dto.XrayClientBulkDto
+	if err := c.ShouldBindJSON(&d); err != nil {
+		c.JSON(http.StatusOK, dto.Err("参数错误"))
+		return
+	}
+	c.JSON(http.StatusOK, service.BulkSetXrayClientsEnable(d.Ids, true, GetUserId(c), GetRoleId(c)))
+}
+
+func XrayClientBulkDisable(c *gin.Context) {
+	var d dto.XrayClientBulkDto
+	if err := c.ShouldBindJSON(&d); err != nil {
+		c.JSON(http.StatusOK, dto.Err("参数错误"))
+		return
+	}
+	c.JSON(http.StatusOK, service.BulkSetXrayClientsEnable(d.Ids, false, GetUserId(c), GetRoleId(c)))
+}
+
+func XrayClientBulkDelete(c *gin.Context) {
+	var d dto.XrayClientBulkDto
+	if err := c.ShouldBindJSON(&d); err != nil {
+		c.JSON(http.StatusOK, dto.Err("参数错误"))
+		return
+	}
+	c.JSON(http.StatusOK, service.BulkDeleteXrayClients(d.Ids, GetUserId(c), GetRoleId(c)))
+}
+
+func XrayClientBulkResetTraffic(c *gin.Context) {
+	var d dto.XrayClientBulkDto
+	if err := c.ShouldBindJSON(&d); err != nil {
+		c.JSON(http.StatusOK, dto.Err("参数错误"))
+		return
+	}
+	c.JSON(http.StatusOK, service.BulkResetXrayClientTraffic(d.Ids, GetUserId(c), GetRoleId(c)))
+}
+
+func XrayClientBulkMove(c *gin.Context) {
+	var d dto.XrayClientBulkMoveDto
+	if err := c.ShouldBindJSON(&d); err != nil {
+		c.JSON(http.StatusOK, dto.Err("参数错误"))
+		return
+	}
+	c.JSON(http.StatusOK, service.BulkMoveXrayClients(d, GetUserId(c), GetRoleId(c)))
+}
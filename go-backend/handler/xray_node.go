@@ -131,3 +131,65 @@ func XrayNodeSwitchVersion(c *gin.Context) {
 	result := pkg.XraySwitchVersion(d.NodeId, d.Version)
 	c.JSON(http.StatusOK, dto.Ok(result))
 }
+
+func SingboxNodeStart(c *gin.Context) {
+	var d struct {
+		NodeId int64 `json:"nodeId" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&d); err != nil {
+		c.JSON(http.StatusOK, dto.Err("参数错误"))
+		return
+	}
+	result := pkg.SingboxStart(d.NodeId)
+	c.JSON(http.StatusOK, dto.Ok(result))
+}
+
+func SingboxNodeStop(c *gin.Context) {
+	var d struct {
+		NodeId int64 `json:"nodeId" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&d); err != nil {
+		c.JSON(http.StatusOK, dto.Err("参数错误"))
+		return
+	}
+	result := pkg.SingboxStop(d.NodeId)
+	c.JSON(http.StatusOK, dto.Ok(result))
+}
+
+func SingboxNodeRestart(c *gin.Context) {
+	var d struct {
+		NodeId int64 `json:"nodeId" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&d); err != nil {
+		c.JSON(http.StatusOK, dto.Err("参数错误"))
+		return
+	}
+	result := pkg.SingboxRestart(d.NodeId)
+	c.JSON(http.StatusOK, dto.Ok(result))
+}
+
+func SingboxNodeStatus(c *gin.Context) {
+	var d struct {
+		NodeId int64 `json:"nodeId" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&d); err != nil {
+		c.JSON(http.StatusOK, dto.Err("参数错误"))
+		return
+	}
+	result := pkg.SingboxStatus(d.NodeId)
+	c.JSON(http.StatusOK, dto.Ok(result))
+}
+
+func SingboxNodeSwitchVersion(c *gin.Context) {
+	var d struct {
+		NodeId int64  `json:"nodeId" binding:"required"`
+		Url    string `json:"url" binding:"required"`
+		Sha256 string `json:"sha256" binding:"required,len=64,hexadecimal"`
+	}
+	if err := c.ShouldBindJSON(&d); err != nil {
+		c.JSON(http.StatusOK, dto.Err("参数错误"))
+		return
+	}
+	result := pkg.SingboxSwitchVersion(d.NodeId, d.Url, d.Sha256)
+	c.JSON(http.StatusOK, dto.Ok(result))
+}
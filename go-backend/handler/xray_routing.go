@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"flux-panel/go-backend/dto"
+	"flux-panel/go-backend/service"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type routingNodeRequest struct {
+	NodeId int64 `json:"nodeId" binding:"required"`
+}
+
+func XrayOutboundList(c *gin.Context) {
+	var d routingNodeRequest
+	if err := c.ShouldBindJSON(&d); err != nil {
+		c.JSON(http.StatusOK, dto.Err("参数错误"))
+		return
+	}
+	c.JSON(http.StatusOK, service.ListXrayOutbounds(d.NodeId))
+}
+
+func XrayOutboundCreate(c *gin.Context) {
+	var d dto.XrayOutboundDto
+	if err := c.ShouldBindJSON(&d); err != nil {
+		c.JSON(http.StatusOK, dto.Err("参数错误"))
+		return
+	}
+	c.JSON(http.StatusOK, service.CreateXrayOutbound(d))
+}
+
+func XrayOutboundUpdate(c *gin.Context) {
+	var d dto.XrayOutboundUpdateDto
+	if err := c.ShouldBindJSON(&d); err != nil {
+		c.JSON(http.StatusOK, dto.Err("参数错误"))
+		return
+	}
+	c.JSON(http.StatusOK, service.UpdateXrayOutbound(d))
+}
+
+func XrayOutboundDelete(c *gin.Context) {
+	var d struct {
+		ID int64 `json:"id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&d); err != nil {
+		c.JSON(http.StatusOK, dto.Err("参数错误"))
+		return
+	}
+	c.JSON(http.StatusOK, service.DeleteXrayOutbound(d.ID))
+}
+
+func XrayRoutingRuleList(c *gin.Context) {
+	var d routingNodeRequest
+	if err := c.ShouldBindJSON(&d); err != nil {
+		c.JSON(http.StatusOK, dto.Err("参数错误"))
+		return
+	}
+	c.JSON(http.StatusOK, service.ListXrayRoutingRules(d.NodeId))
+}
+
+func XrayRoutingRuleCreate(c *gin.Context) {
+	var d dto.XrayRoutingRuleDto
+	if err := c.ShouldBindJSON(&d); err != nil {
+		c.JSON(http.StatusOK, dto.Err("参数错误"))
+		return
+	}
+	c.JSON(http.StatusOK, service.CreateXrayRoutingRule(d))
+}
+
+func XrayRoutingRuleUpdate(c *gin.Context) {
+	var d dto.XrayRoutingRuleUpdateDto
+	if err := c.ShouldBindJSON(&d); err != nil {
+		c.JSON(http.StatusOK, dto.Err("参数错误"))
+		return
+	}
+	c.JSON(http.StatusOK, service.UpdateXrayRoutingRule(d))
+}
+
+func XrayRoutingRuleDelete(c *gin.Context) {
+	var d struct {
+		ID int64 `json:"id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&d); err != nil {
+		c.JSON(http.StatusOK, dto.Err("参数错误"))
+		return
+	}
+	c.JSON(http.StatusOK, service.DeleteXrayRoutingRule(d.ID))
+}
+
+func XrayRoutingPreview(c *gin.Context) {
+	var d routingNodeRequest
+	if err := c.ShouldBindJSON(&d); err != nil {
+		c.JSON(http.StatusOK, dto.Err("参数错误"))
+		return
+	}
+	c.JSON(http.StatusOK, service.PreviewXrayRouting(d.NodeId))
+}
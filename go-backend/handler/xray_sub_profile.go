@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"flux-panel/go-backend/dto"
+	"flux-panel/go-backend/service"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func SubProfileList(c *gin.Context) {
+	c.JSON(http.StatusOK, service.ListSubscriptionProfiles())
+}
+
+func SubProfileCreate(c *gin.Context) {
+	var d dto.SubscriptionProfileDto
+	if err := c.ShouldBindJSON(&d); err != nil {
+		c.JSON(http.StatusOK, dto.Err("参数错误"))
+		return
+	}
+	c.JSON(http.StatusOK, service.CreateSubscriptionProfile(d))
+}
+
+func SubProfileUpdate(c *gin.Context) {
+	var d dto.SubscriptionProfileUpdateDto
+	if err := c.ShouldBindJSON(&d); err != nil {
+		c.JSON(http.StatusOK, dto.Err("参数错误"))
+		return
+	}
+	c.JSON(http.StatusOK, service.UpdateSubscriptionProfile(d))
+}
+
+func SubProfileDelete(c *gin.Context) {
+	var d struct {
+		ID int64 `json:"id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&d); err != nil {
+		c.JSON(http.StatusOK, dto.Err("参数错误"))
+		return
+	}
+	c.JSON(http.StatusOK, service.DeleteSubscriptionProfile(d.ID))
+}
+
+func SubTokenList(c *gin.Context) {
+	c.JSON(http.StatusOK, service.ListSubscriptionTokens(GetUserId(c)))
+}
+
+func SubTokenCreate(c *gin.Context) {
+	var d dto.SubscriptionTokenDto
+	if err := c.ShouldBindJSON(&d); err != nil {
+		c.JSON(http.StatusOK, dto.Err("参数错误"))
+		return
+	}
+	c.JSON(http.StatusOK, service.CreateSubscriptionToken(GetUserId(c), GetRoleId(c), d))
+}
+
+func SubTokenUpdate(c *gin.Context) {
+	var d dto.SubscriptionTokenUpdateDto
+	if err := c.ShouldBindJSON(&d); err != nil {
+		c.JSON(http.StatusOK, dto.Err("参数错误"))
+		return
+	}
+	c.JSON(http.StatusOK, service.UpdateSubscriptionToken(GetUserId(c), d))
+}
+
+func SubTokenReset(c *gin.Context) {
+	var d struct {
+		ID int64 `json:"id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&d); err != nil {
+		c.JSON(http.StatusOK, dto.Err("参数错误"))
+		return
+	}
+	c.JSON(http.StatusOK, service.ResetSubscriptionToken(GetUserId(c), d.ID))
+}
+
+func SubTokenDelete(c *gin.Context) {
+	var d struct {
+		ID int64 `json:"id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&d); err != nil {
+		c.JSON(http.StatusOK, dto.Err("参数错误"))
+		return
+	}
+	c.JSON(http.StatusOK, service.DeleteSubscriptionToken(GetUserId(c), d.ID))
+}
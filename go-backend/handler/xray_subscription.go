@@ -29,34 +29,13 @@ func XraySubscription(c *gin.Context) {
 		return
 	}
 
-	var user model.User
-	if err := service.DB.Where("sub_token = ?", token).First(&user).Error; err != nil {
+	user, profile, ok := service.ResolveSubscriptionToken(token)
+	if !ok {
 		c.String(http.StatusUnauthorized, "invalid or expired token")
 		return
 	}
 
-	result := service.GetSubscriptionLinks(user.ID)
-	if result.Code != 0 {
-		c.String(http.StatusInternalServerError, result.Msg)
-		return
-	}
-
-	links, ok := result.Data.([]map[string]interface{})
-	if !ok || len(links) == 0 {
-		c.String(http.StatusOK, "")
-		return
-	}
-
-	var linkStrs []string
-	for _, item := range links {
-		if link, ok := item["link"].(string); ok {
-			linkStrs = append(linkStrs, link)
-		}
-	}
-
-	encoded := base64.StdEncoding.EncodeToString([]byte(strings.Join(linkStrs, "\n")))
-	c.Header("Content-Type", "text/plain; charset=utf-8")
-	c.String(http.StatusOK, encoded)
+	writeSubscription(c, user, profile)
 }
 
 func XraySubToken(c *gin.Context) {
@@ -128,7 +107,7 @@ func XraySubReset(c *gin.Context) {
 
 func XraySubLinks(c *gin.Context) {
 	userId := GetUserId(c)
-	c.JSON(http.StatusOK, service.GetSubscriptionLinks(userId))
+	c.JSON(http.StatusOK, service.GetSubscriptionLinks(userId, nil))
 }
 
 func GetSubStore(c *gin.Context) {
@@ -138,32 +117,88 @@ func GetSubStore(c *gin.Context) {
 		return
 	}
 
-	var user model.User
-	if err := service.DB.Where("sub_token = ?", token).First(&user).Error; err != nil {
+	user, profile, ok := service.ResolveSubscriptionToken(token)
+	if !ok {
 		c.String(http.StatusUnauthorized, "invalid token")
 		return
 	}
 
-	result := service.GetSubscriptionLinks(user.ID)
-	if result.Code != 0 {
-		c.String(http.StatusInternalServerError, result.Msg)
+	writeSubscription(c, user, profile)
+}
+
+// Subscription output formats
+const (
+	subFormatBase64  = "base64"
+	subFormatClash   = "clash"
+	subFormatSingbox = "singbox"
+)
+
+// subscriptionFormat picks the output format from ?format=, falling back to
+// the client's User-Agent, then to base64 share links.
+func subscriptionFormat(c *gin.Context) string {
+	switch strings.ToLower(c.Query("format")) {
+	case "clash", "mihomo", "meta":
+		return subFormatClash
+	case "singbox", "sing-box":
+		return subFormatSingbox
+	case "base64", "v2ray", "links":
+		return subFormatBase64
+	}
+
+	ua := strings.ToLower(c.GetHeader("User-Agent"))
+	if strings.Contains(ua, "sing-box") || strings.Contains(ua, "singbox") {
+		return subFormatSingbox
+	}
+	for _, k := range []string{"clash", "mihomo", "stash"} {
+		if strings.Contains(ua, k) {
+			return subFormatClash
+		}
+	}
+	return subFormatBase64
+}
+
+func writeSubscription(c *gin.Context, user *model.User, profile *model.SubscriptionProfile) {
+	for k, v := range service.GetSubscriptionHeaders(user) {
+		c.Header(k, v)
+	}
+
+	switch subscriptionFormat(c) {
+	case subFormatClash:
+		writeRenderedSubscription(c, service.GetClashSubscription(user, profile), "text/yaml; charset=utf-8")
+		return
+	case subFormatSingbox:
+		writeRenderedSubscription(c, service.GetSingboxSubscription(user, profile), "application/json; charset=utf-8")
 		return
 	}
 
-	links, ok := result.Data.([]map[string]interface{})
-	if !ok || len(links) == 0 {
-		c.String(http.StatusOK, "")
+	result := service.GetSubscriptionLinks(user.ID, profile)
+	if result.Code != 0 {
+		c.String(http.StatusInternalServerError, result.Msg)
 		return
 	}
 
-	var linkStrs []string
+	links, _ := result.Data.([]map[string]interface{})
+	linkStrs := service.GetSubscriptionInfoLinks(user)
 	for _, item := range links {
 		if link, ok := item["link"].(string); ok {
 			linkStrs = append(linkStrs, link)
 		}
 	}
+	if len(linkStrs) == 0 {
+		c.String(http.StatusOK, "")
+		return
+	}
 
 	encoded := base64.StdEncoding.EncodeToString([]byte(strings.Join(linkStrs, "\n")))
 	c.Header("Content-Type", "text/plain; charset=utf-8")
 	c.String(http.StatusOK, encoded)
 }
+
+func writeRenderedSubscription(c *gin.Context, result dto.R, contentType string) {
+	if result.Code != 0 {
+		c.String(http.StatusInternalServerError, result.Msg)
+		return
+	}
+	c.Header("Content-Type", contentType)
+	c.String(http.StatusOK, result.Data.(string))
+}
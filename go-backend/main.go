@@ -1,11 +1,17 @@
 package main
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/hex"
 	"fmt"
 	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
+	_ "time/tzdata" // the runtime image ships without a zoneinfo database
 
 	"flux-panel/go-backend/config"
 	"flux-panel/go-backend/model"
@@ -20,6 +26,9 @@ import (
 	"gorm.io/gorm/logger"
 )
 
+// shutdownTimeout bounds the whole graceful shutdown sequence.
+const shutdownTimeout = 30 * time.Second
+
 func main() {
 	// Load config
 	config.Load()
@@ -59,6 +68,24 @@ func main() {
 		&model.StatisticsXrayFlow{},
 		&model.MonitorLatency{},
 		&model.StatisticsUserFlow{},
+		&model.NodeFlowSeq{},
+		&model.NodeFlowEpoch{},
+		&model.BackendInstance{},
+		&model.NodeSession{},
+		&model.ClusterLease{},
+		&model.NodeRequestNonce{},
+		&model.ScheduledJob{},
+		&model.ScheduledJobRun{},
+		&model.XrayClientIp{},
+		&model.SubscriptionProfile{},
+		&model.SubscriptionToken{},
+		&model.XrayOutbound{},
+		&model.XrayRoutingRule{},
+		&model.NodeRollout{},
+		&model.NodeRolloutItem{},
+		&model.NodeJoinToken{},
+		&model.NodeMetric{},
+		&model.NodeLinkMetric{},
 	)
 
 	// Drop legacy unique constraints that are no longer needed
@@ -77,9 +104,14 @@ func main() {
 
 	// Set global DB
 	service.DB = db
+	service.BackfillNodeKeyIds()
 
 	// ── Security startup checks ──
 	if config.Cfg.JWTSecret == "" {
+		if config.Cfg.ClusterAddr != "" {
+			// Tokens issued by one replica must validate on all of them
+			log.Fatal("多实例模式 (CLUSTER_ADVERTISE_ADDR) 需要设置 JWT_SECRET")
+		}
 		config.Cfg.JWTSecret = generateRandomPassword(64)
 		log.Println("========================================")
 		log.Println("WARNING ⚠️  JWT_SECRET 未设置，已自动生成随机密钥")
@@ -87,6 +119,16 @@ func main() {
 		log.Println("WARNING ⚠️  请设置 JWT_SECRET 环境变量以持久化密钥")
 		log.Println("========================================")
 	}
+	if config.Cfg.ClusterAddr != "" {
+		// Replicas send this secret to each other on every call, so it must
+		// never be the key that signs admin tokens
+		if config.Cfg.ClusterSecret == "" {
+			log.Fatal("多实例模式 (CLUSTER_ADVERTISE_ADDR) 需要设置 CLUSTER_SECRET")
+		}
+		if config.Cfg.ClusterSecret == config.Cfg.JWTSecret {
+			log.Fatal("CLUSTER_SECRET 不能与 JWT_SECRET 相同")
+		}
+	}
 
 	ensureAdminUser(db)
 
@@ -98,23 +140,16 @@ func main() {
 	// If nodeId > 0, validates that specific node; if 0, looks up by secret.
 	// Returns the resolved nodeId (0 = rejected).
 	pkg.WS.ValidateNodeSecret = func(nodeId int64, secret string) int64 {
-		var node model.Node
-		if nodeId > 0 {
-			if err := db.First(&node, nodeId).Error; err != nil {
-				return 0
-			}
-			if node.Secret != secret {
-				return 0
-			}
-			return node.ID
-		}
-		// No id provided — look up by secret
-		if err := db.Where("secret = ?", secret).First(&node).Error; err != nil {
+		node := service.FindNodeBySecret(secret)
+		if node == nil || node.Pending || (nodeId > 0 && node.ID != nodeId) {
 			return 0
 		}
 		return node.ID
 	}
 
+	// LookupNodeKey resolves the key id a node presents in the handshake.
+	pkg.WS.LookupNodeKey = service.LookupNodeKey
+
 	pkg.WS.OnNodeOnline = func(nodeId int64, version, http, tls, socks string) {
 		updates := map[string]interface{}{
 			"status": 1,
@@ -146,23 +181,79 @@ func main() {
 		log.Printf("Node %d offline", nodeId)
 	}
 
-	// Start scheduled tasks
-	task.StartResetFlowTask(db)
-	task.StartStatisticsTask()
-	task.StartLatencyMonitor()
-	service.StartXrayScheduler()
+	// Downsampled metrics history of the nodes connected to this replica
+	pkg.WS.OnNodeMetrics = service.RecordNodeMetricsSample
+	service.StartNodeMetricsFlusher()
+
+	// Join the replica cluster before tasks start so only the leader runs them
+	if config.Cfg.ClusterAddr != "" {
+		pkg.InitCluster(db, config.Cfg.ClusterAddr, config.Cfg.ClusterSecret)
+		pkg.Cluster.OnNodeLost = pkg.WS.OnNodeOffline
+	}
+
+	// Register scheduled jobs and start the scheduler
+	task.RegisterResetFlowJob(db)
+	task.RegisterStatisticsJob()
+	task.RegisterLatencyMonitorJob()
+	service.RegisterXrayJobs()
+	service.RegisterNodeMatrixJob()
+	service.StartScheduler()
 
 	// Setup Gin
 	gin.SetMode(gin.ReleaseMode)
 	r := gin.New()
 	r.Use(gin.Recovery())
+	// X-Forwarded-For is only believed from these; anyone else could set it
+	// to dodge rate limits or fake the address a node enrolls from
+	if err := r.SetTrustedProxies(config.Cfg.TrustedProxies); err != nil {
+		log.Fatalf("TRUSTED_PROXIES 无效: %v", err)
+	}
 
 	router.Setup(r)
 
-	addr := fmt.Sprintf(":%d", config.Cfg.Port)
-	log.Printf("Server starting on %s", addr)
-	if err := r.Run(addr); err != nil {
+	srv := &http.Server{
+		Addr:    fmt.Sprintf(":%d", config.Cfg.Port),
+		Handler: r,
+	}
+	serveErr := make(chan error, 1)
+	go func() {
+		log.Printf("Server starting on %s", srv.Addr)
+		serveErr <- srv.ListenAndServe()
+	}()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	select {
+	case err := <-serveErr:
 		log.Fatalf("Failed to start server: %v", err)
+	case <-ctx.Done():
+	}
+	stop()
+
+	log.Println("收到退出信号，开始优雅关闭...")
+	shutdown(db, srv)
+	log.Println("服务已停止")
+}
+
+// shutdown stops the backend in dependency order: HTTP handlers (including
+// flow uploads mid-transaction) finish first, then background tasks, then
+// node and admin WebSockets, and finally the DB pool they all share.
+func shutdown(db *gorm.DB, srv *http.Server) {
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := srv.Shutdown(ctx); err != nil {
+		log.Printf("HTTP 服务关闭超时: %v", err)
+	}
+	if !pkg.Tasks.Stop(ctx) {
+		log.Println("后台任务退出超时")
+	}
+	pkg.WS.Shutdown(ctx)
+	if pkg.Cluster != nil {
+		pkg.Cluster.Shutdown()
+	}
+	if sqlDB, err := db.DB(); err == nil {
+		sqlDB.Close()
 	}
 }
 
@@ -242,10 +333,12 @@ func ensureDefaultConfig(db *gorm.DB) {
 		log.Println("默认配置已初始化 (app_name=flux)")
 	}
 
-	// Ensure monitor config defaults exist
+	// Ensure monitor and IP limit config defaults exist
 	monitorDefaults := map[string]string{
 		"monitor_interval":       "60",
 		"monitor_retention_days": "7",
+		"ip_limit_window":        "180",
+		"ip_limit_ban_duration":  "600",
 	}
 	for name, defaultVal := range monitorDefaults {
 		var c int64
@@ -264,6 +357,11 @@ func ensureDefaultConfig(db *gorm.DB) {
 		"app_name", "site_name", "site_desc",
 		"panel_addr", "captcha_enabled",
 		"monitor_interval", "monitor_retention_days",
+		"timezone",
+		"ip_limit_window", "ip_limit_ban_duration",
+		"node_matrix_cross_group", "node_matrix_max_pairs",
+		"singbox_dns_template", "singbox_route_template",
+		"sub_update_interval", "sub_profile_name", "sub_info_nodes",
 	}
 	result := db.Where("name NOT IN ?", knownKeys).Delete(&model.ViteConfig{})
 	if result.RowsAffected > 0 {
@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"flux-panel/go-backend/pkg"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ClusterAuth guards the replica-to-replica endpoints. They are disabled
+// entirely when clustering is not configured.
+func ClusterAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if pkg.Cluster == nil || pkg.Cluster.Secret == "" {
+			c.AbortWithStatus(http.StatusNotFound)
+			return
+		}
+		given := c.GetHeader(pkg.ClusterSecretHeader)
+		if subtle.ConstantTimeCompare([]byte(given), []byte(pkg.Cluster.Secret)) != 1 {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		c.Next()
+	}
+}
@@ -1,11 +1,13 @@
 package middleware
 
 import (
+	"context"
 	"net/http"
 	"sync"
 	"time"
 
 	"flux-panel/go-backend/dto"
+	"flux-panel/go-backend/pkg"
 
 	"github.com/gin-gonic/gin"
 )
@@ -26,6 +28,7 @@ type rateLimiter struct {
 var (
 	loginLimiter = newRateLimiter(10, time.Minute)
 	captchaLimiter = newRateLimiter(20, time.Minute)
+	joinLimiter    = newRateLimiter(30, time.Minute)
 
 	cleanupOnce sync.Once
 )
@@ -41,13 +44,13 @@ func newRateLimiter(limit int, window time.Duration) *rateLimiter {
 // startCleanup launches a single background goroutine for all limiters.
 func startCleanup() {
 	cleanupOnce.Do(func() {
-		go func() {
-			for {
-				time.Sleep(5 * time.Minute)
+		pkg.Tasks.Go("rate-limit-cleanup", func(ctx context.Context) {
+			for pkg.Sleep(ctx, 5*time.Minute) {
 				loginLimiter.cleanup()
 				captchaLimiter.cleanup()
+				joinLimiter.cleanup()
 			}
-		}()
+		})
 	})
 }
 
@@ -63,6 +66,12 @@ func CaptchaRateLimit() gin.HandlerFunc {
 	return rateLimitMiddleware(captchaLimiter)
 }
 
+// JoinRateLimit returns a middleware that limits node enrollment requests per IP.
+func JoinRateLimit() gin.HandlerFunc {
+	startCleanup()
+	return rateLimitMiddleware(joinLimiter)
+}
+
 func rateLimitMiddleware(rl *rateLimiter) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		ip := c.ClientIP()
@@ -0,0 +1,50 @@
+package model
+
+// BackendInstance is a running backend replica. Replicas refresh
+// HeartbeatTime periodically; rows with a stale heartbeat are considered dead.
+type BackendInstance struct {
+	ID            string `gorm:"column:id;type:varchar(64);primaryKey" json:"id"`
+	Addr          string `gorm:"column:addr" json:"addr"`
+	StartedTime   int64  `gorm:"column:started_time" json:"startedTime"`
+	HeartbeatTime int64  `gorm:"column:heartbeat_time;index" json:"heartbeatTime"`
+}
+
+func (BackendInstance) TableName() string {
+	return "backend_instance"
+}
+
+// NodeSession records which backend replica holds a node's WebSocket.
+type NodeSession struct {
+	NodeId        int64  `gorm:"column:node_id;primaryKey;autoIncrement:false" json:"nodeId"`
+	InstanceId    string `gorm:"column:instance_id;type:varchar(64);index" json:"instanceId"`
+	ConnectedTime int64  `gorm:"column:connected_time" json:"connectedTime"`
+	HeartbeatTime int64  `gorm:"column:heartbeat_time" json:"heartbeatTime"`
+}
+
+func (NodeSession) TableName() string {
+	return "node_session"
+}
+
+// ClusterLease is a named, expiring lock used for leader election.
+type ClusterLease struct {
+	Name       string `gorm:"column:name;type:varchar(64);primaryKey" json:"name"`
+	Holder     string `gorm:"column:holder;type:varchar(64)" json:"holder"`
+	ExpireTime int64  `gorm:"column:expire_time" json:"expireTime"`
+}
+
+func (ClusterLease) TableName() string {
+	return "cluster_lease"
+}
+
+// NodeRequestNonce is a nonce of a signed node report, shared by all
+// replicas so a report can't be replayed to another one. Rows older than
+// the clock window are deleted by the leader.
+type NodeRequestNonce struct {
+	Kid   string `gorm:"column:kid;type:varchar(32);primaryKey" json:"kid"`
+	Nonce string `gorm:"column:nonce;type:varchar(64);primaryKey" json:"nonce"`
+	Ts    int64  `gorm:"column:ts;index" json:"ts"`
+}
+
+func (NodeRequestNonce) TableName() string {
+	return "node_request_nonce"
+}
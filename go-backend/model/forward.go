@@ -18,6 +18,12 @@ type Forward struct {
 	UpdatedTime   int64  `gorm:"column:updated_time" json:"updatedTime"`
 	Status        int    `gorm:"column:status" json:"status"`
 	Inx           int    `gorm:"column:inx" json:"inx"`
+
+	// UdpProbe marks a UDP-primary forward (game, DNS...) by naming the
+	// payload used to check its targets: echo, dns, quic or hex. Empty means
+	// the targets are checked with a TCP ping.
+	UdpProbe     string `gorm:"column:udp_probe;type:varchar(16);default:''" json:"udpProbe"`
+	UdpProbeData string `gorm:"column:udp_probe_data;type:varchar(2048);default:''" json:"udpProbeData"` // hex payload, or the DNS query name
 }
 
 func (Forward) TableName() string {
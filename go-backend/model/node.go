@@ -21,8 +21,21 @@ type Node struct {
 	Status      int    `gorm:"column:status" json:"status"`
 	Inx              int    `gorm:"column:inx" json:"inx"`
 	GroupName        string `gorm:"column:group_name" json:"groupName"`
+	Region           string `gorm:"column:region;size:32" json:"region"` // e.g. "HK"; used by subscription profiles
 	DisguiseName     string `gorm:"column:disguise_name" json:"disguiseName"`
 	XrayDisguiseName string `gorm:"column:xray_disguise_name" json:"vDisguiseName"`
+	// Secret replaced by the last rotation, still accepted until
+	// PrevSecretExpires (ms)
+	PrevSecret        string `gorm:"column:prev_secret" json:"-"`
+	PrevSecretExpires int64  `gorm:"column:prev_secret_expires" json:"-"`
+	// Set on nodes enrolled with a join token
+	JoinTokenId int64  `gorm:"column:join_token_id" json:"joinTokenId"`
+	MachineId   string `gorm:"column:machine_id;size:64;index" json:"-"`    // hash of /etc/machine-id
+	Pending     bool   `gorm:"column:pending;default:false" json:"pending"` // awaiting admin approval; can't connect
+	// Key ids (pkg.NodeKeyId) of Secret and PrevSecret, so a handshake finds
+	// its node by index instead of hashing every secret
+	SecretKid     string `gorm:"column:secret_kid;size:32;index" json:"-"`
+	PrevSecretKid string `gorm:"column:prev_secret_kid;size:32;index" json:"-"`
 }
 
 func (Node) TableName() string {
@@ -0,0 +1,28 @@
+package model
+
+// NodeFlowSeq tracks the highest flow report sequence applied per node.
+// Nodes number their reports per spool epoch; a report whose seq is not
+// above LastSeq for the same epoch is a retransmission and is skipped.
+type NodeFlowSeq struct {
+	NodeId      int64  `gorm:"column:node_id;primaryKey;autoIncrement:false" json:"nodeId"`
+	Epoch       string `gorm:"column:epoch;type:varchar(32)" json:"epoch"`
+	LastSeq     int64  `gorm:"column:last_seq" json:"lastSeq"`
+	UpdatedTime int64  `gorm:"column:updated_time" json:"updatedTime"`
+}
+
+func (NodeFlowSeq) TableName() string {
+	return "node_flow_seq"
+}
+
+// NodeFlowEpoch records each spool epoch a node has reported under and when
+// the panel first saw it. Once a node moves to a new epoch, reports from an
+// earlier one are stale retransmissions and are dropped.
+type NodeFlowEpoch struct {
+	NodeId    int64  `gorm:"column:node_id;primaryKey;autoIncrement:false" json:"nodeId"`
+	Epoch     string `gorm:"column:epoch;primaryKey;type:varchar(32)" json:"epoch"`
+	FirstSeen int64  `gorm:"column:first_seen" json:"firstSeen"`
+}
+
+func (NodeFlowEpoch) TableName() string {
+	return "node_flow_epoch"
+}
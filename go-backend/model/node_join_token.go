@@ -0,0 +1,26 @@
+package model
+
+// NodeJoinToken lets fresh machines enroll themselves: an agent that
+// presents the token gets a node created in the token's group, with the
+// token's port range, and its own secret.
+type NodeJoinToken struct {
+	ID              int64  `gorm:"primaryKey;autoIncrement" json:"id"`
+	Name            string `gorm:"column:name;size:100" json:"name"`
+	Token           string `gorm:"column:token;size:64;uniqueIndex" json:"token"`
+	GroupName       string `gorm:"column:group_name;size:100" json:"groupName"`
+	Region          string `gorm:"column:region;size:32" json:"region"`
+	PortSta         int    `gorm:"column:port_sta" json:"portSta"`
+	PortEnd         int    `gorm:"column:port_end" json:"portEnd"`
+	MaxUses         int    `gorm:"column:max_uses" json:"maxUses"` // 0 = unlimited, 1 = one-shot
+	UsedCount       int    `gorm:"column:used_count" json:"usedCount"`
+	RequireApproval bool   `gorm:"column:require_approval" json:"requireApproval"`
+	Enabled         bool   `gorm:"column:enabled;default:true" json:"enabled"`
+	ExpireTime      int64  `gorm:"column:expire_time" json:"expireTime"` // 0 = never
+	LastUsedTime    int64  `gorm:"column:last_used_time" json:"lastUsedTime"`
+	CreatedTime     int64  `gorm:"column:created_time" json:"createdTime"`
+	UpdatedTime     int64  `gorm:"column:updated_time" json:"updatedTime"`
+}
+
+func (NodeJoinToken) TableName() string {
+	return "node_join_token"
+}
@@ -0,0 +1,20 @@
+package model
+
+// NodeLinkMetric is one bandwidth test from a source node to a target node.
+type NodeLinkMetric struct {
+	ID         int64   `gorm:"primaryKey;autoIncrement" json:"id"`
+	SrcNodeId  int64   `gorm:"column:src_node_id;index:idx_node_link_pair" json:"srcNodeId"`
+	DstNodeId  int64   `gorm:"column:dst_node_id;index:idx_node_link_pair" json:"dstNodeId"`
+	Success    bool    `gorm:"column:success" json:"success"`
+	Error      string  `gorm:"column:error;size:255" json:"error"`
+	Latency    float64 `gorm:"column:latency" json:"latency"` // ms, average round trip
+	Jitter     float64 `gorm:"column:jitter" json:"jitter"`   // ms
+	TcpMbps    float64 `gorm:"column:tcp_mbps" json:"tcpMbps"`
+	UdpMbps    float64 `gorm:"column:udp_mbps" json:"udpMbps"`
+	Loss       float64 `gorm:"column:loss" json:"loss"` // UDP, percent
+	RecordTime int64   `gorm:"column:record_time;index" json:"recordTime"`
+}
+
+func (NodeLinkMetric) TableName() string {
+	return "node_link_metric"
+}
@@ -0,0 +1,27 @@
+package model
+
+// NodeMetric is one downsampled bucket of a node's reported system info.
+// Only written while the node is connected.
+type NodeMetric struct {
+	ID          int64   `gorm:"primaryKey;autoIncrement" json:"id"`
+	NodeId      int64   `gorm:"column:node_id;index:idx_node_metric_node_time" json:"nodeId"`
+	RecordTime  int64   `gorm:"column:record_time;index:idx_node_metric_node_time;index" json:"recordTime"` // bucket start, unix seconds
+	Samples     int     `gorm:"column:samples" json:"samples"`
+	CpuAvg      float64 `gorm:"column:cpu_avg" json:"cpuAvg"`
+	CpuMax      float64 `gorm:"column:cpu_max" json:"cpuMax"`
+	MemAvg      float64 `gorm:"column:mem_avg" json:"memAvg"`
+	MemMax      float64 `gorm:"column:mem_max" json:"memMax"`
+	RxRate      int64   `gorm:"column:rx_rate" json:"rxRate"` // bytes/s
+	TxRate      int64   `gorm:"column:tx_rate" json:"txRate"` // bytes/s
+	DiskUsage   float64 `gorm:"column:disk_usage" json:"diskUsage"`
+	Load1       float64 `gorm:"column:load1" json:"load1"`
+	Load5       float64 `gorm:"column:load5" json:"load5"`
+	Load15      float64 `gorm:"column:load15" json:"load15"`
+	TcpConns    int     `gorm:"column:tcp_conns" json:"tcpConns"`        // established, average
+	TcpConnsMax int     `gorm:"column:tcp_conns_max" json:"tcpConnsMax"` // established, peak
+	XrayRunning bool    `gorm:"column:xray_running" json:"vRunning"`     // at the end of the bucket
+}
+
+func (NodeMetric) TableName() string {
+	return "node_metric"
+}
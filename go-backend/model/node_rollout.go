@@ -0,0 +1,40 @@
+package model
+
+// NodeRollout is a staged node binary update: a canary batch first, then
+// the remaining nodes batch by batch, each batch waiting for its nodes to
+// reconnect on the target version before the next one starts.
+type NodeRollout struct {
+	ID            int64  `gorm:"primaryKey;autoIncrement" json:"id"`
+	TargetVersion string `gorm:"column:target_version;size:64" json:"targetVersion"`
+	GroupName     string `gorm:"column:group_name;size:100" json:"groupName"` // empty = all nodes
+	Percent       int    `gorm:"column:percent" json:"percent"`               // share of the selected nodes to update
+	CanaryCount   int    `gorm:"column:canary_count" json:"canaryCount"`
+	BatchSize     int    `gorm:"column:batch_size" json:"batchSize"`
+	HealthTimeout int    `gorm:"column:health_timeout" json:"healthTimeout"` // seconds to wait for a reconnect
+	Status        string `gorm:"column:status;size:16;index" json:"status"`  // running, completed, failed, cancelled, rolled_back
+	Msg           string `gorm:"column:msg;type:text" json:"msg"`
+	CreatedTime   int64  `gorm:"column:created_time" json:"createdTime"`
+	UpdatedTime   int64  `gorm:"column:updated_time" json:"updatedTime"` // also the runner's heartbeat
+	FinishedTime  int64  `gorm:"column:finished_time" json:"finishedTime"`
+}
+
+func (NodeRollout) TableName() string {
+	return "node_rollout"
+}
+
+// NodeRolloutItem is one node of a rollout.
+type NodeRolloutItem struct {
+	ID           int64  `gorm:"primaryKey;autoIncrement" json:"id"`
+	RolloutId    int64  `gorm:"column:rollout_id;index" json:"rolloutId"`
+	NodeId       int64  `gorm:"column:node_id" json:"nodeId"`
+	Batch        int    `gorm:"column:batch" json:"batch"`           // 0 = canary
+	Status       string `gorm:"column:status;size:16" json:"status"` // pending, updating, success, failed, skipped, rolled_back
+	FromVersion  string `gorm:"column:from_version;size:64" json:"fromVersion"`
+	Msg          string `gorm:"column:msg;type:text" json:"msg"`
+	StartedTime  int64  `gorm:"column:started_time" json:"startedTime"`
+	FinishedTime int64  `gorm:"column:finished_time" json:"finishedTime"`
+}
+
+func (NodeRolloutItem) TableName() string {
+	return "node_rollout_item"
+}
@@ -0,0 +1,37 @@
+package model
+
+// ScheduledJob holds the persisted settings and last-run state of a
+// scheduler job. Rows are created on first start with the job's default
+// schedule and are then owned by the admin.
+type ScheduledJob struct {
+	Name         string `gorm:"column:name;primaryKey;size:64" json:"name"`
+	Spec         string `gorm:"column:spec;size:128" json:"spec"`
+	Enabled      bool   `gorm:"column:enabled;default:true" json:"enabled"`
+	NextRunTime  int64  `gorm:"column:next_run_time" json:"nextRunTime"`
+	LastRunTime  int64  `gorm:"column:last_run_time" json:"lastRunTime"`
+	LastDuration int64  `gorm:"column:last_duration" json:"lastDuration"` // ms
+	LastStatus   int    `gorm:"column:last_status" json:"lastStatus"`     // 0=never run, 1=ok, 2=failed
+	LastError    string `gorm:"column:last_error;type:text" json:"lastError"`
+	RunRequested bool   `gorm:"column:run_requested" json:"runRequested"`
+	UpdatedTime  int64  `gorm:"column:updated_time" json:"updatedTime"`
+}
+
+func (ScheduledJob) TableName() string {
+	return "scheduled_job"
+}
+
+// ScheduledJobRun is one entry of a job's run history.
+type ScheduledJobRun struct {
+	ID           int64  `gorm:"primaryKey;autoIncrement" json:"id"`
+	JobName      string `gorm:"column:job_name;size:64;index" json:"jobName"`
+	Source       string `gorm:"column:source;size:16" json:"source"` // schedule, catchup, manual
+	ScheduledFor int64  `gorm:"column:scheduled_for" json:"scheduledFor"`
+	StartTime    int64  `gorm:"column:start_time;index" json:"startTime"`
+	Duration     int64  `gorm:"column:duration" json:"duration"` // ms
+	Status       int    `gorm:"column:status" json:"status"`     // 1=ok, 2=failed
+	Error        string `gorm:"column:error;type:text" json:"error"`
+}
+
+func (ScheduledJobRun) TableName() string {
+	return "scheduled_job_run"
+}
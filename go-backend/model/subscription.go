@@ -0,0 +1,35 @@
+package model
+
+// SubscriptionProfile is a named subscription template: which nodes and
+// inbounds to include, in what order, and how entries are named.
+type SubscriptionProfile struct {
+	ID             int64  `gorm:"primaryKey;autoIncrement" json:"id"`
+	Name           string `gorm:"column:name;size:100" json:"name"`
+	NodeIds        string `gorm:"column:node_ids;type:text" json:"nodeIds"`       // comma-separated; empty = all nodes
+	InboundIds     string `gorm:"column:inbound_ids;type:text" json:"inboundIds"` // comma-separated; empty = all inbounds
+	SortBy         string `gorm:"column:sort_by;size:20" json:"sortBy"`           // "", "node", "region", "protocol" or "remark"
+	RemarkTemplate string `gorm:"column:remark_template;size:255" json:"remarkTemplate"`
+	HostOverrides  string `gorm:"column:host_overrides;type:text" json:"hostOverrides"` // JSON object: region -> host
+	CreatedTime    int64  `gorm:"column:created_time" json:"createdTime"`
+	UpdatedTime    int64  `gorm:"column:updated_time" json:"updatedTime"`
+}
+
+func (SubscriptionProfile) TableName() string {
+	return "subscription_profile"
+}
+
+// SubscriptionToken is an additional subscription URL of a user, rendered
+// through one profile. The legacy user.sub_token keeps serving everything.
+type SubscriptionToken struct {
+	ID           int64  `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserId       int64  `gorm:"column:user_id;index" json:"userId"`
+	Token        string `gorm:"column:token;size:64;uniqueIndex" json:"token"`
+	ProfileId    int64  `gorm:"column:profile_id;index" json:"profileId"`
+	Name         string `gorm:"column:name;size:100" json:"name"`
+	LastUsedTime int64  `gorm:"column:last_used_time" json:"lastUsedTime"`
+	CreatedTime  int64  `gorm:"column:created_time" json:"createdTime"`
+}
+
+func (SubscriptionToken) TableName() string {
+	return "subscription_token"
+}
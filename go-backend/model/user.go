@@ -15,10 +15,12 @@ type User struct {
 	FlowResetTime int64  `gorm:"column:flow_reset_time" json:"flowResetTime"`
 	FlowResetType int    `gorm:"column:flow_reset_type" json:"flowResetType"`
 	FlowResetDay  int    `gorm:"column:flow_reset_day" json:"flowResetDay"`
+	ResetPeriod   string `gorm:"column:reset_period;size:16" json:"-"` // local date of the last scheduled reset
 	Num           int    `gorm:"column:num" json:"num"`
 	GostEnabled   int    `gorm:"column:gost_enabled" json:"gostEnabled"`
 	XrayEnabled   int    `gorm:"column:xray_enabled" json:"vEnabled"`
 	SubToken      string `gorm:"column:sub_token" json:"subToken"`
+	Timezone      string `gorm:"column:timezone;size:64" json:"timezone"` // IANA name; empty = panel timezone
 	CreatedTime   int64  `gorm:"column:created_time" json:"createdTime"`
 	UpdatedTime   int64  `gorm:"column:updated_time" json:"updatedTime"`
 	Status        int    `gorm:"column:status" json:"status"`
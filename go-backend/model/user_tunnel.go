@@ -12,6 +12,7 @@ type UserTunnel struct {
 	FlowResetTime int64 `gorm:"column:flow_reset_time" json:"flowResetTime"`
 	FlowResetType int   `gorm:"column:flow_reset_type" json:"flowResetType"`
 	FlowResetDay  int   `gorm:"column:flow_reset_day" json:"flowResetDay"`
+	ResetPeriod   string `gorm:"column:reset_period;size:16" json:"-"` // local date of the last scheduled reset
 	ExpTime       int64 `gorm:"column:exp_time" json:"expTime"`
 	Status        int   `gorm:"column:status" json:"status"`
 }
@@ -13,6 +13,7 @@ type XrayClient struct {
 	DownTraffic    int64  `gorm:"column:down_traffic" json:"downTraffic"`
 	ExpTime        *int64 `gorm:"column:exp_time" json:"expTime"`
 	LimitIp        int    `gorm:"column:limit_ip;default:0" json:"limitIp"`
+	IpBanUntil     int64  `gorm:"column:ip_ban_until;default:0" json:"ipBanUntil"` // ms; removed from nodes until then for exceeding LimitIp
 	Reset          int    `gorm:"column:reset;default:0" json:"reset"`
 	Enable         int    `gorm:"column:enable" json:"enable"`
 	Remark         string `gorm:"column:remark" json:"remark"`
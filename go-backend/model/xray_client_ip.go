@@ -0,0 +1,16 @@
+package model
+
+// XrayClientIp records a source IP seen for an Xray client, as reported by
+// the nodes from Xray's access log.
+type XrayClientIp struct {
+	ID        int64  `gorm:"primaryKey;autoIncrement" json:"id"`
+	ClientId  int64  `gorm:"column:client_id;uniqueIndex:uk_client_ip" json:"clientId"`
+	Ip        string `gorm:"column:ip;size:64;uniqueIndex:uk_client_ip" json:"ip"`
+	NodeId    int64  `gorm:"column:node_id" json:"nodeId"`
+	FirstSeen int64  `gorm:"column:first_seen" json:"firstSeen"`
+	LastSeen  int64  `gorm:"column:last_seen;index" json:"lastSeen"`
+}
+
+func (XrayClientIp) TableName() string {
+	return "xray_client_ip"
+}
@@ -0,0 +1,48 @@
+package model
+
+// XrayOutbound is a panel-managed Xray outbound on one node, added after the
+// built-in "direct" and "blocked" outbounds.
+type XrayOutbound struct {
+	ID                 int64  `gorm:"primaryKey;autoIncrement" json:"id"`
+	NodeId             int64  `gorm:"column:node_id;index" json:"nodeId"`
+	Tag                string `gorm:"column:tag;size:100" json:"tag"`
+	Protocol           string `gorm:"column:protocol;size:32" json:"protocol"` // Xray outbound protocol, or "chain" for another of our inbounds
+	SettingsJson       string `gorm:"column:settings_json;type:text" json:"settingsJson"`
+	StreamSettingsJson string `gorm:"column:stream_settings_json;type:text" json:"streamSettingsJson"`
+	ChainInboundId     int64  `gorm:"column:chain_inbound_id;default:0" json:"chainInboundId"` // target inbound of a "chain" outbound
+	ChainClientId      int64  `gorm:"column:chain_client_id;default:0" json:"chainClientId"`   // client created on the target inbound
+	DialerProxy        string `gorm:"column:dialer_proxy;size:100" json:"dialerProxy"`         // tag of another outbound to dial through
+	Remark             string `gorm:"column:remark" json:"remark"`
+	Inx                int    `gorm:"column:inx" json:"inx"`
+	Enable             int    `gorm:"column:enable;default:1" json:"enable"`
+	CreatedTime        int64  `gorm:"column:created_time" json:"createdTime"`
+	UpdatedTime        int64  `gorm:"column:updated_time" json:"updatedTime"`
+}
+
+func (XrayOutbound) TableName() string {
+	return "xray_outbound"
+}
+
+// XrayRoutingRule is a panel-managed Xray routing rule on one node. Rules
+// apply in Inx order after the API rule; all non-empty conditions must match.
+type XrayRoutingRule struct {
+	ID          int64  `gorm:"primaryKey;autoIncrement" json:"id"`
+	NodeId      int64  `gorm:"column:node_id;index" json:"nodeId"`
+	InboundIds  string `gorm:"column:inbound_ids;type:text" json:"inboundIds"` // comma-separated xray_inbound ids
+	UserIds     string `gorm:"column:user_ids;type:text" json:"userIds"`       // comma-separated user ids, matched by their client emails
+	Domain      string `gorm:"column:domain;type:text" json:"domain"`          // one per line: geosite:, domain:, full:, keyword:, regexp:
+	Ip          string `gorm:"column:ip;type:text" json:"ip"`                  // one per line: geoip: or CIDR
+	Port        string `gorm:"column:port;size:255" json:"port"`               // e.g. "53,443,1000-2000"
+	Network     string `gorm:"column:network;size:16" json:"network"`          // "tcp", "udp" or "tcp,udp"
+	Protocol    string `gorm:"column:protocol;size:100" json:"protocol"`       // sniffed: http, tls, quic, bittorrent
+	OutboundTag string `gorm:"column:outbound_tag;size:100" json:"outboundTag"`
+	Remark      string `gorm:"column:remark" json:"remark"`
+	Inx         int    `gorm:"column:inx" json:"inx"`
+	Enable      int    `gorm:"column:enable;default:1" json:"enable"`
+	CreatedTime int64  `gorm:"column:created_time" json:"createdTime"`
+	UpdatedTime int64  `gorm:"column:updated_time" json:"updatedTime"`
+}
+
+func (XrayRoutingRule) TableName() string {
+	return "xray_routing_rule"
+}
@@ -0,0 +1,379 @@
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"flux-panel/go-backend/dto"
+	"flux-panel/go-backend/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	clusterHeartbeatInterval = 10 * time.Second
+	clusterTTL               = 30 * time.Second
+	clusterLeaseName         = "scheduler"
+	clusterSysInfoTTL        = 3 * time.Second
+
+	// ClusterSecretHeader carries the shared secret on replica-to-replica calls.
+	ClusterSecretHeader = "X-Cluster-Secret"
+)
+
+// ClusterManager lets several backend replicas share one database.
+//
+// Every replica registers itself and the nodes whose WebSocket it holds in
+// the DB, refreshing both with heartbeats. Commands for a node connected to
+// another replica are forwarded to that replica's internal endpoint, admin
+// broadcasts are fanned out to all peers, and periodic tasks run only on the
+// replica holding the scheduler lease.
+type ClusterManager struct {
+	db            *gorm.DB
+	InstanceId    string
+	AdvertiseAddr string
+	Secret        string
+
+	client      *http.Client
+	leader      atomic.Bool
+	peers       atomic.Value // []model.BackendInstance (excluding self)
+	remoteNodes atomic.Value // map[int64]string nodeId → owner addr
+	broadcastCh chan string
+
+	sysInfoMu    sync.Mutex
+	sysInfoCache map[string]*clusterSysInfo // owner addr → cached snapshot
+
+	// OnNodeLost is called by the leader for nodes whose owning replica
+	// stopped heartbeating, so their status can be reset.
+	OnNodeLost func(nodeId int64)
+}
+
+type clusterSysInfo struct {
+	fetched time.Time
+	nodes   map[int64]*NodeSystemInfo
+}
+
+// ClusterSendRequest is the body of a forwarded node command.
+type ClusterSendRequest struct {
+	NodeId    int64       `json:"nodeId"`
+	Type      string      `json:"type"`
+	Data      interface{} `json:"data"`
+	TimeoutMs int64       `json:"timeoutMs"`
+}
+
+var Cluster *ClusterManager
+
+// InitCluster registers this replica and starts the heartbeat, lease and
+// broadcast loops. advertiseAddr must be reachable from the other replicas,
+// e.g. "http://10.0.0.5:6365".
+func InitCluster(db *gorm.DB, advertiseAddr, secret string) {
+	c := &ClusterManager{
+		db:            db,
+		InstanceId:    GenerateUUIDv4(),
+		AdvertiseAddr: strings.TrimRight(advertiseAddr, "/"),
+		Secret:        secret,
+		client:        &http.Client{Timeout: 15 * time.Second},
+		broadcastCh:   make(chan string, 1024),
+		sysInfoCache:  make(map[string]*clusterSysInfo),
+	}
+	c.peers.Store([]model.BackendInstance{})
+	c.remoteNodes.Store(map[int64]string{})
+
+	now := time.Now().UnixMilli()
+	db.Create(&model.BackendInstance{
+		ID:            c.InstanceId,
+		Addr:          c.AdvertiseAddr,
+		StartedTime:   now,
+		HeartbeatTime: now,
+	})
+	c.renewLease()
+	c.refreshPeers()
+
+	Cluster = c
+	log.Printf("[Cluster] 实例 %s 已注册 (addr=%s, leader=%v)", c.InstanceId, c.AdvertiseAddr, c.IsLeader())
+
+	Tasks.Go("cluster-heartbeat", c.heartbeatLoop)
+	Tasks.Go("cluster-broadcast", c.broadcastLoop)
+}
+
+// IsLeader reports whether this replica should run singleton tasks.
+// Without clustering every call returns true.
+func IsLeader() bool {
+	return Cluster == nil || Cluster.IsLeader()
+}
+
+func (c *ClusterManager) IsLeader() bool {
+	return c.leader.Load()
+}
+
+// Shutdown deregisters this replica, releasing its lease and node sessions.
+func (c *ClusterManager) Shutdown() {
+	c.db.Where("instance_id = ?", c.InstanceId).Delete(&model.NodeSession{})
+	c.db.Model(&model.ClusterLease{}).Where("name = ? AND holder = ?", clusterLeaseName, c.InstanceId).
+		Update("expire_time", 0)
+	c.db.Where("id = ?", c.InstanceId).Delete(&model.BackendInstance{})
+	c.leader.Store(false)
+}
+
+func (c *ClusterManager) heartbeatLoop(ctx context.Context) {
+	for Sleep(ctx, clusterHeartbeatInterval) {
+		now := time.Now().UnixMilli()
+		c.db.Model(&model.BackendInstance{}).Where("id = ?", c.InstanceId).
+			Update("heartbeat_time", now)
+		c.db.Model(&model.NodeSession{}).Where("instance_id = ?", c.InstanceId).
+			Update("heartbeat_time", now)
+
+		c.renewLease()
+		c.refreshPeers()
+		if c.IsLeader() {
+			c.reapStale()
+			c.reapNodeNonces()
+		}
+	}
+}
+
+// renewLease takes the scheduler lease if it is free or expired, or extends
+// it if this replica already holds it.
+func (c *ClusterManager) renewLease() {
+	now := time.Now().UnixMilli()
+	c.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&model.ClusterLease{Name: clusterLeaseName})
+	res := c.db.Model(&model.ClusterLease{}).
+		Where("name = ? AND (holder = ? OR expire_time < ?)", clusterLeaseName, c.InstanceId, now).
+		Updates(map[string]interface{}{
+			"holder":      c.InstanceId,
+			"expire_time": now + clusterTTL.Milliseconds(),
+		})
+	leader := res.Error == nil && res.RowsAffected > 0
+	if leader != c.leader.Swap(leader) {
+		log.Printf("[Cluster] 实例 %s leader=%v", c.InstanceId, leader)
+	}
+}
+
+// refreshPeers reloads live replicas and the nodes they hold.
+func (c *ClusterManager) refreshPeers() {
+	cutoff := time.Now().Add(-clusterTTL).UnixMilli()
+
+	var instances []model.BackendInstance
+	if err := c.db.Where("heartbeat_time > ? AND id != ?", cutoff, c.InstanceId).Find(&instances).Error; err != nil {
+		return
+	}
+	c.peers.Store(instances)
+
+	addrs := make(map[string]string, len(instances))
+	for _, inst := range instances {
+		addrs[inst.ID] = inst.Addr
+	}
+	var sessions []model.NodeSession
+	c.db.Where("heartbeat_time > ? AND instance_id != ?", cutoff, c.InstanceId).Find(&sessions)
+	remote := make(map[int64]string, len(sessions))
+	for _, s := range sessions {
+		if addr, ok := addrs[s.InstanceId]; ok {
+			remote[s.NodeId] = addr
+		}
+	}
+	c.remoteNodes.Store(remote)
+}
+
+// reapStale removes sessions and replicas that stopped heartbeating. Nodes
+// left without a live session are reported through OnNodeLost.
+func (c *ClusterManager) reapStale() {
+	cutoff := time.Now().Add(-clusterTTL).UnixMilli()
+
+	var stale []model.NodeSession
+	c.db.Where("heartbeat_time < ?", cutoff).Find(&stale)
+	for _, s := range stale {
+		res := c.db.Where("node_id = ? AND instance_id = ? AND heartbeat_time < ?", s.NodeId, s.InstanceId, cutoff).
+			Delete(&model.NodeSession{})
+		if res.RowsAffected > 0 && c.OnNodeLost != nil && !WS.isLocalNode(s.NodeId) {
+			log.Printf("[Cluster] 节点 %d 所在实例 %s 已失联", s.NodeId, s.InstanceId)
+			c.OnNodeLost(s.NodeId)
+		}
+	}
+
+	c.db.Where("heartbeat_time < ?", time.Now().Add(-10*clusterTTL).UnixMilli()).
+		Delete(&model.BackendInstance{})
+}
+
+// RegisterNode records that this replica now holds the node's WebSocket.
+func (c *ClusterManager) RegisterNode(nodeId int64) {
+	now := time.Now().UnixMilli()
+	c.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "node_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"instance_id", "connected_time", "heartbeat_time"}),
+	}).Create(&model.NodeSession{
+		NodeId:        nodeId,
+		InstanceId:    c.InstanceId,
+		ConnectedTime: now,
+		HeartbeatTime: now,
+	})
+}
+
+// UnregisterNode drops the node's session if this replica still owns it.
+// It returns true if the node has meanwhile reconnected to another replica,
+// in which case the caller must not mark it offline.
+func (c *ClusterManager) UnregisterNode(nodeId int64) bool {
+	res := c.db.Where("node_id = ? AND instance_id = ?", nodeId, c.InstanceId).Delete(&model.NodeSession{})
+	if res.Error == nil && res.RowsAffected > 0 {
+		return false
+	}
+	_, ok := c.locateNode(nodeId)
+	return ok
+}
+
+// IsRemoteNodeOnline reports whether another replica holds the node, based
+// on the snapshot refreshed with each heartbeat.
+func (c *ClusterManager) IsRemoteNodeOnline(nodeId int64) bool {
+	_, ok := c.remoteNodes.Load().(map[int64]string)[nodeId]
+	return ok
+}
+
+// locateNode returns the address of the live replica holding the node.
+func (c *ClusterManager) locateNode(nodeId int64) (string, bool) {
+	cutoff := time.Now().Add(-clusterTTL).UnixMilli()
+
+	var sess model.NodeSession
+	if err := c.db.Where("node_id = ? AND heartbeat_time > ?", nodeId, cutoff).First(&sess).Error; err != nil {
+		return "", false
+	}
+	if sess.InstanceId == c.InstanceId {
+		return "", false
+	}
+	var inst model.BackendInstance
+	if err := c.db.Where("id = ? AND heartbeat_time > ?", sess.InstanceId, cutoff).First(&inst).Error; err != nil {
+		return "", false
+	}
+	return inst.Addr, true
+}
+
+// Forward sends a command to a node held by another replica and waits for
+// the node's response.
+func (c *ClusterManager) Forward(nodeId int64, data interface{}, cmdType string, timeout time.Duration) *dto.GostResponse {
+	addr, ok := c.locateNode(nodeId)
+	if !ok {
+		return &dto.GostResponse{Msg: "节点不在线"}
+	}
+
+	body, err := json.Marshal(ClusterSendRequest{
+		NodeId:    nodeId,
+		Type:      cmdType,
+		Data:      data,
+		TimeoutMs: timeout.Milliseconds(),
+	})
+	if err != nil {
+		return &dto.GostResponse{Msg: fmt.Sprintf("序列化转发请求失败: %v", err)}
+	}
+
+	client := &http.Client{Timeout: timeout + 5*time.Second}
+	resp, err := c.post(client, addr+"/internal/cluster/send", body)
+	if err != nil {
+		log.Printf("[Cluster] 转发到 %s 失败 (node=%d): %v", addr, nodeId, err)
+		return &dto.GostResponse{Msg: "转发到节点所在实例失败"}
+	}
+
+	var result dto.GostResponse
+	if err := json.Unmarshal(resp, &result); err != nil {
+		return &dto.GostResponse{Msg: "解析转发响应失败"}
+	}
+	return &result
+}
+
+// RemoteNodeSystemInfo returns the cached system info of a node held by
+// another replica, refreshing the owner's snapshot when it is stale.
+func (c *ClusterManager) RemoteNodeSystemInfo(nodeId int64) *NodeSystemInfo {
+	addr, ok := c.remoteNodes.Load().(map[int64]string)[nodeId]
+	if !ok {
+		return nil
+	}
+
+	c.sysInfoMu.Lock()
+	defer c.sysInfoMu.Unlock()
+
+	cached := c.sysInfoCache[addr]
+	if cached == nil || time.Since(cached.fetched) > clusterSysInfoTTL {
+		cached = &clusterSysInfo{fetched: time.Now()}
+		client := &http.Client{Timeout: 2 * time.Second}
+		if resp, err := c.post(client, addr+"/internal/cluster/sysinfo", nil); err == nil {
+			json.Unmarshal(resp, &cached.nodes)
+		}
+		c.sysInfoCache[addr] = cached
+	}
+	return cached.nodes[nodeId]
+}
+
+// Publish queues an admin broadcast for delivery to all peers.
+func (c *ClusterManager) Publish(message string) {
+	select {
+	case c.broadcastCh <- message:
+	default:
+		// Peers are slow or down; live metrics are best-effort
+	}
+}
+
+// broadcastLoop batches queued broadcasts and posts them to every peer.
+func (c *ClusterManager) broadcastLoop(ctx context.Context) {
+	const maxBatch = 100
+	for {
+		var msg string
+		select {
+		case msg = <-c.broadcastCh:
+		case <-ctx.Done():
+			return
+		}
+		batch := []string{msg}
+		timer := time.NewTimer(200 * time.Millisecond)
+	collect:
+		for len(batch) < maxBatch {
+			select {
+			case m := <-c.broadcastCh:
+				batch = append(batch, m)
+			case <-timer.C:
+				break collect
+			}
+		}
+		timer.Stop()
+
+		peers := c.peers.Load().([]model.BackendInstance)
+		if len(peers) == 0 {
+			continue
+		}
+		body, _ := json.Marshal(batch)
+		for _, p := range peers {
+			if _, err := c.post(c.client, p.Addr+"/internal/cluster/broadcast", body); err != nil {
+				log.Printf("[Cluster] 广播到 %s 失败: %v", p.Addr, err)
+			}
+		}
+	}
+}
+
+func (c *ClusterManager) post(client *http.Client, url string, body []byte) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(ClusterSecretHeader, c.Secret)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 10<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	return data, nil
+}
@@ -0,0 +1,214 @@
+package pkg
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule computes activation times for a job.
+type Schedule interface {
+	// Next returns the first activation strictly after t, in t's location.
+	Next(t time.Time) time.Time
+}
+
+// ParseCron parses a standard five-field cron expression
+// ("minute hour day-of-month month day-of-week") or one of the descriptors
+// @yearly, @monthly, @weekly, @daily, @midnight, @hourly and "@every <duration>".
+//
+// Fields accept *, numbers, ranges (a-b), lists (a,b) and steps (*/n, a-b/n).
+// Day-of-week is 0-6 with 0 or 7 for Sunday. As in Vixie cron, when both
+// day-of-month and day-of-week are restricted a day matching either fires;
+// a field starting with * (including */n) counts as unrestricted.
+//
+// Times are wall-clock times in the location passed to Next. A time skipped
+// by a daylight saving change fires at the end of the gap, and a time in a
+// repeated hour fires once unless the hour field starts with *.
+func ParseCron(spec string) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if strings.HasPrefix(spec, "@every ") {
+		d, err := time.ParseDuration(strings.TrimSpace(spec[len("@every "):]))
+		if err != nil {
+			return nil, fmt.Errorf("无效的间隔: %v", err)
+		}
+		if d < time.Second {
+			return nil, fmt.Errorf("间隔不能小于 1s")
+		}
+		return everySchedule(d), nil
+	}
+
+	switch spec {
+	case "@yearly", "@annually":
+		spec = "0 0 1 1 *"
+	case "@monthly":
+		spec = "0 0 1 * *"
+	case "@weekly":
+		spec = "0 0 * * 0"
+	case "@daily", "@midnight":
+		spec = "0 0 * * *"
+	case "@hourly":
+		spec = "0 * * * *"
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron 表达式需要 5 个字段，实际为 %d 个", len(fields))
+	}
+
+	s := &cronSchedule{}
+	var err error
+	if s.minute, err = parseCronField(fields[0], 0, 59); err != nil {
+		return nil, fmt.Errorf("分钟字段: %v", err)
+	}
+	if s.hour, err = parseCronField(fields[1], 0, 23); err != nil {
+		return nil, fmt.Errorf("小时字段: %v", err)
+	}
+	if s.dom, err = parseCronField(fields[2], 1, 31); err != nil {
+		return nil, fmt.Errorf("日期字段: %v", err)
+	}
+	if s.month, err = parseCronField(fields[3], 1, 12); err != nil {
+		return nil, fmt.Errorf("月份字段: %v", err)
+	}
+	if s.dow, err = parseCronField(fields[4], 0, 7); err != nil {
+		return nil, fmt.Errorf("星期字段: %v", err)
+	}
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1 << 0
+	}
+	s.hourStar = isCronStar(fields[1])
+	s.domStar = isCronStar(fields[2])
+	s.dowStar = isCronStar(fields[4])
+	return s, nil
+}
+
+func isCronStar(field string) bool {
+	return strings.HasPrefix(field, "*") || strings.HasPrefix(field, "?")
+}
+
+type everySchedule time.Duration
+
+func (e everySchedule) Next(t time.Time) time.Time {
+	return t.Add(time.Duration(e)).Truncate(time.Second)
+}
+
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	hourStar, domStar, dowStar    bool
+}
+
+func (s *cronSchedule) Next(t time.Time) time.Time {
+	loc := t.Location()
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = forward(t, time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc))
+			continue
+		}
+		if !s.dayMatches(t) {
+			t = forward(t, time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc))
+			continue
+		}
+		if s.skippedByGap(t) {
+			return t
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = forward(t, time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc))
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 || (!s.hourStar && repeatedHour(t)) {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// forward returns next, or the start of the following hour if next isn't
+// after t. time.Date resolves a wall time inside a daylight saving gap
+// using the offset from before it, which can land at or before t.
+func forward(t, next time.Time) time.Time {
+	if next.After(t) {
+		return next
+	}
+	return t.Add(time.Duration(60-t.Minute()) * time.Minute)
+}
+
+// skippedByGap reports whether t is the first minute after a daylight saving
+// gap that skipped one of the schedule's hours.
+func (s *cronSchedule) skippedByGap(t time.Time) bool {
+	prev := t.Add(-time.Minute)
+	if t.Minute() != 0 || prev.Day() != t.Day() {
+		return false
+	}
+	for h := prev.Hour() + 1; h < t.Hour(); h++ {
+		if s.hour&(1<<uint(h)) != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// repeatedHour reports whether t is in the second pass of an hour repeated
+// when daylight saving ends.
+func repeatedHour(t time.Time) bool {
+	prev := t.Add(-time.Hour)
+	return prev.Hour() == t.Hour() && prev.Day() == t.Day()
+}
+
+func (s *cronSchedule) dayMatches(t time.Time) bool {
+	domOk := s.dom&(1<<uint(t.Day())) != 0
+	dowOk := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domStar || s.dowStar {
+		return domOk && dowOk
+	}
+	return domOk || dowOk
+}
+
+func parseCronField(field string, min, max int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("无效的步长 %q", part)
+			}
+			step = n
+			part = part[:i]
+		}
+
+		lo, hi := min, max
+		switch {
+		case part == "*" || part == "?":
+		case strings.Contains(part, "-"):
+			bounds := strings.SplitN(part, "-", 2)
+			var err1, err2 error
+			lo, err1 = strconv.Atoi(bounds[0])
+			hi, err2 = strconv.Atoi(bounds[1])
+			if err1 != nil || err2 != nil {
+				return 0, fmt.Errorf("无效的范围 %q", part)
+			}
+		default:
+			n, err := strconv.Atoi(part)
+			if err != nil {
+				return 0, fmt.Errorf("无效的值 %q", part)
+			}
+			lo = n
+			if step == 1 {
+				hi = n
+			}
+		}
+
+		if lo < min || hi > max || lo > hi {
+			return 0, fmt.Errorf("%q 超出范围 %d-%d", part, min, max)
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
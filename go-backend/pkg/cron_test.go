@@ -0,0 +1,114 @@
+package pkg
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCronNext(t *testing.T) {
+	utc := func(s string) time.Time {
+		v, err := time.Parse("2006-01-02 15:04", s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+
+	tests := []struct {
+		spec string
+		from string
+		want []string
+	}{
+		{"*/15 * * * *", "2026-03-01 10:07", []string{"2026-03-01 10:15", "2026-03-01 10:30", "2026-03-01 10:45", "2026-03-01 11:00"}},
+		{"0 9-11 * * *", "2026-03-01 10:30", []string{"2026-03-01 11:00", "2026-03-02 09:00"}},
+		{"0 8-18/4 * * *", "2026-03-01 09:00", []string{"2026-03-01 12:00", "2026-03-01 16:00", "2026-03-02 08:00"}},
+		{"5,35 1,13 * * *", "2026-03-01 01:05", []string{"2026-03-01 01:35", "2026-03-01 13:05", "2026-03-01 13:35", "2026-03-02 01:05"}},
+		{"0 0 31 * *", "2026-01-31 00:00", []string{"2026-03-31 00:00", "2026-05-31 00:00"}},
+		{"0 0 29 2 *", "2026-01-01 00:00", []string{"2028-02-29 00:00"}},
+		// 2026-03-01 is a Sunday; 7 is Sunday too
+		{"0 12 * * 7", "2026-03-01 12:00", []string{"2026-03-08 12:00"}},
+		{"0 12 * * 1-5", "2026-03-06 12:00", []string{"2026-03-09 12:00"}},
+		// Both day fields restricted: the 15th or any Monday
+		{"0 0 15 * 1", "2026-03-01 00:00", []string{"2026-03-02 00:00", "2026-03-09 00:00", "2026-03-15 00:00", "2026-03-16 00:00"}},
+		// */n counts as unrestricted, so both fields must match: odd days that are Mondays
+		{"0 0 */2 * 1", "2026-03-01 00:00", []string{"2026-03-09 00:00", "2026-03-23 00:00", "2026-04-13 00:00"}},
+		// Only the 10th, whatever the weekday
+		{"0 0 10 * */1", "2026-03-01 00:00", []string{"2026-03-10 00:00", "2026-04-10 00:00"}},
+		{"@yearly", "2026-03-01 00:00", []string{"2027-01-01 00:00"}},
+		{"@monthly", "2026-03-01 00:00", []string{"2026-04-01 00:00"}},
+		{"@weekly", "2026-03-01 00:00", []string{"2026-03-08 00:00"}},
+		{"@daily", "2026-03-01 00:00", []string{"2026-03-02 00:00"}},
+		{"@hourly", "2026-03-01 23:59", []string{"2026-03-02 00:00"}},
+		{"@every 90m", "2026-03-01 00:00", []string{"2026-03-01 01:30", "2026-03-01 03:00"}},
+	}
+	for _, tt := range tests {
+		sched, err := ParseCron(tt.spec)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.spec, err)
+		}
+		next := utc(tt.from)
+		for _, w := range tt.want {
+			next = sched.Next(next)
+			if !next.Equal(utc(w)) {
+				t.Fatalf("%s: got %s, want %s", tt.spec, next.Format("2006-01-02 15:04"), w)
+			}
+		}
+	}
+}
+
+func TestParseCronRejects(t *testing.T) {
+	for _, spec := range []string{
+		"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *",
+		"* * * * 8", "5-1 * * * *", "*/0 * * * *", "a * * * *", "@every 10ms", "@every x",
+	} {
+		if _, err := ParseCron(spec); err == nil {
+			t.Errorf("%q accepted", spec)
+		}
+	}
+}
+
+func TestCronNextDST(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+	at := func(day, hour, min int) time.Time {
+		return time.Date(2026, time.March, day, hour, min, 0, 0, ny)
+	}
+
+	// 2026-03-08: 02:00 EST jumps to 03:00 EDT. A job at 02:30 runs when
+	// the gap ends instead of being skipped.
+	daily, _ := ParseCron("30 2 * * *")
+	if got := daily.Next(at(8, 0, 0)); !got.Equal(at(8, 3, 0)) {
+		t.Fatalf("skipped time: got %s", got)
+	}
+	if got := daily.Next(at(8, 3, 0)); !got.Equal(at(9, 2, 30)) {
+		t.Fatalf("after gap: got %s", got)
+	}
+	hourly, _ := ParseCron("0 * * * *")
+	if got := hourly.Next(at(8, 1, 0)); !got.Equal(at(8, 3, 0)) {
+		t.Fatalf("hourly across gap: got %s", got)
+	}
+
+	// 2026-11-01: 02:00 EDT falls back to 01:00 EST. A job at 01:30 runs
+	// once; an hourly job runs in both passes of the hour.
+	fall := time.Date(2026, time.November, 1, 0, 0, 0, 0, ny)
+	daily, _ = ParseCron("30 1 * * *")
+	first := daily.Next(fall)
+	if first.Hour() != 1 || first.Minute() != 30 {
+		t.Fatalf("repeated hour: got %s", first)
+	}
+	if got := daily.Next(first); got.Day() != 2 || got.Hour() != 1 || got.Minute() != 30 {
+		t.Fatalf("ran twice in the repeated hour: got %s", got)
+	}
+	var runs []time.Time
+	for next := fall; len(runs) < 4; {
+		next = hourly.Next(next)
+		runs = append(runs, next)
+	}
+	for i := 1; i < len(runs); i++ {
+		if d := runs[i].Sub(runs[i-1]); d != time.Hour {
+			t.Fatalf("hourly runs %v are not an hour apart", runs)
+		}
+	}
+}
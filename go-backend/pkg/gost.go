@@ -149,14 +149,34 @@ func ResumeRemoteService(nodeId int64, name string) *dto.GostResponse {
 	return WS.SendMsg(nodeId, data, "ResumeService")
 }
 
-func NodeUpdateBinary(nodeId int64, panelAddr string) *dto.GostResponse {
+// NodeUpdateBinary tells a node to fetch, verify and install the panel's
+// node binary. publicKey is offered to nodes that haven't pinned a release
+// key yet; version, if set, must match the signed manifest.
+func NodeUpdateBinary(nodeId int64, panelAddr, version string) *dto.GostResponse {
 	// 新版节点使用自身 config.json 中的 addr 构建下载地址，
 	// 但仍传入 panelAddr 以兼容旧版节点
 	return WS.SendMsgWithTimeout(nodeId, map[string]interface{}{
 		"panelAddr": panelAddr,
+		"version":   version,
 	}, "NodeUpdateBinary", 6*time.Minute)
 }
 
+// NodeRollbackBinary restores the binary a node backed up before its last
+// update.
+func NodeRollbackBinary(nodeId int64) *dto.GostResponse {
+	return WS.SendMsg(nodeId, map[string]interface{}{}, "NodeRollbackBinary")
+}
+
+// NodeRotateSecret hands a node its new secret. The node keeps accepting
+// the old one for graceSeconds.
+func NodeRotateSecret(nodeId int64, secret string, graceSeconds int64) *dto.GostResponse {
+	data := map[string]interface{}{
+		"secret":       secret,
+		"graceSeconds": graceSeconds,
+	}
+	return WS.SendMsg(nodeId, data, "RotateSecret")
+}
+
 func AddChains(nodeId int64, name string, remoteAddr string, protocol string, interfaceName string) *dto.GostResponse {
 	data := buildChainData(name, remoteAddr, protocol, interfaceName)
 	return WS.SendMsg(nodeId, data, "AddChains")
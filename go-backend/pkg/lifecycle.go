@@ -0,0 +1,75 @@
+package pkg
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// TaskGroup runs background goroutines that share one cancellable context
+// and can be awaited on shutdown.
+type TaskGroup struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// Tasks holds every long-running background task of the backend.
+var Tasks = NewTaskGroup()
+
+func NewTaskGroup() *TaskGroup {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &TaskGroup{ctx: ctx, cancel: cancel}
+}
+
+// Context is cancelled once shutdown begins.
+func (g *TaskGroup) Context() context.Context {
+	return g.ctx
+}
+
+// Go starts fn in a tracked goroutine. A panic is logged instead of taking
+// the whole process down.
+func (g *TaskGroup) Go(name string, fn func(ctx context.Context)) {
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		defer func() {
+			if r := recover(); r != nil {
+				log.Printf("[Task] %s panic: %v", name, r)
+			}
+		}()
+		fn(g.ctx)
+	}()
+}
+
+// Stop cancels all tasks and waits for them to return until ctx expires.
+// It reports whether every task finished in time.
+func (g *TaskGroup) Stop(ctx context.Context) bool {
+	g.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		g.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// Sleep pauses for d or until ctx is cancelled. It returns false if the
+// caller should exit.
+func Sleep(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
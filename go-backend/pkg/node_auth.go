@@ -0,0 +1,213 @@
+package pkg
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"golang.org/x/crypto/hkdf"
+)
+
+// Node channel authentication.
+//
+// Nodes that connect with auth=2 never put their secret in the URL.
+// Instead, right after the upgrade:
+//
+//	panel → node   {"kind":"challenge","nonce":Np,"ts":Tp}
+//	node  → panel  {"kind":"auth","kid":K,"nonce":Nn,"ts":Tn,"mac":HMAC(secret, node proof)}
+//	panel → node   {"kind":"auth_ok","mac":HMAC(secret, panel proof)}
+//
+// so each side proves it knows the secret without sending it. Both then
+// derive one AES-256-GCM key per direction with HKDF, salted with the two
+// nonces. Every message carries a sequence number and timestamp bound into
+// the AEAD; the receiver drops anything that isn't strictly newer than the
+// previous message or falls outside the clock window.
+//
+// Connections without auth=2 take the legacy path (secret in the query,
+// one static key) only while NODE_LEGACY_AUTH is on.
+
+const (
+	NodeAuthVersion = "2"
+
+	MsgKindChallenge = "challenge"
+	MsgKindAuth      = "auth"
+	MsgKindAuthOK    = "auth_ok"
+
+	nodeHandshakeTimeout = 10 * time.Second
+	// Allowed drift between a message's timestamp and the clock offset
+	// measured during the handshake
+	nodeClockWindow = 2 * time.Minute
+)
+
+// NodeKeyId identifies a node's secret without revealing it.
+func NodeKeyId(secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("flux-node-kid:v2"))
+	return hex.EncodeToString(mac.Sum(nil))[:32]
+}
+
+type authMessage struct {
+	Kind  string `json:"kind"`
+	V     int    `json:"v,omitempty"`
+	Kid   string `json:"kid,omitempty"`
+	Nonce string `json:"nonce,omitempty"`
+	Ts    int64  `json:"ts,omitempty"`
+	Mac   string `json:"mac,omitempty"`
+}
+
+func authMac(secret string, parts ...string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	for i, p := range parts {
+		if i > 0 {
+			mac.Write([]byte{'\n'})
+		}
+		mac.Write([]byte(p))
+	}
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func nodeProof(secret, panelNonce, nodeNonce, kid string, ts int64) string {
+	return authMac(secret, "flux-node-auth:v2", panelNonce, nodeNonce, kid, strconv.FormatInt(ts, 10))
+}
+
+func panelProof(secret, panelNonce, nodeNonce string) string {
+	return authMac(secret, "flux-panel-auth:v2", panelNonce, nodeNonce)
+}
+
+// authenticateNode runs the panel side of the handshake on a freshly
+// upgraded connection. Returns the node and its session keys.
+func (m *WSManager) authenticateNode(conn *websocket.Conn) (int64, string, *sessionCipher, error) {
+	if m.LookupNodeKey == nil {
+		return 0, "", nil, errors.New("node key lookup not configured")
+	}
+	conn.SetReadDeadline(time.Now().Add(nodeHandshakeTimeout))
+	defer conn.SetReadDeadline(time.Time{})
+
+	nonce := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return 0, "", nil, err
+	}
+	panelNonce := base64.StdEncoding.EncodeToString(nonce)
+	if err := conn.WriteJSON(authMessage{Kind: MsgKindChallenge, V: ProtocolVersion, Nonce: panelNonce, Ts: time.Now().UnixMilli()}); err != nil {
+		return 0, "", nil, err
+	}
+
+	var auth authMessage
+	if err := conn.ReadJSON(&auth); err != nil {
+		return 0, "", nil, err
+	}
+	if auth.Kind != MsgKindAuth || auth.Kid == "" || auth.Nonce == "" || auth.Mac == "" {
+		return 0, "", nil, errors.New("invalid auth message")
+	}
+
+	nodeId, secret := m.LookupNodeKey(auth.Kid)
+	if nodeId == 0 {
+		return 0, "", nil, fmt.Errorf("unknown key id %s", auth.Kid)
+	}
+	expected := nodeProof(secret, panelNonce, auth.Nonce, auth.Kid, auth.Ts)
+	if !hmac.Equal([]byte(expected), []byte(auth.Mac)) {
+		return 0, "", nil, fmt.Errorf("node %d failed authentication", nodeId)
+	}
+
+	if err := conn.WriteJSON(authMessage{Kind: MsgKindAuthOK, V: ProtocolVersion, Mac: panelProof(secret, panelNonce, auth.Nonce)}); err != nil {
+		return 0, "", nil, err
+	}
+
+	session, err := newSessionCipher(secret, panelNonce, auth.Nonce, time.Now().UnixMilli()-auth.Ts)
+	if err != nil {
+		return 0, "", nil, err
+	}
+	return nodeId, secret, session, nil
+}
+
+// sessionCipher holds the per-connection keys of an authenticated node.
+type sessionCipher struct {
+	send    cipher.AEAD // panel → node
+	recv    cipher.AEAD // node → panel
+	sendSeq atomic.Uint64
+	recvSeq uint64 // only touched by the connection's read loop
+	offset  int64  // ms to add to the node's clock to get ours
+}
+
+func newSessionCipher(secret, panelNonce, nodeNonce string, offset int64) (*sessionCipher, error) {
+	salt := []byte(panelNonce + "\n" + nodeNonce)
+	send, err := sessionAEAD(secret, salt, "flux-node-session:v2:panel")
+	if err != nil {
+		return nil, err
+	}
+	recv, err := sessionAEAD(secret, salt, "flux-node-session:v2:node")
+	if err != nil {
+		return nil, err
+	}
+	return &sessionCipher{send: send, recv: recv, offset: offset}, nil
+}
+
+func sessionAEAD(secret string, salt []byte, info string) (cipher.AEAD, error) {
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, []byte(secret), salt, []byte(info)), key); err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func sessionAAD(seq uint64, ts int64) []byte {
+	return []byte(fmt.Sprintf("flux:%d:%d", seq, ts))
+}
+
+// seal encrypts one outgoing message. Callers serialize sends.
+func (s *sessionCipher) seal(plaintext string) (string, error) {
+	enc := EncryptedMessage{
+		Encrypted: true,
+		Seq:       s.sendSeq.Add(1),
+		Timestamp: time.Now().UnixMilli(),
+	}
+	nonce := make([]byte, s.send.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	enc.Data = base64.StdEncoding.EncodeToString(s.send.Seal(nonce, nonce, []byte(plaintext), sessionAAD(enc.Seq, enc.Timestamp)))
+	b, err := json.Marshal(enc)
+	return string(b), err
+}
+
+// open decrypts one incoming message, rejecting replays.
+func (s *sessionCipher) open(payload string) (string, error) {
+	var enc EncryptedMessage
+	if err := json.Unmarshal([]byte(payload), &enc); err != nil || !enc.Encrypted {
+		return "", errors.New("unencrypted message on authenticated session")
+	}
+	if enc.Seq <= s.recvSeq {
+		return "", fmt.Errorf("replayed message (seq %d <= %d)", enc.Seq, s.recvSeq)
+	}
+	drift := time.Duration(time.Now().UnixMilli()-(enc.Timestamp+s.offset)) * time.Millisecond
+	if drift > nodeClockWindow || drift < -nodeClockWindow {
+		return "", fmt.Errorf("message timestamp outside window (%v)", drift)
+	}
+	data, err := base64.StdEncoding.DecodeString(enc.Data)
+	if err != nil || len(data) < s.recv.NonceSize() {
+		return "", errors.New("malformed ciphertext")
+	}
+	nonce, ciphertext := data[:s.recv.NonceSize()], data[s.recv.NonceSize():]
+	plaintext, err := s.recv.Open(nil, nonce, ciphertext, sessionAAD(enc.Seq, enc.Timestamp))
+	if err != nil {
+		return "", err
+	}
+	s.recvSeq = enc.Seq
+	return string(plaintext), nil
+}
@@ -0,0 +1,131 @@
+package pkg
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"flux-panel/go-backend/model"
+
+	"gorm.io/gorm/clause"
+)
+
+// Signed node HTTP requests.
+//
+// Nodes post traffic, config and online-IP reports over plain HTTP. The
+// secret is never sent; each request carries
+//
+//	X-Node-Kid    NodeKeyId(secret)
+//	X-Node-Ts     unix ms
+//	X-Node-Nonce  random
+//	X-Node-Sig    HMAC(secret, "flux-node-http:v2", method, path, ts, nonce, hex(sha256(body)))
+//
+// The panel drops signatures outside the clock window and nonces it has
+// already accepted inside it. With several replicas the nonces are kept in
+// the database, since a captured report could otherwise be replayed to a
+// replica that hasn't seen it.
+
+const (
+	HeaderNodeKid   = "X-Node-Kid"
+	HeaderNodeTs    = "X-Node-Ts"
+	HeaderNodeNonce = "X-Node-Nonce"
+	HeaderNodeSig   = "X-Node-Sig"
+
+	maxNodeNonceLen = 64
+)
+
+// NodeRequestSig signs one node request.
+func NodeRequestSig(secret, method, path, ts, nonce string, body []byte) string {
+	sum := sha256.Sum256(body)
+	return authMac(secret, "flux-node-http:v2", method, path, ts, nonce, hex.EncodeToString(sum[:]))
+}
+
+// VerifyNodeRequest checks a signed node request and returns the node and
+// the secret it signed with.
+func VerifyNodeRequest(h http.Header, method, path string, body []byte, lookup func(kid string) (int64, string)) (int64, string, error) {
+	kid, ts, nonce, sig := h.Get(HeaderNodeKid), h.Get(HeaderNodeTs), h.Get(HeaderNodeNonce), h.Get(HeaderNodeSig)
+	if kid == "" || ts == "" || nonce == "" || sig == "" {
+		return 0, "", errors.New("unsigned request")
+	}
+	if len(kid) > 32 || len(nonce) > maxNodeNonceLen {
+		return 0, "", errors.New("invalid key id or nonce")
+	}
+	ms, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return 0, "", errors.New("invalid timestamp")
+	}
+	drift := time.Since(time.UnixMilli(ms))
+	if drift > nodeClockWindow || drift < -nodeClockWindow {
+		return 0, "", fmt.Errorf("timestamp outside window (%v)", drift)
+	}
+
+	nodeId, secret := lookup(kid)
+	if nodeId == 0 {
+		return 0, "", fmt.Errorf("unknown key id %s", kid)
+	}
+	if !hmac.Equal([]byte(NodeRequestSig(secret, method, path, ts, nonce, body)), []byte(sig)) {
+		return 0, "", fmt.Errorf("node %d: bad signature", nodeId)
+	}
+	if !claimNodeNonce(kid, nonce, ms) {
+		return 0, "", fmt.Errorf("node %d: replayed request", nodeId)
+	}
+	return nodeId, secret, nil
+}
+
+// claimNodeNonce records a nonce, reporting false if it was used before.
+func claimNodeNonce(kid, nonce string, ts int64) bool {
+	if Cluster != nil {
+		return Cluster.claimNodeNonce(kid, nonce, ts)
+	}
+	return requestNonces.add(kid+"\n"+nonce, ts)
+}
+
+// claimNodeNonce inserts the nonce into the shared table; the primary key
+// makes a second insert, on any replica, a no-op. A database error rejects
+// the request and the node retries.
+func (c *ClusterManager) claimNodeNonce(kid, nonce string, ts int64) bool {
+	res := c.db.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&model.NodeRequestNonce{Kid: kid, Nonce: nonce, Ts: ts})
+	return res.Error == nil && res.RowsAffected > 0
+}
+
+// reapNodeNonces drops nonces whose timestamp has left the clock window.
+func (c *ClusterManager) reapNodeNonces() {
+	c.db.Where("ts < ?", time.Now().Add(-2*nodeClockWindow).UnixMilli()).Delete(&model.NodeRequestNonce{})
+}
+
+// nonceCache remembers accepted nonces until their timestamp leaves the
+// clock window, after which the timestamp check rejects them anyway.
+type nonceCache struct {
+	mu        sync.Mutex
+	seen      map[string]int64
+	lastPrune time.Time
+}
+
+var requestNonces = &nonceCache{seen: make(map[string]int64)}
+
+func (c *nonceCache) add(key string, ts int64) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	if now.Sub(c.lastPrune) > nodeClockWindow {
+		cutoff := now.Add(-2 * nodeClockWindow).UnixMilli()
+		for k, t := range c.seen {
+			if t < cutoff {
+				delete(c.seen, k)
+			}
+		}
+		c.lastPrune = now
+	}
+	if _, ok := c.seen[key]; ok {
+		return false
+	}
+	c.seen[key] = ts
+	return true
+}
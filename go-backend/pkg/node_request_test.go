@@ -0,0 +1,114 @@
+package pkg
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"flux-panel/go-backend/model"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func signedHeader(secret, path, nonce string, ts time.Time, body []byte) http.Header {
+	h := http.Header{}
+	t := strconv.FormatInt(ts.UnixMilli(), 10)
+	h.Set(HeaderNodeKid, NodeKeyId(secret))
+	h.Set(HeaderNodeTs, t)
+	h.Set(HeaderNodeNonce, nonce)
+	h.Set(HeaderNodeSig, NodeRequestSig(secret, "POST", path, t, nonce, body))
+	return h
+}
+
+func TestVerifyNodeRequest(t *testing.T) {
+	const secret = "node-secret"
+	lookup := func(kid string) (int64, string) {
+		if kid == NodeKeyId(secret) {
+			return 7, secret
+		}
+		return 0, ""
+	}
+	body := []byte(`{"s":1}`)
+	now := time.Now()
+
+	h := signedHeader(secret, "/flow/su", "n1", now, body)
+	id, got, err := VerifyNodeRequest(h, "POST", "/flow/su", body, lookup)
+	if err != nil || id != 7 || got != secret {
+		t.Fatalf("valid request: id=%d secret=%q err=%v", id, got, err)
+	}
+	if _, _, err := VerifyNodeRequest(h, "POST", "/flow/su", body, lookup); err == nil {
+		t.Fatal("replayed nonce accepted")
+	}
+
+	rejected := []struct {
+		name string
+		h    http.Header
+		path string
+		body []byte
+	}{
+		{"other endpoint", signedHeader(secret, "/flow/su", "n2", now, body), "/flow/upload", body},
+		{"altered body", signedHeader(secret, "/flow/su", "n3", now, body), "/flow/su", []byte(`{"s":2}`)},
+		{"stale", signedHeader(secret, "/flow/su", "n4", now.Add(-5*time.Minute), body), "/flow/su", body},
+		{"unknown key", signedHeader("other", "/flow/su", "n5", now, body), "/flow/su", body},
+		{"unsigned", http.Header{}, "/flow/su", body},
+	}
+	for _, tt := range rejected {
+		if _, _, err := VerifyNodeRequest(tt.h, "POST", tt.path, tt.body, lookup); err == nil {
+			t.Fatalf("%s: accepted", tt.name)
+		}
+	}
+}
+
+// The node signs with its own copy of this code
+// (go-node/x/service/report_auth.go), which must produce the same values.
+func TestNodeRequestSigVector(t *testing.T) {
+	if kid := NodeKeyId("node-secret"); kid != "9f74915ab712769662dfacf010668b0e" {
+		t.Fatalf("kid = %s", kid)
+	}
+	sig := NodeRequestSig("node-secret", "POST", "/flow/su", "1700000000000", "bm9uY2U", []byte(`{"s":1}`))
+	if sig != "SDTiZeHgrUK2fuow2JcaAt+0HSarKmOLSwbqR7lC6zQ=" {
+		t.Fatalf("sig = %s", sig)
+	}
+}
+
+func TestVerifyNodeRequestSharesNoncesAcrossReplicas(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	if err := db.AutoMigrate(&model.NodeRequestNonce{}); err != nil {
+		t.Fatal(err)
+	}
+
+	const secret = "node-secret"
+	lookup := func(string) (int64, string) { return 7, secret }
+	body := []byte(`{"clients":[]}`)
+	h := signedHeader(secret, "/flow/ips", "shared-nonce", time.Now(), body)
+
+	// Two replicas with their own memory but one database
+	prev := Cluster
+	t.Cleanup(func() { Cluster = prev })
+	Cluster = &ClusterManager{db: db}
+	if _, _, err := VerifyNodeRequest(h, "POST", "/flow/ips", body, lookup); err != nil {
+		t.Fatal(err)
+	}
+	Cluster = &ClusterManager{db: db}
+	if _, _, err := VerifyNodeRequest(h, "POST", "/flow/ips", body, lookup); err == nil {
+		t.Fatal("report replayed to another replica was accepted")
+	}
+
+	// Expired nonces are reaped
+	db.Create(&model.NodeRequestNonce{Kid: "k", Nonce: "old", Ts: time.Now().Add(-time.Hour).UnixMilli()})
+	Cluster.reapNodeNonces()
+	var n int64
+	db.Model(&model.NodeRequestNonce{}).Count(&n)
+	if n != 1 {
+		t.Fatalf("%d nonces left, want 1", n)
+	}
+}
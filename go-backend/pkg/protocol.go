@@ -0,0 +1,117 @@
+package pkg
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ProtocolVersion is the node control protocol spoken by this panel.
+//
+// Every message carries a "kind" and "v" next to its legacy fields, so
+// nodes and panels that predate the envelope keep working: they ignore the
+// extra fields, and messages without a kind are classified the old way.
+//
+// On connect the panel sends a hello (as a "call", which old nodes drop);
+// a node that understands it answers with its own hello listing the
+// commands it handles. From then on commands the node doesn't list are
+// refused immediately instead of waiting for the response timeout.
+const ProtocolVersion = 1
+
+// Message kinds.
+const (
+	MsgKindHello    = "hello"
+	MsgKindCommand  = "command"
+	MsgKindResponse = "response"
+	MsgKindMetrics  = "metrics"
+	MsgKindEvent    = "event"
+)
+
+// UnknownCommandMsg starts the error for commands a node doesn't handle.
+// Nodes answer unknown commands with the same text, so callers that fall
+// back to older commands can match on it either way.
+const UnknownCommandMsg = "未知命令类型"
+
+// MessageHeader is the part of the envelope shared by all messages.
+type MessageHeader struct {
+	Kind string `json:"kind,omitempty"`
+	V    int    `json:"v,omitempty"`
+}
+
+// NodeCapabilities is what a node advertised in its hello.
+type NodeCapabilities struct {
+	Protocol int      `json:"protocol"` // negotiated: min(node, panel)
+	Version  string   `json:"version"`
+	Commands []string `json:"commands"`
+	Features []string `json:"features,omitempty"`
+}
+
+// Supports reports whether the node handles cmdType.
+func (c *NodeCapabilities) Supports(cmdType string) bool {
+	for _, cmd := range c.Commands {
+		if cmd == cmdType {
+			return true
+		}
+	}
+	return false
+}
+
+// HasFeature reports whether the node advertised a feature flag.
+func (c *NodeCapabilities) HasFeature(feature string) bool {
+	for _, f := range c.Features {
+		if f == feature {
+			return true
+		}
+	}
+	return false
+}
+
+type helloMessage struct {
+	MessageHeader
+	Type string      `json:"type"`
+	Data interface{} `json:"data"`
+}
+
+// panelHello is sent to every node right after it connects.
+func panelHello() string {
+	b, _ := json.Marshal(helloMessage{
+		MessageHeader: MessageHeader{Kind: MsgKindHello, V: ProtocolVersion},
+		Type:          "call", // dropped by nodes without envelope support
+		Data: map[string]interface{}{
+			"protocol": ProtocolVersion,
+			"version":  Version,
+		},
+	})
+	return string(b)
+}
+
+// parseNodeHello reads the capabilities out of a node's hello.
+func parseNodeHello(message string) (*NodeCapabilities, error) {
+	var hello struct {
+		MessageHeader
+		Data NodeCapabilities `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(message), &hello); err != nil {
+		return nil, err
+	}
+	caps := hello.Data
+	if caps.Protocol <= 0 {
+		caps.Protocol = hello.V
+	}
+	if caps.Protocol <= 0 {
+		return nil, fmt.Errorf("hello 缺少协议版本")
+	}
+	if caps.Protocol > ProtocolVersion {
+		caps.Protocol = ProtocolVersion
+	}
+	return &caps, nil
+}
+
+// unsupportedCommand is the response for a command the node didn't list.
+func unsupportedCommand(cmdType string, caps *NodeCapabilities) string {
+	version := strings.TrimSpace(caps.Version)
+	if version == "" {
+		version = "未知"
+	}
+	return fmt.Sprintf("%s: %s（节点版本 %s 不支持，请更新节点）", UnknownCommandMsg, cmdType, version)
+}
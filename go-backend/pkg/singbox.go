@@ -0,0 +1,70 @@
+package pkg
+
+import (
+	"flux-panel/go-backend/dto"
+	"flux-panel/go-backend/model"
+)
+
+// sing-box runs Hysteria2/TUIC inbounds on the node. The V* inbound and
+// client commands are routed to it by protocol, so these are only needed to
+// manage the process itself or to target sing-box explicitly.
+
+func SingboxStart(nodeId int64) *dto.GostResponse {
+	return WS.SendMsg(nodeId, map[string]interface{}{}, "SBStart")
+}
+
+func SingboxStop(nodeId int64) *dto.GostResponse {
+	return WS.SendMsg(nodeId, map[string]interface{}{}, "SBStop")
+}
+
+func SingboxRestart(nodeId int64) *dto.GostResponse {
+	return WS.SendMsg(nodeId, map[string]interface{}{}, "SBRestart")
+}
+
+func SingboxStatus(nodeId int64) *dto.GostResponse {
+	return WS.SendMsg(nodeId, map[string]interface{}{}, "SBStatus")
+}
+
+func SingboxApplyConfig(nodeId int64, inbounds []model.XrayInbound) *dto.GostResponse {
+	var arr []map[string]interface{}
+	for _, ib := range inbounds {
+		arr = append(arr, map[string]interface{}{
+			"tag":                ib.Tag,
+			"protocol":           ib.Protocol,
+			"listen":             ib.Listen,
+			"port":               ib.Port,
+			"settingsJson":       ib.SettingsJson,
+			"streamSettingsJson": ib.StreamSettingsJson,
+		})
+	}
+	data := map[string]interface{}{
+		"inbounds": arr,
+	}
+	return WS.SendMsg(nodeId, data, "SBApplyConfig")
+}
+
+func SingboxAddClient(nodeId int64, inboundTag, email, uuidOrPassword, protocol string) *dto.GostResponse {
+	data := map[string]interface{}{
+		"inboundTag":     inboundTag,
+		"email":          email,
+		"uuidOrPassword": uuidOrPassword,
+		"protocol":       protocol,
+	}
+	return WS.SendMsg(nodeId, data, "SBAddClient")
+}
+
+func SingboxRemoveClient(nodeId int64, inboundTag, email string) *dto.GostResponse {
+	data := map[string]interface{}{
+		"inboundTag": inboundTag,
+		"email":      email,
+	}
+	return WS.SendMsg(nodeId, data, "SBRemoveClient")
+}
+
+// SingboxSwitchVersion installs the sing-box tarball at url after checking
+// it against sha256. It must be a build with the V2Ray API enabled: the node
+// refuses builds that can't meter traffic, which includes official releases.
+func SingboxSwitchVersion(nodeId int64, url, sha256 string) *dto.GostResponse {
+	data := map[string]interface{}{"url": url, "sha256": sha256}
+	return WS.SendMsg(nodeId, data, "SBSwitchVersion")
+}
@@ -1,6 +1,7 @@
 package pkg
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/json"
 	"fmt"
@@ -9,6 +10,7 @@ import (
 	"net/url"
 	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"flux-panel/go-backend/config"
@@ -55,6 +57,9 @@ type WSManager struct {
 	pendingRequests sync.Map // requestID(string) → chan *dto.GostResponse
 	nodeSystemInfo  sync.Map // nodeID(int64) → *NodeSystemInfo
 
+	closing atomic.Bool
+	readers sync.WaitGroup // one per connection read loop
+
 	// Callbacks set by the application
 	OnNodeOnline       func(nodeId int64, version, http, tls, socks string)
 	OnNodeOffline      func(nodeId int64)
@@ -63,6 +68,12 @@ type WSManager struct {
 	// the specific node; if nodeId == 0, looks up by secret alone.
 	// Returns the resolved nodeId (0 = rejected).
 	ValidateNodeSecret func(nodeId int64, secret string) int64
+	// LookupNodeKey resolves a NodeKeyId to the node and the secret it
+	// was derived from (0 = unknown).
+	LookupNodeKey func(kid string) (int64, string)
+	// OnNodeMetrics receives every system info report of a node
+	// connected to this replica.
+	OnNodeMetrics func(nodeId int64, info *NodeSystemInfo, at time.Time)
 }
 
 // NetInterface represents a network interface with its name and IP addresses.
@@ -71,6 +82,25 @@ type NetInterface struct {
 	IPs  []string `json:"ips"`
 }
 
+// NodeDisk is one mounted filesystem of a node.
+type NodeDisk struct {
+	Mount  string  `json:"mount"`
+	Fstype string  `json:"fstype"`
+	Total  uint64  `json:"total"`
+	Used   uint64  `json:"used"`
+	Usage  float64 `json:"usage"`
+}
+
+// NodeNetStat holds one interface's counters and the rates derived from the
+// previous report (bytes/s).
+type NodeNetStat struct {
+	Name    string `json:"name"`
+	RxBytes uint64 `json:"rxBytes"`
+	TxBytes uint64 `json:"txBytes"`
+	RxRate  int64  `json:"rxRate"`
+	TxRate  int64  `json:"txRate"`
+}
+
 // NodeSystemInfo holds the latest system metrics reported by a node.
 type NodeSystemInfo struct {
 	Uptime           uint64         `json:"uptime"`
@@ -83,6 +113,26 @@ type NodeSystemInfo struct {
 	Interfaces       []NetInterface `json:"interfaces"`
 	PanelAddr        string         `json:"panelAddr"`
 	Runtime          string         `json:"runtime"`
+	// Reported by nodes that collect them; zero otherwise
+	DiskUsage      float64           `json:"diskUsage"` // root filesystem, percent
+	Load1          float64           `json:"load1"`
+	Load5          float64           `json:"load5"`
+	Load15         float64           `json:"load15"`
+	TcpEstablished int               `json:"tcpEstablished"`
+	Disks          []NodeDisk        `json:"disks,omitempty"`
+	NetStats       []NodeNetStat     `json:"netStats,omitempty"`
+	TcpStates      map[string]int    `json:"tcpStates,omitempty"`
+	UdpSockets     int               `json:"udpSockets"`
+	ConntrackCount uint64            `json:"conntrackCount"`
+	ConntrackMax   uint64            `json:"conntrackMax"`
+	Kernel         string            `json:"kernel,omitempty"`
+	OS             string            `json:"os,omitempty"`
+	Arch           string            `json:"arch,omitempty"`
+	GostConns      uint64            `json:"gostConns"`
+	ServiceConns   map[string]uint64 `json:"serviceConns,omitempty"` // gost service name -> current connections
+	ReportedAt     int64             `json:"reportedAt"`             // ms
+	// From the node's hello; nil for nodes without protocol support
+	Capabilities *NodeCapabilities `json:"capabilities,omitempty"`
 }
 
 // GetNodeSystemInfo returns the latest cached system info for a node, or nil.
@@ -90,28 +140,49 @@ func (m *WSManager) GetNodeSystemInfo(nodeId int64) *NodeSystemInfo {
 	if val, ok := m.nodeSystemInfo.Load(nodeId); ok {
 		return val.(*NodeSystemInfo)
 	}
+	if Cluster != nil {
+		return Cluster.RemoteNodeSystemInfo(nodeId)
+	}
 	return nil
 }
 
+// LocalNodeSystemInfo returns the system info of all nodes connected to
+// this replica.
+func (m *WSManager) LocalNodeSystemInfo() map[int64]*NodeSystemInfo {
+	result := make(map[int64]*NodeSystemInfo)
+	m.nodeSystemInfo.Range(func(key, value interface{}) bool {
+		result[key.(int64)] = value.(*NodeSystemInfo)
+		return true
+	})
+	return result
+}
+
 type NodeSession struct {
 	Conn   *websocket.Conn
 	Secret string
 	mu     sync.Mutex
+	caps   atomic.Pointer[NodeCapabilities] // set once the node's hello arrives
+	// Per-session keys of nodes that authenticated with the handshake;
+	// nil on the legacy path
+	session *sessionCipher
 }
 
 type EncryptedMessage struct {
 	Encrypted bool   `json:"encrypted"`
 	Data      string `json:"data"`
 	Timestamp int64  `json:"timestamp"`
+	Seq       uint64 `json:"seq,omitempty"` // authenticated sessions only
 }
 
 type WSCommand struct {
+	MessageHeader
 	Type      string      `json:"type"`
 	Data      interface{} `json:"data"`
 	RequestId string      `json:"requestId"`
 }
 
 type WSResponse struct {
+	MessageHeader
 	RequestId string          `json:"requestId"`
 	Message   string          `json:"message"`
 	Type      string          `json:"type"`
@@ -125,6 +196,11 @@ func InitWSManager() {
 }
 
 func (m *WSManager) HandleConnection(w http.ResponseWriter, r *http.Request) {
+	if m.closing.Load() {
+		http.Error(w, "Server shutting down", http.StatusServiceUnavailable)
+		return
+	}
+
 	q := r.URL.Query()
 	id := q.Get("id")
 	connType := q.Get("type")
@@ -132,8 +208,17 @@ func (m *WSManager) HandleConnection(w http.ResponseWriter, r *http.Request) {
 
 	var respHeader http.Header
 
-	if connType == "1" {
-		// Node connection — validate secret against DB before upgrading
+	nodeAuth := connType == "1" && q.Get("auth") == NodeAuthVersion
+
+	if nodeAuth {
+		// Node proves its secret after the upgrade (see node_auth.go)
+	} else if connType == "1" {
+		// Legacy node connection — secret in the query
+		if !config.Cfg.NodeLegacyAuth {
+			http.Error(w, "Legacy node authentication disabled, please update the node", http.StatusUnauthorized)
+			return
+		}
+		// Validate secret against DB before upgrading
 		if secret == "" || m.ValidateNodeSecret == nil {
 			http.Error(w, "Unauthorized", http.StatusUnauthorized)
 			return
@@ -187,7 +272,19 @@ func (m *WSManager) HandleConnection(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if connType == "1" {
-		// Node connection (already validated above)
+		var session *sessionCipher
+		if nodeAuth {
+			var err error
+			var resolved int64
+			resolved, secret, session, err = m.authenticateNode(conn)
+			if err != nil {
+				log.Printf("节点认证失败 (%s): %v", conn.RemoteAddr(), err)
+				conn.Close()
+				return
+			}
+			id = strconv.FormatInt(resolved, 10)
+		}
+		// Node connection (authenticated above)
 		nodeId, _ := strconv.ParseInt(id, 10, 64)
 
 		version := q.Get("nodeVersion")
@@ -201,13 +298,19 @@ func (m *WSManager) HandleConnection(w http.ResponseWriter, r *http.Request) {
 			old.Conn.Close()
 		}
 
-		ns := &NodeSession{Conn: conn, Secret: secret}
+		ns := &NodeSession{Conn: conn, Secret: secret, session: session}
 		m.nodeSessions.Store(nodeId, ns)
+		if Cluster != nil {
+			Cluster.RegisterNode(nodeId)
+		}
 
 		if m.OnNodeOnline != nil {
 			m.OnNodeOnline(nodeId, version, httpVal, tlsVal, socksVal)
 		}
 
+		m.sendToNode(ns, panelHello())
+
+		m.readers.Add(1)
 		go m.readNodeMessages(nodeId, ns)
 	} else {
 		// Admin connection
@@ -215,18 +318,22 @@ func (m *WSManager) HandleConnection(w http.ResponseWriter, r *http.Request) {
 		as := &AdminSession{Conn: conn}
 		m.adminSessions.Store(sessionId, as)
 
+		m.readers.Add(1)
 		go m.readAdminMessages(sessionId, as)
 	}
 }
 
 func (m *WSManager) readNodeMessages(nodeId int64, ns *NodeSession) {
+	defer m.readers.Done()
 	defer func() {
 		// Only update offline if this is still the current session
 		if current, ok := m.nodeSessions.Load(nodeId); ok {
 			if current.(*NodeSession) == ns {
 				m.nodeSessions.Delete(nodeId)
 				m.nodeSystemInfo.Delete(nodeId)
-				if m.OnNodeOffline != nil {
+				// The node may already have reconnected to another replica
+				ownedElsewhere := Cluster != nil && Cluster.UnregisterNode(nodeId)
+				if m.OnNodeOffline != nil && !ownedElsewhere {
 					m.OnNodeOffline(nodeId)
 				}
 			}
@@ -242,90 +349,195 @@ func (m *WSManager) readNodeMessages(nodeId int64, ns *NodeSession) {
 		}
 
 		payload := string(message)
-		decrypted := m.decryptIfNeeded(payload, ns.Secret)
-
-		if containsStr(decrypted, "memory_usage") {
-			m.sendToNode(ns, `{"type":"call"}`)
-
-			// Cache latest system info for REST API access
-			var sysInfo struct {
-				Uptime           uint64  `json:"uptime"`
-				CPUUsage         float64 `json:"cpu_usage"`
-				MemoryUsage      float64 `json:"memory_usage"`
-				BytesReceived    uint64  `json:"bytes_received"`
-				BytesTransmitted uint64  `json:"bytes_transmitted"`
-				XrayRunning      bool    `json:"v_running"`
-				XrayVersion      string  `json:"v_version"`
-				PanelAddr        string  `json:"panel_addr"`
-				Runtime          string  `json:"runtime"`
-				Interfaces       []struct {
-					Name string   `json:"name"`
-					IPs  []string `json:"ips"`
-				} `json:"interfaces"`
+		var decrypted string
+		if ns.session != nil {
+			if decrypted, err = ns.session.open(payload); err != nil {
+				log.Printf("节点 %d 消息被拒绝: %v", nodeId, err)
+				continue
 			}
-			if json.Unmarshal([]byte(decrypted), &sysInfo) == nil {
-				info := &NodeSystemInfo{
-					Uptime:           sysInfo.Uptime,
-					CPUUsage:         sysInfo.CPUUsage,
-					MemoryUsage:      sysInfo.MemoryUsage,
-					BytesReceived:    sysInfo.BytesReceived,
-					BytesTransmitted: sysInfo.BytesTransmitted,
-					XrayRunning:      sysInfo.XrayRunning,
-					XrayVersion:      sysInfo.XrayVersion,
-					PanelAddr:        sysInfo.PanelAddr,
-					Runtime:          sysInfo.Runtime,
-				}
-				for _, iface := range sysInfo.Interfaces {
-					info.Interfaces = append(info.Interfaces, NetInterface{
-						Name: iface.Name,
-						IPs:  iface.IPs,
-					})
-				}
-				m.nodeSystemInfo.Store(nodeId, info)
+		} else {
+			decrypted = m.decryptIfNeeded(payload, ns.Secret)
+		}
+
+		var header MessageHeader
+		json.Unmarshal([]byte(decrypted), &header)
+		kind := header.Kind
+		if kind == "" {
+			// Nodes without the envelope: classify by content
+			switch {
+			case containsStr(decrypted, "memory_usage"):
+				kind = MsgKindMetrics
+			case containsStr(decrypted, "requestId"):
+				kind = MsgKindResponse
+			default:
+				kind = MsgKindEvent
 			}
+		}
 
-			// Broadcast system info to admin sessions
-			broadcastMsg := map[string]interface{}{
-				"id":   strconv.FormatInt(nodeId, 10),
-				"type": "info",
-				"data": decrypted,
+		switch kind {
+		case MsgKindHello:
+			caps, err := parseNodeHello(decrypted)
+			if err != nil {
+				log.Printf("节点 %d hello 无效: %v", nodeId, err)
+				continue
 			}
-			broadcastJSON, _ := json.Marshal(broadcastMsg)
-			m.broadcastToAdmins(string(broadcastJSON))
-		} else if containsStr(decrypted, "requestId") {
+			ns.caps.Store(caps)
+			log.Printf("节点 %d 协议版本 %d，支持 %d 个命令", nodeId, caps.Protocol, len(caps.Commands))
+		case MsgKindMetrics:
+			m.handleNodeMetrics(nodeId, ns, decrypted)
+		case MsgKindResponse:
 			log.Printf("收到消息: %s", decrypted)
-			var resp WSResponse
-			if err := json.Unmarshal([]byte(decrypted), &resp); err == nil && resp.RequestId != "" {
-				if ch, ok := m.pendingRequests.LoadAndDelete(resp.RequestId); ok {
-					result := &dto.GostResponse{
-						Msg: resp.Message,
-					}
-					if result.Msg == "" {
-						result.Msg = "OK"
-					}
-					if resp.Data != nil {
-						var dataMap interface{}
-						json.Unmarshal(resp.Data, &dataMap)
-						result.Data = dataMap
-					}
-					ch.(chan *dto.GostResponse) <- result
-				}
+			if !m.handleNodeResponse(decrypted) {
+				m.broadcastNodeInfo(nodeId, decrypted)
 			}
-		} else {
+		default:
 			log.Printf("收到消息: %s", decrypted)
-			// Broadcast to admin sessions
-			broadcastMsg := map[string]interface{}{
-				"id":   strconv.FormatInt(nodeId, 10),
-				"type": "info",
-				"data": decrypted,
+			m.broadcastNodeInfo(nodeId, decrypted)
+		}
+	}
+}
+
+func (m *WSManager) handleNodeMetrics(nodeId int64, ns *NodeSession, decrypted string) {
+	m.sendToNode(ns, `{"type":"call"}`)
+
+	// Cache latest system info for REST API access
+	var sysInfo struct {
+		Uptime           uint64     `json:"uptime"`
+		CPUUsage         float64    `json:"cpu_usage"`
+		MemoryUsage      float64    `json:"memory_usage"`
+		BytesReceived    uint64     `json:"bytes_received"`
+		BytesTransmitted uint64     `json:"bytes_transmitted"`
+		XrayRunning      bool       `json:"v_running"`
+		XrayVersion      string     `json:"v_version"`
+		PanelAddr        string     `json:"panel_addr"`
+		Runtime          string     `json:"runtime"`
+		DiskUsage        float64    `json:"disk_usage"`
+		Load1            float64    `json:"load1"`
+		Load5            float64    `json:"load5"`
+		Load15           float64    `json:"load15"`
+		TcpEstablished   int        `json:"tcp_established"`
+		Disks            []NodeDisk `json:"disks"`
+		NetCounters      []struct {
+			Name    string `json:"name"`
+			RxBytes uint64 `json:"rx_bytes"`
+			TxBytes uint64 `json:"tx_bytes"`
+		} `json:"net_counters"`
+		TcpStates      map[string]int    `json:"tcp_states"`
+		UdpSockets     int               `json:"udp_sockets"`
+		ConntrackCount uint64            `json:"conntrack_count"`
+		ConntrackMax   uint64            `json:"conntrack_max"`
+		Kernel         string            `json:"kernel"`
+		OS             string            `json:"os"`
+		Arch           string            `json:"arch"`
+		GostConns      uint64            `json:"gost_conns"`
+		ServiceConns   map[string]uint64 `json:"service_conns"`
+		Interfaces     []struct {
+			Name string   `json:"name"`
+			IPs  []string `json:"ips"`
+		} `json:"interfaces"`
+	}
+	if json.Unmarshal([]byte(decrypted), &sysInfo) == nil {
+		now := time.Now()
+		info := &NodeSystemInfo{
+			Uptime:           sysInfo.Uptime,
+			CPUUsage:         sysInfo.CPUUsage,
+			MemoryUsage:      sysInfo.MemoryUsage,
+			BytesReceived:    sysInfo.BytesReceived,
+			BytesTransmitted: sysInfo.BytesTransmitted,
+			XrayRunning:      sysInfo.XrayRunning,
+			XrayVersion:      sysInfo.XrayVersion,
+			PanelAddr:        sysInfo.PanelAddr,
+			Runtime:          sysInfo.Runtime,
+			DiskUsage:        sysInfo.DiskUsage,
+			Load1:            sysInfo.Load1,
+			Load5:            sysInfo.Load5,
+			Load15:           sysInfo.Load15,
+			TcpEstablished:   sysInfo.TcpEstablished,
+			Disks:            sysInfo.Disks,
+			TcpStates:        sysInfo.TcpStates,
+			UdpSockets:       sysInfo.UdpSockets,
+			ConntrackCount:   sysInfo.ConntrackCount,
+			ConntrackMax:     sysInfo.ConntrackMax,
+			Kernel:           sysInfo.Kernel,
+			OS:               sysInfo.OS,
+			Arch:             sysInfo.Arch,
+			GostConns:        sysInfo.GostConns,
+			ServiceConns:     sysInfo.ServiceConns,
+			ReportedAt:       now.UnixMilli(),
+			Capabilities:     ns.caps.Load(),
+		}
+		for _, iface := range sysInfo.Interfaces {
+			info.Interfaces = append(info.Interfaces, NetInterface{
+				Name: iface.Name,
+				IPs:  iface.IPs,
+			})
+		}
+
+		// Per-interface rates from the previous report's counters
+		prev := map[string]NodeNetStat{}
+		var elapsed float64
+		if val, ok := m.nodeSystemInfo.Load(nodeId); ok {
+			p := val.(*NodeSystemInfo)
+			for _, s := range p.NetStats {
+				prev[s.Name] = s
+			}
+			elapsed = float64(info.ReportedAt-p.ReportedAt) / 1000
+		}
+		for _, c := range sysInfo.NetCounters {
+			stat := NodeNetStat{Name: c.Name, RxBytes: c.RxBytes, TxBytes: c.TxBytes}
+			if p, ok := prev[c.Name]; ok && elapsed > 0 && c.RxBytes >= p.RxBytes && c.TxBytes >= p.TxBytes {
+				stat.RxRate = int64(float64(c.RxBytes-p.RxBytes) / elapsed)
+				stat.TxRate = int64(float64(c.TxBytes-p.TxBytes) / elapsed)
 			}
-			broadcastJSON, _ := json.Marshal(broadcastMsg)
-			m.broadcastToAdmins(string(broadcastJSON))
+			info.NetStats = append(info.NetStats, stat)
+		}
+
+		m.nodeSystemInfo.Store(nodeId, info)
+		if m.OnNodeMetrics != nil {
+			m.OnNodeMetrics(nodeId, info, now)
+		}
+	}
+
+	// Broadcast system info to admin sessions
+	m.broadcastNodeInfo(nodeId, decrypted)
+}
+
+// handleNodeResponse hands a command response to its waiting sender.
+// Returns false for responses that don't answer a request.
+func (m *WSManager) handleNodeResponse(decrypted string) bool {
+	var resp WSResponse
+	if err := json.Unmarshal([]byte(decrypted), &resp); err != nil || resp.RequestId == "" {
+		return false
+	}
+	if ch, ok := m.pendingRequests.LoadAndDelete(resp.RequestId); ok {
+		result := &dto.GostResponse{
+			Msg: resp.Message,
+		}
+		if result.Msg == "" {
+			result.Msg = "OK"
 		}
+		if resp.Data != nil {
+			var dataMap interface{}
+			json.Unmarshal(resp.Data, &dataMap)
+			result.Data = dataMap
+		}
+		ch.(chan *dto.GostResponse) <- result
+	}
+	return true
+}
+
+func (m *WSManager) broadcastNodeInfo(nodeId int64, data string) {
+	broadcastMsg := map[string]interface{}{
+		"id":   strconv.FormatInt(nodeId, 10),
+		"type": "info",
+		"data": data,
 	}
+	broadcastJSON, _ := json.Marshal(broadcastMsg)
+	m.broadcastToAdmins(string(broadcastJSON))
 }
 
 func (m *WSManager) readAdminMessages(sessionId string, as *AdminSession) {
+	defer m.readers.Done()
 	defer func() {
 		m.adminSessions.Delete(sessionId)
 		as.Conn.Close()
@@ -343,21 +555,40 @@ func (m *WSManager) SendMsg(nodeId int64, data interface{}, cmdType string) *dto
 	return m.SendMsgWithTimeout(nodeId, data, cmdType, 10*time.Second)
 }
 
+// SendMsgWithTimeout sends a command to the node, forwarding it to the
+// replica that holds the node's connection when it is not connected here.
 func (m *WSManager) SendMsgWithTimeout(nodeId int64, data interface{}, cmdType string, timeout time.Duration) *dto.GostResponse {
+	if !m.isLocalNode(nodeId) && Cluster != nil {
+		return Cluster.Forward(nodeId, data, cmdType, timeout)
+	}
+	return m.SendLocalMsgWithTimeout(nodeId, data, cmdType, timeout)
+}
+
+// SendLocalMsgWithTimeout sends a command only over a connection held by
+// this replica.
+func (m *WSManager) SendLocalMsgWithTimeout(nodeId int64, data interface{}, cmdType string, timeout time.Duration) *dto.GostResponse {
+	if m.closing.Load() {
+		return &dto.GostResponse{Msg: "服务正在关闭"}
+	}
 	val, ok := m.nodeSessions.Load(nodeId)
 	if !ok {
 		return &dto.GostResponse{Msg: "节点不在线"}
 	}
 	ns := val.(*NodeSession)
 
+	if caps := ns.caps.Load(); caps != nil && !caps.Supports(cmdType) {
+		return &dto.GostResponse{Msg: unsupportedCommand(cmdType, caps)}
+	}
+
 	requestId := generateUUID()
 	ch := make(chan *dto.GostResponse, 1)
 	m.pendingRequests.Store(requestId, ch)
 
 	cmd := WSCommand{
-		Type:      cmdType,
-		Data:      data,
-		RequestId: requestId,
+		MessageHeader: MessageHeader{Kind: MsgKindCommand, V: ProtocolVersion},
+		Type:          cmdType,
+		Data:          data,
+		RequestId:     requestId,
 	}
 	cmdJSON, _ := json.Marshal(cmd)
 
@@ -379,7 +610,14 @@ func (m *WSManager) sendToNode(ns *NodeSession, message string) {
 	defer ns.mu.Unlock()
 
 	finalMsg := message
-	if ns.Secret != "" {
+	if ns.session != nil {
+		sealed, err := ns.session.seal(message)
+		if err != nil {
+			log.Printf("加密WebSocket消息失败: %v", err)
+			return
+		}
+		finalMsg = sealed
+	} else if ns.Secret != "" {
 		crypto := GetOrCreateCrypto(ns.Secret)
 		if crypto != nil {
 			encrypted, err := crypto.Encrypt(message)
@@ -401,6 +639,14 @@ func (m *WSManager) sendToNode(ns *NodeSession, message string) {
 }
 
 func (m *WSManager) broadcastToAdmins(message string) {
+	m.BroadcastLocal(message)
+	if Cluster != nil {
+		Cluster.Publish(message)
+	}
+}
+
+// BroadcastLocal delivers a message to admin sessions on this replica only.
+func (m *WSManager) BroadcastLocal(message string) {
 	m.adminSessions.Range(func(key, value interface{}) bool {
 		as := value.(*AdminSession)
 		as.mu.Lock()
@@ -414,11 +660,97 @@ func (m *WSManager) BroadcastMessage(message string) {
 	m.broadcastToAdmins(message)
 }
 
+// GetNodeCapabilities returns what the node advertised in its hello, or
+// nil if it hasn't sent one (older nodes never do).
+func (m *WSManager) GetNodeCapabilities(nodeId int64) *NodeCapabilities {
+	if val, ok := m.nodeSessions.Load(nodeId); ok {
+		return val.(*NodeSession).caps.Load()
+	}
+	if info := m.GetNodeSystemInfo(nodeId); info != nil {
+		return info.Capabilities
+	}
+	return nil
+}
+
+// NodeSupports reports whether a node handles cmdType. Nodes that never
+// advertised their commands are assumed to.
+func (m *WSManager) NodeSupports(nodeId int64, cmdType string) bool {
+	caps := m.GetNodeCapabilities(nodeId)
+	return caps == nil || caps.Supports(cmdType)
+}
+
 func (m *WSManager) IsNodeOnline(nodeId int64) bool {
+	if m.isLocalNode(nodeId) {
+		return true
+	}
+	return Cluster != nil && Cluster.IsRemoteNodeOnline(nodeId)
+}
+
+func (m *WSManager) isLocalNode(nodeId int64) bool {
 	_, ok := m.nodeSessions.Load(nodeId)
 	return ok
 }
 
+// Shutdown stops accepting connections and commands, waits for requests
+// already sent to nodes to be answered, then closes every node and admin
+// connection with a close frame. It returns once all read loops have exited
+// or ctx expires.
+func (m *WSManager) Shutdown(ctx context.Context) {
+	m.closing.Store(true)
+
+	// Give commands already on the wire a chance to complete
+	for m.pendingCount() > 0 {
+		if !Sleep(ctx, 100*time.Millisecond) {
+			break
+		}
+	}
+	m.pendingRequests.Range(func(key, value interface{}) bool {
+		if ch, ok := m.pendingRequests.LoadAndDelete(key); ok {
+			ch.(chan *dto.GostResponse) <- &dto.GostResponse{Msg: "服务正在关闭"}
+		}
+		return true
+	})
+
+	closeMsg := websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutdown")
+	deadline := time.Now().Add(time.Second)
+	m.nodeSessions.Range(func(key, value interface{}) bool {
+		ns := value.(*NodeSession)
+		ns.mu.Lock()
+		ns.Conn.WriteControl(websocket.CloseMessage, closeMsg, deadline)
+		ns.mu.Unlock()
+		ns.Conn.Close()
+		return true
+	})
+	m.adminSessions.Range(func(key, value interface{}) bool {
+		as := value.(*AdminSession)
+		as.mu.Lock()
+		as.Conn.WriteControl(websocket.CloseMessage, closeMsg, deadline)
+		as.mu.Unlock()
+		as.Conn.Close()
+		return true
+	})
+
+	done := make(chan struct{})
+	go func() {
+		m.readers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		log.Println("等待 WebSocket 连接关闭超时")
+	}
+}
+
+func (m *WSManager) pendingCount() int {
+	n := 0
+	m.pendingRequests.Range(func(key, value interface{}) bool {
+		n++
+		return true
+	})
+	return n
+}
+
 func (m *WSManager) decryptIfNeeded(payload, secret string) string {
 	if secret == "" {
 		return payload
@@ -61,6 +61,34 @@ func XrayRemoveClient(nodeId int64, inboundTag, email string) *dto.GostResponse
 	return WS.SendMsg(nodeId, data, "VRemoveClient")
 }
 
+// XrayAddClients adds many clients to one inbound in a single command.
+func XrayAddClients(nodeId int64, inboundTag, protocol string, clients []model.XrayClient) *dto.GostResponse {
+	arr := make([]map[string]interface{}, 0, len(clients))
+	for _, c := range clients {
+		arr = append(arr, map[string]interface{}{
+			"email":          c.Email,
+			"uuidOrPassword": c.UuidOrPassword,
+			"flow":           c.Flow,
+			"alterId":        c.AlterId,
+		})
+	}
+	data := map[string]interface{}{
+		"inboundTag": inboundTag,
+		"protocol":   protocol,
+		"clients":    arr,
+	}
+	return WS.SendMsg(nodeId, data, "VAddClients")
+}
+
+// XrayRemoveClients removes many clients from one inbound in a single command.
+func XrayRemoveClients(nodeId int64, inboundTag string, emails []string) *dto.GostResponse {
+	data := map[string]interface{}{
+		"inboundTag": inboundTag,
+		"emails":     emails,
+	}
+	return WS.SendMsg(nodeId, data, "VRemoveClients")
+}
+
 func XrayGetTraffic(nodeId int64) *dto.GostResponse {
 	data := map[string]interface{}{
 		"reset": true,
@@ -68,7 +96,27 @@ func XrayGetTraffic(nodeId int64) *dto.GostResponse {
 	return WS.SendMsg(nodeId, data, "VGetTraffic")
 }
 
-func XrayApplyConfig(nodeId int64, inbounds []model.XrayInbound) *dto.GostResponse {
+// XrayApplyConfig rewrites the node's Xray config. routing carries the
+// node's extra outbounds and routing rules; nil keeps the node's defaults.
+func XrayApplyConfig(nodeId int64, inbounds []model.XrayInbound, routing interface{}) *dto.GostResponse {
+	data := map[string]interface{}{
+		"inbounds": inboundPayloads(inbounds),
+	}
+	if routing != nil {
+		data["routing"] = routing
+	}
+	return WS.SendMsg(nodeId, data, "VApplyConfig")
+}
+
+// XrayValidateConfig asks the node to test inbounds without applying them.
+func XrayValidateConfig(nodeId int64, inbounds []model.XrayInbound) *dto.GostResponse {
+	data := map[string]interface{}{
+		"inbounds": inboundPayloads(inbounds),
+	}
+	return WS.SendMsg(nodeId, data, "VValidateConfig")
+}
+
+func inboundPayloads(inbounds []model.XrayInbound) []map[string]interface{} {
 	var arr []map[string]interface{}
 	for _, ib := range inbounds {
 		arr = append(arr, map[string]interface{}{
@@ -81,10 +129,7 @@ func XrayApplyConfig(nodeId int64, inbounds []model.XrayInbound) *dto.GostRespon
 			"sniffingJson":       ib.SniffingJson,
 		})
 	}
-	data := map[string]interface{}{
-		"inbounds": arr,
-	}
-	return WS.SendMsg(nodeId, data, "VApplyConfig")
+	return arr
 }
 
 func XraySwitchVersion(nodeId int64, version string) *dto.GostResponse {
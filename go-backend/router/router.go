@@ -35,6 +35,7 @@ func Setup(r *gin.Engine) {
 	r.POST("/flow/test", handler.FlowTest)
 	r.POST("/flow/su", handler.FlowXrayUpload)
 	r.POST("/flow/xray-upload", handler.FlowXrayUpload) // backward compat
+	r.POST("/flow/ips", handler.FlowXrayIPs)
 
 	// Node install (legacy routes — kept for backward compatibility)
 	r.GET("/node-install/script", handler.NodeInstallScript)
@@ -47,6 +48,14 @@ func Setup(r *gin.Engine) {
 	r.GET("/s/:secret/b/:arch", handler.CamoInstallBinary)
 	r.GET("/s/:secret/x/:arch", handler.CamoInstallXray)
 
+	// Node self-update (signed request = auth)
+	r.GET("/u/m/:arch", handler.NodeReleaseManifest)
+	r.GET("/u/b/:arch", handler.NodeReleaseBinary)
+
+	// Node enrollment (join token in path = auth)
+	r.GET("/j/:token", middleware.JoinRateLimit(), handler.JoinBootstrapScript)
+	r.POST("/j/:token/enroll", middleware.JoinRateLimit(), handler.JoinEnroll)
+
 	// Subscription (token in path)
 	r.GET("/api/v1/v/sub/:token", handler.XraySubscription)
 	r.GET("/api/v1/xray/sub/:token", handler.XraySubscription) // backward compat
@@ -62,6 +71,15 @@ func Setup(r *gin.Engine) {
 		pkg.WS.HandleConnection(c.Writer, c.Request)
 	})
 
+	// Replica-to-replica calls (shared secret)
+	cluster := r.Group("/internal/cluster")
+	cluster.Use(middleware.ClusterAuth())
+	{
+		cluster.POST("/send", handler.ClusterSend)
+		cluster.POST("/broadcast", handler.ClusterBroadcast)
+		cluster.POST("/sysinfo", handler.ClusterSysInfo)
+	}
+
 	// ─── Authenticated routes ───
 
 	auth := r.Group("/api/v1")
@@ -86,6 +104,20 @@ func Setup(r *gin.Engine) {
 		auth.POST("/node/install/docker", middleware.Admin(), handler.NodeInstallDocker)
 		auth.POST("/node/reconcile", middleware.Admin(), handler.NodeReconcile)
 		auth.POST("/node/update-binary", middleware.Admin(), handler.NodeUpdateBinary)
+		auth.POST("/node/rollback-binary", middleware.Admin(), handler.NodeRollbackBinary)
+		auth.POST("/node/rotate-secret", middleware.Admin(), handler.NodeRotateSecret)
+		auth.POST("/node/release-info", middleware.Admin(), handler.NodeReleaseInfo)
+		auth.POST("/node/rollout/create", middleware.Admin(), handler.NodeRolloutCreate)
+		auth.POST("/node/rollout/list", middleware.Admin(), handler.NodeRolloutList)
+		auth.POST("/node/rollout/detail", middleware.Admin(), handler.NodeRolloutDetail)
+		auth.POST("/node/rollout/cancel", middleware.Admin(), handler.NodeRolloutCancel)
+		auth.POST("/node/rollout/resume", middleware.Admin(), handler.NodeRolloutResume)
+		auth.POST("/node/rollout/rollback", middleware.Admin(), handler.NodeRolloutRollback)
+		auth.POST("/node/approve", middleware.Admin(), handler.NodeApprove)
+		auth.POST("/node/join-token/create", middleware.Admin(), handler.NodeJoinTokenCreate)
+		auth.POST("/node/join-token/list", middleware.Admin(), handler.NodeJoinTokenList)
+		auth.POST("/node/join-token/update", middleware.Admin(), handler.NodeJoinTokenUpdate)
+		auth.POST("/node/join-token/delete", middleware.Admin(), handler.NodeJoinTokenDelete)
 		auth.POST("/node/update-order", middleware.Admin(), handler.NodeUpdateOrder)
 		auth.POST("/node/set-protocol", middleware.Admin(), handler.NodeSetProtocol)
 
@@ -140,7 +172,14 @@ func Setup(r *gin.Engine) {
 		auth.POST("/v/client/update", handler.XrayClientUpdate)
 		auth.POST("/v/client/delete", handler.XrayClientDelete)
 		auth.POST("/v/client/reset-traffic", handler.XrayClientResetTraffic)
+		auth.POST("/v/client/online-ips", handler.XrayClientOnlineIPs)
 		auth.POST("/v/client/link", handler.XrayClientLink)
+		auth.POST("/v/client/bulk-create", handler.XrayClientBulkCreate)
+		auth.POST("/v/client/bulk-enable", handler.XrayClientBulkEnable)
+		auth.POST("/v/client/bulk-disable", handler.XrayClientBulkDisable)
+		auth.POST("/v/client/bulk-delete", handler.XrayClientBulkDelete)
+		auth.POST("/v/client/bulk-reset-traffic", handler.XrayClientBulkResetTraffic)
+		auth.POST("/v/client/bulk-move", handler.XrayClientBulkMove)
 
 		// Proxy Cert (permission checked in service layer)
 		auth.POST("/v/cert/create", handler.XrayCertCreate)
@@ -156,20 +195,55 @@ func Setup(r *gin.Engine) {
 		auth.POST("/v/node/status", middleware.Admin(), handler.XrayNodeStatus)
 		auth.POST("/v/node/switch-version", middleware.Admin(), handler.XrayNodeSwitchVersion)
 		auth.GET("/v/node/versions", middleware.Admin(), handler.XrayNodeVersions)
+		auth.POST("/v/node/singbox/start", middleware.Admin(), handler.SingboxNodeStart)
+		auth.POST("/v/node/singbox/stop", middleware.Admin(), handler.SingboxNodeStop)
+		auth.POST("/v/node/singbox/restart", middleware.Admin(), handler.SingboxNodeRestart)
+		auth.POST("/v/node/singbox/status", middleware.Admin(), handler.SingboxNodeStatus)
+		auth.POST("/v/node/singbox/switch-version", middleware.Admin(), handler.SingboxNodeSwitchVersion)
+
+		// Xray outbounds & routing (admin)
+		auth.POST("/v/routing/outbound/list", middleware.Admin(), handler.XrayOutboundList)
+		auth.POST("/v/routing/outbound/create", middleware.Admin(), handler.XrayOutboundCreate)
+		auth.POST("/v/routing/outbound/update", middleware.Admin(), handler.XrayOutboundUpdate)
+		auth.POST("/v/routing/outbound/delete", middleware.Admin(), handler.XrayOutboundDelete)
+		auth.POST("/v/routing/rule/list", middleware.Admin(), handler.XrayRoutingRuleList)
+		auth.POST("/v/routing/rule/create", middleware.Admin(), handler.XrayRoutingRuleCreate)
+		auth.POST("/v/routing/rule/update", middleware.Admin(), handler.XrayRoutingRuleUpdate)
+		auth.POST("/v/routing/rule/delete", middleware.Admin(), handler.XrayRoutingRuleDelete)
+		auth.POST("/v/routing/preview", middleware.Admin(), handler.XrayRoutingPreview)
 
 		// Subscription
 		auth.POST("/v/sub/token", handler.XraySubToken)
 		auth.POST("/v/sub/links", handler.XraySubLinks)
 		auth.POST("/v/sub/reset", handler.XraySubReset)
+		auth.POST("/v/sub/profile/list", handler.SubProfileList)
+		auth.POST("/v/sub/profile/create", middleware.Admin(), handler.SubProfileCreate)
+		auth.POST("/v/sub/profile/update", middleware.Admin(), handler.SubProfileUpdate)
+		auth.POST("/v/sub/profile/delete", middleware.Admin(), handler.SubProfileDelete)
+		auth.POST("/v/sub/tokens/list", handler.SubTokenList)
+		auth.POST("/v/sub/tokens/create", handler.SubTokenCreate)
+		auth.POST("/v/sub/tokens/update", handler.SubTokenUpdate)
+		auth.POST("/v/sub/tokens/reset", handler.SubTokenReset)
+		auth.POST("/v/sub/tokens/delete", handler.SubTokenDelete)
 
 		// Monitor
 		auth.POST("/monitor/node-health", middleware.Admin(), handler.MonitorNodeHealth)
 		auth.POST("/monitor/latency-history", handler.MonitorLatencyHistory)
+		auth.POST("/monitor/node-metrics-history", middleware.Admin(), handler.MonitorNodeMetricsHistory)
+		auth.POST("/monitor/node-matrix", middleware.Admin(), handler.MonitorNodeMatrix)
+		auth.POST("/monitor/node-link-history", middleware.Admin(), handler.MonitorNodeLinkHistory)
+		auth.POST("/monitor/node-link-test", middleware.Admin(), handler.MonitorNodeLinkTest)
 		auth.POST("/monitor/forward-flow", middleware.Admin(), handler.MonitorForwardFlowHistory)
 		auth.POST("/monitor/traffic-overview", middleware.Admin(), handler.MonitorTrafficOverview)
 		auth.POST("/monitor/v-traffic-overview", middleware.Admin(), handler.MonitorXrayTrafficOverview)
 		auth.POST("/monitor/v-inbound-flow", middleware.Admin(), handler.MonitorXrayInboundFlowHistory)
 
+		// Scheduled jobs
+		auth.POST("/schedule/list", middleware.Admin(), handler.ScheduleJobList)
+		auth.POST("/schedule/update", middleware.Admin(), handler.ScheduleJobUpdate)
+		auth.POST("/schedule/run", middleware.Admin(), handler.ScheduleJobRun)
+		auth.POST("/schedule/history", middleware.Admin(), handler.ScheduleJobHistory)
+
 		// Dashboard
 		auth.POST("/dashboard/stats", handler.DashboardStats)
 
@@ -96,6 +96,15 @@ func GetUserDashboardStats(userId int64) dto.R {
 	})
 }
 
+// dashboardLocation is the zone "today" is measured in: the panel timezone
+// for the admin overview (userId 0), otherwise the user's own.
+func dashboardLocation(userId int64) *time.Location {
+	if userId == 0 {
+		return PanelLocation()
+	}
+	return userLocationById(userId)
+}
+
 // trafficData holds both 24h traffic history and today's total.
 type trafficData struct {
 	history   []map[string]interface{}
@@ -191,11 +200,11 @@ func getTrafficData(userId int64) trafficData {
 		})
 	}
 
-	// Sum today's traffic — use UTC-based "last 24h" to avoid server-timezone dependency
+	// Sum today's traffic since midnight in the viewer's timezone
 	var todayTotal int64
-	cutoff24h := nowTs - 24*3600
+	dayStart := startOfDay(now, dashboardLocation(userId)).Unix()
 	for bt, flow := range bucketFlow {
-		if bt >= cutoff24h {
+		if bt >= dayStart {
 			todayTotal += flow
 		}
 	}
@@ -289,9 +298,9 @@ func getXrayTrafficData(userId int64) trafficData {
 	}
 
 	var todayTotal int64
-	cutoff24h := nowTs - 24*3600
+	dayStart := startOfDay(now, dashboardLocation(userId)).Unix()
 	for bt, flow := range bucketFlow {
-		if bt >= cutoff24h {
+		if bt >= dayStart {
 			todayTotal += flow
 		}
 	}
@@ -380,14 +389,14 @@ func getUserFlowData(userId int64) (gostData trafficData, xrayData trafficData)
 	}
 
 	var gostTotal, xrayTotal int64
-	cutoff24h := nowTs - 24*3600
+	dayStart := startOfDay(now, userLocationById(userId)).Unix()
 	for bt, flow := range gostBucketFlow {
-		if bt >= cutoff24h {
+		if bt >= dayStart {
 			gostTotal += flow
 		}
 	}
 	for bt, flow := range xrayBucketFlow {
-		if bt >= cutoff24h {
+		if bt >= dayStart {
 			xrayTotal += flow
 		}
 	}
@@ -396,10 +405,10 @@ func getUserFlowData(userId int64) (gostData trafficData, xrayData trafficData)
 		trafficData{history: xrayHistory, todayFlow: xrayTotal}
 }
 
-// getMonthlyTraffic returns this calendar month's GOST + Xray traffic.
+// getMonthlyTraffic returns this calendar month's GOST + Xray traffic, with
+// the month boundary taken in the panel timezone.
 func getMonthlyTraffic() (gostMonthly int64, xrayMonthly int64) {
-	now := time.Now()
-	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location()).Unix()
+	monthStart := startOfMonth(time.Now(), PanelLocation()).Unix()
 	cutoff := monthStart - 3600 // one extra hour for delta base
 
 	// GOST monthly traffic
@@ -535,8 +544,7 @@ func getMonthlyTraffic() (gostMonthly int64, xrayMonthly int64) {
 
 // getNodeTrafficRanking returns per-node monthly traffic ranking.
 func getNodeTrafficRanking() []map[string]interface{} {
-	now := time.Now()
-	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location()).Unix()
+	monthStart := startOfMonth(time.Now(), PanelLocation()).Unix()
 	cutoff := monthStart - 3600
 
 	// Build node name map
@@ -765,8 +773,7 @@ func getNodeTrafficRanking() []map[string]interface{} {
 
 // getUserMonthlyTrafficRanking returns top 5 users by this month's traffic using delta computation.
 func getUserMonthlyTrafficRanking() []map[string]interface{} {
-	now := time.Now()
-	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location()).Unix()
+	monthStart := startOfMonth(time.Now(), PanelLocation()).Unix()
 	cutoff := monthStart - 3600
 
 	// Build user name map
@@ -2,6 +2,7 @@ package service
 
 import (
 	"encoding/json"
+	"errors"
 	"flux-panel/go-backend/dto"
 	"flux-panel/go-backend/model"
 	"flux-panel/go-backend/pkg"
@@ -14,63 +15,94 @@ import (
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 const bytesToGB = 1024 * 1024 * 1024
 
-// Lock maps are keyed by entity IDs. Growth is bounded by the number of
-// users/forwards/tunnels in the system (each sync.Mutex is ~8 bytes), so
-// explicit cleanup is unnecessary for expected workloads.
-var (
-	userLocks    sync.Map
-	tunnelLocks  sync.Map
-	forwardLocks sync.Map
-)
+// userLocks serialises per-user limit checks. The map is keyed by user ID;
+// growth is bounded by the number of users (each sync.Mutex is ~8 bytes),
+// so explicit cleanup is unnecessary for expected workloads.
+var userLocks sync.Map
 
 func getUserLock(id string) *sync.Mutex {
 	v, _ := userLocks.LoadOrStore(id, &sync.Mutex{})
 	return v.(*sync.Mutex)
 }
 
-func getTunnelLock(id string) *sync.Mutex {
-	v, _ := tunnelLocks.LoadOrStore(id, &sync.Mutex{})
-	return v.(*sync.Mutex)
-}
-
-func getForwardLock(id string) *sync.Mutex {
-	v, _ := forwardLocks.LoadOrStore(id, &sync.Mutex{})
-	return v.(*sync.Mutex)
-}
+// flowRetry is returned to a node when its report could not be applied.
+// Nothing was written (the report is applied in one transaction), so the
+// node keeps the report spooled and retries it with the same sequence.
+const flowRetry = "retry"
 
 func ProcessFlowUpload(rawData, secret string) string {
 	// Validate node
-	var nodeCount int64
-	DB.Model(&model.Node{}).Where("secret = ?", secret).Count(&nodeCount)
-	if nodeCount == 0 {
-		log.Printf("[GOST流量] 无效的节点密钥: %s", secret)
+	node := FindNodeBySecret(secret)
+	if node == nil {
+		log.Printf("[GOST流量] 无效的节点密钥")
 		return "ok"
 	}
 
 	// Decrypt if needed
 	decrypted := decryptIfNeeded(rawData, secret)
 
-	var flowData dto.FlowDto
-	if err := json.Unmarshal([]byte(decrypted), &flowData); err != nil {
+	// Sequenced batches carry "s"/"e"/"items"; legacy nodes send a single
+	// FlowDto, which decodes here with Seq == 0.
+	var batch dto.FlowBatchDto
+	if err := json.Unmarshal([]byte(decrypted), &batch); err != nil {
 		log.Printf("[GOST流量] JSON解析失败: %v, raw=%s", err, decrypted)
 		return "ok"
 	}
 
-	if flowData.N == "web_api" {
+	if batch.Seq == 0 {
+		var flowData dto.FlowDto
+		json.Unmarshal([]byte(decrypted), &flowData)
+		if flowData.N == "web_api" {
+			return "ok"
+		}
+		log.Printf("[GOST流量] 上报: %+v", flowData)
+		batch.Items = []dto.FlowDto{flowData}
+	} else {
+		log.Printf("[GOST流量] 上报: node=%d epoch=%s seq=%d items=%d", node.ID, batch.Epoch, batch.Seq, len(batch.Items))
+	}
+
+	var updates []gostFlowUpdate
+	for _, item := range batch.Items {
+		if item.N == "web_api" {
+			continue
+		}
+		if f, ok := resolveGostFlow(item); ok {
+			updates = append(updates, f)
+		}
+	}
+
+	applied, err := applySequencedFlow(node.ID, batch.Epoch, batch.Seq, func(tx *gorm.DB) error {
+		for _, f := range updates {
+			if err := applyGostFlow(tx, f); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("[GOST流量] 节点 %d 流量入账失败 (seq=%d): %v", node.ID, batch.Seq, err)
+		return flowRetry
+	}
+	if !applied {
+		log.Printf("[GOST流量] 节点 %d 重复上报已忽略 (epoch=%s seq=%d)", node.ID, batch.Epoch, batch.Seq)
 		return "ok"
 	}
 
-	log.Printf("[GOST流量] 上报: %+v", flowData)
-	return processFlowData(flowData)
+	// Limit checks pause services on nodes, so they run after commit.
+	for _, f := range updates {
+		checkGostFlowLimits(f)
+	}
+	return "ok"
 }
 
 func ProcessFlowConfig(rawData, secret string) string {
-	var node model.Node
-	if err := DB.Where("secret = ?", secret).First(&node).Error; err != nil {
+	node := FindNodeBySecret(secret)
+	if node == nil {
 		return "ok"
 	}
 
@@ -87,83 +119,177 @@ func ProcessFlowConfig(rawData, secret string) string {
 }
 
 func ProcessXrayFlowUpload(rawData, secret string) string {
-	var nodeCount int64
-	DB.Model(&model.Node{}).Where("secret = ?", secret).Count(&nodeCount)
-	if nodeCount == 0 {
-		log.Printf("[Xray流量] 无效的节点密钥: %s", secret)
+	node := FindNodeBySecret(secret)
+	if node == nil {
+		log.Printf("[Xray流量] 无效的节点密钥")
 		return "ok"
 	}
 
 	decrypted := decryptIfNeeded(rawData, secret)
 
-	var data struct {
-		Clients []struct {
-			Email string `json:"email"`
-			U     int64  `json:"u"`
-			D     int64  `json:"d"`
-		} `json:"clients"`
-	}
+	var data dto.FlowBatchDto
 	if err := json.Unmarshal([]byte(decrypted), &data); err != nil {
 		log.Printf("[Xray流量] JSON解析失败: %v, raw=%s", err, decrypted)
 		return "ok"
 	}
 
-	log.Printf("[Xray流量] 上报: %d 个客户端", len(data.Clients))
+	log.Printf("[Xray流量] 上报: node=%d epoch=%s seq=%d, %d 个客户端", node.ID, data.Epoch, data.Seq, len(data.Clients))
 
-	for _, client := range data.Clients {
-		if client.Email == "" || (client.U == 0 && client.D == 0) {
-			continue
+	var touched []model.XrayClient
+	applied, err := applySequencedFlow(node.ID, data.Epoch, data.Seq, func(tx *gorm.DB) error {
+		touched = touched[:0]
+		for _, client := range data.Clients {
+			if client.Email == "" || (client.U == 0 && client.D == 0) {
+				continue
+			}
+
+			var xrayClient model.XrayClient
+			if err := tx.Where("email = ?", client.Email).First(&xrayClient).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					log.Printf("[Xray流量] 客户端 %s 未找到", client.Email)
+					continue
+				}
+				return err
+			}
+
+			// Atomic update xray_client traffic
+			if err := tx.Model(&model.XrayClient{}).Where("id = ?", xrayClient.ID).
+				UpdateColumns(map[string]interface{}{
+					"up_traffic":   gorm.Expr("up_traffic + ?", client.U),
+					"down_traffic": gorm.Expr("down_traffic + ?", client.D),
+				}).Error; err != nil {
+				return err
+			}
+
+			// Update user Xray flow (separate from GOST flow)
+			if err := tx.Model(&model.User{}).Where("id = ?", xrayClient.UserId).
+				UpdateColumns(map[string]interface{}{
+					"xray_in_flow":  gorm.Expr("xray_in_flow + ?", client.D),
+					"xray_out_flow": gorm.Expr("xray_out_flow + ?", client.U),
+				}).Error; err != nil {
+				return err
+			}
+
+			touched = append(touched, xrayClient)
 		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("[Xray流量] 节点 %d 流量入账失败 (seq=%d): %v", node.ID, data.Seq, err)
+		return flowRetry
+	}
+	if !applied {
+		log.Printf("[Xray流量] 节点 %d 重复上报已忽略 (epoch=%s seq=%d)", node.ID, data.Epoch, data.Seq)
+		return "ok"
+	}
 
-		var xrayClient model.XrayClient
-		if err := DB.Where("email = ?", client.Email).First(&xrayClient).Error; err != nil {
-			log.Printf("[Xray流量] 客户端 %s 未找到: %v", client.Email, err)
-			continue
+	checkedUsers := make(map[int64]bool)
+	for _, xrayClient := range touched {
+		// Check user-level Xray flow limit under lock to prevent races
+		if !checkedUsers[xrayClient.UserId] {
+			checkedUsers[xrayClient.UserId] = true
+			lock := getUserLock(fmt.Sprintf("%d", xrayClient.UserId))
+			lock.Lock()
+			checkUserXrayLimits(xrayClient.UserId)
+			lock.Unlock()
 		}
 
-		// Atomic update xray_client traffic
-		DB.Model(&model.XrayClient{}).Where("id = ?", xrayClient.ID).
-			UpdateColumns(map[string]interface{}{
-				"up_traffic":   gorm.Expr("up_traffic + ?", client.U),
-				"down_traffic": gorm.Expr("down_traffic + ?", client.D),
-			})
-
-		// Update user Xray flow (separate from GOST flow) and check limits under lock
-		lock := getUserLock(fmt.Sprintf("%d", xrayClient.UserId))
-		lock.Lock()
-		DB.Model(&model.User{}).Where("id = ?", xrayClient.UserId).
-			UpdateColumns(map[string]interface{}{
-				"xray_in_flow":  gorm.Expr("xray_in_flow + ?", client.D),
-				"xray_out_flow": gorm.Expr("xray_out_flow + ?", client.U),
-			})
-		// Check user-level Xray flow limit (inside lock to prevent race)
-		checkUserXrayLimits(xrayClient.UserId)
-		lock.Unlock()
-
-		// Check client-level traffic limit
-		if xrayClient.TotalTraffic > 0 {
-			var updated model.XrayClient
-			DB.First(&updated, xrayClient.ID)
-			if updated.UpTraffic+updated.DownTraffic >= xrayClient.TotalTraffic && updated.Enable == 1 {
-				DB.Model(&updated).Update("enable", 0)
-				log.Printf("Xray 客户端 %s 流量超限，已禁用", client.Email)
-
-				// Hot-remove from Xray so the client is cut off immediately
-				var inbound model.XrayInbound
-				if err := DB.First(&inbound, xrayClient.InboundId).Error; err == nil {
-					pkg.XrayRemoveClient(inbound.NodeId, inbound.Tag, xrayClient.Email)
-				}
+		checkXrayClientLimit(xrayClient)
+	}
+
+	return "ok"
+}
+
+// applySequencedFlow applies a node's traffic report in a single transaction.
+// For sequenced reports (seq > 0) the node's sequence row is locked first and
+// apply is skipped if the report was already applied; applied reports false
+// in that case. Legacy reports (seq == 0) are applied unconditionally.
+func applySequencedFlow(nodeId int64, epoch string, seq int64, apply func(tx *gorm.DB) error) (bool, error) {
+	applied := false
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if seq > 0 {
+			fresh, err := claimFlowSeq(tx, nodeId, epoch, seq)
+			if err != nil || !fresh {
+				return err
 			}
 		}
+		applied = true
+		return apply(tx)
+	})
+	if err != nil {
+		return false, err
 	}
+	return applied, nil
+}
 
-	return "ok"
+// claimFlowSeq locks the node's sequence row and advances it to seq.
+// It returns false if (epoch, seq) was already applied. A new epoch means the
+// node's spool was recreated, so its numbering starts over; an epoch that was
+// seen before has already been replaced and its reports are dropped.
+func claimFlowSeq(tx *gorm.DB, nodeId int64, epoch string, seq int64) (bool, error) {
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&model.NodeFlowSeq{NodeId: nodeId}).Error; err != nil {
+		return false, err
+	}
+
+	var st model.NodeFlowSeq
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("node_id = ?", nodeId).First(&st).Error; err != nil {
+		return false, err
+	}
+
+	now := time.Now().UnixMilli()
+	if st.Epoch != epoch {
+		fresh, err := recordFlowEpoch(tx, nodeId, epoch, now)
+		if err != nil {
+			return false, err
+		}
+		if !fresh {
+			log.Printf("[流量序号] 节点 %d 丢弃已被替换的批次 %s 的上报 (当前 %s)", nodeId, epoch, st.Epoch)
+			return false, nil
+		}
+		// Rows from before the epoch history existed
+		if st.Epoch != "" {
+			if _, err := recordFlowEpoch(tx, nodeId, st.Epoch, st.UpdatedTime); err != nil {
+				return false, err
+			}
+		}
+	} else if seq <= st.LastSeq {
+		return false, nil
+	} else if seq > st.LastSeq+1 {
+		log.Printf("[流量序号] 节点 %d 序号跳跃: %d -> %d", nodeId, st.LastSeq, seq)
+	}
+
+	err := tx.Model(&model.NodeFlowSeq{}).Where("node_id = ?", nodeId).Updates(map[string]interface{}{
+		"epoch":        epoch,
+		"last_seq":     seq,
+		"updated_time": now,
+	}).Error
+	return err == nil, err
+}
+
+// recordFlowEpoch adds epoch to the node's epoch history, reporting false if
+// it was already there.
+func recordFlowEpoch(tx *gorm.DB, nodeId int64, epoch string, firstSeen int64) (bool, error) {
+	res := tx.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&model.NodeFlowEpoch{NodeId: nodeId, Epoch: epoch, FirstSeen: firstSeen})
+	return res.RowsAffected > 0, res.Error
+}
+
+// gostFlowUpdate is one gost report item resolved to its forward and scaled
+// by the tunnel's traffic ratio and flow type.
+type gostFlowUpdate struct {
+	forwardId    string
+	userId       string
+	userTunnelId string
+	u            int64
+	d            int64
 }
 
-func processFlowData(flowData dto.FlowDto) string {
+func resolveGostFlow(flowData dto.FlowDto) (gostFlowUpdate, bool) {
 	parts := strings.Split(flowData.N, "_")
 	if len(parts) < 3 {
-		return "ok"
+		return gostFlowUpdate{}, false
 	}
 	forwardId := parts[0]
 	userId := parts[1]
@@ -172,7 +298,7 @@ func processFlowData(flowData dto.FlowDto) string {
 	// Get forward and tunnel for flow type and ratio
 	var forward model.Forward
 	if err := DB.First(&forward, forwardId).Error; err != nil {
-		return "ok"
+		return gostFlowUpdate{}, false
 	}
 
 	flowType := 1
@@ -194,46 +320,77 @@ func processFlowData(flowData dto.FlowDto) string {
 	log.Printf("[GOST流量] 处理: fwd=%s user=%s tunnel=%s flowType=%d ratio=%.2f raw(u=%d,d=%d) calc(u=%d,d=%d)",
 		forwardId, userId, userTunnelId, flowType, trafficRatio, flowData.U, flowData.D, u, d)
 
+	return gostFlowUpdate{
+		forwardId:    forwardId,
+		userId:       userId,
+		userTunnelId: userTunnelId,
+		u:            u,
+		d:            d,
+	}, true
+}
+
+func applyGostFlow(tx *gorm.DB, f gostFlowUpdate) error {
 	// Update forward flow
-	fwdLock := getForwardLock(forwardId)
-	fwdLock.Lock()
-	DB.Model(&model.Forward{}).Where("id = ?", forwardId).
+	if err := tx.Model(&model.Forward{}).Where("id = ?", f.forwardId).
 		UpdateColumns(map[string]interface{}{
-			"in_flow":  gorm.Expr("in_flow + ?", d),
-			"out_flow": gorm.Expr("out_flow + ?", u),
-		})
-	fwdLock.Unlock()
+			"in_flow":  gorm.Expr("in_flow + ?", f.d),
+			"out_flow": gorm.Expr("out_flow + ?", f.u),
+		}).Error; err != nil {
+		return err
+	}
 
 	// Update user flow
-	uLock := getUserLock(userId)
-	uLock.Lock()
-	DB.Model(&model.User{}).Where("id = ?", userId).
+	if err := tx.Model(&model.User{}).Where("id = ?", f.userId).
 		UpdateColumns(map[string]interface{}{
-			"in_flow":  gorm.Expr("in_flow + ?", d),
-			"out_flow": gorm.Expr("out_flow + ?", u),
-		})
-	uLock.Unlock()
+			"in_flow":  gorm.Expr("in_flow + ?", f.d),
+			"out_flow": gorm.Expr("out_flow + ?", f.u),
+		}).Error; err != nil {
+		return err
+	}
 
 	// Update user_tunnel flow
-	if userTunnelId != "0" {
-		tLock := getTunnelLock(userTunnelId)
-		tLock.Lock()
-		DB.Model(&model.UserTunnel{}).Where("id = ?", userTunnelId).
+	if f.userTunnelId != "0" {
+		if err := tx.Model(&model.UserTunnel{}).Where("id = ?", f.userTunnelId).
 			UpdateColumns(map[string]interface{}{
-				"in_flow":  gorm.Expr("in_flow + ?", d),
-				"out_flow": gorm.Expr("out_flow + ?", u),
-			})
-		tLock.Unlock()
+				"in_flow":  gorm.Expr("in_flow + ?", f.d),
+				"out_flow": gorm.Expr("out_flow + ?", f.u),
+			}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkGostFlowLimits pauses services whose user or user_tunnel exceeded
+// its quota (non-admin forwards only).
+func checkGostFlowLimits(f gostFlowUpdate) {
+	if f.userTunnelId == "0" {
+		return
 	}
+	serviceName := f.forwardId + "_" + f.userId + "_" + f.userTunnelId
+	checkUserLimits(f.userId, serviceName)
+	checkUserTunnelLimits(f.userTunnelId, serviceName, f.userId)
+}
 
-	// Check limits (non-admin forwards only)
-	serviceName := forwardId + "_" + userId + "_" + userTunnelId
-	if userTunnelId != "0" {
-		checkUserLimits(userId, serviceName)
-		checkUserTunnelLimits(userTunnelId, serviceName, userId)
+// checkXrayClientLimit disables a client that exceeded its own traffic limit.
+func checkXrayClientLimit(xrayClient model.XrayClient) {
+	if xrayClient.TotalTraffic <= 0 {
+		return
 	}
+	var updated model.XrayClient
+	if err := DB.First(&updated, xrayClient.ID).Error; err != nil {
+		return
+	}
+	if updated.UpTraffic+updated.DownTraffic >= xrayClient.TotalTraffic && updated.Enable == 1 {
+		DB.Model(&updated).Update("enable", 0)
+		log.Printf("Xray 客户端 %s 流量超限，已禁用", xrayClient.Email)
 
-	return "ok"
+		// Hot-remove from Xray so the client is cut off immediately
+		var inbound model.XrayInbound
+		if err := DB.First(&inbound, xrayClient.InboundId).Error; err == nil {
+			pkg.XrayRemoveClient(inbound.NodeId, inbound.Tag, xrayClient.Email)
+		}
+	}
 }
 
 func checkUserLimits(userId, serviceName string) {
@@ -360,10 +517,9 @@ func FlowDebug(secret string) map[string]interface{} {
 	result := map[string]interface{}{}
 
 	// 1. Check if secret matches a node
-	var node model.Node
 	if secret == "" {
 		result["node"] = "未提供 secret 参数"
-	} else if err := DB.Where("secret = ?", secret).First(&node).Error; err != nil {
+	} else if node := FindNodeBySecret(secret); node == nil {
 		result["node"] = "secret 无效，未匹配到节点"
 	} else {
 		result["node"] = map[string]interface{}{
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"flux-panel/go-backend/model"
+
+	"gorm.io/gorm"
+)
+
+func TestApplySequencedFlowDeduplicates(t *testing.T) {
+	useTestDB(t, &model.NodeFlowSeq{}, &model.NodeFlowEpoch{})
+
+	var billed int
+	apply := func(tx *gorm.DB) error {
+		billed++
+		return nil
+	}
+	steps := []struct {
+		epoch string
+		seq   int64
+		want  bool
+	}{
+		{"e1", 1, true},
+		{"e1", 1, false}, // retransmission after a lost ack
+		{"e1", 2, true},
+		{"e1", 1, false}, // late duplicate of an older report
+		{"e1", 5, true},  // gaps are accepted
+		{"e2", 1, true},  // spool recreated: numbering starts over
+		{"e2", 1, false},
+		{"e1", 6, false}, // late report from the replaced spool
+		{"e2", 2, true},
+	}
+	for i, s := range steps {
+		got, err := applySequencedFlow(7, s.epoch, s.seq, apply)
+		if err != nil {
+			t.Fatalf("step %d: %v", i, err)
+		}
+		if got != s.want {
+			t.Fatalf("step %d (%s/%d): applied = %v, want %v", i, s.epoch, s.seq, got, s.want)
+		}
+	}
+	if billed != 5 {
+		t.Fatalf("applied %d reports, want 5", billed)
+	}
+}
+
+func TestApplySequencedFlowRollsBackSeqOnError(t *testing.T) {
+	useTestDB(t, &model.NodeFlowSeq{}, &model.NodeFlowEpoch{})
+
+	fail := func(tx *gorm.DB) error { return errors.New("db down") }
+	if _, err := applySequencedFlow(7, "e1", 1, fail); err == nil {
+		t.Fatal("expected the apply error")
+	}
+
+	// The failed report was not billed, so its retry must still be applied.
+	applied, err := applySequencedFlow(7, "e1", 1, func(tx *gorm.DB) error { return nil })
+	if err != nil || !applied {
+		t.Fatalf("retry: applied = %v, err = %v", applied, err)
+	}
+}
+
+func TestApplySequencedFlowLegacyReports(t *testing.T) {
+	useTestDB(t, &model.NodeFlowSeq{}, &model.NodeFlowEpoch{})
+
+	for i := 0; i < 2; i++ {
+		applied, err := applySequencedFlow(7, "", 0, func(tx *gorm.DB) error { return nil })
+		if err != nil || !applied {
+			t.Fatalf("legacy report %d: applied = %v, err = %v", i, applied, err)
+		}
+	}
+}
+
+func TestClaimFlowSeqRemembersEpochFromBeforeHistory(t *testing.T) {
+	useTestDB(t, &model.NodeFlowSeq{}, &model.NodeFlowEpoch{})
+
+	// A sequence row written before epochs were recorded
+	DB.Create(&model.NodeFlowSeq{NodeId: 7, Epoch: "old", LastSeq: 40, UpdatedTime: 1})
+	noop := func(tx *gorm.DB) error { return nil }
+
+	if applied, err := applySequencedFlow(7, "new", 1, noop); err != nil || !applied {
+		t.Fatalf("new epoch: applied = %v, err = %v", applied, err)
+	}
+	if applied, err := applySequencedFlow(7, "old", 41, noop); err != nil || applied {
+		t.Fatalf("replaced epoch: applied = %v, err = %v", applied, err)
+	}
+	var st model.NodeFlowSeq
+	DB.First(&st, "node_id = ?", 7)
+	if st.Epoch != "new" || st.LastSeq != 1 {
+		t.Fatalf("sequence row = %+v", st)
+	}
+}
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"flux-panel/go-backend/dto"
@@ -9,6 +10,7 @@ import (
 	"log"
 	"net"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -34,18 +36,46 @@ type ForwardWithTunnel struct {
 	OutIp      string `json:"outIp" gorm:"column:out_ip"`
 }
 
-// DiagnosisResult holds a single TCP ping diagnosis result
+// DiagnosisResult holds a single TCP ping diagnosis result, plus the
+// traceroute of the same path when the node could run one.
 type DiagnosisResult struct {
-	NodeId      int64   `json:"nodeId"`
-	NodeName    string  `json:"nodeName"`
-	TargetIp    string  `json:"targetIp"`
-	TargetPort  int     `json:"targetPort"`
-	Description string  `json:"description"`
-	Success     bool    `json:"success"`
-	Message     string  `json:"message"`
-	AverageTime float64 `json:"averageTime"`
-	PacketLoss  float64 `json:"packetLoss"`
-	Timestamp   int64   `json:"timestamp"`
+	NodeId      int64        `json:"nodeId"`
+	NodeName    string       `json:"nodeName"`
+	TargetIp    string       `json:"targetIp"`
+	TargetPort  int          `json:"targetPort"`
+	Protocol    string       `json:"protocol"`
+	Description string       `json:"description"`
+	Success     bool         `json:"success"`
+	Message     string       `json:"message"`
+	AverageTime float64      `json:"averageTime"`
+	PacketLoss  float64      `json:"packetLoss"`
+	Timestamp   int64        `json:"timestamp"`
+	Trace       *TraceResult `json:"trace,omitempty"`
+	TraceError  string       `json:"traceError,omitempty"`
+}
+
+// TraceHop is one hop of a node traceroute, with MTR-style statistics.
+type TraceHop struct {
+	TTL      int      `json:"ttl"`
+	IP       string   `json:"ip"`
+	IPs      []string `json:"ips,omitempty"`
+	Sent     int      `json:"sent"`
+	Received int      `json:"received"`
+	Loss     float64  `json:"loss"`
+	Last     float64  `json:"last"`
+	Avg      float64  `json:"avg"`
+	Best     float64  `json:"best"`
+	Worst    float64  `json:"worst"`
+}
+
+// TraceResult is the traceroute reported by a node.
+type TraceResult struct {
+	Host     string     `json:"host"`
+	IP       string     `json:"ip"`
+	Port     int        `json:"port"`
+	Protocol string     `json:"protocol"`
+	Reached  bool       `json:"reached"`
+	Hops     []TraceHop `json:"hops"`
 }
 
 // ---------------------- Public API functions ----------------------
@@ -55,6 +85,9 @@ func CreateForward(d dto.ForwardDto, userId int64, roleId int, userName string)
 	if ssrfErr := validateRemoteAddr(d.RemoteAddr, roleId); ssrfErr != "" {
 		return dto.Err(ssrfErr)
 	}
+	if probeErr := validateUdpProbe(d.UdpProbe, d.UdpProbeData); probeErr != "" {
+		return dto.Err(probeErr)
+	}
 
 	// 1. Get tunnel, check status
 	var tunnel model.Tunnel
@@ -112,6 +145,8 @@ func CreateForward(d dto.ForwardDto, userId int64, roleId int, userName string)
 		Strategy:      d.Strategy,
 		ListenIp:      d.ListenIp,
 		InterfaceName: d.InterfaceName,
+		UdpProbe:      d.UdpProbe,
+		UdpProbeData:  d.UdpProbeData,
 		InPort:        inPort,
 		OutPort:       outPort,
 		Status:        forwardStatusActive,
@@ -186,6 +221,9 @@ func UpdateForward(d dto.ForwardUpdateDto, userId int64, roleId int) dto.R {
 	if ssrfErr := validateRemoteAddr(d.RemoteAddr, roleId); ssrfErr != "" {
 		return dto.Err(ssrfErr)
 	}
+	if probeErr := validateUdpProbe(d.UdpProbe, d.UdpProbeData); probeErr != "" {
+		return dto.Err(probeErr)
+	}
 
 	// 2. Validate forward exists and user has access
 	existForward := validateForwardExists(d.ID, userId, roleId)
@@ -273,6 +311,8 @@ func UpdateForward(d dto.ForwardUpdateDto, userId int64, roleId int) dto.R {
 		Strategy:      d.Strategy,
 		ListenIp:      d.ListenIp,
 		InterfaceName: d.InterfaceName,
+		UdpProbe:      d.UdpProbe,
+		UdpProbeData:  d.UdpProbeData,
 		UpdatedTime:   time.Now().UnixMilli(),
 	}
 
@@ -391,6 +431,8 @@ func UpdateForward(d dto.ForwardUpdateDto, userId int64, roleId int) dto.R {
 		"strategy":       updatedForward.Strategy,
 		"listen_ip":      updatedForward.ListenIp,
 		"interface_name": updatedForward.InterfaceName,
+		"udp_probe":      updatedForward.UdpProbe,
+		"udp_probe_data": updatedForward.UdpProbeData,
 		"in_port":        updatedForward.InPort,
 		"out_port":       updatedForward.OutPort,
 		"status":         updatedForward.Status,
@@ -643,19 +685,20 @@ func DiagnoseForward(id int64, userId int64, roleId int) dto.R {
 		return dto.Err("入口节点不存在")
 	}
 
-	var results []DiagnosisResult
+	// Collect the paths first, then probe them all at once: each path
+	// runs a ping and a traceroute, which take several seconds.
+	var paths []diagnosisPath
 	remoteAddresses := strings.Split(forward.RemoteAddr, ",")
 
 	if tunnel.Type == tunnelTypePortForward {
-		// Port forward: TCP ping from inNode to remote targets
+		// Port forward: inNode -> remote targets
 		for _, addr := range remoteAddresses {
 			targetIp := extractIpFromAddress(addr)
 			targetPort := extractPortFromAddress(addr)
 			if targetIp == "" || targetPort == -1 {
 				return dto.Err("无法解析目标地址: " + addr)
 			}
-			result := performTcpPingDiagnosis(inNode, targetIp, targetPort, "转发->目标")
-			results = append(results, result)
+			paths = append(paths, diagnosisPath{inNode, targetIp, targetPort, "转发->目标", forward})
 		}
 	} else {
 		// Tunnel forward: inNode -> outNode, outNode -> targets
@@ -664,21 +707,19 @@ func DiagnoseForward(id int64, userId int64, roleId int) dto.R {
 			return dto.Err("出口节点不存在")
 		}
 
-		// inNode TCP ping outNode
-		inToOutResult := performTcpPingDiagnosis(inNode, outNode.ServerIp, forward.OutPort, "入口->出口")
-		results = append(results, inToOutResult)
+		// The tunnel between the nodes always runs over TCP
+		paths = append(paths, diagnosisPath{inNode, outNode.ServerIp, forward.OutPort, "入口->出口", nil})
 
-		// outNode TCP ping targets
 		for _, addr := range remoteAddresses {
 			targetIp := extractIpFromAddress(addr)
 			targetPort := extractPortFromAddress(addr)
 			if targetIp == "" || targetPort == -1 {
 				return dto.Err("无法解析目标地址: " + addr)
 			}
-			outToTargetResult := performTcpPingDiagnosis(outNode, targetIp, targetPort, "出口->目标")
-			results = append(results, outToTargetResult)
+			paths = append(paths, diagnosisPath{outNode, targetIp, targetPort, "出口->目标", forward})
 		}
 	}
+	results := diagnosePaths(paths)
 
 	// Build diagnosis report
 	tunnelTypeStr := "端口转发"
@@ -1316,6 +1357,70 @@ func extractPortFromAddress(address string) int {
 	return -1
 }
 
+// ---------------------- Path Diagnosis ----------------------
+
+// traceTimeout covers the node's default of 5 rounds over 30 hops.
+const traceTimeout = 30 * time.Second
+
+type diagnosisPath struct {
+	node        *model.Node
+	targetIp    string
+	port        int
+	description string
+	forward     *model.Forward // probes a target of this forward; nil for node-to-node paths
+}
+
+// diagnosePaths pings and traces every path concurrently, keeping order.
+func diagnosePaths(paths []diagnosisPath) []DiagnosisResult {
+	results := make([]DiagnosisResult, len(paths))
+	var wg sync.WaitGroup
+	for i, p := range paths {
+		wg.Add(1)
+		go func(i int, p diagnosisPath) {
+			defer wg.Done()
+			protocol := "tcp"
+			if p.forward != nil && p.forward.UdpProbe != "" {
+				protocol = "udp"
+			}
+			var trace *TraceResult
+			var traceErr string
+			done := make(chan struct{})
+			go func() {
+				trace, traceErr = performTraceroute(p.node, p.targetIp, p.port, protocol)
+				close(done)
+			}()
+			if protocol == "udp" {
+				results[i] = performUdpProbeDiagnosis(p.node, p.forward, p.targetIp, p.port, p.description)
+			} else {
+				results[i] = performTcpPingDiagnosis(p.node, p.targetIp, p.port, p.description)
+			}
+			<-done
+			results[i].Trace = trace
+			results[i].TraceError = traceErr
+		}(i, p)
+	}
+	wg.Wait()
+	return results
+}
+
+// performTraceroute asks the node for a traceroute to the target. An empty
+// protocol lets the node pick TCP when a port is given, ICMP otherwise.
+func performTraceroute(node *model.Node, targetIp string, port int, protocol string) (*TraceResult, string) {
+	res := pkg.WS.SendMsgWithTimeout(node.ID, map[string]interface{}{
+		"host":     targetIp,
+		"port":     port,
+		"protocol": protocol,
+	}, "Traceroute", traceTimeout)
+	if !isGostSuccess(res) {
+		return nil, gostMsg(res)
+	}
+	var trace TraceResult
+	if err := remarshal(res.Data, &trace); err != nil {
+		return nil, "路由追踪结果无效"
+	}
+	return &trace, ""
+}
+
 // ---------------------- TCP Ping Diagnosis ----------------------
 
 func performTcpPingDiagnosis(node *model.Node, targetIp string, port int, description string) DiagnosisResult {
@@ -1324,6 +1429,7 @@ func performTcpPingDiagnosis(node *model.Node, targetIp string, port int, descri
 		NodeName:    node.Name,
 		TargetIp:    targetIp,
 		TargetPort:  port,
+		Protocol:    "tcp",
 		Description: description,
 		Timestamp:   time.Now().UnixMilli(),
 	}
@@ -1384,3 +1490,87 @@ func performTcpPingDiagnosis(node *model.Node, targetIp string, port int, descri
 
 	return result
 }
+
+// ---------------------- UDP Probe Diagnosis ----------------------
+
+var udpProbePayloads = map[string]bool{"echo": true, "dns": true, "quic": true, "hex": true}
+
+// validateUdpProbe checks a forward's UDP probe settings. An empty probe
+// keeps the forward on TCP checks.
+func validateUdpProbe(probe, data string) string {
+	if probe == "" {
+		return ""
+	}
+	if !udpProbePayloads[probe] {
+		return "不支持的UDP探测载荷"
+	}
+	if len(data) > 2048 {
+		return "UDP探测数据过长"
+	}
+	if probe == "hex" {
+		b, err := hex.DecodeString(strings.Join(strings.Fields(data), ""))
+		if err != nil || len(b) == 0 {
+			return "UDP探测载荷不是有效的十六进制"
+		}
+	}
+	return ""
+}
+
+// ForwardProbeCommand returns the node command that checks one target of
+// the forward: UdpProbe for UDP-primary forwards, TcpPing otherwise. Both
+// answer with success, averageTime and packetLoss.
+func ForwardProbeCommand(forward *model.Forward, targetIp string, port int) (string, map[string]interface{}) {
+	data := map[string]interface{}{
+		"ip":      targetIp,
+		"port":    port,
+		"count":   2,
+		"timeout": 3000,
+	}
+	if forward == nil || forward.UdpProbe == "" {
+		return "TcpPing", data
+	}
+	data["payload"] = forward.UdpProbe
+	data["data"] = forward.UdpProbeData
+	return "UdpProbe", data
+}
+
+func performUdpProbeDiagnosis(node *model.Node, forward *model.Forward, targetIp string, port int, description string) DiagnosisResult {
+	result := DiagnosisResult{
+		NodeId:      node.ID,
+		NodeName:    node.Name,
+		TargetIp:    targetIp,
+		TargetPort:  port,
+		Protocol:    "udp",
+		Description: description,
+		Timestamp:   time.Now().UnixMilli(),
+		AverageTime: -1,
+		PacketLoss:  100,
+	}
+
+	cmd, data := ForwardProbeCommand(forward, targetIp, port)
+	res := pkg.WS.SendMsg(node.ID, data, cmd)
+	if !isGostSuccess(res) {
+		result.Message = gostMsg(res)
+		return result
+	}
+
+	var probe struct {
+		Success      bool    `json:"success"`
+		AverageTime  float64 `json:"averageTime"`
+		PacketLoss   float64 `json:"packetLoss"`
+		ErrorMessage string  `json:"errorMessage"`
+	}
+	if err := remarshal(res.Data, &probe); err != nil {
+		result.Message = "UDP探测结果无效"
+		return result
+	}
+	result.Success = probe.Success
+	if !probe.Success {
+		result.Message = probe.ErrorMessage
+		return result
+	}
+	result.Message = "UDP响应正常"
+	result.AverageTime = probe.AverageTime
+	result.PacketLoss = probe.PacketLoss
+	return result
+}
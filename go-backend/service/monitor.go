@@ -5,9 +5,14 @@ import (
 	"flux-panel/go-backend/model"
 	"flux-panel/go-backend/pkg"
 	"sort"
+	"strconv"
+	"strings"
 	"time"
 )
 
+// Forwards listed per node in the health list, busiest first
+const maxHealthForwardConns = 10
+
 // IsForwardOwnedByUser checks whether a forward belongs to the given user.
 func IsForwardOwnedByUser(forwardId, userId int64) bool {
 	var count int64
@@ -48,6 +53,22 @@ func GetNodeHealthList() dto.R {
 				item["bytesTransmitted"] = sysInfo.BytesTransmitted
 				item["panelAddr"] = sysInfo.PanelAddr
 				item["runtime"] = sysInfo.Runtime
+				item["diskUsage"] = sysInfo.DiskUsage
+				item["disks"] = sysInfo.Disks
+				item["load1"] = sysInfo.Load1
+				item["load5"] = sysInfo.Load5
+				item["load15"] = sysInfo.Load15
+				item["netStats"] = sysInfo.NetStats
+				item["tcpEstablished"] = sysInfo.TcpEstablished
+				item["tcpStates"] = sysInfo.TcpStates
+				item["udpSockets"] = sysInfo.UdpSockets
+				item["conntrackCount"] = sysInfo.ConntrackCount
+				item["conntrackMax"] = sysInfo.ConntrackMax
+				item["kernel"] = sysInfo.Kernel
+				item["os"] = sysInfo.OS
+				item["arch"] = sysInfo.Arch
+				item["gostConns"] = sysInfo.GostConns
+				item["forwardConns"] = forwardConns(sysInfo.ServiceConns)
 			}
 		}
 
@@ -56,6 +77,49 @@ func GetNodeHealthList() dto.R {
 	return dto.Ok(result)
 }
 
+// forwardConns sums a node's per-service connection counts by forward
+// (the first segment of panel-created service names), busiest first.
+func forwardConns(serviceConns map[string]uint64) []map[string]interface{} {
+	byForward := make(map[int64]uint64)
+	for name, n := range serviceConns {
+		parts := strings.Split(name, "_")
+		if len(parts) < 3 {
+			continue // not created by panel
+		}
+		if fid, err := strconv.ParseInt(parts[0], 10, 64); err == nil {
+			byForward[fid] += n
+		}
+	}
+	if len(byForward) == 0 {
+		return []map[string]interface{}{}
+	}
+
+	ids := make([]int64, 0, len(byForward))
+	for fid := range byForward {
+		ids = append(ids, fid)
+	}
+	sort.Slice(ids, func(i, j int) bool { return byForward[ids[i]] > byForward[ids[j]] })
+	if len(ids) > maxHealthForwardConns {
+		ids = ids[:maxHealthForwardConns]
+	}
+
+	var forwards []model.Forward
+	DB.Select("id, name").Where("id IN ?", ids).Find(&forwards)
+	names := make(map[int64]string, len(forwards))
+	for _, f := range forwards {
+		names[f.ID] = f.Name
+	}
+	result := make([]map[string]interface{}, 0, len(ids))
+	for _, fid := range ids {
+		result = append(result, map[string]interface{}{
+			"forwardId": fid,
+			"name":      names[fid],
+			"conns":     byForward[fid],
+		})
+	}
+	return result
+}
+
 // GetForwardLatencyHistory returns latency time-series for a forward.
 func GetForwardLatencyHistory(forwardId int64, hours int) dto.R {
 	if hours <= 0 {
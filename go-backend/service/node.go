@@ -76,6 +76,7 @@ func CreateNode(d dto.NodeDto) dto.R {
 	}
 
 	disguise, xrayDisguise := pickDisguiseNames()
+	secret := pkg.GenerateSecureSecret()
 
 	node := model.Node{
 		Name:             d.Name,
@@ -84,9 +85,11 @@ func CreateNode(d dto.NodeDto) dto.R {
 		ServerIp:         d.ServerIp,
 		PortSta:          d.PortSta,
 		PortEnd:          d.PortEnd,
-		Secret:           pkg.GenerateSecureSecret(),
+		Secret:           secret,
+		SecretKid:        pkg.NodeKeyId(secret),
 		Status:           0,
 		GroupName:        d.GroupName,
+		Region:           strings.TrimSpace(d.Region),
 		CreatedTime:      time.Now().UnixMilli(),
 		UpdatedTime:      time.Now().UnixMilli(),
 		DisguiseName:     disguise,
@@ -134,8 +137,11 @@ func GetAllNodes() dto.R {
 			"status":           status,
 			"inx":              n.Inx,
 			"groupName":        n.GroupName,
+			"region":           n.Region,
 			"disguiseName":     n.DisguiseName,
 			"xrayDisguiseName": n.XrayDisguiseName,
+			"pending":          n.Pending,
+			"joinTokenId":      n.JoinTokenId,
 		}
 
 		// Overlay live system info from WS cache
@@ -150,6 +156,9 @@ func GetAllNodes() dto.R {
 				item["runtime"] = info.Runtime
 				item["panelAddr"] = info.PanelAddr
 				item["vRunning"] = info.XrayRunning
+				if info.Capabilities != nil {
+					item["protocol"] = info.Capabilities.Protocol
+				}
 				if info.XrayVersion != "" {
 					item["xrayVersion"] = info.XrayVersion
 					item["vVersion"] = info.XrayVersion
@@ -200,6 +209,9 @@ func UpdateNode(d dto.NodeUpdateDto) dto.R {
 	if d.GroupName != nil {
 		updates["group_name"] = *d.GroupName
 	}
+	if d.Region != nil {
+		updates["region"] = strings.TrimSpace(*d.Region)
+	}
 
 	if err := DB.Model(&node).Updates(updates).Error; err != nil {
 		return dto.Err("更新节点失败")
@@ -267,6 +279,10 @@ func DeleteNode(id int64) dto.R {
 	// Cascade cleanup: user_node records
 	DB.Where("node_id = ?", id).Delete(&model.UserNode{})
 
+	// Cascade cleanup: flow report sequence tracking
+	DB.Where("node_id = ?", id).Delete(&model.NodeFlowSeq{})
+	DB.Where("node_id = ?", id).Delete(&model.NodeFlowEpoch{})
+
 	DB.Delete(&node)
 	return dto.Ok("节点删除成功")
 }
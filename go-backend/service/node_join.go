@@ -0,0 +1,347 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"time"
+
+	"flux-panel/go-backend/dto"
+	"flux-panel/go-backend/model"
+	"flux-panel/go-backend/pkg"
+
+	"gorm.io/gorm"
+)
+
+// Port range given to enrolled nodes when the token doesn't set one;
+// same as the node form's defaults.
+const (
+	defaultJoinPortSta = 10000
+	defaultJoinPortEnd = 60000
+)
+
+func CreateNodeJoinToken(d dto.NodeJoinTokenDto) dto.R {
+	if d.PortSta == 0 && d.PortEnd == 0 {
+		d.PortSta, d.PortEnd = defaultJoinPortSta, defaultJoinPortEnd
+	}
+	if d.PortSta < 1 || d.PortEnd > 65535 || d.PortSta >= d.PortEnd {
+		return dto.Err("端口范围无效")
+	}
+	if d.MaxUses < 0 {
+		return dto.Err("使用次数无效")
+	}
+
+	now := time.Now().UnixMilli()
+	token := model.NodeJoinToken{
+		Name:            strings.TrimSpace(d.Name),
+		Token:           pkg.GenerateSecureSecret(),
+		GroupName:       strings.TrimSpace(d.GroupName),
+		Region:          strings.TrimSpace(d.Region),
+		PortSta:         d.PortSta,
+		PortEnd:         d.PortEnd,
+		MaxUses:         d.MaxUses,
+		RequireApproval: d.RequireApproval,
+		Enabled:         true,
+		ExpireTime:      d.ExpireTime,
+		CreatedTime:     now,
+		UpdatedTime:     now,
+	}
+	if err := DB.Create(&token).Error; err != nil {
+		return dto.Err("创建加入令牌失败")
+	}
+	return dto.Ok(token)
+}
+
+func ListNodeJoinTokens(clientAddr string) dto.R {
+	var tokens []model.NodeJoinToken
+	DB.Order("id DESC").Find(&tokens)
+
+	// Nodes enrolled per token, and how many still await approval
+	type countRow struct {
+		JoinTokenId int64
+		Total       int64
+		Pending     int64
+	}
+	var counts []countRow
+	DB.Model(&model.Node{}).
+		Select("join_token_id, COUNT(*) AS total, SUM(CASE WHEN pending THEN 1 ELSE 0 END) AS pending").
+		Where("join_token_id > 0").Group("join_token_id").Scan(&counts)
+	byToken := make(map[int64]countRow, len(counts))
+	for _, c := range counts {
+		byToken[c.JoinTokenId] = c
+	}
+
+	panelAddr := GetPanelAddress(clientAddr)
+	result := make([]map[string]interface{}, 0, len(tokens))
+	for _, t := range tokens {
+		result = append(result, map[string]interface{}{
+			"token":        t,
+			"nodeCount":    byToken[t.ID].Total,
+			"pendingCount": byToken[t.ID].Pending,
+			"command":      fmt.Sprintf("curl -fsSL %s/j/%s | bash", panelAddr, t.Token),
+		})
+	}
+	return dto.Ok(result)
+}
+
+func UpdateNodeJoinToken(d dto.NodeJoinTokenUpdateDto) dto.R {
+	var token model.NodeJoinToken
+	if err := DB.First(&token, d.ID).Error; err != nil {
+		return dto.Err("加入令牌不存在")
+	}
+
+	updates := map[string]interface{}{"updated_time": time.Now().UnixMilli()}
+	if d.Name != nil {
+		updates["name"] = strings.TrimSpace(*d.Name)
+	}
+	if d.GroupName != nil {
+		updates["group_name"] = strings.TrimSpace(*d.GroupName)
+	}
+	if d.Region != nil {
+		updates["region"] = strings.TrimSpace(*d.Region)
+	}
+	portSta, portEnd := token.PortSta, token.PortEnd
+	if d.PortSta != nil {
+		portSta = *d.PortSta
+	}
+	if d.PortEnd != nil {
+		portEnd = *d.PortEnd
+	}
+	if portSta < 1 || portEnd > 65535 || portSta >= portEnd {
+		return dto.Err("端口范围无效")
+	}
+	updates["port_sta"] = portSta
+	updates["port_end"] = portEnd
+	if d.MaxUses != nil {
+		if *d.MaxUses < 0 {
+			return dto.Err("使用次数无效")
+		}
+		updates["max_uses"] = *d.MaxUses
+	}
+	if d.RequireApproval != nil {
+		updates["require_approval"] = *d.RequireApproval
+	}
+	if d.Enabled != nil {
+		updates["enabled"] = *d.Enabled
+	}
+	if d.ExpireTime != nil {
+		updates["expire_time"] = *d.ExpireTime
+	}
+
+	if err := DB.Model(&token).Updates(updates).Error; err != nil {
+		return dto.Err("更新加入令牌失败")
+	}
+	return dto.Ok("更新成功")
+}
+
+// DeleteNodeJoinToken revokes a token. Nodes it already enrolled stay.
+func DeleteNodeJoinToken(id int64) dto.R {
+	if err := DB.Delete(&model.NodeJoinToken{}, id).Error; err != nil {
+		return dto.Err("删除加入令牌失败")
+	}
+	return dto.Ok("删除成功")
+}
+
+// ApproveNode lets an enrolled node that awaits approval connect.
+func ApproveNode(id int64) dto.R {
+	node := GetNodeById(id)
+	if node == nil {
+		return dto.Err("节点不存在")
+	}
+	if !node.Pending {
+		return dto.Ok("节点已批准")
+	}
+	DB.Model(&model.Node{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"pending":      false,
+		"updated_time": time.Now().UnixMilli(),
+	})
+	log.Printf("[节点加入] 节点 %d (%s) 已批准", id, node.Name)
+	return dto.Ok("节点已批准")
+}
+
+// FindNodeJoinToken returns the token if it is enabled and not expired.
+// Whether it has uses left is checked when a new node is enrolled.
+func FindNodeJoinToken(token string) *model.NodeJoinToken {
+	if token == "" {
+		return nil
+	}
+	var t model.NodeJoinToken
+	if err := DB.Where("token = ?", token).First(&t).Error; err != nil {
+		return nil
+	}
+	if !t.Enabled || (t.ExpireTime > 0 && t.ExpireTime <= time.Now().UnixMilli()) {
+		return nil
+	}
+	return &t
+}
+
+// EnrollNode creates a node for a machine presenting a join token. A
+// machine that enrolled with the same token before gets its existing node
+// back, so re-running cloud-init doesn't leave duplicates. The machine id
+// is only what the client claims, so a re-enrollment is treated like a
+// new one: it uses up the token, gets a fresh secret (the old one stops
+// working) and awaits approval again if the token requires it.
+func EnrollNode(token string, d dto.NodeEnrollDto, clientIp string) (*model.Node, error) {
+	t := FindNodeJoinToken(token)
+	if t == nil {
+		return nil, errors.New("加入令牌无效或已失效")
+	}
+
+	machineId := ""
+	if id := strings.TrimSpace(d.MachineId); id != "" {
+		sum := sha256.Sum256([]byte(id))
+		machineId = hex.EncodeToString(sum[:])
+	}
+	if machineId != "" {
+		var existing model.Node
+		if err := DB.Where("join_token_id = ? AND machine_id = ?", t.ID, machineId).First(&existing).Error; err == nil {
+			return reenrollNode(t, &existing)
+		}
+	}
+
+	serverIp, entryIps := enrollAddresses(d.Ips, clientIp)
+	if serverIp == "" {
+		return nil, errors.New("无法识别节点IP")
+	}
+	name := strings.TrimSpace(d.Hostname)
+	if len(name) > 64 {
+		name = name[:64]
+	}
+	if name == "" {
+		name = "node-" + serverIp
+	}
+
+	now := time.Now().UnixMilli()
+	if err := claimJoinTokenUse(DB, t.ID, now); err != nil {
+		return nil, err
+	}
+
+	disguise, xrayDisguise := pickDisguiseNames()
+	secret := pkg.GenerateSecureSecret()
+	node := model.Node{
+		Name:             name,
+		Ip:               serverIp,
+		EntryIps:         entryIps,
+		ServerIp:         serverIp,
+		PortSta:          t.PortSta,
+		PortEnd:          t.PortEnd,
+		Secret:           secret,
+		SecretKid:        pkg.NodeKeyId(secret),
+		GroupName:        t.GroupName,
+		Region:           t.Region,
+		CreatedTime:      now,
+		UpdatedTime:      now,
+		DisguiseName:     disguise,
+		XrayDisguiseName: xrayDisguise,
+		JoinTokenId:      t.ID,
+		MachineId:        machineId,
+		Pending:          t.RequireApproval,
+	}
+	if err := DB.Create(&node).Error; err != nil {
+		DB.Model(&model.NodeJoinToken{}).Where("id = ?", t.ID).Update("used_count", gorm.Expr("used_count - 1"))
+		return nil, errors.New("创建节点失败")
+	}
+
+	log.Printf("[节点加入] 令牌 %d 加入节点 %d (%s, %s)，待批准=%t", t.ID, node.ID, node.Name, serverIp, node.Pending)
+	return &node, nil
+}
+
+// reenrollNode hands a machine that enrolled before its node again, with
+// a new secret, after claiming a use of the token.
+func reenrollNode(t *model.NodeJoinToken, node *model.Node) (*model.Node, error) {
+	now := time.Now().UnixMilli()
+	secret := pkg.GenerateSecureSecret()
+	pending := node.Pending || t.RequireApproval
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := claimJoinTokenUse(tx, t.ID, now); err != nil {
+			return err
+		}
+		return tx.Model(&model.Node{}).Where("id = ?", node.ID).Updates(map[string]interface{}{
+			"secret":              secret,
+			"secret_kid":          pkg.NodeKeyId(secret),
+			"prev_secret":         "",
+			"prev_secret_kid":     "",
+			"prev_secret_expires": 0,
+			"pending":             pending,
+			"updated_time":        now,
+		}).Error
+	})
+	if err != nil {
+		if errors.Is(err, errJoinTokenUsedUp) {
+			return nil, err
+		}
+		return nil, errors.New("重新加入节点失败")
+	}
+
+	node.Secret, node.SecretKid = secret, pkg.NodeKeyId(secret)
+	node.PrevSecret, node.PrevSecretKid, node.PrevSecretExpires = "", "", 0
+	node.Pending = pending
+	log.Printf("[节点加入] 机器已通过令牌 %d 加入为节点 %d，已更换密钥并重新下发安装脚本，待批准=%t", t.ID, node.ID, pending)
+	return node, nil
+}
+
+var errJoinTokenUsedUp = errors.New("加入令牌已达到使用次数上限")
+
+// claimJoinTokenUse takes one use of a token; the condition keeps
+// concurrent enrollments within MaxUses.
+func claimJoinTokenUse(tx *gorm.DB, tokenId int64, now int64) error {
+	res := tx.Model(&model.NodeJoinToken{}).
+		Where("id = ? AND enabled = ? AND (max_uses = 0 OR used_count < max_uses)", tokenId, true).
+		Updates(map[string]interface{}{"used_count": gorm.Expr("used_count + 1"), "last_used_time": now})
+	if res.Error != nil || res.RowsAffected == 0 {
+		return errJoinTokenUsedUp
+	}
+	return nil
+}
+
+// enrollAddresses picks the node's server IP and its other public
+// addresses. The address the request came from wins when it's public:
+// behind NAT the machine's own interfaces only show private addresses.
+func enrollAddresses(reported string, clientIp string) (string, string) {
+	var public, private []string
+	seen := map[string]bool{}
+	for _, s := range strings.Fields(reported) {
+		ip := net.ParseIP(s)
+		if ip == nil || !ip.IsGlobalUnicast() || seen[ip.String()] {
+			continue
+		}
+		seen[ip.String()] = true
+		if isPublicIP(ip) {
+			public = append(public, ip.String())
+		} else {
+			private = append(private, ip.String())
+		}
+	}
+
+	serverIp := ""
+	if ip := net.ParseIP(clientIp); ip != nil && isPublicIP(ip) {
+		serverIp = ip.String()
+	} else if len(public) > 0 {
+		serverIp = public[0]
+	} else if len(private) > 0 {
+		// Panel and node on the same private network; the request may have
+		// come through a proxy, so trust the machine's own address
+		serverIp = private[0]
+	} else if ip != nil {
+		serverIp = ip.String()
+	}
+
+	var entries []string
+	if len(public) > 1 || (len(public) == 1 && public[0] != serverIp) {
+		entries = append(entries, serverIp)
+		for _, ip := range public {
+			if ip != serverIp {
+				entries = append(entries, ip)
+			}
+		}
+	}
+	return serverIp, strings.Join(entries, ",")
+}
+
+func isPublicIP(ip net.IP) bool {
+	return ip.IsGlobalUnicast() && !isPrivateIP(ip.String())
+}
@@ -0,0 +1,50 @@
+package service
+
+import (
+	"testing"
+
+	"flux-panel/go-backend/dto"
+	"flux-panel/go-backend/model"
+)
+
+func TestEnrollNodeAgainRotatesSecret(t *testing.T) {
+	useTestDB(t, &model.Node{}, &model.NodeJoinToken{})
+
+	token := model.NodeJoinToken{Token: "join", PortSta: 10000, PortEnd: 20000, MaxUses: 2, RequireApproval: true, Enabled: true}
+	if err := DB.Create(&token).Error; err != nil {
+		t.Fatal(err)
+	}
+	d := dto.NodeEnrollDto{Hostname: "edge", Ips: "203.0.113.5", MachineId: "machine-1"}
+
+	first, err := EnrollNode("join", d, "203.0.113.5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ApproveNode(first.ID)
+
+	again, err := EnrollNode("join", d, "203.0.113.5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.ID != first.ID {
+		t.Fatalf("re-enrollment created node %d, want %d", again.ID, first.ID)
+	}
+	if again.Secret == first.Secret {
+		t.Fatal("re-enrollment handed out the existing secret")
+	}
+	if FindNodeBySecret(first.Secret) != nil {
+		t.Fatal("old secret still accepted")
+	}
+	if !again.Pending || !GetNodeById(first.ID).Pending {
+		t.Fatal("re-enrolled node skipped approval")
+	}
+
+	var used model.NodeJoinToken
+	DB.First(&used, token.ID)
+	if used.UsedCount != 2 {
+		t.Fatalf("used_count = %d, want 2", used.UsedCount)
+	}
+	if _, err := EnrollNode("join", d, "203.0.113.5"); err == nil {
+		t.Fatal("re-enrollment beyond MaxUses accepted")
+	}
+}
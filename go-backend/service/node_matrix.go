@@ -0,0 +1,308 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"sort"
+	"strconv"
+	"time"
+
+	"flux-panel/go-backend/dto"
+	"flux-panel/go-backend/model"
+	"flux-panel/go-backend/pkg"
+)
+
+// Each pair runs a latency probe, a TCP throughput test and a UDP test at a
+// fixed rate for loss. Pairs run one at a time so tests don't compete for
+// the same links. The TCP test stops after linkTestMaxBytes, so a fast link
+// costs at most that much traffic per pair.
+const (
+	linkTestDuration = 3  // seconds per protocol
+	linkTestUDPRate  = 10 // Mbps
+	linkTestMaxBytes = 64 << 20
+	linkServerTTL    = 30 // seconds the target keeps its receiver open
+	linkTestTimeout  = 25 * time.Second
+	linkPortAttempts = 3
+
+	// "true" tests every pair of online nodes; by default scheduled runs
+	// only pair nodes of the same group
+	nodeMatrixCrossGroupKey = "node_matrix_cross_group"
+
+	// Pairs tested per scheduled run. The number of pairs grows with the
+	// square of the node count, so a run tests the pairs measured longest
+	// ago and later runs pick up the rest.
+	nodeMatrixMaxPairsKey     = "node_matrix_max_pairs"
+	nodeMatrixDefaultMaxPairs = 12
+)
+
+// RegisterNodeMatrixJob schedules the node-to-node link matrix. Every run
+// sends real traffic between nodes, so the job starts out disabled.
+func RegisterNodeMatrixJob() {
+	RegisterJob(&Job{
+		Name:        JobNodeLinkMatrix,
+		Description: "节点互测（延迟/带宽/丢包）",
+		Spec:        "0 */6 * * *",
+		Missed:      MissedSkip,
+		Disabled:    true,
+		Run: func(ctx context.Context, scheduled time.Time) error {
+			return RunNodeLinkMatrix(ctx)
+		},
+	})
+}
+
+// RunNodeLinkMatrix tests ordered pairs of online nodes in the same group
+// (or of all online nodes when node_matrix_cross_group is on) and stores the
+// results. At most node_matrix_max_pairs pairs are tested, oldest-measured
+// first.
+func RunNodeLinkMatrix(ctx context.Context) error {
+	if pkg.WS == nil {
+		return nil
+	}
+	var nodes []model.Node
+	DB.Where("pending = ?", false).Order("id ASC").Find(&nodes)
+	online := make([]model.Node, 0, len(nodes))
+	for _, n := range nodes {
+		if pkg.WS.IsNodeOnline(n.ID) {
+			online = append(online, n)
+		}
+	}
+	if len(online) < 2 {
+		return nil
+	}
+
+	crossGroup := false
+	var cfg model.ViteConfig
+	if err := DB.Where("name = ?", nodeMatrixCrossGroupKey).First(&cfg).Error; err == nil {
+		crossGroup = cfg.Value == "true"
+	}
+	maxPairs := nodeMatrixDefaultMaxPairs
+	cfg = model.ViteConfig{}
+	if err := DB.Where("name = ?", nodeMatrixMaxPairsKey).First(&cfg).Error; err == nil {
+		if v, err := strconv.Atoi(cfg.Value); err == nil && v > 0 {
+			maxPairs = v
+		}
+	}
+
+	var tested, failed int
+	for _, p := range pickLinkPairs(online, crossGroup, maxPairs) {
+		if ctx.Err() != nil {
+			return fmt.Errorf("已中断，完成 %d 组", tested)
+		}
+		if record := testNodeLink(&p[0], &p[1]); !record.Success {
+			failed++
+		}
+		tested++
+	}
+	log.Printf("[节点互测] 完成 %d 组测试，失败 %d 组", tested, failed)
+	return nil
+}
+
+// pickLinkPairs returns up to max ordered pairs of nodes, those never
+// measured first and then by the time of their latest result.
+func pickLinkPairs(nodes []model.Node, crossGroup bool, max int) [][2]model.Node {
+	var rows []struct {
+		SrcNodeId int64
+		DstNodeId int64
+		Last      int64
+	}
+	DB.Model(&model.NodeLinkMetric{}).
+		Select("src_node_id, dst_node_id, MAX(record_time) AS last").
+		Group("src_node_id, dst_node_id").
+		Scan(&rows)
+	last := make(map[[2]int64]int64, len(rows))
+	for _, r := range rows {
+		last[[2]int64{r.SrcNodeId, r.DstNodeId}] = r.Last
+	}
+
+	var pairs [][2]model.Node
+	for _, src := range nodes {
+		for _, dst := range nodes {
+			if src.ID == dst.ID || (!crossGroup && src.GroupName != dst.GroupName) {
+				continue
+			}
+			pairs = append(pairs, [2]model.Node{src, dst})
+		}
+	}
+	sort.SliceStable(pairs, func(i, j int) bool {
+		return last[[2]int64{pairs[i][0].ID, pairs[i][1].ID}] < last[[2]int64{pairs[j][0].ID, pairs[j][1].ID}]
+	})
+	if len(pairs) > max {
+		pairs = pairs[:max]
+	}
+	return pairs
+}
+
+// TestNodeLink runs one bandwidth test from src to dst on demand.
+func TestNodeLink(srcId, dstId int64) dto.R {
+	if srcId == dstId {
+		return dto.Err("源节点和目标节点不能相同")
+	}
+	src, dst := GetNodeById(srcId), GetNodeById(dstId)
+	if src == nil || dst == nil {
+		return dto.Err("节点不存在")
+	}
+	return dto.Ok(testNodeLink(src, dst))
+}
+
+func testNodeLink(src, dst *model.Node) model.NodeLinkMetric {
+	record := model.NodeLinkMetric{
+		SrcNodeId:  src.ID,
+		DstNodeId:  dst.ID,
+		RecordTime: time.Now().Unix(),
+	}
+	if err := runNodeLinkTest(src, dst, &record); err != nil {
+		record.Error = err.Error()
+		if len(record.Error) > 255 {
+			record.Error = record.Error[:255]
+		}
+	} else {
+		record.Success = true
+	}
+	DB.Create(&record)
+	return record
+}
+
+func runNodeLinkTest(src, dst *model.Node, record *model.NodeLinkMetric) error {
+	if !pkg.WS.IsNodeOnline(src.ID) || !pkg.WS.IsNodeOnline(dst.ID) {
+		return errors.New("节点不在线")
+	}
+	if dst.ServerIp == "" {
+		return errors.New("目标节点没有服务器IP")
+	}
+
+	// The receiver listens on a free port of the target's range, which its
+	// firewall already has to allow for forwards
+	ports := freeLinkTestPorts(dst, linkPortAttempts)
+	if len(ports) == 0 {
+		return errors.New("目标节点端口范围内没有空闲端口")
+	}
+	token := pkg.GenerateSecureSecret()
+	port := 0
+	var res *dto.GostResponse
+	for _, p := range ports {
+		res = pkg.WS.SendMsg(dst.ID, map[string]interface{}{
+			"token":   token,
+			"port":    p,
+			"timeout": linkServerTTL,
+		}, "BandwidthServer")
+		if isGostSuccess(res) {
+			port = p
+			break
+		}
+	}
+	if port == 0 {
+		return fmt.Errorf("目标节点启动接收端失败: %s", gostMsg(res))
+	}
+
+	res = pkg.WS.SendMsgWithTimeout(src.ID, map[string]interface{}{
+		"host":     dst.ServerIp,
+		"port":     port,
+		"token":    token,
+		"duration": linkTestDuration,
+		"udpRate":  linkTestUDPRate,
+		"maxBytes": linkTestMaxBytes,
+	}, "BandwidthTest", linkTestTimeout)
+	if !isGostSuccess(res) {
+		return errors.New(gostMsg(res))
+	}
+	var result struct {
+		Latency float64 `json:"latency"`
+		Jitter  float64 `json:"jitter"`
+		TcpMbps float64 `json:"tcpMbps"`
+		UdpMbps float64 `json:"udpMbps"`
+		Loss    float64 `json:"loss"`
+	}
+	if err := remarshal(res.Data, &result); err != nil {
+		return errors.New("测试结果无效")
+	}
+	record.Latency = result.Latency
+	record.Jitter = result.Jitter
+	record.TcpMbps = result.TcpMbps
+	record.UdpMbps = result.UdpMbps
+	record.Loss = result.Loss
+	return nil
+}
+
+// freeLinkTestPorts picks up to n random ports of the node's range that no
+// forward or inbound uses.
+func freeLinkTestPorts(node *model.Node, n int) []int {
+	if node.PortSta <= 0 || node.PortEnd < node.PortSta {
+		return nil
+	}
+	used := getAllUsedPortsOnNode(node.ID, nil)
+	var inboundPorts []int
+	DB.Model(&model.XrayInbound{}).Where("node_id = ?", node.ID).Pluck("port", &inboundPorts)
+	for _, p := range inboundPorts {
+		used[p] = true
+	}
+
+	var ports []int
+	size := node.PortEnd - node.PortSta + 1
+	for _, i := range rand.Perm(size) {
+		if p := node.PortSta + i; !used[p] {
+			ports = append(ports, p)
+			if len(ports) == n {
+				break
+			}
+		}
+	}
+	return ports
+}
+
+func gostMsg(res *dto.GostResponse) string {
+	if res == nil {
+		return "无响应"
+	}
+	return res.Msg
+}
+
+func remarshal(from interface{}, to interface{}) error {
+	b, err := json.Marshal(from)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, to)
+}
+
+// GetNodeLinkMatrix returns the nodes and the latest result of every pair.
+func GetNodeLinkMatrix() dto.R {
+	var nodes []model.Node
+	DB.Select("id, name, group_name, server_ip").Where("pending = ?", false).Order("id ASC").Find(&nodes)
+	nodeList := make([]map[string]interface{}, 0, len(nodes))
+	for _, n := range nodes {
+		nodeList = append(nodeList, map[string]interface{}{
+			"id":        n.ID,
+			"name":      n.Name,
+			"groupName": n.GroupName,
+			"online":    pkg.WS != nil && pkg.WS.IsNodeOnline(n.ID),
+		})
+	}
+
+	var links []model.NodeLinkMetric
+	DB.Where("id IN (?)", DB.Model(&model.NodeLinkMetric{}).
+		Select("MAX(id)").Group("src_node_id, dst_node_id")).
+		Find(&links)
+
+	return dto.Ok(map[string]interface{}{
+		"nodes": nodeList,
+		"links": links,
+	})
+}
+
+// GetNodeLinkHistory returns the results of one pair over the last hours.
+func GetNodeLinkHistory(srcId, dstId int64, hours int) dto.R {
+	if hours <= 0 {
+		hours = 24 * 7
+	}
+	cutoff := time.Now().Unix() - int64(hours*3600)
+
+	var records []model.NodeLinkMetric
+	DB.Where("src_node_id = ? AND dst_node_id = ? AND record_time >= ?", srcId, dstId, cutoff).
+		Order("record_time ASC").
+		Find(&records)
+	return dto.Ok(records)
+}
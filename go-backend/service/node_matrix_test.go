@@ -0,0 +1,67 @@
+package service
+
+import (
+	"testing"
+
+	"flux-panel/go-backend/model"
+)
+
+func TestFreeLinkTestPortsSkipsUsedPorts(t *testing.T) {
+	useTestDB(t, &model.Tunnel{}, &model.Forward{}, &model.XrayInbound{})
+
+	node := &model.Node{ID: 3, PortSta: 20000, PortEnd: 20004}
+	DB.Create(&model.Tunnel{ID: 1, InNodeId: 3, OutNodeId: 3})
+	DB.Create(&model.Forward{TunnelId: 1, InPort: 20000, OutPort: 20001})
+	DB.Create(&model.XrayInbound{NodeId: 3, Port: 20002})
+
+	ports := freeLinkTestPorts(node, 3)
+	if len(ports) != 2 {
+		t.Fatalf("got ports %v, want 20003 and 20004", ports)
+	}
+	for _, p := range ports {
+		if p != 20003 && p != 20004 {
+			t.Fatalf("picked used or out-of-range port %d", p)
+		}
+	}
+
+	if ports := freeLinkTestPorts(&model.Node{ID: 4}, 3); len(ports) != 0 {
+		t.Fatalf("node without a port range got %v", ports)
+	}
+}
+
+func TestPickLinkPairsOldestFirst(t *testing.T) {
+	useTestDB(t, &model.NodeLinkMetric{})
+
+	nodes := []model.Node{{ID: 1, GroupName: "a"}, {ID: 2, GroupName: "a"}, {ID: 3, GroupName: "b"}}
+	DB.Create(&model.NodeLinkMetric{SrcNodeId: 1, DstNodeId: 2, RecordTime: 200})
+	DB.Create(&model.NodeLinkMetric{SrcNodeId: 2, DstNodeId: 1, RecordTime: 100})
+	DB.Create(&model.NodeLinkMetric{SrcNodeId: 2, DstNodeId: 1, RecordTime: 300})
+	DB.Create(&model.NodeLinkMetric{SrcNodeId: 1, DstNodeId: 3, RecordTime: 50})
+
+	ids := func(pairs [][2]model.Node) [][2]int64 {
+		out := make([][2]int64, len(pairs))
+		for i, p := range pairs {
+			out[i] = [2]int64{p[0].ID, p[1].ID}
+		}
+		return out
+	}
+
+	// Same group only: 1->2 was measured before 2->1's latest run
+	if got := ids(pickLinkPairs(nodes, false, 10)); len(got) != 2 || got[0] != [2]int64{1, 2} || got[1] != [2]int64{2, 1} {
+		t.Fatalf("same group pairs = %v", got)
+	}
+
+	// Across groups the never-measured pairs come first and the cap applies
+	got := ids(pickLinkPairs(nodes, true, 4))
+	if len(got) != 4 {
+		t.Fatalf("got %d pairs, want 4", len(got))
+	}
+	for _, p := range got[:3] {
+		if p == [2]int64{1, 2} || p == [2]int64{2, 1} || p == [2]int64{1, 3} {
+			t.Fatalf("measured pair %v picked before unmeasured ones: %v", p, got)
+		}
+	}
+	if got[3] != [2]int64{1, 3} {
+		t.Fatalf("fourth pair = %v, want the oldest measured 1->3", got[3])
+	}
+}
@@ -0,0 +1,216 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"flux-panel/go-backend/dto"
+	"flux-panel/go-backend/model"
+	"flux-panel/go-backend/pkg"
+)
+
+// Nodes report system info every couple of seconds. Each replica folds the
+// reports of the nodes connected to it into one row per node per bucket.
+const (
+	nodeMetricBucket = time.Minute
+	// History queries return at most this many points; longer ranges are
+	// averaged into wider buckets
+	maxNodeMetricPoints = 720
+)
+
+type nodeMetricAcc struct {
+	bucket  int64
+	samples int
+	cpuSum  float64
+	cpuMax  float64
+	memSum  float64
+	memMax  float64
+	tcpSum  int
+	tcpMax  int
+	// First and last counters of the bucket, for the NIC rates
+	firstAt, lastAt time.Time
+	firstRx, lastRx uint64
+	firstTx, lastTx uint64
+	last            pkg.NodeSystemInfo
+}
+
+var (
+	nodeMetricsMu  sync.Mutex
+	nodeMetricsAcc = map[int64]*nodeMetricAcc{}
+)
+
+// RecordNodeMetricsSample adds one system info report to the node's
+// current bucket, flushing the previous bucket when a new one starts.
+func RecordNodeMetricsSample(nodeId int64, info *pkg.NodeSystemInfo, at time.Time) {
+	bucket := at.Truncate(nodeMetricBucket).Unix()
+
+	nodeMetricsMu.Lock()
+	acc := nodeMetricsAcc[nodeId]
+	var done *nodeMetricAcc
+	if acc == nil || acc.bucket != bucket {
+		done = acc
+		acc = &nodeMetricAcc{bucket: bucket, firstAt: at, firstRx: info.BytesReceived, firstTx: info.BytesTransmitted}
+		nodeMetricsAcc[nodeId] = acc
+	}
+	acc.samples++
+	acc.cpuSum += info.CPUUsage
+	acc.memSum += info.MemoryUsage
+	acc.tcpSum += info.TcpEstablished
+	if info.CPUUsage > acc.cpuMax {
+		acc.cpuMax = info.CPUUsage
+	}
+	if info.MemoryUsage > acc.memMax {
+		acc.memMax = info.MemoryUsage
+	}
+	if info.TcpEstablished > acc.tcpMax {
+		acc.tcpMax = info.TcpEstablished
+	}
+	if info.BytesReceived < acc.lastRx || info.BytesTransmitted < acc.lastTx {
+		// Counters went backwards (node restarted); measure from here
+		acc.firstAt, acc.firstRx, acc.firstTx = at, info.BytesReceived, info.BytesTransmitted
+	}
+	acc.lastAt, acc.lastRx, acc.lastTx = at, info.BytesReceived, info.BytesTransmitted
+	acc.last = *info
+	nodeMetricsMu.Unlock()
+
+	if done != nil {
+		saveNodeMetric(nodeId, done)
+	}
+}
+
+// StartNodeMetricsFlusher writes buckets of nodes that stopped reporting,
+// e.g. because they went offline. Runs on every replica.
+func StartNodeMetricsFlusher() {
+	pkg.Tasks.Go("node-metrics", func(ctx context.Context) {
+		for pkg.Sleep(ctx, nodeMetricBucket) {
+			flushStaleNodeMetrics(time.Now())
+		}
+		// Keep the partial buckets on shutdown
+		flushStaleNodeMetrics(time.Now().Add(2 * nodeMetricBucket))
+	})
+}
+
+func flushStaleNodeMetrics(now time.Time) {
+	current := now.Truncate(nodeMetricBucket).Unix()
+	done := map[int64]*nodeMetricAcc{}
+
+	nodeMetricsMu.Lock()
+	for nodeId, acc := range nodeMetricsAcc {
+		if acc.bucket < current {
+			done[nodeId] = acc
+			delete(nodeMetricsAcc, nodeId)
+		}
+	}
+	nodeMetricsMu.Unlock()
+
+	for nodeId, acc := range done {
+		saveNodeMetric(nodeId, acc)
+	}
+}
+
+func saveNodeMetric(nodeId int64, acc *nodeMetricAcc) {
+	if acc.samples == 0 {
+		return
+	}
+	n := float64(acc.samples)
+	record := model.NodeMetric{
+		NodeId:      nodeId,
+		RecordTime:  acc.bucket,
+		Samples:     acc.samples,
+		CpuAvg:      acc.cpuSum / n,
+		CpuMax:      acc.cpuMax,
+		MemAvg:      acc.memSum / n,
+		MemMax:      acc.memMax,
+		DiskUsage:   acc.last.DiskUsage,
+		Load1:       acc.last.Load1,
+		Load5:       acc.last.Load5,
+		Load15:      acc.last.Load15,
+		TcpConns:    acc.tcpSum / acc.samples,
+		TcpConnsMax: acc.tcpMax,
+		XrayRunning: acc.last.XrayRunning,
+	}
+	if secs := acc.lastAt.Sub(acc.firstAt).Seconds(); secs > 0 {
+		record.RxRate = int64(float64(acc.lastRx-acc.firstRx) / secs)
+		record.TxRate = int64(float64(acc.lastTx-acc.firstTx) / secs)
+	}
+	DB.Create(&record)
+}
+
+// GetNodeMetricsHistory returns a node's metrics over the last hours,
+// averaged into wider buckets when there would be too many points.
+func GetNodeMetricsHistory(nodeId int64, hours int) dto.R {
+	if hours <= 0 {
+		hours = 24
+	}
+	cutoff := time.Now().Unix() - int64(hours*3600)
+
+	var records []model.NodeMetric
+	DB.Where("node_id = ? AND record_time >= ?", nodeId, cutoff).
+		Order("record_time ASC").
+		Find(&records)
+
+	step := int64(nodeMetricBucket / time.Second)
+	if points := int64(hours*3600) / step; points > maxNodeMetricPoints {
+		step *= (points + maxNodeMetricPoints - 1) / maxNodeMetricPoints
+	}
+	return dto.Ok(map[string]interface{}{
+		"step":    step,
+		"records": downsampleNodeMetrics(records, step),
+	})
+}
+
+// downsampleNodeMetrics merges consecutive records into buckets of step
+// seconds: averages are weighted by samples, peaks keep the maximum and
+// point-in-time values keep the latest.
+func downsampleNodeMetrics(records []model.NodeMetric, step int64) []model.NodeMetric {
+	if step <= int64(nodeMetricBucket/time.Second) {
+		return records
+	}
+	result := make([]model.NodeMetric, 0, len(records)/2+1)
+	var cur *model.NodeMetric
+	var rows int64
+	finish := func() {
+		if cur == nil {
+			return
+		}
+		if cur.Samples > 0 {
+			n := float64(cur.Samples)
+			cur.CpuAvg /= n
+			cur.MemAvg /= n
+			cur.TcpConns /= cur.Samples
+		}
+		cur.RxRate /= rows
+		cur.TxRate /= rows
+		result = append(result, *cur)
+	}
+	for _, r := range records {
+		bucket := r.RecordTime - r.RecordTime%step
+		if cur == nil || cur.RecordTime != bucket {
+			finish()
+			cur = &model.NodeMetric{NodeId: r.NodeId, RecordTime: bucket}
+			rows = 0
+		}
+		rows++
+		n := float64(r.Samples)
+		cur.Samples += r.Samples
+		cur.CpuAvg += r.CpuAvg * n
+		cur.MemAvg += r.MemAvg * n
+		cur.TcpConns += r.TcpConns * r.Samples
+		cur.RxRate += r.RxRate
+		cur.TxRate += r.TxRate
+		if r.CpuMax > cur.CpuMax {
+			cur.CpuMax = r.CpuMax
+		}
+		if r.MemMax > cur.MemMax {
+			cur.MemMax = r.MemMax
+		}
+		if r.TcpConnsMax > cur.TcpConnsMax {
+			cur.TcpConnsMax = r.TcpConnsMax
+		}
+		cur.DiskUsage, cur.Load1, cur.Load5, cur.Load15 = r.DiskUsage, r.Load1, r.Load5, r.Load15
+		cur.XrayRunning = r.XrayRunning
+	}
+	finish()
+	return result
+}
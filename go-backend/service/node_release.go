@@ -0,0 +1,222 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"flux-panel/go-backend/config"
+	"flux-panel/go-backend/dto"
+	"flux-panel/go-backend/model"
+	"flux-panel/go-backend/pkg"
+)
+
+// Node binaries are published in NodeBinaryDir as node-<arch> with a signed
+// node-<arch>.manifest.json next to them (see cmd/node-release). The panel
+// checks a manifest before telling nodes to update; nodes check it again,
+// against the key embedded in their own build, after downloading.
+
+const releasePubKeyFile = "release.pub"
+
+func versionLessThan(a, b string) bool {
+	parse := func(v string) [3]int {
+		v = strings.TrimSpace(v)
+		v = strings.TrimPrefix(v, "v")
+		if v == "" || v == "dev" {
+			return [3]int{0, 0, 0}
+		}
+		if idx := strings.Index(v, "-"); idx >= 0 {
+			v = v[:idx]
+		}
+		parts := strings.Split(v, ".")
+		var out [3]int
+		for i := 0; i < len(parts) && i < 3; i++ {
+			if n, err := strconv.Atoi(parts[i]); err == nil {
+				out[i] = n
+			}
+		}
+		return out
+	}
+	pa := parse(a)
+	pb := parse(b)
+	for i := 0; i < 3; i++ {
+		if pa[i] != pb[i] {
+			return pa[i] < pb[i]
+		}
+	}
+	return false
+}
+
+// nodeReleasePublicKey returns the configured release key, or "".
+func nodeReleasePublicKey() string {
+	if config.Cfg.NodeReleasePubKey != "" {
+		return strings.TrimSpace(config.Cfg.NodeReleasePubKey)
+	}
+	b, err := os.ReadFile(filepath.Join(config.Cfg.NodeBinaryDir, releasePubKeyFile))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(b))
+}
+
+// nodeReleaseStatus is one published binary as shown to the admin.
+type nodeReleaseStatus struct {
+	Arch    string `json:"arch"`
+	Version string `json:"version"`
+	Size    int64  `json:"size"`
+	SHA256  string `json:"sha256"`
+	Valid   bool   `json:"valid"`
+	Msg     string `json:"msg,omitempty"`
+}
+
+// loadNodeReleases reads every manifest in NodeBinaryDir and checks it
+// against its binary and, if configured, the release key.
+func loadNodeReleases() []nodeReleaseStatus {
+	paths, _ := filepath.Glob(filepath.Join(config.Cfg.NodeBinaryDir, "node-*.manifest.json"))
+	sort.Strings(paths)
+
+	key := nodeReleasePublicKey()
+	var result []nodeReleaseStatus
+	for _, path := range paths {
+		arch := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(path), "node-"), ".manifest.json")
+		status := nodeReleaseStatus{Arch: arch}
+		status.Msg = checkNodeRelease(path, arch, key, &status)
+		status.Valid = status.Msg == ""
+		result = append(result, status)
+	}
+	return result
+}
+
+func checkNodeRelease(path, arch, key string, status *nodeReleaseStatus) string {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "读取发布清单失败"
+	}
+	var release pkg.NodeRelease
+	if err := json.Unmarshal(b, &release); err != nil {
+		return "发布清单格式错误"
+	}
+	status.Version, status.Size, status.SHA256 = release.Version, release.Size, release.SHA256
+	if release.Arch != arch {
+		return "发布清单架构不匹配: " + release.Arch
+	}
+
+	size, sum, err := pkg.HashFile(filepath.Join(config.Cfg.NodeBinaryDir, "node-"+arch))
+	if err != nil {
+		return "二进制文件不存在"
+	}
+	if size != release.Size || !strings.EqualFold(sum, release.SHA256) {
+		return "二进制文件与发布清单的 SHA-256 不符"
+	}
+
+	if key == "" {
+		return "面板未配置发布公钥 (NODE_RELEASE_PUBKEY)，无法校验签名"
+	}
+	pub, err := pkg.ParseReleasePublicKey(key)
+	if err != nil {
+		return err.Error()
+	}
+	if err := release.Verify(pub); err != nil {
+		return "发布清单" + err.Error()
+	}
+	return ""
+}
+
+// nodeReleaseVersion returns the version of the published binaries. All
+// manifests must be valid and agree; "" with no message means none exist.
+func nodeReleaseVersion() (string, string) {
+	version := ""
+	for _, r := range loadNodeReleases() {
+		if !r.Valid {
+			return "", fmt.Sprintf("node-%s: %s", r.Arch, r.Msg)
+		}
+		if version != "" && r.Version != version {
+			return "", fmt.Sprintf("各架构发布清单版本不一致: %s / %s", version, r.Version)
+		}
+		version = r.Version
+	}
+	return version, ""
+}
+
+func GetNodeReleaseInfo() dto.R {
+	return dto.Ok(map[string]interface{}{
+		"publicKey": nodeReleasePublicKey(),
+		"releases":  loadNodeReleases(),
+	})
+}
+
+// sendNodeUpdate checks that node can be updated and sends it the update
+// command. version is the release the node should end up on. Returns an
+// error message, or "".
+func sendNodeUpdate(node *model.Node, version string) string {
+	// Legacy nodes (no disguise name) must be reinstalled manually. Nodes
+	// that advertise their commands are recent enough to know.
+	caps := pkg.WS.GetNodeCapabilities(node.ID)
+	if caps != nil && !caps.Supports("NodeUpdateBinary") {
+		return "该节点不支持在线更新，请使用新的安装命令重新安装"
+	}
+	if caps == nil && node.DisguiseName == "" && versionLessThan(node.Version, "2.1.0") {
+		return "该节点需要使用新的安装命令重新安装，请在节点管理页面点击安装按钮获取新命令"
+	}
+	if !pkg.WS.IsNodeOnline(node.ID) {
+		return "节点不在线"
+	}
+
+	panelAddr := GetPanelAddress("")
+	result := pkg.NodeUpdateBinary(node.ID, panelAddr, version)
+	if result == nil {
+		return "节点更新失败"
+	}
+	if result.Msg != gostSuccessMsg {
+		return result.Msg
+	}
+	return ""
+}
+
+// nodeUpdateVersion resolves the release to install, refusing to go on
+// when nothing verifiable is published.
+func nodeUpdateVersion() (string, string) {
+	version, msg := nodeReleaseVersion()
+	if msg != "" {
+		return "", msg
+	}
+	if version == "" {
+		return "", "未找到签名的节点发布清单，请先签名发布节点二进制"
+	}
+	return version, ""
+}
+
+func UpdateNodeBinary(id int64) dto.R {
+	node := GetNodeById(id)
+	if node == nil {
+		return dto.Err("节点不存在")
+	}
+	version, msg := nodeUpdateVersion()
+	if msg != "" {
+		return dto.Err(msg)
+	}
+	if msg := sendNodeUpdate(node, version); msg != "" {
+		return dto.Err(msg)
+	}
+	return dto.Ok("更新指令已发送")
+}
+
+func RollbackNodeBinary(id int64) dto.R {
+	node := GetNodeById(id)
+	if node == nil {
+		return dto.Err("节点不存在")
+	}
+	result := pkg.NodeRollbackBinary(id)
+	if result == nil || result.Msg != gostSuccessMsg {
+		msg := "节点回滚失败"
+		if result != nil {
+			msg = result.Msg
+		}
+		return dto.Err(msg)
+	}
+	return dto.Ok("回滚指令已发送")
+}
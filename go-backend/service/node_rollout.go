@@ -0,0 +1,397 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"flux-panel/go-backend/dto"
+	"flux-panel/go-backend/model"
+	"flux-panel/go-backend/pkg"
+)
+
+const (
+	rolloutPollInterval      = 3 * time.Second
+	rolloutHeartbeatInterval = 30 * time.Second
+	// A running rollout whose heartbeat is older than this lost its runner
+	// (panel restart) and can be resumed.
+	rolloutStaleAfter = 2 * time.Minute
+
+	defaultRolloutBatchSize     = 5
+	defaultRolloutHealthTimeout = 300 // must exceed the node's own 3 min rollback window
+)
+
+// Rollouts running in this process.
+var activeRollouts sync.Map // rolloutId(int64) → struct{}
+
+func CreateNodeRollout(d dto.NodeRolloutDto) dto.R {
+	version, msg := nodeUpdateVersion()
+	if msg != "" {
+		return dto.Err(msg)
+	}
+	if d.Version != "" && d.Version != version {
+		return dto.Err(fmt.Sprintf("目标版本 %s 与发布清单版本 %s 不一致", d.Version, version))
+	}
+
+	var running int64
+	DB.Model(&model.NodeRollout{}).Where("status = ?", "running").Count(&running)
+	if running > 0 {
+		expireStaleRollouts()
+		DB.Model(&model.NodeRollout{}).Where("status = ?", "running").Count(&running)
+		if running > 0 {
+			return dto.Err("已有正在进行的发布")
+		}
+	}
+
+	if d.Percent <= 0 || d.Percent > 100 {
+		d.Percent = 100
+	}
+	if d.CanaryCount < 0 {
+		d.CanaryCount = 0
+	}
+	if d.BatchSize <= 0 {
+		d.BatchSize = defaultRolloutBatchSize
+	}
+	if d.HealthTimeout <= 0 {
+		d.HealthTimeout = defaultRolloutHealthTimeout
+	}
+
+	query := DB.Order("inx ASC, id ASC")
+	if d.GroupName != "" {
+		query = query.Where("group_name = ?", d.GroupName)
+	}
+	var nodes []model.Node
+	query.Find(&nodes)
+	if len(nodes) == 0 {
+		return dto.Err("没有符合条件的节点")
+	}
+	selected := (len(nodes)*d.Percent + 99) / 100
+	nodes = nodes[:selected]
+
+	now := time.Now().UnixMilli()
+	rollout := model.NodeRollout{
+		TargetVersion: version,
+		GroupName:     d.GroupName,
+		Percent:       d.Percent,
+		CanaryCount:   d.CanaryCount,
+		BatchSize:     d.BatchSize,
+		HealthTimeout: d.HealthTimeout,
+		Status:        "running",
+		CreatedTime:   now,
+		UpdatedTime:   now,
+	}
+	items := make([]model.NodeRolloutItem, 0, len(nodes))
+	for i, n := range nodes {
+		// Canary nodes form batch 0; the rest are split into batches of BatchSize
+		batch := 0
+		if i >= d.CanaryCount {
+			batch = (i-d.CanaryCount)/d.BatchSize + 1
+		}
+		item := model.NodeRolloutItem{NodeId: n.ID, Batch: batch, Status: "pending", FromVersion: n.Version}
+		if n.Version == version {
+			item.Status, item.Msg = "skipped", "已是目标版本"
+		}
+		items = append(items, item)
+	}
+
+	tx := DB.Begin()
+	if err := tx.Create(&rollout).Error; err != nil {
+		tx.Rollback()
+		return dto.Err("创建发布失败")
+	}
+	for i := range items {
+		items[i].RolloutId = rollout.ID
+	}
+	if err := tx.CreateInBatches(&items, 100).Error; err != nil {
+		tx.Rollback()
+		return dto.Err("创建发布失败")
+	}
+	tx.Commit()
+
+	startNodeRollout(rollout.ID)
+	return dto.Ok(rollout)
+}
+
+func ListNodeRollouts() dto.R {
+	expireStaleRollouts()
+	var list []model.NodeRollout
+	DB.Order("id DESC").Limit(50).Find(&list)
+	return dto.Ok(list)
+}
+
+func GetNodeRollout(id int64) dto.R {
+	expireStaleRollouts()
+	var rollout model.NodeRollout
+	if err := DB.First(&rollout, id).Error; err != nil {
+		return dto.Err("发布不存在")
+	}
+	var items []model.NodeRolloutItem
+	DB.Where("rollout_id = ?", id).Order("batch ASC, id ASC").Find(&items)
+	return dto.Ok(map[string]interface{}{"rollout": rollout, "items": items})
+}
+
+func CancelNodeRollout(id int64) dto.R {
+	res := DB.Model(&model.NodeRollout{}).Where("id = ? AND status = ?", id, "running").
+		Updates(map[string]interface{}{
+			"status":        "cancelled",
+			"msg":           "已取消，正在更新的节点会继续完成",
+			"finished_time": time.Now().UnixMilli(),
+		})
+	if res.RowsAffected == 0 {
+		return dto.Err("发布不在进行中")
+	}
+	return dto.Ok("已取消")
+}
+
+// ResumeNodeRollout continues a stopped rollout with its pending nodes.
+func ResumeNodeRollout(id int64) dto.R {
+	expireStaleRollouts()
+	var running int64
+	DB.Model(&model.NodeRollout{}).Where("status = ?", "running").Count(&running)
+	if running > 0 {
+		return dto.Err("已有正在进行的发布")
+	}
+	res := DB.Model(&model.NodeRollout{}).Where("id = ? AND status IN ?", id, []string{"failed", "cancelled"}).
+		Updates(map[string]interface{}{
+			"status":        "running",
+			"msg":           "",
+			"updated_time":  time.Now().UnixMilli(),
+			"finished_time": 0,
+		})
+	if res.RowsAffected == 0 {
+		return dto.Err("只能继续已失败或已取消的发布")
+	}
+	startNodeRollout(id)
+	return dto.Ok("已继续")
+}
+
+// RollbackNodeRollout puts every node this rollout updated back on the
+// binary it backed up.
+func RollbackNodeRollout(id int64) dto.R {
+	var rollout model.NodeRollout
+	if err := DB.First(&rollout, id).Error; err != nil {
+		return dto.Err("发布不存在")
+	}
+	if rollout.Status == "running" {
+		return dto.Err("请先取消正在进行的发布")
+	}
+
+	var items []model.NodeRolloutItem
+	DB.Where("rollout_id = ? AND status = ?", id, "success").Find(&items)
+	failed := 0
+	for _, item := range items {
+		result := pkg.NodeRollbackBinary(item.NodeId)
+		if result == nil || result.Msg != gostSuccessMsg {
+			msg := "节点回滚失败"
+			if result != nil {
+				msg = result.Msg
+			}
+			DB.Model(&item).Update("msg", "回滚失败: "+msg)
+			failed++
+			continue
+		}
+		DB.Model(&item).Updates(map[string]interface{}{"status": "rolled_back", "msg": "已手动回滚"})
+	}
+
+	status, msg := "rolled_back", fmt.Sprintf("已回滚 %d 个节点", len(items)-failed)
+	if failed > 0 {
+		status, msg = rollout.Status, fmt.Sprintf("%s，%d 个节点回滚失败", msg, failed)
+	}
+	DB.Model(&rollout).Updates(map[string]interface{}{"status": status, "msg": msg, "updated_time": time.Now().UnixMilli()})
+	if failed > 0 {
+		return dto.Err(msg)
+	}
+	return dto.Ok(msg)
+}
+
+// expireStaleRollouts marks running rollouts whose runner is gone (panel
+// restarted mid-rollout) as failed so they can be resumed.
+func expireStaleRollouts() {
+	var list []model.NodeRollout
+	DB.Where("status = ? AND updated_time < ?", "running", time.Now().Add(-rolloutStaleAfter).UnixMilli()).Find(&list)
+	for _, r := range list {
+		if _, ok := activeRollouts.Load(r.ID); ok {
+			continue
+		}
+		DB.Model(&model.NodeRollout{}).Where("id = ? AND status = ?", r.ID, "running").Updates(map[string]interface{}{
+			"status":        "failed",
+			"msg":           "发布已中断（面板重启），可继续",
+			"finished_time": time.Now().UnixMilli(),
+		})
+	}
+}
+
+func startNodeRollout(id int64) {
+	if _, loaded := activeRollouts.LoadOrStore(id, struct{}{}); loaded {
+		return
+	}
+	pkg.Tasks.Go(fmt.Sprintf("rollout:%d", id), func(ctx context.Context) {
+		defer activeRollouts.Delete(id)
+
+		stop := make(chan struct{})
+		defer close(stop)
+		go rolloutHeartbeat(id, stop)
+
+		runNodeRollout(ctx, id)
+	})
+}
+
+func rolloutHeartbeat(id int64, stop chan struct{}) {
+	ticker := time.NewTicker(rolloutHeartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			DB.Model(&model.NodeRollout{}).Where("id = ? AND status = ?", id, "running").
+				Update("updated_time", time.Now().UnixMilli())
+		}
+	}
+}
+
+func rolloutRunning(id int64) (*model.NodeRollout, bool) {
+	var rollout model.NodeRollout
+	if err := DB.First(&rollout, id).Error; err != nil {
+		return nil, false
+	}
+	return &rollout, rollout.Status == "running"
+}
+
+func finishRollout(id int64, status, msg string) {
+	DB.Model(&model.NodeRollout{}).Where("id = ? AND status = ?", id, "running").Updates(map[string]interface{}{
+		"status":        status,
+		"msg":           msg,
+		"updated_time":  time.Now().UnixMilli(),
+		"finished_time": time.Now().UnixMilli(),
+	})
+	log.Printf("[Rollout] #%d %s: %s", id, status, msg)
+}
+
+// runNodeRollout works through the batches in order. A batch only starts
+// once every node of the previous one reconnected on the target version;
+// any failure stops the rollout so the admin can look before resuming.
+func runNodeRollout(ctx context.Context, id int64) {
+	for {
+		rollout, ok := rolloutRunning(id)
+		if !ok {
+			return
+		}
+
+		var item model.NodeRolloutItem
+		if err := DB.Where("rollout_id = ? AND status IN ?", id, []string{"pending", "updating"}).
+			Order("batch ASC").First(&item).Error; err != nil {
+			finishRollout(id, "completed", "全部节点已完成")
+			return
+		}
+		batch := item.Batch
+
+		if failed := runRolloutBatch(ctx, rollout, batch); ctx.Err() != nil {
+			return
+		} else if failed > 0 {
+			name := fmt.Sprintf("第 %d 批", batch)
+			if batch == 0 {
+				name = "金丝雀批次"
+			}
+			finishRollout(id, "failed", fmt.Sprintf("%s有 %d 个节点更新失败，发布已暂停", name, failed))
+			return
+		}
+	}
+}
+
+// runRolloutBatch updates one batch and waits for it. Returns the number
+// of nodes that failed.
+func runRolloutBatch(ctx context.Context, rollout *model.NodeRollout, batch int) int {
+	var items []model.NodeRolloutItem
+	DB.Where("rollout_id = ? AND batch = ? AND status IN ?", rollout.ID, batch, []string{"pending", "updating"}).Find(&items)
+
+	// Send the update to the whole batch at once
+	var wg sync.WaitGroup
+	for i := range items {
+		if items[i].Status != "pending" {
+			continue // already sent before a panel restart
+		}
+		wg.Add(1)
+		go func(item *model.NodeRolloutItem) {
+			defer wg.Done()
+			startRolloutItem(item, rollout.TargetVersion)
+		}(&items[i])
+	}
+	wg.Wait()
+
+	deadline := time.Now().Add(time.Duration(rollout.HealthTimeout) * time.Second)
+	for {
+		waiting, failed := 0, 0
+		for i := range items {
+			switch items[i].Status {
+			case "updating":
+				checkRolloutItem(&items[i], rollout.TargetVersion, time.Now().After(deadline))
+				if items[i].Status == "updating" {
+					waiting++
+				}
+			}
+			if items[i].Status == "failed" || items[i].Status == "rolled_back" {
+				failed++
+			}
+		}
+		if waiting == 0 {
+			return failed
+		}
+		if _, ok := rolloutRunning(rollout.ID); !ok {
+			// Cancelled: stop waiting, the nodes finish on their own
+			return 0
+		}
+		if !pkg.Sleep(ctx, rolloutPollInterval) {
+			return 0
+		}
+	}
+}
+
+func startRolloutItem(item *model.NodeRolloutItem, version string) {
+	node := GetNodeById(item.NodeId)
+	updates := map[string]interface{}{"started_time": time.Now().UnixMilli()}
+	switch {
+	case node == nil:
+		updates["status"], updates["msg"] = "skipped", "节点已删除"
+	case node.Version == version:
+		updates["status"], updates["msg"] = "skipped", "已是目标版本"
+	case !pkg.WS.IsNodeOnline(node.ID):
+		updates["status"], updates["msg"] = "skipped", "节点不在线"
+	default:
+		updates["from_version"] = node.Version
+		if msg := sendNodeUpdate(node, version); msg != "" {
+			updates["status"], updates["msg"] = "failed", msg
+		} else {
+			updates["status"], updates["msg"] = "updating", "等待节点以新版本重连"
+		}
+	}
+	if s, _ := updates["status"].(string); s != "updating" {
+		updates["finished_time"] = time.Now().UnixMilli()
+	}
+	DB.Model(item).Updates(updates)
+}
+
+// checkRolloutItem marks an updating node healthy once it's online on the
+// target version. Past the deadline it has failed; if it's back on its old
+// version the node rolled itself back.
+func checkRolloutItem(item *model.NodeRolloutItem, version string, expired bool) {
+	node := GetNodeById(item.NodeId)
+	online := node != nil && pkg.WS.IsNodeOnline(node.ID)
+	updates := map[string]interface{}{}
+	switch {
+	case online && node.Version == version:
+		updates["status"], updates["msg"] = "success", ""
+	case !expired:
+		return
+	case online && node.Version == item.FromVersion:
+		updates["status"], updates["msg"] = "rolled_back", "新版本未能连上面板，节点已自动回滚到 "+item.FromVersion
+	case online:
+		updates["status"], updates["msg"] = "failed", "节点以非预期版本重连: "+node.Version
+	default:
+		updates["status"], updates["msg"] = "failed", "节点未能在规定时间内重新连接"
+	}
+	updates["finished_time"] = time.Now().UnixMilli()
+	DB.Model(item).Updates(updates)
+}
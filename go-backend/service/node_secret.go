@@ -0,0 +1,131 @@
+package service
+
+import (
+	"log"
+	"strings"
+	"time"
+
+	"flux-panel/go-backend/dto"
+	"flux-panel/go-backend/model"
+	"flux-panel/go-backend/pkg"
+)
+
+// After a rotation the old secret keeps working for this long, so flow
+// reports, reconnects and install links in flight don't fail.
+const defaultSecretGraceHours = 24
+
+// FindNodeBySecret returns the node whose current secret, or previous
+// secret within its grace window, is secret.
+func FindNodeBySecret(secret string) *model.Node {
+	if secret == "" {
+		return nil
+	}
+	var node model.Node
+	if err := DB.Where("secret = ?", secret).First(&node).Error; err == nil {
+		return &node
+	}
+	if err := DB.Where("prev_secret = ? AND prev_secret_expires > ?", secret, time.Now().UnixMilli()).First(&node).Error; err == nil {
+		return &node
+	}
+	return nil
+}
+
+// LookupNodeKey resolves the key id a node presents in the WebSocket
+// handshake to the node and the secret it was derived from.
+func LookupNodeKey(kid string) (int64, string) {
+	if kid == "" {
+		return 0, ""
+	}
+	var node model.Node
+	err := DB.Select("id", "secret").Where("secret_kid = ? AND pending = ?", kid, false).First(&node).Error
+	if err == nil && node.Secret != "" && pkg.NodeKeyId(node.Secret) == kid {
+		return node.ID, node.Secret
+	}
+	err = DB.Select("id", "prev_secret").
+		Where("prev_secret_kid = ? AND prev_secret_expires > ? AND pending = ?", kid, time.Now().UnixMilli(), false).
+		First(&node).Error
+	if err == nil && node.PrevSecret != "" && pkg.NodeKeyId(node.PrevSecret) == kid {
+		return node.ID, node.PrevSecret
+	}
+	return 0, ""
+}
+
+// BackfillNodeKeyIds fills the key id columns of nodes created before
+// they existed.
+func BackfillNodeKeyIds() {
+	var nodes []model.Node
+	DB.Select("id", "secret", "prev_secret").
+		Where("(secret <> '' AND (secret_kid IS NULL OR secret_kid = '')) OR (prev_secret <> '' AND (prev_secret_kid IS NULL OR prev_secret_kid = ''))").
+		Find(&nodes)
+	for _, n := range nodes {
+		updates := map[string]interface{}{}
+		if n.Secret != "" {
+			updates["secret_kid"] = pkg.NodeKeyId(n.Secret)
+		}
+		if n.PrevSecret != "" {
+			updates["prev_secret_kid"] = pkg.NodeKeyId(n.PrevSecret)
+		}
+		DB.Model(&model.Node{}).Where("id = ?", n.ID).Updates(updates)
+	}
+	if len(nodes) > 0 {
+		log.Printf("[节点密钥] 已为 %d 个节点补全密钥标识", len(nodes))
+	}
+}
+
+// RotateNodeSecret gives an online node a new secret over its live
+// connection. The panel switches first and keeps the old secret valid for
+// the grace window; if the node definitely didn't take the new one the
+// switch is undone.
+func RotateNodeSecret(id int64, graceHours int) dto.R {
+	node := GetNodeById(id)
+	if node == nil {
+		return dto.Err("节点不存在")
+	}
+	if !pkg.WS.IsNodeOnline(id) {
+		return dto.Err("节点不在线，无法轮换密钥")
+	}
+	if !pkg.WS.NodeSupports(id, "RotateSecret") {
+		return dto.Err("节点版本过旧，不支持密钥轮换，请先更新节点")
+	}
+	if graceHours <= 0 {
+		graceHours = defaultSecretGraceHours
+	}
+	grace := time.Duration(graceHours) * time.Hour
+
+	newSecret := pkg.GenerateSecureSecret()
+	now := time.Now()
+	if err := DB.Model(&model.Node{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"secret":              newSecret,
+		"secret_kid":          pkg.NodeKeyId(newSecret),
+		"prev_secret":         node.Secret,
+		"prev_secret_kid":     pkg.NodeKeyId(node.Secret),
+		"prev_secret_expires": now.Add(grace).UnixMilli(),
+		"updated_time":        now.UnixMilli(),
+	}).Error; err != nil {
+		return dto.Err("密钥轮换失败")
+	}
+
+	result := pkg.NodeRotateSecret(id, newSecret, int64(grace/time.Second))
+	if result != nil && result.Msg == gostSuccessMsg {
+		log.Printf("[密钥轮换] 节点 %d 已切换到新密钥，旧密钥有效期 %d 小时", id, graceHours)
+		return dto.Ok(map[string]interface{}{"secret": newSecret, "graceHours": graceHours})
+	}
+
+	msg := "节点未响应"
+	if result != nil {
+		msg = result.Msg
+	}
+	if strings.Contains(msg, "超时") {
+		// The node may or may not have switched; both secrets work until
+		// the grace window ends
+		return dto.Err("节点未确认密钥轮换，新旧密钥在宽限期内均有效，请稍后确认节点状态")
+	}
+	DB.Model(&model.Node{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"secret":              node.Secret,
+		"secret_kid":          node.SecretKid,
+		"prev_secret":         node.PrevSecret,
+		"prev_secret_kid":     node.PrevSecretKid,
+		"prev_secret_expires": node.PrevSecretExpires,
+	})
+	return dto.Err("密钥轮换失败: " + msg)
+}
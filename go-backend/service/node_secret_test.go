@@ -0,0 +1,51 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"flux-panel/go-backend/model"
+	"flux-panel/go-backend/pkg"
+)
+
+func TestLookupNodeKey(t *testing.T) {
+	useTestDB(t, &model.Node{})
+
+	now := time.Now()
+	nodes := []model.Node{
+		{ID: 1, Secret: "current-1", PrevSecret: "old-1", PrevSecretExpires: now.Add(time.Hour).UnixMilli()},
+		{ID: 2, Secret: "current-2", PrevSecret: "old-2", PrevSecretExpires: now.Add(-time.Hour).UnixMilli()},
+		{ID: 3, Secret: "current-3", Pending: true},
+	}
+	for i := range nodes {
+		if err := DB.Create(&nodes[i]).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Nodes created before the key id columns existed
+	BackfillNodeKeyIds()
+
+	tests := []struct {
+		secret string
+		id     int64
+	}{
+		{"current-1", 1},
+		{"old-1", 1}, // within the grace window
+		{"current-2", 2},
+		{"old-2", 0},     // grace window over
+		{"current-3", 0}, // awaiting approval
+		{"unknown", 0},
+	}
+	for _, tt := range tests {
+		id, secret := LookupNodeKey(pkg.NodeKeyId(tt.secret))
+		if id != tt.id {
+			t.Fatalf("LookupNodeKey(%s) = node %d, want %d", tt.secret, id, tt.id)
+		}
+		if id != 0 && secret != tt.secret {
+			t.Fatalf("LookupNodeKey(%s) returned secret %q", tt.secret, secret)
+		}
+	}
+	if id, _ := LookupNodeKey(""); id != 0 {
+		t.Fatalf("empty key id resolved to node %d", id)
+	}
+}
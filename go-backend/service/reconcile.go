@@ -253,6 +253,7 @@ func reconcileXrayInbounds(nodeId int64, result *ReconcileResult) {
 		// No inbounds — stop Xray if it's running (e.g. stale from before inbounds were deleted)
 		log.Printf("[Reconcile] 节点 %d 无启用的 Xray 入站，停止 Xray", nodeId)
 		pkg.XrayStop(nodeId)
+		appliedRouting.Delete(nodeId)
 		return
 	}
 
@@ -281,10 +282,12 @@ func reconcileXrayInbounds(nodeId int64, result *ReconcileResult) {
 	if xrayNotRunning {
 		// Xray not running — use ApplyConfig to write config and start the process
 		log.Printf("[Reconcile] 节点 %d Xray 未运行 (%s)，使用 ApplyConfig 启动", nodeId, firstMsg)
-		r := pkg.XrayApplyConfig(nodeId, inbounds)
+		routing := buildXrayRouting(nodeId)
+		r := pkg.XrayApplyConfig(nodeId, inbounds, routing)
 		if r != nil && r.Msg != gostSuccessMsg {
 			result.Errors = append(result.Errors, fmt.Sprintf("Xray ApplyConfig: %s", r.Msg))
 		} else {
+			appliedRouting.Store(nodeId, routing.hash())
 			DB.Model(&model.XrayInbound{}).Where("node_id = ? AND enable = -1", nodeId).Update("enable", 1)
 		}
 		result.Inbounds = len(inbounds)
@@ -308,6 +311,15 @@ func reconcileXrayInbounds(nodeId int64, result *ReconcileResult) {
 
 	// Recover error-state inbounds after successful hot-add sync
 	DB.Model(&model.XrayInbound{}).Where("node_id = ? AND enable = -1", nodeId).Update("enable", 1)
+
+	// Hot-add can't change outbounds or routing; restart Xray only when the
+	// node is running something other than the panel's routing
+	if routingOutOfSync(nodeId, buildXrayRouting(nodeId)) {
+		log.Printf("[Reconcile] 节点 %d Xray 路由配置不一致，全量同步", nodeId)
+		if msg := syncXrayNodeConfig(nodeId); msg != "" {
+			result.Errors = append(result.Errors, fmt.Sprintf("Xray 路由: %s", msg))
+		}
+	}
 }
 
 // ---------------------------------------------------------------------------
@@ -0,0 +1,361 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"flux-panel/go-backend/dto"
+	"flux-panel/go-backend/model"
+	"flux-panel/go-backend/pkg"
+
+	"gorm.io/gorm/clause"
+)
+
+// Built-in job names.
+const (
+	JobResetFlow        = "reset-flow"
+	JobStatistics       = "statistics-hourly"
+	JobLatencyMonitor   = "latency-monitor"
+	JobXrayTrafficReset = "v-traffic-reset"
+	JobXrayCertRenew    = "v-cert-renew"
+	JobXrayIPLimit      = "v-ip-limit"
+	JobNodeLinkMatrix   = "node-link-matrix"
+)
+
+// MissedPolicy decides what happens to runs that fell due while no replica
+// was running the scheduler.
+type MissedPolicy int
+
+const (
+	MissedRunOnce MissedPolicy = iota // run once as soon as possible
+	MissedReplay                      // run every missed occurrence in order
+	MissedSkip                        // wait for the next occurrence
+)
+
+const (
+	schedulerTick   = time.Second
+	maxReplayRuns   = 24 * 31 // a month of hourly runs
+	maxJobRunsKept  = 100
+	onTimeTolerance = time.Minute
+)
+
+// Job is a unit of periodic work. Run receives the time the run was
+// scheduled for, which lags behind the wall clock for replayed runs.
+type Job struct {
+	Name        string
+	Description string
+	Spec        string // default schedule, see pkg.ParseCron
+	Missed      MissedPolicy
+	Disabled    bool // created disabled; an admin has to turn it on
+	Run         func(ctx context.Context, scheduled time.Time) error
+}
+
+type jobRun struct {
+	source    string
+	scheduled time.Time
+}
+
+var (
+	jobsMu     sync.Mutex
+	jobs       []*Job
+	jobRunning sync.Map // name → struct{}
+)
+
+// RegisterJob adds a job to the scheduler. It must be called before
+// StartScheduler.
+func RegisterJob(job *Job) {
+	if _, err := pkg.ParseCron(job.Spec); err != nil {
+		log.Fatalf("[Scheduler] 任务 %s 的默认调度无效: %v", job.Name, err)
+	}
+	jobsMu.Lock()
+	jobs = append(jobs, job)
+	jobsMu.Unlock()
+}
+
+func findJob(name string) *Job {
+	jobsMu.Lock()
+	defer jobsMu.Unlock()
+	for _, j := range jobs {
+		if j.Name == name {
+			return j
+		}
+	}
+	return nil
+}
+
+func registeredJobs() []*Job {
+	jobsMu.Lock()
+	defer jobsMu.Unlock()
+	return append([]*Job(nil), jobs...)
+}
+
+// StartScheduler creates missing job rows and starts the dispatch loop.
+// Jobs are dispatched only on the cluster leader; next-run times live in
+// the DB so a new leader picks up exactly where the previous one stopped.
+func StartScheduler() {
+	now := time.Now().In(schedulerLocation())
+	for _, job := range registeredJobs() {
+		sched, _ := pkg.ParseCron(job.Spec)
+		res := DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&model.ScheduledJob{
+			Name:        job.Name,
+			Spec:        job.Spec,
+			Enabled:     true,
+			NextRunTime: sched.Next(now).UnixMilli(),
+			UpdatedTime: now.UnixMilli(),
+		})
+		// enabled has a column default, so false can't go through Create
+		if job.Disabled && res.RowsAffected > 0 {
+			DB.Model(&model.ScheduledJob{}).Where("name = ?", job.Name).Update("enabled", false)
+		}
+	}
+
+	pkg.Tasks.Go("scheduler", func(ctx context.Context) {
+		for {
+			if pkg.IsLeader() {
+				dispatchJobs(time.Now().In(schedulerLocation()))
+			}
+			if !pkg.Sleep(ctx, schedulerTick) {
+				return
+			}
+		}
+	})
+}
+
+// schedulerLocation is the zone cron expressions are evaluated in.
+func schedulerLocation() *time.Location {
+	return PanelLocation()
+}
+
+func dispatchJobs(now time.Time) {
+	var rows []model.ScheduledJob
+	if err := DB.Find(&rows).Error; err != nil {
+		return
+	}
+	byName := make(map[string]*model.ScheduledJob, len(rows))
+	for i := range rows {
+		byName[rows[i].Name] = &rows[i]
+	}
+
+	for _, job := range registeredJobs() {
+		row, ok := byName[job.Name]
+		if !ok {
+			continue
+		}
+		if _, running := jobRunning.Load(job.Name); running {
+			continue
+		}
+
+		if row.RunRequested {
+			res := DB.Model(&model.ScheduledJob{}).
+				Where("name = ? AND run_requested = ?", job.Name, true).
+				Update("run_requested", false)
+			if res.RowsAffected > 0 {
+				launchJob(job, []jobRun{{source: "manual", scheduled: now}})
+				continue
+			}
+		}
+
+		if !row.Enabled || row.NextRunTime > now.UnixMilli() {
+			continue
+		}
+		sched, err := pkg.ParseCron(row.Spec)
+		if err != nil {
+			continue
+		}
+
+		due := time.UnixMilli(row.NextRunTime).In(now.Location())
+		next := sched.Next(now)
+
+		// Claim the due run; a stale leader racing us loses here
+		res := DB.Model(&model.ScheduledJob{}).
+			Where("name = ? AND next_run_time = ?", job.Name, row.NextRunTime).
+			Update("next_run_time", next.UnixMilli())
+		if res.Error != nil || res.RowsAffected == 0 {
+			continue
+		}
+
+		onTime := now.Sub(due) < onTimeTolerance
+		var runs []jobRun
+		switch {
+		case onTime:
+			runs = []jobRun{{source: "schedule", scheduled: due}}
+		case job.Missed == MissedReplay:
+			for t := due; !t.After(now) && len(runs) < maxReplayRuns; t = sched.Next(t) {
+				runs = append(runs, jobRun{source: "catchup", scheduled: t})
+			}
+		case job.Missed == MissedRunOnce:
+			runs = []jobRun{{source: "catchup", scheduled: due}}
+		default:
+			log.Printf("[Scheduler] 跳过错过的任务 %s (应于 %s 执行)", job.Name, due.Format(time.RFC3339))
+		}
+		if len(runs) > 0 {
+			launchJob(job, runs)
+		}
+	}
+}
+
+func launchJob(job *Job, runs []jobRun) {
+	if _, loaded := jobRunning.LoadOrStore(job.Name, struct{}{}); loaded {
+		return
+	}
+	pkg.Tasks.Go("job:"+job.Name, func(ctx context.Context) {
+		defer jobRunning.Delete(job.Name)
+		for _, r := range runs {
+			if ctx.Err() != nil {
+				return
+			}
+			executeJob(ctx, job, r)
+		}
+	})
+}
+
+func executeJob(ctx context.Context, job *Job, r jobRun) {
+	start := time.Now()
+	err := func() (err error) {
+		defer func() {
+			if p := recover(); p != nil {
+				err = fmt.Errorf("panic: %v", p)
+			}
+		}()
+		return job.Run(ctx, r.scheduled)
+	}()
+	duration := time.Since(start).Milliseconds()
+
+	status, errMsg := 1, ""
+	if err != nil {
+		status, errMsg = 2, err.Error()
+		log.Printf("[Scheduler] 任务 %s 执行失败: %v", job.Name, err)
+	}
+
+	DB.Model(&model.ScheduledJob{}).Where("name = ?", job.Name).Updates(map[string]interface{}{
+		"last_run_time": start.UnixMilli(),
+		"last_duration": duration,
+		"last_status":   status,
+		"last_error":    errMsg,
+	})
+	DB.Create(&model.ScheduledJobRun{
+		JobName:      job.Name,
+		Source:       r.source,
+		ScheduledFor: r.scheduled.UnixMilli(),
+		StartTime:    start.UnixMilli(),
+		Duration:     duration,
+		Status:       status,
+		Error:        errMsg,
+	})
+
+	var cutoff []int64
+	DB.Model(&model.ScheduledJobRun{}).Where("job_name = ?", job.Name).
+		Order("id DESC").Offset(maxJobRunsKept).Limit(1).Pluck("id", &cutoff)
+	if len(cutoff) > 0 {
+		DB.Where("job_name = ? AND id <= ?", job.Name, cutoff[0]).Delete(&model.ScheduledJobRun{})
+	}
+}
+
+// ─── Admin API ───
+
+func ListScheduledJobs() dto.R {
+	var rows []model.ScheduledJob
+	DB.Find(&rows)
+	byName := make(map[string]model.ScheduledJob, len(rows))
+	for _, r := range rows {
+		byName[r.Name] = r
+	}
+
+	var result []map[string]interface{}
+	for _, job := range registeredJobs() {
+		row := byName[job.Name]
+		_, running := jobRunning.Load(job.Name)
+		result = append(result, map[string]interface{}{
+			"name":         job.Name,
+			"description":  job.Description,
+			"defaultSpec":  job.Spec,
+			"spec":         row.Spec,
+			"enabled":      row.Enabled,
+			"nextRunTime":  row.NextRunTime,
+			"lastRunTime":  row.LastRunTime,
+			"lastDuration": row.LastDuration,
+			"lastStatus":   row.LastStatus,
+			"lastError":    row.LastError,
+			"runRequested": row.RunRequested,
+			"running":      running,
+		})
+	}
+	return dto.Ok(result)
+}
+
+func UpdateScheduledJob(d dto.ScheduledJobUpdateDto) dto.R {
+	if findJob(d.Name) == nil {
+		return dto.Err("任务不存在")
+	}
+	var row model.ScheduledJob
+	if err := DB.Where("name = ?", d.Name).First(&row).Error; err != nil {
+		return dto.Err("任务不存在")
+	}
+
+	updates := map[string]interface{}{"updated_time": time.Now().UnixMilli()}
+	spec := row.Spec
+	if d.Spec != nil {
+		spec = *d.Spec
+		updates["spec"] = spec
+	}
+	sched, err := pkg.ParseCron(spec)
+	if err != nil {
+		return dto.Err("调度表达式无效: " + err.Error())
+	}
+	if d.Enabled != nil {
+		updates["enabled"] = *d.Enabled
+	}
+	// Changing the schedule or re-enabling starts counting from now, so a
+	// job that was disabled for a while doesn't fire a catch-up run
+	if d.Spec != nil || (d.Enabled != nil && *d.Enabled && !row.Enabled) {
+		updates["next_run_time"] = sched.Next(time.Now().In(schedulerLocation())).UnixMilli()
+	}
+
+	if err := DB.Model(&model.ScheduledJob{}).Where("name = ?", d.Name).Updates(updates).Error; err != nil {
+		return dto.Err("更新任务失败")
+	}
+	return dto.Ok("任务已更新")
+}
+
+// RunScheduledJobNow queues a manual run, picked up by the leader's next
+// dispatch tick.
+func RunScheduledJobNow(name string) dto.R {
+	if findJob(name) == nil {
+		return dto.Err("任务不存在")
+	}
+	if err := DB.Model(&model.ScheduledJob{}).Where("name = ?", name).
+		Update("run_requested", true).Error; err != nil {
+		return dto.Err("触发任务失败")
+	}
+	return dto.Ok("任务已加入执行队列")
+}
+
+func GetScheduledJobRuns(name string, limit int) dto.R {
+	if limit <= 0 || limit > maxJobRunsKept {
+		limit = 20
+	}
+	var runs []model.ScheduledJobRun
+	q := DB.Order("id DESC").Limit(limit)
+	if name != "" {
+		q = q.Where("job_name = ?", name)
+	}
+	q.Find(&runs)
+	return dto.Ok(runs)
+}
+
+// setJobSpec replaces a job's schedule, used when a setting that drives the
+// schedule (such as monitor_interval) changes.
+func setJobSpec(name, spec string) {
+	sched, err := pkg.ParseCron(spec)
+	if err != nil {
+		return
+	}
+	DB.Model(&model.ScheduledJob{}).Where("name = ?", name).Updates(map[string]interface{}{
+		"spec":          spec,
+		"next_run_time": sched.Next(time.Now().In(schedulerLocation())).UnixMilli(),
+		"updated_time":  time.Now().UnixMilli(),
+	})
+}
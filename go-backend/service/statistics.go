@@ -12,7 +12,8 @@ func RecordHourlyStatistics() {
 	var users []model.User
 	DB.Where("role_id != 0").Find(&users)
 
-	hour := fmt.Sprintf("%02d:00", time.Now().Hour())
+	// Labels are shown as-is, so they use the panel timezone
+	hour := fmt.Sprintf("%02d:00", time.Now().In(PanelLocation()).Hour())
 	now := time.Now().UnixMilli()
 
 	for _, user := range users {
@@ -139,5 +140,7 @@ func CleanOldMonitorData() {
 	DB.Where("record_time < ?", cutoff).Delete(&model.StatisticsXrayFlow{})
 	DB.Where("record_time < ?", cutoff).Delete(&model.StatisticsUserFlow{})
 	DB.Where("record_time < ?", cutoff).Delete(&model.MonitorLatency{})
+	DB.Where("record_time < ?", cutoff).Delete(&model.NodeMetric{})
+	DB.Where("record_time < ?", cutoff).Delete(&model.NodeLinkMetric{})
 	log.Printf("已清理 %d 天前的监控数据", days)
 }
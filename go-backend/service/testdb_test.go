@@ -0,0 +1,35 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// useTestDB points DB at a fresh in-memory SQLite database with the given
+// models migrated, and restores the previous DB when the test ends.
+func useTestDB(t *testing.T, models ...interface{}) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	// One connection: every new connection would see its own empty database.
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	if err := db.AutoMigrate(models...); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	prev := DB
+	DB = db
+	t.Cleanup(func() {
+		DB = prev
+		sqlDB.Close()
+	})
+}
@@ -0,0 +1,91 @@
+package service
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"flux-panel/go-backend/model"
+)
+
+// timezoneConfigKey holds the panel-wide IANA timezone (e.g. "Asia/Shanghai").
+// Empty means the server's local zone.
+const timezoneConfigKey = "timezone"
+
+// The panel zone is read on every reset check and dashboard request, so it
+// is cached briefly. The TTL also bounds how long other replicas keep a
+// stale value after the setting changes.
+const panelLocationTTL = time.Minute
+
+var (
+	panelLocMu      sync.Mutex
+	panelLoc        *time.Location
+	panelLocFetched time.Time
+)
+
+// PanelLocation returns the panel-wide timezone.
+func PanelLocation() *time.Location {
+	panelLocMu.Lock()
+	defer panelLocMu.Unlock()
+
+	if panelLoc != nil && time.Since(panelLocFetched) < panelLocationTTL {
+		return panelLoc
+	}
+
+	loc := time.Local
+	var cfg model.ViteConfig
+	if DB != nil && DB.Where("name = ?", timezoneConfigKey).First(&cfg).Error == nil && cfg.Value != "" {
+		if l, err := time.LoadLocation(cfg.Value); err == nil {
+			loc = l
+		}
+	}
+	panelLoc, panelLocFetched = loc, time.Now()
+	return loc
+}
+
+func invalidatePanelLocation() {
+	panelLocMu.Lock()
+	panelLoc = nil
+	panelLocMu.Unlock()
+}
+
+// UserLocation returns the user's own timezone, or the panel timezone if the
+// user has none.
+func UserLocation(user *model.User) *time.Location {
+	if user != nil && user.Timezone != "" {
+		if loc, err := time.LoadLocation(user.Timezone); err == nil {
+			return loc
+		}
+	}
+	return PanelLocation()
+}
+
+func userLocationById(userId int64) *time.Location {
+	var user model.User
+	if DB.Select("id", "timezone").First(&user, userId).Error != nil {
+		return PanelLocation()
+	}
+	return UserLocation(&user)
+}
+
+// validateTimezone accepts an empty name (inherit) or a valid IANA zone.
+func validateTimezone(name string) error {
+	if name == "" {
+		return nil
+	}
+	if _, err := time.LoadLocation(name); err != nil {
+		return fmt.Errorf("无效的时区: %s", name)
+	}
+	return nil
+}
+
+// startOfDay and startOfMonth return local midnight in loc.
+func startOfDay(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+}
+
+func startOfMonth(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, loc)
+}
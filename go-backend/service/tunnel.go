@@ -3,7 +3,6 @@ package service
 import (
 	"flux-panel/go-backend/dto"
 	"flux-panel/go-backend/model"
-	"flux-panel/go-backend/pkg"
 	"time"
 )
 
@@ -157,17 +156,48 @@ func DiagnoseTunnel(id int64) dto.R {
 		return dto.Err("入口节点不存在")
 	}
 
-	if tunnel.Type == 2 {
-		outNode := GetNodeById(tunnel.OutNodeId)
-		if outNode == nil {
-			return dto.Err("出口节点不存在")
+	if tunnel.Type != tunnelTypeTunnelForward {
+		return dto.Ok("端口转发隧道无需诊断")
+	}
+	outNode := GetNodeById(tunnel.OutNodeId)
+	if outNode == nil {
+		return dto.Err("出口节点不存在")
+	}
+
+	// Probe the exit through the port of an active forward. Without one
+	// nothing listens on the exit, so only an ICMP traceroute is possible.
+	var forward model.Forward
+	var result DiagnosisResult
+	if DB.Where("tunnel_id = ? AND status = ? AND out_port > 0", id, forwardStatusActive).First(&forward).Error == nil {
+		result = diagnosePaths([]diagnosisPath{{inNode, outNode.ServerIp, forward.OutPort, "入口->出口", nil}})[0]
+	} else {
+		result = DiagnosisResult{
+			NodeId:      inNode.ID,
+			NodeName:    inNode.Name,
+			TargetIp:    outNode.ServerIp,
+			Protocol:    "icmp",
+			Description: "入口->出口",
+			Timestamp:   time.Now().UnixMilli(),
+		}
+		result.Trace, result.TraceError = performTraceroute(inNode, outNode.ServerIp, 0, "icmp")
+		if result.Trace != nil && result.Trace.Reached {
+			result.Success = true
+			result.Message = "隧道下没有运行中的转发，仅进行ICMP路由追踪"
+		} else {
+			result.Message = "出口节点不可达"
+			if result.TraceError != "" {
+				result.Message = result.TraceError
+			}
 		}
-		// TCP ping from inNode to outNode
-		result := TcpPingNode(inNode.ID, outNode.ServerIp, 0)
-		return dto.Ok(result)
 	}
 
-	return dto.Ok("端口转发隧道无需诊断")
+	return dto.Ok(map[string]interface{}{
+		"tunnelId":   tunnel.ID,
+		"tunnelName": tunnel.Name,
+		"tunnelType": "隧道转发",
+		"results":    []DiagnosisResult{result},
+		"timestamp":  time.Now().UnixMilli(),
+	})
 }
 
 func GetUserAccessibleTunnels(userId int64, roleId int) dto.R {
@@ -204,14 +234,3 @@ func GetUserAccessibleTunnels(userId int64, roleId int) dto.R {
 
 	return dto.Ok(tunnels)
 }
-
-func TcpPingNode(nodeId int64, ip string, port int) interface{} {
-	data := map[string]interface{}{
-		"ip":      ip,
-		"port":    port,
-		"count":   2,
-		"timeout": 3000,
-	}
-	result := pkg.WS.SendMsg(nodeId, data, "TcpPing")
-	return result
-}
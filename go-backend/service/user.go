@@ -8,6 +8,8 @@ import (
 	"log"
 	"strings"
 	"time"
+
+	"gorm.io/gorm"
 )
 
 // ---------------------------------------------------------------------------
@@ -37,6 +39,7 @@ type UserInfoDto struct {
 	ExpTime       int64  `json:"expTime"`
 	FlowResetType int    `json:"flowResetType"`
 	FlowResetDay  int    `json:"flowResetDay"`
+	Timezone      string `json:"timezone"`
 	GostEnabled   int    `json:"gostEnabled"`
 	XrayEnabled   int    `json:"vEnabled"`
 	CreatedTime   int64  `json:"createdTime"`
@@ -167,6 +170,9 @@ func CreateUser(d dto.UserDto) dto.R {
 	if len(d.Pwd) < 8 {
 		return dto.Err("密码长度至少8位")
 	}
+	if err := validateTimezone(d.Timezone); err != nil {
+		return dto.Err(err.Error())
+	}
 
 	// 1. Check username uniqueness
 	var count int64
@@ -192,6 +198,7 @@ func CreateUser(d dto.UserDto) dto.R {
 		ExpTime:       d.ExpTime,
 		FlowResetType: d.FlowResetType,
 		FlowResetDay:  d.FlowResetDay,
+		Timezone:      d.Timezone,
 		Status:        status,
 		GostEnabled:   1,
 		XrayEnabled:   1,
@@ -331,6 +338,12 @@ func UpdateUser(d dto.UserUpdateDto) dto.R {
 	if d.Status != nil {
 		updates["status"] = *d.Status
 	}
+	if d.Timezone != nil {
+		if err := validateTimezone(*d.Timezone); err != nil {
+			return dto.Err(err.Error())
+		}
+		updates["timezone"] = *d.Timezone
+	}
 	if d.Pwd != "" {
 		if len(d.Pwd) < 8 {
 			return dto.Err("密码长度至少8位")
@@ -454,8 +467,15 @@ func DeleteUser(id int64) dto.R {
 	DB.Where("user_id = ?", id).Delete(&model.StatisticsFlow{})
 	DB.Where("user_id = ?", id).Delete(&model.StatisticsUserFlow{})
 
-	// 6. Delete the user
-	if err := DB.Delete(&model.User{}, id).Error; err != nil {
+	// 6. Delete the user together with its subscription tokens, so no
+	// token outlives the user it authenticates
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", id).Delete(&model.SubscriptionToken{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&model.User{}, id).Error
+	})
+	if err != nil {
 		return dto.Err("用户删除失败")
 	}
 
@@ -526,6 +546,7 @@ func GetUserPackageInfo(userId int64, roleId int) dto.R {
 		ExpTime:       user.ExpTime,
 		FlowResetType: user.FlowResetType,
 		FlowResetDay:  user.FlowResetDay,
+		Timezone:      user.Timezone,
 		GostEnabled:   user.GostEnabled,
 		XrayEnabled:   user.XrayEnabled,
 		CreatedTime:   user.CreatedTime,
@@ -617,7 +638,7 @@ func GetUserPackageInfo(userId int64, roleId int) dto.R {
 	statisticsFlows = append(statisticsFlows, recentFlows...)
 
 	if len(statisticsFlows) < 24 {
-		startHour := time.Now().Hour()
+		startHour := time.Now().In(PanelLocation()).Hour()
 		if len(statisticsFlows) > 0 {
 			lastTime := statisticsFlows[len(statisticsFlows)-1].Time
 			startHour = parseHour(lastTime) - 1
@@ -651,11 +672,11 @@ func GetUserPackageInfo(userId int64, roleId int) dto.R {
 // parseHour extracts the hour integer from a "HH:00" time string.
 func parseHour(timeStr string) int {
 	if timeStr == "" {
-		return time.Now().Hour()
+		return time.Now().In(PanelLocation()).Hour()
 	}
 	var h int
 	if _, err := fmt.Sscanf(timeStr, "%d:", &h); err != nil {
-		return time.Now().Hour()
+		return time.Now().In(PanelLocation()).Hour()
 	}
 	return h
 }
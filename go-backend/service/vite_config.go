@@ -3,6 +3,7 @@ package service
 import (
 	"fmt"
 	"log"
+	"strconv"
 	"strings"
 	"time"
 
@@ -106,6 +107,18 @@ func UpdateSingleConfig(name, value string) dto.R {
 }
 
 func updateOrCreateConfig(name, value string) error {
+	if name == timezoneConfigKey {
+		if err := validateTimezone(value); err != nil {
+			return err
+		}
+		defer invalidatePanelLocation()
+	}
+	if name == singboxDNSTemplateKey || name == singboxRouteTemplateKey {
+		if err := validateSingboxTemplate(value); err != nil {
+			return err
+		}
+	}
+
 	var cfg model.ViteConfig
 	result := DB.Where("name = ?", name).First(&cfg)
 
@@ -127,5 +140,12 @@ func updateOrCreateConfig(name, value string) error {
 			return err
 		}
 	}
+
+	// The latency monitor's schedule follows its interval setting
+	if name == "monitor_interval" {
+		if v, err := strconv.Atoi(value); err == nil && v > 0 {
+			setJobSpec(JobLatencyMonitor, fmt.Sprintf("@every %ds", v))
+		}
+	}
 	return nil
 }
@@ -44,8 +44,14 @@ type streamSettings struct {
 	XhttpSettings struct {
 		Path string `json:"path"`
 		Host string `json:"host"`
+		Mode string `json:"mode"`
 	} `json:"xhttpSettings"`
 
+	HttpSettings struct {
+		Path string   `json:"path"`
+		Host []string `json:"host"`
+	} `json:"httpSettings"`
+
 	KcpSettings struct {
 		Header struct {
 			Type string `json:"type"`
@@ -71,6 +77,10 @@ type streamSettings struct {
 // inboundSettings represents the parsed settings_json from an inbound
 type inboundSettings struct {
 	Method string `json:"method"`
+
+	// Hysteria2 / TUIC (served by sing-box on the node)
+	ObfsPassword      string `json:"obfsPassword"`
+	CongestionControl string `json:"congestionControl"`
 }
 
 func parseStreamSettings(jsonStr string) *streamSettings {
@@ -152,6 +162,10 @@ func CreateXrayClient(d dto.XrayClientDto, userId int64, roleId int) dto.R {
 		client.Reset = *d.Reset
 	}
 
+	if msg := validateSingboxClient(inbound.Protocol, d.UuidOrPassword); msg != "" {
+		return dto.Err(msg)
+	}
+
 	// Generate UUID or use specified password
 	if d.UuidOrPassword != "" {
 		client.UuidOrPassword = d.UuidOrPassword
@@ -176,6 +190,9 @@ func CreateXrayClient(d dto.XrayClientDto, userId int64, roleId int) dto.R {
 		DB.Delete(&client)
 		return dto.Err("Xray 热加载客户端失败: " + result.Msg)
 	}
+	if client.UserId > 0 {
+		refreshUserRouting(inbound.NodeId, client.UserId)
+	}
 
 	return dto.Ok(client)
 }
@@ -233,6 +250,9 @@ func UpdateXrayClient(d dto.XrayClientUpdateDto, userId int64, roleId int) dto.R
 			return *r
 		}
 	}
+	if msg := validateSingboxClient(inbound.Protocol, d.UuidOrPassword); msg != "" {
+		return dto.Err(msg)
+	}
 
 	updates := map[string]interface{}{"updated_time": time.Now().UnixMilli()}
 	if d.Email != "" {
@@ -273,8 +293,9 @@ func UpdateXrayClient(d dto.XrayClientUpdateDto, userId int64, roleId int) dto.R
 
 	// Hot update: remove old user + add new user (no Xray restart needed)
 	if inbound.ID > 0 {
-		// Remove old user (only if was enabled; skip if disabled since user isn't in Xray)
-		if oldClient.Enable == 1 {
+		// Remove old user (only if was enabled; skip if disabled or IP-banned since user isn't in Xray)
+		banned := oldClient.IpBanUntil > time.Now().UnixMilli()
+		if oldClient.Enable == 1 && !banned {
 			removeResult := pkg.XrayRemoveClient(inbound.NodeId, inbound.Tag, oldClient.Email)
 			if removeResult != nil && removeResult.Msg != "OK" {
 				// Revert DB
@@ -286,13 +307,13 @@ func UpdateXrayClient(d dto.XrayClientUpdateDto, userId int64, roleId int) dto.R
 		// Reload updated client from DB
 		DB.First(&existing, d.ID)
 
-		// Only re-add if enabled
-		if existing.Enable == 1 {
+		// Only re-add if enabled; a banned client is restored by the IP limit job
+		if existing.Enable == 1 && !banned {
 			result := pkg.XrayAddClient(inbound.NodeId, inbound.Tag, existing.Email, existing.UuidOrPassword, existing.Flow, existing.AlterId, inbound.Protocol)
 			if result != nil && result.Msg != "OK" {
 				// Revert: restore old client in both DB and Xray
 				DB.Save(&oldClient)
-				if oldClient.Enable == 1 {
+				if oldClient.Enable == 1 && !banned {
 					pkg.XrayAddClient(inbound.NodeId, inbound.Tag, oldClient.Email, oldClient.UuidOrPassword, oldClient.Flow, oldClient.AlterId, inbound.Protocol)
 				}
 				return dto.Err("Xray 热加载客户端失败，已回退: " + result.Msg)
@@ -366,7 +387,7 @@ func ResetXrayClientTraffic(id int64, userId int64, roleId int) dto.R {
 	if client.Enable == 0 && client.TotalTraffic > 0 {
 		DB.Model(&client).Update("enable", 1)
 		var inbound model.XrayInbound
-		if err := DB.First(&inbound, client.InboundId).Error; err == nil {
+		if err := DB.First(&inbound, client.InboundId).Error; err == nil && client.IpBanUntil <= time.Now().UnixMilli() {
 			pkg.XrayAddClient(inbound.NodeId, inbound.Tag, client.Email, client.UuidOrPassword, client.Flow, client.AlterId, inbound.Protocol)
 		}
 	}
@@ -374,14 +395,25 @@ func ResetXrayClientTraffic(id int64, userId int64, roleId int) dto.R {
 	return dto.Ok("流量已重置")
 }
 
-func GetSubscriptionLinks(userId int64) dto.R {
-	// Check Xray permission
+// subscriptionEntry is one client as it appears in a user's subscription,
+// whatever the output format.
+type subscriptionEntry struct {
+	Client  model.XrayClient
+	Inbound model.XrayInbound
+	Node    *model.Node
+	Remark  string
+	Host    string // address clients dial
+}
+
+// subscriptionEntries collects the enabled clients visible to the user on
+// enabled inbounds of online nodes. ok is false if the user doesn't exist.
+func subscriptionEntries(userId int64) (entries []subscriptionEntry, ok bool) {
 	var user model.User
 	if err := DB.First(&user, userId).Error; err != nil {
-		return dto.Err("用户不存在")
+		return nil, false
 	}
 	if user.RoleId != 0 && user.XrayEnabled != 1 {
-		return dto.Ok([]map[string]interface{}{})
+		return nil, true
 	}
 
 	var clients []model.XrayClient
@@ -391,8 +423,6 @@ func GetSubscriptionLinks(userId int64) dto.R {
 		DB.Where("user_id = ? AND enable = 1", userId).Find(&clients)
 	}
 
-	var links []map[string]interface{}
-
 	for _, client := range clients {
 		var inbound model.XrayInbound
 		if err := DB.First(&inbound, client.InboundId).Error; err != nil || inbound.Enable != 1 {
@@ -409,46 +439,79 @@ func GetSubscriptionLinks(userId int64) dto.R {
 			continue
 		}
 
-		link := generateProtocolLink(&client, &inbound, node)
-		if link != "" {
-			remark := client.Remark
-			if remark == "" {
-				remark = inbound.Remark
-			}
-			if remark == "" {
-				remark = inbound.Tag
-			}
+		entries = append(entries, subscriptionEntry{
+			Client:  client,
+			Inbound: inbound,
+			Node:    node,
+			Remark:  defaultRemark(&client, &inbound),
+			Host:    inboundHost(&inbound, node),
+		})
+	}
+	return entries, true
+}
 
+// GetSubscriptionLinks returns the user's share links. A nil profile
+// includes every entry with its default remark.
+func GetSubscriptionLinks(userId int64, profile *model.SubscriptionProfile) dto.R {
+	entries, ok := subscriptionEntries(userId)
+	if !ok {
+		return dto.Err("用户不存在")
+	}
+	entries = applySubscriptionProfile(entries, profile)
+
+	var links []map[string]interface{}
+	for i := range entries {
+		e := &entries[i]
+		link := generateEntryLink(e)
+		if link != "" {
 			links = append(links, map[string]interface{}{
 				"link":     link,
-				"protocol": inbound.Protocol,
-				"remark":   remark,
-				"nodeName": node.Name,
+				"protocol": e.Inbound.Protocol,
+				"remark":   e.Remark,
+				"nodeName": e.Node.Name,
 			})
 		}
 	}
-
 	return dto.Ok(links)
 }
 
-func generateProtocolLink(client *model.XrayClient, inbound *model.XrayInbound, node *model.Node) string {
-	host := node.ServerIp
+// inboundHost returns the address clients should connect to for an inbound.
+func inboundHost(inbound *model.XrayInbound, node *model.Node) string {
 	if inbound.CustomEntry != "" {
-		host = inbound.CustomEntry
+		return inbound.CustomEntry
 	}
-	port := inbound.Port
-	remark := client.Remark
-	if remark == "" {
-		remark = inbound.Remark
+	return node.ServerIp
+}
+
+// defaultRemark names a client by its own remark, then the inbound's.
+func defaultRemark(client *model.XrayClient, inbound *model.XrayInbound) string {
+	if client.Remark != "" {
+		return client.Remark
 	}
-	if remark == "" {
-		remark = inbound.Tag
+	if inbound.Remark != "" {
+		return inbound.Remark
 	}
+	return inbound.Tag
+}
 
-	ss := parseStreamSettings(inbound.StreamSettingsJson)
-	is := parseInboundSettings(inbound.SettingsJson)
+func generateProtocolLink(client *model.XrayClient, inbound *model.XrayInbound, node *model.Node) string {
+	return generateEntryLink(&subscriptionEntry{
+		Client:  *client,
+		Inbound: *inbound,
+		Node:    node,
+		Remark:  defaultRemark(client, inbound),
+		Host:    inboundHost(inbound, node),
+	})
+}
+
+func generateEntryLink(e *subscriptionEntry) string {
+	client := &e.Client
+	host, port, remark := e.Host, e.Inbound.Port, e.Remark
+
+	ss := parseStreamSettings(e.Inbound.StreamSettingsJson)
+	is := parseInboundSettings(e.Inbound.SettingsJson)
 
-	switch inbound.Protocol {
+	switch e.Inbound.Protocol {
 	case "vmess":
 		return generateVmessLink(client, host, port, remark, ss)
 	case "vless":
@@ -457,6 +520,10 @@ func generateProtocolLink(client *model.XrayClient, inbound *model.XrayInbound,
 		return generateTrojanLink(client, host, port, remark, ss)
 	case "shadowsocks":
 		return generateShadowsocksLink(client, host, port, remark, is)
+	case "hysteria2":
+		return generateHysteria2Link(client, host, port, remark, ss, is)
+	case "tuic":
+		return generateTuicLink(client, host, port, remark, ss, is)
 	default:
 		return ""
 	}
@@ -473,6 +540,8 @@ func streamPath(ss *streamSettings) string {
 		return ss.HttpupgradeSettings.Path
 	case "xhttp", "splithttp":
 		return ss.XhttpSettings.Path
+	case "http", "h2":
+		return ss.HttpSettings.Path
 	case "tcp":
 		if len(ss.TcpSettings.Header.Request.Path) > 0 {
 			return ss.TcpSettings.Header.Request.Path[0]
@@ -492,6 +561,8 @@ func streamHost(ss *streamSettings) string {
 		return ss.HttpupgradeSettings.Host
 	case "xhttp", "splithttp":
 		return ss.XhttpSettings.Host
+	case "http", "h2":
+		return strings.Join(ss.HttpSettings.Host, ",")
 	case "tcp":
 		if hosts, ok := ss.TcpSettings.Header.Request.Headers["Host"]; ok && len(hosts) > 0 {
 			return hosts[0]
@@ -650,6 +721,50 @@ func generateShadowsocksLink(client *model.XrayClient, host string, port int, re
 	return fmt.Sprintf("ss://%s@%s:%d#%s", encoded, host, port, url.QueryEscape(remark))
 }
 
+// quicALPN returns the ALPN list for QUIC-based protocols, which only
+// negotiate h3. The node applies the same default.
+func quicALPN(ss *streamSettings) []string {
+	for _, a := range ss.TlsSettings.Alpn {
+		if a == "h3" {
+			return ss.TlsSettings.Alpn
+		}
+	}
+	return []string{"h3"}
+}
+
+// tuicCongestionControl returns the inbound's congestion control, defaulting
+// to bbr like the node does.
+func tuicCongestionControl(is *inboundSettings) string {
+	if is.CongestionControl != "" {
+		return is.CongestionControl
+	}
+	return "bbr"
+}
+
+func generateHysteria2Link(client *model.XrayClient, host string, port int, remark string, ss *streamSettings, is *inboundSettings) string {
+	params := url.Values{}
+	if ss.TlsSettings.ServerName != "" {
+		params.Set("sni", ss.TlsSettings.ServerName)
+	}
+	if is.ObfsPassword != "" {
+		params.Set("obfs", "salamander")
+		params.Set("obfs-password", is.ObfsPassword)
+	}
+	params.Set("alpn", strings.Join(quicALPN(ss), ","))
+	return fmt.Sprintf("hysteria2://%s@%s:%d/?%s#%s", url.PathEscape(client.UuidOrPassword), host, port, params.Encode(), url.QueryEscape(remark))
+}
+
+func generateTuicLink(client *model.XrayClient, host string, port int, remark string, ss *streamSettings, is *inboundSettings) string {
+	params := url.Values{}
+	if ss.TlsSettings.ServerName != "" {
+		params.Set("sni", ss.TlsSettings.ServerName)
+	}
+	params.Set("congestion_control", tuicCongestionControl(is))
+	params.Set("alpn", strings.Join(quicALPN(ss), ","))
+	params.Set("udp_relay_mode", "native")
+	return fmt.Sprintf("tuic://%s:%s@%s:%d/?%s#%s", client.UuidOrPassword, client.UuidOrPassword, host, port, params.Encode(), url.QueryEscape(remark))
+}
+
 func GetClientLink(clientId int64, userId int64, roleId int) dto.R {
 	if r := checkXrayPermission(userId, roleId); r != nil {
 		return *r
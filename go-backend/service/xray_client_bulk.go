@@ -0,0 +1,554 @@
+package service
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"flux-panel/go-backend/dto"
+	"flux-panel/go-backend/model"
+	"flux-panel/go-backend/pkg"
+)
+
+const maxBulkClients = 1000
+
+// bulkItemResult is the outcome for one client of a bulk operation.
+type bulkItemResult struct {
+	ID      int64  `json:"id"`
+	Email   string `json:"email"`
+	Success bool   `json:"success"`
+	Msg     string `json:"msg,omitempty"`
+}
+
+type bulkReport struct {
+	Total   int              `json:"total"`
+	Success int              `json:"success"`
+	Failed  int              `json:"failed"`
+	Results []bulkItemResult `json:"results"`
+}
+
+func (r *bulkReport) add(client *model.XrayClient, msg string) {
+	r.Total++
+	item := bulkItemResult{ID: client.ID, Email: client.Email, Success: msg == "", Msg: msg}
+	if item.Success {
+		r.Success++
+	} else {
+		r.Failed++
+	}
+	r.Results = append(r.Results, item)
+}
+
+func (r *bulkReport) addMissing(id int64, msg string) {
+	r.Total++
+	r.Failed++
+	r.Results = append(r.Results, bulkItemResult{ID: id, Msg: msg})
+}
+
+// clientLive reports whether the client is currently deployed on its node.
+func clientLive(c *model.XrayClient, now int64) bool {
+	return c.Enable == 1 && c.IpBanUntil <= now
+}
+
+// nodeAddClients pushes clients to an inbound in one VAddClients command.
+// Nodes that predate it get one VAddClient per client. Returns email ->
+// error message for the clients that didn't make it.
+func nodeAddClients(inbound *model.XrayInbound, clients []model.XrayClient) map[string]string {
+	failed := map[string]string{}
+	if len(clients) == 0 {
+		return failed
+	}
+	result := pkg.XrayAddClients(inbound.NodeId, inbound.Tag, inbound.Protocol, clients)
+	if result == nil || result.Msg == gostSuccessMsg {
+		return failed
+	}
+	if !strings.Contains(result.Msg, pkg.UnknownCommandMsg) {
+		for _, c := range clients {
+			failed[c.Email] = result.Msg
+		}
+		return failed
+	}
+	for _, c := range clients {
+		r := pkg.XrayAddClient(inbound.NodeId, inbound.Tag, c.Email, c.UuidOrPassword, c.Flow, c.AlterId, inbound.Protocol)
+		if r != nil && r.Msg != gostSuccessMsg {
+			failed[c.Email] = r.Msg
+		}
+	}
+	return failed
+}
+
+// nodeRemoveClients is the VRemoveClients counterpart of nodeAddClients.
+func nodeRemoveClients(inbound *model.XrayInbound, emails []string) map[string]string {
+	failed := map[string]string{}
+	if len(emails) == 0 {
+		return failed
+	}
+	result := pkg.XrayRemoveClients(inbound.NodeId, inbound.Tag, emails)
+	if result == nil || result.Msg == gostSuccessMsg {
+		return failed
+	}
+	if !strings.Contains(result.Msg, pkg.UnknownCommandMsg) {
+		for _, e := range emails {
+			failed[e] = result.Msg
+		}
+		return failed
+	}
+	for _, e := range emails {
+		r := pkg.XrayRemoveClient(inbound.NodeId, inbound.Tag, e)
+		if r != nil && r.Msg != gostSuccessMsg {
+			failed[e] = r.Msg
+		}
+	}
+	return failed
+}
+
+// bulkClientGroup is the selected clients of one inbound.
+type bulkClientGroup struct {
+	Inbound model.XrayInbound
+	Clients []model.XrayClient
+}
+
+// loadBulkClients loads the clients behind ids, grouped by inbound, with the
+// same ownership and node checks as the single-client endpoints. Clients
+// that fail them are reported right away.
+func loadBulkClients(ids []int64, userId int64, roleId int, report *bulkReport) []*bulkClientGroup {
+	if len(ids) > maxBulkClients {
+		ids = ids[:maxBulkClients]
+	}
+	var clients []model.XrayClient
+	DB.Where("id IN ?", ids).Find(&clients)
+	found := make(map[int64]bool, len(clients))
+
+	var groups []*bulkClientGroup
+	byInbound := map[int64]*bulkClientGroup{}
+	for _, c := range clients {
+		found[c.ID] = true
+		if roleId != 0 && c.UserId != userId {
+			report.add(&c, "无权操作此客户端")
+			continue
+		}
+		g, ok := byInbound[c.InboundId]
+		if !ok {
+			g = &bulkClientGroup{}
+			if err := DB.First(&g.Inbound, c.InboundId).Error; err != nil {
+				g.Inbound = model.XrayInbound{}
+			} else if r := checkXrayNodeAccess(userId, roleId, g.Inbound.NodeId); r != nil {
+				report.add(&c, r.Msg)
+				continue
+			}
+			byInbound[c.InboundId] = g
+			groups = append(groups, g)
+		}
+		g.Clients = append(g.Clients, c)
+	}
+	for _, id := range ids {
+		if !found[id] {
+			report.addMissing(id, "客户端不存在")
+			found[id] = true
+		}
+	}
+	return groups
+}
+
+// ---------------------------------------------------------------------------
+// Bulk create
+// ---------------------------------------------------------------------------
+
+func BulkCreateXrayClients(d dto.XrayClientBulkCreateDto, userId int64, roleId int) dto.R {
+	if r := checkXrayPermission(userId, roleId); r != nil {
+		return *r
+	}
+	if d.Count < 1 || d.Count > maxBulkClients {
+		return dto.Err(fmt.Sprintf("数量必须在 1 到 %d 之间", maxBulkClients))
+	}
+
+	var inbound model.XrayInbound
+	if err := DB.First(&inbound, d.InboundId).Error; err != nil {
+		return dto.Err("入站不存在")
+	}
+	if r := checkXrayNodeAccess(userId, roleId, inbound.NodeId); r != nil {
+		return *r
+	}
+	if roleId != 0 {
+		d.UserId = userId
+	}
+	if d.UserId > 0 {
+		var user model.User
+		if err := DB.First(&user, d.UserId).Error; err != nil {
+			return dto.Err("用户不存在")
+		}
+	}
+
+	tpl := d.EmailTemplate
+	if tpl == "" {
+		tpl = "{user}_{rand}_{n}@flux"
+	}
+	if !strings.Contains(tpl, "{n}") && !strings.Contains(tpl, "{rand}") {
+		return dto.Err("邮箱模板必须包含 {n} 或 {rand}，以保证唯一")
+	}
+	start := d.StartIndex
+	if start <= 0 {
+		start = 1
+	}
+
+	now := time.Now().UnixMilli()
+	clients := make([]model.XrayClient, 0, d.Count)
+	emails := make([]string, 0, d.Count)
+	seen := map[string]bool{}
+	for i := 0; i < d.Count; i++ {
+		n := strconv.Itoa(start + i)
+		email := strings.NewReplacer(
+			"{prefix}", d.Prefix,
+			"{n}", n,
+			"{user}", strconv.FormatInt(d.UserId, 10),
+			"{rand}", strings.ToLower(generateRandomString(6)),
+		).Replace(tpl)
+		if seen[email] {
+			return dto.Err("邮箱模板生成了重复的邮箱: " + email)
+		}
+		seen[email] = true
+		emails = append(emails, email)
+
+		client := model.XrayClient{
+			InboundId:   inbound.ID,
+			UserId:      d.UserId,
+			Email:       email,
+			Flow:        d.Flow,
+			Enable:      1,
+			Remark:      d.Prefix + n,
+			CreatedTime: now,
+			UpdatedTime: now,
+		}
+		if inbound.Protocol == "shadowsocks" {
+			client.UuidOrPassword = generateRandomString(16)
+		} else {
+			client.UuidOrPassword = generateUUID()
+		}
+		if d.TotalTraffic != nil {
+			client.TotalTraffic = *d.TotalTraffic
+		}
+		if d.ExpTime != nil {
+			client.ExpTime = d.ExpTime
+		}
+		if d.LimitIp != nil {
+			client.LimitIp = *d.LimitIp
+		}
+		if d.Reset != nil {
+			client.Reset = *d.Reset
+		}
+		clients = append(clients, client)
+	}
+
+	var taken []string
+	DB.Model(&model.XrayClient{}).Where("email IN ?", emails).Pluck("email", &taken)
+	if len(taken) > 0 {
+		return dto.Err("邮箱已存在: " + strings.Join(taken, ", "))
+	}
+
+	if err := DB.CreateInBatches(&clients, 100).Error; err != nil {
+		return dto.Err("创建客户端失败")
+	}
+
+	report := &bulkReport{}
+	failed := nodeAddClients(&inbound, clients)
+	var failedIds []int64
+	for i := range clients {
+		msg := failed[clients[i].Email]
+		if msg != "" {
+			failedIds = append(failedIds, clients[i].ID)
+			msg = "Xray 热加载客户端失败: " + msg
+		}
+		report.add(&clients[i], msg)
+	}
+	if len(failedIds) > 0 {
+		DB.Where("id IN ?", failedIds).Delete(&model.XrayClient{})
+	}
+	if d.UserId > 0 && report.Success > 0 {
+		refreshUserRouting(inbound.NodeId, d.UserId)
+	}
+	return dto.Ok(report)
+}
+
+// ---------------------------------------------------------------------------
+// Bulk enable / disable
+// ---------------------------------------------------------------------------
+
+func BulkSetXrayClientsEnable(ids []int64, enable bool, userId int64, roleId int) dto.R {
+	if r := checkXrayPermission(userId, roleId); r != nil {
+		return *r
+	}
+	report := &bulkReport{}
+	now := time.Now().UnixMilli()
+	target := 0
+	if enable {
+		target = 1
+	}
+
+	for _, g := range loadBulkClients(ids, userId, roleId, report) {
+		var pending []model.XrayClient
+		for _, c := range g.Clients {
+			if c.Enable == target {
+				report.add(&c, "")
+				continue
+			}
+			pending = append(pending, c)
+		}
+		if len(pending) == 0 {
+			continue
+		}
+
+		// Only clients that are (or will be) live are touched on the node;
+		// banned ones are restored by the IP limit job
+		failed := map[string]string{}
+		if g.Inbound.ID > 0 {
+			var live []model.XrayClient
+			var liveEmails []string
+			for _, c := range pending {
+				if c.IpBanUntil <= now {
+					live = append(live, c)
+					liveEmails = append(liveEmails, c.Email)
+				}
+			}
+			if enable {
+				failed = nodeAddClients(&g.Inbound, live)
+			} else {
+				failed = nodeRemoveClients(&g.Inbound, liveEmails)
+			}
+		}
+
+		var okIds []int64
+		for i := range pending {
+			if msg := failed[pending[i].Email]; msg != "" {
+				report.add(&pending[i], "节点同步失败: "+msg)
+				continue
+			}
+			okIds = append(okIds, pending[i].ID)
+			report.add(&pending[i], "")
+		}
+		if len(okIds) > 0 {
+			DB.Model(&model.XrayClient{}).Where("id IN ?", okIds).
+				Updates(map[string]interface{}{"enable": target, "updated_time": now})
+		}
+	}
+	return dto.Ok(report)
+}
+
+// ---------------------------------------------------------------------------
+// Bulk delete
+// ---------------------------------------------------------------------------
+
+func BulkDeleteXrayClients(ids []int64, userId int64, roleId int) dto.R {
+	if r := checkXrayPermission(userId, roleId); r != nil {
+		return *r
+	}
+	report := &bulkReport{}
+	now := time.Now().UnixMilli()
+
+	for _, g := range loadBulkClients(ids, userId, roleId, report) {
+		// Skip the node if it's offline — services aren't running
+		failed := map[string]string{}
+		if g.Inbound.ID > 0 && pkg.WS != nil && pkg.WS.IsNodeOnline(g.Inbound.NodeId) {
+			var liveEmails []string
+			for i := range g.Clients {
+				if clientLive(&g.Clients[i], now) {
+					liveEmails = append(liveEmails, g.Clients[i].Email)
+				}
+			}
+			failed = nodeRemoveClients(&g.Inbound, liveEmails)
+		}
+
+		var okIds []int64
+		for i := range g.Clients {
+			if msg := failed[g.Clients[i].Email]; msg != "" {
+				report.add(&g.Clients[i], "Xray 热移除客户端失败: "+msg)
+				continue
+			}
+			okIds = append(okIds, g.Clients[i].ID)
+			report.add(&g.Clients[i], "")
+		}
+		if len(okIds) > 0 {
+			DB.Where("id IN ?", okIds).Delete(&model.XrayClient{})
+		}
+	}
+	return dto.Ok(report)
+}
+
+// ---------------------------------------------------------------------------
+// Bulk traffic reset
+// ---------------------------------------------------------------------------
+
+func BulkResetXrayClientTraffic(ids []int64, userId int64, roleId int) dto.R {
+	if r := checkXrayPermission(userId, roleId); r != nil {
+		return *r
+	}
+	report := &bulkReport{}
+	now := time.Now().UnixMilli()
+
+	for _, g := range loadBulkClients(ids, userId, roleId, report) {
+		var all []int64
+		var revive []model.XrayClient
+		for _, c := range g.Clients {
+			all = append(all, c.ID)
+			// Re-enable clients that were auto-disabled due to traffic limit
+			if c.Enable == 0 && c.TotalTraffic > 0 && c.IpBanUntil <= now {
+				revive = append(revive, c)
+			}
+		}
+		DB.Model(&model.XrayClient{}).Where("id IN ?", all).Updates(map[string]interface{}{
+			"up_traffic":   0,
+			"down_traffic": 0,
+			"updated_time": now,
+		})
+
+		failed := map[string]string{}
+		if g.Inbound.ID > 0 && len(revive) > 0 {
+			failed = nodeAddClients(&g.Inbound, revive)
+			var revived []int64
+			for _, c := range revive {
+				if failed[c.Email] == "" {
+					revived = append(revived, c.ID)
+				}
+			}
+			if len(revived) > 0 {
+				DB.Model(&model.XrayClient{}).Where("id IN ?", revived).Update("enable", 1)
+			}
+		}
+		for i := range g.Clients {
+			msg := ""
+			if f := failed[g.Clients[i].Email]; f != "" {
+				msg = "流量已重置，但重新启用失败: " + f
+			}
+			report.add(&g.Clients[i], msg)
+		}
+	}
+	return dto.Ok(report)
+}
+
+// ---------------------------------------------------------------------------
+// Bulk move
+// ---------------------------------------------------------------------------
+
+// clientFitsProtocol reports why a client's credentials can't be used on an
+// inbound of the given protocol, or "" if they can. Passwords carry over
+// between trojan, shadowsocks and hysteria2; the UUID protocols need a UUID.
+func clientFitsProtocol(c *model.XrayClient, protocol string) string {
+	switch protocol {
+	case "vmess", "vless":
+		if !uuidPattern.MatchString(c.UuidOrPassword) {
+			return protocol + " 客户端必须使用 UUID"
+		}
+	}
+	return validateSingboxClient(protocol, c.UuidOrPassword)
+}
+
+func BulkMoveXrayClients(d dto.XrayClientBulkMoveDto, userId int64, roleId int) dto.R {
+	if r := checkXrayPermission(userId, roleId); r != nil {
+		return *r
+	}
+	var target model.XrayInbound
+	if err := DB.First(&target, d.InboundId).Error; err != nil {
+		return dto.Err("目标入站不存在")
+	}
+	if r := checkXrayNodeAccess(userId, roleId, target.NodeId); r != nil {
+		return *r
+	}
+
+	report := &bulkReport{}
+	now := time.Now().UnixMilli()
+	movedUsers := map[int64]bool{}
+
+	for _, g := range loadBulkClients(d.Ids, userId, roleId, report) {
+		var movable []model.XrayClient
+		for i := range g.Clients {
+			c := &g.Clients[i]
+			if c.InboundId == target.ID {
+				report.add(c, "")
+				continue
+			}
+			if msg := clientFitsProtocol(c, target.Protocol); msg != "" {
+				report.add(c, msg)
+				continue
+			}
+			movable = append(movable, *c)
+		}
+		if len(movable) == 0 {
+			continue
+		}
+
+		// Take live clients off the source inbound first
+		var liveEmails []string
+		for i := range movable {
+			if clientLive(&movable[i], now) {
+				liveEmails = append(liveEmails, movable[i].Email)
+			}
+		}
+		removeFailed := map[string]string{}
+		if g.Inbound.ID > 0 && pkg.WS != nil && pkg.WS.IsNodeOnline(g.Inbound.NodeId) {
+			removeFailed = nodeRemoveClients(&g.Inbound, liveEmails)
+		}
+
+		var moved []model.XrayClient
+		for _, c := range movable {
+			if msg := removeFailed[c.Email]; msg != "" {
+				report.add(&c, "从原入站移除失败: "+msg)
+				continue
+			}
+			c.InboundId = target.ID
+			if target.Protocol != "vless" {
+				c.Flow = ""
+			}
+			moved = append(moved, c)
+		}
+
+		var live []model.XrayClient
+		for i := range moved {
+			if clientLive(&moved[i], now) {
+				live = append(live, moved[i])
+			}
+		}
+		addFailed := nodeAddClients(&target, live)
+
+		var restore []model.XrayClient
+		for i := range moved {
+			c := &moved[i]
+			if msg := addFailed[c.Email]; msg != "" {
+				report.add(c, "加入目标入站失败: "+msg)
+				orig := *c
+				orig.InboundId = g.Inbound.ID
+				restore = append(restore, orig)
+				continue
+			}
+			DB.Model(&model.XrayClient{}).Where("id = ?", c.ID).Updates(map[string]interface{}{
+				"inbound_id":   target.ID,
+				"flow":         c.Flow,
+				"updated_time": now,
+			})
+			report.add(c, "")
+			if c.UserId > 0 {
+				movedUsers[c.UserId] = true
+			}
+		}
+		// Best effort: put clients that couldn't move back where they were
+		if len(restore) > 0 && g.Inbound.ID > 0 {
+			var back []model.XrayClient
+			for i := range restore {
+				for _, orig := range g.Clients {
+					if orig.ID == restore[i].ID && clientLive(&orig, now) {
+						back = append(back, orig)
+					}
+				}
+			}
+			nodeAddClients(&g.Inbound, back)
+		}
+	}
+
+	// Only rules that select moved users by id need the new clients
+	if len(movedUsers) > 0 {
+		userIds := make([]int64, 0, len(movedUsers))
+		for id := range movedUsers {
+			userIds = append(userIds, id)
+		}
+		refreshUsersRouting(target.NodeId, userIds)
+	}
+	return dto.Ok(report)
+}
@@ -127,6 +127,12 @@ func CreateXrayInbound(d dto.XrayInboundDto, userId int64, roleId int) dto.R {
 	if node == nil {
 		return dto.Err("节点不存在")
 	}
+	if msg := validateSingboxInbound(d.Protocol, d.StreamSettingsJson); msg != "" {
+		return dto.Err(msg)
+	}
+	if msg := validateInboundJSON(d.Protocol, d.SettingsJson, d.StreamSettingsJson, d.SniffingJson); msg != "" {
+		return dto.Err(msg)
+	}
 
 	// Check port conflict
 	var portCount int64
@@ -163,6 +169,10 @@ func CreateXrayInbound(d dto.XrayInboundDto, userId int64, roleId int) dto.R {
 		UpdatedTime:        time.Now().UnixMilli(),
 	}
 
+	if msg := validateInboundOnNode(&inbound); msg != "" {
+		return dto.Err(msg)
+	}
+
 	if err := DB.Create(&inbound).Error; err != nil {
 		return dto.Err("创建入站失败")
 	}
@@ -289,6 +299,41 @@ func UpdateXrayInbound(d dto.XrayInboundUpdateDto, userId int64, roleId int) dto
 		}
 	}
 
+	protocol, stream := existing.Protocol, existing.StreamSettingsJson
+	if d.Protocol != "" {
+		protocol = d.Protocol
+	}
+	if d.StreamSettingsJson != "" {
+		stream = d.StreamSettingsJson
+	}
+	if msg := validateSingboxInbound(protocol, stream); msg != "" {
+		return dto.Err(msg)
+	}
+
+	candidate := existing
+	candidate.Protocol, candidate.StreamSettingsJson = protocol, stream
+	if d.Tag != "" {
+		candidate.Tag = d.Tag
+	}
+	if d.Listen != "" {
+		candidate.Listen = d.Listen
+	}
+	if d.Port != nil {
+		candidate.Port = *d.Port
+	}
+	if d.SettingsJson != "" {
+		candidate.SettingsJson = d.SettingsJson
+	}
+	if d.SniffingJson != "" {
+		candidate.SniffingJson = d.SniffingJson
+	}
+	if msg := validateInboundJSON(candidate.Protocol, candidate.SettingsJson, candidate.StreamSettingsJson, candidate.SniffingJson); msg != "" {
+		return dto.Err(msg)
+	}
+	if msg := validateInboundOnNode(&candidate); msg != "" {
+		return dto.Err(msg)
+	}
+
 	// Save old state before updating (for rollback on sync failure)
 	oldInbound := existing
 
@@ -484,8 +529,9 @@ func mergeClientsIntoSettings(inbound *model.XrayInbound) string {
 	// Read method for shadowsocks (Xray requires each client to have its own method)
 	ssMethod, _ := settings["method"].(string)
 
+	// Clients serving an IP limit ban stay off the node until restored
 	var clients []model.XrayClient
-	DB.Where("inbound_id = ? AND enable = 1", inbound.ID).Find(&clients)
+	DB.Where("inbound_id = ? AND enable = 1 AND ip_ban_until <= ?", inbound.ID, time.Now().UnixMilli()).Find(&clients)
 
 	clientArr := []map[string]interface{}{}
 	for _, c := range clients {
@@ -508,6 +554,12 @@ func mergeClientsIntoSettings(inbound *model.XrayInbound) string {
 					obj["method"] = ssMethod
 				}
 			}
+		case "hysteria2":
+			obj["password"] = c.UuidOrPassword
+		case "tuic":
+			// One UUID per client, reused as the TUIC password
+			obj["id"] = c.UuidOrPassword
+			obj["password"] = c.UuidOrPassword
 		}
 		clientArr = append(clientArr, obj)
 	}
@@ -527,10 +579,12 @@ func syncXrayNodeConfig(nodeId int64) string {
 	for i := range inbounds {
 		inbounds[i].SettingsJson = mergeClientsIntoSettings(&inbounds[i])
 	}
-	result := pkg.XrayApplyConfig(nodeId, inbounds)
+	routing := buildXrayRouting(nodeId)
+	result := pkg.XrayApplyConfig(nodeId, inbounds, routing)
 	if result != nil && result.Msg != "OK" {
 		log.Printf("全量同步 Xray 配置到节点 %d 失败: %s", nodeId, result.Msg)
 		return result.Msg
 	}
+	appliedRouting.Store(nodeId, routing.hash())
 	return ""
 }
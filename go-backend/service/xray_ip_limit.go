@@ -0,0 +1,206 @@
+package service
+
+import (
+	"encoding/json"
+	"log"
+	"strconv"
+	"time"
+
+	"flux-panel/go-backend/dto"
+	"flux-panel/go-backend/model"
+	"flux-panel/go-backend/pkg"
+
+	"gorm.io/gorm/clause"
+)
+
+// IP limit settings (seconds). A client exceeding limit_ip distinct source
+// IPs within the window is removed from its node for the ban duration.
+const (
+	ipLimitWindowKey      = "ip_limit_window"
+	ipLimitBanDurationKey = "ip_limit_ban_duration"
+
+	defaultIPLimitWindow      = 180
+	defaultIPLimitBanDuration = 600
+
+	// IP records are kept this long for the online IP list, independent of
+	// the enforcement window.
+	clientIPRetention = 24 * time.Hour
+)
+
+func configSeconds(name string, def int) time.Duration {
+	var cfg model.ViteConfig
+	if err := DB.Where("name = ?", name).First(&cfg).Error; err == nil {
+		if v, err := strconv.Atoi(cfg.Value); err == nil && v > 0 {
+			return time.Duration(v) * time.Second
+		}
+	}
+	return time.Duration(def) * time.Second
+}
+
+// ProcessXrayIPReport records the source IPs a node saw per client and
+// bans clients that exceed their IP limit.
+func ProcessXrayIPReport(rawData, secret string) string {
+	node := FindNodeBySecret(secret)
+	if node == nil {
+		log.Printf("[IP限制] 无效的节点密钥")
+		return "ok"
+	}
+
+	var data dto.XrayIPReportDto
+	if err := json.Unmarshal([]byte(decryptIfNeeded(rawData, secret)), &data); err != nil {
+		log.Printf("[IP限制] JSON解析失败: %v", err)
+		return "ok"
+	}
+
+	now := time.Now()
+	window := configSeconds(ipLimitWindowKey, defaultIPLimitWindow)
+
+	for _, c := range data.Clients {
+		if c.Email == "" || len(c.IPs) == 0 {
+			continue
+		}
+		var client model.XrayClient
+		if err := DB.Where("email = ?", c.Email).First(&client).Error; err != nil {
+			continue
+		}
+
+		for _, seen := range c.IPs {
+			if seen.IP == "" {
+				continue
+			}
+			// Clamp node clocks that run ahead of the panel
+			lastSeen := seen.LastSeen * 1000
+			if lastSeen <= 0 || lastSeen > now.UnixMilli() {
+				lastSeen = now.UnixMilli()
+			}
+			DB.Clauses(clause.OnConflict{
+				Columns: []clause.Column{{Name: "client_id"}, {Name: "ip"}},
+				DoUpdates: clause.Assignments(map[string]interface{}{
+					"node_id":   node.ID,
+					"last_seen": clause.Expr{SQL: "GREATEST(last_seen, ?)", Vars: []interface{}{lastSeen}},
+				}),
+			}).Create(&model.XrayClientIp{
+				ClientId:  client.ID,
+				Ip:        seen.IP,
+				NodeId:    node.ID,
+				FirstSeen: lastSeen,
+				LastSeen:  lastSeen,
+			})
+		}
+
+		if client.LimitIp > 0 && client.Enable == 1 && client.IpBanUntil <= now.UnixMilli() {
+			enforceClientIPLimit(&client, now, window)
+		}
+	}
+	return "ok"
+}
+
+func enforceClientIPLimit(client *model.XrayClient, now time.Time, window time.Duration) {
+	var count int64
+	DB.Model(&model.XrayClientIp{}).
+		Where("client_id = ? AND last_seen >= ?", client.ID, now.Add(-window).UnixMilli()).
+		Count(&count)
+	if count <= int64(client.LimitIp) {
+		return
+	}
+
+	banUntil := now.Add(configSeconds(ipLimitBanDurationKey, defaultIPLimitBanDuration)).UnixMilli()
+	res := DB.Model(&model.XrayClient{}).
+		Where("id = ? AND ip_ban_until <= ?", client.ID, now.UnixMilli()).
+		Update("ip_ban_until", banUntil)
+	if res.RowsAffected == 0 {
+		return // already banned by a concurrent report
+	}
+	log.Printf("[IP限制] 客户端 %s 在 %s 内使用了 %d 个 IP (上限 %d)，暂时移除至 %s",
+		client.Email, window, count, client.LimitIp, time.UnixMilli(banUntil).Format(time.RFC3339))
+
+	var inbound model.XrayInbound
+	if err := DB.First(&inbound, client.InboundId).Error; err == nil {
+		pkg.XrayRemoveClient(inbound.NodeId, inbound.Tag, client.Email)
+	}
+}
+
+// restoreIPBannedClients re-adds clients whose IP ban has expired and drops
+// IP records that are no longer needed.
+func restoreIPBannedClients() {
+	now := time.Now().UnixMilli()
+
+	var clients []model.XrayClient
+	DB.Where("ip_ban_until > 0 AND ip_ban_until <= ?", now).Find(&clients)
+	for _, client := range clients {
+		res := DB.Model(&model.XrayClient{}).
+			Where("id = ? AND ip_ban_until = ?", client.ID, client.IpBanUntil).
+			Update("ip_ban_until", 0)
+		if res.RowsAffected == 0 {
+			continue
+		}
+		// Start a fresh window so the IPs that triggered the ban don't
+		// immediately trigger it again
+		DB.Where("client_id = ?", client.ID).Delete(&model.XrayClientIp{})
+
+		if client.Enable != 1 {
+			continue
+		}
+		var inbound model.XrayInbound
+		if err := DB.First(&inbound, client.InboundId).Error; err != nil || inbound.Enable != 1 {
+			continue
+		}
+		pkg.XrayAddClient(inbound.NodeId, inbound.Tag, client.Email, client.UuidOrPassword, client.Flow, client.AlterId, inbound.Protocol)
+		log.Printf("[IP限制] 客户端 %s 封禁到期，已恢复", client.Email)
+	}
+
+	DB.Where("last_seen < ?", time.Now().Add(-clientIPRetention).UnixMilli()).Delete(&model.XrayClientIp{})
+}
+
+// GetXrayClientOnlineIPs lists the IPs a client used within the IP limit
+// window, plus older ones from the last day for context.
+func GetXrayClientOnlineIPs(id, userId int64, roleId int) dto.R {
+	if r := checkXrayPermission(userId, roleId); r != nil {
+		return *r
+	}
+
+	var client model.XrayClient
+	if err := DB.First(&client, id).Error; err != nil {
+		return dto.Err("客户端不存在")
+	}
+	if roleId != 0 && client.UserId != userId {
+		return dto.Err("无权操作此客户端")
+	}
+
+	window := configSeconds(ipLimitWindowKey, defaultIPLimitWindow)
+	windowStart := time.Now().Add(-window).UnixMilli()
+
+	var records []model.XrayClientIp
+	DB.Where("client_id = ?", client.ID).Order("last_seen DESC").Find(&records)
+
+	nodeNames := make(map[int64]string)
+	ips := make([]map[string]interface{}, 0, len(records))
+	online := 0
+	for _, r := range records {
+		if _, ok := nodeNames[r.NodeId]; !ok {
+			if node := GetNodeById(r.NodeId); node != nil {
+				nodeNames[r.NodeId] = node.Name
+			}
+		}
+		isOnline := r.LastSeen >= windowStart
+		if isOnline {
+			online++
+		}
+		ips = append(ips, map[string]interface{}{
+			"ip":        r.Ip,
+			"nodeId":    r.NodeId,
+			"nodeName":  nodeNames[r.NodeId],
+			"firstSeen": r.FirstSeen,
+			"lastSeen":  r.LastSeen,
+			"online":    isOnline,
+		})
+	}
+
+	return dto.Ok(map[string]interface{}{
+		"limitIp":     client.LimitIp,
+		"ipBanUntil":  client.IpBanUntil,
+		"windowSec":   int(window / time.Second),
+		"onlineCount": online,
+		"ips":         ips,
+	})
+}
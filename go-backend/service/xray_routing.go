@@ -0,0 +1,713 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"flux-panel/go-backend/dto"
+	"flux-panel/go-backend/model"
+	"flux-panel/go-backend/pkg"
+)
+
+// Outbound tags the node's base config already uses.
+var reservedOutboundTags = map[string]bool{"api": true, "direct": true, "blocked": true}
+
+var xrayOutboundProtocols = map[string]bool{
+	"freedom": true, "blackhole": true, "dns": true, "socks": true, "http": true,
+	"wireguard": true, "vmess": true, "vless": true, "trojan": true, "shadowsocks": true,
+	"chain": true,
+}
+
+var routingPortPattern = regexp.MustCompile(`^\d+(-\d+)?(,\d+(-\d+)?)*$`)
+
+// appliedRouting remembers the routing hash each node last accepted, so
+// reconcile only restarts Xray when routing actually changed. It is per
+// process: after a backend restart, nodes with routing are re-applied once.
+var appliedRouting sync.Map // nodeId -> hash
+
+// xrayRoutingPayload is the "routing" part of VApplyConfig.
+type xrayRoutingPayload struct {
+	DomainStrategy string                   `json:"domainStrategy,omitempty"`
+	Outbounds      []map[string]interface{} `json:"outbounds"`
+	Rules          []map[string]interface{} `json:"rules"`
+}
+
+func (p *xrayRoutingPayload) hash() string {
+	data, _ := json.Marshal(p)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// buildXrayRouting renders a node's enabled outbounds and rules into Xray
+// JSON. Rules whose inbound/user conditions resolve to nothing on the node
+// are dropped: an empty list would make Xray match everything.
+func buildXrayRouting(nodeId int64) *xrayRoutingPayload {
+	payload := &xrayRoutingPayload{
+		Outbounds: []map[string]interface{}{},
+		Rules:     []map[string]interface{}{},
+	}
+
+	var outbounds []model.XrayOutbound
+	DB.Where("node_id = ? AND enable = 1", nodeId).Order("inx ASC, id ASC").Find(&outbounds)
+	tags := map[string]bool{"direct": true, "blocked": true}
+	for i := range outbounds {
+		ob, err := renderXrayOutbound(&outbounds[i])
+		if err != nil {
+			log.Printf("[Routing] 节点 %d 出站 %s 已跳过: %v", nodeId, outbounds[i].Tag, err)
+			continue
+		}
+		payload.Outbounds = append(payload.Outbounds, ob)
+		tags[outbounds[i].Tag] = true
+	}
+
+	var rules []model.XrayRoutingRule
+	DB.Where("node_id = ? AND enable = 1", nodeId).Order("inx ASC, id ASC").Find(&rules)
+	for i := range rules {
+		if !tags[rules[i].OutboundTag] {
+			continue
+		}
+		if rule, ok := renderXrayRule(nodeId, &rules[i]); ok {
+			payload.Rules = append(payload.Rules, rule)
+		}
+	}
+	return payload
+}
+
+func renderXrayOutbound(o *model.XrayOutbound) (map[string]interface{}, error) {
+	var ob map[string]interface{}
+	if o.Protocol == "chain" {
+		var err error
+		if ob, err = buildChainOutbound(o); err != nil {
+			return nil, err
+		}
+	} else {
+		ob = map[string]interface{}{"protocol": o.Protocol}
+		if settings := parseJSONObject(o.SettingsJson); settings != nil {
+			ob["settings"] = settings
+		}
+		if stream := parseJSONObject(o.StreamSettingsJson); stream != nil {
+			ob["streamSettings"] = stream
+		}
+	}
+	ob["tag"] = o.Tag
+
+	if o.DialerProxy != "" {
+		stream, _ := ob["streamSettings"].(map[string]interface{})
+		if stream == nil {
+			stream = map[string]interface{}{}
+		}
+		sockopt, _ := stream["sockopt"].(map[string]interface{})
+		if sockopt == nil {
+			sockopt = map[string]interface{}{}
+		}
+		sockopt["dialerProxy"] = o.DialerProxy
+		stream["sockopt"] = sockopt
+		ob["streamSettings"] = stream
+	}
+	return ob, nil
+}
+
+func renderXrayRule(nodeId int64, r *model.XrayRoutingRule) (map[string]interface{}, bool) {
+	rule := map[string]interface{}{"type": "field", "outboundTag": r.OutboundTag}
+
+	if ids := parseIdList(r.InboundIds); len(ids) > 0 {
+		var tags []string
+		var inbounds []model.XrayInbound
+		DB.Where("id IN ? AND node_id = ? AND enable = 1", ids, nodeId).Find(&inbounds)
+		for _, ib := range inbounds {
+			// Hysteria2/TUIC inbounds live in sing-box, not Xray
+			if !isSingboxProtocol(ib.Protocol) {
+				tags = append(tags, ib.Tag)
+			}
+		}
+		if len(tags) == 0 {
+			return nil, false
+		}
+		rule["inboundTag"] = tags
+	}
+
+	if ids := parseIdList(r.UserIds); len(ids) > 0 {
+		var emails []string
+		DB.Model(&model.XrayClient{}).
+			Joins("JOIN xray_inbound ON xray_inbound.id = xray_client.inbound_id").
+			Where("xray_client.user_id IN ? AND xray_inbound.node_id = ?", ids, nodeId).
+			Pluck("xray_client.email", &emails)
+		if len(emails) == 0 {
+			return nil, false
+		}
+		sort.Strings(emails)
+		rule["user"] = emails
+	}
+
+	if list := splitLines(r.Domain); len(list) > 0 {
+		rule["domain"] = list
+	}
+	if list := splitLines(r.Ip); len(list) > 0 {
+		rule["ip"] = list
+	}
+	if r.Port != "" {
+		rule["port"] = r.Port
+	}
+	if r.Network != "" {
+		rule["network"] = r.Network
+	}
+	if list := splitComma(r.Protocol); len(list) > 0 {
+		rule["protocol"] = list
+	}
+	return rule, true
+}
+
+// buildChainOutbound turns one of our inbounds on another node into a
+// client-side outbound, authenticating as the client created for it.
+func buildChainOutbound(o *model.XrayOutbound) (map[string]interface{}, error) {
+	var client model.XrayClient
+	if err := DB.First(&client, o.ChainClientId).Error; err != nil {
+		return nil, fmt.Errorf("链式出站客户端不存在")
+	}
+	var inbound model.XrayInbound
+	if err := DB.First(&inbound, o.ChainInboundId).Error; err != nil {
+		return nil, fmt.Errorf("链式出站目标入站不存在")
+	}
+	node := GetNodeById(inbound.NodeId)
+	if node == nil {
+		return nil, fmt.Errorf("链式出站目标节点不存在")
+	}
+	host := inboundHost(&inbound, node)
+
+	var settings map[string]interface{}
+	switch inbound.Protocol {
+	case "vmess":
+		settings = map[string]interface{}{"vnext": []map[string]interface{}{{
+			"address": host, "port": inbound.Port,
+			"users": []map[string]interface{}{{"id": client.UuidOrPassword, "alterId": client.AlterId, "security": "auto"}},
+		}}}
+	case "vless":
+		user := map[string]interface{}{"id": client.UuidOrPassword, "encryption": "none"}
+		if client.Flow != "" {
+			user["flow"] = client.Flow
+		}
+		settings = map[string]interface{}{"vnext": []map[string]interface{}{{
+			"address": host, "port": inbound.Port, "users": []map[string]interface{}{user},
+		}}}
+	case "trojan":
+		settings = map[string]interface{}{"servers": []map[string]interface{}{{
+			"address": host, "port": inbound.Port, "password": client.UuidOrPassword,
+		}}}
+	case "shadowsocks":
+		method := parseInboundSettings(inbound.SettingsJson).Method
+		if method == "" {
+			method = "aes-256-gcm"
+		}
+		settings = map[string]interface{}{"servers": []map[string]interface{}{{
+			"address": host, "port": inbound.Port, "method": method, "password": client.UuidOrPassword,
+		}}}
+	default:
+		return nil, fmt.Errorf("链式出站不支持 %s 入站", inbound.Protocol)
+	}
+
+	ob := map[string]interface{}{"protocol": inbound.Protocol, "settings": settings}
+	if stream := clientStreamSettings(inbound.StreamSettingsJson); stream != nil {
+		ob["streamSettings"] = stream
+	}
+	return ob, nil
+}
+
+// clientStreamSettings converts server-side stream settings into what a
+// client needs: transport settings carry over, TLS/REALITY keep only the
+// fields a client sends.
+func clientStreamSettings(streamJSON string) map[string]interface{} {
+	stream := parseJSONObject(streamJSON)
+	if stream == nil {
+		return nil
+	}
+	delete(stream, "sockopt")
+
+	ss := parseStreamSettings(streamJSON)
+	switch ss.Security {
+	case "tls":
+		tls := map[string]interface{}{}
+		if ss.TlsSettings.ServerName != "" {
+			tls["serverName"] = ss.TlsSettings.ServerName
+		}
+		if len(ss.TlsSettings.Alpn) > 0 {
+			tls["alpn"] = ss.TlsSettings.Alpn
+		}
+		if ss.TlsSettings.Fingerprint != "" {
+			tls["fingerprint"] = ss.TlsSettings.Fingerprint
+		}
+		stream["tlsSettings"] = tls
+	case "reality":
+		reality := map[string]interface{}{"publicKey": ss.RealitySettings.PublicKey}
+		if len(ss.RealitySettings.ServerNames) > 0 {
+			reality["serverName"] = ss.RealitySettings.ServerNames[0]
+		}
+		if len(ss.RealitySettings.ShortIds) > 0 {
+			reality["shortId"] = ss.RealitySettings.ShortIds[0]
+		}
+		if ss.RealitySettings.SpiderX != "" {
+			reality["spiderX"] = ss.RealitySettings.SpiderX
+		}
+		fp := ss.RealitySettings.Fingerprint
+		if fp == "" {
+			fp = "chrome"
+		}
+		reality["fingerprint"] = fp
+		stream["realitySettings"] = reality
+	}
+	return stream
+}
+
+// parseJSONObject returns nil for empty or non-object JSON.
+func parseJSONObject(value string) map[string]interface{} {
+	if strings.TrimSpace(value) == "" {
+		return nil
+	}
+	var obj map[string]interface{}
+	if err := json.Unmarshal([]byte(value), &obj); err != nil {
+		return nil
+	}
+	return obj
+}
+
+func parseIdList(list string) []int64 {
+	positions := idPositions(list)
+	ids := make([]int64, len(positions))
+	for id, pos := range positions {
+		ids[pos] = id
+	}
+	return ids
+}
+
+func splitLines(value string) []string {
+	var out []string
+	for _, line := range strings.Split(value, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			out = append(out, line)
+		}
+	}
+	return out
+}
+
+func splitComma(value string) []string {
+	var out []string
+	for _, part := range strings.Split(value, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+// routingOutOfSync reports whether the node hasn't accepted this routing
+// yet. Nodes never synced by this process count as having the defaults.
+func routingOutOfSync(nodeId int64, payload *xrayRoutingPayload) bool {
+	applied, ok := appliedRouting.Load(nodeId)
+	if !ok {
+		return len(payload.Outbounds) > 0 || len(payload.Rules) > 0
+	}
+	return applied.(string) != payload.hash()
+}
+
+// applyXrayRouting pushes the node's routing with a full config sync. Offline
+// nodes are skipped; reconcile applies routing when they reconnect.
+func applyXrayRouting(nodeId int64) string {
+	if pkg.WS == nil || !pkg.WS.IsNodeOnline(nodeId) {
+		appliedRouting.Delete(nodeId)
+		return ""
+	}
+	return syncXrayNodeConfig(nodeId)
+}
+
+// refreshUserRouting re-syncs a node whose rules select clients by user, so
+// a client created for that user is routed right away.
+func refreshUserRouting(nodeId, userId int64) {
+	refreshUsersRouting(nodeId, []int64{userId})
+}
+
+// refreshUsersRouting re-syncs a node once if any of its rules selects one
+// of the users. Nodes without such rules are left alone, as a sync restarts
+// Xray.
+func refreshUsersRouting(nodeId int64, userIds []int64) {
+	var rules []model.XrayRoutingRule
+	DB.Where("node_id = ? AND enable = 1 AND user_ids <> ''", nodeId).Find(&rules)
+	for _, r := range rules {
+		selected := idPositions(r.UserIds)
+		for _, userId := range userIds {
+			if _, ok := selected[userId]; ok {
+				go applyXrayRouting(nodeId)
+				return
+			}
+		}
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Validation
+// ---------------------------------------------------------------------------
+
+func validateXrayOutbound(d *dto.XrayOutboundDto, id int64) string {
+	d.Tag = strings.TrimSpace(d.Tag)
+	if d.Tag == "" || reservedOutboundTags[d.Tag] {
+		return "出站标签不能为空或使用保留标签 api/direct/blocked"
+	}
+	if !xrayOutboundProtocols[d.Protocol] {
+		return "不支持的出站协议: " + d.Protocol
+	}
+	var count int64
+	DB.Model(&model.XrayOutbound{}).Where("node_id = ? AND tag = ? AND id <> ?", d.NodeId, d.Tag, id).Count(&count)
+	if count > 0 {
+		return "该节点已存在相同标签的出站"
+	}
+
+	if d.Protocol == "chain" {
+		var inbound model.XrayInbound
+		if err := DB.First(&inbound, d.ChainInboundId).Error; err != nil {
+			return "链式出站目标入站不存在"
+		}
+		if inbound.NodeId == d.NodeId {
+			return "链式出站目标必须位于其他节点"
+		}
+		switch inbound.Protocol {
+		case "vmess", "vless", "trojan", "shadowsocks":
+		default:
+			return "链式出站不支持 " + inbound.Protocol + " 入站"
+		}
+	} else {
+		for _, v := range []string{d.SettingsJson, d.StreamSettingsJson} {
+			if strings.TrimSpace(v) != "" && parseJSONObject(v) == nil {
+				return "出站配置必须是 JSON 对象"
+			}
+		}
+	}
+
+	if d.DialerProxy != "" {
+		if d.DialerProxy == d.Tag {
+			return "前置代理不能是出站自身"
+		}
+		DB.Model(&model.XrayOutbound{}).Where("node_id = ? AND tag = ?", d.NodeId, d.DialerProxy).Count(&count)
+		if count == 0 {
+			return "前置代理出站不存在: " + d.DialerProxy
+		}
+	}
+	return ""
+}
+
+func validateXrayRoutingRule(d *dto.XrayRoutingRuleDto) string {
+	if reservedOutboundTags[d.OutboundTag] {
+		if d.OutboundTag == "api" {
+			return "不能路由到 api 出站"
+		}
+	} else {
+		var count int64
+		DB.Model(&model.XrayOutbound{}).Where("node_id = ? AND tag = ?", d.NodeId, d.OutboundTag).Count(&count)
+		if count == 0 {
+			return "目标出站不存在: " + d.OutboundTag
+		}
+	}
+
+	for _, list := range []string{d.InboundIds, d.UserIds} {
+		for _, part := range splitComma(list) {
+			if id, err := strconv.ParseInt(part, 10, 64); err != nil || id <= 0 {
+				return "ID 列表格式错误: " + part
+			}
+		}
+	}
+	for _, ip := range splitLines(d.Ip) {
+		if strings.HasPrefix(ip, "geoip:") || strings.HasPrefix(ip, "ext:") || net.ParseIP(ip) != nil {
+			continue
+		}
+		if _, _, err := net.ParseCIDR(ip); err != nil {
+			return "IP 格式错误: " + ip
+		}
+	}
+	for _, domain := range splitLines(d.Domain) {
+		if strings.HasPrefix(domain, "regexp:") {
+			if _, err := regexp.Compile(strings.TrimPrefix(domain, "regexp:")); err != nil {
+				return "正则表达式错误: " + domain
+			}
+		}
+	}
+	d.Port = strings.ReplaceAll(d.Port, " ", "")
+	if d.Port != "" && !routingPortPattern.MatchString(d.Port) {
+		return "端口格式错误，如 53,443,1000-2000"
+	}
+	switch d.Network {
+	case "", "tcp", "udp", "tcp,udp":
+	default:
+		return "网络类型只能是 tcp、udp 或 tcp,udp"
+	}
+	for _, p := range splitComma(d.Protocol) {
+		switch p {
+		case "http", "tls", "quic", "bittorrent":
+		default:
+			return "不支持的嗅探协议: " + p
+		}
+	}
+
+	if d.InboundIds == "" && d.UserIds == "" && d.Domain == "" && d.Ip == "" &&
+		d.Port == "" && d.Network == "" && d.Protocol == "" {
+		return "路由规则至少需要一个匹配条件"
+	}
+	return ""
+}
+
+// ---------------------------------------------------------------------------
+// Outbound CRUD (admin)
+// ---------------------------------------------------------------------------
+
+func ListXrayOutbounds(nodeId int64) dto.R {
+	var list []model.XrayOutbound
+	DB.Where("node_id = ?", nodeId).Order("inx ASC, id ASC").Find(&list)
+	return dto.Ok(list)
+}
+
+func CreateXrayOutbound(d dto.XrayOutboundDto) dto.R {
+	if GetNodeById(d.NodeId) == nil {
+		return dto.Err("节点不存在")
+	}
+	if msg := validateXrayOutbound(&d, 0); msg != "" {
+		return dto.Err(msg)
+	}
+
+	now := time.Now().UnixMilli()
+	ob := model.XrayOutbound{
+		NodeId:             d.NodeId,
+		Tag:                d.Tag,
+		Protocol:           d.Protocol,
+		SettingsJson:       d.SettingsJson,
+		StreamSettingsJson: d.StreamSettingsJson,
+		DialerProxy:        d.DialerProxy,
+		Remark:             d.Remark,
+		Inx:                d.Inx,
+		Enable:             1,
+		CreatedTime:        now,
+		UpdatedTime:        now,
+	}
+	if d.Protocol == "chain" {
+		ob.ChainInboundId = d.ChainInboundId
+		clientId, msg := createChainClient(d.NodeId, d.ChainInboundId, d.Tag)
+		if msg != "" {
+			return dto.Err(msg)
+		}
+		ob.ChainClientId = clientId
+	}
+	if err := DB.Create(&ob).Error; err != nil {
+		deleteChainClient(ob.ChainClientId)
+		return dto.Err("创建出站失败")
+	}
+
+	if msg := applyXrayRouting(d.NodeId); msg != "" {
+		DB.Delete(&ob)
+		deleteChainClient(ob.ChainClientId)
+		return dto.Err("同步路由到节点失败: " + msg)
+	}
+	return dto.Ok(ob)
+}
+
+func UpdateXrayOutbound(d dto.XrayOutboundUpdateDto) dto.R {
+	var existing model.XrayOutbound
+	if err := DB.First(&existing, d.ID).Error; err != nil {
+		return dto.Err("出站不存在")
+	}
+	d.NodeId = existing.NodeId
+	if msg := validateXrayOutbound(&d.XrayOutboundDto, existing.ID); msg != "" {
+		return dto.Err(msg)
+	}
+	if existing.Tag != d.Tag {
+		var count int64
+		DB.Model(&model.XrayRoutingRule{}).Where("node_id = ? AND outbound_tag = ?", existing.NodeId, existing.Tag).Count(&count)
+		if count > 0 {
+			return dto.Err("该出站仍被路由规则引用，无法修改标签")
+		}
+	}
+
+	updated := existing
+	updated.Tag = d.Tag
+	updated.Protocol = d.Protocol
+	updated.SettingsJson = d.SettingsJson
+	updated.StreamSettingsJson = d.StreamSettingsJson
+	updated.DialerProxy = d.DialerProxy
+	updated.Remark = d.Remark
+	updated.Inx = d.Inx
+	if d.Enable != nil {
+		updated.Enable = *d.Enable
+	}
+	updated.UpdatedTime = time.Now().UnixMilli()
+
+	// A new chain target needs its own client; the old one goes after a
+	// successful sync
+	var staleClientId int64
+	if d.Protocol != "chain" {
+		updated.ChainInboundId, updated.ChainClientId = 0, 0
+		staleClientId = existing.ChainClientId
+	} else if d.ChainInboundId != existing.ChainInboundId || existing.ChainClientId == 0 {
+		clientId, msg := createChainClient(existing.NodeId, d.ChainInboundId, d.Tag)
+		if msg != "" {
+			return dto.Err(msg)
+		}
+		updated.ChainInboundId, updated.ChainClientId = d.ChainInboundId, clientId
+		staleClientId = existing.ChainClientId
+	}
+
+	DB.Save(&updated)
+	if msg := applyXrayRouting(existing.NodeId); msg != "" {
+		DB.Save(&existing)
+		if updated.ChainClientId != existing.ChainClientId {
+			deleteChainClient(updated.ChainClientId)
+		}
+		return dto.Err("同步路由到节点失败: " + msg)
+	}
+	deleteChainClient(staleClientId)
+	return dto.Ok(updated)
+}
+
+func DeleteXrayOutbound(id int64) dto.R {
+	var ob model.XrayOutbound
+	if err := DB.First(&ob, id).Error; err != nil {
+		return dto.Err("出站不存在")
+	}
+	var count int64
+	DB.Model(&model.XrayRoutingRule{}).Where("node_id = ? AND outbound_tag = ?", ob.NodeId, ob.Tag).Count(&count)
+	if count > 0 {
+		return dto.Err("该出站仍被路由规则引用")
+	}
+	DB.Model(&model.XrayOutbound{}).Where("node_id = ? AND dialer_proxy = ?", ob.NodeId, ob.Tag).Count(&count)
+	if count > 0 {
+		return dto.Err("该出站仍被其他出站用作前置代理")
+	}
+
+	DB.Delete(&ob)
+	if msg := applyXrayRouting(ob.NodeId); msg != "" {
+		DB.Create(&ob)
+		return dto.Err("同步路由到节点失败: " + msg)
+	}
+	deleteChainClient(ob.ChainClientId)
+	return dto.Ok("删除成功")
+}
+
+// createChainClient adds a system-owned client to the chain target, so the
+// node's upstream traffic is accounted like any other client.
+func createChainClient(nodeId, inboundId int64, tag string) (int64, string) {
+	r := CreateXrayClient(dto.XrayClientDto{
+		InboundId: inboundId,
+		Remark:    fmt.Sprintf("链式出站: 节点 %d / %s", nodeId, tag),
+	}, 0, 0)
+	if r.Code != 0 {
+		return 0, r.Msg
+	}
+	client, _ := r.Data.(model.XrayClient)
+	return client.ID, ""
+}
+
+func deleteChainClient(clientId int64) {
+	if clientId > 0 {
+		DeleteXrayClient(clientId, 0, 0)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Routing rule CRUD (admin)
+// ---------------------------------------------------------------------------
+
+func ListXrayRoutingRules(nodeId int64) dto.R {
+	var list []model.XrayRoutingRule
+	DB.Where("node_id = ?", nodeId).Order("inx ASC, id ASC").Find(&list)
+	return dto.Ok(list)
+}
+
+func CreateXrayRoutingRule(d dto.XrayRoutingRuleDto) dto.R {
+	if GetNodeById(d.NodeId) == nil {
+		return dto.Err("节点不存在")
+	}
+	if msg := validateXrayRoutingRule(&d); msg != "" {
+		return dto.Err(msg)
+	}
+
+	now := time.Now().UnixMilli()
+	rule := model.XrayRoutingRule{
+		NodeId:      d.NodeId,
+		InboundIds:  d.InboundIds,
+		UserIds:     d.UserIds,
+		Domain:      d.Domain,
+		Ip:          d.Ip,
+		Port:        d.Port,
+		Network:     d.Network,
+		Protocol:    d.Protocol,
+		OutboundTag: d.OutboundTag,
+		Remark:      d.Remark,
+		Inx:         d.Inx,
+		Enable:      1,
+		CreatedTime: now,
+		UpdatedTime: now,
+	}
+	if err := DB.Create(&rule).Error; err != nil {
+		return dto.Err("创建路由规则失败")
+	}
+	if msg := applyXrayRouting(d.NodeId); msg != "" {
+		DB.Delete(&rule)
+		return dto.Err("同步路由到节点失败: " + msg)
+	}
+	return dto.Ok(rule)
+}
+
+func UpdateXrayRoutingRule(d dto.XrayRoutingRuleUpdateDto) dto.R {
+	var existing model.XrayRoutingRule
+	if err := DB.First(&existing, d.ID).Error; err != nil {
+		return dto.Err("路由规则不存在")
+	}
+	d.NodeId = existing.NodeId
+	if msg := validateXrayRoutingRule(&d.XrayRoutingRuleDto); msg != "" {
+		return dto.Err(msg)
+	}
+
+	updated := existing
+	updated.InboundIds = d.InboundIds
+	updated.UserIds = d.UserIds
+	updated.Domain = d.Domain
+	updated.Ip = d.Ip
+	updated.Port = d.Port
+	updated.Network = d.Network
+	updated.Protocol = d.Protocol
+	updated.OutboundTag = d.OutboundTag
+	updated.Remark = d.Remark
+	updated.Inx = d.Inx
+	if d.Enable != nil {
+		updated.Enable = *d.Enable
+	}
+	updated.UpdatedTime = time.Now().UnixMilli()
+
+	DB.Save(&updated)
+	if msg := applyXrayRouting(existing.NodeId); msg != "" {
+		DB.Save(&existing)
+		return dto.Err("同步路由到节点失败: " + msg)
+	}
+	return dto.Ok(updated)
+}
+
+func DeleteXrayRoutingRule(id int64) dto.R {
+	var rule model.XrayRoutingRule
+	if err := DB.First(&rule, id).Error; err != nil {
+		return dto.Err("路由规则不存在")
+	}
+	DB.Delete(&rule)
+	if msg := applyXrayRouting(rule.NodeId); msg != "" {
+		DB.Create(&rule)
+		return dto.Err("同步路由到节点失败: " + msg)
+	}
+	return dto.Ok("删除成功")
+}
+
+// PreviewXrayRouting returns the routing JSON the node would receive.
+func PreviewXrayRouting(nodeId int64) dto.R {
+	return dto.Ok(buildXrayRouting(nodeId))
+}
@@ -1,39 +1,46 @@
 package service
 
 import (
+	"context"
 	"flux-panel/go-backend/model"
 	"flux-panel/go-backend/pkg"
 	"log"
 	"time"
 )
 
-// StartXrayScheduler starts periodic tasks for Xray management.
+// RegisterXrayJobs schedules periodic tasks for Xray management.
 // Called from main.go after DB is initialized.
-func StartXrayScheduler() {
-	go xrayClientTrafficResetLoop()
-	go xrayCertRenewLoop()
-}
-
-// xrayCertRenewLoop checks once per day for certificates needing renewal.
-func xrayCertRenewLoop() {
-	// Initial delay
-	time.Sleep(30 * time.Second)
-
-	for {
-		RenewExpiringSoon()
-		time.Sleep(24 * time.Hour)
-	}
-}
-
-// xrayClientTrafficResetLoop checks every hour for clients needing traffic reset.
-func xrayClientTrafficResetLoop() {
-	// Initial delay to let the system start up
-	time.Sleep(10 * time.Second)
-
-	for {
-		checkClientTrafficReset()
-		time.Sleep(1 * time.Hour)
-	}
+func RegisterXrayJobs() {
+	RegisterJob(&Job{
+		Name:        JobXrayTrafficReset,
+		Description: "V 客户端流量周期重置",
+		Spec:        "@hourly",
+		Missed:      MissedRunOnce,
+		Run: func(ctx context.Context, scheduled time.Time) error {
+			checkClientTrafficReset()
+			return nil
+		},
+	})
+	RegisterJob(&Job{
+		Name:        JobXrayCertRenew,
+		Description: "证书自动续期",
+		Spec:        "30 3 * * *",
+		Missed:      MissedRunOnce,
+		Run: func(ctx context.Context, scheduled time.Time) error {
+			RenewExpiringSoon()
+			return nil
+		},
+	})
+	RegisterJob(&Job{
+		Name:        JobXrayIPLimit,
+		Description: "IP 限制封禁解除",
+		Spec:        "@every 1m",
+		Missed:      MissedSkip,
+		Run: func(ctx context.Context, scheduled time.Time) error {
+			restoreIPBannedClients()
+			return nil
+		},
+	})
 }
 
 // checkClientTrafficReset resets traffic for clients whose reset cycle has elapsed.
@@ -73,7 +80,7 @@ func checkClientTrafficReset() {
 			if client.Enable == 0 && client.TotalTraffic > 0 {
 				DB.Model(&client).Update("enable", 1)
 				var inbound model.XrayInbound
-				if err := DB.First(&inbound, client.InboundId).Error; err == nil {
+				if err := DB.First(&inbound, client.InboundId).Error; err == nil && client.IpBanUntil <= now {
 					pkg.XrayAddClient(inbound.NodeId, inbound.Tag, client.Email, client.UuidOrPassword, client.Flow, client.AlterId, inbound.Protocol)
 				}
 			}
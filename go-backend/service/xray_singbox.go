@@ -0,0 +1,58 @@
+package service
+
+import (
+	"encoding/json"
+	"regexp"
+)
+
+// Hysteria2 and TUIC inbounds are stored and managed like Xray inbounds, but
+// the node serves them with sing-box. Their settings JSON uses the panel's
+// camelCase fields (upMbps, downMbps, obfsPassword, congestionControl, ...)
+// and TLS comes from the usual Xray-style stream settings.
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// isSingboxProtocol reports whether inbounds of the protocol run on sing-box.
+func isSingboxProtocol(protocol string) bool {
+	return protocol == "hysteria2" || protocol == "tuic"
+}
+
+// validateSingboxInbound checks what sing-box needs to serve a QUIC inbound:
+// TLS with a certificate. Returns an error message, or "" if valid.
+func validateSingboxInbound(protocol, streamSettingsJson string) string {
+	if !isSingboxProtocol(protocol) {
+		return ""
+	}
+	var ss struct {
+		Security    string `json:"security"`
+		TlsSettings struct {
+			Certificates []struct {
+				CertificateFile string   `json:"certificateFile"`
+				Certificate     []string `json:"certificate"`
+			} `json:"certificates"`
+		} `json:"tlsSettings"`
+	}
+	if streamSettingsJson != "" {
+		if err := json.Unmarshal([]byte(streamSettingsJson), &ss); err != nil {
+			return "传输配置格式错误"
+		}
+	}
+	if ss.Security != "tls" {
+		return protocol + " 必须启用 TLS"
+	}
+	for _, c := range ss.TlsSettings.Certificates {
+		if c.CertificateFile != "" || len(c.Certificate) > 0 {
+			return ""
+		}
+	}
+	return protocol + " 必须配置 TLS 证书"
+}
+
+// validateSingboxClient checks client credentials for sing-box inbounds.
+// TUIC authenticates by UUID, so a free-form password won't do.
+func validateSingboxClient(protocol, uuidOrPassword string) string {
+	if protocol == "tuic" && uuidOrPassword != "" && !uuidPattern.MatchString(uuidOrPassword) {
+		return "TUIC 客户端必须使用 UUID"
+	}
+	return ""
+}
@@ -0,0 +1,334 @@
+package service
+
+import (
+	"bytes"
+	"fmt"
+
+	"flux-panel/go-backend/dto"
+	"flux-panel/go-backend/model"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Proxy group names in the generated Clash profile.
+const (
+	clashGroupSelect = "节点选择"
+	clashGroupAuto   = "自动选择"
+)
+
+const clashTestURL = "http://www.gstatic.com/generate_204"
+
+// clashConfig is a Mihomo (Clash Meta) profile. Field order follows the
+// usual layout of hand-written profiles.
+type clashConfig struct {
+	MixedPort   int               `yaml:"mixed-port"`
+	AllowLan    bool              `yaml:"allow-lan"`
+	Mode        string            `yaml:"mode"`
+	LogLevel    string            `yaml:"log-level"`
+	IPv6        bool              `yaml:"ipv6"`
+	DNS         clashDNS          `yaml:"dns"`
+	Proxies     []clashProxy      `yaml:"proxies"`
+	ProxyGroups []clashProxyGroup `yaml:"proxy-groups"`
+	Rules       []string          `yaml:"rules"`
+}
+
+type clashDNS struct {
+	Enable            bool     `yaml:"enable"`
+	IPv6              bool     `yaml:"ipv6"`
+	EnhancedMode      string   `yaml:"enhanced-mode"`
+	FakeIPRange       string   `yaml:"fake-ip-range"`
+	DefaultNameserver []string `yaml:"default-nameserver"`
+	Nameserver        []string `yaml:"nameserver"`
+}
+
+type clashProxy struct {
+	Name     string `yaml:"name"`
+	Type     string `yaml:"type"`
+	Server   string `yaml:"server"`
+	Port     int    `yaml:"port"`
+	UUID     string `yaml:"uuid,omitempty"`
+	AlterID  *int   `yaml:"alterId,omitempty"`
+	Password string `yaml:"password,omitempty"`
+	Cipher   string `yaml:"cipher,omitempty"`
+	Flow     string `yaml:"flow,omitempty"`
+	UDP      bool   `yaml:"udp"`
+
+	Obfs          string `yaml:"obfs,omitempty"`
+	ObfsPassword  string `yaml:"obfs-password,omitempty"`
+	CongestionCtl string `yaml:"congestion-controller,omitempty"`
+	UDPRelayMode  string `yaml:"udp-relay-mode,omitempty"`
+
+	TLS               bool     `yaml:"tls,omitempty"`
+	ServerName        string   `yaml:"servername,omitempty"`
+	SNI               string   `yaml:"sni,omitempty"`
+	ALPN              []string `yaml:"alpn,omitempty"`
+	ClientFingerprint string   `yaml:"client-fingerprint,omitempty"`
+
+	Network     string            `yaml:"network,omitempty"`
+	WsOpts      *clashWsOpts      `yaml:"ws-opts,omitempty"`
+	GrpcOpts    *clashGrpcOpts    `yaml:"grpc-opts,omitempty"`
+	H2Opts      *clashH2Opts      `yaml:"h2-opts,omitempty"`
+	HTTPOpts    *clashHTTPOpts    `yaml:"http-opts,omitempty"`
+	XhttpOpts   *clashXhttpOpts   `yaml:"xhttp-opts,omitempty"`
+	RealityOpts *clashRealityOpts `yaml:"reality-opts,omitempty"`
+}
+
+type clashWsOpts struct {
+	Path             string            `yaml:"path,omitempty"`
+	Headers          map[string]string `yaml:"headers,omitempty"`
+	V2rayHTTPUpgrade bool              `yaml:"v2ray-http-upgrade,omitempty"`
+}
+
+type clashGrpcOpts struct {
+	GrpcServiceName string `yaml:"grpc-service-name"`
+}
+
+type clashH2Opts struct {
+	Host []string `yaml:"host,omitempty"`
+	Path string   `yaml:"path,omitempty"`
+}
+
+type clashHTTPOpts struct {
+	Method  string              `yaml:"method,omitempty"`
+	Path    []string            `yaml:"path,omitempty"`
+	Headers map[string][]string `yaml:"headers,omitempty"`
+}
+
+type clashXhttpOpts struct {
+	Path string `yaml:"path,omitempty"`
+	Host string `yaml:"host,omitempty"`
+	Mode string `yaml:"mode,omitempty"`
+}
+
+type clashRealityOpts struct {
+	PublicKey string `yaml:"public-key"`
+	ShortID   string `yaml:"short-id,omitempty"`
+}
+
+type clashProxyGroup struct {
+	Name      string   `yaml:"name"`
+	Type      string   `yaml:"type"`
+	Proxies   []string `yaml:"proxies"`
+	URL       string   `yaml:"url,omitempty"`
+	Interval  int      `yaml:"interval,omitempty"`
+	Tolerance int      `yaml:"tolerance,omitempty"`
+}
+
+// GetClashSubscription renders the user's subscription as a Mihomo profile.
+// Clients on transports Mihomo can't dial (e.g. mKCP) are left out.
+func GetClashSubscription(user *model.User, profile *model.SubscriptionProfile) dto.R {
+	entries, ok := subscriptionEntries(user.ID)
+	if !ok {
+		return dto.Err("用户不存在")
+	}
+	entries = applySubscriptionProfile(entries, profile)
+
+	cfg := clashConfig{
+		MixedPort: 7890,
+		Mode:      "rule",
+		LogLevel:  "info",
+		DNS: clashDNS{
+			Enable:            true,
+			EnhancedMode:      "fake-ip",
+			FakeIPRange:       "198.18.0.1/16",
+			DefaultNameserver: []string{"223.5.5.5", "119.29.29.29"},
+			Nameserver:        []string{"https://doh.pub/dns-query", "https://dns.alidns.com/dns-query"},
+		},
+		Proxies: []clashProxy{},
+	}
+
+	used := make(map[string]int)
+	var names, infoNames []string
+	for _, remark := range subscriptionInfoRemarks(user) {
+		name := uniqueProxyName(used, remark)
+		cfg.Proxies = append(cfg.Proxies, clashProxy{
+			Name: name, Type: "ss", Server: infoNodeServer, Port: infoNodePort,
+			Cipher: infoNodeCipher, Password: infoNodePassword,
+		})
+		infoNames = append(infoNames, name)
+	}
+	for i := range entries {
+		p, ok := buildClashProxy(&entries[i])
+		if !ok {
+			continue
+		}
+		p.Name = uniqueProxyName(used, p.Name)
+		cfg.Proxies = append(cfg.Proxies, p)
+		names = append(names, p.Name)
+	}
+
+	cfg.ProxyGroups = []clashProxyGroup{
+		// Pseudo-nodes are listed for display only, never auto-selected
+		{Name: clashGroupSelect, Type: "select", Proxies: append(append([]string{clashGroupAuto, "DIRECT"}, names...), infoNames...)},
+		{Name: clashGroupAuto, Type: "url-test", Proxies: append([]string{}, names...), URL: clashTestURL, Interval: 300, Tolerance: 50},
+	}
+	if len(names) == 0 {
+		// url-test groups must not be empty
+		cfg.ProxyGroups[1].Proxies = []string{"DIRECT"}
+	}
+	cfg.Rules = []string{
+		"DOMAIN-SUFFIX,local,DIRECT",
+		"IP-CIDR,127.0.0.0/8,DIRECT,no-resolve",
+		"IP-CIDR,10.0.0.0/8,DIRECT,no-resolve",
+		"IP-CIDR,172.16.0.0/12,DIRECT,no-resolve",
+		"IP-CIDR,192.168.0.0/16,DIRECT,no-resolve",
+		"IP-CIDR,100.64.0.0/10,DIRECT,no-resolve",
+		"GEOIP,CN,DIRECT",
+		"MATCH," + clashGroupSelect,
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&cfg); err != nil {
+		return dto.Err("生成订阅失败")
+	}
+	enc.Close()
+	return dto.Ok(buf.String())
+}
+
+// uniqueProxyName suffixes repeated remarks, since Clash requires proxy
+// names to be unique.
+func uniqueProxyName(used map[string]int, name string) string {
+	used[name]++
+	if used[name] == 1 {
+		return name
+	}
+	for {
+		candidate := fmt.Sprintf("%s %d", name, used[name])
+		if used[candidate] == 0 {
+			used[candidate] = 1
+			return candidate
+		}
+		used[name]++
+	}
+}
+
+func buildClashProxy(e *subscriptionEntry) (clashProxy, bool) {
+	ss := parseStreamSettings(e.Inbound.StreamSettingsJson)
+	p := clashProxy{
+		Name:   e.Remark,
+		Server: e.Host,
+		Port:   e.Inbound.Port,
+		UDP:    true,
+	}
+
+	switch e.Inbound.Protocol {
+	case "vmess":
+		p.Type = "vmess"
+		p.UUID = e.Client.UuidOrPassword
+		alterId := e.Client.AlterId
+		p.AlterID = &alterId
+		p.Cipher = "auto"
+	case "vless":
+		p.Type = "vless"
+		p.UUID = e.Client.UuidOrPassword
+		p.Flow = e.Client.Flow
+	case "trojan":
+		p.Type = "trojan"
+		p.Password = e.Client.UuidOrPassword
+	case "shadowsocks":
+		is := parseInboundSettings(e.Inbound.SettingsJson)
+		p.Type = "ss"
+		p.Cipher = is.Method
+		if p.Cipher == "" {
+			p.Cipher = "aes-256-gcm"
+		}
+		p.Password = e.Client.UuidOrPassword
+		return p, true
+	case "hysteria2", "tuic":
+		is := parseInboundSettings(e.Inbound.SettingsJson)
+		p.Type = e.Inbound.Protocol
+		p.Password = e.Client.UuidOrPassword
+		p.SNI = ss.TlsSettings.ServerName
+		p.ALPN = quicALPN(ss)
+		if p.Type == "tuic" {
+			p.UUID = e.Client.UuidOrPassword
+			p.CongestionCtl = tuicCongestionControl(is)
+			p.UDPRelayMode = "native"
+		} else if is.ObfsPassword != "" {
+			p.Obfs = "salamander"
+			p.ObfsPassword = is.ObfsPassword
+		}
+		return p, true
+	default:
+		return p, false
+	}
+
+	if !applyClashTransport(&p, ss) {
+		return p, false
+	}
+	applyClashSecurity(&p, ss)
+	return p, true
+}
+
+// applyClashTransport maps Xray stream settings onto Mihomo's network
+// options. It reports false for transports Mihomo has no client for.
+func applyClashTransport(p *clashProxy, ss *streamSettings) bool {
+	switch ss.Network {
+	case "", "tcp":
+		if ss.TcpSettings.Header.Type == "http" {
+			p.Network = "http"
+			opts := &clashHTTPOpts{Method: "GET", Path: ss.TcpSettings.Header.Request.Path}
+			if hosts := ss.TcpSettings.Header.Request.Headers["Host"]; len(hosts) > 0 {
+				opts.Headers = map[string][]string{"Host": hosts}
+			}
+			p.HTTPOpts = opts
+		}
+	case "ws", "httpupgrade":
+		p.Network = "ws"
+		opts := &clashWsOpts{Path: streamPath(ss), V2rayHTTPUpgrade: ss.Network == "httpupgrade"}
+		if h := streamHost(ss); h != "" {
+			opts.Headers = map[string]string{"Host": h}
+		}
+		p.WsOpts = opts
+	case "grpc":
+		p.Network = "grpc"
+		p.GrpcOpts = &clashGrpcOpts{GrpcServiceName: ss.GrpcSettings.ServiceName}
+	case "http", "h2":
+		p.Network = "h2"
+		p.H2Opts = &clashH2Opts{Host: ss.HttpSettings.Host, Path: ss.HttpSettings.Path}
+	case "xhttp", "splithttp":
+		p.Network = "xhttp"
+		p.XhttpOpts = &clashXhttpOpts{Path: ss.XhttpSettings.Path, Host: ss.XhttpSettings.Host, Mode: ss.XhttpSettings.Mode}
+	default:
+		return false
+	}
+	return true
+}
+
+func applyClashSecurity(p *clashProxy, ss *streamSettings) {
+	var sni string
+	switch ss.Security {
+	case "tls":
+		p.TLS = true
+		sni = ss.TlsSettings.ServerName
+		p.ALPN = ss.TlsSettings.Alpn
+		p.ClientFingerprint = ss.TlsSettings.Fingerprint
+	case "reality":
+		p.TLS = true
+		if len(ss.RealitySettings.ServerNames) > 0 {
+			sni = ss.RealitySettings.ServerNames[0]
+		}
+		opts := &clashRealityOpts{PublicKey: ss.RealitySettings.PublicKey}
+		if len(ss.RealitySettings.ShortIds) > 0 {
+			opts.ShortID = ss.RealitySettings.ShortIds[0]
+		}
+		p.RealityOpts = opts
+		// Mihomo needs a uTLS fingerprint for REALITY
+		p.ClientFingerprint = ss.RealitySettings.Fingerprint
+		if p.ClientFingerprint == "" {
+			p.ClientFingerprint = "chrome"
+		}
+	default:
+		return
+	}
+
+	// Trojan names the SNI field differently and is always TLS
+	if p.Type == "trojan" {
+		p.SNI = sni
+		p.TLS = false
+	} else {
+		p.ServerName = sni
+	}
+}
@@ -0,0 +1,142 @@
+package service
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"flux-panel/go-backend/model"
+)
+
+// Subscription metadata settings.
+const (
+	subUpdateIntervalKey = "sub_update_interval" // hours
+	subProfileNameKey    = "sub_profile_name"
+	subInfoNodesKey      = "sub_info_nodes" // "true" adds quota pseudo-nodes
+
+	defaultSubUpdateInterval = 24
+)
+
+// Pseudo-nodes only carry a name; they point at an unroutable address so
+// selecting one by mistake fails fast.
+const (
+	infoNodeServer   = "127.0.0.1"
+	infoNodePort     = 1
+	infoNodeCipher   = "chacha20-ietf-poly1305"
+	infoNodePassword = "info"
+)
+
+// subscriptionUsage is what clients show as used/remaining traffic and
+// expiry. Expire is unix seconds; Total and Expire are 0 when unlimited.
+type subscriptionUsage struct {
+	Upload   int64
+	Download int64
+	Total    int64
+	Expire   int64
+}
+
+// usageForUser reports the user's V traffic quota. Without a user-level
+// quota, the limits and counters of the user's own clients are used
+// instead, so used and total traffic always come from the same source.
+// Without a user-level expiry, the earliest client expiry is used.
+func usageForUser(user *model.User) subscriptionUsage {
+	u := subscriptionUsage{
+		Upload:   user.XrayOutFlow,
+		Download: user.XrayInFlow,
+		Total:    user.XrayFlow * bytesToGB,
+	}
+	if user.ExpTime > 0 {
+		u.Expire = user.ExpTime / 1000
+	}
+	if u.Total > 0 && u.Expire > 0 {
+		return u
+	}
+
+	var clients []model.XrayClient
+	DB.Where("user_id = ? AND enable = 1", user.ID).Find(&clients)
+	if len(clients) == 0 {
+		return u
+	}
+
+	var up, down, total, expire int64
+	limited := true
+	for _, c := range clients {
+		if c.TotalTraffic <= 0 {
+			limited = false
+		}
+		up += c.UpTraffic
+		down += c.DownTraffic
+		total += c.TotalTraffic
+		if c.ExpTime != nil && *c.ExpTime > 0 && (expire == 0 || *c.ExpTime/1000 < expire) {
+			expire = *c.ExpTime / 1000
+		}
+	}
+	if u.Total == 0 && limited {
+		u.Upload, u.Download, u.Total = up, down, total
+	}
+	if u.Expire == 0 {
+		u.Expire = expire
+	}
+	return u
+}
+
+// GetSubscriptionHeaders returns the response headers proxy clients read
+// traffic, expiry, refresh interval and profile name from.
+func GetSubscriptionHeaders(user *model.User) map[string]string {
+	u := usageForUser(user)
+
+	interval := defaultSubUpdateInterval
+	if v, err := strconv.Atoi(configValue(subUpdateIntervalKey)); err == nil && v > 0 {
+		interval = v
+	}
+
+	headers := map[string]string{
+		"Subscription-Userinfo":   fmt.Sprintf("upload=%d; download=%d; total=%d; expire=%d", u.Upload, u.Download, u.Total, u.Expire),
+		"Profile-Update-Interval": strconv.Itoa(interval),
+	}
+
+	name := configValue(subProfileNameKey)
+	if name == "" {
+		name = configValue("app_name")
+	}
+	if name != "" {
+		headers["Content-Disposition"] = "attachment; filename*=UTF-8''" + url.PathEscape(name)
+		headers["Profile-Title"] = "base64:" + base64.StdEncoding.EncodeToString([]byte(name))
+	}
+	return headers
+}
+
+// subscriptionInfoRemarks returns the names of the quota pseudo-nodes, or
+// nil if they are turned off.
+func subscriptionInfoRemarks(user *model.User) []string {
+	if configValue(subInfoNodesKey) != "true" {
+		return nil
+	}
+	u := usageForUser(user)
+
+	remaining := "无限"
+	if u.Total > 0 {
+		left := u.Total - u.Upload - u.Download
+		if left < 0 {
+			left = 0
+		}
+		remaining = fmt.Sprintf("%.2f GB", float64(left)/bytesToGB)
+	}
+	expire := "永久"
+	if u.Expire > 0 {
+		expire = time.Unix(u.Expire, 0).In(UserLocation(user)).Format("2006-01-02")
+	}
+	return []string{"剩余流量: " + remaining, "到期时间: " + expire}
+}
+
+// GetSubscriptionInfoLinks returns the quota pseudo-nodes as share links.
+func GetSubscriptionInfoLinks(user *model.User) []string {
+	var links []string
+	userInfo := base64.StdEncoding.EncodeToString([]byte(infoNodeCipher + ":" + infoNodePassword))
+	for _, remark := range subscriptionInfoRemarks(user) {
+		links = append(links, fmt.Sprintf("ss://%s@%s:%d#%s", userInfo, infoNodeServer, infoNodePort, url.QueryEscape(remark)))
+	}
+	return links
+}
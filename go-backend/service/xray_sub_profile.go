@@ -0,0 +1,371 @@
+package service
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"flux-panel/go-backend/dto"
+	"flux-panel/go-backend/model"
+	"flux-panel/go-backend/pkg"
+)
+
+// Profile orderings. The default keeps the order of the profile's inbound
+// list, then its node list, then the database order.
+var subscriptionSortModes = map[string]bool{
+	"":         true,
+	"node":     true,
+	"region":   true,
+	"protocol": true,
+	"remark":   true,
+}
+
+// ResolveSubscriptionToken looks up the user behind a subscription token.
+// Profile tokens come with their profile; the user's own sub_token has none.
+func ResolveSubscriptionToken(token string) (*model.User, *model.SubscriptionProfile, bool) {
+	var user model.User
+	var st model.SubscriptionToken
+	if err := DB.Where("token = ?", token).First(&st).Error; err == nil {
+		if err := DB.First(&user, st.UserId).Error; err != nil {
+			return nil, nil, false
+		}
+		var profile model.SubscriptionProfile
+		if err := DB.First(&profile, st.ProfileId).Error; err != nil {
+			return nil, nil, false
+		}
+		DB.Model(&st).Update("last_used_time", time.Now().UnixMilli())
+		return &user, &profile, true
+	}
+
+	if err := DB.Where("sub_token = ?", token).First(&user).Error; err != nil {
+		return nil, nil, false
+	}
+	return &user, nil, true
+}
+
+// applySubscriptionProfile filters, renames and orders entries as the
+// profile describes. A nil profile leaves them untouched.
+func applySubscriptionProfile(entries []subscriptionEntry, profile *model.SubscriptionProfile) []subscriptionEntry {
+	if profile == nil {
+		return entries
+	}
+
+	nodeOrder := idPositions(profile.NodeIds)
+	inboundOrder := idPositions(profile.InboundIds)
+	overrides := parseHostOverrides(profile.HostOverrides)
+
+	result := make([]subscriptionEntry, 0, len(entries))
+	for _, e := range entries {
+		if len(nodeOrder) > 0 {
+			if _, ok := nodeOrder[e.Node.ID]; !ok {
+				continue
+			}
+		}
+		if len(inboundOrder) > 0 {
+			if _, ok := inboundOrder[e.Inbound.ID]; !ok {
+				continue
+			}
+		}
+		if host := overrides[strings.ToUpper(e.Node.Region)]; host != "" && e.Node.Region != "" {
+			e.Host = host
+		}
+		if profile.RemarkTemplate != "" {
+			if remark := renderRemark(profile.RemarkTemplate, &e); remark != "" {
+				e.Remark = remark
+			}
+		}
+		result = append(result, e)
+	}
+
+	var less func(a, b *subscriptionEntry) bool
+	switch profile.SortBy {
+	case "node":
+		less = func(a, b *subscriptionEntry) bool {
+			if a.Node.Inx != b.Node.Inx {
+				return a.Node.Inx < b.Node.Inx
+			}
+			return a.Node.Name < b.Node.Name
+		}
+	case "region":
+		less = func(a, b *subscriptionEntry) bool {
+			if a.Node.Region != b.Node.Region {
+				return a.Node.Region < b.Node.Region
+			}
+			return a.Node.Inx < b.Node.Inx
+		}
+	case "protocol":
+		less = func(a, b *subscriptionEntry) bool { return a.Inbound.Protocol < b.Inbound.Protocol }
+	case "remark":
+		less = func(a, b *subscriptionEntry) bool { return a.Remark < b.Remark }
+	default:
+		less = func(a, b *subscriptionEntry) bool {
+			if len(inboundOrder) > 0 {
+				return inboundOrder[a.Inbound.ID] < inboundOrder[b.Inbound.ID]
+			}
+			if len(nodeOrder) > 0 {
+				return nodeOrder[a.Node.ID] < nodeOrder[b.Node.ID]
+			}
+			return false
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool { return less(&result[i], &result[j]) })
+	return result
+}
+
+// renderRemark expands {node}, {region}, {protocol}, {remark}, {inbound}
+// and {port} in a remark template.
+func renderRemark(tpl string, e *subscriptionEntry) string {
+	inbound := e.Inbound.Remark
+	if inbound == "" {
+		inbound = e.Inbound.Tag
+	}
+	r := strings.NewReplacer(
+		"{node}", e.Node.Name,
+		"{region}", e.Node.Region,
+		"{protocol}", e.Inbound.Protocol,
+		"{remark}", e.Remark,
+		"{inbound}", inbound,
+		"{port}", strconv.Itoa(e.Inbound.Port),
+	)
+	return strings.TrimSpace(r.Replace(tpl))
+}
+
+// idPositions parses a comma-separated id list into id -> position.
+func idPositions(list string) map[int64]int {
+	positions := make(map[int64]int)
+	for _, part := range strings.Split(list, ",") {
+		id, err := strconv.ParseInt(strings.TrimSpace(part), 10, 64)
+		if err != nil || id <= 0 {
+			continue
+		}
+		if _, ok := positions[id]; !ok {
+			positions[id] = len(positions)
+		}
+	}
+	return positions
+}
+
+// parseHostOverrides reads the region -> host map. Regions match
+// case-insensitively.
+func parseHostOverrides(value string) map[string]string {
+	if value == "" {
+		return nil
+	}
+	var raw map[string]string
+	if err := json.Unmarshal([]byte(value), &raw); err != nil {
+		return nil
+	}
+	overrides := make(map[string]string, len(raw))
+	for region, host := range raw {
+		overrides[strings.ToUpper(strings.TrimSpace(region))] = strings.TrimSpace(host)
+	}
+	return overrides
+}
+
+func validateSubscriptionProfile(sortBy, nodeIds, inboundIds, hostOverrides string) *dto.R {
+	if !subscriptionSortModes[sortBy] {
+		r := dto.Err("不支持的排序方式")
+		return &r
+	}
+	for _, list := range []string{nodeIds, inboundIds} {
+		for _, part := range strings.Split(list, ",") {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+			if id, err := strconv.ParseInt(part, 10, 64); err != nil || id <= 0 {
+				r := dto.Err("ID 列表格式错误: " + part)
+				return &r
+			}
+		}
+	}
+	if hostOverrides != "" {
+		var raw map[string]string
+		if err := json.Unmarshal([]byte(hostOverrides), &raw); err != nil {
+			r := dto.Err("地区地址覆盖必须是 JSON 对象, 如 {\"HK\": \"hk.example.com\"}")
+			return &r
+		}
+	}
+	return nil
+}
+
+// ---------------------------------------------------------------------------
+// Profiles (admin)
+// ---------------------------------------------------------------------------
+
+func ListSubscriptionProfiles() dto.R {
+	var profiles []model.SubscriptionProfile
+	DB.Order("id ASC").Find(&profiles)
+	return dto.Ok(profiles)
+}
+
+func CreateSubscriptionProfile(d dto.SubscriptionProfileDto) dto.R {
+	if r := validateSubscriptionProfile(d.SortBy, d.NodeIds, d.InboundIds, d.HostOverrides); r != nil {
+		return *r
+	}
+	now := time.Now().UnixMilli()
+	profile := model.SubscriptionProfile{
+		Name:           d.Name,
+		NodeIds:        d.NodeIds,
+		InboundIds:     d.InboundIds,
+		SortBy:         d.SortBy,
+		RemarkTemplate: d.RemarkTemplate,
+		HostOverrides:  d.HostOverrides,
+		CreatedTime:    now,
+		UpdatedTime:    now,
+	}
+	if err := DB.Create(&profile).Error; err != nil {
+		return dto.Err("创建订阅模板失败")
+	}
+	return dto.Ok(profile)
+}
+
+func UpdateSubscriptionProfile(d dto.SubscriptionProfileUpdateDto) dto.R {
+	var profile model.SubscriptionProfile
+	if err := DB.First(&profile, d.ID).Error; err != nil {
+		return dto.Err("订阅模板不存在")
+	}
+
+	merged := profile
+	if d.NodeIds != nil {
+		merged.NodeIds = *d.NodeIds
+	}
+	if d.InboundIds != nil {
+		merged.InboundIds = *d.InboundIds
+	}
+	if d.SortBy != nil {
+		merged.SortBy = *d.SortBy
+	}
+	if d.HostOverrides != nil {
+		merged.HostOverrides = *d.HostOverrides
+	}
+	if r := validateSubscriptionProfile(merged.SortBy, merged.NodeIds, merged.InboundIds, merged.HostOverrides); r != nil {
+		return *r
+	}
+
+	updates := map[string]interface{}{
+		"node_ids":       merged.NodeIds,
+		"inbound_ids":    merged.InboundIds,
+		"sort_by":        merged.SortBy,
+		"host_overrides": merged.HostOverrides,
+		"updated_time":   time.Now().UnixMilli(),
+	}
+	if d.Name != "" {
+		updates["name"] = d.Name
+	}
+	if d.RemarkTemplate != nil {
+		updates["remark_template"] = *d.RemarkTemplate
+	}
+	if err := DB.Model(&profile).Updates(updates).Error; err != nil {
+		return dto.Err("更新订阅模板失败")
+	}
+	return dto.Ok("订阅模板更新成功")
+}
+
+func DeleteSubscriptionProfile(id int64) dto.R {
+	var count int64
+	DB.Model(&model.SubscriptionToken{}).Where("profile_id = ?", id).Count(&count)
+	if count > 0 {
+		return dto.Err("该订阅模板仍有订阅令牌在使用")
+	}
+	if err := DB.Delete(&model.SubscriptionProfile{}, id).Error; err != nil {
+		return dto.Err("删除订阅模板失败")
+	}
+	return dto.Ok("删除成功")
+}
+
+// ---------------------------------------------------------------------------
+// Per-user subscription tokens
+// ---------------------------------------------------------------------------
+
+func ListSubscriptionTokens(userId int64) dto.R {
+	var tokens []model.SubscriptionToken
+	DB.Where("user_id = ?", userId).Order("id ASC").Find(&tokens)
+
+	var profiles []model.SubscriptionProfile
+	DB.Find(&profiles)
+	names := make(map[int64]string, len(profiles))
+	for _, p := range profiles {
+		names[p.ID] = p.Name
+	}
+
+	result := make([]map[string]interface{}, 0, len(tokens))
+	for _, t := range tokens {
+		result = append(result, map[string]interface{}{
+			"id":           t.ID,
+			"name":         t.Name,
+			"token":        t.Token,
+			"profileId":    t.ProfileId,
+			"profileName":  names[t.ProfileId],
+			"lastUsedTime": t.LastUsedTime,
+			"createdTime":  t.CreatedTime,
+		})
+	}
+	return dto.Ok(result)
+}
+
+func CreateSubscriptionToken(userId int64, roleId int, d dto.SubscriptionTokenDto) dto.R {
+	if r := checkXrayPermission(userId, roleId); r != nil {
+		return *r
+	}
+	if err := DB.First(&model.SubscriptionProfile{}, d.ProfileId).Error; err != nil {
+		return dto.Err("订阅模板不存在")
+	}
+
+	token := model.SubscriptionToken{
+		UserId:      userId,
+		Token:       pkg.GenerateSecureSecret(),
+		ProfileId:   d.ProfileId,
+		Name:        d.Name,
+		CreatedTime: time.Now().UnixMilli(),
+	}
+	if err := DB.Create(&token).Error; err != nil {
+		return dto.Err("创建订阅令牌失败")
+	}
+	return dto.Ok(token)
+}
+
+func UpdateSubscriptionToken(userId int64, d dto.SubscriptionTokenUpdateDto) dto.R {
+	var token model.SubscriptionToken
+	if err := DB.Where("id = ? AND user_id = ?", d.ID, userId).First(&token).Error; err != nil {
+		return dto.Err("订阅令牌不存在")
+	}
+
+	updates := map[string]interface{}{}
+	if d.Name != "" {
+		updates["name"] = d.Name
+	}
+	if d.ProfileId != nil {
+		if err := DB.First(&model.SubscriptionProfile{}, *d.ProfileId).Error; err != nil {
+			return dto.Err("订阅模板不存在")
+		}
+		updates["profile_id"] = *d.ProfileId
+	}
+	if len(updates) > 0 {
+		DB.Model(&token).Updates(updates)
+	}
+	return dto.Ok("订阅令牌更新成功")
+}
+
+// ResetSubscriptionToken replaces the token string, invalidating the old URL.
+func ResetSubscriptionToken(userId int64, id int64) dto.R {
+	var token model.SubscriptionToken
+	if err := DB.Where("id = ? AND user_id = ?", id, userId).First(&token).Error; err != nil {
+		return dto.Err("订阅令牌不存在")
+	}
+	token.Token = pkg.GenerateSecureSecret()
+	if err := DB.Model(&token).Update("token", token.Token).Error; err != nil {
+		return dto.Err("保存订阅令牌失败")
+	}
+	return dto.Ok(token)
+}
+
+func DeleteSubscriptionToken(userId int64, id int64) dto.R {
+	res := DB.Where("id = ? AND user_id = ?", id, userId).Delete(&model.SubscriptionToken{})
+	if res.RowsAffected == 0 {
+		return dto.Err("订阅令牌不存在")
+	}
+	return dto.Ok("删除成功")
+}
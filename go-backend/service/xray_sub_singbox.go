@@ -0,0 +1,351 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"flux-panel/go-backend/dto"
+	"flux-panel/go-backend/model"
+)
+
+// Optional JSON objects that replace the generated "dns" and "route"
+// sections of sing-box subscriptions.
+const (
+	singboxDNSTemplateKey   = "singbox_dns_template"
+	singboxRouteTemplateKey = "singbox_route_template"
+)
+
+// Outbound tags of the generated groups.
+const (
+	singboxTagSelect = "proxy"
+	singboxTagAuto   = "auto"
+	singboxTagDirect = "direct"
+)
+
+const defaultSingboxDNS = `{
+  "servers": [
+    {"tag": "remote", "address": "https://1.1.1.1/dns-query", "detour": "proxy"},
+    {"tag": "local", "address": "https://223.5.5.5/dns-query", "detour": "direct"}
+  ],
+  "rules": [
+    {"outbound": "any", "server": "local"}
+  ],
+  "final": "remote"
+}`
+
+const defaultSingboxRoute = `{
+  "rules": [
+    {"protocol": "dns", "outbound": "dns-out"},
+    {"ip_is_private": true, "outbound": "direct"}
+  ],
+  "final": "proxy",
+  "auto_detect_interface": true
+}`
+
+type singboxConfig struct {
+	Log       singboxLog        `json:"log"`
+	DNS       json.RawMessage   `json:"dns"`
+	Inbounds  []json.RawMessage `json:"inbounds"`
+	Outbounds []interface{}     `json:"outbounds"`
+	Route     json.RawMessage   `json:"route"`
+}
+
+type singboxLog struct {
+	Level     string `json:"level"`
+	Timestamp bool   `json:"timestamp"`
+}
+
+type singboxOutbound struct {
+	Type       string            `json:"type"`
+	Tag        string            `json:"tag"`
+	Server     string            `json:"server"`
+	ServerPort int               `json:"server_port"`
+	UUID       string            `json:"uuid,omitempty"`
+	Security   string            `json:"security,omitempty"`
+	AlterID    *int              `json:"alter_id,omitempty"`
+	Flow       string            `json:"flow,omitempty"`
+	Password   string            `json:"password,omitempty"`
+	Method     string            `json:"method,omitempty"`
+	Obfs       *singboxObfs      `json:"obfs,omitempty"`
+	Congestion string            `json:"congestion_control,omitempty"`
+	UDPRelay   string            `json:"udp_relay_mode,omitempty"`
+	TLS        *singboxTLS       `json:"tls,omitempty"`
+	Transport  *singboxTransport `json:"transport,omitempty"`
+}
+
+type singboxObfs struct {
+	Type     string `json:"type"`
+	Password string `json:"password"`
+}
+
+type singboxTLS struct {
+	Enabled    bool            `json:"enabled"`
+	ServerName string          `json:"server_name,omitempty"`
+	ALPN       []string        `json:"alpn,omitempty"`
+	UTLS       *singboxUTLS    `json:"utls,omitempty"`
+	Reality    *singboxReality `json:"reality,omitempty"`
+}
+
+type singboxUTLS struct {
+	Enabled     bool   `json:"enabled"`
+	Fingerprint string `json:"fingerprint"`
+}
+
+type singboxReality struct {
+	Enabled   bool   `json:"enabled"`
+	PublicKey string `json:"public_key"`
+	ShortID   string `json:"short_id,omitempty"`
+}
+
+type singboxTransport struct {
+	Type        string            `json:"type"`
+	Path        string            `json:"path,omitempty"`
+	Host        interface{}       `json:"host,omitempty"`
+	Headers     map[string]string `json:"headers,omitempty"`
+	ServiceName string            `json:"service_name,omitempty"`
+}
+
+type singboxGroup struct {
+	Type      string   `json:"type"`
+	Tag       string   `json:"tag"`
+	Outbounds []string `json:"outbounds"`
+	Default   string   `json:"default,omitempty"`
+	URL       string   `json:"url,omitempty"`
+	Interval  string   `json:"interval,omitempty"`
+}
+
+type singboxSimpleOutbound struct {
+	Type string `json:"type"`
+	Tag  string `json:"tag"`
+}
+
+// GetSingboxSubscription renders the user's subscription as a sing-box
+// client config. Clients on transports sing-box has no client for (mKCP,
+// xHTTP, TCP header obfuscation) are left out.
+func GetSingboxSubscription(user *model.User, profile *model.SubscriptionProfile) dto.R {
+	entries, ok := subscriptionEntries(user.ID)
+	if !ok {
+		return dto.Err("用户不存在")
+	}
+	entries = applySubscriptionProfile(entries, profile)
+
+	data, err := buildSingboxConfig(entries, subscriptionInfoRemarks(user),
+		configValue(singboxDNSTemplateKey), configValue(singboxRouteTemplateKey))
+	if err != nil {
+		return dto.Err("生成订阅失败: " + err.Error())
+	}
+	return dto.Ok(string(data))
+}
+
+func configValue(name string) string {
+	var cfg model.ViteConfig
+	if err := DB.Where("name = ?", name).First(&cfg).Error; err != nil {
+		return ""
+	}
+	return cfg.Value
+}
+
+// validateSingboxTemplate accepts an empty value (use the built-in section)
+// or a JSON object.
+func validateSingboxTemplate(value string) error {
+	if value == "" {
+		return nil
+	}
+	var obj map[string]interface{}
+	if err := json.Unmarshal([]byte(value), &obj); err != nil {
+		return fmt.Errorf("必须是 JSON 对象: %v", err)
+	}
+	return nil
+}
+
+// buildSingboxConfig assembles the config. infoRemarks name quota
+// pseudo-nodes, which are added to the selector only.
+func buildSingboxConfig(entries []subscriptionEntry, infoRemarks []string, dnsTemplate, routeTemplate string) ([]byte, error) {
+	if dnsTemplate == "" {
+		dnsTemplate = defaultSingboxDNS
+	}
+	if routeTemplate == "" {
+		routeTemplate = defaultSingboxRoute
+	}
+	if err := validateSingboxTemplate(dnsTemplate); err != nil {
+		return nil, fmt.Errorf("DNS 模板%v", err)
+	}
+	if err := validateSingboxTemplate(routeTemplate); err != nil {
+		return nil, fmt.Errorf("路由模板%v", err)
+	}
+
+	cfg := singboxConfig{
+		Log: singboxLog{Level: "info", Timestamp: true},
+		DNS: json.RawMessage(dnsTemplate),
+		Inbounds: []json.RawMessage{
+			json.RawMessage(`{"type": "tun", "tag": "tun-in", "address": ["172.19.0.1/30"], "auto_route": true, "strict_route": true, "sniff": true}`),
+			json.RawMessage(`{"type": "mixed", "tag": "mixed-in", "listen": "127.0.0.1", "listen_port": 2080, "sniff": true}`),
+		},
+		Route: json.RawMessage(routeTemplate),
+	}
+
+	used := make(map[string]int)
+	var proxies []interface{}
+	var tags, infoTags []string
+	for _, remark := range infoRemarks {
+		tag := uniqueProxyName(used, remark)
+		proxies = append(proxies, singboxOutbound{
+			Type: "shadowsocks", Tag: tag, Server: infoNodeServer, ServerPort: infoNodePort,
+			Method: infoNodeCipher, Password: infoNodePassword,
+		})
+		infoTags = append(infoTags, tag)
+	}
+	for i := range entries {
+		ob, ok := buildSingboxOutbound(&entries[i])
+		if !ok {
+			continue
+		}
+		ob.Tag = uniqueProxyName(used, ob.Tag)
+		proxies = append(proxies, ob)
+		tags = append(tags, ob.Tag)
+	}
+
+	autoMembers := tags
+	if len(autoMembers) == 0 {
+		// Groups must not be empty
+		autoMembers = []string{singboxTagDirect}
+	}
+	cfg.Outbounds = append(cfg.Outbounds,
+		singboxGroup{Type: "selector", Tag: singboxTagSelect, Outbounds: append(append([]string{singboxTagAuto}, tags...), infoTags...), Default: singboxTagAuto},
+		singboxGroup{Type: "urltest", Tag: singboxTagAuto, Outbounds: autoMembers, URL: clashTestURL, Interval: "3m"},
+	)
+	cfg.Outbounds = append(cfg.Outbounds, proxies...)
+	cfg.Outbounds = append(cfg.Outbounds,
+		singboxSimpleOutbound{Type: "direct", Tag: singboxTagDirect},
+		singboxSimpleOutbound{Type: "dns", Tag: "dns-out"},
+	)
+
+	return json.MarshalIndent(&cfg, "", "  ")
+}
+
+func buildSingboxOutbound(e *subscriptionEntry) (singboxOutbound, bool) {
+	ob := singboxOutbound{
+		Tag:        e.Remark,
+		Server:     e.Host,
+		ServerPort: e.Inbound.Port,
+	}
+
+	switch e.Inbound.Protocol {
+	case "vmess":
+		ob.Type = "vmess"
+		ob.UUID = e.Client.UuidOrPassword
+		ob.Security = "auto"
+		alterId := e.Client.AlterId
+		ob.AlterID = &alterId
+	case "vless":
+		ob.Type = "vless"
+		ob.UUID = e.Client.UuidOrPassword
+		ob.Flow = e.Client.Flow
+	case "trojan":
+		ob.Type = "trojan"
+		ob.Password = e.Client.UuidOrPassword
+	case "shadowsocks":
+		is := parseInboundSettings(e.Inbound.SettingsJson)
+		ob.Type = "shadowsocks"
+		ob.Method = is.Method
+		if ob.Method == "" {
+			ob.Method = "aes-256-gcm"
+		}
+		ob.Password = e.Client.UuidOrPassword
+		return ob, true
+	case "hysteria2", "tuic":
+		return buildSingboxQUICOutbound(e, ob), true
+	default:
+		return ob, false
+	}
+
+	ss := parseStreamSettings(e.Inbound.StreamSettingsJson)
+	transport, ok := singboxTransportFor(ss)
+	if !ok {
+		return ob, false
+	}
+	ob.Transport = transport
+	ob.TLS = singboxTLSFor(ss)
+	return ob, true
+}
+
+// buildSingboxQUICOutbound fills in Hysteria2 and TUIC outbounds. Both run
+// over QUIC with mandatory TLS and no V2Ray transport.
+func buildSingboxQUICOutbound(e *subscriptionEntry, ob singboxOutbound) singboxOutbound {
+	ss := parseStreamSettings(e.Inbound.StreamSettingsJson)
+	is := parseInboundSettings(e.Inbound.SettingsJson)
+
+	ob.Type = e.Inbound.Protocol
+	ob.Password = e.Client.UuidOrPassword
+	if e.Inbound.Protocol == "tuic" {
+		ob.UUID = e.Client.UuidOrPassword
+		ob.Congestion = tuicCongestionControl(is)
+		ob.UDPRelay = "native"
+	} else if is.ObfsPassword != "" {
+		ob.Obfs = &singboxObfs{Type: "salamander", Password: is.ObfsPassword}
+	}
+	ob.TLS = &singboxTLS{Enabled: true, ServerName: ss.TlsSettings.ServerName, ALPN: quicALPN(ss)}
+	return ob
+}
+
+// singboxTransportFor maps Xray stream settings onto a sing-box V2Ray
+// transport. A nil transport means plain TCP.
+func singboxTransportFor(ss *streamSettings) (*singboxTransport, bool) {
+	switch ss.Network {
+	case "", "tcp":
+		if ss.TcpSettings.Header.Type == "http" {
+			return nil, false
+		}
+		return nil, true
+	case "ws":
+		t := &singboxTransport{Type: "ws", Path: ss.WsSettings.Path}
+		if h := streamHost(ss); h != "" {
+			t.Headers = map[string]string{"Host": h}
+		}
+		return t, true
+	case "httpupgrade":
+		t := &singboxTransport{Type: "httpupgrade", Path: ss.HttpupgradeSettings.Path}
+		if ss.HttpupgradeSettings.Host != "" {
+			t.Host = ss.HttpupgradeSettings.Host
+		}
+		return t, true
+	case "grpc":
+		return &singboxTransport{Type: "grpc", ServiceName: ss.GrpcSettings.ServiceName}, true
+	case "http", "h2":
+		t := &singboxTransport{Type: "http", Path: ss.HttpSettings.Path}
+		if len(ss.HttpSettings.Host) > 0 {
+			t.Host = ss.HttpSettings.Host
+		}
+		return t, true
+	}
+	return nil, false
+}
+
+func singboxTLSFor(ss *streamSettings) *singboxTLS {
+	switch ss.Security {
+	case "tls":
+		t := &singboxTLS{Enabled: true, ServerName: ss.TlsSettings.ServerName, ALPN: ss.TlsSettings.Alpn}
+		if ss.TlsSettings.Fingerprint != "" {
+			t.UTLS = &singboxUTLS{Enabled: true, Fingerprint: ss.TlsSettings.Fingerprint}
+		}
+		return t
+	case "reality":
+		t := &singboxTLS{Enabled: true}
+		if len(ss.RealitySettings.ServerNames) > 0 {
+			t.ServerName = ss.RealitySettings.ServerNames[0]
+		}
+		r := &singboxReality{Enabled: true, PublicKey: ss.RealitySettings.PublicKey}
+		if len(ss.RealitySettings.ShortIds) > 0 {
+			r.ShortID = ss.RealitySettings.ShortIds[0]
+		}
+		t.Reality = r
+		// REALITY requires uTLS in sing-box
+		fp := ss.RealitySettings.Fingerprint
+		if fp == "" {
+			fp = "chrome"
+		}
+		t.UTLS = &singboxUTLS{Enabled: true, Fingerprint: fp}
+		return t
+	}
+	return nil
+}
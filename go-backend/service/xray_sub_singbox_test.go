@@ -0,0 +1,155 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"flux-panel/go-backend/model"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite golden files in testdata/")
+
+func singboxTestEntry(protocol, remark, streamJSON, settingsJSON string) subscriptionEntry {
+	client := model.XrayClient{UuidOrPassword: "b831381d-6324-4d53-ad4f-8cda48b30811", AlterId: 0}
+	switch protocol {
+	case "trojan", "shadowsocks", "hysteria2":
+		client.UuidOrPassword = "s3cr3t-pass"
+	}
+	return subscriptionEntry{
+		Client: client,
+		Inbound: model.XrayInbound{
+			Protocol:           protocol,
+			Port:               443,
+			StreamSettingsJson: streamJSON,
+			SettingsJson:       settingsJSON,
+		},
+		Node:   &model.Node{ServerIp: "203.0.113.10"},
+		Remark: remark,
+		Host:   "203.0.113.10",
+	}
+}
+
+func checkGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", "singbox", name+".golden.json")
+	if *updateGolden {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read golden file (run with -update to create): %v", err)
+	}
+	if !bytes.Equal(bytes.TrimSpace(got), bytes.TrimSpace(want)) {
+		t.Errorf("%s mismatch\n--- got ---\n%s\n--- want ---\n%s", name, got, want)
+	}
+}
+
+func TestSingboxOutbounds(t *testing.T) {
+	cases := []struct {
+		name     string
+		protocol string
+		stream   string
+		settings string
+		flow     string
+	}{
+		{name: "vmess_tcp", protocol: "vmess", stream: `{"network":"tcp"}`},
+		{name: "vmess_ws_tls", protocol: "vmess", stream: `{"network":"ws","security":"tls","wsSettings":{"path":"/ws","headers":{"Host":"cdn.example.com"}},"tlsSettings":{"serverName":"cdn.example.com","alpn":["http/1.1"],"fingerprint":"firefox"}}`},
+		{name: "vmess_grpc_tls", protocol: "vmess", stream: `{"network":"grpc","security":"tls","grpcSettings":{"serviceName":"vm-grpc"},"tlsSettings":{"serverName":"grpc.example.com"}}`},
+		{name: "vless_tcp_reality_vision", protocol: "vless", flow: "xtls-rprx-vision", stream: `{"network":"tcp","security":"reality","realitySettings":{"serverNames":["www.microsoft.com"],"publicKey":"jNXHt1yRo0vDuchQlIP6Z0ZvjT3KtzVI-T4E7RoLJS0","shortIds":["6ba85179e30d4fc2"]}}`},
+		{name: "vless_grpc_reality", protocol: "vless", stream: `{"network":"grpc","security":"reality","grpcSettings":{"serviceName":"vl-grpc"},"realitySettings":{"serverNames":["www.apple.com"],"publicKey":"jNXHt1yRo0vDuchQlIP6Z0ZvjT3KtzVI-T4E7RoLJS0","shortIds":[""],"fingerprint":"safari"}}`},
+		{name: "vless_httpupgrade_tls", protocol: "vless", stream: `{"network":"httpupgrade","security":"tls","httpupgradeSettings":{"path":"/up","host":"up.example.com"},"tlsSettings":{"serverName":"up.example.com"}}`},
+		{name: "vless_h2_tls", protocol: "vless", stream: `{"network":"h2","security":"tls","httpSettings":{"path":"/h2","host":["h2.example.com"]},"tlsSettings":{"serverName":"h2.example.com","alpn":["h2"]}}`},
+		{name: "trojan_tcp_tls", protocol: "trojan", stream: `{"network":"tcp","security":"tls","tlsSettings":{"serverName":"trojan.example.com"}}`},
+		{name: "trojan_ws_tls", protocol: "trojan", stream: `{"network":"ws","security":"tls","wsSettings":{"path":"/tj"},"tlsSettings":{"serverName":"trojan.example.com"}}`},
+		{name: "shadowsocks_aes", protocol: "shadowsocks", settings: `{"method":"aes-128-gcm"}`},
+		{name: "shadowsocks_2022", protocol: "shadowsocks", settings: `{"method":"2022-blake3-aes-256-gcm"}`},
+		{name: "hysteria2_obfs", protocol: "hysteria2", stream: `{"security":"tls","tlsSettings":{"serverName":"hy.example.com"}}`, settings: `{"obfsPassword":"cry_me_a_r1ver"}`},
+		{name: "tuic_bbr", protocol: "tuic", stream: `{"security":"tls","tlsSettings":{"serverName":"tuic.example.com","alpn":["h3","spdy/3.1"]}}`},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			e := singboxTestEntry(tc.protocol, tc.name, tc.stream, tc.settings)
+			e.Client.Flow = tc.flow
+			ob, ok := buildSingboxOutbound(&e)
+			if !ok {
+				t.Fatalf("outbound for %s was skipped", tc.name)
+			}
+			got, err := json.MarshalIndent(ob, "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkGolden(t, tc.name, got)
+		})
+	}
+}
+
+func TestSingboxUnsupportedTransports(t *testing.T) {
+	for _, stream := range []string{
+		`{"network":"kcp"}`,
+		`{"network":"xhttp","xhttpSettings":{"path":"/x"}}`,
+		`{"network":"tcp","tcpSettings":{"header":{"type":"http"}}}`,
+	} {
+		e := singboxTestEntry("vless", "skip", stream, "")
+		if _, ok := buildSingboxOutbound(&e); ok {
+			t.Errorf("expected %s to be skipped", stream)
+		}
+	}
+}
+
+func TestSingboxConfig(t *testing.T) {
+	entries := []subscriptionEntry{
+		singboxTestEntry("vless", "HK", `{"network":"ws","security":"tls","wsSettings":{"path":"/ws"},"tlsSettings":{"serverName":"hk.example.com"}}`, ""),
+		singboxTestEntry("trojan", "HK", `{"network":"tcp","security":"tls","tlsSettings":{"serverName":"hk.example.com"}}`, ""),
+		singboxTestEntry("vless", "KCP", `{"network":"kcp"}`, ""),
+	}
+
+	got, err := buildSingboxConfig(entries, nil, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, "config_default", got)
+
+	got, err = buildSingboxConfig(entries, nil,
+		`{"servers":[{"tag":"google","address":"tls://8.8.8.8"}]}`,
+		`{"rules":[{"domain_suffix":["cn"],"outbound":"direct"}],"final":"proxy"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, "config_templates", got)
+
+	if _, err := buildSingboxConfig(entries, nil, `[1,2]`, ""); err == nil {
+		t.Error("expected a non-object DNS template to be rejected")
+	}
+}
+
+func TestSingboxConfigEmpty(t *testing.T) {
+	got, err := buildSingboxConfig(nil, nil, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cfg struct {
+		Outbounds []struct {
+			Tag       string   `json:"tag"`
+			Outbounds []string `json:"outbounds"`
+		} `json:"outbounds"`
+	}
+	if err := json.Unmarshal(got, &cfg); err != nil {
+		t.Fatal(err)
+	}
+	for _, ob := range cfg.Outbounds {
+		if ob.Tag == singboxTagAuto && len(ob.Outbounds) == 0 {
+			t.Error("urltest group must not be empty")
+		}
+	}
+}
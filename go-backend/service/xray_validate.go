@@ -0,0 +1,222 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"flux-panel/go-backend/model"
+	"flux-panel/go-backend/pkg"
+)
+
+// Panel-side checks for inbound JSON. They catch the common mistakes with a
+// readable message before anything reaches a node; validateInboundOnNode
+// then lets the node's own runtime have the final word.
+
+var inboundProtocols = map[string]bool{
+	"vmess": true, "vless": true, "trojan": true, "shadowsocks": true,
+	"hysteria2": true, "tuic": true,
+}
+
+var streamNetworks = map[string]bool{
+	"tcp": true, "raw": true, "ws": true, "grpc": true, "httpupgrade": true,
+	"xhttp": true, "splithttp": true, "kcp": true, "mkcp": true,
+}
+
+// REALITY only works over these transports.
+var realityNetworks = map[string]bool{"": true, "tcp": true, "raw": true, "grpc": true, "xhttp": true}
+
+var shadowsocksMethods = map[string]bool{
+	"aes-128-gcm": true, "aes-256-gcm": true, "chacha20-poly1305": true,
+	"chacha20-ietf-poly1305": true, "xchacha20-poly1305": true, "xchacha20-ietf-poly1305": true,
+	"2022-blake3-aes-128-gcm": true, "2022-blake3-aes-256-gcm": true,
+	"2022-blake3-chacha20-poly1305": true, "none": true, "plain": true,
+}
+
+var sniffingDestOverrides = map[string]bool{"http": true, "tls": true, "quic": true, "fakedns": true}
+
+var shortIdPattern = regexp.MustCompile(`^([0-9a-fA-F]{2}){0,8}$`)
+
+// validateInboundJSON checks the structure of an inbound's settings, stream
+// settings and sniffing JSON. Returns an error message, or "" if valid.
+func validateInboundJSON(protocol, settingsJson, streamJson, sniffingJson string) string {
+	if !inboundProtocols[protocol] {
+		return "不支持的协议: " + protocol
+	}
+
+	settings, msg := decodeJSONObject(settingsJson, "协议配置")
+	if msg != "" {
+		return msg
+	}
+	stream, msg := decodeJSONObject(streamJson, "传输配置")
+	if msg != "" {
+		return msg
+	}
+	sniffing, msg := decodeJSONObject(sniffingJson, "嗅探配置")
+	if msg != "" {
+		return msg
+	}
+
+	if msg := validateInboundSettings(protocol, settings); msg != "" {
+		return msg
+	}
+	if !isSingboxProtocol(protocol) {
+		if msg := validateStreamSettings(stream); msg != "" {
+			return msg
+		}
+	}
+	return validateSniffing(sniffing)
+}
+
+// decodeJSONObject returns nil for empty input.
+func decodeJSONObject(value, name string) (map[string]interface{}, string) {
+	if strings.TrimSpace(value) == "" {
+		return nil, ""
+	}
+	var obj map[string]interface{}
+	if err := json.Unmarshal([]byte(value), &obj); err != nil {
+		return nil, fmt.Sprintf("%s不是有效的 JSON 对象: %v", name, err)
+	}
+	return obj, ""
+}
+
+func validateInboundSettings(protocol string, settings map[string]interface{}) string {
+	if settings == nil {
+		return ""
+	}
+	if v, ok := settings["clients"]; ok {
+		if _, ok := v.([]interface{}); !ok {
+			return "协议配置中 clients 必须是数组"
+		}
+	}
+	if v, ok := settings["fallbacks"]; ok {
+		list, ok := v.([]interface{})
+		if !ok {
+			return "协议配置中 fallbacks 必须是数组"
+		}
+		for i, item := range list {
+			fb, ok := item.(map[string]interface{})
+			if !ok || fb["dest"] == nil {
+				return fmt.Sprintf("第 %d 个回落缺少 dest", i+1)
+			}
+		}
+	}
+
+	switch protocol {
+	case "shadowsocks":
+		method, _ := settings["method"].(string)
+		if method != "" && !shadowsocksMethods[method] {
+			return "不支持的 Shadowsocks 加密方式: " + method
+		}
+		if network, _ := settings["network"].(string); network != "" &&
+			network != "tcp" && network != "udp" && network != "tcp,udp" {
+			return "Shadowsocks network 只能是 tcp、udp 或 tcp,udp"
+		}
+	case "tuic":
+		switch cc, _ := settings["congestionControl"].(string); cc {
+		case "", "bbr", "cubic", "new_reno":
+		default:
+			return "不支持的拥塞控制算法: " + cc
+		}
+	}
+	return ""
+}
+
+func validateStreamSettings(stream map[string]interface{}) string {
+	if stream == nil {
+		return ""
+	}
+	network, _ := stream["network"].(string)
+	if network != "" && !streamNetworks[network] {
+		return "不支持的传输方式: " + network
+	}
+
+	for _, key := range []string{"wsSettings", "httpupgradeSettings", "xhttpSettings"} {
+		obj, _ := stream[key].(map[string]interface{})
+		if path, _ := obj["path"].(string); path != "" && !strings.HasPrefix(path, "/") {
+			return key + ".path 必须以 / 开头"
+		}
+	}
+
+	security, _ := stream["security"].(string)
+	switch security {
+	case "", "none":
+	case "tls":
+		tls, _ := stream["tlsSettings"].(map[string]interface{})
+		certs, _ := tls["certificates"].([]interface{})
+		if len(certs) == 0 {
+			return "TLS 至少需要配置一个证书"
+		}
+		for i, item := range certs {
+			cert, _ := item.(map[string]interface{})
+			hasFile := cert["certificateFile"] != nil && cert["keyFile"] != nil
+			hasInline := cert["certificate"] != nil && cert["key"] != nil
+			if !hasFile && !hasInline {
+				return fmt.Sprintf("第 %d 个 TLS 证书缺少证书或私钥", i+1)
+			}
+		}
+	case "reality":
+		if !realityNetworks[network] {
+			return "REALITY 不支持 " + network + " 传输"
+		}
+		reality, _ := stream["realitySettings"].(map[string]interface{})
+		if reality["dest"] == nil && reality["target"] == nil {
+			return "REALITY 缺少目标地址 (dest)"
+		}
+		if key, _ := reality["privateKey"].(string); key == "" {
+			return "REALITY 缺少私钥"
+		}
+		if names, _ := reality["serverNames"].([]interface{}); len(names) == 0 {
+			return "REALITY 至少需要一个 serverName"
+		}
+		ids, _ := reality["shortIds"].([]interface{})
+		for _, item := range ids {
+			if id, ok := item.(string); !ok || !shortIdPattern.MatchString(id) {
+				return fmt.Sprintf("REALITY shortId 无效: %v (需为 0-16 位偶数长度十六进制)", item)
+			}
+		}
+	default:
+		return "不支持的安全类型: " + security
+	}
+	return ""
+}
+
+func validateSniffing(sniffing map[string]interface{}) string {
+	if sniffing == nil {
+		return ""
+	}
+	if v, ok := sniffing["destOverride"]; ok {
+		list, ok := v.([]interface{})
+		if !ok {
+			return "嗅探配置中 destOverride 必须是数组"
+		}
+		for _, item := range list {
+			if s, _ := item.(string); !sniffingDestOverrides[s] {
+				return fmt.Sprintf("不支持的嗅探类型: %v", item)
+			}
+		}
+	}
+	return ""
+}
+
+// validateInboundOnNode dry-runs the inbound on its node (`xray run -test`
+// or `sing-box check`) without touching the running config. Offline nodes
+// and nodes too old to know VValidateConfig are skipped; reconcile still
+// rolls back a bad config there.
+func validateInboundOnNode(inbound *model.XrayInbound) string {
+	if pkg.WS == nil || !pkg.WS.IsNodeOnline(inbound.NodeId) || !pkg.WS.NodeSupports(inbound.NodeId, "VValidateConfig") {
+		return ""
+	}
+	candidate := *inbound
+	if candidate.Tag == "" {
+		candidate.Tag = "inbound-validate"
+	}
+	candidate.SettingsJson = mergeClientsIntoSettings(&candidate)
+
+	result := pkg.XrayValidateConfig(inbound.NodeId, []model.XrayInbound{candidate})
+	if result == nil || result.Msg == gostSuccessMsg || strings.Contains(result.Msg, pkg.UnknownCommandMsg) {
+		return ""
+	}
+	return "配置校验失败: " + result.Msg
+}
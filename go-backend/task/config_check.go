@@ -1,6 +1,8 @@
 package task
 
 import (
+	"context"
+	"flux-panel/go-backend/pkg"
 	"flux-panel/go-backend/service"
 	"log"
 	"time"
@@ -10,8 +12,10 @@ import (
 // It delays briefly then triggers a full config reconciliation.
 func RunConfigCheck(nodeId int64) {
 	log.Printf("[ConfigCheck] Node %d online, scheduling reconcile", nodeId)
-	go func() {
-		time.Sleep(2 * time.Second)
+	pkg.Tasks.Go("config-check", func(ctx context.Context) {
+		if !pkg.Sleep(ctx, 2*time.Second) {
+			return
+		}
 		service.ReconcileNode(nodeId)
-	}()
+	})
 }
@@ -1,7 +1,9 @@
 package task
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"flux-panel/go-backend/model"
 	"flux-panel/go-backend/pkg"
 	"flux-panel/go-backend/service"
@@ -11,17 +13,19 @@ import (
 	"time"
 )
 
-func StartLatencyMonitor() {
-	go func() {
-		// Wait for DB and WS to be ready
-		time.Sleep(10 * time.Second)
-
-		for {
-			interval := getMonitorInterval()
+// RegisterLatencyMonitorJob schedules the forward latency probe. Its
+// interval follows the monitor_interval setting.
+func RegisterLatencyMonitorJob() {
+	service.RegisterJob(&service.Job{
+		Name:        service.JobLatencyMonitor,
+		Description: "转发延迟监控",
+		Spec:        fmt.Sprintf("@every %ds", getMonitorInterval()),
+		Missed:      service.MissedSkip,
+		Run: func(ctx context.Context, scheduled time.Time) error {
 			runLatencyCheck()
-			time.Sleep(time.Duration(interval) * time.Second)
-		}
-	}()
+			return nil
+		},
+	})
 }
 
 func getMonitorInterval() int {
@@ -112,14 +116,10 @@ func runLatencyCheck() {
 			targetIp := extractIp(ct.addr)
 			targetPort := extractPort(ct.addr)
 
-			tcpPingData := map[string]interface{}{
-				"ip":      targetIp,
-				"port":    targetPort,
-				"count":   2,
-				"timeout": 3000,
-			}
-
-			result := pkg.WS.SendMsg(ct.nodeId, tcpPingData, "TcpPing")
+			// UDP-primary forwards are probed over UDP; both commands
+			// answer with the same success/averageTime fields
+			cmd, probeData := service.ForwardProbeCommand(&ct.forward, targetIp, targetPort)
+			result := pkg.WS.SendMsg(ct.nodeId, probeData, cmd)
 
 			record := model.MonitorLatency{
 				ForwardId:  ct.forward.ID,
@@ -133,14 +133,14 @@ func runLatencyCheck() {
 			if result != nil && result.Msg == "OK" && result.Data != nil {
 				dataBytes, err := json.Marshal(result.Data)
 				if err == nil {
-					var tcpPingResp struct {
+					var probeResp struct {
 						Success     bool    `json:"success"`
 						AverageTime float64 `json:"averageTime"`
 					}
-					if json.Unmarshal(dataBytes, &tcpPingResp) == nil {
-						record.Success = tcpPingResp.Success
-						if tcpPingResp.Success {
-							record.Latency = tcpPingResp.AverageTime
+					if json.Unmarshal(dataBytes, &probeResp) == nil {
+						record.Success = probeResp.Success
+						if probeResp.Success {
+							record.Latency = probeResp.AverageTime
 						}
 					}
 				}
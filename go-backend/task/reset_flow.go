@@ -1,8 +1,10 @@
 package task
 
 import (
+	"context"
 	"flux-panel/go-backend/model"
 	"flux-panel/go-backend/pkg"
+	"flux-panel/go-backend/service"
 	"fmt"
 	"log"
 	"strings"
@@ -11,65 +13,81 @@ import (
 	"gorm.io/gorm"
 )
 
-func StartResetFlowTask(db *gorm.DB) {
-	go func() {
-		for {
-			now := time.Now()
-			// Schedule next run at 00:00:05
-			next := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 5, 0, now.Location())
-			time.Sleep(time.Until(next))
-
-			log.Println("[ResetFlowTask] Starting daily flow reset...")
-			resetFlow(db)
-		}
-	}()
+// RegisterResetFlowJob schedules the flow reset and expiry check. It runs
+// hourly so that each user is reset early on the reset day in their own
+// timezone; missed runs are replayed so a reset day is never skipped by
+// downtime.
+func RegisterResetFlowJob(db *gorm.DB) {
+	service.RegisterJob(&service.Job{
+		Name:        service.JobResetFlow,
+		Description: "流量重置与到期检查 (按用户时区)",
+		Spec:        "@hourly",
+		Missed:      service.MissedReplay,
+		Run: func(ctx context.Context, scheduled time.Time) error {
+			log.Printf("[ResetFlowTask] Starting flow reset for %s...", scheduled.Format(time.RFC3339))
+			resetFlow(db, scheduled)
+			return nil
+		},
+	})
 }
 
-func resetFlow(db *gorm.DB) {
-	today := time.Now()
-	dayOfMonth := today.Day()
-	dayOfWeek := int(today.Weekday()) // 0=Sunday, 6=Saturday
-	daysInMonth := daysInCurrentMonth(today)
-
+// resetFlow resets the counters of users for whom the given run time falls
+// on a reset day in their timezone that they haven't been reset for yet, and
+// disables accounts that have expired by now. Each reset records the local
+// date it was made for, so later runs on the same day are no-ops.
+func resetFlow(db *gorm.DB, at time.Time) {
 	// 1. Reset user flow by periodic schedule
-	// Monthly reset: flow_reset_type=1 AND (flow_reset_day=today OR (flow_reset_day>daysInMonth AND today is last day))
-	var usersMonthly []model.User
-	db.Where("flow_reset_type = 1 AND status = 1 AND (flow_reset_day = ? OR (flow_reset_day > ? AND ? = ?))",
-		dayOfMonth, daysInMonth, dayOfMonth, daysInMonth).
-		Find(&usersMonthly)
-
-	// Weekly reset: flow_reset_type=2 AND flow_reset_day=weekday
-	var usersWeekly []model.User
-	db.Where("flow_reset_type = 2 AND status = 1 AND flow_reset_day = ?", dayOfWeek).
-		Find(&usersWeekly)
-
-	usersToReset := append(usersMonthly, usersWeekly...)
-	for _, user := range usersToReset {
-		db.Model(&model.User{}).Where("id = ?", user.ID).Updates(map[string]interface{}{
-			"in_flow":       0,
-			"out_flow":      0,
-			"xray_in_flow":  0,
-			"xray_out_flow": 0,
-		})
+	var users []model.User
+	db.Find(&users)
+
+	userLocs := make(map[int64]*time.Location, len(users))
+	for i := range users {
+		userLocs[users[i].ID] = service.UserLocation(&users[i])
+	}
+
+	for _, user := range users {
+		local := at.In(userLocs[user.ID])
+		if user.Status != 1 || !isResetDue(user.FlowResetType, user.FlowResetDay, local) {
+			continue
+		}
+		period := resetPeriod(local)
+		res := db.Model(&model.User{}).Where("id = ? AND (reset_period IS NULL OR reset_period <> ?)", user.ID, period).
+			Updates(map[string]interface{}{
+				"in_flow":       0,
+				"out_flow":      0,
+				"xray_in_flow":  0,
+				"xray_out_flow": 0,
+				"reset_period":  period,
+			})
+		if res.RowsAffected == 0 {
+			continue
+		}
 		log.Printf("[ResetFlowTask] Reset user flow: userId=%d, user=%s, type=%d, day=%d", user.ID, user.User, user.FlowResetType, user.FlowResetDay)
 	}
 
-	// 2. Reset user_tunnel flow by periodic schedule
-	var tunnelsMonthly []model.UserTunnel
-	db.Where("flow_reset_type = 1 AND status = 1 AND (flow_reset_day = ? OR (flow_reset_day > ? AND ? = ?))",
-		dayOfMonth, daysInMonth, dayOfMonth, daysInMonth).
-		Find(&tunnelsMonthly)
-
-	var tunnelsWeekly []model.UserTunnel
-	db.Where("flow_reset_type = 2 AND status = 1 AND flow_reset_day = ?", dayOfWeek).
-		Find(&tunnelsWeekly)
-
-	tunnelsToReset := append(tunnelsMonthly, tunnelsWeekly...)
-	for _, ut := range tunnelsToReset {
-		db.Model(&model.UserTunnel{}).Where("id = ?", ut.ID).Updates(map[string]interface{}{
-			"in_flow":  0,
-			"out_flow": 0,
-		})
+	// 2. Reset user_tunnel flow by periodic schedule, in the owning user's timezone
+	var tunnels []model.UserTunnel
+	db.Where("flow_reset_type IN (1, 2) AND status = 1").Find(&tunnels)
+
+	for _, ut := range tunnels {
+		loc, ok := userLocs[ut.UserId]
+		if !ok {
+			loc = service.PanelLocation()
+		}
+		local := at.In(loc)
+		if !isResetDue(ut.FlowResetType, ut.FlowResetDay, local) {
+			continue
+		}
+		period := resetPeriod(local)
+		res := db.Model(&model.UserTunnel{}).Where("id = ? AND (reset_period IS NULL OR reset_period <> ?)", ut.ID, period).
+			Updates(map[string]interface{}{
+				"in_flow":      0,
+				"out_flow":     0,
+				"reset_period": period,
+			})
+		if res.RowsAffected == 0 {
+			continue
+		}
 		log.Printf("[ResetFlowTask] Reset user_tunnel flow: id=%d, userId=%d, tunnelId=%d", ut.ID, ut.UserId, ut.TunnelId)
 	}
 
@@ -94,7 +112,7 @@ func resetFlow(db *gorm.DB) {
 		log.Printf("[ResetFlowTask] Disabled expired user_tunnel: id=%d, userId=%d, tunnelId=%d", ut.ID, ut.UserId, ut.TunnelId)
 	}
 
-	log.Println("[ResetFlowTask] Daily flow reset completed")
+	log.Println("[ResetFlowTask] Flow reset completed")
 }
 
 func pauseUserForwards(db *gorm.DB, userId int64) {
@@ -152,6 +170,27 @@ func pauseSingleForward(db *gorm.DB, fwd *model.Forward) {
 	db.Model(&model.Forward{}).Where("id = ?", fwd.ID).Update("status", 0)
 }
 
+// isResetDue reports whether local falls on a reset day. Monthly resets
+// (type 1) fire on flow_reset_day, or on the last day of shorter months;
+// weekly resets (type 2) fire on that weekday (0=Sunday). The hour isn't
+// checked: the first run of the day resets and reset_period makes the rest
+// no-ops, which also covers days where DST skips midnight.
+func isResetDue(resetType, resetDay int, local time.Time) bool {
+	switch resetType {
+	case 1:
+		daysInMonth := daysInCurrentMonth(local)
+		return resetDay == local.Day() || (resetDay > daysInMonth && local.Day() == daysInMonth)
+	case 2:
+		return resetDay == int(local.Weekday())
+	}
+	return false
+}
+
+// resetPeriod identifies the reset period that starts on local's date.
+func resetPeriod(local time.Time) string {
+	return local.Format("2006-01-02")
+}
+
 // daysInCurrentMonth returns the number of days in the current month.
 func daysInCurrentMonth(t time.Time) int {
 	return time.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, t.Location()).Day()
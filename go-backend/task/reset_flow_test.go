@@ -0,0 +1,47 @@
+package task
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIsResetDue(t *testing.T) {
+	day := func(y int, m time.Month, d, h int) time.Time {
+		return time.Date(y, m, d, h, 0, 0, 0, time.UTC)
+	}
+	tests := []struct {
+		name      string
+		resetType int
+		resetDay  int
+		at        time.Time
+		want      bool
+	}{
+		{"monthly on the day", 1, 15, day(2026, 3, 15, 0), true},
+		{"monthly later that day", 1, 15, day(2026, 3, 15, 5), true},
+		{"monthly other day", 1, 15, day(2026, 3, 16, 0), false},
+		{"monthly 31 in a 30-day month", 1, 31, day(2026, 4, 30, 0), true},
+		{"monthly 31 in February", 1, 31, day(2026, 2, 28, 0), true},
+		{"monthly 31 before month end", 1, 31, day(2026, 4, 29, 0), false},
+		{"weekly Sunday", 2, 0, day(2026, 3, 1, 0), true},
+		{"weekly other day", 2, 0, day(2026, 3, 2, 0), false},
+		{"no reset", 0, 1, day(2026, 3, 1, 0), false},
+	}
+	for _, tt := range tests {
+		if got := isResetDue(tt.resetType, tt.resetDay, tt.at); got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	// Chile starts DST at midnight: 2026-09-06 begins at 01:00
+	santiago, err := time.LoadLocation("America/Santiago")
+	if err != nil {
+		t.Skip(err)
+	}
+	first := time.Date(2026, time.September, 6, 0, 0, 0, 0, santiago)
+	if first.Hour() != 1 {
+		t.Skipf("midnight not skipped in tzdata: %s", first)
+	}
+	if !isResetDue(1, 6, first) || !isResetDue(2, 0, first) {
+		t.Fatal("reset day without a midnight hour was skipped")
+	}
+}
@@ -1,29 +1,37 @@
 package task
 
 import (
+	"context"
+	"flux-panel/go-backend/pkg"
 	"flux-panel/go-backend/service"
 	"log"
 	"time"
 )
 
-func StartStatisticsTask() {
+// RegisterStatisticsJob records baseline snapshots and schedules the hourly
+// statistics job.
+func RegisterStatisticsJob() {
 	// Record flow snapshots immediately on startup as baseline,
 	// so the first hourly run can compute deltas.
-	log.Println("[StatisticsTask] Recording initial flow snapshots...")
-	service.RecordForwardFlowSnapshots()
-	service.RecordXrayFlowSnapshots()
-	service.RecordUserFlowSnapshots()
-
-	go func() {
-		for {
-			now := time.Now()
-			// Schedule at the top of each hour
-			next := time.Date(now.Year(), now.Month(), now.Day(), now.Hour()+1, 0, 0, 0, now.Location())
-			time.Sleep(time.Until(next))
+	// Snapshots live in the DB, so replicas other than the leader skip them.
+	if pkg.IsLeader() {
+		log.Println("[StatisticsTask] Recording initial flow snapshots...")
+		service.RecordForwardFlowSnapshots()
+		service.RecordXrayFlowSnapshots()
+		service.RecordUserFlowSnapshots()
+	}
 
+	// Hourly deltas only make sense on the hour, so missed runs are skipped
+	service.RegisterJob(&service.Job{
+		Name:        service.JobStatistics,
+		Description: "每小时流量统计",
+		Spec:        "@hourly",
+		Missed:      service.MissedSkip,
+		Run: func(ctx context.Context, scheduled time.Time) error {
 			log.Println("[StatisticsTask] Recording hourly statistics...")
 			service.RecordHourlyStatistics()
 			log.Println("[StatisticsTask] Hourly statistics recorded")
-		}
-	}()
+			return nil
+		},
+	})
 }
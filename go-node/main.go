@@ -128,6 +128,9 @@ func main() {
 		os.Remove(legacy + ".bak")
 	}
 
+	// A freshly updated binary must reach the panel or be rolled back
+	socket.CheckPendingUpdate()
+
 	// 加载配置文件
 	config, err := LoadConfig("config.json")
 	if err != nil {
@@ -155,8 +158,10 @@ func main() {
 	defer wsReporter.Stop()
 	service.SetHTTPReportURL(config.Addr, config.Secret, config.UseTLS)
 
-	// Start Xray traffic reporter (collects stats every 30s and uploads to panel)
-	wsReporter.StartXrayTrafficReporter(config.Addr)
+	// Start Xray traffic reporter (collects stats every 30s into the flow spool,
+	// which must be opened by SetHTTPReportURL first)
+	wsReporter.StartXrayTrafficReporter()
+	wsReporter.StartSingboxTrafficReporter()
 
 	p := &program{}
 	if err := svc.Run(p); err != nil {
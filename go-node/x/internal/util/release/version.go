@@ -0,0 +1,45 @@
+// Package release compares node release versions.
+package release
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parse reads "v1.2.3", "1.2.3-rc1" or "1.2" as major, minor, patch. "dev"
+// and unparsable parts count as zero.
+func parse(v string) [3]int {
+	v = strings.TrimPrefix(strings.TrimSpace(v), "v")
+	if idx := strings.Index(v, "-"); idx >= 0 {
+		v = v[:idx]
+	}
+	var out [3]int
+	for i, part := range strings.SplitN(v, ".", 3) {
+		if n, err := strconv.Atoi(part); err == nil {
+			out[i] = n
+		}
+	}
+	return out
+}
+
+// Less reports whether version a is older than b.
+func Less(a, b string) bool {
+	pa, pb := parse(a), parse(b)
+	for i := range pa {
+		if pa[i] != pb[i] {
+			return pa[i] < pb[i]
+		}
+	}
+	return false
+}
+
+// CheckUpgrade refuses a release older than the running one. Going back is
+// only done by restoring the backup of the previous binary, so a panel
+// can't push an old signed build with known flaws onto a node.
+func CheckUpgrade(running, target string) error {
+	if Less(target, running) {
+		return fmt.Errorf("拒绝降级: 发布版本 %s 低于当前版本 %s，如需回退请使用回滚", target, running)
+	}
+	return nil
+}
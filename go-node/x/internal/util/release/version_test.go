@@ -0,0 +1,27 @@
+package release
+
+import "testing"
+
+func TestCheckUpgrade(t *testing.T) {
+	tests := []struct {
+		running, target string
+		ok              bool
+	}{
+		{"1.4.2", "1.4.3", true},
+		{"1.4.2", "1.4.2", true}, // reinstalling the same build
+		{"1.4.2", "2.0.0", true},
+		{"v1.4.2", "1.10.0", true},
+		{"dev", "1.0.0", true},
+		{"1.4.2", "1.4.1", false},
+		{"1.4.2", "1.3.9", false},
+		{"2.0.0", "v1.9.9", false},
+		{"1.4.2", "1.4.2-rc1", true}, // pre-release suffix is ignored
+		{"1.4.2", "dev", false},
+	}
+	for _, tt := range tests {
+		err := CheckUpgrade(tt.running, tt.target)
+		if (err == nil) != tt.ok {
+			t.Errorf("CheckUpgrade(%q, %q) = %v, want ok=%v", tt.running, tt.target, err, tt.ok)
+		}
+	}
+}
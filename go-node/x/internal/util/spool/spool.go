@@ -0,0 +1,312 @@
+// Package spool implements a durable, sequenced queue of traffic reports.
+//
+// Every report that leaves the node carries the spool epoch and a per-node
+// monotonically increasing sequence number. Entries are persisted to disk
+// before they are sent and only removed once the panel acknowledges them,
+// so counters survive process restarts and panel outages, and the panel can
+// discard retransmissions of a report it has already applied.
+package spool
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Report kinds. Each kind is delivered to its own panel endpoint but all
+// kinds share one sequence space per node.
+const (
+	KindGost = "gost" // gost forward traffic, /flow/upload
+	KindXray = "xray" // V service client traffic, /flow/su
+)
+
+// Item is a single traffic counter delta. Key is the gost service name for
+// gost reports and the client email for Xray reports.
+type Item struct {
+	Key string `json:"k"`
+	U   int64  `json:"u"`
+	D   int64  `json:"d"`
+}
+
+// Entry is one sequenced report waiting to be delivered.
+type Entry struct {
+	Seq   int64  `json:"seq"`
+	Kind  string `json:"kind"`
+	Items []Item `json:"items"`
+}
+
+type state struct {
+	Epoch   string  `json:"epoch"`
+	NextSeq int64   `json:"next_seq"`
+	Entries []Entry `json:"entries"`
+}
+
+// Spool is safe for concurrent use.
+type Spool struct {
+	path   string
+	syncMu sync.Mutex // serialises writers so an older snapshot never overwrites a newer one
+	mu     sync.Mutex
+	st     state
+	sealed int64 // entries up to this seq may have reached the panel and must not change
+	dirty  bool
+}
+
+// Open loads the spool stored at path, creating a fresh one with a new
+// random epoch if the file does not exist. An empty path gives a memory-only
+// spool, which still deduplicates retries but does not survive restarts.
+func Open(path string) (*Spool, error) {
+	s := &Spool{path: path}
+
+	if path != "" {
+		b, err := os.ReadFile(path)
+		switch {
+		case err == nil:
+			if err := json.Unmarshal(b, &s.st); err != nil {
+				return nil, fmt.Errorf("parse spool %s: %v", path, err)
+			}
+		case os.IsNotExist(err):
+		default:
+			return nil, fmt.Errorf("read spool %s: %v", path, err)
+		}
+	}
+
+	if s.st.Epoch == "" {
+		s.st.Epoch = newEpoch()
+		s.st.NextSeq = 1
+		s.st.Entries = nil
+		s.dirty = true
+	}
+	if s.st.NextSeq <= 0 {
+		s.st.NextSeq = 1
+	}
+	for _, e := range s.st.Entries {
+		if e.Seq >= s.st.NextSeq {
+			s.st.NextSeq = e.Seq + 1
+		}
+		// A loaded entry may have been sent before the restart without the
+		// ack arriving; the panel would drop anything merged into it later.
+		if e.Seq > s.sealed {
+			s.sealed = e.Seq
+		}
+	}
+
+	if err := s.Sync(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Epoch identifies this spool instance. It changes only when the spool file
+// is lost, which lets the panel tell a reinstalled node from a replay.
+func (s *Spool) Epoch() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.st.Epoch
+}
+
+// Add records counter deltas of the given kind. Deltas are merged into the
+// newest pending entry of the same kind unless that entry may already have
+// been sent, so the spool stays small during long panel outages.
+//
+// Add only updates memory; call Sync to make the change durable, or use
+// Record. Head always syncs before handing out an entry.
+func (s *Spool) Add(kind string, items []Item) {
+	if len(items) == 0 {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.st = s.withItems(kind, items)
+	s.dirty = true
+}
+
+// Record adds deltas like Add and persists them before returning. On error
+// the spool is left unchanged, so the caller can keep its counters and retry
+// instead of losing or double counting the deltas.
+func (s *Spool) Record(kind string, items []Item) error {
+	if len(items) == 0 {
+		return nil
+	}
+
+	s.syncMu.Lock()
+	defer s.syncMu.Unlock()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	next := s.withItems(kind, items)
+	if s.path != "" {
+		data, err := json.Marshal(next)
+		if err == nil {
+			err = writeFileAtomic(s.path, data)
+		}
+		if err != nil {
+			return fmt.Errorf("sync spool %s: %v", s.path, err)
+		}
+	}
+	s.st = next
+	s.dirty = false
+	return nil
+}
+
+// withItems returns a copy of the state with items merged in. Entries that
+// are modified are copied, so the current state stays intact. Callers hold mu.
+func (s *Spool) withItems(kind string, items []Item) state {
+	st := s.st
+	st.Entries = append([]Entry(nil), s.st.Entries...)
+
+	target := -1
+	for i := len(st.Entries) - 1; i >= 0 && st.Entries[i].Seq > s.sealed; i-- {
+		if st.Entries[i].Kind == kind {
+			target = i
+			break
+		}
+	}
+
+	if target < 0 {
+		st.Entries = append(st.Entries, Entry{
+			Seq:  st.NextSeq,
+			Kind: kind,
+		})
+		st.NextSeq++
+		target = len(st.Entries) - 1
+	}
+
+	e := &st.Entries[target]
+	e.Items = append([]Item(nil), e.Items...)
+	index := make(map[string]int, len(e.Items))
+	for i, it := range e.Items {
+		index[it.Key] = i
+	}
+	for _, it := range items {
+		if it.U == 0 && it.D == 0 {
+			continue
+		}
+		if i, ok := index[it.Key]; ok {
+			e.Items[i].U += it.U
+			e.Items[i].D += it.D
+			continue
+		}
+		index[it.Key] = len(e.Items)
+		e.Items = append(e.Items, it)
+	}
+	return st
+}
+
+// Head returns the oldest undelivered entry and seals it, so later Add calls
+// never modify it, even if the ack is lost and the entry is sent again. The
+// spool is synced to disk before the entry is returned: an entry must never
+// reach the panel before its sequence number and contents are durable.
+func (s *Spool) Head() (Entry, bool, error) {
+	s.mu.Lock()
+	if len(s.st.Entries) == 0 {
+		s.mu.Unlock()
+		return Entry{}, false, nil
+	}
+	head := s.st.Entries[0]
+	if head.Seq > s.sealed {
+		s.sealed = head.Seq
+	}
+	items := make([]Item, len(head.Items))
+	copy(items, head.Items)
+	head.Items = items
+	s.mu.Unlock()
+
+	if err := s.Sync(); err != nil {
+		return Entry{}, false, err
+	}
+	return head, true, nil
+}
+
+// Ack removes the entry with the given sequence number after the panel has
+// confirmed it, and persists the result.
+func (s *Spool) Ack(seq int64) error {
+	s.mu.Lock()
+	for i, e := range s.st.Entries {
+		if e.Seq == seq {
+			s.st.Entries = append(s.st.Entries[:i], s.st.Entries[i+1:]...)
+			s.dirty = true
+			break
+		}
+	}
+	s.mu.Unlock()
+
+	return s.Sync()
+}
+
+// Len returns the number of undelivered entries.
+func (s *Spool) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.st.Entries)
+}
+
+// Sync writes the spool to disk atomically if it changed since the last sync.
+func (s *Spool) Sync() error {
+	s.syncMu.Lock()
+	defer s.syncMu.Unlock()
+
+	s.mu.Lock()
+	if !s.dirty || s.path == "" {
+		s.dirty = false
+		s.mu.Unlock()
+		return nil
+	}
+	data, err := json.Marshal(s.st)
+	s.dirty = false
+	s.mu.Unlock()
+
+	if err == nil {
+		err = writeFileAtomic(s.path, data)
+	}
+	if err != nil {
+		s.mu.Lock()
+		s.dirty = true
+		s.mu.Unlock()
+		return fmt.Errorf("sync spool %s: %v", s.path, err)
+	}
+	return nil
+}
+
+// writeFileAtomic writes data to a temp file in the same directory, fsyncs
+// it and renames it over path.
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
+
+func newEpoch() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		panic("crypto/rand failed: " + err.Error())
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,136 @@
+package spool
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// panel mimics the panel's per-node dedup: a report is applied only if its
+// seq is newer than the last one applied for the epoch.
+type panel struct {
+	epoch   string
+	lastSeq int64
+	totals  map[string]int64
+}
+
+func (p *panel) apply(epoch string, e Entry) bool {
+	if p.totals == nil {
+		p.totals = make(map[string]int64)
+	}
+	if p.epoch == epoch && e.Seq <= p.lastSeq {
+		return false
+	}
+	p.epoch, p.lastSeq = epoch, e.Seq
+	for _, it := range e.Items {
+		p.totals[it.Key] += it.U + it.D
+	}
+	return true
+}
+
+func mustOpen(t *testing.T, path string) *Spool {
+	t.Helper()
+	s, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	return s
+}
+
+func mustHead(t *testing.T, s *Spool) Entry {
+	t.Helper()
+	e, ok, err := s.Head()
+	if err != nil || !ok {
+		t.Fatalf("Head: ok=%v err=%v", ok, err)
+	}
+	return e
+}
+
+func TestAddAfterHeadStartsNewEntry(t *testing.T) {
+	s := mustOpen(t, "")
+	s.Add(KindGost, []Item{{Key: "a", U: 1}})
+	first := mustHead(t, s)
+
+	s.Add(KindGost, []Item{{Key: "a", U: 2}})
+	if s.Len() != 2 {
+		t.Fatalf("Len = %d, want 2: deltas were merged into a sent entry", s.Len())
+	}
+	if again := mustHead(t, s); again.Seq != first.Seq || again.Items[0].U != 1 {
+		t.Fatalf("resent entry changed: %+v", again)
+	}
+}
+
+func TestRestartResendIsDeduplicated(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spool.json")
+	p := &panel{}
+
+	s := mustOpen(t, path)
+	if err := s.Record(KindGost, []Item{{Key: "fwd", U: 100}}); err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+	sent := mustHead(t, s)
+	p.apply(s.Epoch(), sent) // delivered, but the ack is lost in a crash
+
+	// Restart: the entry is loaded again and must be treated as sent.
+	s = mustOpen(t, path)
+	if err := s.Record(KindGost, []Item{{Key: "fwd", U: 50}}); err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+
+	resent := mustHead(t, s)
+	if resent.Seq != sent.Seq || resent.Items[0].U != 100 {
+		t.Fatalf("resent entry = %+v, want the original seq %d with 100 bytes", resent, sent.Seq)
+	}
+	if p.apply(s.Epoch(), resent) {
+		t.Fatal("panel applied a resent entry twice")
+	}
+	if err := s.Ack(resent.Seq); err != nil {
+		t.Fatalf("Ack: %v", err)
+	}
+
+	next := mustHead(t, s)
+	if !p.apply(s.Epoch(), next) {
+		t.Fatal("panel dropped the deltas recorded after the restart")
+	}
+	if got := p.totals["fwd"]; got != 150 {
+		t.Fatalf("billed %d bytes, want 150", got)
+	}
+}
+
+func TestRecordIsDurable(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spool.json")
+	s := mustOpen(t, path)
+	if err := s.Record(KindXray, []Item{{Key: "user@x", D: 7}}); err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+
+	// No Sync or Head: a crash right after Record must not lose the deltas.
+	reopened := mustOpen(t, path)
+	e := mustHead(t, reopened)
+	if e.Kind != KindXray || len(e.Items) != 1 || e.Items[0].D != 7 {
+		t.Fatalf("reloaded entry = %+v", e)
+	}
+}
+
+func TestRecordFailureLeavesSpoolUnchanged(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spool.json")
+	s := mustOpen(t, path)
+	if err := s.Record(KindGost, []Item{{Key: "a", U: 1}}); err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+
+	// A non-empty directory in place of the file makes the rename fail.
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(path, "blocker"), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Record(KindGost, []Item{{Key: "a", U: 5}}); err == nil {
+		t.Fatal("Record succeeded although the spool could not be written")
+	}
+	if e := mustHead(t, s); e.Items[0].U != 1 {
+		t.Fatalf("failed Record changed the spool: %+v", e)
+	}
+}
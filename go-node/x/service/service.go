@@ -392,20 +392,14 @@ func (s *defaultService) observeStats(ctx context.Context) {
 				inputBytes := st.Get(stats.KindInputBytes)
 				outputBytes := st.Get(stats.KindOutputBytes)
 
-				// Report traffic to panel (independent of observer)
+				// Queue traffic for the panel (independent of observer).
+				// Once it is in the durable spool the counters can be reset;
+				// delivery and retries are handled by the spool flusher.
 				if outputBytes > 0 || inputBytes > 0 {
-					reportItems := TrafficReportItem{
-						N: s.name,
-						U: int64(outputBytes),
-						D: int64(inputBytes),
-					}
-					success, err := sendTrafficReport(ctx, reportItems)
-					if err != nil {
-						fmt.Printf("发送流量报告失败: %v", err)
-					} else if success {
-						if xstats, ok := st.(*xstats.Stats); ok {
-							xstats.ResetTraffic(st.Get(stats.KindInputBytes)-inputBytes, st.Get(stats.KindOutputBytes)-outputBytes)
-						}
+					if err := recordTraffic(s.name, int64(outputBytes), int64(inputBytes)); err != nil {
+						fmt.Printf("记录流量报告失败: %v", err)
+					} else if xstats, ok := st.(*xstats.Stats); ok {
+						xstats.ResetTraffic(st.Get(stats.KindInputBytes)-inputBytes, st.Get(stats.KindOutputBytes)-outputBytes)
 					}
 				}
 
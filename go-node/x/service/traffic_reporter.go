@@ -6,20 +6,35 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"os"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-gost/core/observer/stats"
 	"github.com/go-gost/x/config"
 	"github.com/go-gost/x/internal/util/crypto"
+	"github.com/go-gost/x/internal/util/spool"
 	"github.com/go-gost/x/registry"
 )
 
 var httpReportURL string
 var configReportURL string
-var httpNodeSecret string                // Node secret for X-Node-Secret header
+var xrayReportURL string
+var ipReportURL string
+var httpNodeSecret string           // 上报签名用的节点密钥，不随请求发送
 var httpAESCrypto *crypto.AESCrypto // 新增：HTTP上报加密器
 
+// flowSpool 持久化的流量上报队列（带序号，面板据此去重）
+var flowSpool *spool.Spool
+var flowSpoolOnce sync.Once
+
+const (
+	flowSpoolPath     = "flow_spool.json"
+	flowFlushInterval = 5 * time.Second
+	flowMaxBackoff    = 60 * time.Second
+)
+
 // TrafficReportItem 流量报告项（压缩格式）
 type TrafficReportItem struct {
 	N string `json:"n"` // 服务名（name缩写）
@@ -27,13 +42,31 @@ type TrafficReportItem struct {
 	D int64  `json:"d"` // 下行流量（down缩写）
 }
 
+// xrayTrafficItem V 服务客户端流量项
+type xrayTrafficItem struct {
+	Email string `json:"email"`
+	U     int64  `json:"u"`
+	D     int64  `json:"d"`
+}
+
+// flowBatchReport 带序号的批量流量报告
+// 面板按 (节点, e, s) 去重，同一批次重发不会重复计费
+type flowBatchReport struct {
+	Seq     int64               `json:"s"`
+	Epoch   string              `json:"e"`
+	Items   []TrafficReportItem `json:"items,omitempty"`
+	Clients []xrayTrafficItem   `json:"clients,omitempty"`
+}
+
 func SetHTTPReportURL(addr string, secret string, useTLS bool) {
 	scheme := "http"
 	if useTLS {
 		scheme = "https"
 	}
-	httpReportURL = scheme + "://" + addr + "/flow/upload?secret=" + secret
-	configReportURL = scheme + "://" + addr + "/flow/config?secret=" + secret
+	httpReportURL = scheme + "://" + addr + "/flow/upload"
+	configReportURL = scheme + "://" + addr + "/flow/config"
+	xrayReportURL = scheme + "://" + addr + "/flow/su"
+	ipReportURL = scheme + "://" + addr + "/flow/ips"
 	httpNodeSecret = secret
 
 	// 创建 AES 加密器
@@ -45,80 +78,235 @@ func SetHTTPReportURL(addr string, secret string, useTLS bool) {
 	} else {
 		fmt.Printf("🔐 HTTP AES 加密器创建成功\n")
 	}
+
+	flowSpoolOnce.Do(func() {
+		flowSpool = openFlowSpool()
+		go runFlowSpool(context.Background())
+	})
 }
 
-// sendTrafficReport 发送流量报告到HTTP接口
-func sendTrafficReport(ctx context.Context, reportItems TrafficReportItem) (bool, error) {
-	jsonData, err := json.Marshal(reportItems)
-	if err != nil {
-		return false, fmt.Errorf("序列化报告数据失败: %v", err)
+// openFlowSpool 打开流量上报队列文件；文件损坏时另存后重建，
+// 无法落盘时退化为仅内存队列（仍可去重，但重启后丢失未上报流量）
+func openFlowSpool() *spool.Spool {
+	sp, err := spool.Open(flowSpoolPath)
+	if err == nil {
+		fmt.Printf("📦 流量上报队列已加载: 待上报 %d 批\n", sp.Len())
+		return sp
+	}
+	fmt.Printf("⚠️ 加载流量上报队列失败: %v\n", err)
+
+	if _, statErr := os.Stat(flowSpoolPath); statErr == nil {
+		broken := fmt.Sprintf("%s.broken-%d", flowSpoolPath, time.Now().Unix())
+		if os.Rename(flowSpoolPath, broken) == nil {
+			fmt.Printf("⚠️ 已将损坏的队列文件另存为 %s\n", broken)
+			if sp, err = spool.Open(flowSpoolPath); err == nil {
+				return sp
+			}
+		}
 	}
 
-	var requestBody []byte
+	sp, _ = spool.Open("")
+	fmt.Printf("⚠️ 流量上报队列退化为仅内存模式\n")
+	return sp
+}
 
-	// 如果有加密器，则加密数据
-	if httpAESCrypto != nil {
-		encryptedData, err := httpAESCrypto.Encrypt(jsonData)
-		if err != nil {
-			fmt.Printf("⚠️ 加密流量报告失败，发送原始数据: %v\n", err)
-			requestBody = jsonData
-		} else {
-			// 创建加密消息包装器
-			encryptedMessage := map[string]interface{}{
-				"encrypted": true,
-				"data":      encryptedData,
-				"timestamp": time.Now().Unix(),
-			}
-			requestBody, err = json.Marshal(encryptedMessage)
-			if err != nil {
-				fmt.Printf("⚠️ 序列化加密流量报告失败，发送原始数据: %v\n", err)
-				requestBody = jsonData
+// FlowSpool 返回流量上报队列，供 V 服务流量上报复用同一序号空间
+func FlowSpool() *spool.Spool {
+	return flowSpool
+}
+
+// recordTraffic 将服务流量写入上报队列并落盘，成功后调用方才可清零计数器；
+// 失败时队列不变，计数器保留到下一周期重试
+func recordTraffic(name string, up, down int64) error {
+	if flowSpool == nil {
+		return fmt.Errorf("流量上报队列未初始化")
+	}
+	return flowSpool.Record(spool.KindGost, []spool.Item{{Key: name, U: up, D: down}})
+}
+
+// runFlowSpool 按顺序上报队列中的流量批次，失败时指数退避重试
+func runFlowSpool(ctx context.Context) {
+	backoff := flowFlushInterval
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+
+		if err := flushFlowSpool(ctx); err != nil {
+			fmt.Printf("发送流量报告失败: %v（待上报 %d 批）\n", err, flowSpool.Len())
+			backoff *= 2
+			if backoff > flowMaxBackoff {
+				backoff = flowMaxBackoff
 			}
+			continue
 		}
-	} else {
-		requestBody = jsonData
+		backoff = flowFlushInterval
+	}
+}
+
+// flushFlowSpool 逐批发送直到队列清空；只有面板确认后才删除批次
+func flushFlowSpool(ctx context.Context) error {
+	for {
+		entry, ok, err := flowSpool.Head()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return nil
+		}
+		if err := sendFlowEntry(ctx, entry); err != nil {
+			return err
+		}
+		if err := flowSpool.Ack(entry.Seq); err != nil {
+			return err
+		}
+	}
+}
+
+// sendFlowEntry 发送一批流量报告到对应的HTTP接口
+func sendFlowEntry(ctx context.Context, entry spool.Entry) error {
+	report := flowBatchReport{
+		Seq:   entry.Seq,
+		Epoch: flowSpool.Epoch(),
+	}
+	reportURL := httpReportURL
+	switch entry.Kind {
+	case spool.KindGost:
+		for _, it := range entry.Items {
+			report.Items = append(report.Items, TrafficReportItem{N: it.Key, U: it.U, D: it.D})
+		}
+	case spool.KindXray:
+		reportURL = xrayReportURL
+		for _, it := range entry.Items {
+			report.Clients = append(report.Clients, xrayTrafficItem{Email: it.Key, U: it.U, D: it.D})
+		}
+	default:
+		// 未知类型无法上报，直接确认丢弃以免阻塞队列
+		fmt.Printf("⚠️ 丢弃未知类型的流量批次: kind=%s seq=%d\n", entry.Kind, entry.Seq)
+		return nil
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", httpReportURL, bytes.NewBuffer(requestBody))
+	jsonData, err := json.Marshal(report)
 	if err != nil {
-		return false, fmt.Errorf("创建HTTP请求失败: %v", err)
+		return fmt.Errorf("序列化报告数据失败: %v", err)
+	}
+
+	body := encryptReportBody(jsonData)
+	req, err := http.NewRequestWithContext(ctx, "POST", reportURL, bytes.NewBuffer(body))
+	if err != nil {
+		return fmt.Errorf("创建HTTP请求失败: %v", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("User-Agent", "Traffic-Reporter/1.0")
-	if httpNodeSecret != "" {
-		req.Header.Set("X-Node-Secret", httpNodeSecret)
-	}
+	signReportRequest(req, body)
 
 	client := &http.Client{
-		Timeout: 5 * time.Second,
+		Timeout: 10 * time.Second,
 	}
 
 	resp, err := client.Do(req)
 	if err != nil {
-		return false, fmt.Errorf("发送HTTP请求失败: %v", err)
+		return fmt.Errorf("发送HTTP请求失败: %v", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return false, fmt.Errorf("HTTP响应错误: %d %s", resp.StatusCode, resp.Status)
+		return fmt.Errorf("HTTP响应错误: %d %s", resp.StatusCode, resp.Status)
 	}
 
 	// 读取响应内容
 	var responseBytes bytes.Buffer
 	_, err = responseBytes.ReadFrom(resp.Body)
 	if err != nil {
-		return false, fmt.Errorf("读取响应内容失败: %v", err)
+		return fmt.Errorf("读取响应内容失败: %v", err)
 	}
 
 	responseText := strings.TrimSpace(responseBytes.String())
 
 	// 检查响应是否为"ok"
-	if responseText == "ok" {
-		return true, nil
-	} else {
-		return false, fmt.Errorf("服务器响应: %s (期望: ok)", responseText)
+	if responseText != "ok" {
+		return fmt.Errorf("服务器响应: %s (期望: ok)", responseText)
 	}
+	return nil
+}
+
+// xrayClientIPs V 服务客户端在线 IP 上报项
+type xrayClientIPs struct {
+	Email string       `json:"email"`
+	IPs   []xrayIPSeen `json:"ips"`
+}
+
+type xrayIPSeen struct {
+	IP string `json:"ip"`
+	T  int64  `json:"t"` // 最后出现时间（unix 秒）
+}
+
+// ReportOnlineIPs 上报各客户端最近出现的来源 IP，面板据此执行 IP 数限制
+// 尽力而为：失败不重试，下一周期会带上新的数据
+func ReportOnlineIPs(seen map[string]map[string]int64) {
+	if ipReportURL == "" || len(seen) == 0 {
+		return
+	}
+
+	report := struct {
+		Clients []xrayClientIPs `json:"clients"`
+	}{}
+	for email, ips := range seen {
+		item := xrayClientIPs{Email: email}
+		for ip, t := range ips {
+			item.IPs = append(item.IPs, xrayIPSeen{IP: ip, T: t})
+		}
+		report.Clients = append(report.Clients, item)
+	}
+
+	jsonData, err := json.Marshal(report)
+	if err != nil {
+		return
+	}
+
+	body := encryptReportBody(jsonData)
+	req, err := http.NewRequest("POST", ipReportURL, bytes.NewBuffer(body))
+	if err != nil {
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Traffic-Reporter/1.0")
+	signReportRequest(req, body)
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		fmt.Printf("⚠️ 在线 IP 上报失败: %v\n", err)
+		return
+	}
+	resp.Body.Close()
+}
+
+// encryptReportBody 如果有加密器，则加密上报数据；加密失败时发送原始数据
+func encryptReportBody(jsonData []byte) []byte {
+	if httpAESCrypto == nil {
+		return jsonData
+	}
+	encryptedData, err := httpAESCrypto.Encrypt(jsonData)
+	if err != nil {
+		fmt.Printf("⚠️ 加密流量报告失败，发送原始数据: %v\n", err)
+		return jsonData
+	}
+	// 创建加密消息包装器
+	encryptedMessage := map[string]interface{}{
+		"encrypted": true,
+		"data":      encryptedData,
+		"timestamp": time.Now().Unix(),
+	}
+	body, err := json.Marshal(encryptedMessage)
+	if err != nil {
+		fmt.Printf("⚠️ 序列化加密流量报告失败，发送原始数据: %v\n", err)
+		return jsonData
+	}
+	return body
 }
 
 // sendConfigReport 发送配置报告到HTTP接口
@@ -165,9 +353,7 @@ func sendConfigReport(ctx context.Context) (bool, error) {
 
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("User-Agent", "Config-Reporter/1.0")
-	if httpNodeSecret != "" {
-		req.Header.Set("X-Node-Secret", httpNodeSecret)
-	}
+	signReportRequest(req, requestBody)
 
 	client := &http.Client{
 		Timeout: 10 * time.Second, // 配置上报可以稍长一些
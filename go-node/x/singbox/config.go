@@ -0,0 +1,220 @@
+package singbox
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/go-gost/x/xray"
+)
+
+// IsProtocol reports whether inbounds of the given protocol run on sing-box
+// rather than Xray.
+func IsProtocol(protocol string) bool {
+	switch protocol {
+	case "hysteria2", "tuic":
+		return true
+	}
+	return false
+}
+
+// inboundSettings is the panel's settings JSON for sing-box inbounds. Field
+// names follow the panel's Xray-style camelCase; clients are merged in by
+// the panel the same way as for Xray inbounds.
+type inboundSettings struct {
+	Clients []struct {
+		Email    string `json:"email"`
+		ID       string `json:"id"`
+		Password string `json:"password"`
+	} `json:"clients"`
+
+	// Hysteria2
+	UpMbps                int    `json:"upMbps"`
+	DownMbps              int    `json:"downMbps"`
+	ObfsPassword          string `json:"obfsPassword"`
+	IgnoreClientBandwidth bool   `json:"ignoreClientBandwidth"`
+	Masquerade            string `json:"masquerade"`
+
+	// TUIC
+	CongestionControl string `json:"congestionControl"`
+	ZeroRTTHandshake  bool   `json:"zeroRttHandshake"`
+}
+
+// streamSettings is the subset of Xray stream settings used for TLS.
+type streamSettings struct {
+	Security    string `json:"security"`
+	TlsSettings struct {
+		ServerName   string   `json:"serverName"`
+		Alpn         []string `json:"alpn"`
+		Certificates []struct {
+			CertificateFile string   `json:"certificateFile"`
+			KeyFile         string   `json:"keyFile"`
+			Certificate     []string `json:"certificate"`
+			Key             []string `json:"key"`
+		} `json:"certificates"`
+	} `json:"tlsSettings"`
+}
+
+// buildInbound converts a panel inbound into a sing-box inbound object and
+// returns the client emails it serves.
+func buildInbound(ib xray.InboundConfig) (map[string]interface{}, []string, error) {
+	var settings inboundSettings
+	if ib.SettingsJSON != "" {
+		if err := json.Unmarshal([]byte(ib.SettingsJSON), &settings); err != nil {
+			return nil, nil, fmt.Errorf("invalid settings for %s: %v", ib.Tag, err)
+		}
+	}
+	var stream streamSettings
+	if ib.StreamSettingsJSON != "" {
+		if err := json.Unmarshal([]byte(ib.StreamSettingsJSON), &stream); err != nil {
+			return nil, nil, fmt.Errorf("invalid stream settings for %s: %v", ib.Tag, err)
+		}
+	}
+
+	tls, err := buildTLS(&stream)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %v", ib.Tag, err)
+	}
+
+	listen := ib.Listen
+	if listen == "" {
+		listen = "::"
+	}
+	obj := map[string]interface{}{
+		"type":        ib.Protocol,
+		"tag":         ib.Tag,
+		"listen":      listen,
+		"listen_port": ib.Port,
+		"tls":         tls,
+	}
+
+	var emails []string
+	users := []map[string]interface{}{}
+	for _, c := range settings.Clients {
+		emails = append(emails, c.Email)
+		switch ib.Protocol {
+		case "hysteria2":
+			users = append(users, map[string]interface{}{"name": c.Email, "password": c.Password})
+		case "tuic":
+			// The panel issues one UUID per client and uses it as the password too
+			password := c.Password
+			if password == "" {
+				password = c.ID
+			}
+			users = append(users, map[string]interface{}{"name": c.Email, "uuid": c.ID, "password": password})
+		}
+	}
+	obj["users"] = users
+
+	switch ib.Protocol {
+	case "hysteria2":
+		if settings.UpMbps > 0 {
+			obj["up_mbps"] = settings.UpMbps
+		}
+		if settings.DownMbps > 0 {
+			obj["down_mbps"] = settings.DownMbps
+		}
+		if settings.ObfsPassword != "" {
+			obj["obfs"] = map[string]interface{}{"type": "salamander", "password": settings.ObfsPassword}
+		}
+		if settings.IgnoreClientBandwidth {
+			obj["ignore_client_bandwidth"] = true
+		}
+		if settings.Masquerade != "" {
+			obj["masquerade"] = settings.Masquerade
+		}
+	case "tuic":
+		cc := settings.CongestionControl
+		if cc == "" {
+			cc = "bbr"
+		}
+		obj["congestion_control"] = cc
+		if settings.ZeroRTTHandshake {
+			obj["zero_rtt_handshake"] = true
+		}
+	default:
+		return nil, nil, fmt.Errorf("unsupported protocol: %s", ib.Protocol)
+	}
+	return obj, emails, nil
+}
+
+// buildTLS maps Xray TLS settings onto sing-box's inbound TLS. Both
+// protocols run over QUIC, so TLS is mandatory and ALPN must offer h3.
+func buildTLS(stream *streamSettings) (map[string]interface{}, error) {
+	if stream.Security != "tls" {
+		return nil, fmt.Errorf("TLS is required")
+	}
+	ts := stream.TlsSettings
+	if len(ts.Certificates) == 0 {
+		return nil, fmt.Errorf("a TLS certificate is required")
+	}
+
+	alpn := []string{"h3"}
+	for _, a := range ts.Alpn {
+		if a == "h3" {
+			alpn = ts.Alpn
+			break
+		}
+	}
+	tls := map[string]interface{}{
+		"enabled": true,
+		"alpn":    alpn,
+	}
+	if ts.ServerName != "" {
+		tls["server_name"] = ts.ServerName
+	}
+
+	cert := ts.Certificates[0]
+	switch {
+	case cert.CertificateFile != "":
+		tls["certificate_path"] = cert.CertificateFile
+		tls["key_path"] = cert.KeyFile
+	case len(cert.Certificate) > 0:
+		tls["certificate"] = strings.Join(cert.Certificate, "\n")
+		tls["key"] = strings.Join(cert.Key, "\n")
+	default:
+		return nil, fmt.Errorf("a TLS certificate is required")
+	}
+	return tls, nil
+}
+
+// buildConfig assembles the full sing-box config. With statsAddr set, the
+// V2Ray-compatible stats API is enabled so per-user traffic can be queried
+// like Xray's.
+func buildConfig(inbounds []xray.InboundConfig, statsAddr string) (map[string]interface{}, error) {
+	var objs []map[string]interface{}
+	var tags, users []string
+	for _, ib := range inbounds {
+		obj, emails, err := buildInbound(ib)
+		if err != nil {
+			return nil, err
+		}
+		objs = append(objs, obj)
+		tags = append(tags, ib.Tag)
+		users = append(users, emails...)
+	}
+
+	config := map[string]interface{}{
+		"log": map[string]interface{}{
+			"level":     "warn",
+			"timestamp": true,
+		},
+		"inbounds": objs,
+		"outbounds": []map[string]interface{}{
+			{"type": "direct", "tag": "direct"},
+		},
+	}
+	if statsAddr != "" {
+		config["experimental"] = map[string]interface{}{
+			"v2ray_api": map[string]interface{}{
+				"listen": statsAddr,
+				"stats": map[string]interface{}{
+					"enabled":  true,
+					"inbounds": tags,
+					"users":    users,
+				},
+			},
+		}
+	}
+	return config, nil
+}
@@ -0,0 +1,637 @@
+package singbox
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/go-gost/x/xray"
+)
+
+// Manager runs sing-box for the protocols Xray doesn't serve (Hysteria2,
+// TUIC). The panel's inbounds are kept in a state file next to the sing-box
+// config; every change regenerates the config and reloads sing-box.
+//
+// sing-box has no API for adding users or inbounds at runtime, so "hot"
+// changes are a validated config rewrite followed by SIGHUP, which reloads
+// in place without restarting the process.
+//
+// Inbounds are only served by a build with the V2Ray API: without it the
+// traffic of Hysteria2/TUIC users could not be metered.
+type Manager struct {
+	binaryPath string
+	configPath string
+	statePath  string
+	apiAddr    string
+
+	cmd     *exec.Cmd
+	running bool
+	version string
+	tags    string // build tags reported by `sing-box version`
+
+	mu        sync.Mutex // guards the process
+	stateMu   sync.Mutex // guards inbounds and the files on disk
+	versionMu sync.Mutex // guards version and tags
+	pathMu    sync.Mutex // guards binaryPath
+	inbounds  []xray.InboundConfig
+}
+
+// errNoStats is returned when the installed build can't meter traffic.
+var errNoStats = errors.New("sing-box build lacks the V2Ray API (with_v2ray_api), traffic could not be metered; install a build with it first")
+
+// NewManager creates a sing-box manager. configPath is the generated
+// sing-box config; apiAddr is where its V2Ray stats API listens.
+func NewManager(binaryPath, configPath, apiAddr string) *Manager {
+	if binaryPath == "" {
+		binaryPath = "sing-box"
+	}
+	if configPath == "" {
+		configPath = "singbox_config.json"
+	}
+	if apiAddr == "" {
+		apiAddr = "127.0.0.1:10086"
+	}
+	m := &Manager{
+		binaryPath: binaryPath,
+		configPath: configPath,
+		statePath:  filepath.Join(filepath.Dir(configPath), "singbox_inbounds.json"),
+		apiAddr:    apiAddr,
+	}
+	m.loadState()
+	return m
+}
+
+// GetAPIAddr returns the address of the V2Ray-compatible stats API.
+func (m *Manager) GetAPIAddr() string {
+	return m.apiAddr
+}
+
+// IsRunning returns whether sing-box is currently running
+func (m *Manager) IsRunning() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.running
+}
+
+// GetVersion returns the sing-box version number (e.g. "1.10.1")
+func (m *Manager) GetVersion() string {
+	version, _ := m.versionInfo()
+	return version
+}
+
+// StatsEnabled reports whether the installed binary was built with the V2Ray
+// API. Official release builds are not, so they can't serve inbounds.
+func (m *Manager) StatsEnabled() bool {
+	_, tags := m.versionInfo()
+	return hasStats(tags)
+}
+
+// binary returns the sing-box executable in use.
+func (m *Manager) binary() string {
+	m.pathMu.Lock()
+	defer m.pathMu.Unlock()
+	return m.binaryPath
+}
+
+// setBinary switches to another executable and forgets the version
+// probed from the previous one.
+func (m *Manager) setBinary(path string) {
+	m.pathMu.Lock()
+	changed := m.binaryPath != path
+	m.binaryPath = path
+	m.pathMu.Unlock()
+	if changed {
+		m.versionMu.Lock()
+		m.version, m.tags = "", ""
+		m.versionMu.Unlock()
+	}
+}
+
+func (m *Manager) versionInfo() (string, string) {
+	m.versionMu.Lock()
+	defer m.versionMu.Unlock()
+	if m.version == "" {
+		version, tags, err := probeVersion(m.binary())
+		if err != nil {
+			return "unknown", ""
+		}
+		m.version, m.tags = version, tags
+	}
+	return m.version, m.tags
+}
+
+// probeVersion runs `sing-box version` and returns the version and build tags.
+func probeVersion(binaryPath string) (string, string, error) {
+	output, err := exec.Command(binaryPath, "version").Output()
+	if err != nil {
+		return "", "", err
+	}
+	// "sing-box version 1.10.1\n\nEnvironment: ...\nTags: with_gvisor,...\n"
+	version, tags := "unknown", ""
+	for _, line := range strings.Split(string(output), "\n") {
+		if strings.HasPrefix(line, "sing-box version ") {
+			version = strings.TrimSpace(strings.TrimPrefix(line, "sing-box version "))
+		}
+		if strings.HasPrefix(line, "Tags:") {
+			tags = strings.TrimSpace(strings.TrimPrefix(line, "Tags:"))
+		}
+	}
+	return version, tags, nil
+}
+
+func hasStats(tags string) bool {
+	for _, tag := range strings.Split(tags, ",") {
+		if strings.TrimSpace(tag) == "with_v2ray_api" {
+			return true
+		}
+	}
+	return false
+}
+
+// Owns reports whether an inbound tag is served by sing-box.
+func (m *Manager) Owns(tag string) bool {
+	m.stateMu.Lock()
+	defer m.stateMu.Unlock()
+	return m.indexOf(tag) >= 0
+}
+
+// Tags returns the tags of all sing-box inbounds.
+func (m *Manager) Tags() []string {
+	m.stateMu.Lock()
+	defer m.stateMu.Unlock()
+	tags := make([]string, 0, len(m.inbounds))
+	for _, ib := range m.inbounds {
+		tags = append(tags, ib.Tag)
+	}
+	return tags
+}
+
+// Start starts sing-box with the current config.
+func (m *Manager) Start() error {
+	if err := m.ensureBinary(); err != nil {
+		return err
+	}
+	if !m.StatsEnabled() {
+		return errNoStats
+	}
+	m.stateMu.Lock()
+	err := m.writeConfig()
+	m.stateMu.Unlock()
+	if err != nil {
+		return err
+	}
+	return m.startProcess()
+}
+
+func (m *Manager) startProcess() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.running && m.cmd != nil && m.cmd.Process != nil {
+		return fmt.Errorf("sing-box is already running")
+	}
+
+	absConfig, err := filepath.Abs(m.configPath)
+	if err != nil {
+		absConfig = m.configPath
+	}
+	cmd := exec.Command(m.binary(), "run", "-c", absConfig)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start sing-box: %v", err)
+	}
+	m.cmd = cmd
+	m.running = true
+	fmt.Printf("✅ sing-box started with PID %d\n", cmd.Process.Pid)
+
+	go func() {
+		if err := cmd.Wait(); err != nil {
+			fmt.Printf("⚠️ sing-box process exited: %v\n", err)
+		}
+		m.mu.Lock()
+		if m.cmd == cmd {
+			m.running = false
+		}
+		m.mu.Unlock()
+	}()
+	return nil
+}
+
+// Stop stops sing-box
+func (m *Manager) Stop() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.running || m.cmd == nil || m.cmd.Process == nil {
+		m.running = false
+		return nil
+	}
+	proc := m.cmd.Process
+	if err := proc.Signal(syscall.SIGTERM); err != nil {
+		proc.Kill()
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if proc.Signal(syscall.Signal(0)) != nil {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	proc.Kill()
+
+	m.running = false
+	fmt.Printf("🛑 sing-box stopped\n")
+	return nil
+}
+
+// Restart restarts sing-box
+func (m *Manager) Restart() error {
+	if err := m.Stop(); err != nil {
+		fmt.Printf("⚠️ Error stopping sing-box: %v\n", err)
+	}
+	time.Sleep(500 * time.Millisecond)
+	return m.Start()
+}
+
+// ApplyConfig replaces all sing-box inbounds. sing-box is stopped when the
+// list is empty.
+func (m *Manager) ApplyConfig(inbounds []xray.InboundConfig) error {
+	return m.mutate(func() error {
+		m.inbounds = append([]xray.InboundConfig(nil), inbounds...)
+		return nil
+	})
+}
+
+// AddInbound adds or replaces one inbound.
+func (m *Manager) AddInbound(cfg xray.InboundConfig) error {
+	return m.mutate(func() error {
+		if i := m.indexOf(cfg.Tag); i >= 0 {
+			m.inbounds[i] = cfg
+		} else {
+			m.inbounds = append(m.inbounds, cfg)
+		}
+		return nil
+	})
+}
+
+// RemoveInbound removes one inbound.
+func (m *Manager) RemoveInbound(tag string) error {
+	return m.mutate(func() error {
+		i := m.indexOf(tag)
+		if i < 0 {
+			return fmt.Errorf("inbound %s not found", tag)
+		}
+		m.inbounds = append(m.inbounds[:i], m.inbounds[i+1:]...)
+		return nil
+	})
+}
+
+// AddUser adds a client to an inbound. For TUIC, uuidOrPassword is the
+// client's UUID and doubles as its password.
+func (m *Manager) AddUser(tag, email, uuidOrPassword, protocol string) error {
+	return m.AddUsers(tag, []xray.User{{Email: email, UuidOrPassword: uuidOrPassword, Protocol: protocol}})
+}
+
+// AddUsers adds clients to an inbound with a single reload.
+func (m *Manager) AddUsers(tag string, users []xray.User) error {
+	return m.mutateClients(tag, func(clients []interface{}) []interface{} {
+		for _, u := range users {
+			client := map[string]interface{}{"email": u.Email, "password": u.UuidOrPassword}
+			if u.Protocol == "tuic" {
+				client["id"] = u.UuidOrPassword
+			}
+			clients = append(removeClient(clients, u.Email), client)
+		}
+		return clients
+	})
+}
+
+// RemoveUser removes a client from an inbound.
+func (m *Manager) RemoveUser(tag, email string) error {
+	return m.RemoveUsers(tag, []string{email})
+}
+
+// RemoveUsers removes clients from an inbound with a single reload.
+func (m *Manager) RemoveUsers(tag string, emails []string) error {
+	return m.mutateClients(tag, func(clients []interface{}) []interface{} {
+		for _, email := range emails {
+			clients = removeClient(clients, email)
+		}
+		return clients
+	})
+}
+
+func removeClient(clients []interface{}, email string) []interface{} {
+	filtered := make([]interface{}, 0, len(clients))
+	for _, c := range clients {
+		if cm, ok := c.(map[string]interface{}); ok && cm["email"] == email {
+			continue
+		}
+		filtered = append(filtered, c)
+	}
+	return filtered
+}
+
+func (m *Manager) mutateClients(tag string, fn func([]interface{}) []interface{}) error {
+	return m.mutate(func() error {
+		i := m.indexOf(tag)
+		if i < 0 {
+			return fmt.Errorf("inbound %s not found", tag)
+		}
+		var settings map[string]interface{}
+		if err := json.Unmarshal([]byte(m.inbounds[i].SettingsJSON), &settings); err != nil || settings == nil {
+			settings = map[string]interface{}{}
+		}
+		clients, _ := settings["clients"].([]interface{})
+		settings["clients"] = fn(clients)
+		data, _ := json.Marshal(settings)
+		m.inbounds[i].SettingsJSON = string(data)
+		return nil
+	})
+}
+
+// mutate applies a change to the inbound list, validates the resulting
+// config and activates it. On failure the previous state is kept.
+func (m *Manager) mutate(fn func() error) error {
+	m.stateMu.Lock()
+	defer m.stateMu.Unlock()
+
+	old := append([]xray.InboundConfig(nil), m.inbounds...)
+	if err := fn(); err != nil {
+		m.inbounds = old
+		return err
+	}
+	if err := m.activate(); err != nil {
+		m.inbounds = old
+		if len(old) > 0 {
+			m.writeConfig()
+		}
+		return err
+	}
+	m.saveState()
+	return nil
+}
+
+// activate writes the config and makes the running process pick it up.
+// Must be called with stateMu held.
+func (m *Manager) activate() error {
+	if len(m.inbounds) == 0 {
+		return m.Stop()
+	}
+	if err := m.ensureBinary(); err != nil {
+		return err
+	}
+	if !m.StatsEnabled() {
+		return errNoStats
+	}
+	if err := m.writeConfig(); err != nil {
+		return err
+	}
+	if out, err := exec.Command(m.binary(), "check", "-c", m.configPath).CombinedOutput(); err != nil {
+		return fmt.Errorf("invalid sing-box config: %s", strings.TrimSpace(string(out)))
+	}
+
+	m.mu.Lock()
+	running := m.running && m.cmd != nil && m.cmd.Process != nil
+	var proc *os.Process
+	if running {
+		proc = m.cmd.Process
+	}
+	m.mu.Unlock()
+
+	if !running {
+		if err := m.startProcess(); err != nil {
+			return err
+		}
+	} else if err := proc.Signal(syscall.SIGHUP); err != nil {
+		return fmt.Errorf("failed to reload sing-box: %v", err)
+	}
+
+	// Catch crashes caused by the new config (e.g. port already in use)
+	time.Sleep(time.Second)
+	if !m.IsRunning() {
+		return fmt.Errorf("sing-box exited after applying config")
+	}
+	return nil
+}
+
+// TestConfig checks a config holding only the given inbounds with
+// `sing-box check`. Without a binary only the config build is checked.
+func (m *Manager) TestConfig(inbounds []xray.InboundConfig) error {
+	config, err := buildConfig(inbounds, m.apiAddr)
+	if err != nil {
+		return err
+	}
+	if m.ensureBinary() != nil {
+		return nil
+	}
+	if !m.StatsEnabled() {
+		return errNoStats
+	}
+	data, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal config: %v", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(m.configPath), "singbox-test-*.json")
+	if err != nil {
+		return fmt.Errorf("failed to create temp config: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write temp config: %v", err)
+	}
+	tmp.Close()
+
+	if out, err := exec.Command(m.binary(), "check", "-c", tmp.Name()).CombinedOutput(); err != nil {
+		return fmt.Errorf("invalid sing-box config: %s", strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// indexOf must be called with stateMu held.
+func (m *Manager) indexOf(tag string) int {
+	for i, ib := range m.inbounds {
+		if ib.Tag == tag {
+			return i
+		}
+	}
+	return -1
+}
+
+// writeConfig must be called with stateMu held.
+func (m *Manager) writeConfig() error {
+	config, err := buildConfig(m.inbounds, m.apiAddr)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal config: %v", err)
+	}
+	return os.WriteFile(m.configPath, data, 0644)
+}
+
+func (m *Manager) loadState() {
+	data, err := os.ReadFile(m.statePath)
+	if err != nil {
+		return
+	}
+	if err := json.Unmarshal(data, &m.inbounds); err != nil {
+		fmt.Printf("⚠️ Failed to parse sing-box state: %v\n", err)
+	}
+}
+
+func (m *Manager) saveState() {
+	if len(m.inbounds) == 0 {
+		os.Remove(m.statePath)
+		return
+	}
+	data, _ := json.MarshalIndent(m.inbounds, "", "  ")
+	if err := os.WriteFile(m.statePath, data, 0644); err != nil {
+		fmt.Printf("⚠️ Failed to save sing-box state: %v\n", err)
+	}
+}
+
+// ensureBinary checks that sing-box is installed. The copy Install put in
+// the config directory wins over one on PATH: a distribution package is
+// usually built without the V2Ray API.
+func (m *Manager) ensureBinary() error {
+	persistPath := m.persistPath()
+	if _, err := os.Stat(persistPath); err == nil {
+		m.setBinary(persistPath)
+		return nil
+	}
+	if _, err := exec.LookPath(m.binary()); err == nil {
+		return nil
+	}
+	return fmt.Errorf("sing-box is not installed, please install a version in node management first")
+}
+
+func (m *Manager) persistPath() string {
+	dir, _ := filepath.Abs(filepath.Dir(m.configPath))
+	return filepath.Join(dir, filepath.Base(m.binary()))
+}
+
+// Install downloads a sing-box release tarball into the config directory
+// and restarts sing-box if it was running. The tarball must match checksum
+// (hex SHA-256) and contain a build with the V2Ray API; official release
+// builds lack it, so url points at such a build.
+func (m *Manager) Install(url, checksum string) error {
+	want, err := hex.DecodeString(strings.TrimSpace(checksum))
+	if err != nil || len(want) != sha256.Size {
+		return fmt.Errorf("invalid sha256 checksum")
+	}
+	fmt.Printf("⬇️ Downloading sing-box from %s\n", url)
+
+	httpClient := &http.Client{Timeout: 5 * time.Minute}
+	resp, err := httpClient.Get(url)
+	if err != nil {
+		return fmt.Errorf("failed to download: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("download failed with status %d", resp.StatusCode)
+	}
+
+	// Verify the whole archive before anything is extracted from it
+	archive, err := os.CreateTemp(filepath.Dir(m.persistPath()), "sb-dl-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %v", err)
+	}
+	defer os.Remove(archive.Name())
+	defer archive.Close()
+	hasher := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(archive, hasher), resp.Body); err != nil {
+		return fmt.Errorf("failed to download: %v", err)
+	}
+	if got := hasher.Sum(nil); !bytes.Equal(got, want) {
+		return fmt.Errorf("sha256 mismatch: got %x, want %x", got, want)
+	}
+	if _, err := archive.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to read download: %v", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(m.persistPath()), "sb-bin-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := extractBinary(archive, tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	tmp.Close()
+	if err := os.Chmod(tmp.Name(), 0755); err != nil {
+		return fmt.Errorf("failed to chmod binary: %v", err)
+	}
+	version, tags, err := probeVersion(tmp.Name())
+	if err != nil {
+		return fmt.Errorf("downloaded binary does not run: %v", err)
+	}
+	if !hasStats(tags) {
+		return errNoStats
+	}
+
+	wasRunning := m.IsRunning()
+	if wasRunning {
+		m.Stop()
+	}
+	target := m.persistPath()
+	if err := os.Rename(tmp.Name(), target); err != nil {
+		return fmt.Errorf("failed to install binary: %v", err)
+	}
+	m.setBinary(target)
+	m.versionMu.Lock()
+	m.version, m.tags = "", ""
+	m.versionMu.Unlock()
+
+	if wasRunning {
+		return m.Start()
+	}
+	fmt.Printf("✅ sing-box %s installed\n", version)
+	return nil
+}
+
+// extractBinary copies the sing-box executable out of a release tarball.
+func extractBinary(r io.Reader, out io.Writer) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return fmt.Errorf("invalid archive: %v", err)
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return fmt.Errorf("binary not found in archive")
+		}
+		if err != nil {
+			return fmt.Errorf("invalid archive: %v", err)
+		}
+		if hdr.Typeflag == tar.TypeReg && filepath.Base(hdr.Name) == "sing-box" {
+			if _, err := io.Copy(out, tr); err != nil {
+				return fmt.Errorf("failed to extract binary: %v", err)
+			}
+			return nil
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package singbox
+
+import (
+	"github.com/go-gost/x/xray"
+)
+
+// sing-box's V2Ray API keeps V2Ray's proto package, which Xray's CLI
+// (xray.app.stats.command) can't talk to, so stats are queried directly.
+const queryStatsMethod = "/v2ray.core.app.stats.command.StatsService/QueryStats"
+
+// StatsClient queries per-user traffic from sing-box's V2Ray stats API.
+type StatsClient struct {
+	addr string
+}
+
+// NewStatsClient creates a stats client for the API at addr.
+func NewStatsClient(addr string) *StatsClient {
+	return &StatsClient{addr: addr}
+}
+
+// QueryTraffic returns per-user traffic. When reset=true, counters are reset
+// after reading (incremental stats).
+func (c *StatsClient) QueryTraffic(reset bool) ([]xray.TrafficStat, error) {
+	stats, err := xray.QueryStats(c.addr, queryStatsMethod, "user>>>", reset)
+	if err != nil {
+		return nil, err
+	}
+	return xray.AggregateUserStats(stats), nil
+}
@@ -0,0 +1,435 @@
+package socket
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 节点间带宽测试：面板从目标节点的端口范围中选一个空闲端口，让目标节点在该端口上
+// 启动接收端（TCP 与 UDP 同端口），再让源节点连过去依次测量往返延迟、TCP 吞吐和 UDP 丢包。
+// 接收端只接受带本次测试令牌的连接，超时后自动关闭。TCP 测试的数据量有上限，
+// 高带宽链路上提前发完即结束，不会占满链路整段时长。
+const (
+	bwHeaderMagic     = "FLUXBW"
+	bwUDPPacketSize   = 1200
+	bwDefaultTimeout  = 30 // 秒
+	bwMaxTimeout      = 120
+	bwDefaultDuration = 3 // 秒
+	bwMaxDuration     = 10
+	bwDefaultUDPRate  = 10 // Mbps
+	bwMaxUDPRate      = 1000
+	bwDefaultMaxBytes = 64 << 20 // TCP 测试单次最多发送的字节数
+	bwMaxBytes        = 1 << 30
+	bwDefaultPings    = 5
+	bwMaxServers      = 4
+)
+
+// BandwidthServerRequest 启动接收端请求
+type BandwidthServerRequest struct {
+	Token   string `json:"token"`
+	Port    int    `json:"port"`    // 面板选定的监听端口
+	Timeout int    `json:"timeout"` // 接收端存活时间（秒）
+}
+
+// BandwidthTestRequest 发起测试请求
+type BandwidthTestRequest struct {
+	Host     string `json:"host"`
+	Port     int    `json:"port"`
+	Token    string `json:"token"`
+	Protocol string `json:"protocol"` // tcp / udp，留空两者都测
+	Duration int    `json:"duration"` // 每项测试时长（秒）
+	UDPRate  int    `json:"udpRate"`  // UDP 发送速率（Mbps）
+	MaxBytes int64  `json:"maxBytes"` // TCP 测试最多发送的字节数
+	Pings    int    `json:"pings"`
+}
+
+// BandwidthTestResult 测试结果
+type BandwidthTestResult struct {
+	Host        string  `json:"host"`
+	Port        int     `json:"port"`
+	Latency     float64 `json:"latency"`    // 平均往返延迟（毫秒）
+	MinLatency  float64 `json:"minLatency"` // 最小往返延迟（毫秒）
+	Jitter      float64 `json:"jitter"`     // 相邻往返延迟差的平均值（毫秒）
+	TcpMbps     float64 `json:"tcpMbps"`
+	UdpMbps     float64 `json:"udpMbps"`
+	UdpSent     int64   `json:"udpSent"`
+	UdpReceived int64   `json:"udpReceived"`
+	Loss        float64 `json:"loss"` // UDP 丢包率（百分比）
+}
+
+var activeBandwidthServers atomic.Int32
+
+// handleBandwidthServer 启动一次性接收端，返回监听端口
+func (w *WebSocketReporter) handleBandwidthServer(data interface{}) (map[string]interface{}, error) {
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("序列化数据失败: %v", err)
+	}
+	var req BandwidthServerRequest
+	if err := json.Unmarshal(jsonData, &req); err != nil {
+		return nil, fmt.Errorf("解析带宽测试请求失败: %v", err)
+	}
+	if len(req.Token) < 16 {
+		return nil, fmt.Errorf("测试令牌无效")
+	}
+	if req.Port <= 0 || req.Port > 65535 {
+		return nil, fmt.Errorf("无效的端口号，范围应为1-65535")
+	}
+	if req.Timeout <= 0 {
+		req.Timeout = bwDefaultTimeout
+	}
+	if req.Timeout > bwMaxTimeout {
+		req.Timeout = bwMaxTimeout
+	}
+
+	if activeBandwidthServers.Add(1) > bwMaxServers {
+		activeBandwidthServers.Add(-1)
+		return nil, fmt.Errorf("进行中的带宽测试过多")
+	}
+	srv, err := startBandwidthServer(req.Token, req.Port, time.Duration(req.Timeout)*time.Second)
+	if err != nil {
+		activeBandwidthServers.Add(-1)
+		return nil, err
+	}
+	fmt.Printf("📶 带宽测试接收端已启动，端口 %d，%d 秒后关闭\n", srv.port, req.Timeout)
+	return map[string]interface{}{"port": srv.port}, nil
+}
+
+type bandwidthServer struct {
+	token    string
+	tag      []byte
+	port     int
+	deadline time.Time
+	tcp      net.Listener
+	udp      net.PacketConn
+
+	udpPackets atomic.Int64
+	udpBytes   atomic.Int64
+	closeOnce  sync.Once
+}
+
+// startBandwidthServer 在指定端口上同时监听 TCP 和 UDP
+func startBandwidthServer(token string, port int, lifetime time.Duration) (*bandwidthServer, error) {
+	addr := ":" + strconv.Itoa(port)
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("监听端口 %d 失败: %v", port, err)
+	}
+	pc, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		ln.Close()
+		return nil, fmt.Errorf("监听端口 %d 失败: %v", port, err)
+	}
+	s := &bandwidthServer{
+		token:    token,
+		tag:      bandwidthTag(token),
+		port:     port,
+		deadline: time.Now().Add(lifetime),
+		tcp:      ln,
+		udp:      pc,
+	}
+
+	time.AfterFunc(lifetime, s.close)
+	go s.serveTCP()
+	go s.serveUDP()
+	return s, nil
+}
+
+func (s *bandwidthServer) close() {
+	s.closeOnce.Do(func() {
+		s.tcp.Close()
+		s.udp.Close()
+		activeBandwidthServers.Add(-1)
+		fmt.Printf("📶 带宽测试接收端已关闭，端口 %d\n", s.port)
+	})
+}
+
+func (s *bandwidthServer) serveTCP() {
+	for {
+		conn, err := s.tcp.Accept()
+		if err != nil {
+			return
+		}
+		go s.handleConn(conn)
+	}
+}
+
+// handleConn 处理一条测试连接，首行为 "FLUXBW <token> <mode>"
+func (s *bandwidthServer) handleConn(conn net.Conn) {
+	defer conn.Close()
+	conn.SetDeadline(s.deadline)
+
+	r := bufio.NewReader(conn)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return
+	}
+	fields := strings.Fields(line)
+	if len(fields) != 3 || fields[0] != bwHeaderMagic ||
+		subtle.ConstantTimeCompare([]byte(fields[1]), []byte(s.token)) != 1 {
+		return
+	}
+	conn.SetReadDeadline(s.deadline)
+
+	switch fields[2] {
+	case "ping":
+		// 原样回显 8 字节探测包
+		buf := make([]byte, 8)
+		for {
+			if _, err := io.ReadFull(r, buf); err != nil {
+				return
+			}
+			if _, err := conn.Write(buf); err != nil {
+				return
+			}
+		}
+	case "tcp":
+		// 接收到对端关闭写方向或达到上限为止，回复收到的字节数
+		n, _ := io.Copy(io.Discard, io.LimitReader(r, bwMaxBytes))
+		fmt.Fprintf(conn, "%d\n", n)
+	case "udp-result":
+		fmt.Fprintf(conn, "%d %d\n", s.udpPackets.Load(), s.udpBytes.Load())
+	}
+}
+
+func (s *bandwidthServer) serveUDP() {
+	buf := make([]byte, 65535)
+	for {
+		n, _, err := s.udp.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		if n >= 16 && subtle.ConstantTimeCompare(buf[:8], s.tag) == 1 {
+			s.udpPackets.Add(1)
+			s.udpBytes.Add(int64(n))
+		}
+	}
+}
+
+// bandwidthTag UDP 包头中用于识别本次测试的 8 字节标记
+func bandwidthTag(token string) []byte {
+	sum := sha256.Sum256([]byte(token))
+	return sum[:8]
+}
+
+// handleBandwidthTest 作为发送端对目标节点执行测试
+func (w *WebSocketReporter) handleBandwidthTest(data interface{}) (BandwidthTestResult, error) {
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		return BandwidthTestResult{}, fmt.Errorf("序列化数据失败: %v", err)
+	}
+	var req BandwidthTestRequest
+	if err := json.Unmarshal(jsonData, &req); err != nil {
+		return BandwidthTestResult{}, fmt.Errorf("解析带宽测试请求失败: %v", err)
+	}
+	if net.ParseIP(req.Host) == nil && !isValidHostname(req.Host) {
+		return BandwidthTestResult{}, fmt.Errorf("无效的IP地址或主机名")
+	}
+	if req.Port <= 0 || req.Port > 65535 {
+		return BandwidthTestResult{}, fmt.Errorf("无效的端口号，范围应为1-65535")
+	}
+	if req.Duration <= 0 {
+		req.Duration = bwDefaultDuration
+	}
+	if req.Duration > bwMaxDuration {
+		req.Duration = bwMaxDuration
+	}
+	if req.UDPRate <= 0 {
+		req.UDPRate = bwDefaultUDPRate
+	}
+	if req.UDPRate > bwMaxUDPRate {
+		req.UDPRate = bwMaxUDPRate
+	}
+	if req.MaxBytes <= 0 {
+		req.MaxBytes = bwDefaultMaxBytes
+	}
+	if req.MaxBytes > bwMaxBytes {
+		req.MaxBytes = bwMaxBytes
+	}
+	if req.Pings <= 0 {
+		req.Pings = bwDefaultPings
+	}
+
+	target := net.JoinHostPort(req.Host, strconv.Itoa(req.Port))
+	duration := time.Duration(req.Duration) * time.Second
+	result := BandwidthTestResult{Host: req.Host, Port: req.Port}
+	fmt.Printf("📶 开始带宽测试: %s，时长 %v\n", target, duration)
+
+	if err := bandwidthPing(target, req.Token, req.Pings, &result); err != nil {
+		return result, fmt.Errorf("延迟测试失败: %v", err)
+	}
+	if req.Protocol == "" || req.Protocol == "tcp" {
+		mbps, err := bandwidthTCP(target, req.Token, duration, req.MaxBytes)
+		if err != nil {
+			return result, fmt.Errorf("TCP 吞吐测试失败: %v", err)
+		}
+		result.TcpMbps = mbps
+	}
+	if req.Protocol == "" || req.Protocol == "udp" {
+		if err := bandwidthUDP(target, req.Token, duration, req.UDPRate, &result); err != nil {
+			return result, fmt.Errorf("UDP 测试失败: %v", err)
+		}
+	}
+
+	fmt.Printf("📶 带宽测试完成: %s，延迟 %.2fms，TCP %.2f Mbps，UDP %.2f Mbps，丢包 %.2f%%\n",
+		target, result.Latency, result.TcpMbps, result.UdpMbps, result.Loss)
+	return result, nil
+}
+
+func dialBandwidth(target, token, mode string) (net.Conn, error) {
+	conn, err := net.DialTimeout("tcp", target, 5*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := fmt.Fprintf(conn, "%s %s %s\n", bwHeaderMagic, token, mode); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// bandwidthPing 在一条连接上做多次 8 字节往返
+func bandwidthPing(target, token string, count int, result *BandwidthTestResult) error {
+	conn, err := dialBandwidth(target, token, "ping")
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var rtts []float64
+	buf := make([]byte, 8)
+	for i := 0; i < count; i++ {
+		binary.BigEndian.PutUint64(buf, uint64(i))
+		conn.SetDeadline(time.Now().Add(3 * time.Second))
+		start := time.Now()
+		if _, err := conn.Write(buf); err != nil {
+			return err
+		}
+		if _, err := io.ReadFull(conn, buf); err != nil {
+			return err
+		}
+		rtts = append(rtts, float64(time.Since(start).Microseconds())/1000)
+	}
+
+	result.MinLatency = math.MaxFloat64
+	var sum, diffs float64
+	for i, rtt := range rtts {
+		sum += rtt
+		result.MinLatency = math.Min(result.MinLatency, rtt)
+		if i > 0 {
+			diffs += math.Abs(rtt - rtts[i-1])
+		}
+	}
+	result.Latency = sum / float64(len(rtts))
+	if len(rtts) > 1 {
+		result.Jitter = diffs / float64(len(rtts)-1)
+	}
+	return nil
+}
+
+// bandwidthTCP 持续发送 duration 或 maxBytes 后关闭写方向，按接收端统计的字节数计算吞吐
+func bandwidthTCP(target, token string, duration time.Duration, maxBytes int64) (float64, error) {
+	conn, err := dialBandwidth(target, token, "tcp")
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	buf := make([]byte, 64*1024)
+	start := time.Now()
+	conn.SetWriteDeadline(start.Add(duration))
+	for sent := int64(0); sent < maxBytes && time.Since(start) < duration; {
+		chunk := buf
+		if rest := maxBytes - sent; rest < int64(len(chunk)) {
+			chunk = chunk[:rest]
+		}
+		n, err := conn.Write(chunk)
+		sent += int64(n)
+		if err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Timeout() {
+				break
+			}
+			return 0, err
+		}
+	}
+	if tc, ok := conn.(*net.TCPConn); ok {
+		tc.CloseWrite()
+	}
+
+	conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		return 0, fmt.Errorf("读取接收端结果失败: %v", err)
+	}
+	received, err := strconv.ParseInt(strings.TrimSpace(line), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("接收端结果无效")
+	}
+	elapsed := time.Since(start).Seconds()
+	return float64(received) * 8 / elapsed / 1e6, nil
+}
+
+// bandwidthUDP 按固定速率发送 duration，再向接收端查询收包数
+func bandwidthUDP(target, token string, duration time.Duration, rateMbps int, result *BandwidthTestResult) error {
+	conn, err := net.Dial("udp", target)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	packet := make([]byte, bwUDPPacketSize)
+	copy(packet, bandwidthTag(token))
+	pps := float64(rateMbps) * 1e6 / 8 / bwUDPPacketSize
+
+	var sent int64
+	start := time.Now()
+	for {
+		elapsed := time.Since(start)
+		if elapsed >= duration {
+			break
+		}
+		for due := int64(elapsed.Seconds() * pps); sent < due; sent++ {
+			binary.BigEndian.PutUint64(packet[8:16], uint64(sent))
+			conn.Write(packet)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	// 等待在途的包到达
+	time.Sleep(500 * time.Millisecond)
+
+	rc, err := dialBandwidth(target, token, "udp-result")
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	rc.SetReadDeadline(time.Now().Add(5 * time.Second))
+	line, err := bufio.NewReader(rc).ReadString('\n')
+	if err != nil {
+		return fmt.Errorf("读取接收端结果失败: %v", err)
+	}
+	var packets, bytes int64
+	if _, err := fmt.Sscanf(line, "%d %d", &packets, &bytes); err != nil {
+		return fmt.Errorf("接收端结果无效")
+	}
+
+	result.UdpSent = sent
+	result.UdpReceived = packets
+	result.UdpMbps = float64(bytes) * 8 / duration.Seconds() / 1e6
+	if sent > 0 && packets < sent {
+		result.Loss = float64(sent-packets) / float64(sent) * 100
+	}
+	return nil
+}
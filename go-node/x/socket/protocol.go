@@ -0,0 +1,69 @@
+package socket
+
+import "fmt"
+
+// 节点控制协议版本。每条消息都带 kind 和 v 字段（与旧字段并存，旧面板会忽略），
+// 面板连接后发送 hello，节点回复自己的 hello 并列出支持的命令，
+// 面板据此直接拒绝节点不支持的命令，不必等待超时。
+const protocolVersion = 1
+
+// 消息类型
+const (
+	msgKindHello    = "hello"
+	msgKindCommand  = "command"
+	msgKindResponse = "response"
+	msgKindMetrics  = "metrics"
+)
+
+// supportedCommands 节点支持的命令，需与 routeCommand 保持一致
+var supportedCommands = []string{
+	"AddService", "UpdateService", "DeleteService", "PauseService", "ResumeService", "UpdateForwarder",
+	"AddChains", "UpdateChains", "DeleteChains",
+	"AddLimiters", "UpdateLimiters", "DeleteLimiters",
+	"TcpPing", "UdpProbe", "SetProtocol",
+	"VStart", "VStop", "VRestart", "VStatus",
+	"VAddInbound", "VRemoveInbound", "VAddClient", "VRemoveClient", "VAddClients", "VRemoveClients",
+	"VGetTraffic", "VApplyConfig", "VValidateConfig", "VDeployCert", "VSwitchVersion", "VGetInboundTags",
+	"SBStart", "SBStop", "SBRestart", "SBStatus", "SBApplyConfig", "SBAddClient", "SBRemoveClient", "SBSwitchVersion",
+	"GetServiceNames",
+	"NodeUpdateBinary", "NodeRollbackBinary", "RotateSecret",
+	"BandwidthServer", "BandwidthTest", "Traceroute",
+}
+
+// nodeFeatures 非命令类的能力标记
+var nodeFeatures = []string{
+	"gzip",          // 接受压缩命令
+	"signed-update", // 更新前校验发布清单签名
+}
+
+// NodeHello 节点 hello 消息内容
+type NodeHello struct {
+	Protocol int      `json:"protocol"`
+	Version  string   `json:"version"`
+	Commands []string `json:"commands"`
+	Features []string `json:"features"`
+}
+
+// handlePanelHello 回复面板的 hello
+func (w *WebSocketReporter) handlePanelHello(data interface{}) {
+	panelProtocol := 0
+	if m, ok := data.(map[string]interface{}); ok {
+		if v, ok := m["protocol"].(float64); ok {
+			panelProtocol = int(v)
+		}
+	}
+	fmt.Printf("🤝 面板协议版本 %d，本节点协议版本 %d\n", panelProtocol, protocolVersion)
+
+	w.sendResponse(CommandResponse{
+		Kind:    msgKindHello,
+		Type:    "Hello",
+		Success: true,
+		Message: "OK",
+		Data: NodeHello{
+			Protocol: protocolVersion,
+			Version:  w.version,
+			Commands: supportedCommands,
+			Features: nodeFeatures,
+		},
+	})
+}
@@ -0,0 +1,108 @@
+package socket
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/go-gost/x/service"
+)
+
+const localConfigPath = "config.json"
+
+// updateConfigJSON 修改 config.json 中的字段，保留其余字段，先写临时文件再重命名
+func updateConfigJSON(mutate func(cfg map[string]interface{})) error {
+	cfg := map[string]interface{}{}
+	if b, err := os.ReadFile(localConfigPath); err == nil {
+		if err := json.Unmarshal(b, &cfg); err != nil {
+			return fmt.Errorf("解析config.json失败: %v", err)
+		}
+	}
+	mutate(cfg)
+
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := localConfigPath + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	f.Close()
+	return os.Rename(tmp, localConfigPath)
+}
+
+// authSecrets 连接时依次尝试的密钥：当前密钥，以及宽限期内的旧密钥
+func (w *WebSocketReporter) authSecrets(prevSecret string, prevExpires int64) []string {
+	secrets := []string{w.secret}
+	if prevSecret != "" && prevSecret != w.secret && time.Now().Unix() < prevExpires {
+		secrets = append(secrets, prevSecret)
+	}
+	return secrets
+}
+
+// handleRotateSecret 切换到面板下发的新密钥
+func (w *WebSocketReporter) handleRotateSecret(data interface{}) error {
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("序列化数据失败: %v", err)
+	}
+	var req struct {
+		Secret       string `json:"secret"`
+		GraceSeconds int64  `json:"graceSeconds"`
+	}
+	if err := json.Unmarshal(jsonData, &req); err != nil {
+		return fmt.Errorf("解析请求失败: %v", err)
+	}
+	if len(req.Secret) < 32 {
+		return fmt.Errorf("新密钥无效")
+	}
+
+	w.connMutex.Lock()
+	oldSecret := w.secret
+	w.connMutex.Unlock()
+	if req.Secret == oldSecret {
+		return nil
+	}
+	if req.GraceSeconds <= 0 {
+		req.GraceSeconds = 24 * 3600
+	}
+	expires := time.Now().Unix() + req.GraceSeconds
+
+	if err := updateConfigJSON(func(cfg map[string]interface{}) {
+		cfg["secret"] = req.Secret
+		cfg["prev_secret"] = oldSecret
+		cfg["prev_secret_expires"] = expires
+	}); err != nil {
+		return fmt.Errorf("写入config.json失败: %v", err)
+	}
+
+	w.connMutex.Lock()
+	w.secret = req.Secret
+	w.connMutex.Unlock()
+	service.SetHTTPReportURL(w.addr, req.Secret, w.useTLS)
+	fmt.Printf("🔑 节点密钥已轮换，旧密钥在 %d 秒内仍有效\n", req.GraceSeconds)
+
+	// 响应发出后用新密钥重连，确认面板接受新密钥
+	time.AfterFunc(2*time.Second, func() {
+		w.connMutex.Lock()
+		if w.conn != nil {
+			w.conn.Close()
+		}
+		w.connected = false
+		w.connMutex.Unlock()
+	})
+	return nil
+}
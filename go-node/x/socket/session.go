@@ -0,0 +1,202 @@
+package socket
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"golang.org/x/crypto/hkdf"
+)
+
+// 节点通道认证（与面板 go-backend/pkg/node_auth.go 对应）
+//
+// 节点以 auth=2 连接，URL 中不再携带密钥。升级后：
+//
+//	面板 → 节点  challenge（面板随机数）
+//	节点 → 面板  auth（密钥标识、节点随机数、HMAC 证明）
+//	面板 → 节点  auth_ok（面板的 HMAC 证明）
+//
+// 双方各自证明持有密钥，再用 HKDF 为两个方向派生独立的会话密钥。
+// 每条消息带递增序号和时间戳并绑定到 AEAD，重放或超出时间窗口的消息会被丢弃。
+
+const (
+	nodeAuthVersion      = "2"
+	nodeHandshakeTimeout = 10 * time.Second
+	nodeClockWindow      = 2 * time.Minute
+)
+
+// legacyAuthAllowed 面板不支持握手时是否退回旧的 URL 密钥方式，
+// 默认关闭，仅在连接旧面板时通过 NODE_LEGACY_AUTH=true 开启
+func legacyAuthAllowed() bool {
+	return os.Getenv("NODE_LEGACY_AUTH") == "true"
+}
+
+// nodeKeyId 密钥标识，不泄露密钥本身
+func nodeKeyId(secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("flux-node-kid:v2"))
+	return hex.EncodeToString(mac.Sum(nil))[:32]
+}
+
+type authMessage struct {
+	Kind  string `json:"kind"`
+	V     int    `json:"v,omitempty"`
+	Kid   string `json:"kid,omitempty"`
+	Nonce string `json:"nonce,omitempty"`
+	Ts    int64  `json:"ts,omitempty"`
+	Mac   string `json:"mac,omitempty"`
+}
+
+func authMac(secret string, parts ...string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	for i, p := range parts {
+		if i > 0 {
+			mac.Write([]byte{'\n'})
+		}
+		mac.Write([]byte(p))
+	}
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// authenticate 在新建立的连接上完成握手，返回会话密钥
+func authenticate(conn *websocket.Conn, secret string) (*sessionCipher, error) {
+	conn.SetReadDeadline(time.Now().Add(nodeHandshakeTimeout))
+	defer conn.SetReadDeadline(time.Time{})
+
+	var challenge authMessage
+	if err := conn.ReadJSON(&challenge); err != nil {
+		return nil, fmt.Errorf("读取认证挑战失败: %v", err)
+	}
+	if challenge.Kind != "challenge" || challenge.Nonce == "" {
+		return nil, errors.New("面板认证挑战无效")
+	}
+
+	buf := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, buf); err != nil {
+		return nil, err
+	}
+	nodeNonce := base64.StdEncoding.EncodeToString(buf)
+	kid := nodeKeyId(secret)
+	ts := time.Now().UnixMilli()
+	auth := authMessage{
+		Kind:  "auth",
+		V:     protocolVersion,
+		Kid:   kid,
+		Nonce: nodeNonce,
+		Ts:    ts,
+		Mac:   authMac(secret, "flux-node-auth:v2", challenge.Nonce, nodeNonce, kid, strconv.FormatInt(ts, 10)),
+	}
+	if err := conn.WriteJSON(auth); err != nil {
+		return nil, err
+	}
+
+	var ok authMessage
+	if err := conn.ReadJSON(&ok); err != nil {
+		return nil, fmt.Errorf("面板拒绝认证: %v", err)
+	}
+	expected := authMac(secret, "flux-panel-auth:v2", challenge.Nonce, nodeNonce)
+	if ok.Kind != "auth_ok" || !hmac.Equal([]byte(expected), []byte(ok.Mac)) {
+		return nil, errors.New("面板身份校验失败")
+	}
+
+	return newSessionCipher(secret, challenge.Nonce, nodeNonce, time.Now().UnixMilli()-challenge.Ts)
+}
+
+// sessionCipher 一次连接的会话密钥
+type sessionCipher struct {
+	send    cipher.AEAD // 节点 → 面板
+	recv    cipher.AEAD // 面板 → 节点
+	sendSeq atomic.Uint64
+	recvSeq uint64 // 仅由接收协程访问
+	offset  int64  // 面板时钟加上该值（毫秒）得到本地时间
+}
+
+func newSessionCipher(secret, panelNonce, nodeNonce string, offset int64) (*sessionCipher, error) {
+	salt := []byte(panelNonce + "\n" + nodeNonce)
+	send, err := sessionAEAD(secret, salt, "flux-node-session:v2:node")
+	if err != nil {
+		return nil, err
+	}
+	recv, err := sessionAEAD(secret, salt, "flux-node-session:v2:panel")
+	if err != nil {
+		return nil, err
+	}
+	return &sessionCipher{send: send, recv: recv, offset: offset}, nil
+}
+
+func sessionAEAD(secret string, salt []byte, info string) (cipher.AEAD, error) {
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, []byte(secret), salt, []byte(info)), key); err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func sessionAAD(seq uint64, ts int64) []byte {
+	return []byte(fmt.Sprintf("flux:%d:%d", seq, ts))
+}
+
+type sessionMessage struct {
+	Encrypted bool   `json:"encrypted"`
+	Data      string `json:"data"`
+	Timestamp int64  `json:"timestamp"`
+	Seq       uint64 `json:"seq"`
+}
+
+// seal 加密一条发出的消息，调用方需串行发送
+func (s *sessionCipher) seal(plaintext []byte) ([]byte, error) {
+	msg := sessionMessage{
+		Encrypted: true,
+		Seq:       s.sendSeq.Add(1),
+		Timestamp: time.Now().UnixMilli(),
+	}
+	nonce := make([]byte, s.send.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	msg.Data = base64.StdEncoding.EncodeToString(s.send.Seal(nonce, nonce, plaintext, sessionAAD(msg.Seq, msg.Timestamp)))
+	return json.Marshal(msg)
+}
+
+// open 解密一条收到的消息，拒绝重放
+func (s *sessionCipher) open(message []byte) ([]byte, error) {
+	var msg sessionMessage
+	if err := json.Unmarshal(message, &msg); err != nil || !msg.Encrypted {
+		return nil, errors.New("已认证会话收到未加密消息")
+	}
+	if msg.Seq <= s.recvSeq {
+		return nil, fmt.Errorf("重放的消息 (seq %d <= %d)", msg.Seq, s.recvSeq)
+	}
+	drift := time.Duration(time.Now().UnixMilli()-(msg.Timestamp+s.offset)) * time.Millisecond
+	if drift > nodeClockWindow || drift < -nodeClockWindow {
+		return nil, fmt.Errorf("消息时间戳超出窗口 (%v)", drift)
+	}
+	data, err := base64.StdEncoding.DecodeString(msg.Data)
+	if err != nil || len(data) < s.recv.NonceSize() {
+		return nil, errors.New("密文格式错误")
+	}
+	nonce, ciphertext := data[:s.recv.NonceSize()], data[s.recv.NonceSize():]
+	plaintext, err := s.recv.Open(nil, nonce, ciphertext, sessionAAD(msg.Seq, msg.Timestamp))
+	if err != nil {
+		return nil, err
+	}
+	s.recvSeq = msg.Seq
+	return plaintext, nil
+}
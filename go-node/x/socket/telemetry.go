@@ -0,0 +1,254 @@
+package socket
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-gost/core/observer/stats"
+	"github.com/go-gost/x/registry"
+	"github.com/go-gost/x/service"
+	"github.com/shirou/gopsutil/v3/disk"
+	"github.com/shirou/gopsutil/v3/host"
+	"github.com/shirou/gopsutil/v3/load"
+	psnet "github.com/shirou/gopsutil/v3/net"
+)
+
+// 系统信息每 2 秒上报一次，开销较大的采集项按各自的间隔缓存
+const (
+	diskRefreshInterval   = 30 * time.Second
+	socketRefreshInterval = 10 * time.Second
+)
+
+// DiskInfo 磁盘分区使用情况
+type DiskInfo struct {
+	Mount  string  `json:"mount"`
+	Fstype string  `json:"fstype"`
+	Total  uint64  `json:"total"`
+	Used   uint64  `json:"used"`
+	Usage  float64 `json:"usage"` // 百分比
+}
+
+// NetCounter 单个网卡的累计流量
+type NetCounter struct {
+	Name    string `json:"name"`
+	RxBytes uint64 `json:"rx_bytes"`
+	TxBytes uint64 `json:"tx_bytes"`
+}
+
+// SocketStats 套接字统计
+type SocketStats struct {
+	TcpStates      map[string]int `json:"tcp_states"` // 按状态统计的 TCP 连接数
+	TcpEstablished int            `json:"tcp_established"`
+	UdpSockets     int            `json:"udp_sockets"`
+	ConntrackCount uint64         `json:"conntrack_count"`
+	ConntrackMax   uint64         `json:"conntrack_max"`
+}
+
+// HostInfo 内核与系统信息，启动后不变
+type HostInfo struct {
+	Kernel string `json:"kernel"`
+	OS     string `json:"os"`
+	Arch   string `json:"arch"`
+}
+
+var (
+	hostInfoOnce sync.Once
+	hostInfo     HostInfo
+
+	telemetryMu    sync.Mutex
+	diskCache      []DiskInfo
+	diskCachedAt   time.Time
+	socketCache    SocketStats
+	socketCachedAt time.Time
+)
+
+// getHostInfo 获取内核与系统版本
+func getHostInfo() HostInfo {
+	hostInfoOnce.Do(func() {
+		info, err := host.Info()
+		if err != nil {
+			return
+		}
+		hostInfo.Kernel = info.KernelVersion
+		hostInfo.Arch = info.KernelArch
+		hostInfo.OS = strings.TrimSpace(info.Platform + " " + info.PlatformVersion)
+		if hostInfo.OS == "" {
+			hostInfo.OS = info.OS
+		}
+	})
+	return hostInfo
+}
+
+// getLoadAvg 获取系统负载
+func getLoadAvg() (float64, float64, float64) {
+	avg, err := load.Avg()
+	if err != nil {
+		return 0, 0, 0
+	}
+	return avg.Load1, avg.Load5, avg.Load15
+}
+
+// getDisks 获取物理分区的使用情况
+func getDisks() []DiskInfo {
+	telemetryMu.Lock()
+	defer telemetryMu.Unlock()
+	if diskCache != nil && time.Since(diskCachedAt) < diskRefreshInterval {
+		return diskCache
+	}
+
+	result := make([]DiskInfo, 0)
+	partitions, err := disk.Partitions(false)
+	if err == nil {
+		seen := make(map[string]bool)
+		for _, p := range partitions {
+			// 同一设备可能挂载多次（如 docker 的 bind mount）
+			if seen[p.Device] {
+				continue
+			}
+			usage, err := disk.Usage(p.Mountpoint)
+			if err != nil || usage.Total == 0 {
+				continue
+			}
+			seen[p.Device] = true
+			result = append(result, DiskInfo{
+				Mount:  p.Mountpoint,
+				Fstype: p.Fstype,
+				Total:  usage.Total,
+				Used:   usage.Used,
+				Usage:  usage.UsedPercent,
+			})
+		}
+	}
+	diskCache, diskCachedAt = result, time.Now()
+	return result
+}
+
+// rootDiskUsage 根分区使用率，没有根分区时取使用率最高的分区
+func rootDiskUsage(disks []DiskInfo) float64 {
+	max := 0.0
+	for _, d := range disks {
+		if d.Mount == "/" {
+			return d.Usage
+		}
+		if d.Usage > max {
+			max = d.Usage
+		}
+	}
+	return max
+}
+
+// getNetCounters 获取各网卡的累计流量（排除回环和虚拟网卡）
+func getNetCounters() []NetCounter {
+	ioCounters, err := psnet.IOCounters(true)
+	if err != nil {
+		return nil
+	}
+	result := make([]NetCounter, 0, len(ioCounters))
+	for _, io := range ioCounters {
+		if strings.HasPrefix(io.Name, "lo") || isVirtualInterface(io.Name) {
+			continue
+		}
+		result = append(result, NetCounter{Name: io.Name, RxBytes: io.BytesRecv, TxBytes: io.BytesSent})
+	}
+	return result
+}
+
+// tcpStateNames /proc/net/tcp 中的状态编码
+var tcpStateNames = map[string]string{
+	"01": "ESTABLISHED",
+	"02": "SYN_SENT",
+	"03": "SYN_RECV",
+	"04": "FIN_WAIT1",
+	"05": "FIN_WAIT2",
+	"06": "TIME_WAIT",
+	"07": "CLOSE",
+	"08": "CLOSE_WAIT",
+	"09": "LAST_ACK",
+	"0A": "LISTEN",
+	"0B": "CLOSING",
+}
+
+// getSocketStats 统计 TCP/UDP 套接字和 conntrack 使用情况
+func getSocketStats() SocketStats {
+	telemetryMu.Lock()
+	defer telemetryMu.Unlock()
+	if socketCache.TcpStates != nil && time.Since(socketCachedAt) < socketRefreshInterval {
+		return socketCache
+	}
+
+	s := SocketStats{TcpStates: make(map[string]int)}
+	for _, f := range []string{"/proc/net/tcp", "/proc/net/tcp6"} {
+		countProcNet(f, func(state string) {
+			name, ok := tcpStateNames[state]
+			if !ok {
+				name = "UNKNOWN"
+			}
+			s.TcpStates[name]++
+		})
+	}
+	s.TcpEstablished = s.TcpStates["ESTABLISHED"]
+	for _, f := range []string{"/proc/net/udp", "/proc/net/udp6"} {
+		countProcNet(f, func(string) { s.UdpSockets++ })
+	}
+	s.ConntrackCount = readUintFile("/proc/sys/net/netfilter/nf_conntrack_count")
+	s.ConntrackMax = readUintFile("/proc/sys/net/netfilter/nf_conntrack_max")
+
+	socketCache, socketCachedAt = s, time.Now()
+	return s
+}
+
+// countProcNet 逐行读取 /proc/net/{tcp,udp}，回调每个套接字的状态字段
+func countProcNet(path string, fn func(state string)) {
+	f, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Scan() // 表头
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) > 3 {
+			fn(fields[3])
+		}
+	}
+}
+
+func readUintFile(path string) uint64 {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return 0
+	}
+	v, _ := strconv.ParseUint(strings.TrimSpace(string(b)), 10, 64)
+	return v
+}
+
+// getServiceConns 统计 gost 服务的当前连接数，只返回有连接的服务
+func getServiceConns() (uint64, map[string]uint64) {
+	var total uint64
+	conns := make(map[string]uint64)
+	for name, svc := range registry.ServiceRegistry().GetAll() {
+		ss, ok := svc.(interface{ Status() *service.Status })
+		if !ok || ss == nil {
+			continue
+		}
+		status := ss.Status()
+		if status == nil {
+			continue
+		}
+		st := status.Stats()
+		if st == nil {
+			continue
+		}
+		if n := st.Get(stats.KindCurrentConns); n > 0 {
+			conns[name] = n
+			total += n
+		}
+	}
+	return total, conns
+}
@@ -0,0 +1,139 @@
+package socket
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"time"
+)
+
+// 路由追踪：每轮向 1..maxHops 的每一跳各发一个探测包，共 rounds 轮，
+// 按跳统计丢包和往返延迟（类似 mtr）。中间路由返回的 ICMP 超时报文
+// 需要原始套接字接收，因此节点需以 root 或 CAP_NET_RAW 运行。
+const (
+	traceDefaultRounds  = 5
+	traceMaxRounds      = 20
+	traceDefaultMaxHops = 30
+	traceMaxHops        = 64
+	traceDefaultTimeout = 1000 // 毫秒
+	traceMaxTimeout     = 5000
+	traceDefaultUDPPort = 33434
+)
+
+// TracerouteRequest 路由追踪请求
+type TracerouteRequest struct {
+	Host     string `json:"host"`
+	Port     int    `json:"port"`
+	Protocol string `json:"protocol"` // tcp / udp / icmp，留空时有端口用 tcp，否则 icmp
+	MaxHops  int    `json:"maxHops"`
+	Rounds   int    `json:"rounds"`
+	Timeout  int    `json:"timeout"` // 单个探测包超时（毫秒）
+}
+
+// TracerouteHop 单跳统计
+type TracerouteHop struct {
+	TTL      int      `json:"ttl"`
+	IP       string   `json:"ip"`            // 无响应时为空
+	IPs      []string `json:"ips,omitempty"` // 多路径时各轮响应的不同地址
+	Sent     int      `json:"sent"`
+	Received int      `json:"received"`
+	Loss     float64  `json:"loss"` // 百分比
+	Last     float64  `json:"last"` // 毫秒
+	Avg      float64  `json:"avg"`
+	Best     float64  `json:"best"`
+	Worst    float64  `json:"worst"`
+}
+
+// TracerouteResult 路由追踪结果
+type TracerouteResult struct {
+	Host     string          `json:"host"`
+	IP       string          `json:"ip"`
+	Port     int             `json:"port"`
+	Protocol string          `json:"protocol"`
+	Reached  bool            `json:"reached"`
+	Hops     []TracerouteHop `json:"hops"`
+}
+
+// handleTraceroute 处理路由追踪命令
+func (w *WebSocketReporter) handleTraceroute(data interface{}) (TracerouteResult, error) {
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		return TracerouteResult{}, fmt.Errorf("序列化数据失败: %v", err)
+	}
+	var req TracerouteRequest
+	if err := json.Unmarshal(jsonData, &req); err != nil {
+		return TracerouteResult{}, fmt.Errorf("解析路由追踪请求失败: %v", err)
+	}
+	if net.ParseIP(req.Host) == nil && !isValidHostname(req.Host) {
+		return TracerouteResult{}, fmt.Errorf("无效的IP地址或主机名")
+	}
+	if req.Port < 0 || req.Port > 65535 {
+		return TracerouteResult{}, fmt.Errorf("无效的端口号，范围应为1-65535")
+	}
+	if req.Protocol == "" {
+		req.Protocol = "icmp"
+		if req.Port > 0 {
+			req.Protocol = "tcp"
+		}
+	}
+	switch req.Protocol {
+	case "tcp":
+		if req.Port == 0 {
+			return TracerouteResult{}, fmt.Errorf("TCP 路由追踪需要端口")
+		}
+	case "udp":
+		if req.Port == 0 {
+			req.Port = traceDefaultUDPPort
+		}
+	case "icmp":
+	default:
+		return TracerouteResult{}, fmt.Errorf("不支持的协议: %s", req.Protocol)
+	}
+	req.Rounds = clampInt(req.Rounds, traceDefaultRounds, traceMaxRounds)
+	req.MaxHops = clampInt(req.MaxHops, traceDefaultMaxHops, traceMaxHops)
+	req.Timeout = clampInt(req.Timeout, traceDefaultTimeout, traceMaxTimeout)
+
+	ip, err := resolveTraceTarget(req.Host)
+	if err != nil {
+		return TracerouteResult{}, err
+	}
+
+	fmt.Printf("🧭 开始路由追踪: %s (%s) %s/%d，%d 轮\n", req.Host, ip, req.Protocol, req.Port, req.Rounds)
+	result, err := runTraceroute(req, ip)
+	if err != nil {
+		return TracerouteResult{}, err
+	}
+	result.Host = req.Host
+	fmt.Printf("🧭 路由追踪完成: %s，%d 跳，到达=%t\n", req.Host, len(result.Hops), result.Reached)
+	return result, nil
+}
+
+// resolveTraceTarget 解析目标地址，优先 IPv4
+func resolveTraceTarget(host string) (net.IP, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		return ip, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil || len(addrs) == 0 {
+		return nil, fmt.Errorf("DNS解析失败: %v", err)
+	}
+	for _, a := range addrs {
+		if a.IP.To4() != nil {
+			return a.IP, nil
+		}
+	}
+	return addrs[0].IP, nil
+}
+
+func clampInt(v, def, max int) int {
+	if v <= 0 {
+		return def
+	}
+	if v > max {
+		return max
+	}
+	return v
+}
@@ -0,0 +1,427 @@
+//go:build linux
+
+package socket
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"net"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+// 同一轮内相邻探测包的间隔，避免触发路由器的 ICMP 限速
+const traceProbeInterval = 10 * time.Millisecond
+
+type traceProbe struct {
+	ttl      int
+	sent     time.Time
+	answered bool
+}
+
+type traceHopStat struct {
+	sent int
+	rtts []float64
+	ips  []string
+}
+
+type tracer struct {
+	req     TracerouteRequest
+	ip      net.IP
+	v6      bool
+	timeout time.Duration
+	raw     *icmp.PacketConn
+	echoID  int
+	echoSeq int
+
+	mu      sync.Mutex
+	probes  map[int]*traceProbe // 源端口（TCP/UDP）或序号（ICMP）→ 探测包
+	stats   map[int]*traceHopStat
+	reached int // 到达目标的最小 TTL，0 表示未到达
+}
+
+func runTraceroute(req TracerouteRequest, ip net.IP) (TracerouteResult, error) {
+	t := &tracer{
+		req:     req,
+		ip:      ip,
+		v6:      ip.To4() == nil,
+		timeout: time.Duration(req.Timeout) * time.Millisecond,
+		echoID:  rand.Intn(0xffff),
+		echoSeq: rand.Intn(0xffff),
+		probes:  make(map[int]*traceProbe),
+		stats:   make(map[int]*traceHopStat),
+	}
+
+	var err error
+	if t.v6 {
+		t.raw, err = icmp.ListenPacket("ip6:ipv6-icmp", "::")
+	} else {
+		t.raw, err = icmp.ListenPacket("ip4:icmp", "0.0.0.0")
+	}
+	if err != nil {
+		return TracerouteResult{}, fmt.Errorf("创建ICMP监听失败（需要root或CAP_NET_RAW权限）: %v", err)
+	}
+	defer t.raw.Close()
+	go t.readLoop()
+
+	for round := 0; round < req.Rounds; round++ {
+		t.runRound()
+	}
+	return t.result(), nil
+}
+
+// runRound 向每一跳发送一个探测包，等待响应或超时
+func (t *tracer) runRound() {
+	maxTTL := t.req.MaxHops
+	t.mu.Lock()
+	if t.reached > 0 {
+		maxTTL = t.reached
+	}
+	t.mu.Unlock()
+
+	var wg sync.WaitGroup
+	var closers []func()
+	for ttl := 1; ttl <= maxTTL; ttl++ {
+		switch t.req.Protocol {
+		case "tcp":
+			wg.Add(1)
+			go func(ttl int) {
+				defer wg.Done()
+				t.probeTCP(ttl)
+			}(ttl)
+		case "udp":
+			if c := t.probeUDP(ttl); c != nil {
+				closers = append(closers, func() { c.Close() })
+			}
+		default:
+			t.probeICMP(ttl)
+		}
+		time.Sleep(traceProbeInterval)
+	}
+
+	// 全部应答或超时即结束本轮
+	deadline := time.Now().Add(t.timeout)
+	for time.Now().Before(deadline) && !t.roundAnswered() {
+		time.Sleep(50 * time.Millisecond)
+	}
+	wg.Wait()
+	for _, c := range closers {
+		c()
+	}
+
+	t.mu.Lock()
+	t.probes = make(map[int]*traceProbe)
+	t.mu.Unlock()
+}
+
+func (t *tracer) roundAnswered() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, p := range t.probes {
+		if !p.answered && (t.reached == 0 || p.ttl <= t.reached) {
+			return false
+		}
+	}
+	return true
+}
+
+func (t *tracer) register(key, ttl int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.probes[key] = &traceProbe{ttl: ttl, sent: time.Now()}
+	s := t.stats[ttl]
+	if s == nil {
+		s = &traceHopStat{}
+		t.stats[ttl] = s
+	}
+	s.sent++
+}
+
+// answer 记录一个探测包的响应，reached 表示响应来自目标本身
+func (t *tracer) answer(key int, from string, reached bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	p := t.probes[key]
+	if p == nil || p.answered {
+		return
+	}
+	p.answered = true
+	s := t.stats[p.ttl]
+	s.rtts = append(s.rtts, float64(time.Since(p.sent).Microseconds())/1000)
+	found := false
+	for _, ip := range s.ips {
+		if ip == from {
+			found = true
+			break
+		}
+	}
+	if !found {
+		s.ips = append(s.ips, from)
+	}
+	if reached && (t.reached == 0 || p.ttl < t.reached) {
+		t.reached = p.ttl
+	}
+}
+
+func (t *tracer) probeTCP(ttl int) {
+	key := 0
+	dialer := net.Dialer{
+		Timeout: t.timeout,
+		Control: func(network, address string, c syscall.RawConn) error {
+			var serr error
+			err := c.Control(func(fd uintptr) {
+				if serr = t.setTTL(int(fd), ttl); serr != nil {
+					return
+				}
+				// 先绑定端口，以便在发出 SYN 前登记探测包
+				var sa syscall.Sockaddr = &syscall.SockaddrInet4{}
+				if t.v6 {
+					sa = &syscall.SockaddrInet6{}
+				}
+				if serr = syscall.Bind(int(fd), sa); serr != nil {
+					return
+				}
+				local, err := syscall.Getsockname(int(fd))
+				if err != nil {
+					serr = err
+					return
+				}
+				switch a := local.(type) {
+				case *syscall.SockaddrInet4:
+					key = a.Port
+				case *syscall.SockaddrInet6:
+					key = a.Port
+				}
+				t.register(key, ttl)
+			})
+			if err != nil {
+				return err
+			}
+			return serr
+		},
+	}
+
+	network := "tcp4"
+	if t.v6 {
+		network = "tcp6"
+	}
+	conn, err := dialer.Dial(network, net.JoinHostPort(t.ip.String(), strconv.Itoa(t.req.Port)))
+	if key == 0 {
+		return
+	}
+	// 握手成功或被目标重置都说明探测包到达了目标
+	if err == nil {
+		conn.Close()
+		t.answer(key, t.ip.String(), true)
+	} else if errors.Is(err, syscall.ECONNREFUSED) {
+		t.answer(key, t.ip.String(), true)
+	}
+}
+
+func (t *tracer) setTTL(fd, ttl int) error {
+	if t.v6 {
+		return syscall.SetsockoptInt(fd, syscall.IPPROTO_IPV6, syscall.IPV6_UNICAST_HOPS, ttl)
+	}
+	return syscall.SetsockoptInt(fd, syscall.IPPROTO_IP, syscall.IP_TTL, ttl)
+}
+
+func (t *tracer) probeUDP(ttl int) net.PacketConn {
+	network := "udp4"
+	if t.v6 {
+		network = "udp6"
+	}
+	c, err := net.ListenPacket(network, ":0")
+	if err != nil {
+		return nil
+	}
+	if t.v6 {
+		err = ipv6.NewPacketConn(c).SetHopLimit(ttl)
+	} else {
+		err = ipv4.NewPacketConn(c).SetTTL(ttl)
+	}
+	if err != nil {
+		c.Close()
+		return nil
+	}
+	t.register(c.LocalAddr().(*net.UDPAddr).Port, ttl)
+	c.WriteTo(make([]byte, 32), &net.UDPAddr{IP: t.ip, Port: t.req.Port})
+	return c
+}
+
+func (t *tracer) probeICMP(ttl int) {
+	t.echoSeq = (t.echoSeq + 1) & 0xffff
+	seq := t.echoSeq
+	msg := icmp.Message{
+		Type: ipv4.ICMPTypeEcho,
+		Body: &icmp.Echo{ID: t.echoID, Seq: seq, Data: make([]byte, 32)},
+	}
+	var err error
+	if t.v6 {
+		msg.Type = ipv6.ICMPTypeEchoRequest
+		err = t.raw.IPv6PacketConn().SetHopLimit(ttl)
+	} else {
+		err = t.raw.IPv4PacketConn().SetTTL(ttl)
+	}
+	if err != nil {
+		return
+	}
+	b, err := msg.Marshal(nil)
+	if err != nil {
+		return
+	}
+	t.register(seq, ttl)
+	t.raw.WriteTo(b, &net.IPAddr{IP: t.ip})
+}
+
+// readLoop 接收 ICMP 报文并匹配到探测包
+func (t *tracer) readLoop() {
+	proto := 1
+	if t.v6 {
+		proto = 58
+	}
+	buf := make([]byte, 1500)
+	for {
+		n, peer, err := t.raw.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		from := ""
+		if a, ok := peer.(*net.IPAddr); ok {
+			from = a.IP.String()
+		}
+		msg, err := icmp.ParseMessage(proto, buf[:n])
+		if err != nil {
+			continue
+		}
+		fromTarget := from == t.ip.String()
+		switch body := msg.Body.(type) {
+		case *icmp.TimeExceeded:
+			if key, ok := t.quotedKey(body.Data); ok {
+				t.answer(key, from, false)
+			}
+		case *icmp.DstUnreach:
+			// 目标返回的端口不可达等同于到达
+			if key, ok := t.quotedKey(body.Data); ok {
+				t.answer(key, from, fromTarget)
+			}
+		case *icmp.Echo:
+			if t.req.Protocol == "icmp" && body.ID == t.echoID &&
+				(msg.Type == ipv4.ICMPTypeEchoReply || msg.Type == ipv6.ICMPTypeEchoReply) {
+				t.answer(body.Seq, from, fromTarget)
+			}
+		}
+	}
+}
+
+// quotedKey 从 ICMP 差错报文引用的原始数据包中取出探测包标识
+func (t *tracer) quotedKey(data []byte) (int, bool) {
+	var proto int
+	var dst net.IP
+	var payload []byte
+	if t.v6 {
+		if len(data) < 48 {
+			return 0, false
+		}
+		proto, dst, payload = int(data[6]), net.IP(data[24:40]), data[40:]
+	} else {
+		if len(data) < 20 {
+			return 0, false
+		}
+		ihl := int(data[0]&0x0f) * 4
+		if len(data) < ihl+8 {
+			return 0, false
+		}
+		proto, dst, payload = int(data[9]), net.IP(data[16:20]), data[ihl:]
+	}
+	if len(payload) < 8 || !dst.Equal(t.ip) {
+		return 0, false
+	}
+
+	switch t.req.Protocol {
+	case "tcp":
+		if proto != syscall.IPPROTO_TCP {
+			return 0, false
+		}
+		return int(binary.BigEndian.Uint16(payload[0:2])), true
+	case "udp":
+		if proto != syscall.IPPROTO_UDP {
+			return 0, false
+		}
+		return int(binary.BigEndian.Uint16(payload[0:2])), true
+	default:
+		if proto != syscall.IPPROTO_ICMP && proto != syscall.IPPROTO_ICMPV6 {
+			return 0, false
+		}
+		if int(binary.BigEndian.Uint16(payload[4:6])) != t.echoID {
+			return 0, false
+		}
+		return int(binary.BigEndian.Uint16(payload[6:8])), true
+	}
+}
+
+// result 汇总各跳统计。未到达目标时保留到最后一个有响应的跳之后一跳
+func (t *tracer) result() TracerouteResult {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	last := t.reached
+	if last == 0 {
+		for ttl, s := range t.stats {
+			if len(s.rtts) > 0 && ttl > last {
+				last = ttl
+			}
+		}
+		if last < t.req.MaxHops {
+			last++
+		}
+	}
+
+	result := TracerouteResult{
+		IP:       t.ip.String(),
+		Port:     t.req.Port,
+		Protocol: t.req.Protocol,
+		Reached:  t.reached > 0,
+		Hops:     make([]TracerouteHop, 0, last),
+	}
+	if t.req.Protocol == "icmp" {
+		result.Port = 0
+	}
+	for ttl := 1; ttl <= last; ttl++ {
+		hop := TracerouteHop{TTL: ttl}
+		if s := t.stats[ttl]; s != nil {
+			hop.Sent = s.sent
+			hop.Received = len(s.rtts)
+			if len(s.ips) > 0 {
+				hop.IP = s.ips[0]
+			}
+			if len(s.ips) > 1 {
+				hop.IPs = s.ips
+			}
+			if hop.Sent > 0 {
+				hop.Loss = math.Round(float64(hop.Sent-hop.Received)/float64(hop.Sent)*10000) / 100
+			}
+			if hop.Received > 0 {
+				hop.Best = math.MaxFloat64
+				var sum float64
+				for _, rtt := range s.rtts {
+					sum += rtt
+					hop.Best = math.Min(hop.Best, rtt)
+					hop.Worst = math.Max(hop.Worst, rtt)
+				}
+				hop.Avg = math.Round(sum/float64(hop.Received)*1000) / 1000
+				hop.Last = s.rtts[len(s.rtts)-1]
+			}
+		}
+		result.Hops = append(result.Hops, hop)
+	}
+	return result
+}
@@ -0,0 +1,12 @@
+//go:build !linux
+
+package socket
+
+import (
+	"fmt"
+	"net"
+)
+
+func runTraceroute(req TracerouteRequest, ip net.IP) (TracerouteResult, error) {
+	return TracerouteResult{}, fmt.Errorf("当前系统不支持路由追踪")
+}
@@ -0,0 +1,279 @@
+package socket
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// UDP 探测：UDP 没有握手，只有收到响应才能确认目标在工作，因此按目标
+// 服务选择能引起回应的载荷。
+const (
+	udpProbeEcho = "echo" // 任意数据，适用于回显或收到任何数据都会回包的服务
+	udpProbeDNS  = "dns"  // DNS A 记录查询
+	udpProbeQUIC = "quic" // QUIC Initial 包，使用保留版本号，服务端应回复版本协商包
+	udpProbeHex  = "hex"  // 自定义十六进制载荷
+
+	udpProbeDefaultDNSName = "example.com"
+	udpProbeMaxPayload     = 1400
+	udpProbeMaxCount       = 20
+	udpProbeMaxTimeout     = 10000 // 毫秒
+	quicMinDatagram        = 1200
+)
+
+// UdpProbeRequest UDP 探测请求结构体
+type UdpProbeRequest struct {
+	IP        string `json:"ip"`
+	Port      int    `json:"port"`
+	Payload   string `json:"payload"` // echo / dns / quic / hex，默认 echo
+	Data      string `json:"data"`    // hex 载荷，或 dns 查询的域名
+	Count     int    `json:"count"`
+	Timeout   int    `json:"timeout"` // 超时时间(毫秒)
+	RequestId string `json:"requestId,omitempty"`
+}
+
+// UdpProbeResponse UDP 探测响应结构体，字段与 TcpPingResponse 保持一致
+type UdpProbeResponse struct {
+	IP           string  `json:"ip"`
+	Port         int     `json:"port"`
+	Payload      string  `json:"payload"`
+	Success      bool    `json:"success"`
+	AverageTime  float64 `json:"averageTime"` // 平均响应时间(ms)
+	PacketLoss   float64 `json:"packetLoss"`  // 无响应比例(%)
+	ResponseSize int     `json:"responseSize,omitempty"`
+	ErrorMessage string  `json:"errorMessage,omitempty"`
+	RequestId    string  `json:"requestId,omitempty"`
+}
+
+// handleUdpProbe 处理UDP探测诊断命令
+func (w *WebSocketReporter) handleUdpProbe(data interface{}) (UdpProbeResponse, error) {
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		return UdpProbeResponse{}, fmt.Errorf("序列化UDP探测数据失败: %v", err)
+	}
+
+	var req UdpProbeRequest
+	if err := json.Unmarshal(jsonData, &req); err != nil {
+		return UdpProbeResponse{}, fmt.Errorf("解析UDP探测请求失败: %v", err)
+	}
+	if req.Payload == "" {
+		req.Payload = udpProbeEcho
+	}
+
+	response := UdpProbeResponse{
+		IP:        req.IP,
+		Port:      req.Port,
+		Payload:   req.Payload,
+		RequestId: req.RequestId,
+	}
+
+	if net.ParseIP(req.IP) == nil && !isValidHostname(req.IP) {
+		response.ErrorMessage = "无效的IP地址或主机名"
+		return response, nil
+	}
+	if req.Port <= 0 || req.Port > 65535 {
+		response.ErrorMessage = "无效的端口号，范围应为1-65535"
+		return response, nil
+	}
+	if req.Count <= 0 {
+		req.Count = 4
+	}
+	if req.Count > udpProbeMaxCount {
+		req.Count = udpProbeMaxCount
+	}
+	if req.Timeout <= 0 {
+		req.Timeout = 3000
+	}
+	if req.Timeout > udpProbeMaxTimeout {
+		req.Timeout = udpProbeMaxTimeout
+	}
+
+	probe, err := newUdpProbe(req.Payload, req.Data)
+	if err != nil {
+		response.ErrorMessage = err.Error()
+		return response, nil
+	}
+
+	avgTime, packetLoss, size, err := udpProbeHost(req.IP, req.Port, probe, req.Count, req.Timeout)
+	if err != nil {
+		response.ErrorMessage = err.Error()
+	} else {
+		response.Success = true
+		response.AverageTime = avgTime
+		response.PacketLoss = packetLoss
+		response.ResponseSize = size
+	}
+	return response, nil
+}
+
+// udpProbe 生成每次探测的数据包并校验响应
+type udpProbe struct {
+	build func() ([]byte, func(reply []byte) bool)
+}
+
+func newUdpProbe(payload, data string) (*udpProbe, error) {
+	switch payload {
+	case udpProbeEcho:
+		return &udpProbe{build: func() ([]byte, func([]byte) bool) {
+			b := append([]byte("FLUXPROBE "), randomBytes(22)...)
+			return b, func([]byte) bool { return true }
+		}}, nil
+
+	case udpProbeDNS:
+		name := strings.TrimSuffix(strings.TrimSpace(data), ".")
+		if name == "" {
+			name = udpProbeDefaultDNSName
+		}
+		if !isValidHostname(name) {
+			return nil, errors.New("无效的DNS查询域名")
+		}
+		return &udpProbe{build: func() ([]byte, func([]byte) bool) {
+			id := binary.BigEndian.Uint16(randomBytes(2))
+			return buildDNSQuery(id, name), func(reply []byte) bool {
+				// 同一 ID 且 QR 位为应答即可，RCODE 非零也说明服务在工作
+				return len(reply) >= 12 && binary.BigEndian.Uint16(reply[0:2]) == id && reply[2]&0x80 != 0
+			}
+		}}, nil
+
+	case udpProbeQUIC:
+		return &udpProbe{build: func() ([]byte, func([]byte) bool) {
+			return buildQUICProbe(), func(reply []byte) bool {
+				// 长包头且版本号为 0 即版本协商包
+				return len(reply) >= 5 && reply[0]&0x80 != 0 && binary.BigEndian.Uint32(reply[1:5]) == 0
+			}
+		}}, nil
+
+	case udpProbeHex:
+		b, err := hex.DecodeString(strings.Join(strings.Fields(data), ""))
+		if err != nil || len(b) == 0 {
+			return nil, errors.New("无效的十六进制载荷")
+		}
+		if len(b) > udpProbeMaxPayload {
+			return nil, fmt.Errorf("载荷过长，最多 %d 字节", udpProbeMaxPayload)
+		}
+		return &udpProbe{build: func() ([]byte, func([]byte) bool) {
+			return b, func([]byte) bool { return true }
+		}}, nil
+	}
+	return nil, fmt.Errorf("不支持的探测载荷: %s", payload)
+}
+
+// udpProbeHost 发送探测包并等待响应，返回平均响应时间、无响应比例和响应大小
+func udpProbeHost(ip string, port int, probe *udpProbe, count int, timeoutMs int) (float64, float64, int, error) {
+	raddr, err := net.ResolveUDPAddr("udp", net.JoinHostPort(ip, strconv.Itoa(port)))
+	if err != nil {
+		return 0, 100.0, 0, fmt.Errorf("DNS解析失败: %v", err)
+	}
+	timeout := time.Duration(timeoutMs) * time.Millisecond
+
+	fmt.Printf("🔍 开始UDP探测: %s，次数: %d，超时: %dms\n", raddr, count, timeoutMs)
+
+	var totalTime float64
+	var successCount, refused, invalid, size int
+	buf := make([]byte, 65535)
+	for i := 0; i < count; i++ {
+		// 每次使用新的套接字，端口不可达的 ICMP 报文才能对应到本次探测
+		conn, err := net.DialUDP("udp", nil, raddr)
+		if err != nil {
+			return 0, 100.0, 0, fmt.Errorf("创建UDP套接字失败: %v", err)
+		}
+		packet, valid := probe.build()
+		start := time.Now()
+		_, err = conn.Write(packet)
+		for err == nil {
+			conn.SetReadDeadline(start.Add(timeout))
+			var n int
+			n, err = conn.Read(buf)
+			if err != nil {
+				break
+			}
+			if valid(buf[:n]) {
+				size = n
+				break
+			}
+			invalid++
+		}
+		elapsed := time.Since(start)
+		conn.Close()
+
+		switch {
+		case err == nil:
+			fmt.Printf("  第%d次收到响应: %d 字节 %.2fms\n", i+1, size, elapsed.Seconds()*1000)
+			totalTime += elapsed.Seconds() * 1000
+			successCount++
+		case errors.Is(err, syscall.ECONNREFUSED):
+			fmt.Printf("  第%d次端口不可达\n", i+1)
+			refused++
+		default:
+			fmt.Printf("  第%d次无响应: %v\n", i+1, err)
+		}
+
+		if i < count-1 {
+			time.Sleep(100 * time.Millisecond)
+		}
+	}
+
+	if successCount == 0 {
+		switch {
+		case refused > 0:
+			return 0, 100.0, 0, errors.New("目标端口不可达（收到ICMP端口不可达）")
+		case invalid > 0:
+			return 0, 100.0, 0, errors.New("收到的响应与探测载荷不匹配")
+		default:
+			return 0, 100.0, 0, errors.New("未收到UDP响应（目标未监听或忽略了该探测包）")
+		}
+	}
+
+	avgTime := totalTime / float64(successCount)
+	packetLoss := float64(count-successCount) / float64(count) * 100
+
+	fmt.Printf("✅ UDP探测完成: 平均响应时间 %.2fms，无响应率 %.1f%%\n", avgTime, packetLoss)
+
+	return avgTime, packetLoss, size, nil
+}
+
+// buildDNSQuery 构造递归查询 name 的 A 记录的 DNS 请求
+func buildDNSQuery(id uint16, name string) []byte {
+	b := make([]byte, 12, 12+len(name)+6)
+	binary.BigEndian.PutUint16(b[0:2], id)
+	b[2] = 0x01                           // RD
+	binary.BigEndian.PutUint16(b[4:6], 1) // QDCOUNT
+	for _, label := range strings.Split(name, ".") {
+		if label == "" {
+			continue
+		}
+		b = append(b, byte(len(label)))
+		b = append(b, label...)
+	}
+	b = append(b, 0)          // 根
+	b = append(b, 0, 1, 0, 1) // QTYPE A, QCLASS IN
+	return b
+}
+
+// buildQUICProbe 构造一个使用保留版本号的 QUIC Initial 长包头数据包。
+// 按 RFC 9000 第 6 节，服务端对不支持的版本且不小于 1200 字节的数据报
+// 回复版本协商包，因此无需完成加密握手即可确认 QUIC 服务在工作。
+func buildQUICProbe() []byte {
+	b := make([]byte, 0, quicMinDatagram)
+	b = append(b, 0xc0)                   // 长包头 + 固定位，类型 Initial
+	b = append(b, 0x1a, 0x2a, 0x3a, 0x4a) // 保留版本号（0x?a?a?a?a）
+	b = append(b, 8)
+	b = append(b, randomBytes(8)...) // DCID
+	b = append(b, 8)
+	b = append(b, randomBytes(8)...) // SCID
+	return b[:quicMinDatagram]
+}
+
+func randomBytes(n int) []byte {
+	b := make([]byte, n)
+	rand.Read(b)
+	return b
+}
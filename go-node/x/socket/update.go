@@ -0,0 +1,439 @@
+package socket
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-gost/x/internal/util/release"
+	"github.com/go-gost/x/service"
+)
+
+// ReleasePublicKey is the base64 Ed25519 key release manifests are signed
+// with, set at build time:
+//
+//	-ldflags "-X github.com/go-gost/x/socket.ReleasePublicKey=<base64>"
+//
+// Nodes built without it refuse every update: a key offered by the panel
+// could be the attacker's own.
+var ReleasePublicKey = ""
+
+const (
+	updateStateFile = "update.json" // swapped-in binary waiting for a healthy reconnect
+
+	// A new binary has this long to reach the panel before it's rolled back
+	updateConfirmTimeout = 3 * time.Minute
+	// Starts allowed before a binary that keeps dying is rolled back
+	maxUpdateStarts = 3
+
+	maxBinarySize = 256 * 1024 * 1024
+)
+
+// releaseManifest describes one signed node binary, as served by the panel
+// at /u/m/<arch>.
+type releaseManifest struct {
+	Version   string `json:"version"`
+	Arch      string `json:"arch"`
+	Size      int64  `json:"size"`
+	SHA256    string `json:"sha256"`
+	Signature string `json:"signature"` // base64 Ed25519 over signedMessage()
+}
+
+// signedMessage must stay byte-identical to the panel's release signer.
+func (m *releaseManifest) signedMessage() []byte {
+	return []byte(fmt.Sprintf("flux-node-release:v1\n%s\n%s\n%d\n%s",
+		m.Version, m.Arch, m.Size, strings.ToLower(m.SHA256)))
+}
+
+func (m *releaseManifest) verify(key ed25519.PublicKey) error {
+	sig, err := base64.StdEncoding.DecodeString(m.Signature)
+	if err != nil || len(sig) != ed25519.SignatureSize {
+		return fmt.Errorf("发布清单签名格式无效")
+	}
+	if !ed25519.Verify(key, m.signedMessage(), sig) {
+		return fmt.Errorf("发布清单签名校验失败")
+	}
+	if m.Arch != runtime.GOARCH {
+		return fmt.Errorf("发布清单架构不匹配: %s (本机 %s)", m.Arch, runtime.GOARCH)
+	}
+	if m.Size <= 0 || m.Size > maxBinarySize {
+		return fmt.Errorf("发布清单文件大小异常: %d", m.Size)
+	}
+	return nil
+}
+
+func parseReleaseKey(s string) (ed25519.PublicKey, error) {
+	b, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil || len(b) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("发布公钥格式无效")
+	}
+	return ed25519.PublicKey(b), nil
+}
+
+// trustedReleaseKey returns the key embedded at build time.
+func trustedReleaseKey() (ed25519.PublicKey, error) {
+	if ReleasePublicKey == "" {
+		return nil, fmt.Errorf("节点构建时未内嵌发布公钥，拒绝更新，请使用官方构建重新安装")
+	}
+	return parseReleaseKey(ReleasePublicKey)
+}
+
+// fetchReleaseManifest returns nil, nil when the panel has no manifest.
+func fetchReleaseManifest(client *http.Client, url string) (*releaseManifest, error) {
+	req, err := service.NewSignedGet(url)
+	if err != nil {
+		return nil, fmt.Errorf("下载发布清单失败: %v", err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("下载发布清单失败: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("下载发布清单失败，状态码: %d", resp.StatusCode)
+	}
+	var m releaseManifest
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 64*1024)).Decode(&m); err != nil {
+		return nil, fmt.Errorf("解析发布清单失败: %v", err)
+	}
+	return &m, nil
+}
+
+// updateState is written next to config.json when a new binary is swapped
+// in and removed once it has reconnected to the panel.
+type updateState struct {
+	Binary  string `json:"binary"`
+	Backup  string `json:"backup"`
+	Persist string `json:"persist,omitempty"` // Docker: copy restored by the entrypoint
+	From    string `json:"from"`
+	To      string `json:"to"`
+	Starts  int    `json:"starts"`
+}
+
+func readUpdateState() *updateState {
+	b, err := os.ReadFile(updateStateFile)
+	if err != nil {
+		return nil
+	}
+	var st updateState
+	if err := json.Unmarshal(b, &st); err != nil || st.Binary == "" || st.Backup == "" {
+		os.Remove(updateStateFile)
+		return nil
+	}
+	return &st
+}
+
+func writeUpdateState(st *updateState) error {
+	b, err := json.Marshal(st)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(updateStateFile, b, 0600)
+}
+
+var (
+	pendingUpdateMu sync.Mutex
+	pendingUpdate   *updateState
+)
+
+// CheckPendingUpdate runs once at startup. If the last process swapped in a
+// new binary, this one is on probation: it has updateConfirmTimeout to
+// reach the panel, and a binary that keeps dying before it does is rolled
+// back after maxUpdateStarts starts.
+func CheckPendingUpdate() {
+	st := readUpdateState()
+	if st == nil {
+		return
+	}
+	st.Starts++
+	if st.Starts > maxUpdateStarts {
+		fmt.Printf("❌ 新版本 %s 启动 %d 次仍未连上面板，回滚到 %s\n", st.To, maxUpdateStarts, st.From)
+		rollbackAndExit(st)
+		return
+	}
+	if err := writeUpdateState(st); err != nil {
+		fmt.Printf("⚠️ 更新状态写入失败: %v\n", err)
+	}
+
+	pendingUpdateMu.Lock()
+	pendingUpdate = st
+	pendingUpdateMu.Unlock()
+	fmt.Printf("🕒 新版本 %s 试运行中，%v 内未连上面板将自动回滚\n", st.To, updateConfirmTimeout)
+
+	go func() {
+		time.Sleep(updateConfirmTimeout)
+		pendingUpdateMu.Lock()
+		st := pendingUpdate
+		pendingUpdate = nil
+		pendingUpdateMu.Unlock()
+		if st != nil {
+			fmt.Printf("❌ 新版本 %s 未能在 %v 内连上面板，回滚到 %s\n", st.To, updateConfirmTimeout, st.From)
+			rollbackAndExit(st)
+		}
+	}()
+}
+
+// confirmPendingUpdate is called on every successful panel connection.
+func confirmPendingUpdate() {
+	pendingUpdateMu.Lock()
+	st := pendingUpdate
+	pendingUpdate = nil
+	pendingUpdateMu.Unlock()
+	if st == nil {
+		return
+	}
+	os.Remove(updateStateFile)
+	fmt.Printf("✅ 新版本 %s 已连上面板，更新确认\n", st.To)
+}
+
+// restoreBackup copies the backup binary back over the current one.
+func restoreBackup(st *updateState) error {
+	if _, err := os.Stat(st.Backup); err != nil {
+		return fmt.Errorf("备份文件不存在: %v", err)
+	}
+	os.Remove(st.Binary)
+	if err := copyFileForUpdate(st.Backup, st.Binary); err != nil {
+		return fmt.Errorf("恢复备份失败: %v", err)
+	}
+	os.Chmod(st.Binary, 0755)
+	if st.Persist != "" {
+		if err := copyFileForUpdate(st.Backup, st.Persist); err != nil {
+			fmt.Printf("⚠️ Docker 持久化回滚失败: %v\n", err)
+		} else {
+			os.Chmod(st.Persist, 0755)
+		}
+	}
+	return nil
+}
+
+// rollbackAndExit exits non-zero either way so the service manager
+// (systemd Restart=on-failure, Docker restart policy) starts the restored
+// binary.
+func rollbackAndExit(st *updateState) {
+	if err := restoreBackup(st); err != nil {
+		fmt.Printf("❌ 回滚失败: %v\n", err)
+	} else {
+		fmt.Printf("📦 已回滚到 %s\n", st.Backup)
+	}
+	os.Remove(updateStateFile)
+	os.Exit(1)
+}
+
+// currentBinaryPaths returns the running binary, its backup and, in Docker,
+// the persisted copy in the working directory.
+func currentBinaryPaths() (binary, backup, persist string, err error) {
+	binary, err = os.Executable()
+	if err != nil {
+		return "", "", "", fmt.Errorf("获取当前二进制路径失败: %v", err)
+	}
+	// 解析软链接得到真实路径
+	binary, _ = filepath.EvalSymlinks(binary)
+	if _, err := os.Stat("/.dockerenv"); err == nil {
+		persist = filepath.Join(".", filepath.Base(binary))
+	}
+	return binary, binary + ".bak", persist, nil
+}
+
+func (w *WebSocketReporter) handleNodeUpdateBinary(data interface{}) error {
+	if !atomic.CompareAndSwapInt32(&w.updating, 0, 1) {
+		return fmt.Errorf("节点正在更新中，请勿重复操作")
+	}
+	defer atomic.StoreInt32(&w.updating, 0)
+
+	var req struct {
+		Version string `json:"version"` // expected release version, if the panel knows it
+	}
+	if data != nil {
+		if b, err := json.Marshal(data); err == nil {
+			json.Unmarshal(b, &req)
+		}
+	}
+
+	// 使用节点自身 config.json 中的 addr 构建下载地址，
+	// 该地址是节点已经成功连接 WebSocket 的地址，保证可达，
+	// 避免面板端 panelAddr 可能指向 Cloudflare 代理域名导致下载失败。
+	// 下载请求与上报一样签名，密钥不出现在 URL 中。
+	scheme := "http"
+	if w.useTLS {
+		scheme = "https"
+	}
+	base := fmt.Sprintf("%s://%s/u", scheme, w.addr)
+	httpClient := &http.Client{Timeout: 5 * time.Minute}
+
+	// 1. 获取并校验发布清单
+	key, err := trustedReleaseKey()
+	if err != nil {
+		return err
+	}
+	manifest, err := fetchReleaseManifest(httpClient, base+"/m/"+runtime.GOARCH)
+	if err != nil {
+		return err
+	}
+	if manifest == nil {
+		return fmt.Errorf("面板未提供签名的发布清单，拒绝更新")
+	}
+	if err := manifest.verify(key); err != nil {
+		return err
+	}
+	if req.Version != "" && manifest.Version != req.Version {
+		return fmt.Errorf("发布清单版本 %s 与预期版本 %s 不一致", manifest.Version, req.Version)
+	}
+	if err := release.CheckUpgrade(w.version, manifest.Version); err != nil {
+		return err
+	}
+	fmt.Printf("🔏 发布清单签名校验通过: %s (%s)\n", manifest.Version, manifest.Arch)
+
+	// 2. 下载到临时文件，同时计算 SHA-256
+	downloadURL := base + "/b/" + runtime.GOARCH
+	fmt.Printf("⬇️ 开始下载节点更新: %s\n", downloadURL)
+	dlReq, err := service.NewSignedGet(downloadURL)
+	if err != nil {
+		return fmt.Errorf("下载失败: %v", err)
+	}
+	resp, err := httpClient.Do(dlReq)
+	if err != nil {
+		return fmt.Errorf("下载失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("下载失败，状态码: %d", resp.StatusCode)
+	}
+
+	tmpFile, err := os.CreateTemp("", "svc-update-*")
+	if err != nil {
+		return fmt.Errorf("创建临时文件失败: %v", err)
+	}
+	tmpPath := tmpFile.Name()
+	defer os.Remove(tmpPath)
+
+	// 限制下载大小，防止恶意/异常响应耗尽磁盘
+	hasher := sha256.New()
+	written, err := io.Copy(io.MultiWriter(tmpFile, hasher), io.LimitReader(resp.Body, maxBinarySize+1))
+	tmpFile.Close()
+	if err != nil {
+		return fmt.Errorf("保存下载文件失败: %v", err)
+	}
+	if written > maxBinarySize {
+		return fmt.Errorf("下载文件过大 (%d bytes)", written)
+	}
+	if written < 1024 {
+		return fmt.Errorf("下载文件异常 (%d bytes)，文件过小", written)
+	}
+	if written != manifest.Size {
+		return fmt.Errorf("文件大小与发布清单不符: %d != %d", written, manifest.Size)
+	}
+	if sum := hex.EncodeToString(hasher.Sum(nil)); !strings.EqualFold(sum, manifest.SHA256) {
+		return fmt.Errorf("SHA-256 校验失败: %s", sum)
+	}
+
+	// 3. 备份旧二进制（回滚依赖备份，失败则中止）
+	currentBinary, backupPath, persistPath, err := currentBinaryPaths()
+	if err != nil {
+		return err
+	}
+	if err := copyFileForUpdate(currentBinary, backupPath); err != nil {
+		return fmt.Errorf("备份旧二进制失败: %v", err)
+	}
+	fmt.Printf("📦 已备份旧二进制到 %s\n", backupPath)
+
+	// 4. 替换二进制（先删除旧文件再写入，避免 "text file busy"）
+	os.Remove(currentBinary)
+	if err := copyFileForUpdate(tmpPath, currentBinary); err != nil {
+		// 尝试从备份恢复
+		if restoreErr := copyFileForUpdate(backupPath, currentBinary); restoreErr != nil {
+			fmt.Printf("❌ 恢复备份也失败: %v\n", restoreErr)
+		} else {
+			os.Chmod(currentBinary, 0755)
+			fmt.Printf("📦 已从备份恢复\n")
+		}
+		return fmt.Errorf("替换二进制失败: %v", err)
+	}
+	os.Chmod(currentBinary, 0755)
+
+	// 5. Docker 持久化：如果是 Docker 环境，保存到工作目录
+	if persistPath != "" {
+		if err := copyFileForUpdate(currentBinary, persistPath); err != nil {
+			fmt.Printf("⚠️ Docker 持久化失败: %v\n", err)
+		} else {
+			os.Chmod(persistPath, 0755)
+			fmt.Printf("📦 已持久化到 %s\n", persistPath)
+		}
+	}
+
+	// 6. 记录试运行状态，新进程连上面板后确认，否则回滚
+	st := &updateState{Binary: currentBinary, Backup: backupPath, Persist: persistPath, From: w.version, To: manifest.Version}
+	if err := writeUpdateState(st); err != nil {
+		fmt.Printf("⚠️ 更新状态写入失败，新版本将不会自动回滚: %v\n", err)
+	}
+
+	fmt.Printf("✅ 节点更新完成 (%d bytes)，正在退出进程...\n", written)
+	// 7. 延迟退出，确保响应先发送回面板；非零退出码让 systemd
+	// (Restart=on-failure) 和 Docker 都会拉起新版本
+	go func() {
+		time.Sleep(1 * time.Second)
+		os.Exit(1)
+	}()
+
+	return nil
+}
+
+// handleNodeRollbackBinary puts the backup from the last update back.
+func (w *WebSocketReporter) handleNodeRollbackBinary() error {
+	if !atomic.CompareAndSwapInt32(&w.updating, 0, 1) {
+		return fmt.Errorf("节点正在更新中，请勿重复操作")
+	}
+	defer atomic.StoreInt32(&w.updating, 0)
+
+	currentBinary, backupPath, persistPath, err := currentBinaryPaths()
+	if err != nil {
+		return err
+	}
+	st := &updateState{Binary: currentBinary, Backup: backupPath, Persist: persistPath}
+	if err := restoreBackup(st); err != nil {
+		return err
+	}
+	os.Remove(updateStateFile)
+
+	fmt.Printf("📦 已回滚到 %s，正在退出进程...\n", backupPath)
+	go func() {
+		time.Sleep(1 * time.Second)
+		os.Exit(1)
+	}()
+	return nil
+}
+
+// copyFileForUpdate copies a file from src to dst (used by node self-update)
+func copyFileForUpdate(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	if _, err := io.Copy(out, in); err != nil {
+		return err
+	}
+	return out.Sync()
+}
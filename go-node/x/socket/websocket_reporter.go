@@ -6,23 +6,21 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net"
 	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
-	"runtime"
 	"strconv"
 	"strings"
 	"sync"
-	"sync/atomic"
 	"time"
 
 	"github.com/go-gost/x/config"
 	"github.com/go-gost/x/internal/util/crypto"
 	"github.com/go-gost/x/registry"
 	"github.com/go-gost/x/service"
+	"github.com/go-gost/x/singbox"
 	"github.com/go-gost/x/xray"
 	"github.com/gorilla/websocket"
 	"github.com/shirou/gopsutil/v3/cpu"
@@ -39,6 +37,8 @@ type NetInterface struct {
 
 // SystemInfo 系统信息结构体
 type SystemInfo struct {
+	Kind             string         `json:"kind"`              // 消息类型，固定为 metrics
+	V                int            `json:"v"`                 // 协议版本
 	Uptime           uint64         `json:"uptime"`            // 开机时间	（秒）
 	BytesReceived    uint64         `json:"bytes_received"`    // 接收字节数
 	BytesTransmitted uint64         `json:"bytes_transmitted"` // 发送字节数
@@ -49,6 +49,17 @@ type SystemInfo struct {
 	Interfaces       []NetInterface `json:"interfaces"`        // 网卡列表
 	PanelAddr        string         `json:"panel_addr"`        // 连接的面板地址
 	Runtime          string         `json:"runtime"`           // 运行环境: docker / host
+
+	DiskUsage    float64           `json:"disk_usage"`              // 根分区使用率（百分比）
+	Disks        []DiskInfo        `json:"disks"`                   // 分区列表
+	Load1        float64           `json:"load1"`                   // 系统负载
+	Load5        float64           `json:"load5"`
+	Load15       float64           `json:"load15"`
+	NetCounters  []NetCounter      `json:"net_counters"`            // 各网卡累计流量
+	GostConns    uint64            `json:"gost_conns"`              // 转发服务当前连接总数
+	ServiceConns map[string]uint64 `json:"service_conns,omitempty"` // 各转发服务当前连接数
+	SocketStats
+	HostInfo
 }
 
 // NetworkStats 网络统计信息
@@ -69,6 +80,8 @@ type MemoryInfo struct {
 
 // CommandMessage 命令消息结构体
 type CommandMessage struct {
+	Kind      string      `json:"kind,omitempty"`
+	V         int         `json:"v,omitempty"`
 	Type      string      `json:"type"`
 	Data      interface{} `json:"data"`
 	RequestId string      `json:"requestId,omitempty"`
@@ -76,6 +89,8 @@ type CommandMessage struct {
 
 // CommandResponse 命令响应结构体
 type CommandResponse struct {
+	Kind      string      `json:"kind,omitempty"`
+	V         int         `json:"v,omitempty"`
 	Type      string      `json:"type"`
 	Success   bool        `json:"success"`
 	Message   string      `json:"message"`
@@ -123,7 +138,11 @@ type WebSocketReporter struct {
 	aesCrypto      *crypto.AESCrypto // 新增：AES加密器
 	xrayManager    *xray.XrayManager        // Xray 进程管理
 	xrayTraffic    *xray.TrafficReporter    // Xray 流量上报
+	singboxManager *singbox.Manager         // sing-box 进程管理 (Hysteria2/TUIC)
+	singboxTraffic *xray.TrafficReporter    // sing-box 流量上报
 	updating       int32                    // 原子标记：节点更新中
+	session        *sessionCipher           // 握手认证后的会话密钥，旧方式连接时为 nil
+	authV2Seen     bool                     // 面板支持握手认证后不再退回旧方式
 }
 
 // NewWebSocketReporter 创建一个新的WebSocket报告器
@@ -224,38 +243,85 @@ func (w *WebSocketReporter) connect() error {
 
 	// 重新读取 config.json 获取最新的协议配置
 	type LocalConfig struct {
-		Addr   string `json:"addr"`
-		Secret string `json:"secret"`
-		Http   int    `json:"http"`
-		Tls    int    `json:"tls"`
-		Socks  int    `json:"socks"`
+		Addr              string `json:"addr"`
+		Secret            string `json:"secret"`
+		Http              int    `json:"http"`
+		Tls               int    `json:"tls"`
+		Socks             int    `json:"socks"`
+		PrevSecret        string `json:"prev_secret"`
+		PrevSecretExpires int64  `json:"prev_secret_expires"`
+		AuthV2            bool   `json:"auth_v2"` // 曾完成握手认证，此后不再退回旧方式
 	}
 
 	var cfg LocalConfig
 	if b, err := os.ReadFile("config.json"); err == nil {
 		json.Unmarshal(b, &cfg)
 	}
+	if cfg.AuthV2 {
+		w.authV2Seen = true
+	}
+	// 密钥轮换后旧密钥在宽限期内仍可用于连接
+	secrets := w.authSecrets(cfg.PrevSecret, cfg.PrevSecretExpires)
 
-	// 使用最新的配置重新构建 URL
+	// 使用最新的配置重新构建 URL，密钥不再出现在 URL 中
 	wsScheme := "ws"
 	if w.useTLS {
 		wsScheme = "wss"
 	}
-	currentURL := wsScheme + "://" + w.addr + "/system-info?type=1&secret=" + w.secret + "&nodeVersion=" + w.version +
+	baseURL := wsScheme + "://" + w.addr + "/system-info?type=1&"
+	query := "nodeVersion=" + url.QueryEscape(w.version) +
 		"&http=" + strconv.Itoa(cfg.Http) + "&tls=" + strconv.Itoa(cfg.Tls) + "&socks=" + strconv.Itoa(cfg.Socks)
 
-	u, err := url.Parse(currentURL)
-	if err != nil {
-		return fmt.Errorf("解析URL失败: %v", err)
-	}
-
 	dialer := websocket.DefaultDialer
 	dialer.HandshakeTimeout = 10 * time.Second
 
-	conn, _, err := dialer.Dial(u.String(), nil)
+	var (
+		conn    *websocket.Conn
+		resp    *http.Response
+		session *sessionCipher
+		err     error
+	)
+	usedSecret := ""
+	for _, secret := range secrets {
+		conn, resp, err = dialer.Dial(baseURL+"auth="+nodeAuthVersion+"&"+query, nil)
+		if err != nil {
+			break
+		}
+		if session, err = authenticate(conn, secret); err == nil {
+			usedSecret = secret
+			w.authV2Seen = true
+			break
+		}
+		conn.Close()
+		err = fmt.Errorf("节点认证失败: %v", err)
+	}
+	if usedSecret == "" && resp != nil && resp.StatusCode == http.StatusUnauthorized && !w.authV2Seen && legacyAuthAllowed() {
+		// 旧面板不支持握手认证
+		fmt.Printf("⚠️ 面板不支持握手认证，使用旧方式连接\n")
+		for _, secret := range secrets {
+			if conn, _, err = dialer.Dial(baseURL+"secret="+url.QueryEscape(secret)+"&"+query, nil); err == nil {
+				usedSecret = secret
+				if c, e := crypto.NewAESCrypto(secret); e == nil {
+					w.aesCrypto = c
+				}
+				break
+			}
+		}
+	}
 	if err != nil {
 		return fmt.Errorf("连接WebSocket失败: %v", err)
 	}
+	// 只有旧方式连接时，上报请求才额外带上明文密钥
+	service.SetReportLegacyAuth(session == nil)
+	if session != nil && !cfg.AuthV2 {
+		// 记住面板支持握手认证，重启后也不会被降级到旧方式
+		if err := updateConfigJSON(func(m map[string]interface{}) { m["auth_v2"] = true }); err != nil {
+			fmt.Printf("⚠️ 保存握手认证状态失败: %v\n", err)
+		}
+	}
+	if usedSecret != w.secret {
+		fmt.Printf("⚠️ 当前密钥未被面板接受，使用宽限期内的旧密钥连接\n")
+	}
 
 	// 如果在连接过程中已经有连接了，关闭新连接
 	if w.conn != nil && w.connected {
@@ -264,6 +330,7 @@ func (w *WebSocketReporter) connect() error {
 	}
 
 	w.conn = conn
+	w.session = session
 	w.connected = true
 
 	// 设置关闭处理器来检测连接状态
@@ -275,6 +342,7 @@ func (w *WebSocketReporter) connect() error {
 	})
 
 	fmt.Printf("✅ WebSocket连接建立成功 (http=%d, tls=%d, socks=%d)\n", cfg.Http, cfg.Tls, cfg.Socks)
+	confirmPendingUpdate()
 	return nil
 }
 
@@ -329,13 +397,22 @@ func (w *WebSocketReporter) collectSystemInfo() SystemInfo {
 	memoryInfo := getMemoryInfo()
 
 	info := SystemInfo{
+		Kind:             msgKindMetrics,
+		V:                protocolVersion,
 		Uptime:           getUptime(),
 		BytesReceived:    networkStats.BytesReceived,
 		BytesTransmitted: networkStats.BytesTransmitted,
 		CPUUsage:         cpuInfo.Usage,
 		MemoryUsage:      memoryInfo.Usage,
 		Interfaces:       getInterfaces(),
+		Disks:            getDisks(),
+		NetCounters:      getNetCounters(),
+		SocketStats:      getSocketStats(),
+		HostInfo:         getHostInfo(),
 	}
+	info.DiskUsage = rootDiskUsage(info.Disks)
+	info.Load1, info.Load5, info.Load15 = getLoadAvg()
+	info.GostConns, info.ServiceConns = getServiceConns()
 
 	// Detect runtime environment
 	if _, err := os.Stat("/.dockerenv"); err == nil {
@@ -378,7 +455,11 @@ func (w *WebSocketReporter) sendSystemInfo(sysInfo SystemInfo) error {
 	var messageData []byte
 
 	// 如果有加密器，则加密数据
-	if w.aesCrypto != nil {
+	if w.session != nil {
+		if messageData, err = w.session.seal(jsonData); err != nil {
+			return fmt.Errorf("加密系统信息失败: %v", err)
+		}
+	} else if w.aesCrypto != nil {
 		encryptedData, err := w.aesCrypto.Encrypt(jsonData)
 		if err != nil {
 			fmt.Printf("⚠️ 加密失败，发送原始数据: %v\n", err)
@@ -421,6 +502,7 @@ func (w *WebSocketReporter) receiveMessages() {
 			w.connMutex.Lock()
 			conn := w.conn
 			connected := w.connected
+			session := w.session
 			w.connMutex.Unlock()
 
 			if conn == nil || !connected {
@@ -442,15 +524,25 @@ func (w *WebSocketReporter) receiveMessages() {
 			}
 
 			// 处理接收到的消息
-			w.handleReceivedMessage(messageType, message)
+			w.handleReceivedMessage(messageType, message, session)
 		}
 	}
 }
 
 // handleReceivedMessage 处理接收到的消息
-func (w *WebSocketReporter) handleReceivedMessage(messageType int, message []byte) {
+func (w *WebSocketReporter) handleReceivedMessage(messageType int, message []byte, session *sessionCipher) {
 	switch messageType {
 	case websocket.TextMessage:
+		// 握手认证的会话只接受带序号的加密消息
+		if session != nil {
+			plaintext, err := session.open(message)
+			if err != nil {
+				fmt.Printf("❌ 消息被拒绝: %v\n", err)
+				return
+			}
+			message = plaintext
+		}
+
 		// 先检查是否是加密消息
 		var encryptedWrapper struct {
 			Encrypted bool   `json:"encrypted"`
@@ -459,7 +551,7 @@ func (w *WebSocketReporter) handleReceivedMessage(messageType int, message []byt
 		}
 
 		// 尝试解析为加密消息格式
-		if err := json.Unmarshal(message, &encryptedWrapper); err == nil && encryptedWrapper.Encrypted {
+		if err := json.Unmarshal(message, &encryptedWrapper); session == nil && err == nil && encryptedWrapper.Encrypted {
 			if w.aesCrypto != nil {
 				// 解密数据
 				decryptedData, err := w.aesCrypto.Decrypt(encryptedWrapper.Data)
@@ -527,6 +619,10 @@ func (w *WebSocketReporter) handleReceivedMessage(messageType int, message []byt
 				w.sendErrorResponse("ParseError", fmt.Sprintf("解析命令失败: %v", err))
 				return
 			}
+			if cmdMsg.Kind == msgKindHello {
+				w.handlePanelHello(cmdMsg.Data)
+				return
+			}
 			if cmdMsg.Type != "call" {
 				w.routeCommand(cmdMsg)
 			}
@@ -602,6 +698,12 @@ func (w *WebSocketReporter) routeCommand(cmd CommandMessage) {
 		response.Type = "TcpPingResponse"
 		response.Data = tcpPingResult
 
+	// UDP 探测诊断命令
+	case "UdpProbe":
+		response.Type = "UdpProbeResponse"
+		w.runAsync(response, func() (interface{}, error) { return w.handleUdpProbe(cmd.Data) })
+		return
+
 	// Protocol blocking switches
 	case "SetProtocol":
 		err = w.handleSetProtocol(cmd.Data)
@@ -634,6 +736,12 @@ func (w *WebSocketReporter) routeCommand(cmd CommandMessage) {
 	case "VRemoveClient":
 		err = w.handleXrayRemoveClient(cmd.Data)
 		response.Type = "VRemoveClientResponse"
+	case "VAddClients":
+		err = w.handleXrayAddClients(cmd.Data)
+		response.Type = "VAddClientsResponse"
+	case "VRemoveClients":
+		err = w.handleXrayRemoveClients(cmd.Data)
+		response.Type = "VRemoveClientsResponse"
 	case "VGetTraffic":
 		var trafficData interface{}
 		trafficData, err = w.handleXrayGetTraffic(cmd.Data)
@@ -642,6 +750,9 @@ func (w *WebSocketReporter) routeCommand(cmd CommandMessage) {
 	case "VApplyConfig":
 		err = w.handleXrayApplyConfig(cmd.Data)
 		response.Type = "VApplyConfigResponse"
+	case "VValidateConfig":
+		err = w.handleXrayValidateConfig(cmd.Data)
+		response.Type = "VValidateConfigResponse"
 	case "VDeployCert":
 		err = w.handleXrayDeployCert(cmd.Data)
 		response.Type = "VDeployCertResponse"
@@ -649,6 +760,33 @@ func (w *WebSocketReporter) routeCommand(cmd CommandMessage) {
 		err = w.handleXraySwitchVersion(cmd.Data)
 		response.Type = "VSwitchVersionResponse"
 
+	// sing-box commands (Hysteria2/TUIC). The V* inbound/client commands
+	// above are routed here automatically by protocol or tag.
+	case "SBStart":
+		err = w.getOrInitSingboxManager().Start()
+		response.Type = "SBStartResponse"
+	case "SBStop":
+		err = w.getOrInitSingboxManager().Stop()
+		response.Type = "SBStopResponse"
+	case "SBRestart":
+		err = w.getOrInitSingboxManager().Restart()
+		response.Type = "SBRestartResponse"
+	case "SBStatus":
+		response.Data = w.handleSingboxStatus()
+		response.Type = "SBStatusResponse"
+	case "SBApplyConfig":
+		err = w.handleSingboxApplyConfig(cmd.Data)
+		response.Type = "SBApplyConfigResponse"
+	case "SBAddClient":
+		err = w.handleXrayAddClient(cmd.Data)
+		response.Type = "SBAddClientResponse"
+	case "SBRemoveClient":
+		err = w.handleXrayRemoveClient(cmd.Data)
+		response.Type = "SBRemoveClientResponse"
+	case "SBSwitchVersion":
+		err = w.handleSingboxSwitchVersion(cmd.Data)
+		response.Type = "SBSwitchVersionResponse"
+
 	case "GetServiceNames":
 		names := make([]string, 0)
 		for name := range registry.ServiceRegistry().GetAll() {
@@ -660,10 +798,11 @@ func (w *WebSocketReporter) routeCommand(cmd CommandMessage) {
 	case "VGetInboundTags":
 		mgr := w.getOrInitXrayManager()
 		tags, e := mgr.GetInboundTags()
-		if e != nil {
+		sbTags := w.getOrInitSingboxManager().Tags()
+		if e != nil && len(sbTags) == 0 {
 			err = e
 		} else {
-			response.Data = map[string]interface{}{"tags": tags}
+			response.Data = map[string]interface{}{"tags": append(tags, sbTags...)}
 		}
 		response.Type = "VGetInboundTagsResponse"
 
@@ -671,6 +810,29 @@ func (w *WebSocketReporter) routeCommand(cmd CommandMessage) {
 		err = w.handleNodeUpdateBinary(cmd.Data)
 		response.Type = "NodeUpdateBinaryResponse"
 
+	case "NodeRollbackBinary":
+		err = w.handleNodeRollbackBinary()
+		response.Type = "NodeRollbackBinaryResponse"
+
+	case "RotateSecret":
+		err = w.handleRotateSecret(cmd.Data)
+		response.Type = "RotateSecretResponse"
+
+	// 节点间带宽测试
+	case "BandwidthServer":
+		response.Data, err = w.handleBandwidthServer(cmd.Data)
+		response.Type = "BandwidthServerResponse"
+	case "BandwidthTest":
+		response.Type = "BandwidthTestResponse"
+		w.runAsync(response, func() (interface{}, error) { return w.handleBandwidthTest(cmd.Data) })
+		return
+
+	// 路由追踪
+	case "Traceroute":
+		response.Type = "TracerouteResponse"
+		w.runAsync(response, func() (interface{}, error) { return w.handleTraceroute(cmd.Data) })
+		return
+
 	default:
 		err = fmt.Errorf("未知命令类型: %s", cmd.Type)
 		response.Type = "UnknownCommandResponse"
@@ -690,6 +852,22 @@ func (w *WebSocketReporter) routeCommand(cmd CommandMessage) {
 	w.sendResponse(response)
 }
 
+// runAsync 在后台执行耗时数秒的命令并单独发送响应，避免阻塞消息读取
+func (w *WebSocketReporter) runAsync(response CommandResponse, fn func() (interface{}, error)) {
+	go func() {
+		data, err := fn()
+		response.Data = data
+		if err != nil {
+			response.Success = false
+			response.Message = err.Error()
+		} else {
+			response.Success = true
+			response.Message = "OK"
+		}
+		w.sendResponse(response)
+	}()
+}
+
 // Service 命令处理函数
 func (w *WebSocketReporter) handleAddService(data interface{}) error {
 	// 将 interface{} 转换为 JSON 再解析为具体类型
@@ -983,32 +1161,11 @@ func (w *WebSocketReporter) handleSetProtocol(data interface{}) error {
 
 // updateLocalConfigJSON 将 http/tls/socks 写入工作目录下的 config.json
 func updateLocalConfigJSON(httpVal int, tlsVal int, socksVal int) error {
-	path := "config.json"
-
-	// 读取现有配置
-	type LocalConfig struct {
-		Addr   string `json:"addr"`
-		Secret string `json:"secret"`
-		Http   int    `json:"http"`
-		Tls    int    `json:"tls"`
-		Socks  int    `json:"socks"`
-	}
-
-	var cfg LocalConfig
-	if b, err := os.ReadFile(path); err == nil {
-		_ = json.Unmarshal(b, &cfg)
-	}
-
-	cfg.Http = httpVal
-	cfg.Tls = tlsVal
-	cfg.Socks = socksVal
-
-	// 写回
-	data, err := json.MarshalIndent(cfg, "", "  ")
-	if err != nil {
-		return err
-	}
-	return os.WriteFile(path, data, 0644)
+	return updateConfigJSON(func(cfg map[string]interface{}) {
+		cfg["http"] = httpVal
+		cfg["tls"] = tlsVal
+		cfg["socks"] = socksVal
+	})
 }
 
 // InitXray initializes the Xray manager for this reporter
@@ -1018,15 +1175,14 @@ func (w *WebSocketReporter) InitXray(binaryPath, configPath, grpcAddr string) {
 }
 
 // StartXrayTrafficReporter starts the Xray traffic reporter
-func (w *WebSocketReporter) StartXrayTrafficReporter(panelAddr string) {
+func (w *WebSocketReporter) StartXrayTrafficReporter() {
 	mgr := w.getOrInitXrayManager()
 	w.xrayTraffic = xray.NewTrafficReporter(
 		mgr.GetGrpcAddr(),
-		panelAddr,
-		w.secret,
 		mgr.GetBinaryPath(),
-		w.useTLS,
+		service.FlowSpool(),
 	)
+	w.xrayTraffic.TrackOnlineIPs(mgr.AccessLogPath(), service.ReportOnlineIPs)
 	w.xrayTraffic.Start()
 	fmt.Printf("📊 Traffic reporter started\n")
 }
@@ -1081,6 +1237,9 @@ func (w *WebSocketReporter) handleXrayAddInbound(data interface{}) error {
 		return fmt.Errorf("解析入站配置失败: %v", err)
 	}
 
+	if singbox.IsProtocol(inbound.Protocol) {
+		return w.getOrInitSingboxManager().AddInbound(inbound)
+	}
 	mgr := w.getOrInitXrayManager()
 	return mgr.HotAddInbound(inbound)
 }
@@ -1098,6 +1257,9 @@ func (w *WebSocketReporter) handleXrayRemoveInbound(data interface{}) error {
 		return fmt.Errorf("解析删除入站请求失败: %v", err)
 	}
 
+	if sb := w.getOrInitSingboxManager(); sb.Owns(req.Tag) {
+		return sb.RemoveInbound(req.Tag)
+	}
 	mgr := w.getOrInitXrayManager()
 	return mgr.HotRemoveInbound(req.Tag)
 }
@@ -1120,6 +1282,9 @@ func (w *WebSocketReporter) handleXrayAddClient(data interface{}) error {
 		return fmt.Errorf("解析添加客户端请求失败: %v", err)
 	}
 
+	if sb := w.getOrInitSingboxManager(); singbox.IsProtocol(req.Protocol) || sb.Owns(req.InboundTag) {
+		return sb.AddUser(req.InboundTag, req.Email, req.UuidOrPassword, req.Protocol)
+	}
 	mgr := w.getOrInitXrayManager()
 	return mgr.HotAddUser(req.InboundTag, req.Email, req.UuidOrPassword, req.Flow, req.Protocol, req.AlterId)
 }
@@ -1138,10 +1303,69 @@ func (w *WebSocketReporter) handleXrayRemoveClient(data interface{}) error {
 		return fmt.Errorf("解析删除客户端请求失败: %v", err)
 	}
 
+	if sb := w.getOrInitSingboxManager(); sb.Owns(req.InboundTag) {
+		return sb.RemoveUser(req.InboundTag, req.Email)
+	}
 	mgr := w.getOrInitXrayManager()
 	return mgr.HotRemoveUser(req.InboundTag, req.Email)
 }
 
+// handleXrayAddClients adds many clients to one inbound in a single batch.
+func (w *WebSocketReporter) handleXrayAddClients(data interface{}) error {
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("序列化数据失败: %v", err)
+	}
+
+	var req struct {
+		InboundTag string `json:"inboundTag"`
+		Protocol   string `json:"protocol"`
+		Clients    []struct {
+			Email          string `json:"email"`
+			UuidOrPassword string `json:"uuidOrPassword"`
+			Flow           string `json:"flow"`
+			AlterId        int    `json:"alterId"`
+		} `json:"clients"`
+	}
+	if err := json.Unmarshal(jsonData, &req); err != nil {
+		return fmt.Errorf("解析批量添加客户端请求失败: %v", err)
+	}
+
+	users := make([]xray.User, 0, len(req.Clients))
+	for _, c := range req.Clients {
+		users = append(users, xray.User{
+			Email: c.Email, UuidOrPassword: c.UuidOrPassword, Flow: c.Flow,
+			Protocol: req.Protocol, AlterId: c.AlterId,
+		})
+	}
+
+	if sb := w.getOrInitSingboxManager(); singbox.IsProtocol(req.Protocol) || sb.Owns(req.InboundTag) {
+		return sb.AddUsers(req.InboundTag, users)
+	}
+	return w.getOrInitXrayManager().HotAddUsers(req.InboundTag, users)
+}
+
+// handleXrayRemoveClients removes many clients from one inbound in a single batch.
+func (w *WebSocketReporter) handleXrayRemoveClients(data interface{}) error {
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("序列化数据失败: %v", err)
+	}
+
+	var req struct {
+		InboundTag string   `json:"inboundTag"`
+		Emails     []string `json:"emails"`
+	}
+	if err := json.Unmarshal(jsonData, &req); err != nil {
+		return fmt.Errorf("解析批量删除客户端请求失败: %v", err)
+	}
+
+	if sb := w.getOrInitSingboxManager(); sb.Owns(req.InboundTag) {
+		return sb.RemoveUsers(req.InboundTag, req.Emails)
+	}
+	return w.getOrInitXrayManager().HotRemoveUsers(req.InboundTag, req.Emails)
+}
+
 func (w *WebSocketReporter) handleXrayGetTraffic(data interface{}) (interface{}, error) {
 	mgr := w.getOrInitXrayManager()
 	grpcClient := xray.NewXrayGrpcClient(mgr.GetGrpcAddr())
@@ -1156,7 +1380,7 @@ func (w *WebSocketReporter) handleXrayGetTraffic(data interface{}) (interface{},
 	}, nil
 }
 
-func (w *WebSocketReporter) handleXrayApplyConfig(data interface{}) error {
+func (w *WebSocketReporter) handleXrayApplyConfig(data interface{}) (err error) {
 	jsonData, err := json.Marshal(data)
 	if err != nil {
 		return fmt.Errorf("序列化数据失败: %v", err)
@@ -1164,13 +1388,77 @@ func (w *WebSocketReporter) handleXrayApplyConfig(data interface{}) error {
 
 	var req struct {
 		Inbounds []xray.InboundConfig `json:"inbounds"`
+		Routing  *xray.RoutingConfig  `json:"routing"`
 	}
 	if err := json.Unmarshal(jsonData, &req); err != nil {
 		return fmt.Errorf("解析配置失败: %v", err)
 	}
 
+	// Hysteria2/TUIC inbounds go to sing-box, everything else to Xray
+	var xrayInbounds, sbInbounds []xray.InboundConfig
+	for _, ib := range req.Inbounds {
+		if singbox.IsProtocol(ib.Protocol) {
+			sbInbounds = append(sbInbounds, ib)
+		} else {
+			xrayInbounds = append(xrayInbounds, ib)
+		}
+	}
+
+	sb := w.getOrInitSingboxManager()
+	if err := sb.ApplyConfig(sbInbounds); err != nil {
+		return err
+	}
 	mgr := w.getOrInitXrayManager()
-	return mgr.ApplyConfig(req.Inbounds)
+	if req.Routing != nil {
+		// Panels that predate routing management omit it; keep the defaults then
+		prev := mgr.SetRouting(req.Routing)
+		defer func() {
+			if err != nil {
+				mgr.SetRouting(prev)
+			}
+		}()
+	}
+	if len(xrayInbounds) == 0 && len(sbInbounds) > 0 && !mgr.IsRunning() {
+		// sing-box-only node: don't start an idle Xray
+		return nil
+	}
+	return mgr.ApplyConfig(xrayInbounds)
+}
+
+// handleXrayValidateConfig dry-runs inbounds through the runtime that would
+// serve them, without touching the live config.
+func (w *WebSocketReporter) handleXrayValidateConfig(data interface{}) error {
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("序列化数据失败: %v", err)
+	}
+
+	var req struct {
+		Inbounds []xray.InboundConfig `json:"inbounds"`
+	}
+	if err := json.Unmarshal(jsonData, &req); err != nil {
+		return fmt.Errorf("解析配置失败: %v", err)
+	}
+
+	var xrayInbounds, sbInbounds []xray.InboundConfig
+	for _, ib := range req.Inbounds {
+		if singbox.IsProtocol(ib.Protocol) {
+			sbInbounds = append(sbInbounds, ib)
+		} else {
+			xrayInbounds = append(xrayInbounds, ib)
+		}
+	}
+	if len(sbInbounds) > 0 {
+		if err := w.getOrInitSingboxManager().TestConfig(sbInbounds); err != nil {
+			return err
+		}
+	}
+	if len(xrayInbounds) > 0 {
+		if err := w.getOrInitXrayManager().ValidateInbounds(xrayInbounds); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (w *WebSocketReporter) handleXrayDeployCert(data interface{}) error {
@@ -1236,133 +1524,84 @@ func (w *WebSocketReporter) handleXraySwitchVersion(data interface{}) error {
 	return nil
 }
 
-func (w *WebSocketReporter) handleNodeUpdateBinary(data interface{}) error {
-	if !atomic.CompareAndSwapInt32(&w.updating, 0, 1) {
-		return fmt.Errorf("节点正在更新中，请勿重复操作")
-	}
-	defer atomic.StoreInt32(&w.updating, 0)
-
-	// 使用节点自身 config.json 中的 addr 构建下载地址，
-	// 该地址是节点已经成功连接 WebSocket 的地址，保证可达，
-	// 避免面板端 panelAddr 可能指向 Cloudflare 代理域名导致下载失败。
-	scheme := "http"
-	if w.useTLS {
-		scheme = "https"
-	}
-	// Use camouflaged download URL if secret is available
-	downloadURL := fmt.Sprintf("%s://%s/s/%s/b/%s", scheme, w.addr, w.secret, runtime.GOARCH)
-	fmt.Printf("⬇️ 开始下载节点更新: %s\n", downloadURL)
+// StartSingboxTrafficReporter starts polling sing-box's stats API. Ticks are
+// skipped while sing-box isn't running or its build lacks the V2Ray API.
+func (w *WebSocketReporter) StartSingboxTrafficReporter() {
+	sb := w.getOrInitSingboxManager()
+	w.singboxTraffic = xray.NewTrafficReporterFrom(singbox.NewStatsClient(sb.GetAPIAddr()), service.FlowSpool())
+	w.singboxTraffic.SetActive(func() bool {
+		return sb.IsRunning() && sb.StatsEnabled()
+	})
+	w.singboxTraffic.Start()
+}
 
-	// 1. 下载到临时文件
-	httpClient := &http.Client{Timeout: 5 * time.Minute}
-	resp, err := httpClient.Get(downloadURL)
-	if err != nil {
-		return fmt.Errorf("下载失败: %v", err)
+func (w *WebSocketReporter) getOrInitSingboxManager() *singbox.Manager {
+	if w.singboxManager == nil {
+		cfg := w.xrayCfg
+		if cfg == "" {
+			cfg = "xray_config.json"
+		}
+		w.singboxManager = singbox.NewManager("sing-box", filepath.Join(filepath.Dir(cfg), "singbox_config.json"), "127.0.0.1:10086")
 	}
-	defer resp.Body.Close()
+	return w.singboxManager
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("下载失败，状态码: %d", resp.StatusCode)
+func (w *WebSocketReporter) handleSingboxStatus() map[string]interface{} {
+	sb := w.getOrInitSingboxManager()
+	return map[string]interface{}{
+		"running":      sb.IsRunning(),
+		"version":      sb.GetVersion(),
+		"statsEnabled": sb.StatsEnabled(),
+		"tags":         sb.Tags(),
 	}
+}
 
-	tmpFile, err := os.CreateTemp("", "svc-update-*")
+func (w *WebSocketReporter) handleSingboxApplyConfig(data interface{}) error {
+	jsonData, err := json.Marshal(data)
 	if err != nil {
-		return fmt.Errorf("创建临时文件失败: %v", err)
+		return fmt.Errorf("序列化数据失败: %v", err)
 	}
-	tmpPath := tmpFile.Name()
 
-	// 限制下载大小为 256MB，防止恶意/异常响应耗尽磁盘
-	const maxBinarySize = 256 * 1024 * 1024
-	written, err := io.Copy(tmpFile, io.LimitReader(resp.Body, maxBinarySize+1))
-	tmpFile.Close()
-	if err != nil {
-		os.Remove(tmpPath)
-		return fmt.Errorf("保存下载文件失败: %v", err)
+	var req struct {
+		Inbounds []xray.InboundConfig `json:"inbounds"`
 	}
-	if written > maxBinarySize {
-		os.Remove(tmpPath)
-		return fmt.Errorf("下载文件过大 (%d bytes)", written)
+	if err := json.Unmarshal(jsonData, &req); err != nil {
+		return fmt.Errorf("解析配置失败: %v", err)
 	}
-	if written < 1024 {
-		os.Remove(tmpPath)
-		return fmt.Errorf("下载文件异常 (%d bytes)，文件过小", written)
+	for _, ib := range req.Inbounds {
+		if !singbox.IsProtocol(ib.Protocol) {
+			return fmt.Errorf("sing-box 不支持协议: %s", ib.Protocol)
+		}
 	}
+	return w.getOrInitSingboxManager().ApplyConfig(req.Inbounds)
+}
 
-	// 2. 获取当前二进制路径
-	currentBinary, err := os.Executable()
+func (w *WebSocketReporter) handleSingboxSwitchVersion(data interface{}) error {
+	jsonData, err := json.Marshal(data)
 	if err != nil {
-		os.Remove(tmpPath)
-		return fmt.Errorf("获取当前二进制路径失败: %v", err)
+		return fmt.Errorf("序列化数据失败: %v", err)
 	}
-	// 解析软链接得到真实路径
-	currentBinary, _ = filepath.EvalSymlinks(currentBinary)
 
-	// 3. 备份旧二进制
-	backupPath := currentBinary + ".bak"
-	if err := copyFileForUpdate(currentBinary, backupPath); err != nil {
-		fmt.Printf("⚠️ 备份旧二进制失败: %v\n", err)
-	} else {
-		fmt.Printf("📦 已备份旧二进制到 %s\n", backupPath)
+	var req struct {
+		URL    string `json:"url"`
+		SHA256 string `json:"sha256"`
 	}
-
-	// 4. 替换二进制（先删除旧文件再写入，避免 "text file busy"）
-	os.Remove(currentBinary)
-	if err := copyFileForUpdate(tmpPath, currentBinary); err != nil {
-		// 尝试从备份恢复
-		if restoreErr := copyFileForUpdate(backupPath, currentBinary); restoreErr != nil {
-			fmt.Printf("❌ 恢复备份也失败: %v\n", restoreErr)
-		} else {
-			os.Chmod(currentBinary, 0755)
-			fmt.Printf("📦 已从备份恢复\n")
-		}
-		os.Remove(tmpPath)
-		return fmt.Errorf("替换二进制失败: %v", err)
+	if err := json.Unmarshal(jsonData, &req); err != nil {
+		return fmt.Errorf("解析版本切换请求失败: %v", err)
 	}
-	os.Chmod(currentBinary, 0755)
-	os.Remove(tmpPath)
-
-	// 5. Docker 持久化：如果是 Docker 环境，保存到工作目录
-	if _, err := os.Stat("/.dockerenv"); err == nil {
-		binaryName := filepath.Base(currentBinary)
-		persistPath := filepath.Join(".", binaryName)
-		if err := copyFileForUpdate(currentBinary, persistPath); err != nil {
-			fmt.Printf("⚠️ Docker 持久化失败: %v\n", err)
-		} else {
-			os.Chmod(persistPath, 0755)
-			fmt.Printf("📦 已持久化到 %s\n", persistPath)
-		}
+	if req.URL == "" || req.SHA256 == "" {
+		return fmt.Errorf("下载地址和 SHA-256 校验值不能为空")
 	}
 
-	fmt.Printf("✅ 节点更新完成 (%d bytes)，正在退出进程...\n", written)
-	// 6. 延迟退出，确保响应先发送回面板
+	sb := w.getOrInitSingboxManager()
 	go func() {
-		time.Sleep(1 * time.Second)
-		os.Exit(0)
+		if err := sb.Install(req.URL, req.SHA256); err != nil {
+			fmt.Printf("❌ sing-box 版本切换失败: %v\n", err)
+		}
 	}()
-
 	return nil
 }
 
-// copyFileForUpdate copies a file from src to dst (used by node self-update)
-func copyFileForUpdate(src, dst string) error {
-	in, err := os.Open(src)
-	if err != nil {
-		return err
-	}
-	defer in.Close()
-
-	out, err := os.Create(dst)
-	if err != nil {
-		return err
-	}
-	defer out.Close()
-
-	if _, err := io.Copy(out, in); err != nil {
-		return err
-	}
-	return out.Sync()
-}
-
 // handleCall 处理服务端的call回调消息
 func (w *WebSocketReporter) handleCall(data interface{}) error {
 	// 解析call数据
@@ -1410,6 +1649,11 @@ func (w *WebSocketReporter) sendResponse(response CommandResponse) {
 		return
 	}
 
+	if response.Kind == "" {
+		response.Kind = msgKindResponse
+	}
+	response.V = protocolVersion
+
 	jsonData, err := json.Marshal(response)
 	if err != nil {
 		fmt.Printf("❌ 序列化响应失败: %v\n", err)
@@ -1419,7 +1663,12 @@ func (w *WebSocketReporter) sendResponse(response CommandResponse) {
 	var messageData []byte
 
 	// 如果有加密器，则加密数据
-	if w.aesCrypto != nil {
+	if w.session != nil {
+		if messageData, err = w.session.seal(jsonData); err != nil {
+			fmt.Printf("❌ 加密响应失败: %v\n", err)
+			return
+		}
+	} else if w.aesCrypto != nil {
 		encryptedData, err := w.aesCrypto.Encrypt(jsonData)
 		if err != nil {
 			fmt.Printf("⚠️ 加密响应失败，发送原始数据: %v\n", err)
@@ -1595,7 +1844,7 @@ func StartWebSocketReporterWithConfig(addr string, secret string, http int, tls
 	if useTLS {
 		wsScheme = "wss"
 	}
-	fullURL := wsScheme + "://" + addr + "/system-info?type=1&secret=" + secret + "&nodeVersion=" + version + "&http=" + strconv.Itoa(http) + "&tls=" + strconv.Itoa(tls) + "&socks=" + strconv.Itoa(socks)
+	fullURL := wsScheme + "://" + addr + "/system-info?type=1&nodeVersion=" + version + "&http=" + strconv.Itoa(http) + "&tls=" + strconv.Itoa(tls) + "&socks=" + strconv.Itoa(socks)
 
 	fmt.Printf("🔗 WebSocket连接URL: %s\n", fullURL)
 
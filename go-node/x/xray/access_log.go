@@ -0,0 +1,119 @@
+package xray
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"time"
+)
+
+// maxAccessLogSize is the size above which the access log is truncated after
+// being read. Xray opens the file with O_APPEND, so it keeps writing at the
+// new end.
+const maxAccessLogSize = 10 << 20
+
+// accessLinePattern matches accepted connections in Xray's access log, e.g.
+//
+//	2024/01/02 15:04:05.123456 from 1.2.3.4:5678 accepted tcp:example.com:443 [in -> direct] email: 1_17@flux
+//	2024/01/02 15:04:05.123456 from tcp:[2001:db8::1]:5678 accepted ... email: 1_17@flux
+var accessLinePattern = regexp.MustCompile(`from (?:tcp:|udp:)?\[?([0-9a-fA-F.:]+?)\]?:\d+ accepted .*email: (\S+)`)
+
+const accessTimeLayout = "2006/01/02 15:04:05"
+
+// AccessLogTracker tails Xray's access log and collects the source IPs seen
+// per client email since the last Drain.
+type AccessLogTracker struct {
+	path    string
+	offset  int64
+	started bool
+	partial []byte
+}
+
+// NewAccessLogTracker creates a tracker for the given access log path.
+// Entries written before the first Drain are ignored.
+func NewAccessLogTracker(path string) *AccessLogTracker {
+	return &AccessLogTracker{path: path}
+}
+
+// Drain reads new log entries and returns email -> ip -> last seen (unix seconds).
+func (t *AccessLogTracker) Drain() map[string]map[string]int64 {
+	f, err := os.Open(t.path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil
+	}
+	size := info.Size()
+
+	if !t.started {
+		t.started = true
+		t.offset = size
+		return nil
+	}
+	if size < t.offset {
+		// Truncated or rotated
+		t.offset = 0
+		t.partial = nil
+	}
+	if size == t.offset {
+		return nil
+	}
+
+	if _, err := f.Seek(t.offset, io.SeekStart); err != nil {
+		return nil
+	}
+	data, err := io.ReadAll(io.LimitReader(f, size-t.offset))
+	if err != nil {
+		return nil
+	}
+	t.offset += int64(len(data))
+
+	if len(t.partial) > 0 {
+		data = append(t.partial, data...)
+		t.partial = nil
+	}
+	// Keep an incomplete trailing line for the next read
+	if i := bytes.LastIndexByte(data, '\n'); i < len(data)-1 {
+		t.partial = append([]byte(nil), data[i+1:]...)
+		data = data[:i+1]
+	}
+
+	result := make(map[string]map[string]int64)
+	now := time.Now().Unix()
+	for _, line := range bytes.Split(data, []byte{'\n'}) {
+		m := accessLinePattern.FindSubmatch(line)
+		if m == nil {
+			continue
+		}
+		ip, email := string(m[1]), string(m[2])
+		seen := now
+		if len(line) >= len(accessTimeLayout) {
+			if ts, err := time.ParseInLocation(accessTimeLayout, string(line[:len(accessTimeLayout)]), time.Local); err == nil {
+				seen = ts.Unix()
+			}
+		}
+		ips := result[email]
+		if ips == nil {
+			ips = make(map[string]int64)
+			result[email] = ips
+		}
+		if seen > ips[ip] {
+			ips[ip] = seen
+		}
+	}
+
+	if t.offset > maxAccessLogSize {
+		if err := os.Truncate(t.path, 0); err != nil {
+			fmt.Printf("⚠️ Failed to truncate access log: %v\n", err)
+		} else {
+			t.offset = 0
+		}
+	}
+	return result
+}
@@ -0,0 +1,365 @@
+package xray
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// The node doesn't link Xray's generated protos, so the handful of API
+// messages it needs are encoded by hand with protowire and sent through a
+// pass-through codec. Field numbers follow xray-core's app/proxyman/command,
+// app/stats/command, common/protocol and proxy/*/config.proto; api_test.go
+// checks the encoding against descriptors generated from xray-core.
+const (
+	alterInboundMethod  = "/xray.app.proxyman.command.HandlerService/AlterInbound"
+	removeInboundMethod = "/xray.app.proxyman.command.HandlerService/RemoveInbound"
+	xrayStatsMethod     = "/xray.app.stats.command.StatsService/QueryStats"
+
+	apiCallTimeout = 10 * time.Second
+
+	// AlterInbound takes one user per call, so a batch is pipelined over
+	// the shared connection with at most this many calls in flight.
+	apiBatchConcurrency = 16
+)
+
+// RawCodec passes pre-encoded protobuf bytes through gRPC unchanged.
+type RawCodec struct{}
+
+func (RawCodec) Marshal(v interface{}) ([]byte, error) {
+	b, ok := v.(*[]byte)
+	if !ok {
+		return nil, fmt.Errorf("rawCodec: unexpected type %T", v)
+	}
+	return *b, nil
+}
+
+func (RawCodec) Unmarshal(data []byte, v interface{}) error {
+	b, ok := v.(*[]byte)
+	if !ok {
+		return fmt.Errorf("rawCodec: unexpected type %T", v)
+	}
+	*b = append((*b)[:0], data...)
+	return nil
+}
+
+func (RawCodec) Name() string { return "proto" }
+
+var _ encoding.Codec = RawCodec{}
+
+// APIError is a failed API call. Msg keeps the gRPC description, so dial
+// failures still read "connection refused" to callers that match on it.
+type APIError struct {
+	Op     string // gRPC method, e.g. "AlterInbound"
+	Target string // inbound tag or tag/email the call was about
+	Code   codes.Code
+	Msg    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%s %s: %s (%s)", e.Op, e.Target, e.Msg, e.Code)
+}
+
+// Unsupported reports whether the running Xray can't serve the call at all
+// (old version, message type not registered), as opposed to rejecting it.
+func (e *APIError) Unsupported() bool {
+	if e.Code == codes.Unimplemented {
+		return true
+	}
+	msg := strings.ToLower(e.Msg)
+	return strings.Contains(msg, "unknown type") || strings.Contains(msg, "not registered")
+}
+
+// AlreadyExists reports whether a user or inbound with that key exists.
+func (e *APIError) AlreadyExists() bool {
+	return strings.Contains(strings.ToLower(e.Msg), "already exist")
+}
+
+// NotFound reports whether the user or inbound didn't exist.
+func (e *APIError) NotFound() bool {
+	msg := strings.ToLower(e.Msg)
+	return strings.Contains(msg, "not found") || strings.Contains(msg, "not exist")
+}
+
+func newAPIError(op, target string, err error) *APIError {
+	st, _ := status.FromError(err)
+	return &APIError{Op: op, Target: target, Code: st.Code(), Msg: st.Message()}
+}
+
+// BatchError collects per-user failures of a batch call.
+type BatchError struct {
+	Failed map[string]error // email -> error
+}
+
+func (e *BatchError) Error() string {
+	parts := make([]string, 0, len(e.Failed))
+	for email, err := range e.Failed {
+		parts = append(parts, fmt.Sprintf("%s: %v", email, err))
+	}
+	return fmt.Sprintf("%d user(s) failed: %s", len(e.Failed), strings.Join(parts, "; "))
+}
+
+var (
+	apiConnsMu sync.Mutex
+	apiConns   = map[string]*grpc.ClientConn{}
+)
+
+// APIConn returns a shared connection to the API at addr. gRPC reconnects
+// on its own, so one connection per address serves every call.
+func APIConn(addr string) (*grpc.ClientConn, error) {
+	apiConnsMu.Lock()
+	defer apiConnsMu.Unlock()
+	if conn, ok := apiConns[addr]; ok {
+		return conn, nil
+	}
+	conn, err := grpc.NewClient(addr,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithDefaultCallOptions(grpc.ForceCodec(RawCodec{})))
+	if err != nil {
+		return nil, err
+	}
+	apiConns[addr] = conn
+	return conn, nil
+}
+
+// ---------------------------------------------------------------------------
+// Message encoding
+// ---------------------------------------------------------------------------
+
+func appendString(b []byte, num protowire.Number, s string) []byte {
+	if s == "" {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, s)
+}
+
+func appendMessage(b []byte, num protowire.Number, msg []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, msg)
+}
+
+// typedMessage encodes xray.common.serial.TypedMessage { type = 1; value = 2; }.
+func typedMessage(typeName string, value []byte) []byte {
+	b := appendString(nil, 1, typeName)
+	return appendMessage(b, 2, value)
+}
+
+// userAccount encodes the protocol-specific account of a user. Shadowsocks
+// is left out: its account carries the cipher, which only the CLI's config
+// loader resolves (including the 2022 variants).
+func userAccount(u User) ([]byte, error) {
+	switch u.Protocol {
+	case "vless":
+		// Account { id = 1; flow = 2; encryption = 3; }
+		b := appendString(nil, 1, u.UuidOrPassword)
+		b = appendString(b, 2, u.Flow)
+		b = appendString(b, 3, "none")
+		return typedMessage("xray.proxy.vless.Account", b), nil
+	case "vmess":
+		// Account { id = 1; ... }; alterId is gone from Xray's VMess
+		return typedMessage("xray.proxy.vmess.Account", appendString(nil, 1, u.UuidOrPassword)), nil
+	case "trojan":
+		// Account { password = 1; }
+		return typedMessage("xray.proxy.trojan.Account", appendString(nil, 1, u.UuidOrPassword)), nil
+	}
+	return nil, fmt.Errorf("no native account encoding for %s", u.Protocol)
+}
+
+// addUserRequest encodes AlterInboundRequest { tag = 1; operation = 2; }
+// carrying AddUserOperation { user = 1; } with
+// User { level = 1; email = 2; account = 3; }.
+func addUserRequest(tag string, u User) ([]byte, error) {
+	account, err := userAccount(u)
+	if err != nil {
+		return nil, err
+	}
+	user := appendString(nil, 2, u.Email)
+	user = appendMessage(user, 3, account)
+	op := appendMessage(nil, 1, user)
+
+	req := appendString(nil, 1, tag)
+	return appendMessage(req, 2, typedMessage("xray.app.proxyman.command.AddUserOperation", op)), nil
+}
+
+// removeUserRequest encodes AlterInboundRequest carrying
+// RemoveUserOperation { email = 1; }.
+func removeUserRequest(tag, email string) []byte {
+	op := appendString(nil, 1, email)
+	req := appendString(nil, 1, tag)
+	return appendMessage(req, 2, typedMessage("xray.app.proxyman.command.RemoveUserOperation", op))
+}
+
+// queryStatsRequest encodes QueryStatsRequest { string pattern = 1; bool reset = 2; }.
+func queryStatsRequest(pattern string, reset bool) []byte {
+	req := appendString(nil, 1, pattern)
+	if reset {
+		req = protowire.AppendTag(req, 2, protowire.VarintType)
+		req = protowire.AppendVarint(req, 1)
+	}
+	return req
+}
+
+// ---------------------------------------------------------------------------
+// Calls
+// ---------------------------------------------------------------------------
+
+func invoke(addr, method, target string, req []byte) error {
+	conn, err := APIConn(addr)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), apiCallTimeout)
+	defer cancel()
+	var resp []byte
+	if err := conn.Invoke(ctx, method, &req, &resp); err != nil {
+		op := method[strings.LastIndex(method, "/")+1:]
+		return newAPIError(op, target, err)
+	}
+	return nil
+}
+
+// invokeBatch sends one call per request, concurrently, and returns the
+// error of each. The first call goes out alone: if the running Xray can't
+// serve it, nothing else is sent and unsupported is set.
+func invokeBatch(addr, method string, targets []string, reqs [][]byte) (errs []error, unsupported *APIError) {
+	errs = make([]error, len(reqs))
+	if len(reqs) == 0 {
+		return errs, nil
+	}
+	errs[0] = invoke(addr, method, targets[0], reqs[0])
+	if apiErr, ok := errs[0].(*APIError); ok && apiErr.Unsupported() {
+		return nil, apiErr
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, apiBatchConcurrency)
+	for i := 1; i < len(reqs); i++ {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer func() { <-sem; wg.Done() }()
+			errs[i] = invoke(addr, method, targets[i], reqs[i])
+		}(i)
+	}
+	wg.Wait()
+	return errs, nil
+}
+
+// QueryStats calls a V2Ray-style StatsService/QueryStats at addr and returns
+// counter name -> value. Xray and sing-box share the message layout, only
+// the service's package differs.
+func QueryStats(addr, method, pattern string, reset bool) (map[string]int64, error) {
+	conn, err := APIConn(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	req := queryStatsRequest(pattern, reset)
+	ctx, cancel := context.WithTimeout(context.Background(), apiCallTimeout)
+	defer cancel()
+	var resp []byte
+	if err := conn.Invoke(ctx, method, &req, &resp); err != nil {
+		return nil, newAPIError("QueryStats", pattern, err)
+	}
+	return parseQueryStatsResponse(resp)
+}
+
+// parseQueryStatsResponse decodes QueryStatsResponse { repeated Stat stat = 1; }
+// with Stat { string name = 1; int64 value = 2; }.
+func parseQueryStatsResponse(b []byte) (map[string]int64, error) {
+	stats := make(map[string]int64)
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		b = b[n:]
+		if num != 1 || typ != protowire.BytesType {
+			n = protowire.ConsumeFieldValue(num, typ, b)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			b = b[n:]
+			continue
+		}
+		msg, n := protowire.ConsumeBytes(b)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		var name string
+		var value int64
+		for len(msg) > 0 {
+			fnum, ftyp, m := protowire.ConsumeTag(msg)
+			if m < 0 {
+				return nil, protowire.ParseError(m)
+			}
+			msg = msg[m:]
+			switch {
+			case fnum == 1 && ftyp == protowire.BytesType:
+				s, m := protowire.ConsumeString(msg)
+				if m < 0 {
+					return nil, protowire.ParseError(m)
+				}
+				name, msg = s, msg[m:]
+			case fnum == 2 && ftyp == protowire.VarintType:
+				v, m := protowire.ConsumeVarint(msg)
+				if m < 0 {
+					return nil, protowire.ParseError(m)
+				}
+				value, msg = int64(v), msg[m:]
+			default:
+				m = protowire.ConsumeFieldValue(fnum, ftyp, msg)
+				if m < 0 {
+					return nil, protowire.ParseError(m)
+				}
+				msg = msg[m:]
+			}
+		}
+		stats[name] += value
+	}
+	return stats, nil
+}
+
+// AggregateUserStats folds "user>>>email>>>traffic>>>uplink|downlink"
+// counters into one entry per user, skipping users without traffic.
+func AggregateUserStats(stats map[string]int64) []TrafficStat {
+	byEmail := make(map[string]*TrafficStat)
+	var order []string
+	for name, value := range stats {
+		parts := strings.Split(name, ">>>")
+		if len(parts) != 4 || parts[0] != "user" || parts[2] != "traffic" {
+			continue
+		}
+		st, ok := byEmail[parts[1]]
+		if !ok {
+			st = &TrafficStat{Email: parts[1]}
+			byEmail[parts[1]] = st
+			order = append(order, parts[1])
+		}
+		switch parts[3] {
+		case "uplink":
+			st.Uplink += value
+		case "downlink":
+			st.Downlink += value
+		}
+	}
+	result := make([]TrafficStat, 0, len(order))
+	for _, email := range order {
+		if st := byEmail[email]; st.Uplink > 0 || st.Downlink > 0 {
+			result = append(result, *st)
+		}
+	}
+	return result
+}
@@ -0,0 +1,265 @@
+package xray
+
+import (
+	"bytes"
+	"net"
+	"os"
+	"sync/atomic"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// xrayTypes loads the descriptors xray-core generates its API types from
+// (see testdata/gen_descriptors.go).
+func xrayTypes(t *testing.T) *protoregistry.Files {
+	t.Helper()
+	data, err := os.ReadFile("testdata/xray_descriptors.pb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var set descriptorpb.FileDescriptorSet
+	if err := proto.Unmarshal(data, &set); err != nil {
+		t.Fatal(err)
+	}
+	files, err := protodesc.NewFiles(&set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+// message builds an Xray message by field name, the way the generated
+// types would be filled in.
+func message(t *testing.T, files *protoregistry.Files, name string, fields map[string]interface{}) *dynamicpb.Message {
+	t.Helper()
+	d, err := files.FindDescriptorByName(protoreflect.FullName(name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := dynamicpb.NewMessage(d.(protoreflect.MessageDescriptor))
+	for field, v := range fields {
+		fd := msg.Descriptor().Fields().ByName(protoreflect.Name(field))
+		if fd == nil {
+			t.Fatalf("%s has no field %s", name, field)
+		}
+		switch v := v.(type) {
+		case *dynamicpb.Message:
+			msg.Set(fd, protoreflect.ValueOfMessage(v))
+		case []*dynamicpb.Message:
+			list := msg.Mutable(fd).List()
+			for _, m := range v {
+				list.Append(protoreflect.ValueOfMessage(m))
+			}
+		default:
+			msg.Set(fd, protoreflect.ValueOf(v))
+		}
+	}
+	return msg
+}
+
+func marshal(t *testing.T, m proto.Message) []byte {
+	t.Helper()
+	b, err := proto.MarshalOptions{Deterministic: true}.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// typed wraps a message in xray.common.serial.TypedMessage.
+func typed(t *testing.T, files *protoregistry.Files, m *dynamicpb.Message) *dynamicpb.Message {
+	return message(t, files, "xray.common.serial.TypedMessage", map[string]interface{}{
+		"type":  string(m.Descriptor().FullName()),
+		"value": marshal(t, m),
+	})
+}
+
+func TestAddUserRequestMatchesXrayTypes(t *testing.T) {
+	files := xrayTypes(t)
+	const uuid = "b831381d-6324-4d53-ad4f-8cda48b30811"
+
+	tests := []struct {
+		user    User
+		account *dynamicpb.Message
+	}{
+		{
+			User{Email: "a@vless", UuidOrPassword: uuid, Flow: "xtls-rprx-vision", Protocol: "vless"},
+			message(t, files, "xray.proxy.vless.Account", map[string]interface{}{
+				"id": uuid, "flow": "xtls-rprx-vision", "encryption": "none",
+			}),
+		},
+		{
+			User{Email: "b@vless", UuidOrPassword: uuid, Protocol: "vless"},
+			message(t, files, "xray.proxy.vless.Account", map[string]interface{}{
+				"id": uuid, "encryption": "none",
+			}),
+		},
+		{
+			User{Email: "c@vmess", UuidOrPassword: uuid, Protocol: "vmess", AlterId: 64},
+			message(t, files, "xray.proxy.vmess.Account", map[string]interface{}{"id": uuid}),
+		},
+		{
+			User{Email: "d@trojan", UuidOrPassword: "secret", Protocol: "trojan"},
+			message(t, files, "xray.proxy.trojan.Account", map[string]interface{}{"password": "secret"}),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.user.Email, func(t *testing.T) {
+			user := message(t, files, "xray.common.protocol.User", map[string]interface{}{
+				"email":   tt.user.Email,
+				"account": typed(t, files, tt.account),
+			})
+			op := message(t, files, "xray.app.proxyman.command.AddUserOperation", map[string]interface{}{"user": user})
+			want := marshal(t, message(t, files, "xray.app.proxyman.command.AlterInboundRequest", map[string]interface{}{
+				"tag":       "in-1",
+				"operation": typed(t, files, op),
+			}))
+
+			got, err := addUserRequest("in-1", tt.user)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Fatalf("addUserRequest:\n got %x\nwant %x", got, want)
+			}
+		})
+	}
+}
+
+func TestAddUserRequestRejectsShadowsocks(t *testing.T) {
+	if _, err := addUserRequest("in-1", User{Email: "e@ss", UuidOrPassword: "pw", Protocol: "shadowsocks"}); err == nil {
+		t.Fatal("shadowsocks must go through the CLI")
+	}
+}
+
+func TestRemoveUserRequestMatchesXrayTypes(t *testing.T) {
+	files := xrayTypes(t)
+	op := message(t, files, "xray.app.proxyman.command.RemoveUserOperation", map[string]interface{}{"email": "a@vless"})
+	want := marshal(t, message(t, files, "xray.app.proxyman.command.AlterInboundRequest", map[string]interface{}{
+		"tag":       "in-1",
+		"operation": typed(t, files, op),
+	}))
+
+	if got := removeUserRequest("in-1", "a@vless"); !bytes.Equal(got, want) {
+		t.Fatalf("removeUserRequest:\n got %x\nwant %x", got, want)
+	}
+}
+
+func TestQueryStatsRequestMatchesXrayTypes(t *testing.T) {
+	files := xrayTypes(t)
+	for _, reset := range []bool{false, true} {
+		want := marshal(t, message(t, files, "xray.app.stats.command.QueryStatsRequest", map[string]interface{}{
+			"pattern": "user>>>",
+			"reset":   reset,
+		}))
+		if got := queryStatsRequest("user>>>", reset); !bytes.Equal(got, want) {
+			t.Fatalf("queryStatsRequest(reset=%v):\n got %x\nwant %x", reset, got, want)
+		}
+	}
+}
+
+func TestParseQueryStatsResponse(t *testing.T) {
+	files := xrayTypes(t)
+	stat := func(name string, value int64) *dynamicpb.Message {
+		return message(t, files, "xray.app.stats.command.Stat", map[string]interface{}{"name": name, "value": value})
+	}
+	resp := marshal(t, message(t, files, "xray.app.stats.command.QueryStatsResponse", map[string]interface{}{
+		"stat": []*dynamicpb.Message{
+			stat("user>>>a@vless>>>traffic>>>uplink", 1<<40),
+			stat("user>>>a@vless>>>traffic>>>downlink", 7),
+			stat("user>>>b@vless>>>traffic>>>uplink", 0),
+		},
+	}))
+
+	got, err := parseQueryStatsResponse(resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]int64{
+		"user>>>a@vless>>>traffic>>>uplink":   1 << 40,
+		"user>>>a@vless>>>traffic>>>downlink": 7,
+		"user>>>b@vless>>>traffic>>>uplink":   0,
+	}
+	if len(got) != len(want) {
+		t.Fatalf("parsed %v, want %v", got, want)
+	}
+	for name, v := range want {
+		if got[name] != v {
+			t.Fatalf("%s = %d, want %d", name, got[name], v)
+		}
+	}
+
+	if _, err := parseQueryStatsResponse(resp[:len(resp)-1]); err == nil {
+		t.Fatal("truncated response parsed without error")
+	}
+}
+
+// fakeAPI serves every method with handle and counts the calls.
+func fakeAPI(t *testing.T, handle func() error) (string, *int32) {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var calls int32
+	srv := grpc.NewServer(grpc.UnknownServiceHandler(func(_ interface{}, stream grpc.ServerStream) error {
+		atomic.AddInt32(&calls, 1)
+		var req []byte
+		if err := stream.RecvMsg(&req); err != nil {
+			return err
+		}
+		if err := handle(); err != nil {
+			return err
+		}
+		resp := []byte{}
+		return stream.SendMsg(&resp)
+	}), grpc.ForceServerCodec(RawCodec{}))
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+	return lis.Addr().String(), &calls
+}
+
+func TestInvokeBatchSendsEveryCall(t *testing.T) {
+	addr, calls := fakeAPI(t, func() error { return nil })
+	targets := make([]string, 40)
+	reqs := make([][]byte, 40)
+	for i := range reqs {
+		targets[i] = "in-1/user"
+		reqs[i] = removeUserRequest("in-1", "user")
+	}
+
+	errs, unsupported := invokeBatch(addr, alterInboundMethod, targets, reqs)
+	if unsupported != nil {
+		t.Fatalf("unsupported: %v", unsupported)
+	}
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("call %d: %v", i, err)
+		}
+	}
+	if n := atomic.LoadInt32(calls); n != 40 {
+		t.Fatalf("%d calls, want 40", n)
+	}
+}
+
+func TestInvokeBatchStopsWhenUnsupported(t *testing.T) {
+	addr, calls := fakeAPI(t, func() error { return status.Error(codes.Unimplemented, "unknown method") })
+	reqs := [][]byte{removeUserRequest("in-1", "a"), removeUserRequest("in-1", "b")}
+
+	_, unsupported := invokeBatch(addr, alterInboundMethod, []string{"in-1/a", "in-1/b"}, reqs)
+	if unsupported == nil {
+		t.Fatal("Unimplemented was not reported as unsupported")
+	}
+	if n := atomic.LoadInt32(calls); n != 1 {
+		t.Fatalf("%d calls after an unsupported one, want 1", n)
+	}
+}
@@ -8,7 +8,9 @@ import (
 	"strings"
 )
 
-// XrayGrpcClient provides communication with Xray-core via CLI
+// XrayGrpcClient talks to Xray-core's API. User management, inbound removal
+// and stats go over gRPC directly; AddInbound and anything the running Xray
+// doesn't support natively fall back to the `xray api` CLI.
 type XrayGrpcClient struct {
 	addr       string
 	binaryPath string
@@ -23,30 +25,140 @@ func NewXrayGrpcClient(addr string, binaryPaths ...string) *XrayGrpcClient {
 	return &XrayGrpcClient{addr: addr, binaryPath: bp}
 }
 
-// AddUser adds a user to an inbound via Xray API CLI command.
-// Requires Xray v25.7.26+ (adu command). Returns error on older versions.
+// User is a client to add to an inbound.
+type User struct {
+	Email          string
+	UuidOrPassword string
+	Flow           string
+	Protocol       string
+	AlterId        int
+}
+
+// AddUser adds a user to an inbound.
 func (c *XrayGrpcClient) AddUser(inboundTag, email, uuidOrPassword, flow, protocol string, alterId int) error {
-	clientObj := map[string]interface{}{"email": email, "level": 0}
-	switch protocol {
-	case "vmess":
-		clientObj["id"] = uuidOrPassword
-		clientObj["alterId"] = alterId
-	case "vless":
-		clientObj["id"] = uuidOrPassword
-		clientObj["flow"] = flow
-	case "trojan":
-		clientObj["password"] = uuidOrPassword
-	case "shadowsocks":
-		clientObj["password"] = uuidOrPassword
-	default:
-		return fmt.Errorf("unsupported protocol: %s", protocol)
+	return c.AddUsers(inboundTag, []User{{
+		Email: email, UuidOrPassword: uuidOrPassword, Flow: flow, Protocol: protocol, AlterId: alterId,
+	}})
+}
+
+// AddUsers adds users to an inbound, pipelining the calls over one
+// connection. Users the API can't take natively (shadowsocks, or an Xray
+// without the call) are added in a single `xray api adu` run instead.
+// Per-user failures come back as a *BatchError.
+func (c *XrayGrpcClient) AddUsers(inboundTag string, users []User) error {
+	failed := map[string]error{}
+	var native, viaCLI []User
+	var targets []string
+	var reqs [][]byte
+	for _, u := range users {
+		req, err := addUserRequest(inboundTag, u)
+		if err != nil {
+			viaCLI = append(viaCLI, u)
+			continue
+		}
+		native = append(native, u)
+		targets = append(targets, inboundTag+"/"+u.Email)
+		reqs = append(reqs, req)
+	}
+
+	errs, unsupported := invokeBatch(c.addr, alterInboundMethod, targets, reqs)
+	if unsupported != nil {
+		fmt.Printf("⚠️ Native AlterInbound unsupported (%v), using CLI\n", unsupported)
+		viaCLI = append(viaCLI, native...)
+	}
+	for i, err := range errs {
+		if err != nil {
+			failed[native[i].Email] = err
+		}
+	}
+
+	if len(viaCLI) > 0 {
+		if err := c.execAddUsers(inboundTag, viaCLI); err != nil {
+			for _, u := range viaCLI {
+				failed[u.Email] = err
+			}
+		}
+	}
+
+	fmt.Printf("📡 gRPC addUsers: tag=%s users=%d failed=%d\n", inboundTag, len(users), len(failed))
+	if len(failed) > 0 {
+		if len(users) == 1 {
+			return failed[users[0].Email]
+		}
+		return &BatchError{Failed: failed}
+	}
+	return nil
+}
+
+// RemoveUser removes a user from an inbound.
+func (c *XrayGrpcClient) RemoveUser(inboundTag, email string) error {
+	return c.RemoveUsers(inboundTag, []string{email})
+}
+
+// RemoveUsers removes users from an inbound, pipelining the calls over one
+// connection, falling back to a single `xray api rmu` run if the API call is
+// unsupported.
+func (c *XrayGrpcClient) RemoveUsers(inboundTag string, emails []string) error {
+	failed := map[string]error{}
+	targets := make([]string, len(emails))
+	reqs := make([][]byte, len(emails))
+	for i, email := range emails {
+		targets[i] = inboundTag + "/" + email
+		reqs[i] = removeUserRequest(inboundTag, email)
+	}
+
+	errs, unsupported := invokeBatch(c.addr, alterInboundMethod, targets, reqs)
+	if unsupported != nil {
+		fmt.Printf("⚠️ Native AlterInbound unsupported (%v), using CLI\n", unsupported)
+		if err := c.execRemoveUsers(inboundTag, emails); err != nil {
+			for _, e := range emails {
+				failed[e] = err
+			}
+		}
+	}
+	for i, err := range errs {
+		if err != nil {
+			failed[emails[i]] = err
+		}
+	}
+
+	fmt.Printf("📡 gRPC removeUsers: tag=%s users=%d failed=%d\n", inboundTag, len(emails), len(failed))
+	if len(failed) > 0 {
+		if len(emails) == 1 {
+			return failed[emails[0]]
+		}
+		return &BatchError{Failed: failed}
+	}
+	return nil
+}
+
+// execAddUsers adds users with `xray api adu` (Xray v25.7.26+).
+func (c *XrayGrpcClient) execAddUsers(inboundTag string, users []User) error {
+	clients := make([]interface{}, 0, len(users))
+	for _, u := range users {
+		clientObj := map[string]interface{}{"email": u.Email, "level": 0}
+		switch u.Protocol {
+		case "vmess":
+			clientObj["id"] = u.UuidOrPassword
+			clientObj["alterId"] = u.AlterId
+		case "vless":
+			clientObj["id"] = u.UuidOrPassword
+			clientObj["flow"] = u.Flow
+		case "trojan":
+			clientObj["password"] = u.UuidOrPassword
+		case "shadowsocks":
+			clientObj["password"] = u.UuidOrPassword
+		default:
+			return fmt.Errorf("unsupported protocol: %s", u.Protocol)
+		}
+		clients = append(clients, clientObj)
 	}
 
 	// adu expects a full config with "inbounds" array, each inbound has tag + settings with clients
 	inboundCfg := map[string]interface{}{
 		"tag": inboundTag,
 		"settings": map[string]interface{}{
-			"clients": []interface{}{clientObj},
+			"clients": clients,
 		},
 	}
 	wrapped := map[string]interface{}{
@@ -62,7 +174,6 @@ func (c *XrayGrpcClient) AddUser(inboundTag, email, uuidOrPassword, flow, protoc
 	tmpFile.Write(configData)
 	tmpFile.Close()
 
-	fmt.Printf("📡 gRPC addUser: tag=%s email=%s\n", inboundTag, email)
 	cmd := exec.Command(c.binaryPath, "api", "adu",
 		"--server="+c.addr, tmpFile.Name())
 	output, err := cmd.CombinedOutput()
@@ -72,22 +183,21 @@ func (c *XrayGrpcClient) AddUser(inboundTag, email, uuidOrPassword, flow, protoc
 	return nil
 }
 
-// RemoveUser removes a user from an inbound via Xray API CLI command.
-// Requires Xray v25.7.26+ (rmu command). Returns error on older versions.
-func (c *XrayGrpcClient) RemoveUser(inboundTag, email string) error {
-	fmt.Printf("📡 gRPC removeUser: tag=%s email=%s\n", inboundTag, email)
-	cmd := exec.Command(c.binaryPath, "api", "rmu",
-		"--server="+c.addr, "-tag="+inboundTag, email)
-	output, err := cmd.CombinedOutput()
+// execRemoveUsers removes users with `xray api rmu` (Xray v25.7.26+).
+func (c *XrayGrpcClient) execRemoveUsers(inboundTag string, emails []string) error {
+	args := append([]string{"api", "rmu", "--server=" + c.addr, "-tag=" + inboundTag}, emails...)
+	output, err := exec.Command(c.binaryPath, args...).CombinedOutput()
 	if err != nil {
 		return fmt.Errorf("api rmu failed: %v, output: %s", err, string(output))
 	}
 	return nil
 }
 
-// AddInbound adds an inbound to a running Xray instance via gRPC API.
+// AddInbound adds an inbound to a running Xray instance.
 // configJSON should be a single inbound object JSON; this method wraps it
-// in {"inbounds": [...]} as required by `xray api adi`.
+// in {"inbounds": [...]} as required by `xray api adi`. This stays on the
+// CLI: the API takes a compiled InboundHandlerConfig, and only Xray's own
+// config loader can build one from JSON.
 func (c *XrayGrpcClient) AddInbound(configJSON string) error {
 	fmt.Printf("📡 gRPC addInbound\n")
 
@@ -118,9 +228,15 @@ func (c *XrayGrpcClient) AddInbound(configJSON string) error {
 	return nil
 }
 
-// RemoveInbound removes an inbound from a running Xray instance via gRPC API
+// RemoveInbound removes an inbound from a running Xray instance.
 func (c *XrayGrpcClient) RemoveInbound(tag string) error {
 	fmt.Printf("📡 gRPC removeInbound: tag=%s\n", tag)
+	// RemoveInboundRequest { tag = 1; }
+	err := invoke(c.addr, removeInboundMethod, tag, appendString(nil, 1, tag))
+	if apiErr, ok := err.(*APIError); !ok || !apiErr.Unsupported() {
+		return err
+	}
+
 	cmd := exec.Command(c.binaryPath, "api", "rmi",
 		"--server="+c.addr, tag)
 	output, err := cmd.CombinedOutput()
@@ -137,9 +253,17 @@ type TrafficStat struct {
 	Downlink int64  `json:"d"`
 }
 
-// QueryTraffic queries traffic stats for all users via xray api statsquery CLI.
-// When reset=true, counters are reset after reading (incremental stats).
+// QueryTraffic queries per-user traffic stats. When reset=true, counters
+// are reset after reading (incremental stats).
 func (c *XrayGrpcClient) QueryTraffic(reset bool) ([]TrafficStat, error) {
+	stats, err := QueryStats(c.addr, xrayStatsMethod, "user>>>", reset)
+	if err == nil {
+		return AggregateUserStats(stats), nil
+	}
+	if apiErr, ok := err.(*APIError); !ok || !apiErr.Unsupported() {
+		return nil, err
+	}
+
 	args := []string{"api", "statsquery", "-s", c.addr, "-pattern", "user"}
 	if reset {
 		args = append(args, "-reset")
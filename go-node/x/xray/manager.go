@@ -25,6 +25,9 @@ type XrayManager struct {
 	running    bool
 	mu         sync.Mutex
 	version    string
+
+	routingMu sync.Mutex
+	routing   *RoutingConfig
 }
 
 // NewXrayManager creates a new XrayManager
@@ -169,6 +172,15 @@ func (m *XrayManager) GetGrpcAddr() string {
 	return m.grpcAddr
 }
 
+// AccessLogPath returns the Xray access log path, kept next to the config file
+func (m *XrayManager) AccessLogPath() string {
+	dir, err := filepath.Abs(filepath.Dir(m.configPath))
+	if err != nil {
+		dir = filepath.Dir(m.configPath)
+	}
+	return filepath.Join(dir, "xray_access.log")
+}
+
 // GetBinaryPath returns the Xray binary path
 func (m *XrayManager) GetBinaryPath() string {
 	return m.binaryPath
@@ -384,6 +396,8 @@ func (m *XrayManager) buildBaseConfig(inbounds []InboundConfig) map[string]inter
 	config := map[string]interface{}{
 		"log": map[string]interface{}{
 			"loglevel": "warning",
+			// The access log carries client source IPs for IP limit enforcement
+			"access": m.AccessLogPath(),
 		},
 		"stats": map[string]interface{}{},
 		"api": map[string]interface{}{
@@ -481,13 +495,19 @@ func (m *XrayManager) buildBaseConfig(inbounds []InboundConfig) map[string]inter
 	}
 
 	config["inbounds"] = allInbounds
+	m.applyRouting(config)
 	return config
 }
 
 // ApplyConfig builds a full config with inbounds, writes it, and restarts Xray.
-// If Xray fails to start or crashes within 2 seconds, the old config is restored.
+// The config is tested first, so a bad outbound or rule never replaces a
+// working config. If Xray still fails to start or crashes within 2 seconds,
+// the old config is restored.
 func (m *XrayManager) ApplyConfig(inbounds []InboundConfig) error {
 	config := m.buildBaseConfig(inbounds)
+	if err := m.TestConfig(config); err != nil {
+		return err
+	}
 
 	// 1. Backup old config
 	oldConfig, _ := os.ReadFile(m.configPath)
@@ -677,11 +697,28 @@ func (m *XrayManager) HotRemoveInbound(tag string) error {
 
 // HotAddUser adds a user to an inbound on the running Xray instance via gRPC API
 // and updates the config file for persistence (no restart needed).
-// If adu command is unavailable (Xray < v25.7.26), falls back to rmi+adi (brief inbound reconnect).
+// If the API call fails, falls back to rmi+adi (brief inbound reconnect).
 func (m *XrayManager) HotAddUser(tag, email, uuidOrPassword, flow, protocol string, alterId int) error {
+	return m.HotAddUsers(tag, []User{{
+		Email: email, UuidOrPassword: uuidOrPassword, Flow: flow, Protocol: protocol, AlterId: alterId,
+	}})
+}
+
+// HotAddUsers adds users to one inbound with a single config write and one
+// batch of API calls. The batch succeeds or fails as a whole: if any user
+// can't be added live, the inbound is reloaded from the config file.
+func (m *XrayManager) HotAddUsers(tag string, users []User) error {
 	if !m.IsRunning() {
 		return fmt.Errorf("service is not running")
 	}
+	if len(users) == 0 {
+		return nil
+	}
+
+	emails := make(map[string]bool, len(users))
+	for _, u := range users {
+		emails[u.Email] = true
+	}
 
 	// Update config file first (always, for persistence)
 	m.updateConfigFile(func(config map[string]interface{}) {
@@ -696,46 +733,25 @@ func (m *XrayManager) HotAddUser(tag, email, uuidOrPassword, flow, protocol stri
 				settings = map[string]interface{}{}
 				ibMap["settings"] = settings
 			}
-			clients, _ := settings["clients"].([]interface{})
-
-			clientObj := map[string]interface{}{"email": email, "level": 0}
-			switch protocol {
-			case "vmess":
-				clientObj["id"] = uuidOrPassword
-				clientObj["alterId"] = alterId
-			case "vless":
-				clientObj["id"] = uuidOrPassword
-				clientObj["flow"] = flow
-			case "trojan":
-				clientObj["password"] = uuidOrPassword
-			case "shadowsocks":
-				clientObj["password"] = uuidOrPassword
-				// SS2022 multi-user: clients must have empty method (method is inbound-level only)
-				// Legacy SS: each client needs its own method field
-				if ssMethod, ok := settings["method"].(string); ok && ssMethod != "" {
-					if strings.HasPrefix(ssMethod, "2022-blake3-") {
-						clientObj["method"] = ""
-					} else {
-						clientObj["method"] = ssMethod
-					}
-				}
+			clients := filterClients(settings, emails, nil)
+			for _, u := range users {
+				clients = append(clients, clientConfig(u, settings))
 			}
-			clients = append(clients, clientObj)
 			settings["clients"] = clients
 			break
 		}
 	})
 
-	// Try adu command (Xray v25.7.26+)
+	// Try the API (native gRPC, or adu on Xray v25.7.26+)
 	client := NewXrayGrpcClient(m.grpcAddr, m.binaryPath)
-	err := client.AddUser(tag, email, uuidOrPassword, flow, protocol, alterId)
-	if err == nil {
-		fmt.Printf("✅ Hot-added user: %s to %s\n", email, tag)
+	err := client.AddUsers(tag, users)
+	if onlyBenign(err, (*APIError).AlreadyExists) {
+		fmt.Printf("✅ Hot-added %d user(s) to %s\n", len(users), tag)
 		return nil
 	}
 
 	// Fallback: reload inbound via rmi+adi (works with all Xray versions)
-	fmt.Printf("⚠️ adu command failed (%v), falling back to inbound reload\n", err)
+	fmt.Printf("⚠️ Add user API failed (%v), falling back to inbound reload\n", err)
 	if reloadErr := m.reloadInbound(tag); reloadErr != nil {
 		// adi failed after rmi — inbound is down. Revert config and try to restore.
 		fmt.Printf("❌ reloadInbound failed, reverting config and restoring inbound: %v\n", reloadErr)
@@ -750,38 +766,43 @@ func (m *XrayManager) HotAddUser(tag, email, uuidOrPassword, flow, protocol stri
 				if settings == nil {
 					continue
 				}
-				clients, _ := settings["clients"].([]interface{})
-				var filtered []interface{}
-				for _, c := range clients {
-					cMap, ok := c.(map[string]interface{})
-					if ok && cMap["email"] == email {
-						continue
-					}
-					filtered = append(filtered, c)
-				}
-				settings["clients"] = filtered
+				settings["clients"] = filterClients(settings, emails, nil)
 				break
 			}
 		})
-		// Try to restore the inbound without the new user (best effort)
+		// Try to restore the inbound without the new users (best effort)
 		_ = m.reloadInbound(tag)
-		return fmt.Errorf("hot add user failed: adu: %v, reload: %v", err, reloadErr)
+		return fmt.Errorf("hot add user failed: api: %v, reload: %v", err, reloadErr)
 	}
 
-	fmt.Printf("✅ Hot-added user (via reload): %s to %s\n", email, tag)
+	fmt.Printf("✅ Hot-added %d user(s) (via reload) to %s\n", len(users), tag)
 	return nil
 }
 
 // HotRemoveUser removes a user from an inbound on the running Xray instance via gRPC API
 // and updates the config file for persistence (no restart needed).
-// If rmu command is unavailable (Xray < v25.7.26), falls back to rmi+adi (brief inbound reconnect).
+// If the API call fails, falls back to rmi+adi (brief inbound reconnect).
 func (m *XrayManager) HotRemoveUser(tag, email string) error {
+	return m.HotRemoveUsers(tag, []string{email})
+}
+
+// HotRemoveUsers removes users from one inbound with a single config write
+// and one batch of API calls, reloading the inbound if any removal fails.
+func (m *XrayManager) HotRemoveUsers(tag string, emailList []string) error {
 	if !m.IsRunning() {
 		return fmt.Errorf("service is not running")
 	}
+	if len(emailList) == 0 {
+		return nil
+	}
 
-	// Update config file first (always, for persistence); save removed client for rollback
-	var removedClient interface{}
+	emails := make(map[string]bool, len(emailList))
+	for _, e := range emailList {
+		emails[e] = true
+	}
+
+	// Update config file first (always, for persistence); save removed clients for rollback
+	var removedClients []interface{}
 	m.updateConfigFile(func(config map[string]interface{}) {
 		inbounds, _ := config["inbounds"].([]interface{})
 		for _, ib := range inbounds {
@@ -793,35 +814,25 @@ func (m *XrayManager) HotRemoveUser(tag, email string) error {
 			if settings == nil {
 				continue
 			}
-			clients, _ := settings["clients"].([]interface{})
-			var filtered []interface{}
-			for _, c := range clients {
-				cMap, ok := c.(map[string]interface{})
-				if ok && cMap["email"] == email {
-					removedClient = c
-					continue
-				}
-				filtered = append(filtered, c)
-			}
-			settings["clients"] = filtered
+			settings["clients"] = filterClients(settings, emails, &removedClients)
 			break
 		}
 	})
 
-	// Try rmu command (Xray v25.7.26+)
+	// Try the API (native gRPC, or rmu on Xray v25.7.26+)
 	client := NewXrayGrpcClient(m.grpcAddr, m.binaryPath)
-	err := client.RemoveUser(tag, email)
-	if err == nil {
-		fmt.Printf("✅ Hot-removed user: %s from %s\n", email, tag)
+	err := client.RemoveUsers(tag, emailList)
+	if onlyBenign(err, (*APIError).NotFound) {
+		fmt.Printf("✅ Hot-removed %d user(s) from %s\n", len(emailList), tag)
 		return nil
 	}
 
 	// Fallback: reload inbound via rmi+adi (works with all Xray versions)
-	fmt.Printf("⚠️ rmu command failed (%v), falling back to inbound reload\n", err)
+	fmt.Printf("⚠️ Remove user API failed (%v), falling back to inbound reload\n", err)
 	if reloadErr := m.reloadInbound(tag); reloadErr != nil {
 		// adi failed after rmi — inbound is down. Revert config and try to restore.
 		fmt.Printf("❌ reloadInbound failed, reverting config and restoring inbound: %v\n", reloadErr)
-		if removedClient != nil {
+		if len(removedClients) > 0 {
 			m.updateConfigFile(func(config map[string]interface{}) {
 				inbounds, _ := config["inbounds"].([]interface{})
 				for _, ib := range inbounds {
@@ -834,20 +845,88 @@ func (m *XrayManager) HotRemoveUser(tag, email string) error {
 						continue
 					}
 					clients, _ := settings["clients"].([]interface{})
-					clients = append(clients, removedClient)
-					settings["clients"] = clients
+					settings["clients"] = append(clients, removedClients...)
 					break
 				}
 			})
 			_ = m.reloadInbound(tag) // Best effort restore
 		}
-		return fmt.Errorf("hot remove user failed: rmu: %v, reload: %v", err, reloadErr)
+		return fmt.Errorf("hot remove user failed: api: %v, reload: %v", err, reloadErr)
 	}
 
-	fmt.Printf("✅ Hot-removed user (via reload): %s from %s\n", email, tag)
+	fmt.Printf("✅ Hot-removed %d user(s) (via reload) from %s\n", len(emailList), tag)
 	return nil
 }
 
+// clientConfig renders a user as an entry of an inbound's settings.clients.
+func clientConfig(u User, settings map[string]interface{}) map[string]interface{} {
+	clientObj := map[string]interface{}{"email": u.Email, "level": 0}
+	switch u.Protocol {
+	case "vmess":
+		clientObj["id"] = u.UuidOrPassword
+		clientObj["alterId"] = u.AlterId
+	case "vless":
+		clientObj["id"] = u.UuidOrPassword
+		clientObj["flow"] = u.Flow
+	case "trojan":
+		clientObj["password"] = u.UuidOrPassword
+	case "shadowsocks":
+		clientObj["password"] = u.UuidOrPassword
+		// SS2022 multi-user: clients must have empty method (method is inbound-level only)
+		// Legacy SS: each client needs its own method field
+		if ssMethod, ok := settings["method"].(string); ok && ssMethod != "" {
+			if strings.HasPrefix(ssMethod, "2022-blake3-") {
+				clientObj["method"] = ""
+			} else {
+				clientObj["method"] = ssMethod
+			}
+		}
+	}
+	return clientObj
+}
+
+// filterClients returns settings.clients without the given emails,
+// collecting the dropped entries into removed if non-nil.
+func filterClients(settings map[string]interface{}, emails map[string]bool, removed *[]interface{}) []interface{} {
+	clients, _ := settings["clients"].([]interface{})
+	var filtered []interface{}
+	for _, c := range clients {
+		if cMap, ok := c.(map[string]interface{}); ok {
+			if email, _ := cMap["email"].(string); emails[email] {
+				if removed != nil {
+					*removed = append(*removed, c)
+				}
+				continue
+			}
+		}
+		filtered = append(filtered, c)
+	}
+	return filtered
+}
+
+// onlyBenign reports whether err is nil or consists only of API errors
+// that leave the inbound in the wanted state (e.g. user already added).
+func onlyBenign(err error, benign func(*APIError) bool) bool {
+	if err == nil {
+		return true
+	}
+	var errs []error
+	if batch, ok := err.(*BatchError); ok {
+		for _, e := range batch.Failed {
+			errs = append(errs, e)
+		}
+	} else {
+		errs = []error{err}
+	}
+	for _, e := range errs {
+		apiErr, ok := e.(*APIError)
+		if !ok || !benign(apiErr) {
+			return false
+		}
+	}
+	return true
+}
+
 // reloadInbound removes and re-adds an inbound to apply config file changes to the running Xray.
 // Used as fallback when adu/rmu commands are unavailable (Xray < v25.7.26).
 func (m *XrayManager) reloadInbound(tag string) error {
@@ -0,0 +1,117 @@
+package xray
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// RoutingConfig is the panel-managed part of the Xray config: extra
+// outbounds and routing rules, both already rendered as Xray JSON objects.
+type RoutingConfig struct {
+	DomainStrategy string            `json:"domainStrategy"`
+	Outbounds      []json.RawMessage `json:"outbounds"`
+	Rules          []json.RawMessage `json:"rules"`
+}
+
+// SetRouting replaces the panel-managed outbounds and routing rules used by
+// the next config build and returns the previous ones. nil restores the
+// built-in direct/blocked defaults.
+func (m *XrayManager) SetRouting(routing *RoutingConfig) *RoutingConfig {
+	m.routingMu.Lock()
+	defer m.routingMu.Unlock()
+	prev := m.routing
+	m.routing = routing
+	return prev
+}
+
+// applyRouting appends the panel's outbounds after the built-in ones and its
+// rules after the API rule, which must stay first.
+func (m *XrayManager) applyRouting(config map[string]interface{}) {
+	m.routingMu.Lock()
+	routing := m.routing
+	m.routingMu.Unlock()
+	if routing == nil {
+		return
+	}
+
+	outbounds := config["outbounds"].([]map[string]interface{})
+	for _, raw := range routing.Outbounds {
+		var ob map[string]interface{}
+		if err := json.Unmarshal(raw, &ob); err != nil {
+			fmt.Printf("⚠️ Skipping invalid outbound: %v\n", err)
+			continue
+		}
+		outbounds = append(outbounds, ob)
+	}
+	config["outbounds"] = outbounds
+
+	routingObj := config["routing"].(map[string]interface{})
+	rules := routingObj["rules"].([]map[string]interface{})
+	for _, raw := range routing.Rules {
+		var rule map[string]interface{}
+		if err := json.Unmarshal(raw, &rule); err != nil {
+			fmt.Printf("⚠️ Skipping invalid routing rule: %v\n", err)
+			continue
+		}
+		rules = append(rules, rule)
+	}
+	routingObj["rules"] = rules
+	if routing.DomainStrategy != "" {
+		routingObj["domainStrategy"] = routing.DomainStrategy
+	}
+}
+
+// TestConfig checks a config with `xray run -test` without touching the live
+// config file. It returns nil when the binary isn't installed, since there is
+// nothing to validate against yet.
+func (m *XrayManager) TestConfig(config map[string]interface{}) error {
+	if err := m.EnsureBinary(); err != nil {
+		return nil
+	}
+	data, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal config: %v", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(m.configPath), "xray-test-*.json")
+	if err != nil {
+		return fmt.Errorf("failed to create temp config: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write temp config: %v", err)
+	}
+	tmp.Close()
+
+	output, err := exec.Command(m.binaryPath, "run", "-test", "-c", tmp.Name()).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("config test failed: %s", lastLines(string(output), 5))
+	}
+	return nil
+}
+
+// ValidateInbounds tests a config holding only the given inbounds, on top
+// of the current routing, without writing or restarting anything.
+func (m *XrayManager) ValidateInbounds(inbounds []InboundConfig) error {
+	return m.TestConfig(m.buildBaseConfig(inbounds))
+}
+
+// lastLines returns the last n non-empty lines of s, where Xray prints the
+// actual error after its version banner.
+func lastLines(s string, n int) string {
+	var lines []string
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,62 @@
+//go:build ignore
+
+// Regenerates testdata/xray_descriptors.pb, the descriptors of the Xray API
+// messages the hand encoding in api.go is checked against. Run from a module
+// that requires github.com/xtls/xray-core:
+//
+//	go run gen_descriptors.go > xray_descriptors.pb
+//
+// The descriptors were generated from xray-core v1.251208.0.
+package main
+
+import (
+	"os"
+
+	_ "github.com/xtls/xray-core/app/proxyman/command"
+	_ "github.com/xtls/xray-core/app/stats/command"
+	_ "github.com/xtls/xray-core/proxy/trojan"
+	_ "github.com/xtls/xray-core/proxy/vless"
+	_ "github.com/xtls/xray-core/proxy/vmess"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+var roots = []string{
+	"app/proxyman/command/command.proto",
+	"app/stats/command/command.proto",
+	"proxy/vless/account.proto",
+	"proxy/vmess/account.proto",
+	"proxy/trojan/config.proto",
+}
+
+func main() {
+	set := &descriptorpb.FileDescriptorSet{}
+	seen := map[string]bool{}
+	var add func(fd protoreflect.FileDescriptor)
+	add = func(fd protoreflect.FileDescriptor) {
+		if seen[fd.Path()] {
+			return
+		}
+		seen[fd.Path()] = true
+		imports := fd.Imports()
+		for i := 0; i < imports.Len(); i++ {
+			add(imports.Get(i).FileDescriptor)
+		}
+		set.File = append(set.File, protodesc.ToFileDescriptorProto(fd))
+	}
+	for _, path := range roots {
+		fd, err := protoregistry.GlobalFiles.FindFileByPath(path)
+		if err != nil {
+			panic(err)
+		}
+		add(fd)
+	}
+	b, err := proto.MarshalOptions{Deterministic: true}.Marshal(set)
+	if err != nil {
+		panic(err)
+	}
+	os.Stdout.Write(b)
+}
@@ -1,64 +1,73 @@
 package xray
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
-	"io"
-	"net/http"
 	"time"
 
-	"github.com/go-gost/x/internal/util/crypto"
+	"github.com/go-gost/x/internal/util/spool"
 )
 
-// TrafficReporter periodically queries Xray traffic stats and uploads to the panel
+// TrafficReporter periodically queries Xray traffic stats and queues them in
+// the node's flow spool, which delivers them to the panel with sequence
+// numbers so a lost response never double-counts traffic.
 type TrafficReporter struct {
-	grpcClient *XrayGrpcClient
-	panelURL   string // Panel base URL (e.g., http://panel:6365)
-	secret     string // Node secret for authentication
-	interval   time.Duration
-	ctx        context.Context
-	cancel     context.CancelFunc
-	aesCrypto  *crypto.AESCrypto
-	useTLS     bool
-}
-
-// TrafficUploadPayload is the JSON payload sent to the panel
-type TrafficUploadPayload struct {
-	Clients []TrafficStat `json:"clients"`
+	source   TrafficSource
+	spool    *spool.Spool
+	interval time.Duration
+	ctx      context.Context
+	cancel   context.CancelFunc
+
+	ipTracker   *AccessLogTracker
+	onOnlineIPs func(map[string]map[string]int64)
+	active      func() bool
+
+	// Deltas already cleared in Xray that the spool failed to persist;
+	// only touched by the reporting loop
+	pending []spool.Item
 }
 
 // NewTrafficReporter creates a new TrafficReporter
-func NewTrafficReporter(grpcAddr, panelURL, secret, binaryPath string, useTLS bool) *TrafficReporter {
-	ctx, cancel := context.WithCancel(context.Background())
-
-	var aesCrypto *crypto.AESCrypto
-	if secret != "" {
-		var err error
-		aesCrypto, err = crypto.NewAESCrypto(secret)
-		if err != nil {
-			fmt.Printf("⚠️ TrafficReporter: failed to create AES crypto: %v\n", err)
-		}
-	}
-
+func NewTrafficReporter(grpcAddr, binaryPath string, sp *spool.Spool) *TrafficReporter {
 	client := NewXrayGrpcClient(grpcAddr)
 	if binaryPath != "" {
 		client.binaryPath = binaryPath
 	}
+	return NewTrafficReporterFrom(client, sp)
+}
 
+// TrafficSource is anything that can report per-client traffic deltas.
+type TrafficSource interface {
+	QueryTraffic(reset bool) ([]TrafficStat, error)
+}
+
+// NewTrafficReporterFrom creates a TrafficReporter that polls src instead of
+// the Xray CLI, e.g. for another runtime exposing a compatible stats API.
+func NewTrafficReporterFrom(src TrafficSource, sp *spool.Spool) *TrafficReporter {
+	ctx, cancel := context.WithCancel(context.Background())
 	return &TrafficReporter{
-		grpcClient: client,
-		panelURL:   panelURL,
-		secret:     secret,
-		interval:   30 * time.Second,
-		ctx:        ctx,
-		cancel:     cancel,
-		aesCrypto:  aesCrypto,
-		useTLS:     useTLS,
+		source:   src,
+		spool:    sp,
+		interval: 30 * time.Second,
+		ctx:      ctx,
+		cancel:   cancel,
 	}
 }
 
+// TrackOnlineIPs tails the access log at path and passes the source IPs seen
+// per client to fn on every report tick. Must be called before Start.
+func (r *TrafficReporter) TrackOnlineIPs(path string, fn func(map[string]map[string]int64)) {
+	r.ipTracker = NewAccessLogTracker(path)
+	r.onOnlineIPs = fn
+	r.ipTracker.Drain() // skip entries from before startup
+}
+
+// SetActive makes the reporter skip ticks while fn returns false, e.g. when
+// the process behind grpcAddr isn't running. Must be called before Start.
+func (r *TrafficReporter) SetActive(fn func() bool) {
+	r.active = fn
+}
+
 // Start begins the traffic reporting loop
 func (r *TrafficReporter) Start() {
 	go r.run()
@@ -78,90 +87,63 @@ func (r *TrafficReporter) run() {
 		case <-r.ctx.Done():
 			return
 		case <-ticker.C:
+			if r.active != nil && !r.active() {
+				continue
+			}
 			r.reportTraffic()
+			r.reportOnlineIPs()
 		}
 	}
 }
 
 func (r *TrafficReporter) reportTraffic() {
-	// Query traffic with reset=true to get incremental stats
-	stats, err := r.grpcClient.QueryTraffic(true)
-	if err != nil {
-		fmt.Printf("⚠️ Traffic query failed: %v\n", err)
+	if r.spool == nil {
 		return
 	}
 
-	if len(stats) == 0 {
+	// Query traffic with reset=true to get incremental stats. Xray's counters
+	// are cleared by the query, so the deltas are kept in memory until the
+	// spool has them on disk and retried on the next tick if that fails.
+	stats, err := r.source.QueryTraffic(true)
+	if err != nil {
+		fmt.Printf("⚠️ Traffic query failed: %v\n", err)
 		return
 	}
 
-	// Filter out zero-traffic entries
-	var nonZeroStats []TrafficStat
 	for _, stat := range stats {
 		if stat.Uplink > 0 || stat.Downlink > 0 {
-			nonZeroStats = append(nonZeroStats, stat)
+			r.pending = mergeItem(r.pending, spool.Item{Key: stat.Email, U: stat.Uplink, D: stat.Downlink})
 		}
 	}
-
-	if len(nonZeroStats) == 0 {
+	if len(r.pending) == 0 {
 		return
 	}
 
-	// Build upload payload
-	payload := TrafficUploadPayload{
-		Clients: nonZeroStats,
-	}
-
-	payloadJSON, err := json.Marshal(payload)
-	if err != nil {
-		fmt.Printf("⚠️ Failed to marshal traffic data: %v\n", err)
+	if err := r.spool.Record(spool.KindXray, r.pending); err != nil {
+		fmt.Printf("⚠️ Failed to persist traffic data, retrying next tick: %v\n", err)
 		return
 	}
+	fmt.Printf("📊 Traffic queued: %d clients\n", len(r.pending))
+	r.pending = nil
+}
 
-	// Upload to panel
-	scheme := "http"
-	if r.useTLS {
-		scheme = "https"
-	}
-	url := fmt.Sprintf("%s://%s/flow/su?secret=%s", scheme, r.panelURL, r.secret)
-
-	var bodyData []byte
-	if r.aesCrypto != nil {
-		// Encrypt the payload
-		encrypted, err := r.aesCrypto.Encrypt(payloadJSON)
-		if err != nil {
-			fmt.Printf("⚠️ Failed to encrypt traffic data: %v\n", err)
-			bodyData = payloadJSON
-		} else {
-			wrapper := map[string]interface{}{
-				"encrypted": true,
-				"data":      encrypted,
-				"timestamp": time.Now().Unix(),
-			}
-			bodyData, _ = json.Marshal(wrapper)
+// mergeItem adds it to items, summing with an entry for the same client.
+func mergeItem(items []spool.Item, it spool.Item) []spool.Item {
+	for i := range items {
+		if items[i].Key == it.Key {
+			items[i].U += it.U
+			items[i].D += it.D
+			return items
 		}
-	} else {
-		bodyData = payloadJSON
 	}
+	return append(items, it)
+}
 
-	req, err := http.NewRequest("POST", url, bytes.NewReader(bodyData))
-	if err != nil {
-		fmt.Printf("⚠️ Failed to create traffic request: %v\n", err)
+func (r *TrafficReporter) reportOnlineIPs() {
+	if r.ipTracker == nil || r.onOnlineIPs == nil {
 		return
 	}
-	req.Header.Set("Content-Type", "application/json")
-	if r.secret != "" {
-		req.Header.Set("X-Node-Secret", r.secret)
-	}
-
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		fmt.Printf("⚠️ Failed to upload traffic: %v\n", err)
-		return
+	if ips := r.ipTracker.Drain(); len(ips) > 0 {
+		r.onOnlineIPs(ips)
 	}
-	defer resp.Body.Close()
-	io.Copy(io.Discard, resp.Body)
-
-	fmt.Printf("📊 Traffic reported: %d clients\n", len(nonZeroStats))
 }
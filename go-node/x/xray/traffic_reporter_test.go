@@ -0,0 +1,69 @@
+package xray
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-gost/x/internal/util/spool"
+)
+
+// fakeTraffic hands out the given deltas once per query, like Xray with
+// reset=true.
+type fakeTraffic struct{ ticks [][]TrafficStat }
+
+func (f *fakeTraffic) QueryTraffic(reset bool) ([]TrafficStat, error) {
+	if len(f.ticks) == 0 {
+		return nil, nil
+	}
+	stats := f.ticks[0]
+	f.ticks = f.ticks[1:]
+	return stats, nil
+}
+
+func TestReportTrafficKeepsDeltasWhenSpoolFails(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "spool")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "flow_spool.json")
+	sp, err := spool.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := &fakeTraffic{ticks: [][]TrafficStat{
+		{{Email: "a", Uplink: 100, Downlink: 10}},
+		{{Email: "a", Uplink: 1, Downlink: 2}, {Email: "b", Uplink: 5}},
+	}}
+	r := NewTrafficReporterFrom(src, sp)
+
+	// The spool can't be written: the cleared counters must not be lost
+	os.RemoveAll(dir)
+	r.reportTraffic()
+	if sp.Len() != 0 || len(r.pending) != 1 {
+		t.Fatalf("after failed write: spool %d entries, pending %v", sp.Len(), r.pending)
+	}
+
+	os.Mkdir(dir, 0755)
+	r.reportTraffic()
+	if len(r.pending) != 0 {
+		t.Fatalf("pending not cleared after a successful write: %v", r.pending)
+	}
+
+	// What is on disk survives a restart
+	reopened, err := spool.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, ok, err := reopened.Head()
+	if err != nil || !ok {
+		t.Fatalf("no entry on disk: %v", err)
+	}
+	got := map[string]spool.Item{}
+	for _, it := range e.Items {
+		got[it.Key] = it
+	}
+	if got["a"].U != 101 || got["a"].D != 12 || got["b"].U != 5 {
+		t.Fatalf("persisted %v", e.Items)
+	}
+}
@@ -6,6 +6,7 @@ import { Button } from '@/components/ui/button';
 import { Input } from '@/components/ui/input';
 import { Label } from '@/components/ui/label';
 import { Switch } from '@/components/ui/switch';
+import { Textarea } from '@/components/ui/textarea';
 import { Save, Loader2, Eye, EyeOff, RefreshCw, CheckCircle, ArrowUpCircle } from 'lucide-react';
 import { toast } from 'sonner';
 import { getConfigs, updateConfigs } from '@/lib/api/config';
@@ -17,7 +18,7 @@ import { useSiteConfig } from '@/lib/site-config';
 interface ConfigFieldDef {
   label: string;
   description: string;
-  type: 'text' | 'switch' | 'password' | 'number';
+  type: 'text' | 'switch' | 'password' | 'number' | 'json';
   suffix?: string;
 }
 
@@ -33,6 +34,16 @@ function ConfigField({ configKey, value, onChange }: { configKey: string; value:
     captcha_enabled: { label: t('config.captchaEnabled'), description: t('config.captchaEnabledDesc'), type: 'switch' },
     monitor_interval: { label: t('config.monitorInterval'), description: t('config.monitorIntervalDesc'), type: 'number', suffix: t('config.seconds') },
     monitor_retention_days: { label: t('config.monitorRetentionDays'), description: t('config.monitorRetentionDaysDesc'), type: 'number', suffix: t('config.days') },
+    timezone: { label: t('config.timezone'), description: t('config.timezoneDesc'), type: 'text' },
+    ip_limit_window: { label: t('config.ipLimitWindow'), description: t('config.ipLimitWindowDesc'), type: 'number', suffix: t('config.seconds') },
+    ip_limit_ban_duration: { label: t('config.ipLimitBanDuration'), description: t('config.ipLimitBanDurationDesc'), type: 'number', suffix: t('config.seconds') },
+    node_matrix_cross_group: { label: t('config.nodeMatrixCrossGroup'), description: t('config.nodeMatrixCrossGroupDesc'), type: 'switch' },
+    node_matrix_max_pairs: { label: t('config.nodeMatrixMaxPairs'), description: t('config.nodeMatrixMaxPairsDesc'), type: 'number' },
+    sub_profile_name: { label: t('config.subProfileName'), description: t('config.subProfileNameDesc'), type: 'text' },
+    sub_update_interval: { label: t('config.subUpdateInterval'), description: t('config.subUpdateIntervalDesc'), type: 'number', suffix: t('config.hours') },
+    sub_info_nodes: { label: t('config.subInfoNodes'), description: t('config.subInfoNodesDesc'), type: 'switch' },
+    singbox_dns_template: { label: t('config.singboxDnsTemplate'), description: t('config.singboxDnsTemplateDesc'), type: 'json' },
+    singbox_route_template: { label: t('config.singboxRouteTemplate'), description: t('config.singboxRouteTemplateDesc'), type: 'json' },
   };
 
   function getFieldDef(key: string): ConfigFieldDef {
@@ -108,6 +119,24 @@ function ConfigField({ configKey, value, onChange }: { configKey: string; value:
     );
   }
 
+  if (field.type === 'json') {
+    return (
+      <div className="grid grid-cols-1 md:grid-cols-3 gap-4 items-start">
+        <Label className="md:text-right font-medium pt-2">{field.label}</Label>
+        <div className="md:col-span-2 space-y-1">
+          <Textarea
+            value={value}
+            onChange={e => onChange(configKey, e.target.value)}
+            placeholder="{}"
+            rows={6}
+            className="font-mono text-xs"
+          />
+          {field.description && <p className="text-xs text-muted-foreground">{field.description}</p>}
+        </div>
+      </div>
+    );
+  }
+
   // Default text input
   return (
     <div className="grid grid-cols-1 md:grid-cols-3 gap-4 items-start">
@@ -135,11 +164,12 @@ export default function ConfigPage() {
   const [updating, setUpdating] = useState(false);
   const [updateInfo, setUpdateInfo] = useState<UpdateInfo | null>(null);
 
-  const configFieldKeys = ['app_name', 'site_name', 'site_desc', 'panel_addr', 'captcha_enabled', 'monitor_interval', 'monitor_retention_days'];
+  const configFieldKeys = ['app_name', 'site_name', 'site_desc', 'panel_addr', 'timezone', 'captcha_enabled', 'monitor_interval', 'monitor_retention_days', 'ip_limit_window', 'ip_limit_ban_duration', 'node_matrix_cross_group', 'node_matrix_max_pairs', 'sub_profile_name', 'sub_update_interval', 'sub_info_nodes', 'singbox_dns_template', 'singbox_route_template'];
 
   const groups: { titleKey: string; keys: string[] }[] = [
-    { titleKey: 'config.basicInfo', keys: ['app_name', 'site_name', 'site_desc', 'panel_addr'] },
-    { titleKey: 'config.securityAndMonitor', keys: ['captcha_enabled', 'monitor_interval', 'monitor_retention_days'] },
+    { titleKey: 'config.basicInfo', keys: ['app_name', 'site_name', 'site_desc', 'panel_addr', 'timezone'] },
+    { titleKey: 'config.securityAndMonitor', keys: ['captcha_enabled', 'monitor_interval', 'monitor_retention_days', 'ip_limit_window', 'ip_limit_ban_duration', 'node_matrix_cross_group', 'node_matrix_max_pairs'] },
+    { titleKey: 'config.subscription', keys: ['sub_profile_name', 'sub_update_interval', 'sub_info_nodes', 'singbox_dns_template', 'singbox_route_template'] },
   ];
 
   const loadData = useCallback(async () => {
@@ -28,7 +28,7 @@ export default function ForwardPage() {
   const [loading, setLoading] = useState(true);
   const [dialogOpen, setDialogOpen] = useState(false);
   const [editingForward, setEditingForward] = useState<any>(null);
-  const [form, setForm] = useState({ name: '', tunnelId: '', remoteAddr: '', inPort: '', listenIp: '', strategy: 'round', interfaceName: '' });
+  const [form, setForm] = useState({ name: '', tunnelId: '', remoteAddr: '', inPort: '', listenIp: '', strategy: 'round', interfaceName: '', udpProbe: 'tcp', udpProbeData: '' });
   const [filterTunnelId, setFilterTunnelId] = useState('');
   const [diagnoseDialogOpen, setDiagnoseDialogOpen] = useState(false);
   const [diagnoseResult, setDiagnoseResult] = useState<any>(null);
@@ -117,7 +117,7 @@ export default function ForwardPage() {
 
   const handleCreate = () => {
     setEditingForward(null);
-    setForm({ name: '', tunnelId: '', remoteAddr: '', inPort: '', listenIp: '::', strategy: 'round', interfaceName: '' });
+    setForm({ name: '', tunnelId: '', remoteAddr: '', inPort: '', listenIp: '::', strategy: 'round', interfaceName: '', udpProbe: 'tcp', udpProbeData: '' });
     setDialogOpen(true);
   };
 
@@ -131,6 +131,8 @@ export default function ForwardPage() {
       listenIp: forward.listenIp || '::',
       strategy: forward.strategy || 'round',
       interfaceName: forward.interfaceName || '',
+      udpProbe: forward.udpProbe || 'tcp',
+      udpProbeData: forward.udpProbeData || '',
     });
     setDialogOpen(true);
   };
@@ -147,6 +149,8 @@ export default function ForwardPage() {
       listenIp: form.listenIp || undefined,
       strategy: form.strategy,
       interfaceName: form.interfaceName || null,
+      udpProbe: form.udpProbe === 'tcp' ? '' : form.udpProbe,
+      udpProbeData: form.udpProbe === 'dns' || form.udpProbe === 'hex' ? form.udpProbeData.trim() : '',
     };
     if (form.inPort) data.inPort = parseInt(form.inPort);
 
@@ -516,7 +520,7 @@ export default function ForwardPage() {
 
       {/* Diagnose Dialog */}
       <Dialog open={diagnoseDialogOpen} onOpenChange={setDiagnoseDialogOpen}>
-        <DialogContent className="max-w-lg">
+        <DialogContent className="max-w-2xl">
           <DialogHeader>
             <DialogTitle>{t('forward.diagnoseResult')} — {diagnoseResult?.forwardName}</DialogTitle>
           </DialogHeader>
@@ -537,7 +541,7 @@ export default function ForwardPage() {
                       )}
                     </div>
                     <div className="text-xs text-muted-foreground">
-                      {r.nodeName} → {r.targetIp}:{r.targetPort}
+                      {r.nodeName} → {r.targetIp}{r.targetPort ? `:${r.targetPort}` : ''}{r.protocol && <span className="ml-1 uppercase">({r.protocol})</span>}
                     </div>
                     {r.success ? (
                       <div className="text-xs">
@@ -547,6 +551,42 @@ export default function ForwardPage() {
                     ) : (
                       <div className="text-xs text-destructive">{r.message}</div>
                     )}
+                    {r.trace ? (
+                      <details className="text-xs">
+                        <summary className="cursor-pointer text-muted-foreground">
+                          {t('forward.traceSummary', { n: r.trace.hops?.length || 0, protocol: r.trace.protocol.toUpperCase() })}
+                          {!r.trace.reached && <span className="ml-2 text-orange-600">{t('forward.traceNotReached')}</span>}
+                        </summary>
+                        <Table className="mt-1">
+                          <TableHeader>
+                            <TableRow>
+                              <TableHead className="h-7 px-1">#</TableHead>
+                              <TableHead className="h-7 px-1">{t('forward.traceHost')}</TableHead>
+                              <TableHead className="h-7 px-1 text-right">{t('forward.packetLoss')}</TableHead>
+                              <TableHead className="h-7 px-1 text-right">{t('forward.traceAvg')}</TableHead>
+                              <TableHead className="h-7 px-1 text-right">{t('forward.traceBest')}</TableHead>
+                              <TableHead className="h-7 px-1 text-right">{t('forward.traceWorst')}</TableHead>
+                            </TableRow>
+                          </TableHeader>
+                          <TableBody>
+                            {r.trace.hops?.map((h: any) => (
+                              <TableRow key={h.ttl}>
+                                <TableCell className="py-1 px-1">{h.ttl}</TableCell>
+                                <TableCell className="py-1 px-1 font-mono" title={h.ips?.join(', ')}>
+                                  {h.ip || '*'}{h.ips?.length > 1 && <span className="text-muted-foreground"> +{h.ips.length - 1}</span>}
+                                </TableCell>
+                                <TableCell className={`py-1 px-1 text-right ${h.loss > 0 ? 'text-orange-600' : ''}`}>{h.loss.toFixed(0)}%</TableCell>
+                                <TableCell className="py-1 px-1 text-right font-mono">{h.received ? h.avg.toFixed(1) : '-'}</TableCell>
+                                <TableCell className="py-1 px-1 text-right font-mono">{h.received ? h.best.toFixed(1) : '-'}</TableCell>
+                                <TableCell className="py-1 px-1 text-right font-mono">{h.received ? h.worst.toFixed(1) : '-'}</TableCell>
+                              </TableRow>
+                            ))}
+                          </TableBody>
+                        </Table>
+                      </details>
+                    ) : r.traceError ? (
+                      <div className="text-xs text-muted-foreground">{t('forward.traceFailed')}: {r.traceError}</div>
+                    ) : null}
                   </div>
                 ))}
               </div>
@@ -695,6 +735,33 @@ export default function ForwardPage() {
                 </>
               );
             })()}
+            <div className="grid grid-cols-2 gap-4">
+              <div className="space-y-2">
+                <Label>{t('forward.probe')}</Label>
+                <Select value={form.udpProbe} onValueChange={v => setForm(p => ({ ...p, udpProbe: v }))}>
+                  <SelectTrigger><SelectValue /></SelectTrigger>
+                  <SelectContent>
+                    <SelectItem value="tcp">{t('forward.probeTcp')}</SelectItem>
+                    <SelectItem value="echo">{t('forward.probeUdpEcho')}</SelectItem>
+                    <SelectItem value="dns">{t('forward.probeUdpDns')}</SelectItem>
+                    <SelectItem value="quic">{t('forward.probeUdpQuic')}</SelectItem>
+                    <SelectItem value="hex">{t('forward.probeUdpHex')}</SelectItem>
+                  </SelectContent>
+                </Select>
+              </div>
+              {(form.udpProbe === 'dns' || form.udpProbe === 'hex') && (
+                <div className="space-y-2">
+                  <Label>{form.udpProbe === 'dns' ? t('forward.probeDnsName') : t('forward.probeHexPayload')}</Label>
+                  <Input
+                    value={form.udpProbeData}
+                    onChange={e => setForm(p => ({ ...p, udpProbeData: e.target.value }))}
+                    placeholder={form.udpProbe === 'dns' ? 'example.com' : 'ffffffff54536f7572636520456e67696e6520517565727900'}
+                    className="font-mono"
+                  />
+                </div>
+              )}
+            </div>
+            {form.udpProbe !== 'tcp' && <p className="text-xs text-muted-foreground -mt-2">{t('forward.probeUdpHint')}</p>}
           </div>
           <DialogFooter>
             <Button variant="outline" onClick={() => setDialogOpen(false)}>{t('common.cancel')}</Button>
@@ -10,7 +10,7 @@ import {
 import {
   LayoutDashboard, ArrowRightLeft, Link2, Server, Users, Clock, Settings,
   Menu, ChevronDown, LogOut, KeyRound, Shield, Inbox, Award, Rss,
-  Activity,
+  Activity, Route, RefreshCw, Ticket, Grid3x3,
 } from 'lucide-react';
 import { useAuth, logout } from '@/lib/hooks/use-auth';
 import { useIsMobile } from '@/hooks/use-mobile';
@@ -37,13 +37,17 @@ const navItems: NavItem[] = [
   { path: '/limit', labelKey: 'nav.limit', icon: <Clock className="h-4 w-4" />, adminOnly: true, section: 'GOST', sectionKey: 'GOST' },
   // Xray
   { path: '/xray/inbound', labelKey: 'nav.xrayInbound', icon: <Inbox className="h-4 w-4" />, section: 'Xray', sectionKey: 'Xray' },
+  { path: '/xray/routing', labelKey: 'nav.xrayRouting', icon: <Route className="h-4 w-4" />, adminOnly: true, section: 'Xray', sectionKey: 'Xray' },
   { path: '/xray/certificate', labelKey: 'nav.xrayCert', icon: <Award className="h-4 w-4" />, section: 'Xray', sectionKey: 'Xray' },
   { path: '/xray/subscription', labelKey: 'nav.xraySub', icon: <Rss className="h-4 w-4" />, section: 'Xray', sectionKey: 'Xray' },
   // System
   { path: '/node', labelKey: 'nav.node', icon: <Server className="h-4 w-4" />, adminOnly: true, section: 'system', sectionKey: 'nav.system' },
   { path: '/user', labelKey: 'nav.user', icon: <Users className="h-4 w-4" />, adminOnly: true, section: 'system', sectionKey: 'nav.system' },
+  { path: '/node/join-token', labelKey: 'nav.nodeJoinToken', icon: <Ticket className="h-4 w-4" />, adminOnly: true, section: 'system', sectionKey: 'nav.system' },
+  { path: '/node/rollout', labelKey: 'nav.nodeRollout', icon: <RefreshCw className="h-4 w-4" />, adminOnly: true, section: 'system', sectionKey: 'nav.system' },
   { path: '/monitor/node', labelKey: 'nav.nodeMonitor', icon: <Server className="h-4 w-4" />, adminOnly: true, section: 'system', sectionKey: 'nav.system' },
   { path: '/monitor/network', labelKey: 'nav.networkMonitor', icon: <Activity className="h-4 w-4" />, adminOnly: true, section: 'system', sectionKey: 'nav.system' },
+  { path: '/monitor/matrix', labelKey: 'nav.nodeMatrix', icon: <Grid3x3 className="h-4 w-4" />, adminOnly: true, section: 'system', sectionKey: 'nav.system' },
   { path: '/config', labelKey: 'nav.config', icon: <Settings className="h-4 w-4" />, adminOnly: true, section: 'system', sectionKey: 'nav.system' },
 ];
 
@@ -0,0 +1,264 @@
+'use client';
+
+import { useState, useEffect, useCallback, useMemo } from 'react';
+import { Card, CardContent } from '@/components/ui/card';
+import { Button } from '@/components/ui/button';
+import { Badge } from '@/components/ui/badge';
+import { Table, TableBody, TableCell, TableHead, TableHeader, TableRow } from '@/components/ui/table';
+import { Dialog, DialogContent, DialogHeader, DialogTitle } from '@/components/ui/dialog';
+import { Select, SelectContent, SelectItem, SelectTrigger, SelectValue } from '@/components/ui/select';
+import { RefreshCw, Play, Loader2 } from 'lucide-react';
+import { toast } from 'sonner';
+import { useAuth } from '@/lib/hooks/use-auth';
+import { getNodeMatrix, getNodeLinkHistory, testNodeLink, runNodeMatrix } from '@/lib/api/monitor';
+import { useTranslation } from '@/lib/i18n';
+import { LineChart, Line, XAxis, YAxis, CartesianGrid, Tooltip, ResponsiveContainer, Legend } from 'recharts';
+
+interface MatrixNode {
+  id: number;
+  name: string;
+  groupName?: string;
+  online: boolean;
+}
+
+interface NodeLink {
+  srcNodeId: number;
+  dstNodeId: number;
+  success: boolean;
+  error: string;
+  latency: number;
+  jitter: number;
+  tcpMbps: number;
+  udpMbps: number;
+  loss: number;
+  recordTime: number;
+}
+
+function formatTime(ts: number) {
+  const d = new Date(ts * 1000);
+  return `${d.getMonth() + 1}/${d.getDate()} ${String(d.getHours()).padStart(2, '0')}:${String(d.getMinutes()).padStart(2, '0')}`;
+}
+
+// Cell colour from loss first, then latency
+function linkColor(link: NodeLink) {
+  if (!link.success) return 'bg-destructive/15';
+  if (link.loss >= 5 || link.latency >= 200) return 'bg-orange-500/15';
+  if (link.loss >= 1 || link.latency >= 100) return 'bg-yellow-500/15';
+  return 'bg-green-500/10';
+}
+
+export default function NodeMatrixPage() {
+  const { isAdmin } = useAuth();
+  const { t } = useTranslation();
+  const [nodes, setNodes] = useState<MatrixNode[]>([]);
+  const [links, setLinks] = useState<NodeLink[]>([]);
+  const [loading, setLoading] = useState(true);
+  const [testing, setTesting] = useState<string | null>(null);
+  const [selected, setSelected] = useState<{ src: MatrixNode; dst: MatrixNode } | null>(null);
+  const [historyHours, setHistoryHours] = useState('168');
+  const [history, setHistory] = useState<any[]>([]);
+
+  const loadData = useCallback(async () => {
+    const res = await getNodeMatrix();
+    if (res.code === 0) {
+      setNodes(res.data?.nodes || []);
+      setLinks(res.data?.links || []);
+    }
+    setLoading(false);
+  }, []);
+
+  useEffect(() => { loadData(); }, [loadData]);
+
+  const linkMap = useMemo(() => {
+    const m = new Map<string, NodeLink>();
+    for (const l of links) m.set(`${l.srcNodeId}-${l.dstNodeId}`, l);
+    return m;
+  }, [links]);
+
+  useEffect(() => {
+    if (!selected) return;
+    getNodeLinkHistory(selected.src.id, selected.dst.id, parseInt(historyHours)).then((res) => {
+      if (res.code !== 0) return;
+      setHistory((res.data || []).filter((r: NodeLink) => r.success).map((r: NodeLink) => ({ ...r, time: formatTime(r.recordTime) })));
+    });
+  }, [selected, historyHours]);
+
+  const handleRunAll = async () => {
+    const res = await runNodeMatrix();
+    if (res.code === 0) toast.success(t('nodeMatrix.runQueued'));
+    else toast.error(res.msg);
+  };
+
+  const handleTest = async (src: MatrixNode, dst: MatrixNode) => {
+    const key = `${src.id}-${dst.id}`;
+    setTesting(key);
+    const res = await testNodeLink(src.id, dst.id);
+    setTesting(null);
+    if (res.code !== 0) {
+      toast.error(res.msg);
+      return;
+    }
+    if (res.data?.success) toast.success(t('nodeMatrix.testDone'));
+    else toast.error(res.data?.error || t('nodeMatrix.failed'));
+    loadData();
+    if (selected?.src.id === src.id && selected?.dst.id === dst.id) setSelected({ src, dst });
+  };
+
+  if (!isAdmin) {
+    return (
+      <div className="flex items-center justify-center h-64">
+        <p className="text-muted-foreground">{t('common.noPermission')}</p>
+      </div>
+    );
+  }
+
+  const renderCell = (src: MatrixNode, dst: MatrixNode) => {
+    if (src.id === dst.id) return <TableCell key={dst.id} className="bg-muted/50" />;
+    const key = `${src.id}-${dst.id}`;
+    const link = linkMap.get(key);
+    return (
+      <TableCell key={dst.id} className={`p-1 ${link ? linkColor(link) : ''}`}>
+        <button className="w-full text-left text-xs px-1 py-0.5 hover:underline" onClick={() => setSelected({ src, dst })}>
+          {testing === key ? (
+            <Loader2 className="h-3.5 w-3.5 animate-spin" />
+          ) : !link ? (
+            <span className="text-muted-foreground">-</span>
+          ) : !link.success ? (
+            <span className="text-destructive" title={link.error}>{t('nodeMatrix.failed')}</span>
+          ) : (
+            <div className="space-y-0.5" title={formatTime(link.recordTime)}>
+              <div>{link.latency.toFixed(1)} ms</div>
+              <div className="text-muted-foreground">{link.tcpMbps.toFixed(0)} Mbps</div>
+              <div className={link.loss > 0 ? 'text-orange-600' : 'text-muted-foreground'}>{link.loss.toFixed(1)}%</div>
+            </div>
+          )}
+        </button>
+      </TableCell>
+    );
+  };
+
+  const selectedLink = selected ? linkMap.get(`${selected.src.id}-${selected.dst.id}`) : undefined;
+
+  return (
+    <div className="space-y-4">
+      <div className="flex items-center justify-between">
+        <h2 className="text-2xl font-bold">{t('nodeMatrix.title')}</h2>
+        <div className="flex gap-2">
+          <Button variant="outline" onClick={loadData}><RefreshCw className="mr-2 h-4 w-4" />{t('common.refresh')}</Button>
+          <Button onClick={handleRunAll}><Play className="mr-2 h-4 w-4" />{t('nodeMatrix.runAll')}</Button>
+        </div>
+      </div>
+      <p className="text-sm text-muted-foreground">{t('nodeMatrix.hint')}</p>
+
+      <Card>
+        <CardContent className="p-0 overflow-x-auto">
+          {loading ? (
+            <p className="text-center py-8">{t('common.loading')}</p>
+          ) : nodes.length < 2 ? (
+            <p className="text-center py-8 text-muted-foreground">{t('common.noData')}</p>
+          ) : (
+            <Table>
+              <TableHeader>
+                <TableRow>
+                  <TableHead className="whitespace-nowrap">{t('nodeMatrix.source')} \ {t('nodeMatrix.target')}</TableHead>
+                  {nodes.map(n => (
+                    <TableHead key={n.id} className="whitespace-nowrap text-xs">{n.name}</TableHead>
+                  ))}
+                </TableRow>
+              </TableHeader>
+              <TableBody>
+                {nodes.map(src => (
+                  <TableRow key={src.id}>
+                    <TableCell className="font-medium text-xs whitespace-nowrap">
+                      {src.name}
+                      {!src.online && <Badge variant="secondary" className="ml-1 text-xs">{t('common.offline')}</Badge>}
+                    </TableCell>
+                    {nodes.map(dst => renderCell(src, dst))}
+                  </TableRow>
+                ))}
+              </TableBody>
+            </Table>
+          )}
+        </CardContent>
+      </Card>
+
+      <Dialog open={!!selected} onOpenChange={(open) => { if (!open) { setSelected(null); setHistory([]); } }}>
+        <DialogContent className="max-w-3xl">
+          <DialogHeader>
+            <DialogTitle>{selected?.src.name} → {selected?.dst.name}</DialogTitle>
+          </DialogHeader>
+          {selected && (
+            <div className="space-y-4">
+              <div className="flex items-center justify-between gap-2">
+                <div className="text-sm">
+                  {!selectedLink ? (
+                    <span className="text-muted-foreground">{t('nodeMatrix.notTested')}</span>
+                  ) : selectedLink.success ? (
+                    <span>
+                      {t('nodeMatrix.latency')} {selectedLink.latency.toFixed(1)} ms · {t('nodeMatrix.jitter')} {selectedLink.jitter.toFixed(1)} ms
+                      · TCP {selectedLink.tcpMbps.toFixed(1)} Mbps · UDP {selectedLink.udpMbps.toFixed(1)} Mbps
+                      · {t('nodeMatrix.loss')} {selectedLink.loss.toFixed(2)}%
+                      <span className="text-muted-foreground ml-2">{formatTime(selectedLink.recordTime)}</span>
+                    </span>
+                  ) : (
+                    <span className="text-destructive">{selectedLink.error}</span>
+                  )}
+                </div>
+                <div className="flex items-center gap-2">
+                  <Select value={historyHours} onValueChange={setHistoryHours}>
+                    <SelectTrigger className="w-28"><SelectValue /></SelectTrigger>
+                    <SelectContent>
+                      <SelectItem value="24">{t('monitor.hours24')}</SelectItem>
+                      <SelectItem value="168">{t('monitor.days7')}</SelectItem>
+                    </SelectContent>
+                  </Select>
+                  <Button size="sm" onClick={() => handleTest(selected.src, selected.dst)} disabled={testing !== null}>
+                    {testing ? <Loader2 className="mr-2 h-4 w-4 animate-spin" /> : <Play className="mr-2 h-4 w-4" />}
+                    {t('nodeMatrix.testNow')}
+                  </Button>
+                </div>
+              </div>
+
+              {history.length === 0 ? (
+                <p className="text-center py-8 text-muted-foreground">{t('common.noData')}</p>
+              ) : (
+                <>
+                  <div>
+                    <p className="text-sm font-medium mb-1">{t('nodeMatrix.latency')} / {t('nodeMatrix.loss')}</p>
+                    <ResponsiveContainer width="100%" height={180}>
+                      <LineChart data={history}>
+                        <CartesianGrid strokeDasharray="3 3" />
+                        <XAxis dataKey="time" fontSize={11} />
+                        <YAxis yAxisId="ms" fontSize={11} unit="ms" />
+                        <YAxis yAxisId="loss" orientation="right" fontSize={11} unit="%" />
+                        <Tooltip />
+                        <Legend />
+                        <Line yAxisId="ms" type="monotone" dataKey="latency" name={t('nodeMatrix.latency')} stroke="#8884d8" dot={false} />
+                        <Line yAxisId="ms" type="monotone" dataKey="jitter" name={t('nodeMatrix.jitter')} stroke="#82ca9d" dot={false} />
+                        <Line yAxisId="loss" type="monotone" dataKey="loss" name={t('nodeMatrix.loss')} stroke="#ff7c43" dot={false} />
+                      </LineChart>
+                    </ResponsiveContainer>
+                  </div>
+                  <div>
+                    <p className="text-sm font-medium mb-1">Mbps</p>
+                    <ResponsiveContainer width="100%" height={180}>
+                      <LineChart data={history}>
+                        <CartesianGrid strokeDasharray="3 3" />
+                        <XAxis dataKey="time" fontSize={11} />
+                        <YAxis fontSize={11} />
+                        <Tooltip />
+                        <Legend />
+                        <Line type="monotone" dataKey="tcpMbps" name={t('nodeMatrix.tcp')} stroke="#4363d8" dot={false} />
+                        <Line type="monotone" dataKey="udpMbps" name={t('nodeMatrix.udp')} stroke="#469990" dot={false} />
+                      </LineChart>
+                    </ResponsiveContainer>
+                  </div>
+                </>
+              )}
+            </div>
+          )}
+        </DialogContent>
+      </Dialog>
+    </div>
+  );
+}
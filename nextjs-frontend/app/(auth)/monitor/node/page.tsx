@@ -5,10 +5,13 @@ import { Card, CardContent, CardHeader, CardTitle } from '@/components/ui/card';
 import { Badge } from '@/components/ui/badge';
 import { Button } from '@/components/ui/button';
 import { Table, TableBody, TableCell, TableHead, TableHeader, TableRow } from '@/components/ui/table';
-import { Server, Cpu, HardDrive, Network, RefreshCw, LayoutGrid, TableProperties } from 'lucide-react';
+import { Dialog, DialogContent, DialogHeader, DialogTitle } from '@/components/ui/dialog';
+import { Select, SelectContent, SelectItem, SelectTrigger, SelectValue } from '@/components/ui/select';
+import { Server, Cpu, HardDrive, Network, RefreshCw, LayoutGrid, TableProperties, LineChart as LineChartIcon } from 'lucide-react';
 import { useAuth } from '@/lib/hooks/use-auth';
-import { getNodeHealth } from '@/lib/api/monitor';
+import { getNodeHealth, getNodeMetricsHistory } from '@/lib/api/monitor';
 import { useTranslation } from '@/lib/i18n';
+import { LineChart, Line, XAxis, YAxis, CartesianGrid, Tooltip, ResponsiveContainer, Legend } from 'recharts';
 
 function formatBytes(bytes: number) {
   if (bytes === 0) return '0 B';
@@ -51,6 +54,22 @@ interface NodeHealth {
   bytesTransmitted?: number;
   panelAddr?: string;
   runtime?: string;
+  diskUsage?: number;
+  disks?: { mount: string; fstype: string; total: number; used: number; usage: number }[];
+  load1?: number;
+  load5?: number;
+  load15?: number;
+  netStats?: { name: string; rxBytes: number; txBytes: number; rxRate: number; txRate: number }[];
+  tcpEstablished?: number;
+  tcpStates?: Record<string, number>;
+  udpSockets?: number;
+  conntrackCount?: number;
+  conntrackMax?: number;
+  kernel?: string;
+  os?: string;
+  arch?: string;
+  gostConns?: number;
+  forwardConns?: { forwardId: number; name: string; conns: number }[];
 }
 
 export default function NodeMonitorPage() {
@@ -156,7 +175,14 @@ export default function NodeMonitorPage() {
 
               setNodes(prev => prev.map(n =>
                 n.id === nodeId
-                  ? { ...n, bytesReceived: rx, bytesTransmitted: tx, cpuUsage: sysData.cpu_usage, memUsage: sysData.memory_usage, uptime: sysData.uptime }
+                  ? {
+                    ...n, bytesReceived: rx, bytesTransmitted: tx, cpuUsage: sysData.cpu_usage, memUsage: sysData.memory_usage, uptime: sysData.uptime,
+                    ...(sysData.load1 !== undefined ? {
+                      diskUsage: sysData.disk_usage, load1: sysData.load1, load5: sysData.load5, load15: sysData.load15,
+                      tcpEstablished: sysData.tcp_established, udpSockets: sysData.udp_sockets,
+                      conntrackCount: sysData.conntrack_count, conntrackMax: sysData.conntrack_max, gostConns: sysData.gost_conns,
+                    } : {}),
+                  }
                   : n
               ));
             }
@@ -183,6 +209,27 @@ export default function NodeMonitorPage() {
     };
   }, []);
 
+  const [historyNode, setHistoryNode] = useState<NodeHealth | null>(null);
+  const [historyHours, setHistoryHours] = useState('24');
+  const [history, setHistory] = useState<any[]>([]);
+
+  useEffect(() => {
+    if (!historyNode) return;
+    const hours = parseInt(historyHours);
+    getNodeMetricsHistory(historyNode.id, hours).then((res) => {
+      if (res.code !== 0) return;
+      setHistory((res.data?.records || []).map((r: any) => {
+        const d = new Date(r.recordTime * 1000);
+        return {
+          ...r,
+          time: hours > 24
+            ? `${d.getMonth() + 1}/${d.getDate()} ${d.getHours()}:${String(d.getMinutes()).padStart(2, '0')}`
+            : `${d.getHours()}:${String(d.getMinutes()).padStart(2, '0')}`,
+        };
+      }));
+    });
+  }, [historyNode, historyHours]);
+
   const loadData = useCallback(async () => {
     if (initialLoad.current) setLoading(true);
     setRefreshing(true);
@@ -217,6 +264,9 @@ export default function NodeMonitorPage() {
           <CardTitle className="text-sm font-medium flex items-center gap-2">
             <Server className="h-4 w-4" />
             {node.name}
+            <Button variant="ghost" size="icon" className="h-6 w-6" onClick={() => setHistoryNode(node)} title={t('monitor.metricsHistory')}>
+              <LineChartIcon className="h-3.5 w-3.5" />
+            </Button>
           </CardTitle>
           <Badge variant={node.online ? 'default' : 'secondary'}>
             {node.online ? t('common.online') : t('common.offline')}
@@ -247,6 +297,36 @@ export default function NodeMonitorPage() {
               <span className="flex items-center gap-1"><HardDrive className="h-3 w-3" />{t('monitor.memory')}</span>
               <span>{node.memUsage?.toFixed(1)}%</span>
             </div>
+            {node.disks && node.disks.length > 0 && (
+              <div className="flex items-center justify-between">
+                <span className="flex items-center gap-1"><HardDrive className="h-3 w-3" />{t('monitor.disk')}</span>
+                <span title={node.disks.map(d => `${d.mount} ${formatBytes(d.used)} / ${formatBytes(d.total)}`).join('\n')}>
+                  {node.diskUsage?.toFixed(1)}%
+                </span>
+              </div>
+            )}
+            {node.load1 !== undefined && node.kernel && (
+              <div className="flex items-center justify-between">
+                <span>{t('monitor.load')}</span>
+                <span>{node.load1.toFixed(2)} / {node.load5?.toFixed(2)} / {node.load15?.toFixed(2)}</span>
+              </div>
+            )}
+            {node.tcpStates && (
+              <div className="flex items-center justify-between">
+                <span>{t('monitor.connections')}</span>
+                <span title={Object.entries(node.tcpStates).map(([k, v]) => `${k}: ${v}`).join('\n')}>
+                  TCP {node.tcpEstablished} · UDP {node.udpSockets}
+                </span>
+              </div>
+            )}
+            {!!node.conntrackMax && (
+              <div className="flex items-center justify-between">
+                <span>{t('monitor.conntrack')}</span>
+                <span className={(node.conntrackCount || 0) / node.conntrackMax > 0.8 ? 'text-destructive' : ''}>
+                  {node.conntrackCount} / {node.conntrackMax}
+                </span>
+              </div>
+            )}
             {node.uptime !== undefined && (
               <div className="flex items-center justify-between">
                 <span>{t('monitor.uptime')}</span>
@@ -255,8 +335,23 @@ export default function NodeMonitorPage() {
             )}
             <div className="flex items-center justify-between">
               <span>GOST</span>
-              <Badge variant="default" className="text-xs">{t('monitor.running')}</Badge>
+              <span className="flex items-center gap-1">
+                {node.gostConns !== undefined && node.kernel && (
+                  <span className="text-xs text-muted-foreground">{t('monitor.activeConns', { n: node.gostConns })}</span>
+                )}
+                <Badge variant="default" className="text-xs">{t('monitor.running')}</Badge>
+              </span>
             </div>
+            {node.forwardConns && node.forwardConns.length > 0 && (
+              <div className="space-y-0.5">
+                {node.forwardConns.map(f => (
+                  <div key={f.forwardId} className="flex items-center justify-between text-xs text-muted-foreground">
+                    <span className="truncate">{f.name || `#${f.forwardId}`}</span>
+                    <span>{f.conns}</span>
+                  </div>
+                ))}
+              </div>
+            )}
             <div className="flex items-center justify-between">
               <span>Xray</span>
               {node.vRunning ? (
@@ -307,12 +402,18 @@ export default function NodeMonitorPage() {
                   <span className="text-xs">{t('monitor.nic')}</span>
                 </div>
                 <div className="space-y-0.5">
-                  {node.interfaces.map((iface) => (
-                    <div key={iface.name} className="text-xs font-mono">
-                      <span className="text-foreground">{iface.name}</span>
-                      <span className="text-muted-foreground ml-1">{iface.ips.join(', ')}</span>
-                    </div>
-                  ))}
+                  {node.interfaces.map((iface) => {
+                    const stat = node.netStats?.find(s => s.name === iface.name);
+                    return (
+                      <div key={iface.name} className="text-xs font-mono">
+                        <span className="text-foreground">{iface.name}</span>
+                        <span className="text-muted-foreground ml-1">{iface.ips.join(', ')}</span>
+                        {stat && (
+                          <span className="text-muted-foreground ml-1">↑{formatSpeed(stat.txRate)} ↓{formatSpeed(stat.rxRate)}</span>
+                        )}
+                      </div>
+                    );
+                  })}
                 </div>
               </div>
             )}
@@ -321,6 +422,11 @@ export default function NodeMonitorPage() {
         {node.version && (
           <div className="text-xs text-muted-foreground">v{node.version}</div>
         )}
+        {node.online && node.os && (
+          <div className="text-xs text-muted-foreground truncate" title={`${node.os} ${node.arch || ''} · ${node.kernel || ''}`}>
+            {node.os}{node.arch ? ` (${node.arch})` : ''} · {node.kernel}
+          </div>
+        )}
       </CardContent>
     </Card>
   );
@@ -334,7 +440,7 @@ export default function NodeMonitorPage() {
       if (hasGroups && group !== lastGroup) {
         rows.push(
           <TableRow key={`group-${group}`} className="bg-muted/50 hover:bg-muted/50">
-            <TableCell colSpan={13} className="py-1.5 px-4 text-xs font-semibold text-muted-foreground uppercase tracking-wider">
+            <TableCell colSpan={16} className="py-1.5 px-4 text-xs font-semibold text-muted-foreground uppercase tracking-wider">
               {group || t('monitor.ungrouped')}
             </TableCell>
           </TableRow>
@@ -343,7 +449,14 @@ export default function NodeMonitorPage() {
       }
       rows.push(
         <TableRow key={node.id}>
-          <TableCell className="font-medium text-sm">{node.name}</TableCell>
+          <TableCell className="font-medium text-sm">
+            <div className="flex items-center gap-1">
+              {node.name}
+              <Button variant="ghost" size="icon" className="h-6 w-6" onClick={() => setHistoryNode(node)} title={t('monitor.metricsHistory')}>
+                <LineChartIcon className="h-3.5 w-3.5" />
+              </Button>
+            </div>
+          </TableCell>
           <TableCell>
             <div className="flex items-center gap-1">
               <Badge variant={node.online ? 'default' : 'secondary'} className="text-xs">
@@ -359,6 +472,9 @@ export default function NodeMonitorPage() {
           <TableCell className="text-sm">{node.serverIp}</TableCell>
           <TableCell className="text-sm">{node.online && node.cpuUsage != null ? `${node.cpuUsage.toFixed(1)}%` : '-'}</TableCell>
           <TableCell className="text-sm">{node.online && node.memUsage != null ? `${node.memUsage.toFixed(1)}%` : '-'}</TableCell>
+          <TableCell className="text-sm">{node.online && node.disks?.length ? `${node.diskUsage?.toFixed(1)}%` : '-'}</TableCell>
+          <TableCell className="text-sm">{node.online && node.kernel && node.load1 != null ? node.load1.toFixed(2) : '-'}</TableCell>
+          <TableCell className="text-sm">{node.online && node.tcpStates ? `${node.tcpEstablished} / ${node.gostConns ?? 0}` : '-'}</TableCell>
           <TableCell className="text-sm">
             {node.online ? <Badge variant="default" className="text-xs">{t('monitor.running')}</Badge> : '-'}
           </TableCell>
@@ -432,6 +548,9 @@ export default function NodeMonitorPage() {
                   <TableHead>IP</TableHead>
                   <TableHead>CPU</TableHead>
                   <TableHead>{t('monitor.memory')}</TableHead>
+                  <TableHead>{t('monitor.disk')}</TableHead>
+                  <TableHead>{t('monitor.load')}</TableHead>
+                  <TableHead title={t('monitor.connsColumnHint')}>{t('monitor.connections')}</TableHead>
                   <TableHead>GOST</TableHead>
                   <TableHead>Xray</TableHead>
                   <TableHead>{t('monitor.upload')}</TableHead>
@@ -464,6 +583,76 @@ export default function NodeMonitorPage() {
           ))}
         </div>
       )}
+
+      <Dialog open={!!historyNode} onOpenChange={(open) => { if (!open) { setHistoryNode(null); setHistory([]); } }}>
+        <DialogContent className="max-w-4xl">
+          <DialogHeader>
+            <DialogTitle>{t('monitor.metricsHistory')} - {historyNode?.name}</DialogTitle>
+          </DialogHeader>
+          <div className="flex justify-end">
+            <Select value={historyHours} onValueChange={setHistoryHours}>
+              <SelectTrigger className="w-32"><SelectValue /></SelectTrigger>
+              <SelectContent>
+                <SelectItem value="1">{t('monitor.hours1')}</SelectItem>
+                <SelectItem value="6">{t('monitor.hours6')}</SelectItem>
+                <SelectItem value="24">{t('monitor.hours24')}</SelectItem>
+                <SelectItem value="168">{t('monitor.days7')}</SelectItem>
+              </SelectContent>
+            </Select>
+          </div>
+          {history.length === 0 ? (
+            <div className="text-center py-12 text-muted-foreground">{t('monitor.noMetricsData')}</div>
+          ) : (
+            <div className="space-y-4">
+              <div>
+                <p className="text-sm font-medium mb-1">{t('monitor.cpuMemory')}</p>
+                <ResponsiveContainer width="100%" height={180}>
+                  <LineChart data={history}>
+                    <CartesianGrid strokeDasharray="3 3" />
+                    <XAxis dataKey="time" fontSize={12} />
+                    <YAxis fontSize={12} domain={[0, 100]} unit="%" />
+                    <Tooltip formatter={(v) => `${Number(v).toFixed(1)}%`} />
+                    <Legend wrapperStyle={{ fontSize: 12 }} />
+                    <Line type="monotone" dataKey="cpuAvg" name="CPU" stroke="#8884d8" dot={false} />
+                    <Line type="monotone" dataKey="cpuMax" name={t('monitor.cpuPeak')} stroke="#8884d8" strokeDasharray="4 2" dot={false} />
+                    <Line type="monotone" dataKey="memAvg" name={t('monitor.memory')} stroke="#82ca9d" dot={false} />
+                    <Line type="monotone" dataKey="diskUsage" name={t('monitor.disk')} stroke="#ffc658" dot={false} />
+                  </LineChart>
+                </ResponsiveContainer>
+              </div>
+              <div>
+                <p className="text-sm font-medium mb-1">{t('monitor.bandwidth')}</p>
+                <ResponsiveContainer width="100%" height={180}>
+                  <LineChart data={history}>
+                    <CartesianGrid strokeDasharray="3 3" />
+                    <XAxis dataKey="time" fontSize={12} />
+                    <YAxis fontSize={12} tickFormatter={(v) => formatSpeed(v)} width={80} />
+                    <Tooltip formatter={(v) => formatSpeed(Number(v))} />
+                    <Legend wrapperStyle={{ fontSize: 12 }} />
+                    <Line type="monotone" dataKey="txRate" name={t('monitor.upload')} stroke="#ff7c43" dot={false} />
+                    <Line type="monotone" dataKey="rxRate" name={t('monitor.download')} stroke="#4363d8" dot={false} />
+                  </LineChart>
+                </ResponsiveContainer>
+              </div>
+              <div>
+                <p className="text-sm font-medium mb-1">{t('monitor.loadConnections')}</p>
+                <ResponsiveContainer width="100%" height={180}>
+                  <LineChart data={history}>
+                    <CartesianGrid strokeDasharray="3 3" />
+                    <XAxis dataKey="time" fontSize={12} />
+                    <YAxis yAxisId="load" fontSize={12} />
+                    <YAxis yAxisId="conns" orientation="right" fontSize={12} />
+                    <Tooltip />
+                    <Legend wrapperStyle={{ fontSize: 12 }} />
+                    <Line yAxisId="load" type="monotone" dataKey="load1" name={t('monitor.load1')} stroke="#911eb4" dot={false} />
+                    <Line yAxisId="conns" type="monotone" dataKey="tcpConns" name={t('monitor.tcpConns')} stroke="#469990" dot={false} />
+                  </LineChart>
+                </ResponsiveContainer>
+              </div>
+            </div>
+          )}
+        </DialogContent>
+      </Dialog>
     </div>
   );
 }
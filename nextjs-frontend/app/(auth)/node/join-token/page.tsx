@@ -0,0 +1,235 @@
+'use client';
+
+import { useState, useEffect, useCallback } from 'react';
+import { Card, CardContent } from '@/components/ui/card';
+import { Button } from '@/components/ui/button';
+import { Badge } from '@/components/ui/badge';
+import { Table, TableBody, TableCell, TableHead, TableHeader, TableRow } from '@/components/ui/table';
+import { Dialog, DialogContent, DialogHeader, DialogTitle, DialogFooter } from '@/components/ui/dialog';
+import { Input } from '@/components/ui/input';
+import { Label } from '@/components/ui/label';
+import { Switch } from '@/components/ui/switch';
+import { Plus, RefreshCw, Copy, Trash2 } from 'lucide-react';
+import { toast } from 'sonner';
+import { getNodeJoinTokenList, createNodeJoinToken, updateNodeJoinToken, deleteNodeJoinToken } from '@/lib/api/node';
+import { useAuth } from '@/lib/hooks/use-auth';
+import { useTranslation } from '@/lib/i18n';
+
+const emptyForm = { name: '', groupName: '', region: '', portSta: '10000', portEnd: '60000', maxUses: '1', requireApproval: false, expireDays: '7' };
+
+export default function NodeJoinTokenPage() {
+  const { isAdmin } = useAuth();
+  const { t } = useTranslation();
+  const [tokens, setTokens] = useState<any[]>([]);
+  const [loading, setLoading] = useState(true);
+  const [dialogOpen, setDialogOpen] = useState(false);
+  const [form, setForm] = useState(emptyForm);
+
+  const loadData = useCallback(async () => {
+    const res = await getNodeJoinTokenList();
+    if (res.code === 0) setTokens(res.data || []);
+    setLoading(false);
+  }, []);
+
+  useEffect(() => { loadData(); }, [loadData]);
+
+  const handleCreate = async () => {
+    if (!form.name.trim()) {
+      toast.error(t('common.fillRequired'));
+      return;
+    }
+    const days = parseInt(form.expireDays) || 0;
+    const res = await createNodeJoinToken({
+      name: form.name.trim(),
+      groupName: form.groupName.trim() || undefined,
+      region: form.region.trim() || undefined,
+      portSta: parseInt(form.portSta) || undefined,
+      portEnd: parseInt(form.portEnd) || undefined,
+      maxUses: parseInt(form.maxUses) || 0,
+      requireApproval: form.requireApproval,
+      expireTime: days > 0 ? Date.now() + days * 86400000 : 0,
+    });
+    if (res.code === 0) {
+      toast.success(t('nodeJoinToken.created'));
+      setDialogOpen(false);
+      loadData();
+    } else {
+      toast.error(res.msg);
+    }
+  };
+
+  const handleUpdate = async (id: number, data: { enabled?: boolean; requireApproval?: boolean }) => {
+    const res = await updateNodeJoinToken({ id, ...data });
+    if (res.code === 0) loadData();
+    else toast.error(res.msg);
+  };
+
+  const handleDelete = async (id: number) => {
+    if (!confirm(t('nodeJoinToken.confirmDelete'))) return;
+    const res = await deleteNodeJoinToken(id);
+    if (res.code === 0) {
+      toast.success(t('common.deleteSuccess'));
+      loadData();
+    } else {
+      toast.error(res.msg);
+    }
+  };
+
+  const copyCommand = async (cmd: string) => {
+    try {
+      await navigator.clipboard.writeText(cmd);
+      toast.success(t('common.copySuccess'));
+    } catch {
+      toast.error(cmd);
+    }
+  };
+
+  if (!isAdmin) {
+    return (
+      <div className="flex items-center justify-center h-64">
+        <p className="text-muted-foreground">无权限访问</p>
+      </div>
+    );
+  }
+
+  return (
+    <div className="space-y-4">
+      <div className="flex items-center justify-between">
+        <h2 className="text-2xl font-bold">{t('nodeJoinToken.title')}</h2>
+        <div className="flex gap-2">
+          <Button variant="outline" onClick={loadData}><RefreshCw className="mr-2 h-4 w-4" />{t('common.refresh')}</Button>
+          <Button onClick={() => { setForm(emptyForm); setDialogOpen(true); }}>
+            <Plus className="mr-2 h-4 w-4" />{t('nodeJoinToken.create')}
+          </Button>
+        </div>
+      </div>
+      <p className="text-sm text-muted-foreground">{t('nodeJoinToken.hint')}</p>
+
+      <Card>
+        <CardContent className="p-0">
+          <Table>
+            <TableHeader>
+              <TableRow>
+                <TableHead>{t('nodeJoinToken.name')}</TableHead>
+                <TableHead>{t('nodeJoinToken.group')}</TableHead>
+                <TableHead>{t('nodeJoinToken.portRange')}</TableHead>
+                <TableHead>{t('nodeJoinToken.uses')}</TableHead>
+                <TableHead>{t('nodeJoinToken.nodes')}</TableHead>
+                <TableHead>{t('nodeJoinToken.expires')}</TableHead>
+                <TableHead>{t('nodeJoinToken.requireApproval')}</TableHead>
+                <TableHead>{t('nodeJoinToken.enabled')}</TableHead>
+                <TableHead>{t('nodeJoinToken.actions')}</TableHead>
+              </TableRow>
+            </TableHeader>
+            <TableBody>
+              {loading ? (
+                <TableRow><TableCell colSpan={9} className="text-center py-8">{t('common.loading')}</TableCell></TableRow>
+              ) : tokens.length === 0 ? (
+                <TableRow><TableCell colSpan={9} className="text-center py-8 text-muted-foreground">{t('common.noData')}</TableCell></TableRow>
+              ) : (
+                tokens.map(({ token: tk, nodeCount, pendingCount, command }) => {
+                  const expired = tk.expireTime > 0 && tk.expireTime <= Date.now();
+                  const usedUp = tk.maxUses > 0 && tk.usedCount >= tk.maxUses;
+                  return (
+                    <TableRow key={tk.id}>
+                      <TableCell className="font-medium">{tk.name}</TableCell>
+                      <TableCell>{tk.groupName || '-'}{tk.region ? ` · ${tk.region}` : ''}</TableCell>
+                      <TableCell className="text-xs">{tk.portSta}-{tk.portEnd}</TableCell>
+                      <TableCell className={usedUp ? 'text-muted-foreground' : ''}>
+                        {tk.usedCount} / {tk.maxUses > 0 ? tk.maxUses : t('nodeJoinToken.unlimited')}
+                      </TableCell>
+                      <TableCell>
+                        {nodeCount}
+                        {pendingCount > 0 && (
+                          <Badge variant="outline" className="ml-1 text-xs text-orange-600 border-orange-400">
+                            {t('nodeJoinToken.pendingCount', { n: pendingCount })}
+                          </Badge>
+                        )}
+                      </TableCell>
+                      <TableCell className="text-xs">
+                        {tk.expireTime > 0 ? (
+                          <span className={expired ? 'text-destructive' : ''}>
+                            {expired ? t('nodeJoinToken.expired') : new Date(tk.expireTime).toLocaleString()}
+                          </span>
+                        ) : t('nodeJoinToken.never')}
+                      </TableCell>
+                      <TableCell>
+                        <Switch size="sm" checked={tk.requireApproval} onCheckedChange={(v) => handleUpdate(tk.id, { requireApproval: v })} />
+                      </TableCell>
+                      <TableCell>
+                        <Switch size="sm" checked={tk.enabled} onCheckedChange={(v) => handleUpdate(tk.id, { enabled: v })} />
+                      </TableCell>
+                      <TableCell>
+                        <div className="flex gap-1">
+                          <Button variant="ghost" size="icon" onClick={() => copyCommand(command)} title={t('nodeJoinToken.command')} disabled={!tk.enabled || expired || usedUp}>
+                            <Copy className="h-4 w-4" />
+                          </Button>
+                          <Button variant="ghost" size="icon" onClick={() => handleDelete(tk.id)} className="text-destructive" title={t('common.delete')}>
+                            <Trash2 className="h-4 w-4" />
+                          </Button>
+                        </div>
+                      </TableCell>
+                    </TableRow>
+                  );
+                })
+              )}
+            </TableBody>
+          </Table>
+        </CardContent>
+      </Card>
+
+      <Dialog open={dialogOpen} onOpenChange={setDialogOpen}>
+        <DialogContent>
+          <DialogHeader>
+            <DialogTitle>{t('nodeJoinToken.create')}</DialogTitle>
+          </DialogHeader>
+          <div className="space-y-4">
+            <div className="space-y-2">
+              <Label>{t('nodeJoinToken.name')}</Label>
+              <Input value={form.name} onChange={e => setForm(p => ({ ...p, name: e.target.value }))} />
+            </div>
+            <div className="grid grid-cols-2 gap-4">
+              <div className="space-y-2">
+                <Label>{t('nodeJoinToken.group')}</Label>
+                <Input value={form.groupName} onChange={e => setForm(p => ({ ...p, groupName: e.target.value }))} />
+              </div>
+              <div className="space-y-2">
+                <Label>{t('nodeJoinToken.region')}</Label>
+                <Input value={form.region} onChange={e => setForm(p => ({ ...p, region: e.target.value }))} placeholder="HK" />
+              </div>
+              <div className="space-y-2">
+                <Label>{t('nodeJoinToken.portRange')}</Label>
+                <div className="flex items-center gap-2">
+                  <Input type="number" value={form.portSta} onChange={e => setForm(p => ({ ...p, portSta: e.target.value }))} />
+                  <span>-</span>
+                  <Input type="number" value={form.portEnd} onChange={e => setForm(p => ({ ...p, portEnd: e.target.value }))} />
+                </div>
+              </div>
+              <div className="space-y-2">
+                <Label>{t('nodeJoinToken.maxUses')}</Label>
+                <Input type="number" min={0} value={form.maxUses} onChange={e => setForm(p => ({ ...p, maxUses: e.target.value }))} />
+                <p className="text-xs text-muted-foreground">{t('nodeJoinToken.maxUsesHint')}</p>
+              </div>
+              <div className="space-y-2">
+                <Label>{t('nodeJoinToken.expireDays')}</Label>
+                <Input type="number" min={0} value={form.expireDays} onChange={e => setForm(p => ({ ...p, expireDays: e.target.value }))} />
+                <p className="text-xs text-muted-foreground">{t('nodeJoinToken.expireDaysHint')}</p>
+              </div>
+            </div>
+            <div className="flex items-center justify-between">
+              <div>
+                <Label>{t('nodeJoinToken.requireApproval')}</Label>
+                <p className="text-xs text-muted-foreground">{t('nodeJoinToken.requireApprovalHint')}</p>
+              </div>
+              <Switch checked={form.requireApproval} onCheckedChange={(v) => setForm(p => ({ ...p, requireApproval: v }))} />
+            </div>
+          </div>
+          <DialogFooter>
+            <Button variant="outline" onClick={() => setDialogOpen(false)}>{t('common.cancel')}</Button>
+            <Button onClick={handleCreate}>{t('common.confirm')}</Button>
+          </DialogFooter>
+        </DialogContent>
+      </Dialog>
+    </div>
+  );
+}
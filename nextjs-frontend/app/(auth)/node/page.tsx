@@ -9,10 +9,10 @@ import { Dialog, DialogContent, DialogHeader, DialogTitle, DialogFooter } from '
 import { Input } from '@/components/ui/input';
 import { Label } from '@/components/ui/label';
 import { Textarea } from '@/components/ui/textarea';
-import { Plus, Trash2, Edit2, Terminal, Container, Copy, Eye, EyeOff, RefreshCw, ArrowUpDown, Network, Download, Check, AlertTriangle } from 'lucide-react';
+import { Plus, Trash2, Edit2, Terminal, Container, Copy, Eye, EyeOff, RefreshCw, ArrowUpDown, Network, Download, Check, AlertTriangle, Zap, KeyRound } from 'lucide-react';
 import { toast } from 'sonner';
-import { getNodeList, createNode, updateNode, deleteNode, getNodeInstallCommand, getNodeDockerCommand, reconcileNode, updateNodeBinary, setNodeProtocol } from '@/lib/api/node';
-import { switchXrayVersion, getXrayVersions } from '@/lib/api/xray-node';
+import { getNodeList, createNode, updateNode, deleteNode, getNodeInstallCommand, getNodeDockerCommand, reconcileNode, updateNodeBinary, setNodeProtocol, rotateNodeSecret, approveNode } from '@/lib/api/node';
+import { switchXrayVersion, getXrayVersions, getSingboxStatus, restartSingbox, switchSingboxVersion } from '@/lib/api/xray-node';
 import { Select, SelectContent, SelectItem, SelectTrigger, SelectValue } from '@/components/ui/select';
 import { Tabs, TabsList, TabsTrigger } from '@/components/ui/tabs';
 import { getVersion } from '@/lib/api/system';
@@ -38,7 +38,7 @@ export default function NodePage() {
   const [loading, setLoading] = useState(true);
   const [dialogOpen, setDialogOpen] = useState(false);
   const [editingNode, setEditingNode] = useState<any>(null);
-  const [form, setForm] = useState({ name: '', entryIps: '', serverIp: '', portSta: '', portEnd: '', secret: '', groupName: '' });
+  const [form, setForm] = useState({ name: '', entryIps: '', serverIp: '', portSta: '', portEnd: '', secret: '', groupName: '', region: '' });
   const [commandDialog, setCommandDialog] = useState(false);
   const [commandContent, setCommandContent] = useState('');
   const [commandTitle, setCommandTitle] = useState('');
@@ -135,7 +135,7 @@ export default function NodePage() {
 
   const handleCreate = () => {
     setEditingNode(null);
-    setForm({ name: '', entryIps: '', serverIp: '', portSta: '10000', portEnd: '60000', secret: '', groupName: '' });
+    setForm({ name: '', entryIps: '', serverIp: '', portSta: '10000', portEnd: '60000', secret: '', groupName: '', region: '' });
     setShowSecret(false);
     setDialogOpen(true);
   };
@@ -150,6 +150,7 @@ export default function NodePage() {
       portEnd: node.portEnd?.toString() || '',
       secret: node.secret || '',
       groupName: node.groupName || '',
+      region: node.region || '',
     });
     setShowSecret(false);
     setProtocolForm({ http: node.http || 0, tls: node.tls || 0, socks: node.socks || 0 });
@@ -192,6 +193,7 @@ export default function NodePage() {
       serverIp: form.serverIp,
       secret: form.secret || undefined,
       groupName: form.groupName,
+      region: form.region,
     };
     if (form.portSta) data.portSta = parseInt(form.portSta);
     if (form.portEnd) data.portEnd = parseInt(form.portEnd);
@@ -251,6 +253,7 @@ export default function NodePage() {
   const [ifaceNode, setIfaceNode] = useState<any>(null);
   const [reconcilingId, setReconcilingId] = useState<number | null>(null);
   const [updatingId, setUpdatingId] = useState<number | null>(null);
+  const [rotatingId, setRotatingId] = useState<number | null>(null);
   const [xrayVersionDialog, setXrayVersionDialog] = useState(false);
   const [xrayVersionNode, setXrayVersionNode] = useState<any>(null);
   const [xrayTargetVersion, setXrayTargetVersion] = useState('');
@@ -298,6 +301,57 @@ export default function NodePage() {
     }
   };
 
+  const [singboxNode, setSingboxNode] = useState<any>(null);
+  const [singboxStatus, setSingboxStatus] = useState<any>(null);
+  const [singboxUrl, setSingboxUrl] = useState('');
+  const [singboxSha256, setSingboxSha256] = useState('');
+  const [singboxBusy, setSingboxBusy] = useState(false);
+
+  const handleSingbox = async (node: any) => {
+    setSingboxNode(node);
+    setSingboxStatus(null);
+    setSingboxUrl('');
+    setSingboxSha256('');
+    const res = await getSingboxStatus(node.id);
+    if (res.code === 0 && res.data?.msg === 'OK') {
+      setSingboxStatus(res.data.data);
+    }
+  };
+
+  const handleSingboxInstall = async () => {
+    if (!singboxUrl.trim() || !/^[0-9a-fA-F]{64}$/.test(singboxSha256.trim())) {
+      toast.error(t('node.singboxInstallRequired'));
+      return;
+    }
+    setSingboxBusy(true);
+    try {
+      const res = await switchSingboxVersion(singboxNode.id, singboxUrl.trim(), singboxSha256.trim());
+      if (res.code === 0 && res.data?.msg === 'OK') {
+        toast.success(t('node.versionSwitchStarted'));
+        setSingboxNode(null);
+      } else {
+        toast.error(res.data?.msg || res.msg || t('node.switchFailed'));
+      }
+    } finally {
+      setSingboxBusy(false);
+    }
+  };
+
+  const handleSingboxRestart = async () => {
+    setSingboxBusy(true);
+    try {
+      const res = await restartSingbox(singboxNode.id);
+      if (res.code === 0 && res.data?.msg === 'OK') {
+        toast.success(t('node.singboxRestarted'));
+        handleSingbox(singboxNode);
+      } else {
+        toast.error(res.data?.msg || res.msg || t('node.singboxRestartFailed'));
+      }
+    } finally {
+      setSingboxBusy(false);
+    }
+  };
+
   const handleReconcile = async (id: number) => {
     setReconcilingId(id);
     try {
@@ -331,6 +385,32 @@ export default function NodePage() {
     }
   };
 
+  const handleApprove = async (node: any) => {
+    const res = await approveNode(node.id);
+    if (res.code === 0) {
+      toast.success(t('node.approveSuccess', { name: node.name }));
+      loadData();
+    } else {
+      toast.error(res.msg || t('common.networkError'));
+    }
+  };
+
+  const handleRotateSecret = async (node: any) => {
+    if (!confirm(t('node.confirmRotateSecret', { name: node.name }))) return;
+    setRotatingId(node.id);
+    try {
+      const res = await rotateNodeSecret(node.id);
+      if (res.code === 0) {
+        toast.success(t('node.rotateSecretSuccess'));
+        loadData();
+      } else {
+        toast.error(res.msg || t('common.networkError'));
+      }
+    } finally {
+      setRotatingId(null);
+    }
+  };
+
   const [copied, setCopied] = useState(false);
   const copyToClipboard = async (text: string) => {
     try {
@@ -391,7 +471,15 @@ export default function NodePage() {
       const isOnline = n.status === 1;
       rows.push(
         <TableRow key={n.id}>
-          <TableCell className="font-medium text-sm">{n.name}</TableCell>
+          <TableCell className="font-medium text-sm">
+            {n.name}
+            {n.pending && (
+              <div className="flex items-center gap-1 mt-0.5">
+                <Badge variant="outline" className="text-xs text-orange-600 border-orange-400">{t('node.pendingApproval')}</Badge>
+                <Button variant="outline" size="sm" className="h-5 px-1.5 text-xs" onClick={() => handleApprove(n)}>{t('node.approve')}</Button>
+              </div>
+            )}
+          </TableCell>
           <TableCell className="text-xs">
             <div title={t('node.disguiseName')}>{n.disguiseName || '-'}</div>
             <div className="text-muted-foreground" title={t('node.xrayDisguiseName')}>{n.xrayDisguiseName || '-'}</div>
@@ -461,9 +549,15 @@ export default function NodePage() {
               <Button variant="ghost" size="icon" onClick={() => handleXrayVersionSwitch(n)} title={t('node.xrayVersionTitle')}>
                 <ArrowUpDown className="h-4 w-4" />
               </Button>
+              <Button variant="ghost" size="icon" onClick={() => handleSingbox(n)} title={t('node.singboxTitle')}>
+                <Zap className="h-4 w-4" />
+              </Button>
               <Button variant="ghost" size="icon" onClick={() => handleReconcile(n.id)} disabled={reconcilingId === n.id} title={t('node.syncConfig')}>
                 <RefreshCw className={`h-4 w-4 ${reconcilingId === n.id ? 'animate-spin' : ''}`} />
               </Button>
+              <Button variant="ghost" size="icon" onClick={() => handleRotateSecret(n)} disabled={!isOnline || rotatingId === n.id} title={t('node.rotateSecret')}>
+                <KeyRound className={`h-4 w-4 ${rotatingId === n.id ? 'animate-pulse' : ''}`} />
+              </Button>
               <Button variant="ghost" size="icon" onClick={() => handleInstallCommand(n)} title={t('node.installCommand')}>
                 <Terminal className="h-4 w-4" />
               </Button>
@@ -534,6 +628,10 @@ export default function NodePage() {
               <Label>{t('node.groupName')}</Label>
               <Input value={form.groupName} onChange={e => setForm(p => ({ ...p, groupName: e.target.value }))} placeholder={t('node.groupNamePlaceholder')} />
             </div>
+            <div className="space-y-2">
+              <Label>{t('node.region')}</Label>
+              <Input value={form.region} onChange={e => setForm(p => ({ ...p, region: e.target.value }))} placeholder={t('node.regionPlaceholder')} />
+            </div>
             <div className="space-y-2">
               <Label>{t('node.entryIpList')}</Label>
               <Textarea
@@ -683,6 +781,50 @@ export default function NodePage() {
         </DialogContent>
       </Dialog>
 
+      {/* sing-box Dialog */}
+      <Dialog open={!!singboxNode} onOpenChange={() => setSingboxNode(null)}>
+        <DialogContent>
+          <DialogHeader>
+            <DialogTitle>{t('node.singboxTitle')} — {singboxNode?.name}</DialogTitle>
+          </DialogHeader>
+          <div className="space-y-4">
+            <div className="grid grid-cols-3 gap-4 text-sm">
+              <div className="space-y-1">
+                <Label>{t('node.currentVersion')}</Label>
+                <p className="text-muted-foreground">{singboxStatus?.version || t('node.unknown')}</p>
+              </div>
+              <div className="space-y-1">
+                <Label>{t('node.singboxRunning')}</Label>
+                <p className="text-muted-foreground">{singboxStatus?.running ? t('node.singboxYes') : t('node.singboxNo')}</p>
+              </div>
+              <div className="space-y-1">
+                <Label>{t('node.singboxStats')}</Label>
+                <p className="text-muted-foreground">{singboxStatus?.statsEnabled ? t('node.singboxYes') : t('node.singboxNo')}</p>
+              </div>
+            </div>
+            {singboxStatus && !singboxStatus.statsEnabled && (
+              <p className="text-xs text-muted-foreground">{t('node.singboxStatsHint')}</p>
+            )}
+            <div className="space-y-2">
+              <Label>{t('node.singboxUrl')}</Label>
+              <Input value={singboxUrl} onChange={e => setSingboxUrl(e.target.value)} placeholder={t('node.singboxUrlPlaceholder')} />
+            </div>
+            <div className="space-y-2">
+              <Label>{t('node.singboxSha256')}</Label>
+              <Input value={singboxSha256} onChange={e => setSingboxSha256(e.target.value)} placeholder={t('node.singboxSha256Placeholder')} className="font-mono" />
+            </div>
+          </div>
+          <DialogFooter>
+            <Button variant="outline" onClick={handleSingboxRestart} disabled={singboxBusy || !singboxStatus?.tags?.length}>
+              {t('node.singboxRestart')}
+            </Button>
+            <Button onClick={handleSingboxInstall} disabled={singboxBusy}>
+              {singboxBusy ? t('node.switching') : t('node.singboxInstall')}
+            </Button>
+          </DialogFooter>
+        </DialogContent>
+      </Dialog>
+
       {/* NIC Info Dialog */}
       <Dialog open={!!ifaceNode} onOpenChange={() => setIfaceNode(null)}>
         <DialogContent>
@@ -0,0 +1,311 @@
+'use client';
+
+import { useState, useEffect, useCallback } from 'react';
+import { Card, CardContent, CardHeader, CardTitle } from '@/components/ui/card';
+import { Button } from '@/components/ui/button';
+import { Badge } from '@/components/ui/badge';
+import { Table, TableBody, TableCell, TableHead, TableHeader, TableRow } from '@/components/ui/table';
+import { Dialog, DialogContent, DialogHeader, DialogTitle, DialogFooter } from '@/components/ui/dialog';
+import { Input } from '@/components/ui/input';
+import { Label } from '@/components/ui/label';
+import { Select, SelectContent, SelectItem, SelectTrigger, SelectValue } from '@/components/ui/select';
+import { Plus, RefreshCw } from 'lucide-react';
+import { toast } from 'sonner';
+import {
+  getNodeList, getNodeReleaseInfo, createNodeRollout, getNodeRolloutList, getNodeRollout,
+  cancelNodeRollout, resumeNodeRollout, rollbackNodeRollout, rollbackNodeBinary,
+} from '@/lib/api/node';
+import { useAuth } from '@/lib/hooks/use-auth';
+import { useTranslation } from '@/lib/i18n';
+
+const statusVariant = (status: string): 'default' | 'secondary' | 'destructive' | 'outline' => {
+  switch (status) {
+    case 'running':
+    case 'updating':
+      return 'default';
+    case 'completed':
+    case 'success':
+      return 'secondary';
+    case 'failed':
+    case 'rolled_back':
+      return 'destructive';
+    default:
+      return 'outline';
+  }
+};
+
+export default function NodeRolloutPage() {
+  const { isAdmin } = useAuth();
+  const { t } = useTranslation();
+  const [release, setRelease] = useState<any>(null);
+  const [rollouts, setRollouts] = useState<any[]>([]);
+  const [nodes, setNodes] = useState<any[]>([]);
+  const [loading, setLoading] = useState(true);
+  const [selected, setSelected] = useState<any>(null);
+  const [items, setItems] = useState<any[]>([]);
+  const [dialogOpen, setDialogOpen] = useState(false);
+  const [form, setForm] = useState({ version: '', groupName: '', percent: '100', canaryCount: '1', batchSize: '5', healthTimeout: '300' });
+
+  const loadData = useCallback(async () => {
+    const [releaseRes, listRes, nodesRes] = await Promise.all([
+      getNodeReleaseInfo(),
+      getNodeRolloutList(),
+      getNodeList(),
+    ]);
+    if (releaseRes.code === 0) setRelease(releaseRes.data);
+    if (listRes.code === 0) setRollouts(listRes.data || []);
+    if (nodesRes.code === 0) setNodes(nodesRes.data || []);
+    setLoading(false);
+  }, []);
+
+  const loadDetail = useCallback(async (id: number) => {
+    const res = await getNodeRollout(id);
+    if (res.code === 0) {
+      setSelected(res.data.rollout);
+      setItems(res.data.items || []);
+    }
+  }, []);
+
+  useEffect(() => { loadData(); }, [loadData]);
+
+  // Poll while a rollout is running
+  const running = rollouts.some(r => r.status === 'running');
+  useEffect(() => {
+    if (!running) return;
+    const timer = setInterval(() => {
+      loadData();
+      if (selected) loadDetail(selected.id);
+    }, 5000);
+    return () => clearInterval(timer);
+  }, [running, selected, loadData, loadDetail]);
+
+  const groups = Array.from(new Set(nodes.map(n => n.groupName).filter(Boolean))) as string[];
+  const nodeName = (id: number) => nodes.find(n => n.id === id)?.name || `#${id}`;
+  const signedVersion = (release?.releases || []).find((r: any) => r.valid)?.version || '';
+
+  const handleCreate = async () => {
+    const res = await createNodeRollout({
+      version: form.version.trim() || undefined,
+      groupName: form.groupName || undefined,
+      percent: parseInt(form.percent) || 100,
+      canaryCount: parseInt(form.canaryCount) || 0,
+      batchSize: parseInt(form.batchSize) || 5,
+      healthTimeout: parseInt(form.healthTimeout) || 300,
+    });
+    if (res.code === 0) {
+      toast.success(t('nodeRollout.started'));
+      setDialogOpen(false);
+      await loadData();
+      loadDetail(res.data.id);
+    } else {
+      toast.error(res.msg);
+    }
+  };
+
+  const runAction = async (fn: (id: number) => Promise<any>, id: number, confirmKey?: string) => {
+    if (confirmKey && !confirm(t(confirmKey))) return;
+    const res = await fn(id);
+    if (res.code === 0) toast.success(typeof res.data === 'string' ? res.data : t('common.success'));
+    else toast.error(res.msg);
+    await loadData();
+    if (selected) loadDetail(selected.id);
+  };
+
+  if (!isAdmin) {
+    return (
+      <div className="flex items-center justify-center h-64">
+        <p className="text-muted-foreground">无权限访问</p>
+      </div>
+    );
+  }
+
+  return (
+    <div className="space-y-4">
+      <div className="flex items-center justify-between">
+        <h2 className="text-2xl font-bold">{t('nodeRollout.title')}</h2>
+        <div className="flex gap-2">
+          <Button variant="outline" onClick={loadData}><RefreshCw className="mr-2 h-4 w-4" />{t('common.refresh')}</Button>
+          <Button onClick={() => { setForm(p => ({ ...p, version: signedVersion })); setDialogOpen(true); }} disabled={running}>
+            <Plus className="mr-2 h-4 w-4" />{t('nodeRollout.create')}
+          </Button>
+        </div>
+      </div>
+
+      <Card>
+        <CardHeader><CardTitle className="text-base">{t('nodeRollout.release')}</CardTitle></CardHeader>
+        <CardContent className="space-y-2 text-sm">
+          <p className="text-muted-foreground">
+            {t('nodeRollout.publicKey')}: <span className="font-mono break-all">{release?.publicKey || t('nodeRollout.noPublicKey')}</span>
+          </p>
+          {(release?.releases || []).length === 0 ? (
+            <p className="text-muted-foreground">{t('nodeRollout.noManifest')}</p>
+          ) : (
+            <Table>
+              <TableHeader>
+                <TableRow>
+                  <TableHead>{t('nodeRollout.arch')}</TableHead>
+                  <TableHead>{t('nodeRollout.version')}</TableHead>
+                  <TableHead>SHA-256</TableHead>
+                  <TableHead>{t('nodeRollout.signature')}</TableHead>
+                </TableRow>
+              </TableHeader>
+              <TableBody>
+                {release.releases.map((r: any) => (
+                  <TableRow key={r.arch}>
+                    <TableCell>{r.arch}</TableCell>
+                    <TableCell>{r.version || '-'}</TableCell>
+                    <TableCell className="font-mono text-xs">{r.sha256 ? `${r.sha256.slice(0, 16)}…` : '-'}</TableCell>
+                    <TableCell>
+                      {r.valid
+                        ? <Badge variant="secondary">{t('nodeRollout.verified')}</Badge>
+                        : <span className="text-destructive text-xs">{r.msg}</span>}
+                    </TableCell>
+                  </TableRow>
+                ))}
+              </TableBody>
+            </Table>
+          )}
+        </CardContent>
+      </Card>
+
+      <Card>
+        <CardContent className="p-0">
+          <Table>
+            <TableHeader>
+              <TableRow>
+                <TableHead>ID</TableHead>
+                <TableHead>{t('nodeRollout.version')}</TableHead>
+                <TableHead>{t('nodeRollout.scope')}</TableHead>
+                <TableHead>{t('nodeRollout.status')}</TableHead>
+                <TableHead>{t('nodeRollout.message')}</TableHead>
+                <TableHead>{t('nodeRollout.createdTime')}</TableHead>
+                <TableHead>{t('nodeRollout.actions')}</TableHead>
+              </TableRow>
+            </TableHeader>
+            <TableBody>
+              {loading ? (
+                <TableRow><TableCell colSpan={7} className="text-center py-8">{t('common.loading')}</TableCell></TableRow>
+              ) : rollouts.length === 0 ? (
+                <TableRow><TableCell colSpan={7} className="text-center py-8 text-muted-foreground">{t('common.noData')}</TableCell></TableRow>
+              ) : (
+                rollouts.map((r) => (
+                  <TableRow key={r.id} className={selected?.id === r.id ? 'bg-muted/50' : ''}>
+                    <TableCell>{r.id}</TableCell>
+                    <TableCell className="font-medium">{r.targetVersion}</TableCell>
+                    <TableCell className="text-xs">
+                      {r.groupName || t('nodeRollout.allNodes')} · {r.percent}% · {t('nodeRollout.canaryShort', { n: r.canaryCount })} · {t('nodeRollout.batchShort', { n: r.batchSize })}
+                    </TableCell>
+                    <TableCell><Badge variant={statusVariant(r.status)}>{t(`nodeRollout.status_${r.status}`)}</Badge></TableCell>
+                    <TableCell className="text-xs max-w-xs truncate" title={r.msg}>{r.msg || '-'}</TableCell>
+                    <TableCell className="text-xs">{new Date(r.createdTime).toLocaleString()}</TableCell>
+                    <TableCell>
+                      <div className="flex gap-1">
+                        <Button variant="ghost" size="sm" onClick={() => loadDetail(r.id)}>{t('nodeRollout.detail')}</Button>
+                        {r.status === 'running' && (
+                          <Button variant="ghost" size="sm" onClick={() => runAction(cancelNodeRollout, r.id, 'nodeRollout.confirmCancel')}>{t('common.cancel')}</Button>
+                        )}
+                        {(r.status === 'failed' || r.status === 'cancelled') && (
+                          <Button variant="ghost" size="sm" onClick={() => runAction(resumeNodeRollout, r.id)} disabled={running}>{t('nodeRollout.resume')}</Button>
+                        )}
+                        {r.status !== 'running' && r.status !== 'rolled_back' && (
+                          <Button variant="ghost" size="sm" className="text-destructive" onClick={() => runAction(rollbackNodeRollout, r.id, 'nodeRollout.confirmRollback')}>
+                            {t('nodeRollout.rollback')}
+                          </Button>
+                        )}
+                      </div>
+                    </TableCell>
+                  </TableRow>
+                ))
+              )}
+            </TableBody>
+          </Table>
+        </CardContent>
+      </Card>
+
+      {selected && (
+        <Card>
+          <CardHeader><CardTitle className="text-base">{t('nodeRollout.itemsTitle', { id: selected.id })}</CardTitle></CardHeader>
+          <CardContent className="p-0">
+            <Table>
+              <TableHeader>
+                <TableRow>
+                  <TableHead>{t('nodeRollout.batch')}</TableHead>
+                  <TableHead>{t('nodeRollout.node')}</TableHead>
+                  <TableHead>{t('nodeRollout.fromVersion')}</TableHead>
+                  <TableHead>{t('nodeRollout.status')}</TableHead>
+                  <TableHead>{t('nodeRollout.message')}</TableHead>
+                  <TableHead>{t('nodeRollout.actions')}</TableHead>
+                </TableRow>
+              </TableHeader>
+              <TableBody>
+                {items.map((it) => (
+                  <TableRow key={it.id}>
+                    <TableCell>{it.batch === 0 ? t('nodeRollout.canary') : it.batch}</TableCell>
+                    <TableCell>{nodeName(it.nodeId)}</TableCell>
+                    <TableCell>{it.fromVersion || '-'}</TableCell>
+                    <TableCell><Badge variant={statusVariant(it.status)}>{t(`nodeRollout.status_${it.status}`)}</Badge></TableCell>
+                    <TableCell className="text-xs">{it.msg || '-'}</TableCell>
+                    <TableCell>
+                      {it.status === 'success' && (
+                        <Button variant="ghost" size="sm" className="text-destructive" onClick={() => runAction(rollbackNodeBinary, it.nodeId, 'nodeRollout.confirmRollbackNode')}>
+                          {t('nodeRollout.rollback')}
+                        </Button>
+                      )}
+                    </TableCell>
+                  </TableRow>
+                ))}
+              </TableBody>
+            </Table>
+          </CardContent>
+        </Card>
+      )}
+
+      <Dialog open={dialogOpen} onOpenChange={setDialogOpen}>
+        <DialogContent>
+          <DialogHeader>
+            <DialogTitle>{t('nodeRollout.create')}</DialogTitle>
+          </DialogHeader>
+          <div className="space-y-4">
+            <div className="space-y-2">
+              <Label>{t('nodeRollout.version')}</Label>
+              <Input value={form.version} onChange={e => setForm(p => ({ ...p, version: e.target.value }))} placeholder={t('nodeRollout.versionPlaceholder')} />
+            </div>
+            <div className="space-y-2">
+              <Label>{t('nodeRollout.group')}</Label>
+              <Select value={form.groupName || '__all__'} onValueChange={v => setForm(p => ({ ...p, groupName: v === '__all__' ? '' : v }))}>
+                <SelectTrigger><SelectValue /></SelectTrigger>
+                <SelectContent>
+                  <SelectItem value="__all__">{t('nodeRollout.allNodes')}</SelectItem>
+                  {groups.map(g => <SelectItem key={g} value={g}>{g}</SelectItem>)}
+                </SelectContent>
+              </Select>
+            </div>
+            <div className="grid grid-cols-2 gap-4">
+              <div className="space-y-2">
+                <Label>{t('nodeRollout.percent')}</Label>
+                <Input type="number" min={1} max={100} value={form.percent} onChange={e => setForm(p => ({ ...p, percent: e.target.value }))} />
+              </div>
+              <div className="space-y-2">
+                <Label>{t('nodeRollout.canaryCount')}</Label>
+                <Input type="number" min={0} value={form.canaryCount} onChange={e => setForm(p => ({ ...p, canaryCount: e.target.value }))} />
+              </div>
+              <div className="space-y-2">
+                <Label>{t('nodeRollout.batchSize')}</Label>
+                <Input type="number" min={1} value={form.batchSize} onChange={e => setForm(p => ({ ...p, batchSize: e.target.value }))} />
+              </div>
+              <div className="space-y-2">
+                <Label>{t('nodeRollout.healthTimeout')}</Label>
+                <Input type="number" min={60} value={form.healthTimeout} onChange={e => setForm(p => ({ ...p, healthTimeout: e.target.value }))} />
+              </div>
+            </div>
+            <p className="text-xs text-muted-foreground">{t('nodeRollout.createHint')}</p>
+          </div>
+          <DialogFooter>
+            <Button variant="outline" onClick={() => setDialogOpen(false)}>{t('common.cancel')}</Button>
+            <Button onClick={handleCreate}>{t('nodeRollout.start')}</Button>
+          </DialogFooter>
+        </DialogContent>
+      </Dialog>
+    </div>
+  );
+}
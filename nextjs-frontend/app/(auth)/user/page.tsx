@@ -42,6 +42,7 @@ export default function UserPage() {
     expTime: '',
     flowResetType: '0',
     flowResetDay: '1',
+    timezone: '',
     nodePermissions: [] as { nodeId: number; vEnabled: boolean; gostEnabled: boolean }[],
   });
 
@@ -76,6 +77,7 @@ export default function UserPage() {
       expTime: '',
       flowResetType: '0',
       flowResetDay: '1',
+      timezone: '',
       nodePermissions: allNodeIds.map(id => ({ nodeId: id, vEnabled: true, gostEnabled: true })),
     });
     setDialogOpen(true);
@@ -104,6 +106,7 @@ export default function UserPage() {
       expTime: u.expTime ? new Date(u.expTime).toISOString().slice(0, 16) : '',
       flowResetType: (u.flowResetType || 0).toString(),
       flowResetDay: (u.flowResetDay || 1).toString(),
+      timezone: u.timezone || '',
       nodePermissions: perms,
     });
     setDialogOpen(true);
@@ -129,6 +132,7 @@ export default function UserPage() {
     if (form.expTime) data.expTime = new Date(form.expTime).getTime();
     data.flowResetType = parseInt(form.flowResetType);
     data.flowResetDay = parseInt(form.flowResetDay);
+    data.timezone = form.timezone.trim();
 
     let res;
     if (editingUser) {
@@ -408,6 +412,15 @@ export default function UserPage() {
               )}
             </div>
 
+            <div className="space-y-2">
+              <Label>{t('user.timezone')}</Label>
+              <Input
+                value={form.timezone}
+                placeholder={t('user.timezonePlaceholder')}
+                onChange={e => setForm(p => ({ ...p, timezone: e.target.value }))}
+              />
+            </div>
+
             <Separator />
 
             {/* Node Permissions */}
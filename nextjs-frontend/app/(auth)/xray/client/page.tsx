@@ -9,12 +9,15 @@ import { Dialog, DialogContent, DialogHeader, DialogTitle, DialogFooter } from '
 import { Input } from '@/components/ui/input';
 import { Label } from '@/components/ui/label';
 import { Select, SelectContent, SelectItem, SelectTrigger, SelectValue } from '@/components/ui/select';
-import { Plus, Trash2, Edit2, RotateCcw, Copy, RefreshCw, QrCode } from 'lucide-react';
+import { Checkbox } from '@/components/ui/checkbox';
+import { Plus, Trash2, Edit2, RotateCcw, Copy, RefreshCw, QrCode, Globe, Layers, Power, PowerOff, ArrowRightLeft } from 'lucide-react';
 import { QRCodeSVG } from 'qrcode.react';
 import { toast } from 'sonner';
 import {
   createXrayClient, getXrayClientList, updateXrayClient,
-  deleteXrayClient, resetXrayClientTraffic, getXrayClientLink,
+  deleteXrayClient, resetXrayClientTraffic, getXrayClientLink, getXrayClientOnlineIps,
+  bulkCreateXrayClients, bulkEnableXrayClients, bulkDisableXrayClients,
+  bulkDeleteXrayClients, bulkResetXrayClientTraffic, bulkMoveXrayClients,
 } from '@/lib/api/xray-client';
 import { getXrayInboundList } from '@/lib/api/xray-inbound';
 import { getAllUsers } from '@/lib/api/user';
@@ -33,11 +36,22 @@ export default function XrayClientPage() {
   const [qrDialogOpen, setQrDialogOpen] = useState(false);
   const [qrLink, setQrLink] = useState('');
   const [qrRemark, setQrRemark] = useState('');
+  const [ipDialogOpen, setIpDialogOpen] = useState(false);
+  const [ipInfo, setIpInfo] = useState<any>(null);
   const [form, setForm] = useState({
     inboundId: '', userId: '', email: '', uuid: '', flow: '',
     alterId: '0', totalTraffic: '', expTime: '', remark: '',
     limitIp: '0', reset: '0',
   });
+  const [selectedIds, setSelectedIds] = useState<number[]>([]);
+  const [bulkDialogOpen, setBulkDialogOpen] = useState(false);
+  const [bulkForm, setBulkForm] = useState({
+    inboundId: '', userId: '', count: '10', startIndex: '1', prefix: '', emailTemplate: '',
+    flow: '', totalTraffic: '', expTime: '', limitIp: '0', reset: '0',
+  });
+  const [moveDialogOpen, setMoveDialogOpen] = useState(false);
+  const [moveTarget, setMoveTarget] = useState('');
+  const [bulkReport, setBulkReport] = useState<any>(null);
 
   const formatBytes = (bytes: number) => {
     if (!bytes) return '0 B';
@@ -64,6 +78,7 @@ export default function XrayClientPage() {
     if (results[0].code === 0) setClients(results[0].data || []);
     if (results[1].code === 0) setInbounds(results[1].data || []);
     if (isAdmin && results[2]?.code === 0) setUsers(results[2].data || []);
+    setSelectedIds([]);
     setLoading(false);
   }, [isAdmin]);
 
@@ -191,6 +206,91 @@ export default function XrayClientPage() {
     }
   };
 
+  const handleShowOnlineIps = async (id: number) => {
+    const res = await getXrayClientOnlineIps(id);
+    if (res.code === 0) {
+      setIpInfo(res.data);
+      setIpDialogOpen(true);
+    } else {
+      toast.error(res.msg);
+    }
+  };
+
+  const toggleSelected = (id: number) => {
+    setSelectedIds(prev => prev.includes(id) ? prev.filter(x => x !== id) : [...prev, id]);
+  };
+
+  const toggleSelectAll = () => {
+    setSelectedIds(prev => prev.length === clients.length ? [] : clients.map((c: any) => c.id));
+  };
+
+  // Shows the per-client report of a bulk call and reloads the list
+  const finishBulk = (res: any) => {
+    if (res.code !== 0) {
+      toast.error(res.msg);
+      return;
+    }
+    setBulkReport(res.data);
+    loadData();
+  };
+
+  const handleBulkCreate = () => {
+    setBulkForm({
+      inboundId: '', userId: '', count: '10', startIndex: '1', prefix: '', emailTemplate: '',
+      flow: '', totalTraffic: '', expTime: '', limitIp: '0', reset: '0',
+    });
+    setBulkDialogOpen(true);
+  };
+
+  const handleBulkCreateSubmit = async () => {
+    if (!bulkForm.inboundId) {
+      toast.error(t('xrayClient.selectInbound'));
+      return;
+    }
+    const data: any = {
+      inboundId: parseInt(bulkForm.inboundId),
+      count: parseInt(bulkForm.count) || 0,
+      startIndex: parseInt(bulkForm.startIndex) || 1,
+      prefix: bulkForm.prefix || undefined,
+      emailTemplate: bulkForm.emailTemplate || undefined,
+      flow: bulkForm.flow || undefined,
+      limitIp: parseInt(bulkForm.limitIp) || 0,
+      reset: parseInt(bulkForm.reset) || 0,
+    };
+    if (bulkForm.userId) data.userId = parseInt(bulkForm.userId);
+    if (bulkForm.totalTraffic) data.totalTraffic = parseFloat(bulkForm.totalTraffic) * 1024 * 1024 * 1024;
+    if (bulkForm.expTime) data.expTime = new Date(bulkForm.expTime).getTime();
+
+    const res = await bulkCreateXrayClients(data);
+    if (res.code === 0) setBulkDialogOpen(false);
+    finishBulk(res);
+  };
+
+  const handleBulkEnable = async (enable: boolean) => {
+    const res = enable ? await bulkEnableXrayClients(selectedIds) : await bulkDisableXrayClients(selectedIds);
+    finishBulk(res);
+  };
+
+  const handleBulkDelete = async () => {
+    if (!confirm(t('xrayClient.confirmBulkDelete', { count: selectedIds.length }))) return;
+    finishBulk(await bulkDeleteXrayClients(selectedIds));
+  };
+
+  const handleBulkResetTraffic = async () => {
+    if (!confirm(t('xrayClient.confirmBulkResetTraffic', { count: selectedIds.length }))) return;
+    finishBulk(await bulkResetXrayClientTraffic(selectedIds));
+  };
+
+  const handleBulkMoveSubmit = async () => {
+    if (!moveTarget) {
+      toast.error(t('xrayClient.selectInbound'));
+      return;
+    }
+    const res = await bulkMoveXrayClients(selectedIds, parseInt(moveTarget));
+    if (res.code === 0) setMoveDialogOpen(false);
+    finishBulk(res);
+  };
+
   if (!isAdmin && !vEnabled) {
     return (
       <div className="flex items-center justify-center h-64">
@@ -203,14 +303,45 @@ export default function XrayClientPage() {
     <div className="space-y-4">
       <div className="flex items-center justify-between">
         <h2 className="text-2xl font-bold">{t('xrayClient.title')}</h2>
-        <Button onClick={handleCreate}><Plus className="mr-2 h-4 w-4" />{t('xrayClient.createClient')}</Button>
+        <div className="flex gap-2">
+          <Button variant="outline" onClick={handleBulkCreate}><Layers className="mr-2 h-4 w-4" />{t('xrayClient.bulkCreate')}</Button>
+          <Button onClick={handleCreate}><Plus className="mr-2 h-4 w-4" />{t('xrayClient.createClient')}</Button>
+        </div>
       </div>
 
+      {selectedIds.length > 0 && (
+        <div className="flex flex-wrap items-center gap-2 rounded-lg border px-3 py-2">
+          <span className="text-sm text-muted-foreground mr-2">{t('xrayClient.selected', { count: selectedIds.length })}</span>
+          <Button variant="outline" size="sm" onClick={() => handleBulkEnable(true)}>
+            <Power className="mr-1 h-3 w-3" />{t('xrayClient.enable')}
+          </Button>
+          <Button variant="outline" size="sm" onClick={() => handleBulkEnable(false)}>
+            <PowerOff className="mr-1 h-3 w-3" />{t('xrayClient.disable')}
+          </Button>
+          <Button variant="outline" size="sm" onClick={handleBulkResetTraffic}>
+            <RotateCcw className="mr-1 h-3 w-3" />{t('xrayClient.resetTraffic')}
+          </Button>
+          <Button variant="outline" size="sm" onClick={() => { setMoveTarget(''); setMoveDialogOpen(true); }}>
+            <ArrowRightLeft className="mr-1 h-3 w-3" />{t('xrayClient.moveTo')}
+          </Button>
+          <Button variant="outline" size="sm" className="text-destructive" onClick={handleBulkDelete}>
+            <Trash2 className="mr-1 h-3 w-3" />{t('common.delete')}
+          </Button>
+        </div>
+      )}
+
       <Card>
         <CardContent className="p-0">
           <Table>
             <TableHeader>
               <TableRow>
+                <TableHead className="w-10">
+                  <Checkbox
+                    checked={clients.length > 0 && selectedIds.length === clients.length}
+                    onCheckedChange={toggleSelectAll}
+                    aria-label={t('xrayClient.selectAll')}
+                  />
+                </TableHead>
                 <TableHead>{t('xrayClient.email')}</TableHead>
                 {isAdmin && <TableHead>{t('xrayClient.user')}</TableHead>}
                 <TableHead>{t('xrayClient.inbound')}</TableHead>
@@ -226,17 +357,21 @@ export default function XrayClientPage() {
             </TableHeader>
             <TableBody>
               {loading ? (
-                <TableRow><TableCell colSpan={isAdmin ? 11 : 10} className="text-center py-8">{t('common.loading')}</TableCell></TableRow>
+                <TableRow><TableCell colSpan={isAdmin ? 12 : 11} className="text-center py-8">{t('common.loading')}</TableCell></TableRow>
               ) : clients.length === 0 ? (
-                <TableRow><TableCell colSpan={isAdmin ? 11 : 10} className="text-center py-8 text-muted-foreground">{t('common.noData')}</TableCell></TableRow>
+                <TableRow><TableCell colSpan={isAdmin ? 12 : 11} className="text-center py-8 text-muted-foreground">{t('common.noData')}</TableCell></TableRow>
               ) : (
                 clients.map((c) => {
                   const isExpired = c.expTime && new Date(c.expTime) < new Date();
                   const totalUsed = (c.upTraffic || c.up || 0) + (c.downTraffic || c.down || 0);
                   const isOverTraffic = c.totalTraffic > 0 && totalUsed >= c.totalTraffic;
+                  const isIpBanned = c.ipBanUntil > Date.now();
 
                   return (
                     <TableRow key={c.id}>
+                      <TableCell>
+                        <Checkbox checked={selectedIds.includes(c.id)} onCheckedChange={() => toggleSelected(c.id)} />
+                      </TableCell>
                       <TableCell className="font-medium text-sm">{c.email || '-'}</TableCell>
                       {isAdmin && <TableCell className="text-sm">{c.userId ? getUserName(c.userId) : '-'}</TableCell>}
                       <TableCell className="text-sm">{getInboundTag(c.inboundId)}</TableCell>
@@ -265,6 +400,8 @@ export default function XrayClientPage() {
                           <Badge variant="destructive">{t('xrayClient.overTraffic')}</Badge>
                         ) : c.enable === 0 ? (
                           <Badge variant="secondary">{t('common.disabled')}</Badge>
+                        ) : isIpBanned ? (
+                          <Badge variant="destructive">{t('xrayClient.ipBanned')}</Badge>
                         ) : (
                           <Badge variant="default">{t('common.enabled')}</Badge>
                         )}
@@ -277,6 +414,9 @@ export default function XrayClientPage() {
                           <Button variant="ghost" size="icon" onClick={() => handleResetTraffic(c.id)} title={t('xrayClient.trafficReset')}>
                             <RotateCcw className="h-4 w-4" />
                           </Button>
+                          <Button variant="ghost" size="icon" onClick={() => handleShowOnlineIps(c.id)} title={t('xrayClient.onlineIps')}>
+                            <Globe className="h-4 w-4" />
+                          </Button>
                           <Button variant="ghost" size="icon" onClick={() => handleCopyLink(c.id)} title={t('xrayInbound.copyLink')}>
                             <Copy className="h-4 w-4" />
                           </Button>
@@ -323,6 +463,221 @@ export default function XrayClientPage() {
         </DialogContent>
       </Dialog>
 
+      {/* Online IPs Dialog */}
+      <Dialog open={ipDialogOpen} onOpenChange={setIpDialogOpen}>
+        <DialogContent className="max-w-lg">
+          <DialogHeader>
+            <DialogTitle>{t('xrayClient.onlineIps')}</DialogTitle>
+          </DialogHeader>
+          {ipInfo && (
+            <div className="space-y-3">
+              <p className="text-sm text-muted-foreground">
+                {t('xrayClient.ipWindow', {
+                  sec: ipInfo.windowSec,
+                  count: ipInfo.onlineCount,
+                  limit: ipInfo.limitIp ? ipInfo.limitIp : t('common.unlimited'),
+                })}
+              </p>
+              {ipInfo.ipBanUntil > Date.now() && (
+                <p className="text-sm text-destructive">
+                  {t('xrayClient.ipBannedUntil', { time: new Date(ipInfo.ipBanUntil).toLocaleString() })}
+                </p>
+              )}
+              {ipInfo.ips?.length ? (
+                <Table>
+                  <TableHeader>
+                    <TableRow>
+                      <TableHead>{t('xrayClient.ipAddress')}</TableHead>
+                      <TableHead>{t('xrayClient.ipNode')}</TableHead>
+                      <TableHead>{t('xrayClient.ipLastSeen')}</TableHead>
+                    </TableRow>
+                  </TableHeader>
+                  <TableBody>
+                    {ipInfo.ips.map((ip: any) => (
+                      <TableRow key={ip.ip} className={ip.online ? '' : 'text-muted-foreground'}>
+                        <TableCell className="font-mono text-xs">{ip.ip}</TableCell>
+                        <TableCell className="text-sm">{ip.nodeName || `#${ip.nodeId}`}</TableCell>
+                        <TableCell className="text-xs">{new Date(ip.lastSeen).toLocaleString()}</TableCell>
+                      </TableRow>
+                    ))}
+                  </TableBody>
+                </Table>
+              ) : (
+                <p className="text-sm text-center py-4 text-muted-foreground">{t('xrayClient.noOnlineIps')}</p>
+              )}
+            </div>
+          )}
+        </DialogContent>
+      </Dialog>
+
+      {/* Bulk Create Dialog */}
+      <Dialog open={bulkDialogOpen} onOpenChange={setBulkDialogOpen}>
+        <DialogContent className="max-w-lg max-h-[90vh] overflow-y-auto">
+          <DialogHeader>
+            <DialogTitle>{t('xrayClient.bulkCreate')}</DialogTitle>
+          </DialogHeader>
+          <div className="space-y-4">
+            <div className={isAdmin ? "grid grid-cols-2 gap-4" : ""}>
+              <div className="space-y-2">
+                <Label>{t('xrayClient.inbound')}</Label>
+                <Select value={bulkForm.inboundId} onValueChange={v => setBulkForm(p => ({ ...p, inboundId: v }))}>
+                  <SelectTrigger><SelectValue placeholder={t('xrayClient.selectInbound')} /></SelectTrigger>
+                  <SelectContent>
+                    {inbounds.map((ib: any) => (
+                      <SelectItem key={ib.id} value={ib.id.toString()}>
+                        {ib.remark || ib.tag || `#${ib.id}`} ({ib.protocol})
+                      </SelectItem>
+                    ))}
+                  </SelectContent>
+                </Select>
+              </div>
+              {isAdmin && (
+                <div className="space-y-2">
+                  <Label>{t('xrayClient.userOptional')}</Label>
+                  <Select value={bulkForm.userId} onValueChange={v => setBulkForm(p => ({ ...p, userId: v }))}>
+                    <SelectTrigger><SelectValue placeholder={t('xrayClient.selectUser')} /></SelectTrigger>
+                    <SelectContent>
+                      <SelectItem value="0">{t('xrayClient.noBind')}</SelectItem>
+                      {users.map((u: any) => (
+                        <SelectItem key={u.id} value={u.id.toString()}>{u.user}</SelectItem>
+                      ))}
+                    </SelectContent>
+                  </Select>
+                </div>
+              )}
+            </div>
+            <div className="grid grid-cols-3 gap-4">
+              <div className="space-y-2">
+                <Label>{t('xrayClient.count')}</Label>
+                <Input type="number" value={bulkForm.count} onChange={e => setBulkForm(p => ({ ...p, count: e.target.value }))} />
+              </div>
+              <div className="space-y-2">
+                <Label>{t('xrayClient.startIndex')}</Label>
+                <Input type="number" value={bulkForm.startIndex} onChange={e => setBulkForm(p => ({ ...p, startIndex: e.target.value }))} />
+              </div>
+              <div className="space-y-2">
+                <Label>{t('xrayClient.prefix')}</Label>
+                <Input value={bulkForm.prefix} onChange={e => setBulkForm(p => ({ ...p, prefix: e.target.value }))} placeholder="user-" />
+              </div>
+            </div>
+            <div className="space-y-2">
+              <Label>{t('xrayClient.emailTemplate')}</Label>
+              <Input
+                value={bulkForm.emailTemplate}
+                onChange={e => setBulkForm(p => ({ ...p, emailTemplate: e.target.value }))}
+                placeholder="{prefix}{n}@flux"
+                className="font-mono text-sm"
+              />
+              <p className="text-xs text-muted-foreground">{t('xrayClient.emailTemplateHint')}</p>
+            </div>
+            <p className="text-xs text-muted-foreground">{t('xrayClient.sharedLimits')}</p>
+            <div className="grid grid-cols-2 gap-4">
+              <div className="space-y-2">
+                <Label>{t('xrayClient.flow')}</Label>
+                <Input value={bulkForm.flow} onChange={e => setBulkForm(p => ({ ...p, flow: e.target.value }))} placeholder="xtls-rprx-vision" />
+              </div>
+              <div className="space-y-2">
+                <Label>{t('xrayClient.trafficLimitGb')}</Label>
+                <Input
+                  type="number"
+                  value={bulkForm.totalTraffic}
+                  onChange={e => setBulkForm(p => ({ ...p, totalTraffic: e.target.value }))}
+                  placeholder="0 = 无限"
+                />
+              </div>
+            </div>
+            <div className="grid grid-cols-3 gap-4">
+              <div className="space-y-2">
+                <Label>{t('xrayClient.expireTime')}</Label>
+                <Input
+                  type="datetime-local"
+                  value={bulkForm.expTime}
+                  onChange={e => setBulkForm(p => ({ ...p, expTime: e.target.value }))}
+                />
+              </div>
+              <div className="space-y-2">
+                <Label>{t('xrayClient.ipLimit')}</Label>
+                <Input type="number" value={bulkForm.limitIp} onChange={e => setBulkForm(p => ({ ...p, limitIp: e.target.value }))} />
+              </div>
+              <div className="space-y-2">
+                <Label>{t('xrayClient.resetCycleDays')}</Label>
+                <Input
+                  type="number"
+                  value={bulkForm.reset}
+                  onChange={e => setBulkForm(p => ({ ...p, reset: e.target.value }))}
+                  placeholder={t('xrayClient.noReset')}
+                />
+              </div>
+            </div>
+          </div>
+          <DialogFooter>
+            <Button variant="outline" onClick={() => setBulkDialogOpen(false)}>{t('common.cancel')}</Button>
+            <Button onClick={handleBulkCreateSubmit}>{t('common.confirm')}</Button>
+          </DialogFooter>
+        </DialogContent>
+      </Dialog>
+
+      {/* Bulk Move Dialog */}
+      <Dialog open={moveDialogOpen} onOpenChange={setMoveDialogOpen}>
+        <DialogContent className="max-w-md">
+          <DialogHeader>
+            <DialogTitle>{t('xrayClient.moveClients')}</DialogTitle>
+          </DialogHeader>
+          <div className="space-y-2">
+            <Label>{t('xrayClient.targetInbound')}</Label>
+            <Select value={moveTarget} onValueChange={setMoveTarget}>
+              <SelectTrigger><SelectValue placeholder={t('xrayClient.selectInbound')} /></SelectTrigger>
+              <SelectContent>
+                {inbounds.map((ib: any) => (
+                  <SelectItem key={ib.id} value={ib.id.toString()}>
+                    {ib.remark || ib.tag || `#${ib.id}`} ({ib.protocol})
+                  </SelectItem>
+                ))}
+              </SelectContent>
+            </Select>
+            <p className="text-xs text-muted-foreground">{t('xrayClient.moveHint')}</p>
+          </div>
+          <DialogFooter>
+            <Button variant="outline" onClick={() => setMoveDialogOpen(false)}>{t('common.cancel')}</Button>
+            <Button onClick={handleBulkMoveSubmit}>{t('common.confirm')}</Button>
+          </DialogFooter>
+        </DialogContent>
+      </Dialog>
+
+      {/* Bulk Result Dialog */}
+      <Dialog open={!!bulkReport} onOpenChange={open => { if (!open) setBulkReport(null); }}>
+        <DialogContent className="max-w-lg">
+          <DialogHeader>
+            <DialogTitle>{t('xrayClient.bulkResult')}</DialogTitle>
+          </DialogHeader>
+          {bulkReport && (
+            <div className="space-y-3">
+              <p className="text-sm text-muted-foreground">
+                {t('xrayClient.bulkSummary', { success: bulkReport.success, failed: bulkReport.failed })}
+              </p>
+              <div className="max-h-80 overflow-y-auto">
+                <Table>
+                  <TableBody>
+                    {(bulkReport.results || []).map((r: any, i: number) => (
+                      <TableRow key={`${r.id}-${i}`}>
+                        <TableCell className="text-sm">{r.email || `#${r.id}`}</TableCell>
+                        <TableCell className="text-sm">
+                          {r.success ? (
+                            <Badge variant="default">{t('xrayClient.resultOk')}</Badge>
+                          ) : (
+                            <span className="text-destructive">{r.msg}</span>
+                          )}
+                        </TableCell>
+                      </TableRow>
+                    ))}
+                  </TableBody>
+                </Table>
+              </div>
+            </div>
+          )}
+        </DialogContent>
+      </Dialog>
+
       {/* Create/Edit Client Dialog */}
       <Dialog open={dialogOpen} onOpenChange={setDialogOpen}>
         <DialogContent className="max-w-lg max-h-[90vh] overflow-y-auto">
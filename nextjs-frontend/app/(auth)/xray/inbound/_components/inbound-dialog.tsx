@@ -51,6 +51,8 @@ export default function InboundDialog({ open, onOpenChange, editingInbound, node
 
   // Advanced mode (raw JSON)
   const [advancedMode, setAdvancedMode] = useState(false);
+  // Hysteria2/TUIC run on sing-box over QUIC: TLS only, no transport or sniffing
+  const isQuic = protocol === 'hysteria2' || protocol === 'tuic';
   const [rawSettingsJson, setRawSettingsJson] = useState('{}');
   const [rawStreamSettingsJson, setRawStreamSettingsJson] = useState('{}');
   const [rawSniffingJson, setRawSniffingJson] = useState('{}');
@@ -167,10 +169,10 @@ export default function InboundDialog({ open, onOpenChange, editingInbound, node
       sniffingJson = rawSniffingJson;
     } else {
       settingsJson = buildSettingsJson(protocol, protocolForm);
-      const transportObj = buildTransportJson(transportForm);
+      const transportObj = isQuic ? {} : buildTransportJson(transportForm);
       const securityObj = buildSecurityJson(securityForm);
       streamSettingsJson = JSON.stringify({ ...transportObj, ...securityObj });
-      sniffingJson = buildSniffingJson(sniffingForm);
+      sniffingJson = isQuic ? '{}' : buildSniffingJson(sniffingForm);
     }
 
     const data: any = {
@@ -222,13 +224,19 @@ export default function InboundDialog({ open, onOpenChange, editingInbound, node
             </div>
             <div className="space-y-2">
               <Label className="inline-flex items-center gap-1">{t('inboundDialog.protocol')} <FieldTip content={t('inboundDialog.protocolTooltip')} /></Label>
-              <Select value={protocol} onValueChange={v => { setProtocol(v); setProtocolForm({}); }}>
+              <Select value={protocol} onValueChange={v => {
+                setProtocol(v);
+                setProtocolForm({});
+                if (v === 'hysteria2' || v === 'tuic') setSecurityForm(prev => ({ ...prev, security: 'tls' }));
+              }}>
                 <SelectTrigger><SelectValue /></SelectTrigger>
                 <SelectContent>
                   <SelectItem value="vmess">VMess</SelectItem>
                   <SelectItem value="vless">VLESS</SelectItem>
                   <SelectItem value="trojan">Trojan</SelectItem>
                   <SelectItem value="shadowsocks">Shadowsocks</SelectItem>
+                  <SelectItem value="hysteria2">Hysteria2</SelectItem>
+                  <SelectItem value="tuic">TUIC</SelectItem>
                 </SelectContent>
               </Select>
             </div>
@@ -338,13 +346,15 @@ export default function InboundDialog({ open, onOpenChange, editingInbound, node
                 <ProtocolSettings protocol={protocol} value={protocolForm} onChange={setProtocolForm} transportNetwork={transportForm.network} securityType={securityForm.security} />
               </div>
 
-              <div className="space-y-3">
-                <div className="flex items-center gap-2">
-                  <h4 className="text-sm font-medium text-muted-foreground whitespace-nowrap">{t('inboundDialog.transportSettings')}</h4>
-                  <Separator className="flex-1" />
+              {!isQuic && (
+                <div className="space-y-3">
+                  <div className="flex items-center gap-2">
+                    <h4 className="text-sm font-medium text-muted-foreground whitespace-nowrap">{t('inboundDialog.transportSettings')}</h4>
+                    <Separator className="flex-1" />
+                  </div>
+                  <TransportSettings value={transportForm} onChange={setTransportForm} />
                 </div>
-                <TransportSettings value={transportForm} onChange={setTransportForm} />
-              </div>
+              )}
 
               <div className="space-y-3">
                 <div className="flex items-center gap-2">
@@ -354,13 +364,15 @@ export default function InboundDialog({ open, onOpenChange, editingInbound, node
                 <SecuritySettings value={securityForm} onChange={setSecurityForm} />
               </div>
 
-              <div className="space-y-3">
-                <div className="flex items-center gap-2">
-                  <h4 className="text-sm font-medium text-muted-foreground whitespace-nowrap">{t('inboundDialog.sniffingSettings')}</h4>
-                  <Separator className="flex-1" />
+              {!isQuic && (
+                <div className="space-y-3">
+                  <div className="flex items-center gap-2">
+                    <h4 className="text-sm font-medium text-muted-foreground whitespace-nowrap">{t('inboundDialog.sniffingSettings')}</h4>
+                    <Separator className="flex-1" />
+                  </div>
+                  <SniffingSettings value={sniffingForm} onChange={setSniffingForm} />
                 </div>
-                <SniffingSettings value={sniffingForm} onChange={setSniffingForm} />
-              </div>
+              )}
             </div>
           )}
 
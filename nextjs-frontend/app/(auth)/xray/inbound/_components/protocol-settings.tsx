@@ -30,6 +30,14 @@ export interface ProtocolForm {
   password?: string;
   network?: string;
   ivCheck?: boolean;
+  // Hysteria2
+  upMbps?: number;
+  downMbps?: number;
+  obfsPassword?: string;
+  masquerade?: string;
+  // TUIC
+  congestionControl?: string;
+  zeroRttHandshake?: boolean;
 }
 
 interface Props {
@@ -225,6 +233,58 @@ export default function ProtocolSettings({ protocol, value, onChange, transportN
         </div>
       );
 
+    case 'hysteria2':
+      return (
+        <div className="space-y-3">
+          <p className="text-sm text-muted-foreground">{t('protocol.singboxHint')}</p>
+          <div className="grid grid-cols-2 gap-4">
+            <div className="space-y-2">
+              <Label className="inline-flex items-center gap-1">{t('protocol.upMbps')} <FieldTip content={t('protocol.bandwidthTooltip')} /></Label>
+              <Input type="number" min={0} value={value.upMbps ?? ''} onChange={e => update({ upMbps: parseInt(e.target.value) || undefined })} placeholder="0" />
+            </div>
+            <div className="space-y-2">
+              <Label className="inline-flex items-center gap-1">{t('protocol.downMbps')} <FieldTip content={t('protocol.bandwidthTooltip')} /></Label>
+              <Input type="number" min={0} value={value.downMbps ?? ''} onChange={e => update({ downMbps: parseInt(e.target.value) || undefined })} placeholder="0" />
+            </div>
+          </div>
+          <div className="space-y-2">
+            <div className="flex items-center justify-between">
+              <Label className="inline-flex items-center gap-1">{t('protocol.obfsPassword')} <FieldTip content={t('protocol.obfsPasswordTooltip')} /></Label>
+              <Button type="button" variant="ghost" size="sm" onClick={() => update({ obfsPassword: randomShadowsocksPassword('') })}>
+                <Shuffle className="h-3 w-3 mr-1" />{t('protocol.randomGenerate')}
+              </Button>
+            </div>
+            <Input value={value.obfsPassword ?? ''} onChange={e => update({ obfsPassword: e.target.value })} className="font-mono text-sm" />
+          </div>
+          <div className="space-y-2">
+            <Label className="inline-flex items-center gap-1">{t('protocol.masquerade')} <FieldTip content={t('protocol.masqueradeTooltip')} /></Label>
+            <Input value={value.masquerade ?? ''} onChange={e => update({ masquerade: e.target.value })} placeholder="https://www.bing.com" />
+          </div>
+        </div>
+      );
+
+    case 'tuic':
+      return (
+        <div className="space-y-3">
+          <p className="text-sm text-muted-foreground">{t('protocol.singboxHint')}</p>
+          <div className="space-y-2">
+            <Label className="inline-flex items-center gap-1">{t('protocol.congestionControl')} <FieldTip content={t('protocol.congestionControlTooltip')} /></Label>
+            <Select value={value.congestionControl ?? 'bbr'} onValueChange={v => update({ congestionControl: v })}>
+              <SelectTrigger><SelectValue /></SelectTrigger>
+              <SelectContent>
+                <SelectItem value="bbr">bbr</SelectItem>
+                <SelectItem value="cubic">cubic</SelectItem>
+                <SelectItem value="new_reno">new_reno</SelectItem>
+              </SelectContent>
+            </Select>
+          </div>
+          <div className="flex items-center justify-between">
+            <Label className="text-sm inline-flex items-center gap-1">{t('protocol.zeroRtt')} <FieldTip content={t('protocol.zeroRttTooltip')} /></Label>
+            <Switch checked={value.zeroRttHandshake ?? false} onCheckedChange={v => update({ zeroRttHandshake: v })} />
+          </div>
+        </div>
+      );
+
     default:
       return <p className="text-sm text-muted-foreground">{t('protocol.unknownProtocol')}: {protocol}</p>;
   }
@@ -283,6 +343,24 @@ export function buildSettingsJson(protocol: string, form: ProtocolForm): string
       return JSON.stringify(obj);
     }
 
+    case 'hysteria2': {
+      const obj: Record<string, any> = { clients: [] };
+      if (form.upMbps) obj.upMbps = form.upMbps;
+      if (form.downMbps) obj.downMbps = form.downMbps;
+      if (form.obfsPassword) obj.obfsPassword = form.obfsPassword;
+      if (form.masquerade) obj.masquerade = form.masquerade;
+      return JSON.stringify(obj);
+    }
+
+    case 'tuic': {
+      const obj: Record<string, any> = {
+        clients: [],
+        congestionControl: form.congestionControl || 'bbr',
+      };
+      if (form.zeroRttHandshake) obj.zeroRttHandshake = true;
+      return JSON.stringify(obj);
+    }
+
     default:
       return '{}';
   }
@@ -325,6 +403,18 @@ export function parseSettingsJson(protocol: string, json: string): ProtocolForm
           password: obj.password || '',
           ivCheck: obj.ivCheck ?? false,
         };
+      case 'hysteria2':
+        return {
+          upMbps: obj.upMbps || undefined,
+          downMbps: obj.downMbps || undefined,
+          obfsPassword: obj.obfsPassword || '',
+          masquerade: obj.masquerade || '',
+        };
+      case 'tuic':
+        return {
+          congestionControl: obj.congestionControl || 'bbr',
+          zeroRttHandshake: obj.zeroRttHandshake ?? false,
+        };
       default:
         return {};
     }
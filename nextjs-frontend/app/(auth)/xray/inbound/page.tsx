@@ -111,6 +111,8 @@ export default function XrayInboundPage() {
       case 'trojan': return 'destructive';
       case 'shadowsocks':
       case 'ss': return 'outline';
+      case 'hysteria2':
+      case 'tuic': return 'default';
       default: return 'secondary';
     }
   };
@@ -118,7 +120,7 @@ export default function XrayInboundPage() {
   const getTransportInfo = (ib: any) => {
     try {
       const stream = JSON.parse(ib.streamSettingsJson || ib.streamSettings || '{}');
-      const network = stream.network || 'tcp';
+      const network = stream.network || (ib.protocol === 'hysteria2' || ib.protocol === 'tuic' ? 'quic' : 'tcp');
       const security = stream.security || 'none';
       return `${network}${security !== 'none' ? '+' + security : ''}`;
     } catch {
@@ -0,0 +1,626 @@
+'use client';
+
+import { useState, useEffect, useCallback } from 'react';
+import { Card, CardContent } from '@/components/ui/card';
+import { Button } from '@/components/ui/button';
+import { Badge } from '@/components/ui/badge';
+import { Table, TableBody, TableCell, TableHead, TableHeader, TableRow } from '@/components/ui/table';
+import { Dialog, DialogContent, DialogHeader, DialogTitle, DialogFooter } from '@/components/ui/dialog';
+import { Input } from '@/components/ui/input';
+import { Label } from '@/components/ui/label';
+import { Textarea } from '@/components/ui/textarea';
+import { Checkbox } from '@/components/ui/checkbox';
+import { Select, SelectContent, SelectItem, SelectTrigger, SelectValue } from '@/components/ui/select';
+import { Switch } from '@/components/ui/switch';
+import { Tabs, TabsContent, TabsList, TabsTrigger } from '@/components/ui/tabs';
+import { Plus, Pencil, Trash2, FileJson } from 'lucide-react';
+import { toast } from 'sonner';
+import {
+  getXrayOutboundList, createXrayOutbound, updateXrayOutbound, deleteXrayOutbound,
+  getXrayRoutingRuleList, createXrayRoutingRule, updateXrayRoutingRule, deleteXrayRoutingRule,
+  previewXrayRouting,
+} from '@/lib/api/xray-routing';
+import { getXrayInboundList } from '@/lib/api/xray-inbound';
+import { getAccessibleNodeList } from '@/lib/api/node';
+import { useAuth } from '@/lib/hooks/use-auth';
+import { useTranslation } from '@/lib/i18n';
+
+const OUTBOUND_PROTOCOLS = ['chain', 'socks', 'http', 'wireguard', 'vless', 'vmess', 'trojan', 'shadowsocks', 'freedom', 'blackhole', 'dns'];
+const CHAIN_PROTOCOLS = ['vmess', 'vless', 'trojan', 'shadowsocks'];
+const BUILTIN_OUTBOUNDS = ['direct', 'blocked'];
+
+const SETTINGS_PLACEHOLDERS: Record<string, string> = {
+  socks: '{"servers":[{"address":"1.2.3.4","port":1080,"users":[{"user":"u","pass":"p"}]}]}',
+  http: '{"servers":[{"address":"1.2.3.4","port":8080}]}',
+  wireguard: '{"secretKey":"...","address":["172.16.0.2/32"],"peers":[{"publicKey":"...","endpoint":"engage.cloudflareclient.com:2408"}]}',
+  freedom: '{"domainStrategy":"UseIPv4"}',
+};
+
+const emptyOutbound = {
+  tag: '', protocol: 'chain', settingsJson: '', streamSettingsJson: '',
+  chainInboundId: '', dialerProxy: '', remark: '', inx: 0, enable: true,
+};
+
+const emptyRule = {
+  inboundIds: [] as number[], userIds: '', domain: '', ip: '', port: '',
+  network: '', protocol: '', outboundTag: 'direct', remark: '', inx: 0, enable: true,
+};
+
+export default function XrayRoutingPage() {
+  const { isAdmin } = useAuth();
+  const { t } = useTranslation();
+  const [nodes, setNodes] = useState<any[]>([]);
+  const [nodeId, setNodeId] = useState('');
+  const [inbounds, setInbounds] = useState<any[]>([]);
+  const [outbounds, setOutbounds] = useState<any[]>([]);
+  const [rules, setRules] = useState<any[]>([]);
+  const [loading, setLoading] = useState(false);
+  const [saving, setSaving] = useState(false);
+
+  const [outboundOpen, setOutboundOpen] = useState(false);
+  const [editingOutbound, setEditingOutbound] = useState<any>(null);
+  const [outboundForm, setOutboundForm] = useState(emptyOutbound);
+
+  const [ruleOpen, setRuleOpen] = useState(false);
+  const [editingRule, setEditingRule] = useState<any>(null);
+  const [ruleForm, setRuleForm] = useState(emptyRule);
+
+  const [previewOpen, setPreviewOpen] = useState(false);
+  const [previewJson, setPreviewJson] = useState('');
+
+  useEffect(() => {
+    Promise.all([getAccessibleNodeList({ xrayOnly: true }), getXrayInboundList()]).then(([nodeRes, inboundRes]) => {
+      if (nodeRes.code === 0) {
+        const list = nodeRes.data || [];
+        setNodes(list);
+        if (list.length > 0) setNodeId(list[0].id.toString());
+      }
+      if (inboundRes.code === 0) setInbounds(inboundRes.data || []);
+    });
+  }, []);
+
+  const loadData = useCallback(async () => {
+    if (!nodeId) return;
+    setLoading(true);
+    const id = parseInt(nodeId);
+    const [obRes, ruleRes] = await Promise.all([getXrayOutboundList(id), getXrayRoutingRuleList(id)]);
+    if (obRes.code === 0) setOutbounds(obRes.data || []);
+    if (ruleRes.code === 0) setRules(ruleRes.data || []);
+    setLoading(false);
+  }, [nodeId]);
+
+  useEffect(() => { loadData(); }, [loadData]);
+
+  const nodeInbounds = inbounds.filter((ib: any) => ib.nodeId === parseInt(nodeId));
+  const chainTargets = inbounds.filter((ib: any) => ib.nodeId !== parseInt(nodeId) && CHAIN_PROTOCOLS.includes(ib.protocol));
+  const outboundTags = [...BUILTIN_OUTBOUNDS, ...outbounds.map((o: any) => o.tag)];
+
+  const getNodeName = (id: number) => nodes.find((n: any) => n.id === id)?.name || `#${id}`;
+  const describeInbound = (ib: any) => `${getNodeName(ib.nodeId)} / ${ib.remark || ib.tag} (${ib.protocol}:${ib.port})`;
+  const getInboundLabel = (id: number) => {
+    const ib = inbounds.find((i: any) => i.id === id);
+    return ib ? (ib.remark || ib.tag) : `#${id}`;
+  };
+
+  // ---- Outbounds ----
+
+  const handleCreateOutbound = () => {
+    setEditingOutbound(null);
+    setOutboundForm(emptyOutbound);
+    setOutboundOpen(true);
+  };
+
+  const handleEditOutbound = (ob: any) => {
+    setEditingOutbound(ob);
+    setOutboundForm({
+      tag: ob.tag, protocol: ob.protocol, settingsJson: ob.settingsJson || '',
+      streamSettingsJson: ob.streamSettingsJson || '',
+      chainInboundId: ob.chainInboundId ? ob.chainInboundId.toString() : '',
+      dialerProxy: ob.dialerProxy || '', remark: ob.remark || '', inx: ob.inx || 0,
+      enable: ob.enable === 1,
+    });
+    setOutboundOpen(true);
+  };
+
+  const handleSubmitOutbound = async () => {
+    if (!outboundForm.tag) {
+      toast.error(t('xrayRouting.fillTag'));
+      return;
+    }
+    if (outboundForm.protocol === 'chain' && !outboundForm.chainInboundId) {
+      toast.error(t('xrayRouting.selectChainTarget'));
+      return;
+    }
+    const data: any = {
+      nodeId: parseInt(nodeId),
+      tag: outboundForm.tag.trim(),
+      protocol: outboundForm.protocol,
+      settingsJson: outboundForm.protocol === 'chain' ? '' : outboundForm.settingsJson,
+      streamSettingsJson: outboundForm.protocol === 'chain' ? '' : outboundForm.streamSettingsJson,
+      chainInboundId: outboundForm.protocol === 'chain' ? parseInt(outboundForm.chainInboundId) : 0,
+      dialerProxy: outboundForm.dialerProxy,
+      remark: outboundForm.remark,
+      inx: Number(outboundForm.inx) || 0,
+    };
+    setSaving(true);
+    let res;
+    if (editingOutbound) {
+      res = await updateXrayOutbound({ ...data, id: editingOutbound.id, enable: outboundForm.enable ? 1 : 0 });
+    } else {
+      res = await createXrayOutbound(data);
+    }
+    setSaving(false);
+    if (res.code === 0) {
+      toast.success(editingOutbound ? t('common.updateSuccess') : t('common.createSuccess'));
+      setOutboundOpen(false);
+      loadData();
+    } else {
+      toast.error(res.msg);
+    }
+  };
+
+  const handleDeleteOutbound = async (id: number) => {
+    if (!confirm(t('xrayRouting.confirmDeleteOutbound'))) return;
+    const res = await deleteXrayOutbound(id);
+    if (res.code === 0) { toast.success(t('common.deleteSuccess')); loadData(); }
+    else toast.error(res.msg);
+  };
+
+  // ---- Rules ----
+
+  const handleCreateRule = () => {
+    setEditingRule(null);
+    setRuleForm(emptyRule);
+    setRuleOpen(true);
+  };
+
+  const handleEditRule = (rule: any) => {
+    setEditingRule(rule);
+    setRuleForm({
+      inboundIds: (rule.inboundIds || '').split(',').filter(Boolean).map((v: string) => parseInt(v)),
+      userIds: rule.userIds || '', domain: rule.domain || '', ip: rule.ip || '',
+      port: rule.port || '', network: rule.network || '', protocol: rule.protocol || '',
+      outboundTag: rule.outboundTag, remark: rule.remark || '', inx: rule.inx || 0,
+      enable: rule.enable === 1,
+    });
+    setRuleOpen(true);
+  };
+
+  const toggleRuleInbound = (id: number, checked: boolean) => {
+    setRuleForm(p => ({
+      ...p,
+      inboundIds: checked ? [...p.inboundIds, id] : p.inboundIds.filter(v => v !== id),
+    }));
+  };
+
+  const handleSubmitRule = async () => {
+    const data: any = {
+      nodeId: parseInt(nodeId),
+      inboundIds: ruleForm.inboundIds.join(','),
+      userIds: ruleForm.userIds.replace(/\s/g, ''),
+      domain: ruleForm.domain.trim(),
+      ip: ruleForm.ip.trim(),
+      port: ruleForm.port.trim(),
+      network: ruleForm.network,
+      protocol: ruleForm.protocol.replace(/\s/g, ''),
+      outboundTag: ruleForm.outboundTag,
+      remark: ruleForm.remark,
+      inx: Number(ruleForm.inx) || 0,
+    };
+    setSaving(true);
+    let res;
+    if (editingRule) {
+      res = await updateXrayRoutingRule({ ...data, id: editingRule.id, enable: ruleForm.enable ? 1 : 0 });
+    } else {
+      res = await createXrayRoutingRule(data);
+    }
+    setSaving(false);
+    if (res.code === 0) {
+      toast.success(editingRule ? t('common.updateSuccess') : t('common.createSuccess'));
+      setRuleOpen(false);
+      loadData();
+    } else {
+      toast.error(res.msg);
+    }
+  };
+
+  const handleDeleteRule = async (id: number) => {
+    if (!confirm(t('xrayRouting.confirmDeleteRule'))) return;
+    const res = await deleteXrayRoutingRule(id);
+    if (res.code === 0) { toast.success(t('common.deleteSuccess')); loadData(); }
+    else toast.error(res.msg);
+  };
+
+  const describeRule = (rule: any) => {
+    const parts: string[] = [];
+    if (rule.inboundIds) parts.push(`${t('xrayRouting.inbounds')}: ${rule.inboundIds.split(',').map((id: string) => getInboundLabel(parseInt(id))).join(', ')}`);
+    if (rule.userIds) parts.push(`${t('xrayRouting.users')}: ${rule.userIds}`);
+    if (rule.domain) parts.push(`${t('xrayRouting.domain')}: ${rule.domain.split('\n').length}`);
+    if (rule.ip) parts.push(`IP: ${rule.ip.split('\n').length}`);
+    if (rule.port) parts.push(`${t('xrayRouting.port')}: ${rule.port}`);
+    if (rule.network) parts.push(rule.network);
+    if (rule.protocol) parts.push(rule.protocol);
+    return parts.join(' · ');
+  };
+
+  const handlePreview = async () => {
+    const res = await previewXrayRouting(parseInt(nodeId));
+    if (res.code === 0) {
+      setPreviewJson(JSON.stringify(res.data, null, 2));
+      setPreviewOpen(true);
+    } else {
+      toast.error(res.msg);
+    }
+  };
+
+  if (!isAdmin) {
+    return (
+      <div className="flex items-center justify-center h-64">
+        <p className="text-muted-foreground">{t('common.noPermission')}</p>
+      </div>
+    );
+  }
+
+  return (
+    <div className="space-y-4">
+      <div className="flex items-center justify-between gap-2 flex-wrap">
+        <h2 className="text-2xl font-bold">{t('xrayRouting.title')}</h2>
+        <div className="flex items-center gap-2">
+          <Select value={nodeId} onValueChange={setNodeId}>
+            <SelectTrigger className="w-48"><SelectValue placeholder={t('xrayRouting.selectNode')} /></SelectTrigger>
+            <SelectContent>
+              {nodes.map((n: any) => (
+                <SelectItem key={n.id} value={n.id.toString()}>{n.name}</SelectItem>
+              ))}
+            </SelectContent>
+          </Select>
+          <Button variant="outline" onClick={handlePreview} disabled={!nodeId}>
+            <FileJson className="mr-2 h-4 w-4" />{t('xrayRouting.preview')}
+          </Button>
+        </div>
+      </div>
+
+      <Tabs defaultValue="outbounds">
+        <TabsList>
+          <TabsTrigger value="outbounds">{t('xrayRouting.outbounds')}</TabsTrigger>
+          <TabsTrigger value="rules">{t('xrayRouting.rules')}</TabsTrigger>
+        </TabsList>
+
+        <TabsContent value="outbounds" className="space-y-3">
+          <div className="flex justify-end">
+            <Button onClick={handleCreateOutbound} disabled={!nodeId}><Plus className="mr-2 h-4 w-4" />{t('xrayRouting.addOutbound')}</Button>
+          </div>
+          <Card>
+            <CardContent className="p-0">
+              <Table>
+                <TableHeader>
+                  <TableRow>
+                    <TableHead>{t('xrayRouting.tag')}</TableHead>
+                    <TableHead>{t('xrayRouting.protocol')}</TableHead>
+                    <TableHead>{t('xrayRouting.target')}</TableHead>
+                    <TableHead>{t('xrayRouting.dialerProxy')}</TableHead>
+                    <TableHead>{t('common.status')}</TableHead>
+                    <TableHead>{t('common.actions')}</TableHead>
+                  </TableRow>
+                </TableHeader>
+                <TableBody>
+                  {loading ? (
+                    <TableRow><TableCell colSpan={6} className="text-center py-8">{t('common.loading')}</TableCell></TableRow>
+                  ) : outbounds.length === 0 ? (
+                    <TableRow><TableCell colSpan={6} className="text-center py-8 text-muted-foreground">{t('common.noData')}</TableCell></TableRow>
+                  ) : (
+                    outbounds.map((ob) => {
+                      const target = inbounds.find((ib: any) => ib.id === ob.chainInboundId);
+                      return (
+                        <TableRow key={ob.id}>
+                          <TableCell className="font-medium">
+                            {ob.tag}
+                            {ob.remark && <div className="text-xs text-muted-foreground">{ob.remark}</div>}
+                          </TableCell>
+                          <TableCell><Badge variant="outline">{ob.protocol === 'chain' ? t('xrayRouting.chain') : ob.protocol}</Badge></TableCell>
+                          <TableCell className="text-sm">{ob.protocol === 'chain' ? (target ? describeInbound(target) : `#${ob.chainInboundId}`) : '-'}</TableCell>
+                          <TableCell className="text-sm">{ob.dialerProxy || '-'}</TableCell>
+                          <TableCell>
+                            <Badge variant={ob.enable === 1 ? 'default' : 'secondary'}>
+                              {ob.enable === 1 ? t('common.enabled') : t('common.disabled')}
+                            </Badge>
+                          </TableCell>
+                          <TableCell>
+                            <div className="flex gap-1">
+                              <Button variant="ghost" size="icon" onClick={() => handleEditOutbound(ob)} title={t('common.edit')}>
+                                <Pencil className="h-4 w-4" />
+                              </Button>
+                              <Button variant="ghost" size="icon" onClick={() => handleDeleteOutbound(ob.id)} className="text-destructive" title={t('common.delete')}>
+                                <Trash2 className="h-4 w-4" />
+                              </Button>
+                            </div>
+                          </TableCell>
+                        </TableRow>
+                      );
+                    })
+                  )}
+                </TableBody>
+              </Table>
+            </CardContent>
+          </Card>
+        </TabsContent>
+
+        <TabsContent value="rules" className="space-y-3">
+          <div className="flex items-center justify-between gap-2">
+            <p className="text-xs text-muted-foreground">{t('xrayRouting.rulesHint')}</p>
+            <Button onClick={handleCreateRule} disabled={!nodeId}><Plus className="mr-2 h-4 w-4" />{t('xrayRouting.addRule')}</Button>
+          </div>
+          <Card>
+            <CardContent className="p-0">
+              <Table>
+                <TableHeader>
+                  <TableRow>
+                    <TableHead>{t('xrayRouting.order')}</TableHead>
+                    <TableHead>{t('xrayRouting.conditions')}</TableHead>
+                    <TableHead>{t('xrayRouting.outboundTag')}</TableHead>
+                    <TableHead>{t('common.status')}</TableHead>
+                    <TableHead>{t('common.actions')}</TableHead>
+                  </TableRow>
+                </TableHeader>
+                <TableBody>
+                  {loading ? (
+                    <TableRow><TableCell colSpan={5} className="text-center py-8">{t('common.loading')}</TableCell></TableRow>
+                  ) : rules.length === 0 ? (
+                    <TableRow><TableCell colSpan={5} className="text-center py-8 text-muted-foreground">{t('common.noData')}</TableCell></TableRow>
+                  ) : (
+                    rules.map((rule) => (
+                      <TableRow key={rule.id}>
+                        <TableCell>{rule.inx}</TableCell>
+                        <TableCell className="text-sm">
+                          {describeRule(rule)}
+                          {rule.remark && <div className="text-xs text-muted-foreground">{rule.remark}</div>}
+                        </TableCell>
+                        <TableCell><Badge variant="outline">{rule.outboundTag}</Badge></TableCell>
+                        <TableCell>
+                          <Badge variant={rule.enable === 1 ? 'default' : 'secondary'}>
+                            {rule.enable === 1 ? t('common.enabled') : t('common.disabled')}
+                          </Badge>
+                        </TableCell>
+                        <TableCell>
+                          <div className="flex gap-1">
+                            <Button variant="ghost" size="icon" onClick={() => handleEditRule(rule)} title={t('common.edit')}>
+                              <Pencil className="h-4 w-4" />
+                            </Button>
+                            <Button variant="ghost" size="icon" onClick={() => handleDeleteRule(rule.id)} className="text-destructive" title={t('common.delete')}>
+                              <Trash2 className="h-4 w-4" />
+                            </Button>
+                          </div>
+                        </TableCell>
+                      </TableRow>
+                    ))
+                  )}
+                </TableBody>
+              </Table>
+            </CardContent>
+          </Card>
+        </TabsContent>
+      </Tabs>
+
+      {/* Outbound Dialog */}
+      <Dialog open={outboundOpen} onOpenChange={setOutboundOpen}>
+        <DialogContent className="max-w-lg max-h-[90vh] overflow-y-auto">
+          <DialogHeader>
+            <DialogTitle>{editingOutbound ? t('xrayRouting.editOutbound') : t('xrayRouting.addOutbound')}</DialogTitle>
+          </DialogHeader>
+          <div className="space-y-4">
+            <div className="grid grid-cols-2 gap-3">
+              <div className="space-y-2">
+                <Label>{t('xrayRouting.tag')}</Label>
+                <Input value={outboundForm.tag} onChange={e => setOutboundForm(p => ({ ...p, tag: e.target.value }))} placeholder="warp" />
+              </div>
+              <div className="space-y-2">
+                <Label>{t('xrayRouting.protocol')}</Label>
+                <Select value={outboundForm.protocol} onValueChange={v => setOutboundForm(p => ({ ...p, protocol: v }))}>
+                  <SelectTrigger><SelectValue /></SelectTrigger>
+                  <SelectContent>
+                    {OUTBOUND_PROTOCOLS.map(p => (
+                      <SelectItem key={p} value={p}>{p === 'chain' ? t('xrayRouting.chain') : p}</SelectItem>
+                    ))}
+                  </SelectContent>
+                </Select>
+              </div>
+            </div>
+
+            {outboundForm.protocol === 'chain' ? (
+              <div className="space-y-2">
+                <Label>{t('xrayRouting.chainTarget')}</Label>
+                <Select value={outboundForm.chainInboundId} onValueChange={v => setOutboundForm(p => ({ ...p, chainInboundId: v }))}>
+                  <SelectTrigger><SelectValue placeholder={t('xrayRouting.selectChainTarget')} /></SelectTrigger>
+                  <SelectContent>
+                    {chainTargets.map((ib: any) => (
+                      <SelectItem key={ib.id} value={ib.id.toString()}>{describeInbound(ib)}</SelectItem>
+                    ))}
+                  </SelectContent>
+                </Select>
+                <p className="text-xs text-muted-foreground">{t('xrayRouting.chainHint')}</p>
+              </div>
+            ) : (
+              <>
+                <div className="space-y-2">
+                  <Label>{t('xrayRouting.settingsJson')}</Label>
+                  <Textarea
+                    value={outboundForm.settingsJson}
+                    onChange={e => setOutboundForm(p => ({ ...p, settingsJson: e.target.value }))}
+                    placeholder={SETTINGS_PLACEHOLDERS[outboundForm.protocol] || '{}'}
+                    rows={5}
+                    className="font-mono text-xs"
+                  />
+                </div>
+                <div className="space-y-2">
+                  <Label>{t('xrayRouting.streamSettingsJson')}</Label>
+                  <Textarea
+                    value={outboundForm.streamSettingsJson}
+                    onChange={e => setOutboundForm(p => ({ ...p, streamSettingsJson: e.target.value }))}
+                    placeholder='{"network":"tcp"}'
+                    rows={3}
+                    className="font-mono text-xs"
+                  />
+                </div>
+              </>
+            )}
+
+            <div className="space-y-2">
+              <Label>{t('xrayRouting.dialerProxy')}</Label>
+              <Select value={outboundForm.dialerProxy || '__none'} onValueChange={v => setOutboundForm(p => ({ ...p, dialerProxy: v === '__none' ? '' : v }))}>
+                <SelectTrigger><SelectValue /></SelectTrigger>
+                <SelectContent>
+                  <SelectItem value="__none">{t('xrayRouting.none')}</SelectItem>
+                  {outbounds.filter((o: any) => o.tag !== outboundForm.tag).map((o: any) => (
+                    <SelectItem key={o.id} value={o.tag}>{o.tag}</SelectItem>
+                  ))}
+                </SelectContent>
+              </Select>
+              <p className="text-xs text-muted-foreground">{t('xrayRouting.dialerProxyHint')}</p>
+            </div>
+
+            <div className="grid grid-cols-2 gap-3">
+              <div className="space-y-2">
+                <Label>{t('common.remark')}</Label>
+                <Input value={outboundForm.remark} onChange={e => setOutboundForm(p => ({ ...p, remark: e.target.value }))} />
+              </div>
+              <div className="space-y-2">
+                <Label>{t('xrayRouting.order')}</Label>
+                <Input type="number" value={outboundForm.inx} onChange={e => setOutboundForm(p => ({ ...p, inx: parseInt(e.target.value) || 0 }))} />
+              </div>
+            </div>
+            {editingOutbound && (
+              <div className="flex items-center justify-between">
+                <Label>{t('common.enabled')}</Label>
+                <Switch checked={outboundForm.enable} onCheckedChange={v => setOutboundForm(p => ({ ...p, enable: v }))} />
+              </div>
+            )}
+          </div>
+          <DialogFooter>
+            <Button variant="outline" onClick={() => setOutboundOpen(false)}>{t('common.cancel')}</Button>
+            <Button onClick={handleSubmitOutbound} disabled={saving}>{editingOutbound ? t('common.update') : t('common.create')}</Button>
+          </DialogFooter>
+        </DialogContent>
+      </Dialog>
+
+      {/* Rule Dialog */}
+      <Dialog open={ruleOpen} onOpenChange={setRuleOpen}>
+        <DialogContent className="max-w-lg max-h-[90vh] overflow-y-auto">
+          <DialogHeader>
+            <DialogTitle>{editingRule ? t('xrayRouting.editRule') : t('xrayRouting.addRule')}</DialogTitle>
+          </DialogHeader>
+          <div className="space-y-4">
+            <div className="space-y-2">
+              <Label>{t('xrayRouting.outboundTag')}</Label>
+              <Select value={ruleForm.outboundTag} onValueChange={v => setRuleForm(p => ({ ...p, outboundTag: v }))}>
+                <SelectTrigger><SelectValue /></SelectTrigger>
+                <SelectContent>
+                  {outboundTags.map(tag => (
+                    <SelectItem key={tag} value={tag}>{tag}</SelectItem>
+                  ))}
+                </SelectContent>
+              </Select>
+            </div>
+
+            {nodeInbounds.length > 0 && (
+              <div className="space-y-2">
+                <Label>{t('xrayRouting.inbounds')}</Label>
+                <div className="space-y-1 max-h-32 overflow-y-auto rounded-md border p-2">
+                  {nodeInbounds.map((ib: any) => (
+                    <label key={ib.id} className="flex items-center gap-2 text-sm">
+                      <Checkbox
+                        checked={ruleForm.inboundIds.includes(ib.id)}
+                        onCheckedChange={v => toggleRuleInbound(ib.id, v === true)}
+                      />
+                      {ib.remark || ib.tag} <span className="text-muted-foreground">({ib.protocol}:{ib.port})</span>
+                    </label>
+                  ))}
+                </div>
+              </div>
+            )}
+
+            <div className="space-y-2">
+              <Label>{t('xrayRouting.users')}</Label>
+              <Input value={ruleForm.userIds} onChange={e => setRuleForm(p => ({ ...p, userIds: e.target.value }))} placeholder="1,2,3" />
+              <p className="text-xs text-muted-foreground">{t('xrayRouting.usersHint')}</p>
+            </div>
+
+            <div className="space-y-2">
+              <Label>{t('xrayRouting.domain')}</Label>
+              <Textarea
+                value={ruleForm.domain}
+                onChange={e => setRuleForm(p => ({ ...p, domain: e.target.value }))}
+                placeholder={'geosite:netflix\ndomain:openai.com\nfull:example.com'}
+                rows={3}
+                className="font-mono text-xs"
+              />
+            </div>
+            <div className="space-y-2">
+              <Label>IP</Label>
+              <Textarea
+                value={ruleForm.ip}
+                onChange={e => setRuleForm(p => ({ ...p, ip: e.target.value }))}
+                placeholder={'geoip:cn\ngeoip:private\n10.0.0.0/8'}
+                rows={3}
+                className="font-mono text-xs"
+              />
+            </div>
+
+            <div className="grid grid-cols-2 gap-3">
+              <div className="space-y-2">
+                <Label>{t('xrayRouting.port')}</Label>
+                <Input value={ruleForm.port} onChange={e => setRuleForm(p => ({ ...p, port: e.target.value }))} placeholder="53,443,1000-2000" />
+              </div>
+              <div className="space-y-2">
+                <Label>{t('xrayRouting.network')}</Label>
+                <Select value={ruleForm.network || '__any'} onValueChange={v => setRuleForm(p => ({ ...p, network: v === '__any' ? '' : v }))}>
+                  <SelectTrigger><SelectValue /></SelectTrigger>
+                  <SelectContent>
+                    <SelectItem value="__any">{t('xrayRouting.any')}</SelectItem>
+                    <SelectItem value="tcp">tcp</SelectItem>
+                    <SelectItem value="udp">udp</SelectItem>
+                    <SelectItem value="tcp,udp">tcp,udp</SelectItem>
+                  </SelectContent>
+                </Select>
+              </div>
+            </div>
+            <div className="space-y-2">
+              <Label>{t('xrayRouting.sniffProtocol')}</Label>
+              <Input value={ruleForm.protocol} onChange={e => setRuleForm(p => ({ ...p, protocol: e.target.value }))} placeholder="bittorrent" />
+            </div>
+
+            <div className="grid grid-cols-2 gap-3">
+              <div className="space-y-2">
+                <Label>{t('common.remark')}</Label>
+                <Input value={ruleForm.remark} onChange={e => setRuleForm(p => ({ ...p, remark: e.target.value }))} />
+              </div>
+              <div className="space-y-2">
+                <Label>{t('xrayRouting.order')}</Label>
+                <Input type="number" value={ruleForm.inx} onChange={e => setRuleForm(p => ({ ...p, inx: parseInt(e.target.value) || 0 }))} />
+              </div>
+            </div>
+            {editingRule && (
+              <div className="flex items-center justify-between">
+                <Label>{t('common.enabled')}</Label>
+                <Switch checked={ruleForm.enable} onCheckedChange={v => setRuleForm(p => ({ ...p, enable: v }))} />
+              </div>
+            )}
+          </div>
+          <DialogFooter>
+            <Button variant="outline" onClick={() => setRuleOpen(false)}>{t('common.cancel')}</Button>
+            <Button onClick={handleSubmitRule} disabled={saving}>{editingRule ? t('common.update') : t('common.create')}</Button>
+          </DialogFooter>
+        </DialogContent>
+      </Dialog>
+
+      {/* Preview Dialog */}
+      <Dialog open={previewOpen} onOpenChange={setPreviewOpen}>
+        <DialogContent className="max-w-2xl max-h-[90vh] overflow-y-auto">
+          <DialogHeader>
+            <DialogTitle>{t('xrayRouting.preview')}</DialogTitle>
+          </DialogHeader>
+          <pre className="text-xs font-mono bg-muted rounded-md p-3 overflow-x-auto">{previewJson}</pre>
+        </DialogContent>
+      </Dialog>
+    </div>
+  );
+}
@@ -0,0 +1,126 @@
+'use client';
+
+import { useState, useEffect } from 'react';
+import { Dialog, DialogContent, DialogHeader, DialogTitle, DialogFooter } from '@/components/ui/dialog';
+import { Button } from '@/components/ui/button';
+import { Input } from '@/components/ui/input';
+import { Label } from '@/components/ui/label';
+import { Textarea } from '@/components/ui/textarea';
+import { Checkbox } from '@/components/ui/checkbox';
+import { Select, SelectContent, SelectItem, SelectTrigger, SelectValue } from '@/components/ui/select';
+import { useTranslation } from '@/lib/i18n';
+
+interface Props {
+  open: boolean;
+  onOpenChange: (open: boolean) => void;
+  editingProfile: any;
+  nodes: any[];
+  inbounds: any[];
+  onSubmit: (data: any) => Promise<void>;
+}
+
+const parseIds = (value?: string) =>
+  (value || '').split(',').map(s => parseInt(s.trim())).filter(n => n > 0);
+
+export default function ProfileDialog({ open, onOpenChange, editingProfile, nodes, inbounds, onSubmit }: Props) {
+  const { t } = useTranslation();
+  const [name, setName] = useState('');
+  const [nodeIds, setNodeIds] = useState<number[]>([]);
+  const [inboundIds, setInboundIds] = useState<number[]>([]);
+  const [sortBy, setSortBy] = useState('default');
+  const [remarkTemplate, setRemarkTemplate] = useState('');
+  const [hostOverrides, setHostOverrides] = useState('');
+
+  useEffect(() => {
+    if (!open) return;
+    setName(editingProfile?.name || '');
+    setNodeIds(parseIds(editingProfile?.nodeIds));
+    setInboundIds(parseIds(editingProfile?.inboundIds));
+    setSortBy(editingProfile?.sortBy || 'default');
+    setRemarkTemplate(editingProfile?.remarkTemplate || '');
+    setHostOverrides(editingProfile?.hostOverrides || '');
+  }, [open, editingProfile]);
+
+  // Selection order is the default subscription order
+  const toggle = (list: number[], setList: (v: number[]) => void, id: number) => {
+    setList(list.includes(id) ? list.filter(x => x !== id) : [...list, id]);
+  };
+
+  const handleSubmit = () => {
+    onSubmit({
+      ...(editingProfile ? { id: editingProfile.id } : {}),
+      name,
+      nodeIds: nodeIds.join(','),
+      inboundIds: inboundIds.join(','),
+      sortBy: sortBy === 'default' ? '' : sortBy,
+      remarkTemplate,
+      hostOverrides: hostOverrides.trim(),
+    });
+  };
+
+  const renderList = (items: any[], selected: number[], setSelected: (v: number[]) => void, label: (item: any) => string) => (
+    <div className="max-h-[160px] overflow-y-auto rounded-lg border p-2 space-y-1">
+      {items.map(item => (
+        <div key={item.id} className="flex items-center gap-2 rounded-md px-2 py-1.5 hover:bg-accent">
+          <Checkbox checked={selected.includes(item.id)} onCheckedChange={() => toggle(selected, setSelected, item.id)} />
+          <span className="text-sm flex-1 cursor-pointer" onClick={() => toggle(selected, setSelected, item.id)}>{label(item)}</span>
+          {selected.includes(item.id) && (
+            <span className="text-xs text-muted-foreground">#{selected.indexOf(item.id) + 1}</span>
+          )}
+        </div>
+      ))}
+    </div>
+  );
+
+  return (
+    <Dialog open={open} onOpenChange={onOpenChange}>
+      <DialogContent className="max-w-lg max-h-[90vh] overflow-y-auto">
+        <DialogHeader>
+          <DialogTitle>{editingProfile ? t('xraySub.editProfile') : t('xraySub.createProfile')}</DialogTitle>
+        </DialogHeader>
+        <div className="space-y-4">
+          <div className="space-y-2">
+            <Label>{t('xraySub.profileName')}</Label>
+            <Input value={name} onChange={e => setName(e.target.value)} />
+          </div>
+          <div className="space-y-2">
+            <Label>{t('xraySub.profileNodes')}</Label>
+            <p className="text-xs text-muted-foreground">{t('xraySub.profileSelectHint')}</p>
+            {renderList(nodes, nodeIds, setNodeIds, n => n.region ? `${n.name} (${n.region})` : n.name)}
+          </div>
+          <div className="space-y-2">
+            <Label>{t('xraySub.profileInbounds')}</Label>
+            {renderList(inbounds, inboundIds, setInboundIds, i => `${i.remark || i.tag} · ${i.protocol}:${i.port}`)}
+          </div>
+          <div className="space-y-2">
+            <Label>{t('xraySub.profileSortBy')}</Label>
+            <Select value={sortBy} onValueChange={setSortBy}>
+              <SelectTrigger><SelectValue /></SelectTrigger>
+              <SelectContent>
+                <SelectItem value="default">{t('xraySub.sortDefault')}</SelectItem>
+                <SelectItem value="node">{t('xraySub.sortNode')}</SelectItem>
+                <SelectItem value="region">{t('xraySub.sortRegion')}</SelectItem>
+                <SelectItem value="protocol">{t('xraySub.sortProtocol')}</SelectItem>
+                <SelectItem value="remark">{t('xraySub.sortRemark')}</SelectItem>
+              </SelectContent>
+            </Select>
+          </div>
+          <div className="space-y-2">
+            <Label>{t('xraySub.remarkTemplate')}</Label>
+            <Input value={remarkTemplate} onChange={e => setRemarkTemplate(e.target.value)} placeholder="{node} | {protocol} | {region}" className="font-mono text-sm" />
+            <p className="text-xs text-muted-foreground">{t('xraySub.remarkTemplateHint')}</p>
+          </div>
+          <div className="space-y-2">
+            <Label>{t('xraySub.hostOverrides')}</Label>
+            <Textarea value={hostOverrides} onChange={e => setHostOverrides(e.target.value)} placeholder={'{"HK": "hk.example.com"}'} rows={3} className="font-mono text-sm" />
+            <p className="text-xs text-muted-foreground">{t('xraySub.hostOverridesHint')}</p>
+          </div>
+        </div>
+        <DialogFooter>
+          <Button variant="outline" onClick={() => onOpenChange(false)}>{t('common.cancel')}</Button>
+          <Button onClick={handleSubmit} disabled={!name}>{t('common.save')}</Button>
+        </DialogFooter>
+      </DialogContent>
+    </Dialog>
+  );
+}
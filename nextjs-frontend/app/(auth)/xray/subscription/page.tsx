@@ -6,9 +6,17 @@ import { Button } from '@/components/ui/button';
 import { Input } from '@/components/ui/input';
 import { Badge } from '@/components/ui/badge';
 import { Table, TableBody, TableCell, TableHead, TableHeader, TableRow } from '@/components/ui/table';
-import { Copy, RefreshCw, RotateCcw, Rss, Link2, ExternalLink } from 'lucide-react';
+import { Select, SelectContent, SelectItem, SelectTrigger, SelectValue } from '@/components/ui/select';
+import { Copy, RefreshCw, RotateCcw, Rss, Link2, ExternalLink, Layers, Plus, Pencil, Trash2 } from 'lucide-react';
 import { toast } from 'sonner';
-import { getSubscriptionToken, getSubscriptionLinks, resetSubscriptionToken } from '@/lib/api/xray-subscription';
+import {
+  getSubscriptionToken, getSubscriptionLinks, resetSubscriptionToken,
+  getSubProfileList, createSubProfile, updateSubProfile, deleteSubProfile,
+  getSubTokenList, createSubToken, resetSubToken, deleteSubToken,
+} from '@/lib/api/xray-subscription';
+import { getNodeList } from '@/lib/api/node';
+import { getXrayInboundList } from '@/lib/api/xray-inbound';
+import ProfileDialog from './_components/profile-dialog';
 import { useAuth } from '@/lib/hooks/use-auth';
 import { useTranslation } from '@/lib/i18n';
 
@@ -19,12 +27,27 @@ export default function XraySubscriptionPage() {
   const [subUrl, setSubUrl] = useState('');
   const [links, setLinks] = useState<any[]>([]);
   const [loading, setLoading] = useState(true);
+  const [profiles, setProfiles] = useState<any[]>([]);
+  const [profileTokens, setProfileTokens] = useState<any[]>([]);
+  const [newTokenName, setNewTokenName] = useState('');
+  const [newTokenProfile, setNewTokenProfile] = useState('');
+  const [profileDialogOpen, setProfileDialogOpen] = useState(false);
+  const [editingProfile, setEditingProfile] = useState<any>(null);
+  const [nodes, setNodes] = useState<any[]>([]);
+  const [inbounds, setInbounds] = useState<any[]>([]);
+
+  const loadProfiles = useCallback(async () => {
+    const [profileRes, tokensRes] = await Promise.all([getSubProfileList(), getSubTokenList()]);
+    if (profileRes.code === 0) setProfiles(profileRes.data || []);
+    if (tokensRes.code === 0) setProfileTokens(tokensRes.data || []);
+  }, []);
 
   const loadData = useCallback(async () => {
     setLoading(true);
     const [tokenRes, linksRes] = await Promise.all([
       getSubscriptionToken(),
       getSubscriptionLinks(),
+      loadProfiles(),
     ]);
     if (tokenRes.code === 0 && tokenRes.data) {
       const tokenData = typeof tokenRes.data === 'string' ? tokenRes.data : tokenRes.data.token || tokenRes.data.url || '';
@@ -45,6 +68,73 @@ export default function XraySubscriptionPage() {
 
   useEffect(() => { loadData(); }, [loadData]);
 
+  useEffect(() => {
+    if (!isAdmin) return;
+    getNodeList().then(res => { if (res.code === 0) setNodes(res.data || []); });
+    getXrayInboundList().then(res => { if (res.code === 0) setInbounds(res.data || []); });
+  }, [isAdmin]);
+
+  const tokenUrl = (token: string) => `${window.location.origin}/api/v1/v/sub/${token}`;
+
+  const handleCreateToken = async () => {
+    if (!newTokenProfile) {
+      toast.error(t('xraySub.selectProfile'));
+      return;
+    }
+    const res = await createSubToken({ name: newTokenName, profileId: parseInt(newTokenProfile) });
+    if (res.code === 0) {
+      toast.success(t('common.createSuccess'));
+      setNewTokenName('');
+      loadProfiles();
+    } else {
+      toast.error(res.msg || t('xraySub.tokenCreateFailed'));
+    }
+  };
+
+  const handleResetProfileToken = async (id: number) => {
+    if (!confirm(t('xraySub.confirmReset'))) return;
+    const res = await resetSubToken(id);
+    if (res.code === 0) {
+      toast.success(t('xraySub.resetSuccess'));
+      loadProfiles();
+    } else {
+      toast.error(res.msg || t('xraySub.resetFailed'));
+    }
+  };
+
+  const handleDeleteProfileToken = async (id: number) => {
+    if (!confirm(t('common.confirmDelete'))) return;
+    const res = await deleteSubToken(id);
+    if (res.code === 0) {
+      toast.success(t('common.deleteSuccess'));
+      loadProfiles();
+    } else {
+      toast.error(res.msg);
+    }
+  };
+
+  const handleProfileSubmit = async (data: any) => {
+    const res = data.id ? await updateSubProfile(data) : await createSubProfile(data);
+    if (res.code === 0) {
+      toast.success(data.id ? t('common.updateSuccess') : t('common.createSuccess'));
+      setProfileDialogOpen(false);
+      loadProfiles();
+    } else {
+      toast.error(res.msg);
+    }
+  };
+
+  const handleDeleteProfile = async (id: number) => {
+    if (!confirm(t('common.confirmDelete'))) return;
+    const res = await deleteSubProfile(id);
+    if (res.code === 0) {
+      toast.success(t('common.deleteSuccess'));
+      loadProfiles();
+    } else {
+      toast.error(res.msg);
+    }
+  };
+
   const copyToClipboard = (text: string, label?: string) => {
     navigator.clipboard.writeText(text);
     toast.success(t('xraySub.copied', { label: label || t('common.copySuccess') }));
@@ -68,6 +158,8 @@ export default function XraySubscriptionPage() {
       case 'trojan': return 'TR';
       case 'ss':
       case 'shadowsocks': return 'SS';
+      case 'hysteria2': return 'HY2';
+      case 'tuic': return 'TU';
       default: return '??';
     }
   };
@@ -129,6 +221,23 @@ export default function XraySubscriptionPage() {
           ) : (
             <p className="text-muted-foreground text-sm">{t('xraySub.noSubAddress')}</p>
           )}
+          {subUrl && (
+            <div className="space-y-2">
+              <p className="text-xs text-muted-foreground">{t('xraySub.formatHint')}</p>
+              {[
+                { label: 'Clash / Mihomo', format: 'clash' },
+                { label: 'sing-box', format: 'singbox' },
+              ].map(f => (
+                <div key={f.format} className="flex items-center gap-2">
+                  <span className="text-sm w-32 shrink-0">{f.label}</span>
+                  <Input value={`${subUrl}?format=${f.format}`} readOnly className="font-mono text-xs" />
+                  <Button variant="outline" size="icon" onClick={() => copyToClipboard(`${subUrl}?format=${f.format}`, t('xraySub.subAddrCopied'))}>
+                    <Copy className="h-4 w-4" />
+                  </Button>
+                </div>
+              ))}
+            </div>
+          )}
           {token && (
             <div className="text-xs text-muted-foreground">
               Token: <code className="bg-muted px-1 py-0.5 rounded">{token}</code>
@@ -137,6 +246,102 @@ export default function XraySubscriptionPage() {
         </CardContent>
       </Card>
 
+      {/* Profile Subscriptions Card */}
+      {profiles.length > 0 && (
+        <Card>
+          <CardHeader className="flex flex-row items-center gap-3 pb-2">
+            <Layers className="h-5 w-5 text-primary" />
+            <CardTitle className="text-lg">{t('xraySub.profileTokens')}</CardTitle>
+          </CardHeader>
+          <CardContent className="space-y-4">
+            <p className="text-sm text-muted-foreground">{t('xraySub.profileTokensDescription')}</p>
+            <div className="flex gap-2">
+              <Input value={newTokenName} onChange={e => setNewTokenName(e.target.value)} placeholder={t('xraySub.tokenName')} className="max-w-[200px]" />
+              <Select value={newTokenProfile} onValueChange={setNewTokenProfile}>
+                <SelectTrigger className="max-w-[200px]"><SelectValue placeholder={t('xraySub.selectProfile')} /></SelectTrigger>
+                <SelectContent>
+                  {profiles.map(p => <SelectItem key={p.id} value={p.id.toString()}>{p.name}</SelectItem>)}
+                </SelectContent>
+              </Select>
+              <Button onClick={handleCreateToken}><Plus className="mr-2 h-4 w-4" />{t('common.create')}</Button>
+            </div>
+            {profileTokens.map(tk => (
+              <div key={tk.id} className="flex items-center gap-2">
+                <span className="text-sm w-40 shrink-0 truncate" title={tk.name}>
+                  {tk.name || '-'} <Badge variant="outline" className="ml-1">{tk.profileName}</Badge>
+                </span>
+                <Input value={tokenUrl(tk.token)} readOnly className="font-mono text-xs" />
+                <Button variant="outline" size="icon" onClick={() => copyToClipboard(tokenUrl(tk.token), t('xraySub.subAddrCopied'))}>
+                  <Copy className="h-4 w-4" />
+                </Button>
+                <Button variant="outline" size="icon" onClick={() => handleResetProfileToken(tk.id)} title={t('xraySub.resetToken')}>
+                  <RotateCcw className="h-4 w-4" />
+                </Button>
+                <Button variant="outline" size="icon" onClick={() => handleDeleteProfileToken(tk.id)} title={t('common.delete')}>
+                  <Trash2 className="h-4 w-4" />
+                </Button>
+              </div>
+            ))}
+          </CardContent>
+        </Card>
+      )}
+
+      {/* Subscription Profiles Card (admin) */}
+      {isAdmin && (
+        <Card>
+          <CardHeader className="flex flex-row items-center justify-between pb-2">
+            <CardTitle className="text-lg">{t('xraySub.profiles')}</CardTitle>
+            <Button size="sm" onClick={() => { setEditingProfile(null); setProfileDialogOpen(true); }}>
+              <Plus className="mr-2 h-4 w-4" />{t('xraySub.createProfile')}
+            </Button>
+          </CardHeader>
+          <CardContent className="p-0">
+            {profiles.length === 0 ? (
+              <p className="py-6 text-center text-sm text-muted-foreground">{t('xraySub.noProfiles')}</p>
+            ) : (
+              <Table>
+                <TableHeader>
+                  <TableRow>
+                    <TableHead>{t('xraySub.profileName')}</TableHead>
+                    <TableHead>{t('xraySub.remarkTemplate')}</TableHead>
+                    <TableHead>{t('xraySub.profileSortBy')}</TableHead>
+                    <TableHead>{t('xraySub.actions')}</TableHead>
+                  </TableRow>
+                </TableHeader>
+                <TableBody>
+                  {profiles.map(p => (
+                    <TableRow key={p.id}>
+                      <TableCell className="font-medium">{p.name}</TableCell>
+                      <TableCell><code className="text-xs">{p.remarkTemplate || '-'}</code></TableCell>
+                      <TableCell>{p.sortBy || t('xraySub.sortDefault')}</TableCell>
+                      <TableCell>
+                        <div className="flex gap-1">
+                          <Button variant="ghost" size="icon" onClick={() => { setEditingProfile(p); setProfileDialogOpen(true); }}>
+                            <Pencil className="h-4 w-4" />
+                          </Button>
+                          <Button variant="ghost" size="icon" onClick={() => handleDeleteProfile(p.id)}>
+                            <Trash2 className="h-4 w-4" />
+                          </Button>
+                        </div>
+                      </TableCell>
+                    </TableRow>
+                  ))}
+                </TableBody>
+              </Table>
+            )}
+          </CardContent>
+        </Card>
+      )}
+
+      <ProfileDialog
+        open={profileDialogOpen}
+        onOpenChange={setProfileDialogOpen}
+        editingProfile={editingProfile}
+        nodes={nodes}
+        inbounds={inbounds}
+        onSubmit={handleProfileSubmit}
+      />
+
       {/* Protocol Links Card */}
       {links.length > 0 && (
         <Card>
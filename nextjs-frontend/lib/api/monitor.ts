@@ -1,6 +1,8 @@
 import { post } from './client';
 
 export const getNodeHealth = () => post('/monitor/node-health', {});
+export const getNodeMetricsHistory = (nodeId: number, hours: number) =>
+  post('/monitor/node-metrics-history', { nodeId, hours });
 export const getLatencyHistory = (forwardId: number, hours: number) =>
   post('/monitor/latency-history', { forwardId, hours });
 export const getForwardFlowHistory = (forwardId: number, hours: number) =>
@@ -11,3 +13,9 @@ export const getXrayTrafficOverview = (granularity: string, hours: number) =>
   post('/monitor/v-traffic-overview', { granularity, hours });
 export const getXrayInboundFlowHistory = (inboundId: number, hours: number) =>
   post('/monitor/v-inbound-flow', { inboundId, hours });
+export const getNodeMatrix = () => post('/monitor/node-matrix', {});
+export const getNodeLinkHistory = (srcNodeId: number, dstNodeId: number, hours: number) =>
+  post('/monitor/node-link-history', { srcNodeId, dstNodeId, hours });
+export const testNodeLink = (srcNodeId: number, dstNodeId: number) =>
+  post('/monitor/node-link-test', { srcNodeId, dstNodeId });
+export const runNodeMatrix = () => post('/schedule/run', { name: 'node-link-matrix' });
@@ -16,3 +16,23 @@ export const reconcileNode = (id: number) => post('/node/reconcile', { id });
 export const updateNodeBinary = (id: number) => post('/node/update-binary', { id });
 export const updateNodeOrder = (items: { id: number; inx: number }[]) => post('/node/update-order', { items });
 export const setNodeProtocol = (data: { id: number; http: number; tls: number; socks: number }) => post('/node/set-protocol', data);
+export const rollbackNodeBinary = (id: number) => post('/node/rollback-binary', { id });
+export const rotateNodeSecret = (id: number, graceHours?: number) => post('/node/rotate-secret', { id, graceHours });
+export const approveNode = (id: number) => post('/node/approve', { id });
+export const createNodeJoinToken = (data: {
+  name: string; groupName?: string; region?: string; portSta?: number; portEnd?: number;
+  maxUses?: number; requireApproval?: boolean; expireTime?: number;
+}) => post('/node/join-token/create', data);
+export const getNodeJoinTokenList = () => post('/node/join-token/list', { panelAddr: window.location.origin });
+export const updateNodeJoinToken = (data: { id: number; enabled?: boolean; requireApproval?: boolean; maxUses?: number }) =>
+  post('/node/join-token/update', data);
+export const deleteNodeJoinToken = (id: number) => post('/node/join-token/delete', { id });
+export const getNodeReleaseInfo = () => post('/node/release-info');
+export const createNodeRollout = (data: {
+  version?: string; groupName?: string; percent?: number; canaryCount?: number; batchSize?: number; healthTimeout?: number;
+}) => post('/node/rollout/create', data);
+export const getNodeRolloutList = () => post('/node/rollout/list');
+export const getNodeRollout = (id: number) => post('/node/rollout/detail', { id });
+export const cancelNodeRollout = (id: number) => post('/node/rollout/cancel', { id });
+export const resumeNodeRollout = (id: number) => post('/node/rollout/resume', { id });
+export const rollbackNodeRollout = (id: number) => post('/node/rollout/rollback', { id });
@@ -5,4 +5,11 @@ export const getXrayClientList = (params?: { inboundId?: number; userId?: number
 export const updateXrayClient = (data: any) => post('/v/client/update', data);
 export const deleteXrayClient = (id: number) => post('/v/client/delete', { id });
 export const resetXrayClientTraffic = (id: number) => post('/v/client/reset-traffic', { id });
+export const getXrayClientOnlineIps = (id: number) => post('/v/client/online-ips', { id });
 export const getXrayClientLink = (id: number) => post('/v/client/link', { id });
+export const bulkCreateXrayClients = (data: any) => post('/v/client/bulk-create', data);
+export const bulkEnableXrayClients = (ids: number[]) => post('/v/client/bulk-enable', { ids });
+export const bulkDisableXrayClients = (ids: number[]) => post('/v/client/bulk-disable', { ids });
+export const bulkDeleteXrayClients = (ids: number[]) => post('/v/client/bulk-delete', { ids });
+export const bulkResetXrayClientTraffic = (ids: number[]) => post('/v/client/bulk-reset-traffic', { ids });
+export const bulkMoveXrayClients = (ids: number[], inboundId: number) => post('/v/client/bulk-move', { ids, inboundId });
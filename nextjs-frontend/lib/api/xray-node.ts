@@ -6,3 +6,10 @@ export const restartXray = (nodeId: number) => post('/v/node/restart', { nodeId
 export const getXrayStatus = (nodeId: number) => post('/v/node/status', { nodeId });
 export const switchXrayVersion = (nodeId: number, version: string) => post('/v/node/switch-version', { nodeId, version });
 export const getXrayVersions = () => get('/v/node/versions');
+
+// sing-box runtime (Hysteria2/TUIC inbounds)
+export const startSingbox = (nodeId: number) => post('/v/node/singbox/start', { nodeId });
+export const stopSingbox = (nodeId: number) => post('/v/node/singbox/stop', { nodeId });
+export const restartSingbox = (nodeId: number) => post('/v/node/singbox/restart', { nodeId });
+export const getSingboxStatus = (nodeId: number) => post('/v/node/singbox/status', { nodeId });
+export const switchSingboxVersion = (nodeId: number, url: string, sha256: string) => post('/v/node/singbox/switch-version', { nodeId, url, sha256 });
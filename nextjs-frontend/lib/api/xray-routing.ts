@@ -0,0 +1,11 @@
+import { post } from './client';
+
+export const getXrayOutboundList = (nodeId: number) => post('/v/routing/outbound/list', { nodeId });
+export const createXrayOutbound = (data: any) => post('/v/routing/outbound/create', data);
+export const updateXrayOutbound = (data: any) => post('/v/routing/outbound/update', data);
+export const deleteXrayOutbound = (id: number) => post('/v/routing/outbound/delete', { id });
+export const getXrayRoutingRuleList = (nodeId: number) => post('/v/routing/rule/list', { nodeId });
+export const createXrayRoutingRule = (data: any) => post('/v/routing/rule/create', data);
+export const updateXrayRoutingRule = (data: any) => post('/v/routing/rule/update', data);
+export const deleteXrayRoutingRule = (id: number) => post('/v/routing/rule/delete', { id });
+export const previewXrayRouting = (nodeId: number) => post('/v/routing/preview', { nodeId });
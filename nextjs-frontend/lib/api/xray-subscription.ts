@@ -3,3 +3,14 @@ import { post } from './client';
 export const getSubscriptionToken = () => post('/v/sub/token');
 export const getSubscriptionLinks = () => post('/v/sub/links');
 export const resetSubscriptionToken = () => post('/v/sub/reset');
+
+export const getSubProfileList = () => post('/v/sub/profile/list');
+export const createSubProfile = (data: any) => post('/v/sub/profile/create', data);
+export const updateSubProfile = (data: any) => post('/v/sub/profile/update', data);
+export const deleteSubProfile = (id: number) => post('/v/sub/profile/delete', { id });
+
+export const getSubTokenList = () => post('/v/sub/tokens/list');
+export const createSubToken = (data: { name: string; profileId: number }) => post('/v/sub/tokens/create', data);
+export const updateSubToken = (data: any) => post('/v/sub/tokens/update', data);
+export const resetSubToken = (id: number) => post('/v/sub/tokens/reset', { id });
+export const deleteSubToken = (id: number) => post('/v/sub/tokens/delete', { id });
@@ -86,13 +86,17 @@ const en: Translations = {
     tunnel: 'Tunnels',
     limit: 'Speed Limits',
     xrayInbound: 'Inbounds',
+    xrayRouting: 'Routing',
     xrayCert: 'Certificates',
     xraySub: 'Subscriptions',
     node: 'Nodes',
     user: 'Users',
     monitor: 'Monitor',
     nodeMonitor: 'Nodes',
+    nodeJoinToken: 'Join Tokens',
+    nodeRollout: 'Node Updates',
     networkMonitor: 'Network',
+    nodeMatrix: 'Link Matrix',
     config: 'Settings',
     system: 'System',
     changePassword: 'Change Password',
@@ -187,6 +191,22 @@ const en: Translations = {
     diagnoseFailed: 'Diagnosis request failed',
     delayMs: 'Latency',
     packetLoss: 'Packet Loss',
+    traceSummary: 'Route: {n} hops ({protocol})',
+    traceNotReached: 'target not reached',
+    traceHost: 'Host',
+    traceAvg: 'Avg ms',
+    traceBest: 'Best ms',
+    traceWorst: 'Worst ms',
+    traceFailed: 'Traceroute unavailable',
+    probe: 'Health Check',
+    probeTcp: 'TCP connect',
+    probeUdpEcho: 'UDP echo (any reply)',
+    probeUdpDns: 'UDP DNS query',
+    probeUdpQuic: 'UDP QUIC initial',
+    probeUdpHex: 'UDP custom payload',
+    probeDnsName: 'Query Name',
+    probeHexPayload: 'Payload (hex)',
+    probeUdpHint: 'UDP-primary: diagnosis and latency monitoring send this UDP payload to the targets and wait for a reply.',
     success: 'Success',
     failed: 'Failed',
     selectEntry: 'Select entry',
@@ -306,12 +326,33 @@ const en: Translations = {
     switchVersion: 'Switch',
     switching: 'Switching...',
     versionSwitchStarted: 'Version switch started, please refresh later',
+    singboxTitle: 'sing-box (Hysteria2/TUIC)',
+    singboxRunning: 'Running',
+    singboxStats: 'Traffic stats',
+    singboxYes: 'Yes',
+    singboxNo: 'No',
+    singboxStatsHint: 'This build has no V2Ray API, so its traffic could not be metered and the node will not serve Hysteria2/TUIC inbounds. Install a build with with_v2ray_api.',
+    singboxUrl: 'Download URL',
+    singboxUrlPlaceholder: 'Release tarball of a build with with_v2ray_api',
+    singboxSha256: 'SHA-256',
+    singboxSha256Placeholder: 'Checksum of the tarball (64 hex characters)',
+    singboxInstallRequired: 'Enter the download URL and a valid SHA-256 checksum',
+    singboxInstall: 'Install',
+    singboxRestart: 'Restart',
+    singboxRestarted: 'sing-box restarted',
+    singboxRestartFailed: 'Failed to restart sing-box',
     switchFailed: 'Switch failed',
     selectTargetVersion: 'Please select target version',
     updateBinary: 'Update',
     updatingBinary: 'Updating',
     confirmUpdateBinary: 'Are you sure to update binary for node "{name}"? It will download new version and restart.',
     updateBinarySent: 'Update command sent, node will download and restart',
+    rotateSecret: 'Rotate secret',
+    pendingApproval: 'Awaiting approval',
+    approve: 'Approve',
+    approveSuccess: 'Node "{name}" approved, it will connect on its next retry',
+    confirmRotateSecret: 'Rotate the secret of node "{name}"? The old secret stays valid for 24 hours; install commands will use the new one.',
+    rotateSecretSuccess: 'Secret rotated, node is reconnecting with the new secret',
     confirmDeleteNode: 'Are you sure to delete this node? Related tunnels and forwards will be affected.',
     current: 'Current',
     days: 'd',
@@ -325,12 +366,90 @@ const en: Translations = {
     xrayDisguiseName: 'Xray Disguise',
     groupName: 'Group',
     groupNamePlaceholder: 'e.g. Hong Kong, US',
+    region: 'Region',
+    regionPlaceholder: 'e.g. HK, US (used by subscription profiles)',
     protocolBlock: 'Protocol Blocking',
     protocolBlockDesc: 'Block specified protocol traffic on this node (node must be online)',
     protocolUpdateSuccess: 'Protocol blocking updated',
     protocolUpdateFailed: 'Failed to update protocol blocking',
     nodeOfflineCannotSet: 'Node is offline, cannot modify protocol blocking',
   },
+  nodeJoinToken: {
+    title: 'Join Tokens',
+    create: 'New Token',
+    hint: 'Run the command on a fresh machine (e.g. from cloud-init). It enrolls the machine as a node in the token\'s group and installs the agent with its own secret.',
+    name: 'Name',
+    group: 'Node group',
+    region: 'Region',
+    portRange: 'Port range',
+    uses: 'Uses',
+    maxUses: 'Max uses',
+    maxUsesHint: '0 = unlimited, 1 = one-shot',
+    unlimited: 'unlimited',
+    requireApproval: 'Require approval',
+    requireApprovalHint: 'Enrolled nodes stay offline until approved on the node page',
+    expireDays: 'Expires in (days)',
+    expireDaysHint: '0 = never',
+    expires: 'Expires',
+    never: 'Never',
+    expired: 'Expired',
+    nodes: 'Nodes',
+    pendingCount: '{n} pending',
+    command: 'Command',
+    enabled: 'Enabled',
+    actions: 'Actions',
+    created: 'Token created',
+    confirmDelete: 'Delete this token? Nodes it enrolled are kept.',
+  },
+  nodeRollout: {
+    title: 'Node Updates',
+    release: 'Published Release',
+    publicKey: 'Release public key',
+    noPublicKey: 'not configured',
+    noManifest: 'No signed manifest found next to the node binaries',
+    arch: 'Arch',
+    version: 'Version',
+    signature: 'Signature',
+    verified: 'Verified',
+    create: 'New Rollout',
+    start: 'Start',
+    started: 'Rollout started',
+    scope: 'Scope',
+    status: 'Status',
+    message: 'Message',
+    createdTime: 'Created',
+    actions: 'Actions',
+    detail: 'Details',
+    resume: 'Resume',
+    rollback: 'Roll back',
+    allNodes: 'All nodes',
+    canaryShort: '{n} canary',
+    batchShort: '{n}/batch',
+    itemsTitle: 'Rollout #{id}',
+    batch: 'Batch',
+    canary: 'Canary',
+    node: 'Node',
+    fromVersion: 'From',
+    group: 'Node group',
+    percent: 'Nodes to update (%)',
+    canaryCount: 'Canary nodes',
+    batchSize: 'Batch size',
+    healthTimeout: 'Reconnect timeout (s)',
+    versionPlaceholder: 'Taken from the signed manifest',
+    createHint: 'Canary nodes update first. Each batch must reconnect on the new version before the next starts; a node that fails to reconnect rolls back to its backup binary and the rollout stops.',
+    confirmCancel: 'Stop this rollout? Nodes already updating will finish.',
+    confirmRollback: 'Roll back every node this rollout updated?',
+    confirmRollbackNode: 'Roll this node back to its previous binary?',
+    status_running: 'Running',
+    status_completed: 'Completed',
+    status_failed: 'Failed',
+    status_cancelled: 'Cancelled',
+    status_rolled_back: 'Rolled back',
+    status_pending: 'Pending',
+    status_updating: 'Updating',
+    status_success: 'Success',
+    status_skipped: 'Skipped',
+  },
   user: {
     title: 'User Management',
     createUser: 'Create User',
@@ -372,6 +491,8 @@ const en: Translations = {
     weekThu: 'Thursday',
     weekFri: 'Friday',
     weekSat: 'Saturday',
+    timezone: 'Timezone',
+    timezonePlaceholder: 'e.g. America/New_York, empty = panel timezone',
     permissionSettings: 'Permission Settings',
     gostForward: 'GOST Forward',
     xrayProxy: 'Xray Proxy',
@@ -388,6 +509,23 @@ const en: Translations = {
     fillUsernameAndPassword: 'Please enter username and password',
     usernamePlaceholder: 'Username',
   },
+  nodeMatrix: {
+    title: 'Link Matrix',
+    hint: 'Latency, TCP throughput and UDP loss between pairs of online nodes. Rows are the sending node, columns the receiving node. The scheduled job "node-link-matrix" is off by default; once enabled it tests nodes of the same group every 6 hours.',
+    runAll: 'Test all',
+    runQueued: 'Full test queued; results appear as pairs complete',
+    source: 'From',
+    target: 'To',
+    latency: 'Latency',
+    jitter: 'Jitter',
+    tcp: 'TCP',
+    udp: 'UDP',
+    loss: 'Loss',
+    testNow: 'Test now',
+    testDone: 'Test finished',
+    failed: 'Failed',
+    notTested: 'Not tested',
+  },
   monitor: {
     title: 'Status Monitor',
     nodeMonitorTitle: 'Node Monitor',
@@ -419,6 +557,20 @@ const en: Translations = {
     outbound: 'Outbound',
     noTrafficData: 'No traffic data',
     forwardLatency: 'Forward Latency',
+    metricsHistory: 'Metrics history',
+    noMetricsData: 'No metrics recorded in this range',
+    cpuMemory: 'CPU / Memory / Disk',
+    cpuPeak: 'CPU peak',
+    disk: 'Disk',
+    bandwidth: 'Bandwidth',
+    loadConnections: 'Load / Connections',
+    load1: 'Load (1m)',
+    tcpConns: 'TCP connections',
+    load: 'Load',
+    connections: 'Connections',
+    connsColumnHint: 'Established TCP connections / active forwarding connections',
+    conntrack: 'Conntrack',
+    activeConns: '{n} conns',
     hours1: '1 Hour',
     hours6: '6 Hours',
     hours24: '24 Hours',
@@ -478,10 +630,32 @@ const en: Translations = {
     monitorIntervalDesc: 'Latency monitor check interval, minimum 10 seconds',
     monitorRetentionDays: 'Data Retention Days',
     monitorRetentionDaysDesc: 'Number of days to keep monitoring data (latency, traffic snapshots)',
+    ipLimitWindow: 'IP Limit Window',
+    ipLimitWindowDesc: 'Distinct source IPs of a client are counted over this period when enforcing its IP limit',
+    ipLimitBanDuration: 'IP Limit Ban Duration',
+    ipLimitBanDurationDesc: 'How long a client that exceeds its IP limit stays removed from the node',
+    nodeMatrixCrossGroup: 'Link Matrix Across Groups',
+    nodeMatrixCrossGroupDesc: 'Let the scheduled link matrix test every pair of online nodes instead of only nodes in the same group. Each pair sends up to 64 MB of test traffic',
+    nodeMatrixMaxPairs: 'Link Matrix Pairs per Run',
+    nodeMatrixMaxPairsDesc: 'Most node pairs one scheduled link matrix run tests (default 12). Pairs measured longest ago go first, so later runs cover the rest',
+    timezone: 'Panel Timezone',
+    timezoneDesc: 'IANA timezone name, e.g. Asia/Shanghai. Flow resets, expiry checks and statistics use this zone; leave empty for the server timezone',
     seconds: 'sec',
     days: 'days',
     basicInfo: 'Basic Info',
     securityAndMonitor: 'Security & Monitoring',
+    subscription: 'Subscription',
+    hours: 'hours',
+    subProfileName: 'Profile Name',
+    subProfileNameDesc: 'Profile name shown in proxy clients (Content-Disposition). Defaults to the app name',
+    subUpdateInterval: 'Update Interval',
+    subUpdateIntervalDesc: 'How often clients should refresh the subscription (profile-update-interval)',
+    subInfoNodes: 'Quota Info Nodes',
+    subInfoNodesDesc: 'Add pseudo-nodes showing remaining traffic and expiry date to subscriptions',
+    singboxDnsTemplate: 'sing-box DNS Template',
+    singboxDnsTemplateDesc: 'JSON object used as the "dns" section of sing-box subscriptions. Leave empty for the built-in default',
+    singboxRouteTemplate: 'sing-box Route Template',
+    singboxRouteTemplateDesc: 'JSON object used as the "route" section. Groups are tagged "proxy" (selector) and "auto" (urltest)',
     other: 'Other',
   },
   profile: {
@@ -601,6 +775,73 @@ const en: Translations = {
     daysUnit: 'days',
     selectUser: 'Select user',
     noBind: 'No binding',
+    onlineIps: 'Online IPs',
+    ipBanned: 'IP Banned',
+    ipBannedUntil: 'Removed for exceeding the IP limit until {time}',
+    ipWindow: 'Distinct IPs in the last {sec}s: {count} / {limit}',
+    noOnlineIps: 'No IPs reported yet',
+    ipAddress: 'IP',
+    ipNode: 'Node',
+    ipLastSeen: 'Last Seen',
+    bulkCreate: 'Bulk Create',
+    count: 'Count',
+    startIndex: 'Start Number',
+    prefix: 'Remark Prefix',
+    emailTemplate: 'Email Template',
+    emailTemplateHint: 'Placeholders: {prefix}, {n}, {user}, {rand}. Must contain {n} or {rand}. Empty = {user}_{rand}_{n}@flux',
+    sharedLimits: 'Every created client gets the same limits below.',
+    selected: '{count} selected',
+    selectAll: 'Select all',
+    enable: 'Enable',
+    disable: 'Disable',
+    resetTraffic: 'Reset Traffic',
+    moveTo: 'Move',
+    moveClients: 'Move Clients',
+    moveHint: 'Clients keep their credentials. UUID protocols (VMess/VLESS/TUIC) only accept clients with a UUID.',
+    targetInbound: 'Target inbound',
+    confirmBulkDelete: 'Delete the {count} selected clients?',
+    confirmBulkResetTraffic: 'Reset traffic for the {count} selected clients?',
+    bulkResult: 'Result',
+    bulkSummary: '{success} succeeded, {failed} failed',
+    resultOk: 'OK',
+  },
+  xrayRouting: {
+    title: 'Routing',
+    selectNode: 'Select node',
+    preview: 'Preview JSON',
+    outbounds: 'Outbounds',
+    rules: 'Routing Rules',
+    addOutbound: 'Add Outbound',
+    editOutbound: 'Edit Outbound',
+    addRule: 'Add Rule',
+    editRule: 'Edit Rule',
+    tag: 'Tag',
+    protocol: 'Protocol',
+    target: 'Target',
+    chain: 'Chain (our node)',
+    chainTarget: 'Upstream inbound',
+    selectChainTarget: 'Select an inbound on another node',
+    chainHint: 'A dedicated client is created on the upstream inbound; its traffic is counted like any other client.',
+    settingsJson: 'Settings (JSON)',
+    streamSettingsJson: 'Stream Settings (JSON)',
+    dialerProxy: 'Dial Through',
+    dialerProxyHint: 'Connect to this outbound\'s server through another outbound (sockopt.dialerProxy).',
+    none: 'None',
+    order: 'Order',
+    conditions: 'Conditions',
+    outboundTag: 'Outbound',
+    inbounds: 'Inbounds',
+    users: 'User IDs',
+    usersHint: 'Matches all clients of these users on this node.',
+    domain: 'Domains',
+    port: 'Ports',
+    network: 'Network',
+    any: 'Any',
+    sniffProtocol: 'Sniffed Protocols',
+    rulesHint: 'Rules are matched in order; unmatched traffic goes direct. Add a last rule with network tcp,udp to change the default.',
+    fillTag: 'Please fill in a tag',
+    confirmDeleteOutbound: 'Delete this outbound?',
+    confirmDeleteRule: 'Delete this rule?',
   },
   xrayCert: {
     title: 'Certificate Management',
@@ -651,6 +892,7 @@ const en: Translations = {
     copySubAddr: 'Copy',
     subAddrCopied: 'Subscription URL',
     noSubAddress: 'No subscription URL available, please contact admin',
+    formatHint: 'Clients are detected by User-Agent. To force a format, use one of these URLs:',
     protocolLinks: 'Protocol Links',
     protocolCol: 'Protocol',
     nameCol: 'Name',
@@ -664,6 +906,29 @@ const en: Translations = {
     confirmReset: 'Are you sure you want to reset the subscription URL? The old URL will become invalid immediately.',
     resetSuccess: 'Subscription URL has been reset',
     resetFailed: 'Failed to reset subscription URL',
+    profileTokens: 'Profile Subscriptions',
+    profileTokensDescription: 'Extra subscription URLs, each rendered through a profile chosen by the admin.',
+    tokenName: 'Name (optional)',
+    selectProfile: 'Select profile',
+    tokenCreateFailed: 'Failed to create subscription URL',
+    profiles: 'Subscription Profiles',
+    noProfiles: 'No profiles yet',
+    createProfile: 'New Profile',
+    editProfile: 'Edit Profile',
+    profileName: 'Profile Name',
+    profileNodes: 'Nodes',
+    profileInbounds: 'Inbounds',
+    profileSelectHint: 'Leave empty to include all. Selection order is the default ordering.',
+    profileSortBy: 'Sort By',
+    sortDefault: 'Selection order',
+    sortNode: 'Node',
+    sortRegion: 'Region',
+    sortProtocol: 'Protocol',
+    sortRemark: 'Name',
+    remarkTemplate: 'Name Template',
+    remarkTemplateHint: 'Placeholders: {node} {region} {protocol} {remark} {inbound} {port}',
+    hostOverrides: 'Host Overrides by Region',
+    hostOverridesHint: 'JSON object mapping node region to the address clients connect to',
   },
   inboundDialog: {
     editInbound: 'Edit Inbound',
@@ -722,6 +987,18 @@ const en: Translations = {
     ivCheck: 'IV Check',
     ivCheckTooltip: 'Check initialization vector to prevent replay attacks, recommended to enable',
     unknownProtocol: 'Unknown protocol',
+    singboxHint: 'Served by sing-box on the node over QUIC. Requires TLS with a certificate; sing-box must be installed on the node.',
+    upMbps: 'Upload (Mbps)',
+    downMbps: 'Download (Mbps)',
+    bandwidthTooltip: 'Server-side bandwidth for Brutal congestion control, 0 or empty lets the client decide',
+    obfsPassword: 'Salamander obfs password',
+    obfsPasswordTooltip: 'Optional. Disguises QUIC traffic; clients must use the same password',
+    masquerade: 'Masquerade URL',
+    masqueradeTooltip: 'Optional. Non-Hysteria requests are proxied to this site',
+    congestionControl: 'Congestion control',
+    congestionControlTooltip: 'QUIC congestion control algorithm, bbr is recommended',
+    zeroRtt: '0-RTT handshake',
+    zeroRttTooltip: 'Lower latency on reconnect at the cost of replay protection',
     sniName: 'SNI (Name)',
     dest: 'Dest',
   },
@@ -84,13 +84,17 @@ const zh = {
     tunnel: '隧道管理',
     limit: '限速规则',
     xrayInbound: '入站管理',
+    xrayRouting: '路由管理',
     xrayCert: '证书管理',
     xraySub: '订阅管理',
     node: '节点管理',
     user: '用户管理',
     monitor: '状态监控',
     nodeMonitor: '节点监控',
+    nodeJoinToken: '加入令牌',
+    nodeRollout: '节点更新',
     networkMonitor: '网络监控',
+    nodeMatrix: '节点互测',
     config: '系统配置',
     system: '系统',
     changePassword: '修改密码',
@@ -185,6 +189,22 @@ const zh = {
     diagnoseFailed: '诊断请求失败',
     delayMs: '延迟',
     packetLoss: '丢包',
+    traceSummary: '路由：{n} 跳（{protocol}）',
+    traceNotReached: '未到达目标',
+    traceHost: '地址',
+    traceAvg: '平均 ms',
+    traceBest: '最佳 ms',
+    traceWorst: '最差 ms',
+    traceFailed: '路由追踪不可用',
+    probe: '健康检查',
+    probeTcp: 'TCP 连接',
+    probeUdpEcho: 'UDP 回显（任意回包）',
+    probeUdpDns: 'UDP DNS 查询',
+    probeUdpQuic: 'UDP QUIC 初始包',
+    probeUdpHex: 'UDP 自定义载荷',
+    probeDnsName: '查询域名',
+    probeHexPayload: '载荷（十六进制）',
+    probeUdpHint: 'UDP 为主：诊断和延迟监控会向目标发送该 UDP 载荷并等待回包。',
     success: '成功',
     failed: '失败',
     selectEntry: '选择入口',
@@ -304,12 +324,33 @@ const zh = {
     switchVersion: '切换',
     switching: '切换中...',
     versionSwitchStarted: '版本切换已开始，请稍候刷新查看',
+    singboxTitle: 'sing-box (Hysteria2/TUIC)',
+    singboxRunning: '运行中',
+    singboxStats: '流量统计',
+    singboxYes: '是',
+    singboxNo: '否',
+    singboxStatsHint: '当前构建未包含 V2Ray API，流量无法计量，节点不会提供 Hysteria2/TUIC 入站。请安装带 with_v2ray_api 的构建。',
+    singboxUrl: '下载地址',
+    singboxUrlPlaceholder: '带 with_v2ray_api 构建的发布包地址',
+    singboxSha256: 'SHA-256',
+    singboxSha256Placeholder: '发布包的校验值（64 位十六进制）',
+    singboxInstallRequired: '请填写下载地址和有效的 SHA-256 校验值',
+    singboxInstall: '安装',
+    singboxRestart: '重启',
+    singboxRestarted: 'sing-box 已重启',
+    singboxRestartFailed: 'sing-box 重启失败',
     switchFailed: '切换失败',
     selectTargetVersion: '请选择目标版本',
     updateBinary: '更新',
     updatingBinary: '更新中',
     confirmUpdateBinary: '确定更新节点 "{name}" 的二进制文件？节点将自动下载新版本并重启。',
     updateBinarySent: '更新指令已发送，节点将自动下载并重启',
+    rotateSecret: '轮换密钥',
+    pendingApproval: '待批准',
+    approve: '批准',
+    approveSuccess: '节点 "{name}" 已批准，将在下次重试时连接',
+    confirmRotateSecret: '确定轮换节点 "{name}" 的密钥吗？旧密钥在 24 小时内仍然有效，安装命令将使用新密钥。',
+    rotateSecretSuccess: '密钥已轮换，节点正在使用新密钥重连',
     confirmDeleteNode: '确定删除此节点? 相关隧道和转发将受影响。',
     current: '当前',
     days: '天',
@@ -323,12 +364,90 @@ const zh = {
     xrayDisguiseName: 'Xray 伪装名',
     groupName: '分组',
     groupNamePlaceholder: '例如: 香港、美国',
+    region: '地区',
+    regionPlaceholder: '例如: HK、US（用于订阅模板）',
     protocolBlock: '协议屏蔽',
     protocolBlockDesc: '开启后将屏蔽节点上对应协议的流量，需节点在线',
     protocolUpdateSuccess: '协议屏蔽已更新',
     protocolUpdateFailed: '协议屏蔽更新失败',
     nodeOfflineCannotSet: '节点离线，无法修改协议屏蔽设置',
   },
+  nodeJoinToken: {
+    title: '加入令牌',
+    create: '新建令牌',
+    hint: '在新机器上执行命令（例如在 cloud-init 中），机器会以令牌的分组自动加入为节点，并使用独立密钥安装节点程序。',
+    name: '名称',
+    group: '节点分组',
+    region: '地区',
+    portRange: '端口范围',
+    uses: '使用次数',
+    maxUses: '最大使用次数',
+    maxUsesHint: '0 为不限，1 为一次性',
+    unlimited: '不限',
+    requireApproval: '需要审批',
+    requireApprovalHint: '加入的节点在节点页面批准前无法连接',
+    expireDays: '有效期（天）',
+    expireDaysHint: '0 为永不过期',
+    expires: '过期时间',
+    never: '永不',
+    expired: '已过期',
+    nodes: '节点',
+    pendingCount: '{n} 个待批准',
+    command: '命令',
+    enabled: '启用',
+    actions: '操作',
+    created: '令牌已创建',
+    confirmDelete: '确定删除该令牌吗？已加入的节点会保留。',
+  },
+  nodeRollout: {
+    title: '节点更新',
+    release: '当前发布',
+    publicKey: '发布公钥',
+    noPublicKey: '未配置',
+    noManifest: '节点二进制旁未找到签名的发布清单',
+    arch: '架构',
+    version: '版本',
+    signature: '签名',
+    verified: '已校验',
+    create: '新建发布',
+    start: '开始',
+    started: '发布已开始',
+    scope: '范围',
+    status: '状态',
+    message: '信息',
+    createdTime: '创建时间',
+    actions: '操作',
+    detail: '详情',
+    resume: '继续',
+    rollback: '回滚',
+    allNodes: '全部节点',
+    canaryShort: '金丝雀 {n}',
+    batchShort: '每批 {n}',
+    itemsTitle: '发布 #{id}',
+    batch: '批次',
+    canary: '金丝雀',
+    node: '节点',
+    fromVersion: '原版本',
+    group: '节点分组',
+    percent: '更新比例 (%)',
+    canaryCount: '金丝雀节点数',
+    batchSize: '每批节点数',
+    healthTimeout: '重连超时 (秒)',
+    versionPlaceholder: '默认取自签名发布清单',
+    createHint: '金丝雀节点先更新。每批节点须以新版本重新连接后才会开始下一批；未能重连的节点会自动回滚到备份的二进制文件，发布随即暂停。',
+    confirmCancel: '确定停止该发布？正在更新的节点会继续完成',
+    confirmRollback: '确定回滚该发布更新过的所有节点？',
+    confirmRollbackNode: '确定将该节点回滚到上一个版本？',
+    status_running: '进行中',
+    status_completed: '已完成',
+    status_failed: '失败',
+    status_cancelled: '已取消',
+    status_rolled_back: '已回滚',
+    status_pending: '等待中',
+    status_updating: '更新中',
+    status_success: '成功',
+    status_skipped: '已跳过',
+  },
   user: {
     title: '用户管理',
     createUser: '创建用户',
@@ -370,6 +489,8 @@ const zh = {
     weekThu: '周四',
     weekFri: '周五',
     weekSat: '周六',
+    timezone: '时区',
+    timezonePlaceholder: '如 Asia/Shanghai，留空使用面板时区',
     permissionSettings: '权限设置',
     gostForward: 'GOST 转发',
     xrayProxy: 'Xray 代理',
@@ -386,6 +507,23 @@ const zh = {
     fillUsernameAndPassword: '请填写用户名和密码',
     usernamePlaceholder: '用户名',
   },
+  nodeMatrix: {
+    title: '节点互测',
+    hint: '在线节点之间的延迟、TCP 吞吐和 UDP 丢包。行为发送节点，列为接收节点。定时任务 "node-link-matrix" 默认关闭，开启后每 6 小时测试同一分组内的节点。',
+    runAll: '全部测试',
+    runQueued: '已加入执行队列，结果将按组陆续更新',
+    source: '从',
+    target: '到',
+    latency: '延迟',
+    jitter: '抖动',
+    tcp: 'TCP',
+    udp: 'UDP',
+    loss: '丢包',
+    testNow: '立即测试',
+    testDone: '测试完成',
+    failed: '失败',
+    notTested: '未测试',
+  },
   monitor: {
     title: '状态监控',
     nodeMonitorTitle: '节点监控',
@@ -417,6 +555,20 @@ const zh = {
     outbound: '出站',
     noTrafficData: '暂无流量数据',
     forwardLatency: '转发延迟',
+    metricsHistory: '历史指标',
+    noMetricsData: '该时间段内没有指标记录',
+    cpuMemory: 'CPU / 内存 / 磁盘',
+    cpuPeak: 'CPU 峰值',
+    disk: '磁盘',
+    bandwidth: '带宽',
+    loadConnections: '负载 / 连接数',
+    load1: '负载 (1分钟)',
+    tcpConns: 'TCP 连接数',
+    load: '负载',
+    connections: '连接数',
+    connsColumnHint: '已建立的 TCP 连接 / 活跃转发连接',
+    conntrack: '连接跟踪',
+    activeConns: '{n} 个连接',
     hours1: '1小时',
     hours6: '6小时',
     hours24: '24小时',
@@ -476,10 +628,32 @@ const zh = {
     monitorIntervalDesc: '延迟监控的检测间隔，最小 10 秒',
     monitorRetentionDays: '监控数据保留天数',
     monitorRetentionDaysDesc: '监控数据（延迟、流量快照）保留的天数',
+    ipLimitWindow: 'IP 限制统计窗口',
+    ipLimitWindowDesc: '执行客户端 IP 限制时，统计该时间段内出现的不同来源 IP 数',
+    ipLimitBanDuration: 'IP 超限封禁时长',
+    ipLimitBanDurationDesc: '客户端超出 IP 限制后从节点移除的时长',
+    nodeMatrixCrossGroup: '节点互测跨分组',
+    nodeMatrixCrossGroupDesc: '定时节点互测测试所有在线节点两两之间的链路，而不只是同一分组内的节点。每组测试最多产生 64 MB 流量',
+    nodeMatrixMaxPairs: '节点互测每次组数',
+    nodeMatrixMaxPairsDesc: '每次定时节点互测最多测试的节点组数（默认 12），优先测试最久未测的组，其余留给后续运行',
+    timezone: '面板时区',
+    timezoneDesc: 'IANA 时区名称，如 Asia/Shanghai。流量重置、到期检查和统计按此时区计算，留空使用服务器时区',
     seconds: '秒',
     days: '天',
     basicInfo: '基本信息',
     securityAndMonitor: '安全与监控',
+    subscription: '订阅',
+    hours: '小时',
+    subProfileName: '配置名称',
+    subProfileNameDesc: '代理客户端中显示的订阅名称（Content-Disposition），默认使用应用名称',
+    subUpdateInterval: '更新间隔',
+    subUpdateIntervalDesc: '客户端自动更新订阅的间隔（profile-update-interval）',
+    subInfoNodes: '流量信息节点',
+    subInfoNodesDesc: '在订阅中加入显示剩余流量和到期时间的伪节点',
+    singboxDnsTemplate: 'sing-box DNS 模板',
+    singboxDnsTemplateDesc: '作为 sing-box 订阅 "dns" 段的 JSON 对象，留空使用内置默认值',
+    singboxRouteTemplate: 'sing-box 路由模板',
+    singboxRouteTemplateDesc: '作为 "route" 段的 JSON 对象。分组标签为 "proxy"（手动选择）和 "auto"（自动测速）',
     other: '其他',
   },
   profile: {
@@ -599,6 +773,73 @@ const zh = {
     daysUnit: '天',
     selectUser: '选择用户',
     noBind: '不绑定',
+    onlineIps: '在线 IP',
+    ipBanned: 'IP 超限',
+    ipBannedUntil: '因超出 IP 限制已暂时移除，{time} 恢复',
+    ipWindow: '最近 {sec} 秒内 IP 数: {count} / {limit}',
+    noOnlineIps: '暂无上报的 IP',
+    ipAddress: 'IP',
+    ipNode: '节点',
+    ipLastSeen: '最后出现',
+    bulkCreate: '批量创建',
+    count: '数量',
+    startIndex: '起始编号',
+    prefix: '备注前缀',
+    emailTemplate: '邮箱模板',
+    emailTemplateHint: '占位符：{prefix}、{n}、{user}、{rand}，必须包含 {n} 或 {rand}。留空为 {user}_{rand}_{n}@flux',
+    sharedLimits: '所有新建客户端使用下方相同的限制。',
+    selected: '已选 {count} 个',
+    selectAll: '全选',
+    enable: '启用',
+    disable: '禁用',
+    resetTraffic: '重置流量',
+    moveTo: '迁移',
+    moveClients: '迁移客户端',
+    moveHint: '客户端保留原有凭据。UUID 类协议（VMess/VLESS/TUIC）只接受使用 UUID 的客户端。',
+    targetInbound: '目标入站',
+    confirmBulkDelete: '确定删除选中的 {count} 个客户端？',
+    confirmBulkResetTraffic: '确定重置选中的 {count} 个客户端的流量？',
+    bulkResult: '执行结果',
+    bulkSummary: '成功 {success} 个，失败 {failed} 个',
+    resultOk: '成功',
+  },
+  xrayRouting: {
+    title: '路由管理',
+    selectNode: '选择节点',
+    preview: '预览 JSON',
+    outbounds: '出站',
+    rules: '路由规则',
+    addOutbound: '添加出站',
+    editOutbound: '编辑出站',
+    addRule: '添加规则',
+    editRule: '编辑规则',
+    tag: '标签',
+    protocol: '协议',
+    target: '目标',
+    chain: '链式 (本面板节点)',
+    chainTarget: '上游入站',
+    selectChainTarget: '选择其他节点上的入站',
+    chainHint: '会在上游入站上创建一个专用客户端，其流量与普通客户端一样统计。',
+    settingsJson: 'Settings (JSON)',
+    streamSettingsJson: 'Stream Settings (JSON)',
+    dialerProxy: '前置代理',
+    dialerProxyHint: '通过另一个出站连接此出站的服务器 (sockopt.dialerProxy)。',
+    none: '无',
+    order: '排序',
+    conditions: '匹配条件',
+    outboundTag: '出站',
+    inbounds: '入站',
+    users: '用户 ID',
+    usersHint: '匹配这些用户在本节点上的所有客户端。',
+    domain: '域名',
+    port: '端口',
+    network: '网络',
+    any: '任意',
+    sniffProtocol: '嗅探协议',
+    rulesHint: '规则按顺序匹配，未命中的流量直连。在最后添加一条 network 为 tcp,udp 的规则可更改默认出站。',
+    fillTag: '请填写标签',
+    confirmDeleteOutbound: '确定删除此出站?',
+    confirmDeleteRule: '确定删除此规则?',
   },
   xrayCert: {
     title: '证书管理',
@@ -649,6 +890,7 @@ const zh = {
     copySubAddr: '复制',
     subAddrCopied: '订阅地址',
     noSubAddress: '暂无订阅地址，请联系管理员',
+    formatHint: '客户端格式会根据 User-Agent 自动识别，如需指定格式可使用以下地址：',
     protocolLinks: '协议链接',
     protocolCol: '协议',
     nameCol: '名称',
@@ -662,6 +904,29 @@ const zh = {
     confirmReset: '确定要重置订阅地址吗？重置后旧地址将立即失效。',
     resetSuccess: '订阅地址已重置',
     resetFailed: '重置订阅地址失败',
+    profileTokens: '模板订阅',
+    profileTokensDescription: '额外的订阅地址，每个地址按管理员配置的订阅模板生成。',
+    tokenName: '名称（可选）',
+    selectProfile: '选择模板',
+    tokenCreateFailed: '创建订阅地址失败',
+    profiles: '订阅模板',
+    noProfiles: '暂无订阅模板',
+    createProfile: '新建模板',
+    editProfile: '编辑模板',
+    profileName: '模板名称',
+    profileNodes: '节点',
+    profileInbounds: '入站',
+    profileSelectHint: '不选则包含全部，勾选顺序即默认排序。',
+    profileSortBy: '排序方式',
+    sortDefault: '勾选顺序',
+    sortNode: '节点',
+    sortRegion: '地区',
+    sortProtocol: '协议',
+    sortRemark: '名称',
+    remarkTemplate: '名称模板',
+    remarkTemplateHint: '可用占位符: {node} {region} {protocol} {remark} {inbound} {port}',
+    hostOverrides: '按地区覆盖地址',
+    hostOverridesHint: 'JSON 对象，键为节点地区，值为客户端连接的地址',
   },
   inboundDialog: {
     editInbound: '编辑入站',
@@ -720,6 +985,18 @@ const zh = {
     ivCheck: 'IV Check',
     ivCheckTooltip: '启用后检查初始化向量，防止重放攻击，建议开启',
     unknownProtocol: '未知协议',
+    singboxHint: '由节点上的 sing-box 通过 QUIC 提供服务，必须启用 TLS 并配置证书，且节点需安装 sing-box。',
+    upMbps: '上行带宽 (Mbps)',
+    downMbps: '下行带宽 (Mbps)',
+    bandwidthTooltip: '服务端 Brutal 拥塞控制带宽，0 或留空由客户端决定',
+    obfsPassword: 'Salamander 混淆密码',
+    obfsPasswordTooltip: '可选，用于伪装 QUIC 流量，客户端需使用相同密码',
+    masquerade: '伪装地址',
+    masqueradeTooltip: '可选，非 Hysteria 请求将被反代到该网站',
+    congestionControl: '拥塞控制',
+    congestionControlTooltip: 'QUIC 拥塞控制算法，推荐 bbr',
+    zeroRtt: '0-RTT 握手',
+    zeroRttTooltip: '降低重连延迟，但会失去重放保护',
     sniName: 'SNI (Name)',
     dest: 'Dest',
   },